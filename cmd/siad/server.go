@@ -531,7 +531,13 @@ func (srv *Server) loadModules() error {
 	if strings.Contains(srv.config.Siad.Modules, "w") {
 		i++
 		fmt.Printf("(%d/%d) Loading wallet...\n", i, len(srv.config.Siad.Modules))
-		w, err = wallet.New(cs, tpool, filepath.Join(srv.config.Siad.SiaDir, modules.WalletDir))
+		walletDir := filepath.Join(srv.config.Siad.SiaDir, modules.WalletDir)
+		if srv.config.Siad.BreakWalletLock {
+			if err := wallet.BreakStaleLock(walletDir); err != nil {
+				return err
+			}
+		}
+		w, err = wallet.New(cs, tpool, walletDir)
 		if err != nil {
 			return err
 		}
@@ -2,8 +2,11 @@ package modules
 
 import (
 	"errors"
+	"io"
+	"time"
 
 	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/types"
 )
 
@@ -20,6 +23,61 @@ const (
 	DiffRevert DiffDirection = false
 )
 
+const (
+	// RejectedBlockLogOff disables the logging of rejected blocks.
+	RejectedBlockLogOff RejectedBlockLogLevel = iota
+
+	// RejectedBlockLogInvalid logs blocks that were rejected because they
+	// are invalid. Blocks that are already known, that do not extend the
+	// longest fork, or that have a timestamp too far in the future are not
+	// logged.
+	RejectedBlockLogInvalid
+
+	// RejectedBlockLogAll additionally logs the blocks that were not added
+	// to the current path for one of those benign reasons. They are logged
+	// as ignored rather than rejected.
+	RejectedBlockLogAll
+)
+
+const (
+	// ValidationFull verifies every block completely. Nothing is trusted.
+	ValidationFull ValidationLevel = iota
+
+	// ValidationCheckpoint skips the verification of signatures of blocks
+	// on the trusted chain until the trusted block has been added to the
+	// consensus set, and afterwards rejects any new block at or below the
+	// height of the trusted block, so that the chain can no longer be
+	// reorganized below it. Any other block at the trusted height is
+	// rejected. The user trusts that the trusted block is part of the valid
+	// chain, and that every block before it carries valid signatures.
+	ValidationCheckpoint
+
+	// ValidationAssumeValid skips the verification of signatures of blocks
+	// on the trusted chain until the trusted block has been added to the
+	// consensus set. Unlike ValidationCheckpoint, reorganizations below the
+	// trusted block are still possible, and blocks that are applied by them
+	// are fully validated. The user trusts that the blocks before the
+	// trusted block carry valid signatures, but not that the trusted block
+	// will remain in the chain.
+	ValidationAssumeValid
+)
+
+const (
+	// FileContractCreated indicates that a file contract was formed.
+	FileContractCreated FileContractEventType = iota
+
+	// FileContractRevised indicates that a file contract was revised.
+	FileContractRevised
+
+	// FileContractProven indicates that a storage proof was submitted for a
+	// file contract, resolving it.
+	FileContractProven
+
+	// FileContractExpired indicates that a file contract reached the end of
+	// its proof window without a storage proof, resolving it.
+	FileContractExpired
+)
+
 var (
 	// ConsensusChangeBeginning is a special consensus change id that tells the
 	// consensus set to provide all consensus changes starting from the very
@@ -39,6 +97,12 @@ var (
 	// target.
 	ErrBlockUnsolved = errors.New("block does not meet target")
 
+	// PrefixSoftForkSignal is the prefix of the arbitrary data that miners use
+	// to signal readiness for soft forks. Sia block headers have no version
+	// field, so a block signals by including a transaction whose arbitrary
+	// data is the prefix followed by an encoded uint64 of version bits.
+	PrefixSoftForkSignal = types.Specifier{'S', 'o', 'f', 't', 'F', 'o', 'r', 'k'}
+
 	// ErrInvalidConsensusChangeID indicates that ConsensusSetPersistSubscribe
 	// was called with a consensus change id that is not recognized. Most
 	// commonly, this means that the consensus set was deleted or replaced and
@@ -56,10 +120,116 @@ type (
 	// ConsensusChangeID is the id of a consensus change.
 	ConsensusChangeID crypto.Hash
 
+	// A RejectedBlockLogLevel determines which rejected blocks are written to
+	// the rejected block log of the consensus set.
+	RejectedBlockLogLevel int
+
+	// A ValidationLevel determines which blocks the consensus set verifies
+	// the transaction signatures of.
+	ValidationLevel int
+
+	// A FileContractEventType identifies the change to a file contract that a
+	// FileContractEvent reports.
+	FileContractEventType int
+
 	// A DiffDirection indicates the "direction" of a diff, either applied or
 	// reverted. A bool is used to restrict the value to these two possibilities.
 	DiffDirection bool
 
+	// ProcessedBlockInfo describes a block that the consensus set has
+	// processed, along with the changes that applying the block made to the
+	// consensus set. Diffs are only available if the block has been part of
+	// the current path at some point.
+	ProcessedBlockInfo struct {
+		ID          types.BlockID     `json:"id"`
+		ParentID    types.BlockID     `json:"parentid"`
+		Height      types.BlockHeight `json:"height"`
+		Timestamp   types.Timestamp   `json:"timestamp"`
+		ChildTarget types.Target      `json:"childtarget"`
+
+		// InCurrentPath indicates whether the block is part of the current
+		// path, as opposed to a fork.
+		InCurrentPath bool `json:"incurrentpath"`
+
+		DiffsGenerated            bool                       `json:"diffsgenerated"`
+		SiacoinOutputDiffs        []SiacoinOutputDiff        `json:"siacoinoutputdiffs"`
+		FileContractDiffs         []FileContractDiff         `json:"filecontractdiffs"`
+		SiafundOutputDiffs        []SiafundOutputDiff        `json:"siafundoutputdiffs"`
+		DelayedSiacoinOutputDiffs []DelayedSiacoinOutputDiff `json:"delayedsiacoinoutputdiffs"`
+		SiafundPoolDiffs          []SiafundPoolDiff          `json:"siafundpooldiffs"`
+	}
+
+	// ForkInfo describes a fork that the consensus set knows about but that
+	// is not part of the current path.
+	ForkInfo struct {
+		// Tip is the last block of the fork, and Height is its height.
+		Tip    types.BlockID     `json:"tip"`
+		Height types.BlockHeight `json:"height"`
+
+		// Work is the total difficulty of the fork, from the genesis block
+		// up to and including the tip.
+		Work types.Currency `json:"work"`
+
+		// CommonAncestor is the most recent block that the fork shares with
+		// the current path.
+		CommonAncestor       types.BlockID     `json:"commonancestor"`
+		CommonAncestorHeight types.BlockHeight `json:"commonancestorheight"`
+	}
+
+	// A ReorgWarning is sent to the subscribers of SubscribeReorgWarning when
+	// a reorg reverts more blocks than the subscriber's threshold. Transactions
+	// and objects that were restored by the newly applied blocks are not
+	// included, so every element of a warning was undone by the reorg.
+	ReorgWarning struct {
+		// Depth is the number of blocks that were reverted.
+		Depth types.BlockHeight
+
+		RevertedBlocks []types.BlockID
+		AppliedBlocks  []types.BlockID
+
+		// RevertedTransactions are the transactions of the reverted blocks
+		// that are not in any of the applied blocks.
+		RevertedTransactions []types.Transaction
+
+		// The objects created by the reverted blocks that were not recreated
+		// by the applied blocks.
+		RevertedSiacoinOutputs []types.SiacoinOutputID
+		RevertedSiafundOutputs []types.SiafundOutputID
+		RevertedFileContracts  []types.FileContractID
+	}
+
+	// A FileContractEvent is sent to the subscribers of
+	// SubscribeFileContracts when a block creates, revises, or resolves a file
+	// contract. When a block is reverted, its events are sent again in
+	// reverse order with Reverted set, so that subscribers can undo them.
+	FileContractEvent struct {
+		ID       types.FileContractID
+		Type     FileContractEventType
+		Reverted bool
+
+		// BlockID and Height identify the block that caused the event.
+		BlockID types.BlockID
+		Height  types.BlockHeight
+	}
+
+	// SubscriberStat describes how quickly a consensus set subscriber keeps
+	// up with the consensus changes that it is sent.
+	SubscriberStat struct {
+		// Subscriber is the type of the subscriber, e.g. "*wallet.Wallet".
+		Subscriber string `json:"subscriber"`
+
+		// Delivered is the number of changes the subscriber has processed,
+		// and Pending is the number of changes that are waiting for it.
+		Delivered uint64 `json:"delivered"`
+		Pending   uint64 `json:"pending"`
+
+		// The time the subscriber spent processing the most recent change,
+		// the average change and the slowest change.
+		LastLatency    time.Duration `json:"lastlatency"`
+		AverageLatency time.Duration `json:"averagelatency"`
+		MaxLatency     time.Duration `json:"maxlatency"`
+	}
+
 	// A ConsensusSetSubscriber is an object that receives updates to the consensus
 	// set every time there is a change in consensus.
 	ConsensusSetSubscriber interface {
@@ -105,6 +275,10 @@ type (
 
 		// DelayedSiacoinOutputDiffs contains the set of delayed siacoin output
 		// diffs that were applied to the consensus set in the recent change.
+		//
+		// The siacoin output, file contract, siafund output, and delayed
+		// siacoin output diffs are each sorted by id. Diffs that share an id
+		// are in the order that they were applied.
 		DelayedSiacoinOutputDiffs []DelayedSiacoinOutputDiff
 
 		// SiafundPoolDiffs are the siafund pool diffs that were applied to the
@@ -186,6 +360,20 @@ type (
 		// still be returned.
 		AcceptBlock(types.Block) error
 
+		// BehindPeers reports whether the current height is well below the
+		// median height reported by connected peers, which indicates that the
+		// consensus set is still synchronizing or is being eclipsed.
+		BehindPeers() (median types.BlockHeight, behind bool)
+
+		// BlockHeaders returns the headers of up to count consecutive blocks
+		// in the current path, starting at the given height. Fewer headers
+		// are returned near the current block, and count is capped.
+		BlockHeaders(start types.BlockHeight, count int) ([]types.BlockHeader, error)
+
+		// BlockReward returns the coinbase subsidy of a block at the given
+		// height according to the emission schedule, excluding miner fees.
+		BlockReward(types.BlockHeight) types.Currency
+
 		// BlockAtHeight returns the block found at the input height, with a
 		// bool to indicate whether that block exists.
 		BlockAtHeight(types.BlockHeight) (types.Block, bool)
@@ -218,6 +406,11 @@ type (
 		// routines.
 		Flush() error
 
+		// Forks returns the tips of the forks that branch off from the
+		// current path. Only forks with a tip near the current height are
+		// reported.
+		Forks() ([]ForkInfo, error)
+
 		// Height returns the current height of consensus.
 		Height() types.BlockHeight
 
@@ -228,16 +421,194 @@ type (
 		// current path, false otherwise.
 		InCurrentPath(types.BlockID) bool
 
+		// LoadBlockchainFile adds the blocks in a file produced by
+		// StreamBlocks to the consensus set. Blocks up to the trusted tip are
+		// applied with reduced validation, later blocks are fully validated.
+		// No blocks are added if the trusted tip is not in the file.
+		LoadBlockchainFile(r io.Reader, trustedTip types.BlockID) error
+
 		// MinimumValidChildTimestamp returns the earliest timestamp that is
 		// valid on the current longest fork according to the consensus set. This is
 		// a required piece of information for the miner, who could otherwise be at
 		// risk of mining invalid blocks.
 		MinimumValidChildTimestamp(types.BlockID) (types.Timestamp, bool)
 
+		// OutputCreationBlock returns the id and height of the block that
+		// created the siacoin output with the provided id. Spent outputs are
+		// still reported if their creating block is in the current path.
+		OutputCreationBlock(types.SiacoinOutputID) (types.BlockID, types.BlockHeight, error)
+
+		// OutputSpentAtHeight reports whether the siacoin output with the
+		// provided id has been spent in the current path, along with the
+		// height and id of the spending transaction.
+		OutputSpentAtHeight(types.SiacoinOutputID) (spent bool, spendHeight types.BlockHeight, spendTxID types.TransactionID, err error)
+
+		// ProcessedBlock returns information about a block in the block map,
+		// which may be in the current path or on a fork, including the diffs
+		// that the block applied.
+		ProcessedBlock(types.BlockID) (ProcessedBlockInfo, error)
+
+		// BlockAtHeightOnFork returns the block at the given height on the
+		// chain that ends at the given tip, which may be a fork of the
+		// current path.
+		BlockAtHeightOnFork(tip types.BlockID, height types.BlockHeight) (types.Block, error)
+
+		// WalkBackward calls visit for the block with the given id and each
+		// of its ancestors, from newest to oldest, until visit returns true
+		// or an error, or the genesis block has been visited.
+		WalkBackward(from types.BlockID, visit func(pbi ProcessedBlockInfo) (stop bool, err error)) error
+
+		// ValidateBlockAgainst checks whether a block would be valid as the
+		// child of the given parent, which must be known. The block's own
+		// ParentID is ignored.
+		ValidateBlockAgainst(b types.Block, parentID types.BlockID) error
+
+		// RecentOrphans returns the ids of the most recently received blocks
+		// whose parents are unknown, and which are still waiting for them.
+		RecentOrphans() []types.BlockID
+
+		// OrphansSeen returns the number of orphan blocks received since the
+		// consensus set was started.
+		OrphansSeen() uint64
+
+		// RetargetWindow returns the heights of the first and last blocks of
+		// the window that determined the target of the next block, and the
+		// timespan measured over the window. After the oak hardfork, the
+		// timespan is the exponentially decayed total time used by oak.
+		RetargetWindow() (start, end types.BlockHeight, timespan time.Duration, err error)
+
+		// SetBlockNotify sets a callback that receives every consensus
+		// change caused by accepting blocks. The callback is called from a
+		// separate goroutine and never blocks block acceptance; changes are
+		// dropped if the callback falls too far behind.
+		SetBlockNotify(func(ConsensusChange))
+
+		// SetRejectedBlockLog sets the writer that receives the id, origin
+		// peer, and rejection reason of blocks rejected by the consensus
+		// set, at the given verbosity. A nil writer disables the log.
+		SetRejectedBlockLog(w io.Writer, level RejectedBlockLogLevel)
+
+		// SetValidationLevel sets how strictly blocks are validated relative
+		// to a trusted block at the provided height. Blocks above the trusted
+		// height, blocks off the trusted chain, and every block that is
+		// applied after the trusted block has been added to the consensus set
+		// are fully validated.
+		SetValidationLevel(level ValidationLevel, trustedBlock types.BlockID, trustedHeight types.BlockHeight)
+
+		// SiacoinOutputs returns the unspent siacoin outputs with the
+		// provided ids, looked up in a single read. Outputs that do not exist
+		// are absent from the returned map.
+		SiacoinOutputs([]types.SiacoinOutputID) (map[types.SiacoinOutputID]types.SiacoinOutput, error)
+
+		// SiafundFee returns the portion of a file contract payout that is
+		// paid to siafund holders when the contract is created at the given
+		// height.
+		SiafundFee(types.BlockHeight, types.Currency) types.Currency
+
+		// SiafundPool returns the current value of the siafund pool, which
+		// holds the fees that are payable to siafund holders.
+		SiafundPool() types.Currency
+
+		// SiafundPoolAtHeight returns the value of the siafund pool after the
+		// block at the given height in the current path was applied.
+		SiafundPoolAtHeight(types.BlockHeight) (types.Currency, error)
+
+		// SoftForkStatus returns the fraction of blocks in the most recent
+		// target window that signal for the given version bit, and whether
+		// the signaling has reached the activation threshold.
+		SoftForkStatus(bit uint) (signaling float64, activated bool, err error)
+
+		// SubscribeAtomic adds a subscriber to the list of subscribers after
+		// sending it every consensus change since the provided change id. The
+		// replay and the registration happen under the consensus lock, so
+		// each change is delivered exactly once.
+		SubscribeAtomic(ConsensusSetSubscriber, ConsensusChangeID) error
+
+		// SubscribeReorgWarning registers a channel that receives a warning
+		// whenever a reorg reverts more than 'depth' blocks.
+		SubscribeReorgWarning(depth types.BlockHeight, ch chan<- ReorgWarning)
+
+		// SubscribeFileContracts registers a channel that receives an event
+		// whenever an applied or reverted block creates, revises, or resolves
+		// a file contract. Events are delivered in order.
+		SubscribeFileContracts(ch chan<- FileContractEvent)
+
+		// SubscribeStaleTip registers a channel that receives the age of the
+		// current block whenever it goes longer than threshold without being
+		// replaced. Each stale block is reported once.
+		SubscribeStaleTip(threshold time.Duration, ch chan<- time.Duration)
+
+		// SubscriberStats returns the number of changes delivered to each
+		// subscriber, the number waiting to be delivered, and how long the
+		// subscriber takes to process them. It can be called while a block
+		// is being accepted, to find the subscriber that is holding it up.
+		SubscriberStats() []SubscriberStat
+
+		// SetMaxDoSBlocks sets the number of known invalid blocks that are
+		// remembered so that they can be rejected without being validated
+		// again. The least recently seen blocks are forgotten first.
+		SetMaxDoSBlocks(n int)
+
+		// TipAge returns how long ago the current block was timestamped.
+		TipAge() time.Duration
+
+		// ChainAge returns how much time has passed since the genesis block
+		// was timestamped.
+		ChainAge() time.Duration
+
+		// EstimatedBlockHeight returns the height that the blockchain should
+		// have reached at the provided time if blocks were found at the
+		// target block frequency.
+		EstimatedBlockHeight(at time.Time) types.BlockHeight
+
+		// ClockSkewWarning returns the median amount by which recently
+		// relayed blocks were timestamped ahead of the local clock, and true
+		// if the local clock appears to be behind the network.
+		ClockSkewWarning() (time.Duration, bool)
+
+		// StreamBlocks writes every block in the current path from the
+		// given height to the current block to the writer, using the Sia
+		// encoding.
+		StreamBlocks(start types.BlockHeight, w io.Writer) error
+
 		// StorageProofSegment returns the segment to be used in the storage proof for
 		// a given file contract.
 		StorageProofSegment(types.FileContractID) (uint64, error)
 
+		// SyncProgress estimates how far the consensus set has progressed in
+		// synchronizing with the network. The target is the highest block
+		// height reported by a connected peer; if no peer has reported a
+		// height, the target and fraction are 0.
+		SyncProgress() (current, target types.BlockHeight, fraction float64)
+
+		// SetChangeLogFile sets a file that every change to the consensus
+		// set is appended to, along with the blocks that the change applied.
+		// An empty path stops writing to the file.
+		SetChangeLogFile(path string) error
+
+		// ReplayChangeLog adds the blocks of a change log file written by
+		// SetChangeLogFile to the consensus set, rebuilding the state that
+		// the file was written from.
+		ReplayChangeLog(path string) error
+
+		// CurrentCoinSupply returns the number of siacoins that have been
+		// created up to the current height.
+		CurrentCoinSupply() types.Currency
+
+		// TotalTransactions returns the number of transactions in the blocks
+		// of the current path, including the genesis block.
+		TotalTransactions() (uint64, error)
+
+		// TransactionInBlock returns the transaction at the given index of
+		// a known block. Only the requested transaction is decoded.
+		TransactionInBlock(blockID types.BlockID, index int) (types.Transaction, error)
+
+		// TransactionExists reports whether the transaction with the provided
+		// id is in the current path, and the height of the block that
+		// contains it. A transaction that does not exist is not in any block
+		// up to the current height.
+		TransactionExists(txid types.TransactionID) (exists bool, height types.BlockHeight, err error)
+
 		// TryTransactionSet checks whether the transaction set would be valid if
 		// it were added in the next block. A consensus change is returned
 		// detailing the diffs that would result from the application of the
@@ -248,6 +619,17 @@ type (
 		// allowing for garbage collection and rescanning. If the subscriber is
 		// not found in the subscriber database, no action is taken.
 		Unsubscribe(ConsensusSetSubscriber)
+
+		// VerifyIntegrity checks that the height index of the consensus
+		// database agrees with the block map. While a discrepancy exists, the
+		// consensus set will not accept new blocks.
+		VerifyIntegrity() error
+
+		// WouldReorg reports whether a chain of headers extending a known
+		// block is heavy enough to become the current path, and the height
+		// that it ends at. Only the headers are validated, which allows
+		// deciding whether a fork is worth downloading.
+		WouldReorg(headers []types.BlockHeader) (wouldReorg bool, newHeight types.BlockHeight, err error)
 	}
 )
 
@@ -265,3 +647,12 @@ func (cc ConsensusChange) Append(cc2 ConsensusChange) ConsensusChange {
 		DelayedSiacoinOutputDiffs: append(cc.DelayedSiacoinOutputDiffs, cc2.DelayedSiacoinOutputDiffs...),
 	}
 }
+
+// SoftForkSignalTransaction returns a transaction that can be included in a
+// block to signal for the soft forks identified by the set bits of
+// 'versionBits'. The transaction has no inputs or outputs.
+func SoftForkSignalTransaction(versionBits uint64) types.Transaction {
+	return types.Transaction{
+		ArbitraryData: [][]byte{encoding.MarshalAll(PrefixSoftForkSignal, versionBits)},
+	}
+}
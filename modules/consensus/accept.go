@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"time"
 
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
@@ -16,15 +15,31 @@ import (
 var (
 	errDoSBlock        = errors.New("block is known to be invalid")
 	errInconsistentSet = errors.New("consensus set is not in a consistent state")
+	errMaxBlockSize    = errors.New("block exceeds the maximum size that the consensus set will validate")
 	errNoBlockMap      = errors.New("block map is not in database")
 	errNonLinearChain  = errors.New("block set is not a contiguous chain")
 	errOrphan          = errors.New("block has no known parent")
 )
 
-// managedBroadcastBlock will broadcast a block to the consensus set's peers.
-func (cs *ConsensusSet) managedBroadcastBlock(b types.Block) {
-	// broadcast the block header to all peers
-	go cs.gateway.Broadcast("RelayHeader", b.Header(), cs.gateway.Peers())
+// managedBroadcastBlock will announce a block to the consensus set's peers by
+// relaying its header, excluding the peer that the block was received from and
+// any peer that is already known to have the block. An empty origin means
+// that the block did not come from a peer (e.g. it was mined locally). Peers
+// that do not have the block will request it using the SendBlk RPC.
+func (cs *ConsensusSet) managedBroadcastBlock(b types.Block, origin modules.NetAddress) {
+	id := b.ID()
+	allPeers := cs.gateway.Peers()
+	cs.inventory.prune(allPeers)
+	var peers []modules.Peer
+	for _, p := range allPeers {
+		if p.NetAddress == origin || cs.inventory.hasSeen(p.NetAddress, id) {
+			continue
+		}
+		// The peer will have the block once it processes the announcement.
+		cs.inventory.markSeen(p.NetAddress, id)
+		peers = append(peers, p)
+	}
+	go cs.gateway.Broadcast("RelayHeader", b.Header(), peers)
 }
 
 // validateHeaderAndBlock does some early, low computation verification on the
@@ -33,11 +48,17 @@ func (cs *ConsensusSet) managedBroadcastBlock(b types.Block) {
 func (cs *ConsensusSet) validateHeaderAndBlock(tx dbTx, b types.Block, id types.BlockID) (parent *processedBlock, err error) {
 	// Check if the block is a DoS block - a known invalid block that is expensive
 	// to validate.
-	_, exists := cs.dosBlocks[id]
-	if exists {
+	if cs.dosBlocks.contains(id) {
 		return nil, errDoSBlock
 	}
 
+	// Check that the block is not larger than the consensus set is willing to
+	// validate. Unlike the block size limit, this limit is a local policy, so
+	// the block is not added to the dosBlocks.
+	if uint64(b.MarshalSiaSize()) > cs.staticMaxBlockSize {
+		return nil, errMaxBlockSize
+	}
+
 	// Check if the block is already known.
 	blockMap := tx.Bucket(BlockMap)
 	if blockMap == nil {
@@ -58,6 +79,11 @@ func (cs *ConsensusSet) validateHeaderAndBlock(tx dbTx, b types.Block, id types.
 	if err != nil {
 		return nil, err
 	}
+	// Check that the block does not fork the chain below a checkpoint.
+	err = cs.checkCheckpoint(tx, parent, id)
+	if err != nil {
+		return nil, err
+	}
 	// Check that the timestamp is not too far in the past to be acceptable.
 	minTimestamp := cs.blockRuleHelper.minimumValidChildTimestamp(blockMap, parent)
 
@@ -65,6 +91,12 @@ func (cs *ConsensusSet) validateHeaderAndBlock(tx dbTx, b types.Block, id types.
 	if err != nil {
 		return nil, err
 	}
+
+	// Check the block against the node-local block policy.
+	err = cs.checkBlockPolicy(b, id, parent.Height+1)
+	if err != nil {
+		return nil, err
+	}
 	return parent, nil
 }
 
@@ -81,8 +113,7 @@ func (cs *ConsensusSet) validateHeader(tx dbTx, h types.BlockHeader) error {
 	// Check if the block is a DoS block - a known invalid block that is expensive
 	// to validate.
 	id := h.ID()
-	_, exists := cs.dosBlocks[id]
-	if exists {
+	if cs.dosBlocks.contains(id) {
 		return errDoSBlock
 	}
 
@@ -107,6 +138,12 @@ func (cs *ConsensusSet) validateHeader(tx dbTx, h types.BlockHeader) error {
 		return err
 	}
 
+	return cs.validateHeaderParent(blockMap, h, &parent)
+}
+
+// validateHeaderParent performs the checks of validateHeader that depend on
+// the parent of the header. 'blockMap' must contain the ancestors of 'parent'.
+func (cs *ConsensusSet) validateHeaderParent(blockMap dbBucket, h types.BlockHeader, parent *processedBlock) error {
 	// Check that the target of the new block is sufficient.
 	if !checkHeaderTarget(h, parent.ChildTarget) {
 		return modules.ErrBlockUnsolved
@@ -116,7 +153,7 @@ func (cs *ConsensusSet) validateHeader(tx dbTx, h types.BlockHeader) error {
 	// downloads are implemented.
 
 	// Check that the timestamp is not too far in the past to be acceptable.
-	minTimestamp := cs.blockRuleHelper.minimumValidChildTimestamp(blockMap, &parent)
+	minTimestamp := cs.blockRuleHelper.minimumValidChildTimestamp(blockMap, parent)
 	if minTimestamp > h.Timestamp {
 		return errEarlyTimestamp
 	}
@@ -125,7 +162,7 @@ func (cs *ConsensusSet) validateHeader(tx dbTx, h types.BlockHeader) error {
 	// future and extreme future because there is an assumption that by the time
 	// the extreme future arrives, this block will no longer be a part of the
 	// longest fork because it will have been ignored by all of the miners.
-	if h.Timestamp > types.CurrentTimestamp()+types.ExtremeFutureThreshold {
+	if h.Timestamp > cs.staticClock.Now()+types.ExtremeFutureThreshold {
 		return errExtremeFutureTimestamp
 	}
 
@@ -171,7 +208,7 @@ func (cs *ConsensusSet) addBlockToTree(tx *bolt.Tx, b types.Block, parent *proce
 	for _, an := range appliedBlocks {
 		ce.AppliedBlocks = append(ce.AppliedBlocks, an.Block.ID())
 	}
-	err = appendChangeLog(tx, ce)
+	err = cs.logChange(tx, ce)
 	if err != nil {
 		return changeEntry{}, err
 	}
@@ -198,16 +235,22 @@ func (cs *ConsensusSet) threadedSleepOnFutureBlock(b types.Block) {
 	select {
 	case <-cs.tg.StopChan():
 		return
-	case <-time.After(time.Duration(b.Timestamp-(types.CurrentTimestamp()+types.FutureThreshold)) * time.Second):
+	case <-cs.staticClock.WaitUntil(b.Timestamp - types.FutureThreshold):
 		_, err := cs.managedAcceptBlocks([]types.Block{b})
 		if err != nil {
 			cs.log.Debugln("WARN: failed to accept a future block:", err)
 		}
-		cs.managedBroadcastBlock(b)
+		cs.managedBroadcastBlock(b, "")
 	}
 }
 
-// managedAcceptBlocks will try to add blocks to the consensus set. If the
+// managedAcceptBlocks will try to add blocks that did not come from a peer to
+// the consensus set. See managedAcceptBlocksFrom.
+func (cs *ConsensusSet) managedAcceptBlocks(blocks []types.Block) (blockchainExtended bool, err error) {
+	return cs.managedAcceptBlocksFrom(blocks, "")
+}
+
+// managedAcceptBlocksFrom will try to add blocks to the consensus set. If the
 // blocks do not extend the longest currently known chain, an error is
 // returned but the blocks are still kept in memory. If the blocks extend a fork
 // such that the fork becomes the longest currently known chain, the consensus
@@ -218,11 +261,20 @@ func (cs *ConsensusSet) threadedSleepOnFutureBlock(b types.Block) {
 // This method is typically only be used when there would otherwise be multiple
 // consecutive calls to AcceptBlock with each successive call accepting the
 // child block of the previous call.
-func (cs *ConsensusSet) managedAcceptBlocks(blocks []types.Block) (blockchainExtended bool, err error) {
+//
+// The origin is the peer that the blocks were received from, and is recorded
+// in the rejected block log. An empty origin means that the blocks did not
+// come from a peer.
+func (cs *ConsensusSet) managedAcceptBlocksFrom(blocks []types.Block, origin modules.NetAddress) (blockchainExtended bool, err error) {
 	// Grab a lock on the consensus set.
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
 
+	// Refuse to extend a database whose tip cannot be trusted.
+	if cs.integrityErr != nil {
+		return false, cs.integrityErr
+	}
+
 	// Make sure that blocks are consecutive. Though this isn't a strict
 	// requirement, if blocks are not consecutive then it becomes a lot harder
 	// to maintain correcetness when adding multiple blocks in a single tx.
@@ -232,6 +284,7 @@ func (cs *ConsensusSet) managedAcceptBlocks(blocks []types.Block) (blockchainExt
 	for i := 0; i < len(blocks); i++ {
 		blockIDs = append(blockIDs, blocks[i].ID())
 		if i > 0 && blocks[i].ParentID != blockIDs[i-1] {
+			cs.logRejectedBlock(blockIDs[i], origin, errNonLinearChain)
 			return false, errNonLinearChain
 		}
 	}
@@ -240,24 +293,33 @@ func (cs *ConsensusSet) managedAcceptBlocks(blocks []types.Block) (blockchainExt
 	// invalid blocks (which includes the children of invalid blocks).
 	chainExtended := false
 	changes := make([]changeEntry, 0, len(blocks))
-	setErr := cs.db.Update(func(tx *bolt.Tx) error {
+	var added []types.BlockID
+	setErr := cs.updateChangeLogged(func(tx *bolt.Tx) error {
 		for i := 0; i < len(blocks); i++ {
 			// Start by checking the header of the block.
 			parent, err := cs.validateHeaderAndBlock(boltTxWrapper{tx}, blocks[i], blockIDs[i])
 			if err == modules.ErrBlockKnown {
 				// Skip over known blocks.
+				cs.logRejectedBlock(blockIDs[i], origin, err)
 				continue
 			}
-			if err == errFutureTimestamp {
+			if err == ErrFutureTimestamp {
 				// Queue the block to be tried again if it is a future block.
 				go cs.threadedSleepOnFutureBlock(blocks[i])
 			}
+			if err == errOrphan {
+				cs.orphans.add(blockIDs[i])
+			}
 			if err != nil {
+				cs.logRejectedBlock(blockIDs[i], origin, err)
 				return err
 			}
 
 			// Try adding the block to consensus.
 			changeEntry, err := cs.addBlockToTree(tx, blocks[i], parent)
+			if err == nil || err == modules.ErrNonExtendingBlock {
+				added = append(added, blockIDs[i])
+			}
 			if err == nil {
 				changes = append(changes, changeEntry)
 				chainExtended = true
@@ -269,6 +331,9 @@ func (cs *ConsensusSet) managedAcceptBlocks(blocks []types.Block) (blockchainExt
 					reverted = append(reverted, b.String()[:6])
 				}
 			}
+			if err != nil {
+				cs.logRejectedBlock(blockIDs[i], origin, err)
+			}
 			if err == modules.ErrNonExtendingBlock {
 				err = nil
 			}
@@ -299,10 +364,13 @@ func (cs *ConsensusSet) managedAcceptBlocks(blocks []types.Block) (blockchainExt
 		}
 		return false, setErr
 	}
+	cs.orphans.remove(added)
 	// Stop here if the blocks did not extend the longest blockchain.
 	if !chainExtended {
 		return false, modules.ErrNonExtendingBlock
 	}
+	cs.refreshSiafundPool()
+	cs.notifyTipChanged()
 	// Send any changes to subscribers.
 	for i := 0; i < len(changes); i++ {
 		cs.updateSubscribers(changes[i])
@@ -329,7 +397,7 @@ func (cs *ConsensusSet) AcceptBlock(b types.Block) error {
 		return err
 	}
 	if chainExtended {
-		cs.managedBroadcastBlock(b)
+		cs.managedBroadcastBlock(b, "")
 	}
 	return nil
 }
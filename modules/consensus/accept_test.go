@@ -3,10 +3,13 @@ package consensus
 import (
 	"bytes"
 	"errors"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/gateway"
 	"github.com/NebulousLabs/Sia/persist"
 	"github.com/NebulousLabs/Sia/types"
 
@@ -208,6 +211,7 @@ func TestUnitValidateHeaderAndBlock(t *testing.T) {
 		earliestValidTimestamp types.Timestamp
 		marshaler              mockBlockMarshaler
 		useNilBlockMap         bool
+		maxBlockSize           uint64
 		validateBlockErr       error
 		errWant                error
 		msg                    string
@@ -232,6 +236,16 @@ func TestUnitValidateHeaderAndBlock(t *testing.T) {
 			errWant:                errDoSBlock,
 			msg:                    "validateHeaderAndBlock should reject known bad blocks",
 		},
+		{
+			block:                  mockValidBlock,
+			dosBlocks:              make(map[types.BlockID]struct{}),
+			blockMapPairs:          serializedParentBlockMap,
+			earliestValidTimestamp: mockValidBlock.Timestamp,
+			marshaler:              parentBlockUnmarshaler,
+			maxBlockSize:           1,
+			errWant:                errMaxBlockSize,
+			msg:                    "validateHeaderAndBlock should reject blocks larger than the maximum block size",
+		},
 		{
 			block:                  mockValidBlock,
 			dosBlocks:              make(map[types.BlockID]struct{}),
@@ -285,14 +299,19 @@ func TestUnitValidateHeaderAndBlock(t *testing.T) {
 		}
 		tx := mockDbTx{dbBucketMap}
 
+		if tt.maxBlockSize == 0 {
+			tt.maxBlockSize = types.BlockSizeLimit
+		}
+
 		mockParent := mockParent()
 		cs := ConsensusSet{
-			dosBlocks: tt.dosBlocks,
+			dosBlocks: newTestDoSBlockSet(tt.dosBlocks),
 			marshaler: tt.marshaler,
 			blockRuleHelper: mockBlockRuleHelper{
 				minTimestamp: tt.earliestValidTimestamp,
 			},
-			blockValidator: mockBlockValidator{tt.validateBlockErr},
+			blockValidator:     mockBlockValidator{tt.validateBlockErr},
+			staticMaxBlockSize: tt.maxBlockSize,
 		}
 		// Reset the stored parameters to ValidateBlock.
 		validateBlockParamsGot = validateBlockParams{}
@@ -476,11 +495,12 @@ func TestUnitValidateHeader(t *testing.T) {
 		tx := mockDbTx{dbBucketMap}
 
 		cs := ConsensusSet{
-			dosBlocks: tt.dosBlocks,
+			dosBlocks: newTestDoSBlockSet(tt.dosBlocks),
 			marshaler: tt.marshaler,
 			blockRuleHelper: mockBlockRuleHelper{
 				minTimestamp: tt.earliestValidTimestamp,
 			},
+			staticClock: stdClock{},
 		}
 		err := cs.validateHeader(tx, tt.header)
 		if err != tt.errWant {
@@ -737,8 +757,8 @@ func TestFutureTimestampHandling(t *testing.T) {
 	block.Timestamp = types.CurrentTimestamp() + 2 + types.FutureThreshold
 	solvedBlock, _ := cst.miner.SolveBlock(block, target)
 	err = cst.cs.AcceptBlock(solvedBlock)
-	if err != errFutureTimestamp {
-		t.Fatalf("expected %v, got %v", errFutureTimestamp, err)
+	if err != ErrFutureTimestamp {
+		t.Fatalf("expected %v, got %v", ErrFutureTimestamp, err)
 	}
 
 	// Poll the consensus set until the future block appears.
@@ -755,6 +775,77 @@ func TestFutureTimestampHandling(t *testing.T) {
 	}
 }
 
+// failOnceBlockValidator is a BlockValidator that returns an error the first
+// time it is called, and defers to the standard validator afterwards.
+type failOnceBlockValidator struct {
+	BlockValidator
+	err    error
+	failed *bool
+}
+
+// ValidateBlock returns the validator's error on the first call, and calls the
+// wrapped validator on subsequent calls.
+func (bv failOnceBlockValidator) ValidateBlock(b types.Block, id types.BlockID, minTimestamp types.Timestamp, target types.Target, height types.BlockHeight, log *persist.Logger) error {
+	if !*bv.failed {
+		*bv.failed = true
+		return bv.err
+	}
+	return bv.BlockValidator.ValidateBlock(b, id, minTimestamp, target, height, log)
+}
+
+// TestCustomBlockValidator checks that a custom BlockValidator passed to
+// NewConfiguredConsensusSet is used, and that a validator returning
+// ErrFutureTimestamp causes the block to be retried.
+func TestCustomBlockValidator(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := blankConsensusSetTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Create a second consensus set with a validator that fails the first
+	// block it sees.
+	testdir := build.TempDir(modules.ConsensusDir, t.Name()+"-cs2")
+	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	failed := false
+	cs, err := NewConfiguredConsensusSet(g, false, filepath.Join(testdir, modules.ConsensusDir), modules.ProdDependencies, Config{
+		BlockValidator: failOnceBlockValidator{
+			BlockValidator: NewBlockValidator(),
+			err:            ErrFutureTimestamp,
+			failed:         &failed,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	// The block should be treated as a future block and accepted when it is
+	// retried.
+	b, _ := cst.miner.FindBlock()
+	err = cs.AcceptBlock(b)
+	if err != ErrFutureTimestamp {
+		t.Fatalf("expected %v, got %v", ErrFutureTimestamp, err)
+	}
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		if cs.CurrentBlock().ID() != b.ID() {
+			return errors.New("future block was not accepted")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 // TestExtremeFutureTimestampHandling checks that blocks in the extreme future
 // are rejected.
 func TestExtremeFutureTimestampHandling(t *testing.T) {
@@ -777,7 +868,7 @@ func TestExtremeFutureTimestampHandling(t *testing.T) {
 	solvedBlock, _ := cst.miner.SolveBlock(block, target)
 	err = cst.cs.AcceptBlock(solvedBlock)
 	if err != errExtremeFutureTimestamp {
-		t.Fatalf("expected %v, got %v", errFutureTimestamp, err)
+		t.Fatalf("expected %v, got %v", ErrFutureTimestamp, err)
 	}
 }
 
@@ -1180,3 +1271,81 @@ func TestChainedAcceptBlock(t *testing.T) {
 		}
 	}
 }
+
+// mockGatewayRecordsBroadcast implements modules.Gateway to record the peers
+// that a broadcast is sent to.
+type mockGatewayRecordsBroadcast struct {
+	modules.Gateway
+	peers          []modules.Peer
+	broadcastPeers chan []modules.Peer
+}
+
+// Peers is a mock implementation of modules.Gateway.Peers that returns a
+// fixed set of peers.
+func (g *mockGatewayRecordsBroadcast) Peers() []modules.Peer {
+	return g.peers
+}
+
+// Broadcast is a mock implementation of modules.Gateway.Broadcast that sends
+// the peers of the broadcast down a channel.
+func (g *mockGatewayRecordsBroadcast) Broadcast(name string, obj interface{}, peers []modules.Peer) {
+	g.broadcastPeers <- peers
+}
+
+// TestBroadcastBlockExcludesOrigin checks that a block is not relayed back to
+// the peer that it was received from.
+func TestBroadcastBlockExcludesOrigin(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := blankConsensusSetTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+	mg := &mockGatewayRecordsBroadcast{
+		Gateway: cst.cs.gateway,
+		peers: []modules.Peer{
+			{NetAddress: "1.1.1.1:9981"},
+			{NetAddress: "2.2.2.2:9981"},
+		},
+		broadcastPeers: make(chan []modules.Peer),
+	}
+	cst.cs.gateway = mg
+
+	b, _ := cst.miner.FindBlock()
+	cst.cs.managedBroadcastBlock(b, "1.1.1.1:9981")
+	select {
+	case peers := <-mg.broadcastPeers:
+		if len(peers) != 1 || peers[0].NetAddress != "2.2.2.2:9981" {
+			t.Error("block was not broadcast to the expected peers:", peers)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("block was not broadcast")
+	}
+
+	// Blocks are not announced again to peers that already have them.
+	cst.cs.managedBroadcastBlock(b, "")
+	select {
+	case peers := <-mg.broadcastPeers:
+		if len(peers) != 1 || peers[0].NetAddress != "1.1.1.1:9981" {
+			t.Error("block was announced to a peer that already has it:", peers)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("block was not broadcast")
+	}
+
+	// Blocks without an origin are broadcast to all peers.
+	b2 := b
+	b2.Timestamp++
+	cst.cs.managedBroadcastBlock(b2, "")
+	select {
+	case peers := <-mg.broadcastPeers:
+		if len(peers) != 2 {
+			t.Error("block was not broadcast to all peers:", peers)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("block was not broadcast")
+	}
+}
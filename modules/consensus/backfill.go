@@ -0,0 +1,130 @@
+package consensus
+
+// backfill.go creates the indices that older consensus databases do not have.
+// Such databases will have completed the 'initDB' process before the indices
+// were added, so the indices are created and filled separately, by a single
+// scan of the database.
+
+import (
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+// pathIndex is an index of the blocks in the current path, which is updated
+// as blocks are applied and reverted.
+type pathIndex struct {
+	bucket []byte
+
+	// genesis indicates whether the genesis block is part of the index.
+	genesis bool
+
+	// init, if not nil, stores the initial value of the index in its newly
+	// created bucket.
+	init func(tx *bolt.Tx)
+
+	// commit applies or reverts a block.
+	commit func(tx *bolt.Tx, pb *processedBlock, dir modules.DiffDirection)
+}
+
+// pathIndices lists the indices of the current path that are filled by
+// backfillIndices.
+var pathIndices = []pathIndex{
+	{
+		bucket: OutputCreations,
+		commit: commitOutputCreations,
+	},
+	{
+		bucket: OutputSpends,
+		commit: commitOutputSpends,
+	},
+	{
+		bucket:  TransactionCount,
+		genesis: true,
+		init:    func(tx *bolt.Tx) { setTransactionCount(tx, 0) },
+		commit:  commitTransactionCount,
+	},
+	{
+		bucket:  TransactionHeights,
+		genesis: true,
+		commit:  commitTransactionHeights,
+	},
+	{
+		bucket:  CoinSupply,
+		genesis: true,
+		init:    func(tx *bolt.Tx) { setCoinSupply(tx, types.ZeroCurrency) },
+		commit:  commitCoinSupply,
+	},
+}
+
+// backfillIndices creates the indices that are missing from the database and
+// fills them. The indices of the current path are filled by one walk over the
+// current path, and the transaction offsets and the height index, which cover
+// every block, by one walk over the block map. Indices that already exist are
+// not touched.
+func backfillIndices(tx *bolt.Tx) error {
+	var missing []pathIndex
+	for _, idx := range pathIndices {
+		if tx.Bucket(idx.bucket) != nil {
+			continue
+		}
+		if _, err := tx.CreateBucket(idx.bucket); err != nil {
+			return err
+		}
+		if idx.init != nil {
+			idx.init(tx)
+		}
+		missing = append(missing, idx)
+	}
+	if len(missing) > 0 {
+		height := blockHeight(tx)
+		for i := types.BlockHeight(0); i <= height; i++ {
+			id, err := getPath(tx, i)
+			if err != nil {
+				return err
+			}
+			pb, err := getBlockMap(tx, id)
+			if err != nil {
+				return err
+			}
+			for _, idx := range missing {
+				if i > 0 || idx.genesis {
+					idx.commit(tx, pb, modules.DiffApply)
+				}
+			}
+		}
+	}
+
+	// The transaction offsets and the height index cover every block, and are
+	// filled by one walk over the block map.
+	fillOffsets := tx.Bucket(TransactionOffsets) == nil
+	fillHeights := tx.Bucket(BlocksByHeight) == nil
+	if !fillOffsets && !fillHeights {
+		return nil
+	}
+	if fillOffsets {
+		if _, err := tx.CreateBucket(TransactionOffsets); err != nil {
+			return err
+		}
+	}
+	if fillHeights {
+		if _, err := tx.CreateBucket(BlocksByHeight); err != nil {
+			return err
+		}
+	}
+	return tx.Bucket(BlockMap).ForEach(func(_, pbBytes []byte) error {
+		var pb processedBlock
+		if err := encoding.Unmarshal(pbBytes, &pb); err != nil {
+			return err
+		}
+		if fillOffsets {
+			addTransactionOffsets(tx, pb.Block)
+		}
+		if fillHeights {
+			addBlockHeight(tx, pb.Height, pb.Block.ID())
+		}
+		return nil
+	})
+}
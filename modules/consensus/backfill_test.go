@@ -0,0 +1,73 @@
+package consensus
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/coreos/bbolt"
+)
+
+// TestBackfillIndices checks that the indices rebuilt by backfillIndices match
+// the indices that were maintained as the blocks were added.
+func TestBackfillIndices(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	rs := createReorgSets(t.Name())
+	defer rs.Close()
+	cs := rs.cstMain.cs
+
+	// Include a reorg, so that the indices have seen reverted blocks. The
+	// main set is extended past the backup set so that it can be saved.
+	if _, err := rs.cstMain.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	rs.save()
+	rs.extend()
+
+	buckets := [][]byte{TransactionOffsets, BlocksByHeight}
+	for _, idx := range pathIndices {
+		buckets = append(buckets, idx.bucket)
+	}
+	// snapshot returns the contents of every index.
+	snapshot := func() map[string]map[string][]byte {
+		contents := make(map[string]map[string][]byte)
+		_ = cs.db.View(func(tx *bolt.Tx) error {
+			for _, bucket := range buckets {
+				m := make(map[string][]byte)
+				_ = tx.Bucket(bucket).ForEach(func(k, v []byte) error {
+					m[string(k)] = append([]byte(nil), v...)
+					return nil
+				})
+				contents[string(bucket)] = m
+			}
+			return nil
+		})
+		return contents
+	}
+	before := snapshot()
+
+	err := cs.db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range buckets {
+			if err := tx.DeleteBucket(bucket); err != nil {
+				return err
+			}
+		}
+		return backfillIndices(tx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := snapshot()
+	for bucket, m := range before {
+		if len(after[bucket]) != len(m) {
+			t.Fatalf("%s: expected %v entries, got %v", bucket, len(m), len(after[bucket]))
+		}
+		for k, v := range m {
+			if !bytes.Equal(after[bucket][k], v) {
+				t.Fatalf("%s: entry %x does not match after backfilling", bucket, k)
+			}
+		}
+	}
+}
@@ -13,18 +13,39 @@ var (
 	errBadMinerPayouts        = errors.New("miner payout sum does not equal block subsidy")
 	errEarlyTimestamp         = errors.New("block timestamp is too early")
 	errExtremeFutureTimestamp = errors.New("block timestamp too far in future, discarded")
-	errFutureTimestamp        = errors.New("block timestamp too far in future, but saved for later use")
 	errLargeBlock             = errors.New("block is too large to be accepted")
+
+	// ErrFutureTimestamp is returned by a BlockValidator when a block is too
+	// far in the future to be accepted, but may become valid later. Blocks
+	// rejected with this error are retried once their timestamp arrives.
+	ErrFutureTimestamp = errors.New("block timestamp too far in future, but saved for later use")
 )
 
-// blockValidator validates a Block against a set of block validity rules.
-type blockValidator interface {
+// BlockValidator validates a Block against a set of block validity rules. It
+// is called for every block that the consensus set receives, after the block
+// has been checked against the DoS blocks, the known blocks and the maximum
+// block size, and after the parent of the block has been found, but before
+// any transactions in the block are validated or applied.
+//
+// A custom BlockValidator can be provided to NewConfiguredConsensusSet, which
+// allows tests and fuzzers to inject failures into block validation. The
+// default implementation is returned by NewBlockValidator, and can be wrapped
+// to only alter the behavior for certain blocks.
+type BlockValidator interface {
 	// ValidateBlock validates a block against a minimum timestamp, a block
-	// target, and a block height.
+	// target, and a block height. The arguments are the block, the id of the
+	// block, the minimum valid timestamp of the block, the target that the
+	// block id must meet, the height that the block would have, and the
+	// logger of the consensus set.
+	//
+	// A nil error indicates that the block is valid and should be added to
+	// the block tree. ErrFutureTimestamp causes the block to be retried
+	// later. Any other error causes the block to be rejected, and the error
+	// is returned by AcceptBlock.
 	ValidateBlock(types.Block, types.BlockID, types.Timestamp, types.Target, types.BlockHeight, *persist.Logger) error
 }
 
-// stdBlockValidator is the standard implementation of blockValidator.
+// stdBlockValidator is the standard implementation of BlockValidator.
 type stdBlockValidator struct {
 	// clock is a Clock interface that indicates the current system time.
 	clock types.Clock
@@ -97,7 +118,7 @@ func (bv stdBlockValidator) ValidateBlock(b types.Block, id types.BlockID, minTi
 	// This is the last check because it's an expensive check, and not worth
 	// performing if the payouts are incorrect.
 	if b.Timestamp > bv.clock.Now()+types.FutureThreshold {
-		return errFutureTimestamp
+		return ErrFutureTimestamp
 	}
 
 	if log != nil {
@@ -0,0 +1,143 @@
+package consensus
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+var (
+	// defaultBlockCacheSize is the number of processed blocks that are kept
+	// in memory if Config.BlockCacheSize is not set.
+	defaultBlockCacheSize = build.Select(build.Var{
+		Standard: 100,
+		Dev:      50,
+		Testing:  20,
+	}).(int)
+)
+
+// blockCache is an LRU cache of the encoded processed blocks of the block
+// map. The tip and its recent ancestors are read repeatedly by the miner,
+// the subscribers, and the API, and the cache saves reading them from the
+// database each time. The cache holds encoded blocks rather than decoded ones
+// so that every caller decodes its own copy, and no caller can modify the
+// slices of a block held by the cache or by another caller.
+//
+// Blocks are only added to the cache by read-only transactions, so that the
+// cache never holds data that could be rolled back. Writing a block to the
+// block map evicts it. minTxID is the id of the last transaction that evicted
+// a block; read-only transactions that started before it may have seen stale
+// data and do not add blocks to the cache.
+type blockCache struct {
+	capacity int
+	elems    map[types.BlockID]*list.Element
+	order    *list.List // most recently used first
+	minTxID  int
+	mu       sync.Mutex
+}
+
+// blockCacheEntry is an element of the blockCache's list.
+type blockCacheEntry struct {
+	id      types.BlockID
+	pbBytes []byte
+}
+
+// newBlockCache returns an empty block cache that holds up to capacity
+// blocks. A capacity of zero or less disables the cache.
+func newBlockCache(capacity int) *blockCache {
+	return &blockCache{
+		capacity: capacity,
+		elems:    make(map[types.BlockID]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// get returns the cached encoding of the block with the provided id. The
+// returned slice must not be modified.
+func (bc *blockCache) get(id types.BlockID) ([]byte, bool) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	e, exists := bc.elems[id]
+	if !exists {
+		return nil, false
+	}
+	bc.order.MoveToFront(e)
+	return e.Value.(*blockCacheEntry).pbBytes, true
+}
+
+// add adds a copy of the encoding of a block that was read by tx to the
+// cache. pbBytes may belong to the database and is not retained.
+func (bc *blockCache) add(tx *bolt.Tx, id types.BlockID, pbBytes []byte) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if tx.ID() < bc.minTxID || bc.capacity <= 0 {
+		return
+	}
+	if _, exists := bc.elems[id]; exists {
+		return
+	}
+	entry := &blockCacheEntry{
+		id:      id,
+		pbBytes: append([]byte(nil), pbBytes...),
+	}
+	bc.elems[id] = bc.order.PushFront(entry)
+	for bc.order.Len() > bc.capacity {
+		delete(bc.elems, bc.order.Remove(bc.order.Back()).(*blockCacheEntry).id)
+	}
+}
+
+// evict removes a block from the cache because tx is changing it. It must be
+// called whenever a block in the block map is written or reverted.
+func (bc *blockCache) evict(tx *bolt.Tx, id types.BlockID) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if e, exists := bc.elems[id]; exists {
+		bc.order.Remove(e)
+		delete(bc.elems, id)
+	}
+	if tx.ID() > bc.minTxID {
+		bc.minTxID = tx.ID()
+	}
+}
+
+// getBlockMap returns a processed block with the input id, reading it through
+// the cache. Writable transactions bypass the cache, as they may see blocks
+// that are not committed.
+func (bc *blockCache) getBlockMap(tx *bolt.Tx, id types.BlockID) (*processedBlock, error) {
+	if tx.Writable() {
+		return getBlockMap(tx, id)
+	}
+	pbBytes, cached := bc.get(id)
+	if !cached {
+		pbBytes = tx.Bucket(BlockMap).Get(id[:])
+		if pbBytes == nil {
+			return nil, errNilItem
+		}
+	}
+
+	// Decode the block - should never fail.
+	var pb processedBlock
+	err := encoding.Unmarshal(pbBytes, &pb)
+	if build.DEBUG && err != nil {
+		panic(err)
+	}
+	if !cached && err == nil {
+		bc.add(tx, id, pbBytes)
+	}
+	return &pb, nil
+}
+
+// currentProcessedBlock returns the most recent block in the consensus set,
+// reading it through the cache.
+func (bc *blockCache) currentProcessedBlock(tx *bolt.Tx) *processedBlock {
+	pb, err := bc.getBlockMap(tx, currentBlockID(tx))
+	if build.DEBUG && err != nil {
+		panic(err)
+	}
+	return pb
+}
@@ -0,0 +1,171 @@
+package consensus
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+// cachedBlock returns true if the block is in the block cache of cs.
+func cachedBlock(cs *ConsensusSet, id types.BlockID) bool {
+	cs.blockCache.mu.Lock()
+	defer cs.blockCache.mu.Unlock()
+	_, cached := cs.blockCache.elems[id]
+	return cached
+}
+
+// TestBlockCacheReorg checks that the block cache agrees with the database
+// after blocks are reverted and reapplied.
+func TestBlockCacheReorg(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	rs := createReorgSets(t.Name())
+	defer rs.Close()
+	cs := rs.cstMain.cs
+
+	// checkCache reads the current path through the cache, and compares every
+	// cached block to the block stored in the database.
+	checkCache := func() {
+		err := cs.db.View(func(tx *bolt.Tx) error {
+			for h := types.BlockHeight(0); h <= blockHeight(tx); h++ {
+				id, err := getPath(tx, h)
+				if err != nil {
+					return err
+				}
+				if _, err := cs.blockCache.getBlockMap(tx, id); err != nil {
+					return err
+				}
+			}
+			bc := cs.blockCache
+			bc.mu.Lock()
+			defer bc.mu.Unlock()
+			for id, e := range bc.elems {
+				if !bytes.Equal(e.Value.(*blockCacheEntry).pbBytes, tx.Bucket(BlockMap).Get(id[:])) {
+					t.Fatal("cached block does not match the database:", id)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Extend the main set past the backup set so that it can be saved.
+	if _, err := rs.cstMain.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	checkCache()
+	if !cachedBlock(cs, cs.CurrentBlock().ID()) {
+		t.Fatal("current block was not cached")
+	}
+
+	// Reorg to a longer fork and back.
+	rs.save()
+	rs.extend()
+	checkCache()
+	rs.restore()
+	checkCache()
+}
+
+// TestBlockCacheSize checks that the block cache is bounded and that writes
+// evict the written blocks.
+func TestBlockCacheSize(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+	bc := cst.cs.blockCache
+
+	for i := 0; i < defaultBlockCacheSize; i++ {
+		if _, err := cst.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := types.BlockHeight(0); i <= cst.cs.Height(); i++ {
+		_ = cst.cs.db.View(func(tx *bolt.Tx) error {
+			id, _ := getPath(tx, i)
+			bc.getBlockMap(tx, id)
+			return nil
+		})
+	}
+	bc.mu.Lock()
+	n := bc.order.Len()
+	bc.mu.Unlock()
+	if n != defaultBlockCacheSize {
+		t.Fatalf("expected %v cached blocks, got %v", defaultBlockCacheSize, n)
+	}
+
+	// Reverting a block evicts it.
+	tip := cst.cs.CurrentBlock().ID()
+	if !cachedBlock(cst.cs, tip) {
+		t.Fatal("current block was not cached")
+	}
+	err = cst.cs.db.Update(func(tx *bolt.Tx) error {
+		pb, err := getBlockMap(tx, tip)
+		if err != nil {
+			return err
+		}
+		parent, err := getBlockMap(tx, pb.Block.ParentID)
+		if err != nil {
+			return err
+		}
+		cst.cs.revertToBlock(tx, parent)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cachedBlock(cst.cs, tip) {
+		t.Fatal("reverted block is still cached")
+	}
+}
+
+// TestBlockCacheCopies checks that the blocks returned through the block cache
+// do not share memory with the cache or with each other.
+func TestBlockCacheCopies(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// currentBlock reads the current block through the cache.
+	currentBlock := func() (pb *processedBlock) {
+		_ = cst.cs.db.View(func(tx *bolt.Tx) error {
+			pb = cst.cs.blockCache.currentProcessedBlock(tx)
+			return nil
+		})
+		return pb
+	}
+
+	// The current block has a miner payout and a diff set.
+	pb := currentBlock()
+	if len(pb.Block.MinerPayouts) == 0 || len(pb.DelayedSiacoinOutputDiffs) == 0 {
+		t.Fatal("current block has no payouts or diffs")
+	}
+	pb.Block.MinerPayouts[0].UnlockHash = types.UnlockHash{1}
+	pb.DelayedSiacoinOutputDiffs[0].ID = types.SiacoinOutputID{1}
+
+	pb2 := currentBlock()
+	if pb2.Block.MinerPayouts[0].UnlockHash == (types.UnlockHash{1}) || pb2.DelayedSiacoinOutputDiffs[0].ID == (types.SiacoinOutputID{1}) {
+		t.Fatal("modifying a block returned by the cache changed the cached block")
+	}
+	if cst.cs.CurrentBlock().MinerPayouts[0].UnlockHash == (types.UnlockHash{1}) {
+		t.Fatal("modifying a block returned by the cache changed the current block")
+	}
+}
@@ -0,0 +1,93 @@
+package consensus
+
+import (
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+var (
+	// blockNotifyBufferSize is the number of consensus changes that are
+	// queued for the block notify callback. Changes that arrive while the
+	// queue is full are dropped.
+	blockNotifyBufferSize = build.Select(build.Var{
+		Standard: 100,
+		Dev:      50,
+		Testing:  5,
+	}).(int)
+)
+
+// A blockNotifier delivers consensus changes to a callback from its own
+// goroutine, so that a slow callback cannot block the consensus set.
+type blockNotifier struct {
+	fn    func(modules.ConsensusChange)
+	queue chan modules.ConsensusChange
+}
+
+// threadedNotifyBlocks delivers the queued changes of a notifier until the
+// notifier is replaced or the consensus set shuts down. The callback is never
+// called after the consensus set has been closed.
+func (cs *ConsensusSet) threadedNotifyBlocks(bn *blockNotifier) {
+	if err := cs.tg.Add(); err != nil {
+		return
+	}
+	defer cs.tg.Done()
+	for {
+		// Check for shutdown first, so that a change that is already queued
+		// is not delivered once the consensus set is stopping.
+		select {
+		case <-cs.tg.StopChan():
+			return
+		default:
+		}
+		select {
+		case cc, ok := <-bn.queue:
+			if !ok {
+				return
+			}
+			bn.fn(cc)
+		case <-cs.tg.StopChan():
+			return
+		}
+	}
+}
+
+// notifyBlock queues a consensus change for the block notify callback without
+// blocking, dropping the change if the callback has fallen too far behind.
+func (cs *ConsensusSet) notifyBlock(cc modules.ConsensusChange) {
+	if cs.blockNotifier == nil {
+		return
+	}
+	select {
+	case cs.blockNotifier.queue <- cc:
+	default:
+		cs.log.Println("WARN: block notify callback is falling behind, dropping consensus change", cc.ID)
+	}
+}
+
+// SetBlockNotify sets a callback that is called with every consensus change
+// that is caused by accepting blocks. The callback is called from a separate
+// goroutine, one change at a time. If the callback is slow, up to
+// blockNotifyBufferSize changes are queued and later changes are dropped. A
+// nil callback removes the current callback.
+func (cs *ConsensusSet) SetBlockNotify(fn func(modules.ConsensusChange)) {
+	if err := cs.tg.Add(); err != nil {
+		return
+	}
+	defer cs.tg.Done()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	// Stop the current notifier once it has delivered its queued changes.
+	if cs.blockNotifier != nil {
+		close(cs.blockNotifier.queue)
+		cs.blockNotifier = nil
+	}
+	if fn == nil {
+		return
+	}
+	cs.blockNotifier = &blockNotifier{
+		fn:    fn,
+		queue: make(chan modules.ConsensusChange, blockNotifyBufferSize),
+	}
+	go cs.threadedNotifyBlocks(cs.blockNotifier)
+}
@@ -0,0 +1,116 @@
+package consensus
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// TestBlockNotify checks that the block notify callback receives the changes
+// caused by accepted blocks, and that a slow callback does not block the
+// consensus set.
+func TestBlockNotify(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	changes := make(chan modules.ConsensusChange, 1)
+	cst.cs.SetBlockNotify(func(cc modules.ConsensusChange) {
+		changes <- cc
+	})
+	b, err := cst.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case cc := <-changes:
+		if len(cc.AppliedBlocks) != 1 || cc.AppliedBlocks[0].ID() != b.ID() {
+			t.Fatal("wrong consensus change delivered to the callback")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("callback was not called")
+	}
+
+	// Block the callback. Accepting blocks should not be blocked, and the
+	// changes beyond the buffer should be dropped.
+	unblock := make(chan struct{})
+	var delivered uint64
+	cst.cs.SetBlockNotify(func(cc modules.ConsensusChange) {
+		<-unblock
+		atomic.AddUint64(&delivered, 1)
+	})
+	for i := 0; i < blockNotifyBufferSize+5; i++ {
+		if _, err := cst.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Removing the callback lets the notifier drain its queue.
+	cst.cs.SetBlockNotify(nil)
+	close(unblock)
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	cst.cs.mu.RLock()
+	removed := cst.cs.blockNotifier == nil
+	cst.cs.mu.RUnlock()
+	if !removed {
+		t.Fatal("callback was not removed")
+	}
+
+	// The queued changes, plus the one that may have been in delivery when
+	// the queue filled up, should reach the callback. The rest were dropped.
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		if n := atomic.LoadUint64(&delivered); n != uint64(blockNotifyBufferSize) && n != uint64(blockNotifyBufferSize+1) {
+			return fmt.Errorf("%v changes were delivered", n)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestBlockNotifyClose checks that the block notify callback is not called
+// after the consensus set has been closed.
+func TestBlockNotifyClose(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Use a slow callback, so that changes are still queued when the
+	// consensus set is closed.
+	var delivered uint64
+	cst.cs.SetBlockNotify(func(cc modules.ConsensusChange) {
+		time.Sleep(50 * time.Millisecond)
+		atomic.AddUint64(&delivered, 1)
+	})
+	for i := 0; i < blockNotifyBufferSize; i++ {
+		if _, err := cst.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cst.Close(); err != nil {
+		t.Fatal(err)
+	}
+	n := atomic.LoadUint64(&delivered)
+	time.Sleep(200 * time.Millisecond)
+	if atomic.LoadUint64(&delivered) != n {
+		t.Fatal("callback was called after the consensus set was closed")
+	}
+}
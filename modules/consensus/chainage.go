@@ -0,0 +1,26 @@
+package consensus
+
+import (
+	"time"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// ChainAge returns how much time has passed since the genesis block was
+// timestamped, according to the consensus set's clock.
+func (cs *ConsensusSet) ChainAge() time.Duration {
+	return cs.tipAge(cs.blockRoot.Block)
+}
+
+// EstimatedBlockHeight returns the height that the blockchain is expected to
+// have reached at the provided time, assuming that blocks were found at the
+// target block frequency since the genesis block. Comparing the estimate to
+// the current height shows whether the node is still catching up or whether
+// the network has stalled.
+func (cs *ConsensusSet) EstimatedBlockHeight(at time.Time) types.BlockHeight {
+	genesis := cs.blockRoot.Block.Timestamp
+	if at.Unix() <= int64(genesis) {
+		return 0
+	}
+	return types.BlockHeight(types.Timestamp(at.Unix())-genesis) / types.BlockFrequency
+}
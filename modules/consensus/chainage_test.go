@@ -0,0 +1,60 @@
+package consensus
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/gateway"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestChainAge checks that ChainAge follows the consensus set's clock, and
+// that EstimatedBlockHeight counts the block intervals since genesis.
+func TestChainAge(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	testdir := build.TempDir(modules.ConsensusDir, t.Name())
+	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	clock := newManualClock(types.GenesisTimestamp)
+	cs, err := NewConfiguredConsensusSet(g, false, filepath.Join(testdir, modules.ConsensusDir), modules.ProdDependencies, Config{
+		Clock: clock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	if age := cs.ChainAge(); age != 0 {
+		t.Fatal("expected a chain age of 0, got", age)
+	}
+	clock.advance(types.Timestamp(10*types.BlockFrequency) + 1)
+	if age, exp := cs.ChainAge(), time.Duration(10*types.BlockFrequency+1)*time.Second; age != exp {
+		t.Fatalf("expected a chain age of %v, got %v", exp, age)
+	}
+
+	genesis := time.Unix(int64(types.GenesisTimestamp), 0)
+	tests := []struct {
+		at     time.Time
+		height types.BlockHeight
+	}{
+		{genesis.Add(-time.Hour), 0},
+		{genesis, 0},
+		{genesis.Add(time.Duration(types.BlockFrequency)*time.Second - time.Second), 0},
+		{genesis.Add(time.Duration(types.BlockFrequency) * time.Second), 1},
+		{genesis.Add(time.Duration(100*types.BlockFrequency) * time.Second), 100},
+	}
+	for _, test := range tests {
+		if h := cs.EstimatedBlockHeight(test.at); h != test.height {
+			t.Errorf("expected height %v at %v, got %v", test.height, test.at, h)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package consensus
+
+// changelogfile.go mirrors the changelog to an append-only file outside of the
+// consensus database. Each change is written as the change entry followed by
+// the blocks that it applied, so the file holds everything needed to rebuild
+// the consensus set if the database is lost or corrupted. The file is written
+// and synced inside the database transaction that records the change, before
+// the transaction commits, so the file is never behind the database. If the
+// transaction does not commit, the file is truncated back to its size from
+// before the transaction, so the file never holds changes that the database
+// does not.
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+const (
+	// maxChangeLogEntrySize is the largest encoded change entry that is read
+	// from a change log file.
+	maxChangeLogEntrySize = 1 << 22
+)
+
+var (
+	// changeLogReplayBatchSize is the number of change entries that are
+	// replayed in a single database transaction by ReplayChangeLog.
+	changeLogReplayBatchSize = build.Select(build.Var{
+		Standard: 1000,
+		Dev:      100,
+		Testing:  2,
+	}).(int)
+
+	errChangeLogFileInUse = errors.New("cannot replay the change log file that the consensus set is writing to")
+)
+
+// writeChangeLogEntry writes a change entry and the blocks that it applied to
+// w.
+func writeChangeLogEntry(tx *bolt.Tx, w io.Writer, ce changeEntry) error {
+	if err := encoding.WriteObject(w, ce); err != nil {
+		return err
+	}
+	for _, id := range ce.AppliedBlocks {
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := encoding.WriteObject(w, pb.Block); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendChangeLogFile appends a change entry to the change log file, if one
+// is set, and syncs the file. A partially written entry is truncated away, so
+// that a failed write leaves the file as it was.
+func (cs *ConsensusSet) appendChangeLogFile(tx *bolt.Tx, ce changeEntry) error {
+	if cs.changeLogFile == nil {
+		return nil
+	}
+	stat, err := cs.changeLogFile.Stat()
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(cs.changeLogFile)
+	err = writeChangeLogEntry(tx, bw, ce)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil {
+		err = cs.changeLogFile.Sync()
+	}
+	if err != nil {
+		cs.changeLogFile.Truncate(stat.Size())
+		return err
+	}
+	return nil
+}
+
+// logChange adds a change entry to the changelog of the database and to the
+// change log file.
+func (cs *ConsensusSet) logChange(tx *bolt.Tx, ce changeEntry) error {
+	if err := appendChangeLog(tx, ce); err != nil {
+		return err
+	}
+	return cs.appendChangeLogFile(tx, ce)
+}
+
+// updateChangeLogged runs fn in a writable database transaction, like
+// cs.db.Update. If the transaction is not committed, the entries that fn
+// appended to the change log file are removed by truncating the file back to
+// its size from before the transaction. The caller must hold cs.mu.
+func (cs *ConsensusSet) updateChangeLogged(fn func(*bolt.Tx) error) error {
+	if cs.changeLogFile == nil {
+		return cs.db.Update(fn)
+	}
+	stat, err := cs.changeLogFile.Stat()
+	if err != nil {
+		return err
+	}
+	err = cs.db.Update(fn)
+	if err != nil {
+		truncErr := cs.changeLogFile.Truncate(stat.Size())
+		if truncErr == nil {
+			truncErr = cs.changeLogFile.Sync()
+		}
+		if truncErr != nil {
+			cs.log.Severe("ERROR: unable to remove uncommitted changes from the change log file:", truncErr)
+		}
+	}
+	return err
+}
+
+// SetChangeLogFile sets the file that every change to the consensus set is
+// appended to, along with the blocks that the change applied. If the file is
+// empty, it is first filled with the blocks of the current path, so that it
+// can be replayed by ReplayChangeLog to rebuild the consensus set from
+// genesis. An empty path stops writing to the file.
+func (cs *ConsensusSet) SetChangeLogFile(path string) error {
+	err := cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if cs.changeLogFile != nil {
+		if err := cs.changeLogFile.Close(); err != nil {
+			cs.log.Println("WARN: unable to close the change log file:", err)
+		}
+		cs.changeLogFile = nil
+	}
+	if path == "" {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	// Seed an empty file with the current path, one block per entry.
+	if stat.Size() == 0 {
+		bw := bufio.NewWriter(f)
+		err = cs.db.View(func(tx *bolt.Tx) error {
+			for h := types.BlockHeight(0); h <= blockHeight(tx); h++ {
+				id, err := getPath(tx, h)
+				if err != nil {
+					return err
+				}
+				ce := changeEntry{AppliedBlocks: []types.BlockID{id}}
+				if err := writeChangeLogEntry(tx, bw, ce); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err == nil {
+			err = bw.Flush()
+		}
+		if err == nil {
+			err = f.Sync()
+		}
+		if err != nil {
+			f.Truncate(0)
+			f.Close()
+			return err
+		}
+	}
+	cs.changeLogFile = f
+	return nil
+}
+
+// ReplayChangeLog adds the blocks of a change log file written by
+// SetChangeLogFile to the consensus set. Replaying the file into an empty
+// consensus set rebuilds the state that the file was written from. Every block
+// is fully validated, and blocks that are already known are skipped. A
+// partially written entry at the end of the file is ignored. The entries are
+// added in batches of changeLogReplayBatchSize, each in its own database
+// transaction. If a block is rejected, the batches before it remain in the
+// consensus set and the rest of the file is not replayed.
+func (cs *ConsensusSet) ReplayChangeLog(path string) error {
+	err := cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if cs.integrityErr != nil {
+		return cs.integrityErr
+	}
+	if cs.changeLogFile != nil {
+		if abs, err := filepath.Abs(path); err == nil {
+			if current, err := filepath.Abs(cs.changeLogFile.Name()); err == nil && abs == current {
+				return errChangeLogFileInUse
+			}
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	for done := false; !done; {
+		var changes []changeEntry
+		err = cs.updateChangeLogged(func(tx *bolt.Tx) error {
+			for i := 0; i < changeLogReplayBatchSize; i++ {
+				var ce changeEntry
+				err := encoding.ReadObject(br, &ce, maxChangeLogEntrySize)
+				if err == io.EOF {
+					done = true
+					return nil
+				} else if err == io.ErrUnexpectedEOF {
+					cs.log.Println("WARN: ignoring a partially written entry at the end of the change log file")
+					done = true
+					return nil
+				} else if err != nil {
+					return err
+				}
+				for range ce.AppliedBlocks {
+					var b types.Block
+					err := encoding.ReadObject(br, &b, cs.staticMaxBlockSize)
+					if err == io.EOF || err == io.ErrUnexpectedEOF {
+						cs.log.Println("WARN: ignoring a partially written entry at the end of the change log file")
+						done = true
+						return nil
+					} else if err != nil {
+						return err
+					}
+
+					parent, err := cs.validateHeaderAndBlock(boltTxWrapper{tx}, b, b.ID())
+					if err == modules.ErrBlockKnown {
+						continue
+					} else if err != nil {
+						return err
+					}
+					ce, err := cs.addBlockToTree(tx, b, parent)
+					if err == modules.ErrNonExtendingBlock {
+						continue
+					} else if err != nil {
+						return err
+					}
+					changes = append(changes, ce)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			cs.log.Println("WARN: failed to replay change log file:", err)
+			return err
+		}
+		cs.refreshSiafundPool()
+		cs.notifyTipChanged()
+		for _, ce := range changes {
+			cs.updateSubscribers(ce)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package consensus
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestChangeLogFile checks that a consensus set can be rebuilt by replaying
+// its change log file, including changes made by reorgs.
+func TestChangeLogFile(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+	cstAlt, err := createConsensusSetTester(t.Name() + "-alt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cstAlt.Close()
+
+	dir := build.TempDir(modules.ConsensusDir, t.Name()+"-changelog")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "changes.log")
+	if err := cst.cs.SetChangeLogFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// Add a block, then reorg onto the alternate chain and add another block.
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	for cstAlt.cs.Height() <= cst.cs.Height() {
+		if _, err := cstAlt.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := types.BlockHeight(1); i <= cstAlt.cs.Height(); i++ {
+		b, _ := cstAlt.cs.BlockAtHeight(i)
+		_ = cst.cs.AcceptBlock(b)
+	}
+	if cst.cs.CurrentBlock().ID() != cstAlt.cs.CurrentBlock().ID() {
+		t.Fatal("consensus set did not reorg")
+	}
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The file being written cannot be replayed into the same consensus set.
+	if err := cst.cs.ReplayChangeLog(path); err != errChangeLogFileInUse {
+		t.Fatal("expected errChangeLogFileInUse, got", err)
+	}
+	if err := cst.cs.SetChangeLogFile(""); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replay the file into a blank consensus set.
+	blank, err := blankConsensusSetTester(t.Name()+"-blank", modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer blank.Close()
+	if err := blank.cs.ReplayChangeLog(path); err != nil {
+		t.Fatal(err)
+	}
+	if blank.cs.CurrentBlock().ID() != cst.cs.CurrentBlock().ID() {
+		t.Fatal("replay did not rebuild the consensus set")
+	}
+
+	// A partially written entry at the end of the file is ignored.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if err := blank.cs.ReplayChangeLog(path); err != nil {
+		t.Fatal(err)
+	}
+	// A rejected block leaves the batches that were replayed before it in
+	// place.
+	var buf bytes.Buffer
+	orphan := types.Block{ParentID: types.BlockID{1}}
+	if err := encoding.WriteObject(&buf, changeEntry{AppliedBlocks: []types.BlockID{orphan.ID()}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := encoding.WriteObject(&buf, orphan); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rejectPath := filepath.Join(dir, "rejected.log")
+	if err := ioutil.WriteFile(rejectPath, append(data[:len(data)-3], buf.Bytes()...), 0600); err != nil {
+		t.Fatal(err)
+	}
+	partial, err := blankConsensusSetTester(t.Name()+"-partial", modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer partial.Close()
+	if err := partial.cs.ReplayChangeLog(rejectPath); err != errOrphan {
+		t.Fatal("expected errOrphan, got", err)
+	}
+	if partial.cs.Height() == 0 {
+		t.Fatal("replayed batches were rolled back after a rejected block")
+	}
+}
+
+// TestChangeLogFileFailedBatch checks that the change log file does not keep
+// the changes of a batch of blocks that was rejected.
+func TestChangeLogFileFailedBatch(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	dir := build.TempDir(modules.ConsensusDir, t.Name()+"-changelog")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "changes.log")
+	if err := cst.cs.SetChangeLogFile(path); err != nil {
+		t.Fatal(err)
+	}
+	before, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first block of the batch is valid and is written to the file
+	// before the second block is rejected.
+	valid, _ := cst.miner.FindBlock()
+	invalid := types.Block{
+		ParentID:  valid.ID(),
+		Timestamp: valid.Timestamp,
+	}
+	if _, err := cst.cs.managedAcceptBlocks([]types.Block{valid, invalid}); err == nil {
+		t.Fatal("invalid batch was accepted")
+	}
+	if cst.cs.CurrentBlock().ID() == valid.ID() {
+		t.Fatal("rejected batch was applied")
+	}
+	after, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Fatal("change log file kept the changes of a rejected batch")
+	}
+
+	// Later changes are still appended to the file.
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	after, err = ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) <= len(before) || !bytes.Equal(after[:len(before)], before) {
+		t.Fatal("change was not appended to the change log file")
+	}
+}
@@ -0,0 +1,36 @@
+package consensus
+
+import (
+	"time"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// Clock is the source of time used by the consensus set when checking block
+// timestamps against the current time and when waiting for future blocks to
+// become valid. A custom Clock can be provided in the Config, which allows
+// tests to control the passage of time. The default clock uses the system
+// time.
+type Clock interface {
+	types.Clock
+
+	// WaitUntil returns a channel that is closed once the current time is at
+	// least t.
+	WaitUntil(t types.Timestamp) <-chan struct{}
+}
+
+// stdClock is the standard implementation of Clock.
+type stdClock struct {
+	types.StdClock
+}
+
+// WaitUntil returns a channel that is closed once the system time reaches t.
+func (c stdClock) WaitUntil(t types.Timestamp) <-chan struct{} {
+	done := make(chan struct{})
+	if now := c.Now(); t <= now {
+		close(done)
+	} else {
+		time.AfterFunc(time.Duration(t-now)*time.Second, func() { close(done) })
+	}
+	return done
+}
@@ -0,0 +1,136 @@
+package consensus
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/gateway"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// manualClock is a Clock that only moves forward when advance is called.
+type manualClock struct {
+	now     types.Timestamp
+	waiters map[chan struct{}]types.Timestamp
+	mu      sync.Mutex
+}
+
+// newManualClock returns a manualClock set to the provided time.
+func newManualClock(now types.Timestamp) *manualClock {
+	return &manualClock{
+		now:     now,
+		waiters: make(map[chan struct{}]types.Timestamp),
+	}
+}
+
+// Now returns the current time of the clock.
+func (c *manualClock) Now() types.Timestamp {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// WaitUntil returns a channel that is closed once the clock has been advanced
+// to t.
+func (c *manualClock) WaitUntil(t types.Timestamp) <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	done := make(chan struct{})
+	if t <= c.now {
+		close(done)
+	} else {
+		c.waiters[done] = t
+	}
+	return done
+}
+
+// advance moves the clock forward by d seconds, waking any waiters whose time
+// has arrived.
+func (c *manualClock) advance(d types.Timestamp) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now += d
+	for done, t := range c.waiters {
+		if t <= c.now {
+			close(done)
+			delete(c.waiters, done)
+		}
+	}
+}
+
+// TestClockFutureBlocks checks that the consensus set uses the clock from its
+// config to reject and schedule blocks with future timestamps.
+func TestClockFutureBlocks(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Create a consensus set whose clock is far ahead of the system clock, so
+	// that the system clock would reject the blocks used below as being in
+	// the extreme future.
+	clock := newManualClock(types.CurrentTimestamp() + 1000)
+	testdir := build.TempDir(modules.ConsensusDir, t.Name()+"-cs2")
+	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	cs, err := NewConfiguredConsensusSet(g, false, filepath.Join(testdir, modules.ConsensusDir), modules.ProdDependencies, Config{
+		Clock: clock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+	for i := types.BlockHeight(1); i <= cst.cs.Height(); i++ {
+		b, _ := cst.cs.BlockAtHeight(i)
+		if err := cs.AcceptBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Blocks in the extreme future of the clock are rejected outright.
+	bfw, target, err := cst.miner.BlockForWork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bfw.Timestamp = clock.Now() + types.ExtremeFutureThreshold + 10
+	extreme, _ := cst.miner.SolveBlock(bfw, target)
+	if err := cs.AcceptBlock(extreme); err != errExtremeFutureTimestamp {
+		t.Fatal("expected errExtremeFutureTimestamp, got", err)
+	}
+
+	// Blocks in the near future of the clock are queued, and are accepted
+	// once the clock advances to their timestamp.
+	bfw.Timestamp = clock.Now() + types.FutureThreshold + 2
+	future, _ := cst.miner.SolveBlock(bfw, target)
+	if err := cs.AcceptBlock(future); err != ErrFutureTimestamp {
+		t.Fatal("expected ErrFutureTimestamp, got", err)
+	}
+	clock.advance(1)
+	time.Sleep(100 * time.Millisecond)
+	if cs.CurrentBlock().ID() == future.ID() {
+		t.Fatal("future block was accepted before its time")
+	}
+	clock.advance(1)
+	err = build.Retry(50, 20*time.Millisecond, func() error {
+		if cs.CurrentBlock().ID() != future.ID() {
+			return errors.New("future block was not accepted")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
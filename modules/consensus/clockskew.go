@@ -0,0 +1,85 @@
+package consensus
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// clockSkewSamples is the number of recently relayed blocks whose
+	// timestamps are compared against the local clock.
+	clockSkewSamples = build.Select(build.Var{
+		Standard: 20,
+		Dev:      10,
+		Testing:  5,
+	}).(int)
+
+	// clockSkewMinSamples is the number of samples required before a clock
+	// skew warning can be raised.
+	clockSkewMinSamples = build.Select(build.Var{
+		Standard: 6,
+		Dev:      4,
+		Testing:  3,
+	}).(int)
+
+	// clockSkewThreshold is the amount by which the median relayed block must
+	// be timestamped ahead of the local clock for the local clock to be
+	// considered behind.
+	clockSkewThreshold = build.Select(build.Var{
+		Standard: 5 * time.Minute,
+		Dev:      30 * time.Second,
+		Testing:  2 * time.Second,
+	}).(time.Duration)
+)
+
+// clockSkewMonitor compares the timestamps of blocks relayed by peers against
+// the local clock. Blocks are timestamped when they are mined, so they usually
+// arrive slightly in the past. If most recent blocks instead arrive from the
+// future, the local clock is most likely behind.
+type clockSkewMonitor struct {
+	samples []time.Duration
+	mu      sync.Mutex
+}
+
+// record adds the skew between a relayed block's timestamp and the local time
+// to the monitor, evicting the oldest sample if the monitor is full.
+func (m *clockSkewMonitor) record(timestamp, now types.Timestamp) {
+	skew := time.Duration(int64(timestamp)-int64(now)) * time.Second
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.samples = append(m.samples, skew)
+	if len(m.samples) > clockSkewSamples {
+		m.samples = m.samples[1:]
+	}
+}
+
+// warning returns the median skew of the recorded samples, and whether it
+// indicates that the local clock is behind the network.
+func (m *clockSkewMonitor) warning() (time.Duration, bool) {
+	m.mu.Lock()
+	sorted := append([]time.Duration(nil), m.samples...)
+	m.mu.Unlock()
+	if len(sorted) < clockSkewMinSamples {
+		return 0, false
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	median := sorted[len(sorted)/2]
+	return median, median > clockSkewThreshold
+}
+
+// ClockSkewWarning compares the timestamps of recently relayed blocks against
+// the local clock. It returns the median amount by which the blocks were
+// timestamped ahead of the local clock, and true if that amount is large
+// enough to suggest that the local clock is behind. A clock that is behind
+// causes valid blocks to be rejected as being in the future.
+func (cs *ConsensusSet) ClockSkewWarning() (time.Duration, bool) {
+	if err := cs.tg.Add(); err != nil {
+		return 0, false
+	}
+	defer cs.tg.Done()
+	return cs.clockSkew.warning()
+}
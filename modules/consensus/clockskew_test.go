@@ -0,0 +1,53 @@
+package consensus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestClockSkewMonitor probes the skew warnings of the clockSkewMonitor.
+func TestClockSkewMonitor(t *testing.T) {
+	const now = types.Timestamp(1e9)
+	skewSecs := types.Timestamp(clockSkewThreshold/time.Second) + 1
+	m := new(clockSkewMonitor)
+
+	// No warning is raised until there are enough samples.
+	for i := 0; i < clockSkewMinSamples-1; i++ {
+		m.record(now+skewSecs, now)
+	}
+	if _, warn := m.warning(); warn {
+		t.Fatal("warning raised with too few samples")
+	}
+	m.record(now+skewSecs, now)
+	skew, warn := m.warning()
+	if !warn || skew != time.Duration(skewSecs)*time.Second {
+		t.Fatalf("expected a warning with skew %vs, got %v %v", skewSecs, skew, warn)
+	}
+
+	// Blocks that arrive from the past replace the future samples, and the
+	// warning clears once they are the majority.
+	for i := 0; i < clockSkewSamples/2+1; i++ {
+		m.record(now-30, now)
+	}
+	if skew, warn := m.warning(); warn {
+		t.Fatal("warning raised for blocks from the past, skew", skew)
+	}
+	if len(m.samples) > clockSkewSamples {
+		t.Fatal("monitor holds too many samples:", len(m.samples))
+	}
+
+	// A few outliers do not cause a warning.
+	m = new(clockSkewMonitor)
+	for i := 0; i < clockSkewSamples; i++ {
+		if i%3 == 0 {
+			m.record(now+10*skewSecs, now)
+		} else {
+			m.record(now-5, now)
+		}
+	}
+	if skew, warn := m.warning(); warn {
+		t.Fatal("warning raised for outliers, skew", skew)
+	}
+}
@@ -0,0 +1,92 @@
+package consensus
+
+// coinsupply.go maintains a running total of the siacoins that have been
+// created by the blocks in the current path. The total is updated in the same
+// database transaction that applies or reverts a block, so it stays consistent
+// across reorgs and restarts.
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+var (
+	// CoinSupply is a database bucket that holds the number of siacoins
+	// created by the blocks in the current path, stored under a key of the
+	// same name.
+	CoinSupply = []byte("CoinSupply")
+
+	errCoinSupplyMismatch = errors.New("coin supply does not match the siacoins in the consensus set")
+)
+
+// getCoinSupply returns the number of siacoins created by the blocks in the
+// current path.
+func getCoinSupply(tx *bolt.Tx) (supply types.Currency) {
+	err := encoding.Unmarshal(tx.Bucket(CoinSupply).Get(CoinSupply), &supply)
+	if build.DEBUG && err != nil {
+		panic(err)
+	}
+	return supply
+}
+
+// setCoinSupply stores the number of siacoins created by the blocks in the
+// current path.
+func setCoinSupply(tx *bolt.Tx, supply types.Currency) {
+	err := tx.Bucket(CoinSupply).Put(CoinSupply, encoding.Marshal(supply))
+	if build.DEBUG && err != nil {
+		panic(err)
+	}
+}
+
+// commitCoinSupply adds the coinbase of a block to the supply when the block
+// is applied, and subtracts it when the block is reverted. Miner fees are not
+// counted, as they move existing siacoins to the miner.
+func commitCoinSupply(tx *bolt.Tx, pb *processedBlock, dir modules.DiffDirection) {
+	supply := getCoinSupply(tx)
+	coinbase := types.CalculateCoinbase(pb.Height)
+	if dir == modules.DiffApply {
+		supply = supply.Add(coinbase)
+	} else {
+		if build.DEBUG && supply.Cmp(coinbase) < 0 {
+			panic("coin supply underflow")
+		}
+		supply = supply.Sub(coinbase)
+	}
+	setCoinSupply(tx, supply)
+}
+
+// checkCoinSupply checks that the coin supply equals the siacoins that can be
+// counted within the consensus set. The check reads every output and file
+// contract, and is therefore only run on request.
+func checkCoinSupply(tx *bolt.Tx) error {
+	dsco, sco, fc, claim := countSiacoins(tx)
+	if !getCoinSupply(tx).Equals(dsco.Add(sco).Add(fc).Add(claim)) {
+		return errCoinSupplyMismatch
+	}
+	return nil
+}
+
+// CurrentCoinSupply returns the number of siacoins that have been created up
+// to the current height, including the coinbase of the genesis block. Siacoins
+// sent to unspendable addresses are still counted, as the consensus set cannot
+// tell them apart from spendable outputs.
+func (cs *ConsensusSet) CurrentCoinSupply() (supply types.Currency) {
+	// A call to a closed database can cause undefined behavior.
+	err := cs.tg.Add()
+	if err != nil {
+		return types.Currency{}
+	}
+	defer cs.tg.Done()
+
+	_ = cs.db.View(func(tx *bolt.Tx) error {
+		supply = getCoinSupply(tx)
+		return nil
+	})
+	return supply
+}
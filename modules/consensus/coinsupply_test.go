@@ -0,0 +1,80 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+// TestCurrentCoinSupply checks that the coin supply follows the current path
+// through block application, reorgs and a rebuild of the supply, and that
+// VerifyIntegrity detects a supply that does not match the consensus set.
+func TestCurrentCoinSupply(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	rs := createReorgSets(t.Name())
+	defer rs.Close()
+	cs := rs.cstMain.cs
+
+	checkSupply := func() {
+		t.Helper()
+		if supply, exp := cs.CurrentCoinSupply(), types.CalculateNumSiacoins(cs.Height()); !supply.Equals(exp) {
+			t.Fatalf("expected a supply of %v, got %v", exp, supply)
+		}
+	}
+	checkSupply()
+
+	// Apply a block, then reorg onto the backup set and back.
+	if _, err := rs.cstMain.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	checkSupply()
+	rs.save()
+	rs.extend()
+	checkSupply()
+	rs.restore()
+	checkSupply()
+	if err := cs.VerifyIntegrity(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Databases without the supply rebuild it from the current path.
+	err := cs.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(CoinSupply); err != nil {
+			return err
+		}
+		return backfillIndices(tx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSupply()
+
+	// A corrupted supply is detected.
+	var supply types.Currency
+	err = cs.db.Update(func(tx *bolt.Tx) error {
+		supply = getCoinSupply(tx)
+		setCoinSupply(tx, supply.Add(types.SiacoinPrecision))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.VerifyIntegrity(); err != errCoinSupplyMismatch {
+		t.Fatal("expected errCoinSupplyMismatch, got", err)
+	}
+	err = cs.db.Update(func(tx *bolt.Tx) error {
+		setCoinSupply(tx, supply)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.VerifyIntegrity(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -91,6 +91,8 @@ func (cs *ConsensusSet) createConsensusDB(tx *bolt.Tx) error {
 		BlockMap,
 		BlockPath,
 		Consistency,
+		OutputCreations,
+		OutputSpends,
 		SiacoinOutputs,
 		FileContracts,
 		SiafundOutputs,
@@ -7,8 +7,12 @@ package consensus
 // commitDiff functions will be sufficient.
 
 import (
+	"bufio"
 	"errors"
+	"io"
+	"os"
 
+	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/persist"
@@ -19,8 +23,27 @@ import (
 	"github.com/coreos/bbolt"
 )
 
+const (
+	// streamBlocksFlushInterval is the number of blocks that StreamBlocks
+	// writes between flushes of its buffered writer.
+	streamBlocksFlushInterval = 100
+)
+
+var (
+	// maxBlockHeaders is the largest number of headers returned by a single
+	// call to BlockHeaders.
+	maxBlockHeaders = build.Select(build.Var{
+		Standard: 2000,
+		Dev:      500,
+		Testing:  20,
+	}).(int)
+)
+
 var (
-	errNilGateway = errors.New("cannot have a nil gateway as input")
+	errHeightAboveTip    = errors.New("height is above the height of the fork tip")
+	errNilGateway        = errors.New("cannot have a nil gateway as input")
+	errStreamStartHeight = errors.New("cannot stream blocks starting above the current block height")
+	errUnknownBlock      = errors.New("block is not in the block map")
 )
 
 // marshaler marshals objects into byte slices and unmarshals byte
@@ -34,6 +57,50 @@ type stdMarshaler struct{}
 func (stdMarshaler) Marshal(v interface{}) []byte            { return encoding.Marshal(v) }
 func (stdMarshaler) Unmarshal(b []byte, v interface{}) error { return encoding.Unmarshal(b, v) }
 
+// Config contains optional settings for a ConsensusSet. The zero value of each
+// field selects the default behavior.
+type Config struct {
+	// MaxBlockSize is the size in bytes of the largest block that the
+	// consensus set will read from a peer or attempt to validate. Blocks that
+	// are larger are rejected before any expensive validation is performed.
+	// Setting MaxBlockSize below types.BlockSizeLimit will cause the consensus
+	// set to reject some valid blocks. Defaults to types.BlockSizeLimit.
+	MaxBlockSize uint64
+
+	// BlockValidator replaces the validator that checks each block before
+	// it is added to the block tree. Defaults to NewBlockValidator().
+	BlockValidator BlockValidator
+
+	// Clock is the source of the current time used to check block
+	// timestamps and to schedule future blocks. It is also used by the
+	// default BlockValidator. Defaults to the system clock.
+	Clock Clock
+
+	// BlockPolicy is a node-local filter that can veto blocks which are
+	// otherwise valid. See the BlockPolicy documentation for the risks of
+	// using a policy. Defaults to no policy.
+	BlockPolicy BlockPolicy
+
+	// ParallelSubscribers causes each subscriber to be sent consensus changes
+	// through its own queue, which a separate goroutine per subscriber
+	// processes in order. Subscribers progress independently of each other
+	// and of the consensus set, so a subscriber may still be processing an
+	// older change while newer blocks are accepted. The consensus set only
+	// waits for a subscriber when its queue is full. Defaults to sequential
+	// delivery.
+	ParallelSubscribers bool
+
+	// BlockCacheSize is the number of recently read processed blocks that
+	// are kept in memory, saving repeated database reads of the current
+	// block and its recent ancestors. A negative size disables the cache.
+	// Defaults to defaultBlockCacheSize.
+	BlockCacheSize int
+
+	// Database tunes how the consensus database is opened. See
+	// DatabaseOptions for the effect of each option on durability.
+	Database DatabaseOptions
+}
+
 // The ConsensusSet is the object responsible for tracking the current status
 // of the blockchain. Broadly speaking, it is responsible for maintaining
 // consensus.  It accepts blocks and constructs a blockchain, forking when
@@ -56,18 +123,46 @@ type ConsensusSet struct {
 	// the function of adding a subscriber should not be exposed.
 	subscribers []modules.ConsensusSetSubscriber
 
+	// subscriberStats measures how quickly each subscriber processes the
+	// changes that it is sent.
+	subscriberStats subscriberStats
+
+	// subscriberQueues holds the queue of each subscriber when subscribers
+	// are notified in parallel.
+	subscriberQueues map[modules.ConsensusSetSubscriber]*subscriberQueue
+
+	// blockNotifier delivers consensus changes to the callback set by
+	// SetBlockNotify.
+	blockNotifier *blockNotifier
+
+	// reorgWarnings are notified whenever a reorg reverts more blocks than
+	// their threshold.
+	reorgWarnings []reorgWarningSubscription
+
+	// fileContractSubs receive the file contract events of every change.
+	fileContractSubs []*fileContractSubscription
+
+	// clockSkew compares the timestamps of relayed blocks against the local
+	// clock.
+	clockSkew *clockSkewMonitor
+
+	// tipChanged is closed and replaced whenever the current block changes,
+	// waking the goroutines that watch for stale tips.
+	tipChanged chan struct{}
+
 	// dosBlocks are blocks that are invalid, but the invalidity is only
 	// discoverable during an expensive step of validation. These blocks are
 	// recorded to eliminate a DoS vector where an expensive-to-validate block
-	// is submitted to the consensus set repeatedly.
-	//
-	// TODO: dosBlocks needs to be moved into the database, and if there's some
-	// reason it can't be in THE database, it should be in a separate database.
-	// dosBlocks is an unbounded map that an attacker can manipulate, though
-	// iirc manipulations are expensive, to the tune of creating a blockchain
-	// PoW per DoS block (though the attacker could conceivably build off of
-	// the genesis block, meaning the PoW is not very expensive.
-	dosBlocks map[types.BlockID]struct{}
+	// is submitted to the consensus set repeatedly. The set is bounded so that
+	// a peer cannot exhaust memory by relaying many unique invalid blocks.
+	dosBlocks *dosBlockSet
+
+	// inventory tracks the blocks that each peer is known to have, so that
+	// blocks are not announced to or requested from peers redundantly.
+	inventory *blockInventory
+
+	// orphans tracks the blocks that were received before their parents.
+	orphans *orphanPool
 
 	// checkingConsistency is a bool indicating whether or not a consistency
 	// check is in progress. The consistency check logic call itself, resulting
@@ -81,10 +176,54 @@ type ConsensusSet struct {
 	// whether the consensus set is synced with the network.
 	synced bool
 
+	// siafundPool caches the current value of the siafund pool. It is
+	// refreshed whenever blocks are added to the consensus set.
+	siafundPool types.Currency
+
+	// integrityErr is set when the height index of the database is found to
+	// disagree with the block map. No blocks are accepted while it is set.
+	integrityErr error
+
 	// Interfaces to abstract the dependencies of the ConsensusSet.
 	marshaler       marshaler
 	blockRuleHelper blockRuleHelper
-	blockValidator  blockValidator
+	blockValidator  BlockValidator
+
+	// blockPolicy is an optional node-local filter on blocks.
+	blockPolicy BlockPolicy
+
+	// rejectLog receives the blocks rejected by the consensus set at the
+	// verbosity of rejectLogLevel. It is nil when the log is disabled.
+	rejectLog      *persist.Logger
+	rejectLogLevel modules.RejectedBlockLogLevel
+
+	// validationLevel determines whether signatures are verified for blocks
+	// on the trusted chain up to trustedHeight that are applied before
+	// trustedBlock is known.
+	validationLevel modules.ValidationLevel
+	trustedBlock    types.BlockID
+	trustedHeight   types.BlockHeight
+
+	// staticMaxBlockSize is the size of the largest block that the consensus
+	// set will read from a peer or attempt to validate.
+	staticMaxBlockSize uint64
+
+	// staticClock is the source of the current time.
+	staticClock Clock
+
+	// staticParallelSubscribers indicates that subscribers are notified
+	// concurrently.
+	staticParallelSubscribers bool
+
+	// blockCache holds recently read processed blocks of the block map.
+	blockCache *blockCache
+
+	// staticDatabaseOptions are the options that the database is opened with.
+	staticDatabaseOptions DatabaseOptions
+
+	// changeLogFile is the file that changes are appended to, or nil if
+	// changes are only recorded in the database.
+	changeLogFile *os.File
 
 	// Utilities
 	db         *persist.BoltDatabase
@@ -106,11 +245,37 @@ func New(gateway modules.Gateway, bootstrap bool, persistDir string) (*Consensus
 // there is an existing block database present in the persist directory, it
 // will be loaded.
 func NewCustomConsensusSet(gateway modules.Gateway, bootstrap bool, persistDir string, deps modules.Dependencies) (*ConsensusSet, error) {
+	return NewConfiguredConsensusSet(gateway, bootstrap, persistDir, deps, Config{})
+}
+
+// NewConfiguredConsensusSet returns a new ConsensusSet that uses the provided
+// config. Fields of the config that are left at their zero value are replaced
+// by their defaults.
+func NewConfiguredConsensusSet(gateway modules.Gateway, bootstrap bool, persistDir string, deps modules.Dependencies, config Config) (*ConsensusSet, error) {
 	// Check for nil dependencies.
 	if gateway == nil {
 		return nil, errNilGateway
 	}
 
+	// Fill out the defaults of the config.
+	if config.MaxBlockSize == 0 {
+		config.MaxBlockSize = types.BlockSizeLimit
+	}
+	if config.Clock == nil {
+		config.Clock = stdClock{}
+	}
+	if config.BlockCacheSize == 0 {
+		config.BlockCacheSize = defaultBlockCacheSize
+	}
+	if config.Database.Timeout == 0 {
+		config.Database.Timeout = defaultDatabaseTimeout
+	}
+	if config.BlockValidator == nil {
+		bv := NewBlockValidator()
+		bv.clock = config.Clock
+		config.BlockValidator = bv
+	}
+
 	// Create the ConsensusSet object.
 	cs := &ConsensusSet{
 		gateway: gateway,
@@ -123,14 +288,26 @@ func NewCustomConsensusSet(gateway modules.Gateway, bootstrap bool, persistDir s
 			DiffsGenerated: true,
 		},
 
-		dosBlocks: make(map[types.BlockID]struct{}),
+		clockSkew:  new(clockSkewMonitor),
+		dosBlocks:  newDoSBlockSet(defaultMaxDoSBlocks),
+		inventory:  newBlockInventory(),
+		orphans:    newOrphanPool(maxOrphans),
+		tipChanged: make(chan struct{}),
+
+		subscriberQueues: make(map[modules.ConsensusSetSubscriber]*subscriberQueue),
 
 		marshaler:       stdMarshaler{},
 		blockRuleHelper: stdBlockRuleHelper{},
-		blockValidator:  NewBlockValidator(),
-
-		staticDeps: deps,
-		persistDir: persistDir,
+		blockValidator:  config.BlockValidator,
+		blockPolicy:     config.BlockPolicy,
+
+		staticClock:               config.Clock,
+		staticDeps:                deps,
+		staticMaxBlockSize:        config.MaxBlockSize,
+		staticParallelSubscribers: config.ParallelSubscribers,
+		blockCache:                newBlockCache(config.BlockCacheSize),
+		staticDatabaseOptions:     config.Database,
+		persistDir:                persistDir,
 	}
 
 	// Create the diffs for the genesis siafund outputs.
@@ -193,6 +370,12 @@ func NewCustomConsensusSet(gateway modules.Gateway, bootstrap bool, persistDir s
 	return cs, nil
 }
 
+// BlockReward returns the coinbase subsidy of a block at the given height,
+// excluding miner fees.
+func (cs *ConsensusSet) BlockReward(height types.BlockHeight) types.Currency {
+	return types.CalculateCoinbase(height)
+}
+
 // BlockAtHeight returns the block at a given height.
 func (cs *ConsensusSet) BlockAtHeight(height types.BlockHeight) (block types.Block, exists bool) {
 	_ = cs.db.View(func(tx *bolt.Tx) error {
@@ -200,7 +383,7 @@ func (cs *ConsensusSet) BlockAtHeight(height types.BlockHeight) (block types.Blo
 		if err != nil {
 			return err
 		}
-		pb, err := getBlockMap(tx, id)
+		pb, err := cs.blockCache.getBlockMap(tx, id)
 		if err != nil {
 			return err
 		}
@@ -214,7 +397,7 @@ func (cs *ConsensusSet) BlockAtHeight(height types.BlockHeight) (block types.Blo
 // BlockByID returns the block for a given BlockID.
 func (cs *ConsensusSet) BlockByID(id types.BlockID) (block types.Block, height types.BlockHeight, exists bool) {
 	_ = cs.db.View(func(tx *bolt.Tx) error {
-		pb, err := getBlockMap(tx, id)
+		pb, err := cs.blockCache.getBlockMap(tx, id)
 		if err != nil {
 			return err
 		}
@@ -226,6 +409,239 @@ func (cs *ConsensusSet) BlockByID(id types.BlockID) (block types.Block, height t
 	return block, height, exists
 }
 
+// ProcessedBlock returns information about a block in the block map, which
+// may be in the current path or on a fork. The diffs of a block are only
+// populated if the block has been part of the current path at some point.
+func (cs *ConsensusSet) ProcessedBlock(id types.BlockID) (pbi modules.ProcessedBlockInfo, err error) {
+	err = cs.tg.Add()
+	if err != nil {
+		return modules.ProcessedBlockInfo{}, err
+	}
+	defer cs.tg.Done()
+
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		pb, err := cs.blockCache.getBlockMap(tx, id)
+		if err == errNilItem {
+			return errUnknownBlock
+		} else if err != nil {
+			return err
+		}
+		pbi = processedBlockInfo(tx, id, pb)
+		return nil
+	})
+	return pbi, err
+}
+
+// processedBlockInfo returns the information about a processed block with the
+// provided id. The processed block is expected to be freshly decoded from the
+// database, so that its slices are not shared with the consensus set.
+func processedBlockInfo(tx *bolt.Tx, id types.BlockID, pb *processedBlock) modules.ProcessedBlockInfo {
+	pbi := modules.ProcessedBlockInfo{
+		ID:          id,
+		ParentID:    pb.Block.ParentID,
+		Height:      pb.Height,
+		Timestamp:   pb.Block.Timestamp,
+		ChildTarget: pb.ChildTarget,
+
+		DiffsGenerated:            pb.DiffsGenerated,
+		SiacoinOutputDiffs:        pb.SiacoinOutputDiffs,
+		FileContractDiffs:         pb.FileContractDiffs,
+		SiafundOutputDiffs:        pb.SiafundOutputDiffs,
+		DelayedSiacoinOutputDiffs: pb.DelayedSiacoinOutputDiffs,
+		SiafundPoolDiffs:          pb.SiafundPoolDiffs,
+	}
+	pathID, err := getPath(tx, pb.Height)
+	pbi.InCurrentPath = err == nil && pathID == id
+	return pbi
+}
+
+// BlockAtHeightOnFork returns the block at the given height on the chain that
+// ends at the given tip, which may be in the current path or on a fork. The
+// chain is walked back through the parent of each block until it joins the
+// current path, after which the block is read from the current path.
+func (cs *ConsensusSet) BlockAtHeightOnFork(tip types.BlockID, height types.BlockHeight) (block types.Block, err error) {
+	err = cs.tg.Add()
+	if err != nil {
+		return types.Block{}, err
+	}
+	defer cs.tg.Done()
+
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		pb, err := cs.blockCache.getBlockMap(tx, tip)
+		if err == errNilItem {
+			return errUnknownBlock
+		} else if err != nil {
+			return err
+		}
+		if height > pb.Height {
+			return errHeightAboveTip
+		}
+		for pb.Height > height {
+			pathID, err := getPath(tx, pb.Height)
+			if err == nil && pathID == pb.Block.ID() {
+				// The rest of the chain is the current path.
+				id, err := getPath(tx, height)
+				if err != nil {
+					return err
+				}
+				pb, err = cs.blockCache.getBlockMap(tx, id)
+				if err != nil {
+					return err
+				}
+				break
+			}
+			pb, err = cs.blockCache.getBlockMap(tx, pb.Block.ParentID)
+			if err != nil {
+				return err
+			}
+		}
+		block = pb.Block
+		return nil
+	})
+	return block, err
+}
+
+// WalkBackward calls visit for the block with the provided id and then for each
+// of its ancestors in turn, ending with the genesis block. The walk stops early
+// if visit returns true or an error, and the error is returned. The blocks are
+// read one at a time, and visit is called without holding a database
+// transaction, so visit may call other methods of the consensus set. If the
+// consensus set reorgs during the walk, InCurrentPath reflects the path at the
+// time that each block was read.
+func (cs *ConsensusSet) WalkBackward(from types.BlockID, visit func(pbi modules.ProcessedBlockInfo) (stop bool, err error)) error {
+	err := cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+
+	id := from
+	for {
+		var pbi modules.ProcessedBlockInfo
+		err = cs.db.View(func(tx *bolt.Tx) error {
+			pb, err := cs.blockCache.getBlockMap(tx, id)
+			if err == errNilItem {
+				return errUnknownBlock
+			} else if err != nil {
+				return err
+			}
+			pbi = processedBlockInfo(tx, id, pb)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		stop, err := visit(pbi)
+		if err != nil || stop || pbi.ParentID == (types.BlockID{}) {
+			return err
+		}
+		id = pbi.ParentID
+	}
+}
+
+// ValidateBlockAgainst checks whether a block would be valid as the child of
+// the block with the given parent id, which must be known. The minimum
+// timestamp, target and height are computed from that parent, and the
+// block's own ParentID is not consulted. Only the block itself is checked;
+// its transactions are validated when the block is applied.
+func (cs *ConsensusSet) ValidateBlockAgainst(b types.Block, parentID types.BlockID) error {
+	err := cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+
+	return cs.db.View(func(tx *bolt.Tx) error {
+		parent, err := cs.blockCache.getBlockMap(tx, parentID)
+		if err == errNilItem {
+			return errOrphan
+		} else if err != nil {
+			return err
+		}
+		minTimestamp := cs.blockRuleHelper.minimumValidChildTimestamp(tx.Bucket(BlockMap), parent)
+		return cs.blockValidator.ValidateBlock(b, b.ID(), minTimestamp, parent.ChildTarget, parent.Height+1, nil)
+	})
+}
+
+// StreamBlocks writes every block in the current path from 'start' to the
+// current block to w, in order, using the Sia encoding. All blocks are read
+// within a single database transaction, so the stream is a consistent view of
+// the blockchain even if blocks are added while streaming.
+func (cs *ConsensusSet) StreamBlocks(start types.BlockHeight, w io.Writer) error {
+	err := cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+
+	bw := bufio.NewWriter(w)
+	enc := encoding.NewEncoder(bw)
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		height := blockHeight(tx)
+		if start > height {
+			return errStreamStartHeight
+		}
+		for i := start; i <= height; i++ {
+			id, err := getPath(tx, i)
+			if err != nil {
+				return err
+			}
+			pb, err := cs.blockCache.getBlockMap(tx, id)
+			if err != nil {
+				return err
+			}
+			if err := enc.Encode(pb.Block); err != nil {
+				return err
+			}
+			if (i-start+1)%streamBlocksFlushInterval == 0 {
+				if err := bw.Flush(); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// BlockHeaders returns the headers of up to count blocks in the current path,
+// starting at height start. Fewer headers are returned if the current block is
+// reached, and none if start is above the current height. count is capped at
+// maxBlockHeaders. All headers are read within a single database transaction.
+func (cs *ConsensusSet) BlockHeaders(start types.BlockHeight, count int) (headers []types.BlockHeader, err error) {
+	err = cs.tg.Add()
+	if err != nil {
+		return nil, err
+	}
+	defer cs.tg.Done()
+
+	if count > maxBlockHeaders {
+		count = maxBlockHeaders
+	}
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		height := blockHeight(tx)
+		for i := start; i <= height && len(headers) < count; i++ {
+			id, err := getPath(tx, i)
+			if err != nil {
+				return err
+			}
+			pb, err := cs.blockCache.getBlockMap(tx, id)
+			if err != nil {
+				return err
+			}
+			headers = append(headers, pb.Block.Header())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return headers, nil
+}
+
 // ChildTarget returns the target for the child of a block.
 func (cs *ConsensusSet) ChildTarget(id types.BlockID) (target types.Target, exists bool) {
 	// A call to a closed database can cause undefined behavior.
@@ -236,7 +652,7 @@ func (cs *ConsensusSet) ChildTarget(id types.BlockID) (target types.Target, exis
 	defer cs.tg.Done()
 
 	_ = cs.db.View(func(tx *bolt.Tx) error {
-		pb, err := getBlockMap(tx, id)
+		pb, err := cs.blockCache.getBlockMap(tx, id)
 		if err != nil {
 			return err
 		}
@@ -258,7 +674,7 @@ func (cs *ConsensusSet) managedCurrentBlock() (block types.Block) {
 	defer cs.mu.RUnlock()
 
 	_ = cs.db.View(func(tx *bolt.Tx) error {
-		pb := currentProcessedBlock(tx)
+		pb := cs.blockCache.currentProcessedBlock(tx)
 		block = pb.Block
 		return nil
 	})
@@ -281,7 +697,7 @@ func (cs *ConsensusSet) CurrentBlock() (block types.Block) {
 	defer cs.mu.Unlock()
 
 	_ = cs.db.View(func(tx *bolt.Tx) error {
-		pb := currentProcessedBlock(tx)
+		pb := cs.blockCache.currentProcessedBlock(tx)
 		block = pb.Block
 		return nil
 	})
@@ -327,7 +743,7 @@ func (cs *ConsensusSet) InCurrentPath(id types.BlockID) (inPath bool) {
 	defer cs.tg.Done()
 
 	_ = cs.db.View(func(tx *bolt.Tx) error {
-		pb, err := getBlockMap(tx, id)
+		pb, err := cs.blockCache.getBlockMap(tx, id)
 		if err != nil {
 			inPath = false
 			return nil
@@ -355,7 +771,7 @@ func (cs *ConsensusSet) MinimumValidChildTimestamp(id types.BlockID) (timestamp
 
 	// Error is not checked because it does not matter.
 	_ = cs.db.View(func(tx *bolt.Tx) error {
-		pb, err := getBlockMap(tx, id)
+		pb, err := cs.blockCache.getBlockMap(tx, id)
 		if err != nil {
 			return err
 		}
@@ -366,6 +782,42 @@ func (cs *ConsensusSet) MinimumValidChildTimestamp(id types.BlockID) (timestamp
 	return timestamp, exists
 }
 
+// SiafundFee returns the portion of a file contract payout that is paid to
+// siafund holders when the contract is created at the given height. The
+// rounding of the fee changed at types.TaxHardforkHeight.
+func (cs *ConsensusSet) SiafundFee(height types.BlockHeight, payout types.Currency) types.Currency {
+	return types.Tax(height, payout)
+}
+
+// SiacoinOutputs returns the unspent siacoin outputs with the provided ids.
+// All of the outputs are looked up in a single database transaction. Outputs
+// that do not exist are absent from the returned map.
+func (cs *ConsensusSet) SiacoinOutputs(ids []types.SiacoinOutputID) (outputs map[types.SiacoinOutputID]types.SiacoinOutput, err error) {
+	err = cs.tg.Add()
+	if err != nil {
+		return nil, err
+	}
+	defer cs.tg.Done()
+
+	outputs = make(map[types.SiacoinOutputID]types.SiacoinOutput)
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		for _, id := range ids {
+			sco, err := getSiacoinOutput(tx, id)
+			if err == errNilItem {
+				continue
+			} else if err != nil {
+				return err
+			}
+			outputs[id] = sco
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return outputs, nil
+}
+
 // StorageProofSegment returns the segment to be used in the storage proof for
 // a given file contract.
 func (cs *ConsensusSet) StorageProofSegment(fcid types.FileContractID) (index uint64, err error) {
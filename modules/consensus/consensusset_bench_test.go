@@ -20,3 +20,32 @@ func BenchmarkCreateServerTester(b *testing.B) {
 		cst.Close()
 	}
 }
+
+// BenchmarkCurrentBlock benchmarks repeated lookups of the current block with
+// and without the block cache.
+func BenchmarkCurrentBlock(b *testing.B) {
+	cst, err := createConsensusSetTester(b.Name())
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer cst.Close()
+
+	setCacheSize := func(size int) {
+		bc := cst.cs.blockCache
+		bc.mu.Lock()
+		bc.capacity = size
+		bc.mu.Unlock()
+	}
+	b.Run("cached", func(b *testing.B) {
+		setCacheSize(defaultBlockCacheSize)
+		for i := 0; i < b.N; i++ {
+			cst.cs.CurrentBlock()
+		}
+	})
+	b.Run("uncached", func(b *testing.B) {
+		setCacheSize(-1)
+		for i := 0; i < b.N; i++ {
+			cst.cs.CurrentBlock()
+		}
+	})
+}
@@ -1,11 +1,14 @@
 package consensus
 
 import (
+	"bytes"
+	"errors"
 	"path/filepath"
 	"testing"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/modules/gateway"
 	"github.com/NebulousLabs/Sia/modules/miner"
@@ -202,3 +205,579 @@ func TestDatabaseClosing(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+// TestOutputCreationBlock checks that the consensus set reports the block that
+// created a siacoin output, including after the output has been spent.
+func TestOutputCreationBlock(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// The miner payout of a block should be attributed to that block, even
+	// after the payout has matured.
+	b, _ := cst.miner.FindBlock()
+	err = cst.cs.AcceptBlock(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payoutHeight := cst.cs.Height()
+	cst.mineSiacoins()
+	bid, height, err := cst.cs.OutputCreationBlock(b.MinerPayoutID(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bid != b.ID() || height != payoutHeight {
+		t.Error("wrong creation block reported for miner payout")
+	}
+
+	// Send some coins and check that the new output is attributed to the
+	// block that confirmed the transaction.
+	txns, err := cst.wallet.SendSiacoins(types.SiacoinPrecision, randAddress())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err = cst.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn := txns[len(txns)-1]
+	bid, height, err = cst.cs.OutputCreationBlock(txn.SiacoinOutputID(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bid != b.ID() || height != cst.cs.Height() {
+		t.Error("wrong creation block reported for transaction output")
+	}
+
+	// The spent parent outputs should still be reported.
+	for _, sci := range txn.SiacoinInputs {
+		_, _, err = cst.cs.OutputCreationBlock(sci.ParentID)
+		if err != nil {
+			t.Error("spent output not found in creation index:", err)
+		}
+	}
+
+	// Unknown outputs should return an error.
+	_, _, err = cst.cs.OutputCreationBlock(types.SiacoinOutputID{})
+	if err != errOutputCreationNotFound {
+		t.Error("expected errOutputCreationNotFound, got", err)
+	}
+}
+
+// TestSiacoinOutputs checks that SiacoinOutputs returns the requested outputs
+// that exist and omits those that do not.
+func TestSiacoinOutputs(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Confirm a transaction to create a new siacoin output.
+	txns, err := cst.wallet.SendSiacoins(types.SiacoinPrecision, randAddress())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	txn := txns[len(txns)-1]
+	outputID := txn.SiacoinOutputID(0)
+	missingID := types.SiacoinOutputID{1, 2, 3}
+	outputs, err := cst.cs.SiacoinOutputs([]types.SiacoinOutputID{outputID, missingID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outputs) != 1 {
+		t.Fatalf("expected 1 output, got %v", len(outputs))
+	}
+	if sco, ok := outputs[outputID]; !ok || sco.UnlockHash != txn.SiacoinOutputs[0].UnlockHash {
+		t.Error("transaction output was not returned correctly")
+	}
+	if _, ok := outputs[missingID]; ok {
+		t.Error("nonexistent output was returned")
+	}
+
+	// An empty request returns an empty map.
+	outputs, err = cst.cs.SiacoinOutputs(nil)
+	if err != nil || len(outputs) != 0 {
+		t.Fatal("expected an empty map, got", outputs, err)
+	}
+}
+
+// TestStreamBlocks checks that StreamBlocks writes the blocks of the current
+// path in order.
+func TestStreamBlocks(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	start := cst.cs.Height() / 2
+	var buf bytes.Buffer
+	err = cst.cs.StreamBlocks(start, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := encoding.NewDecoder(&buf)
+	for i := start; i <= cst.cs.Height(); i++ {
+		var b types.Block
+		err = dec.Decode(&b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, _ := cst.cs.BlockAtHeight(i)
+		if b.ID() != expected.ID() {
+			t.Fatal("streamed block does not match block at height", i)
+		}
+	}
+	if buf.Len() != 0 {
+		t.Error("extra data was written after the current block")
+	}
+
+	// Streaming from beyond the current height should fail.
+	err = cst.cs.StreamBlocks(cst.cs.Height()+1, &buf)
+	if err != errStreamStartHeight {
+		t.Error("expected errStreamStartHeight, got", err)
+	}
+}
+
+// TestBlockHeaders probes the BlockHeaders method of the consensus set.
+func TestBlockHeaders(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// The headers match the blocks of the current path.
+	height := cst.cs.Height()
+	headers, err := cst.cs.BlockHeaders(1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(headers) != 3 {
+		t.Fatal("expected 3 headers, got", len(headers))
+	}
+	for i, h := range headers {
+		b, _ := cst.cs.BlockAtHeight(types.BlockHeight(i + 1))
+		if h != b.Header() {
+			t.Fatal("header does not match block at height", i+1)
+		}
+	}
+
+	// Fewer headers are returned near the current block, and none beyond it.
+	headers, err = cst.cs.BlockHeaders(height-1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(headers) != 2 || headers[1].ID() != cst.cs.CurrentBlock().ID() {
+		t.Fatal("expected the last 2 headers, got", len(headers))
+	}
+	headers, err = cst.cs.BlockHeaders(height+1, 10)
+	if err != nil || len(headers) != 0 {
+		t.Fatal("expected no headers beyond the current block:", len(headers), err)
+	}
+
+	// The count is capped.
+	for cst.cs.Height() < types.BlockHeight(maxBlockHeaders) {
+		if _, err := cst.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	headers, err = cst.cs.BlockHeaders(0, maxBlockHeaders+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(headers) != maxBlockHeaders {
+		t.Fatalf("expected %v headers, got %v", maxBlockHeaders, len(headers))
+	}
+}
+
+// TestBlockReward checks that BlockReward agrees with the miner payouts of the
+// blocks accepted by the consensus set.
+func TestBlockReward(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	for height := types.BlockHeight(1); height <= cst.cs.Height(); height++ {
+		b, exists := cst.cs.BlockAtHeight(height)
+		if !exists {
+			t.Fatal("block does not exist at height", height)
+		}
+		payout, fees := types.ZeroCurrency, types.ZeroCurrency
+		for _, mp := range b.MinerPayouts {
+			payout = payout.Add(mp.Value)
+		}
+		for _, txn := range b.Transactions {
+			for _, fee := range txn.MinerFees {
+				fees = fees.Add(fee)
+			}
+		}
+		if payout.Cmp(cst.cs.BlockReward(height).Add(fees)) != 0 {
+			t.Fatal("block reward does not match the miner payout at height", height)
+		}
+	}
+	if cst.cs.BlockReward(types.BlockHeight(types.InitialCoinbase)).Cmp(types.NewCurrency64(types.MinimumCoinbase).Mul(types.SiacoinPrecision)) != 0 {
+		t.Error("block reward at the end of the emission schedule is not the minimum coinbase")
+	}
+
+	payout := types.SiacoinPrecision.Mul64(1000)
+	if cst.cs.SiafundFee(types.TaxHardforkHeight, payout).Cmp(types.Tax(types.TaxHardforkHeight, payout)) != 0 {
+		t.Error("siafund fee does not match the tax")
+	}
+}
+
+// TestOutputSpentAtHeight checks that the consensus set reports the
+// transaction that spent a siacoin output, and that the spend is forgotten
+// when the spending block is reverted.
+func TestOutputSpentAtHeight(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+	cstAlt, err := createConsensusSetTester(t.Name() + "-alt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cstAlt.Close()
+
+	// Spend some outputs and confirm the spend.
+	txns, err := cst.wallet.SendSiacoins(types.SiacoinPrecision, randAddress())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	txn := txns[len(txns)-1]
+	for _, sci := range txn.SiacoinInputs {
+		spent, height, txid, err := cst.cs.OutputSpentAtHeight(sci.ParentID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !spent || height != cst.cs.Height() || txid != txn.ID() {
+			t.Error("wrong spend reported for spent output")
+		}
+	}
+
+	// The new output has not been spent.
+	spent, _, _, err := cst.cs.OutputSpentAtHeight(txn.SiacoinOutputID(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spent {
+		t.Error("unspent output reported as spent")
+	}
+
+	// Reorg the spending block out of the current path, and check that the
+	// spend is no longer reported.
+	for cstAlt.cs.Height() <= cst.cs.Height() {
+		if _, err := cstAlt.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := types.BlockHeight(1); i <= cstAlt.cs.Height(); i++ {
+		b, _ := cstAlt.cs.BlockAtHeight(i)
+		_ = cst.cs.AcceptBlock(b)
+	}
+	if cst.cs.CurrentBlock().ID() != cstAlt.cs.CurrentBlock().ID() {
+		t.Fatal("consensus set did not reorg")
+	}
+	for _, sci := range txn.SiacoinInputs {
+		spent, _, _, err := cst.cs.OutputSpentAtHeight(sci.ParentID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if spent {
+			t.Error("spend from a reverted block is still reported")
+		}
+	}
+}
+
+// TestProcessedBlock checks that the consensus set reports the processed block
+// of blocks in the current path and on forks.
+func TestProcessedBlock(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+	cstAlt, err := createConsensusSetTester(t.Name() + "-alt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cstAlt.Close()
+
+	// Confirm a transaction so that the block has siacoin output diffs.
+	_, err = cst.wallet.SendSiacoins(types.SiacoinPrecision, randAddress())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := cst.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pbi, err := cst.cs.ProcessedBlock(b.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pbi.ID != b.ID() || pbi.ParentID != b.ParentID || pbi.Timestamp != b.Timestamp {
+		t.Error("processed block does not match the block")
+	}
+	if pbi.Height != cst.cs.Height() || !pbi.InCurrentPath {
+		t.Error("processed block has the wrong position:", pbi.Height, pbi.InCurrentPath)
+	}
+	childTarget, _ := cst.cs.ChildTarget(b.ID())
+	if pbi.ChildTarget != childTarget {
+		t.Error("processed block has the wrong child target")
+	}
+	if !pbi.DiffsGenerated || len(pbi.SiacoinOutputDiffs) == 0 || len(pbi.DelayedSiacoinOutputDiffs) == 0 {
+		t.Error("processed block is missing diffs")
+	}
+
+	// Reorg the block out of the current path, it should still be reported.
+	for cstAlt.cs.Height() <= cst.cs.Height() {
+		if _, err := cstAlt.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := types.BlockHeight(1); i <= cstAlt.cs.Height(); i++ {
+		ab, _ := cstAlt.cs.BlockAtHeight(i)
+		_ = cst.cs.AcceptBlock(ab)
+	}
+	if cst.cs.CurrentBlock().ID() != cstAlt.cs.CurrentBlock().ID() {
+		t.Fatal("consensus set did not reorg")
+	}
+	pbi, err = cst.cs.ProcessedBlock(b.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pbi.InCurrentPath {
+		t.Error("block on a fork is reported to be in the current path")
+	}
+
+	// Unknown blocks are an error.
+	if _, err := cst.cs.ProcessedBlock(types.BlockID{}); err != errUnknownBlock {
+		t.Error("expected errUnknownBlock, got", err)
+	}
+}
+
+// TestBlockAtHeightOnFork checks that the blocks of a fork can be read by
+// height after the fork has been reorged out of the current path.
+func TestBlockAtHeightOnFork(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+	cstAlt, err := createConsensusSetTester(t.Name() + "-alt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cstAlt.Close()
+
+	// Record the chain, then reorg it out of the current path.
+	var chain []types.Block
+	for i := types.BlockHeight(0); i <= cst.cs.Height(); i++ {
+		b, _ := cst.cs.BlockAtHeight(i)
+		chain = append(chain, b)
+	}
+	forkTip := chain[len(chain)-1].ID()
+	for cstAlt.cs.Height() <= cst.cs.Height() {
+		if _, err := cstAlt.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := types.BlockHeight(1); i <= cstAlt.cs.Height(); i++ {
+		b, _ := cstAlt.cs.BlockAtHeight(i)
+		_ = cst.cs.AcceptBlock(b)
+	}
+	if cst.cs.CurrentBlock().ID() != cstAlt.cs.CurrentBlock().ID() {
+		t.Fatal("consensus set did not reorg")
+	}
+
+	// The fork is read from the block map, and the current path from the
+	// path.
+	for i, exp := range chain {
+		b, err := cst.cs.BlockAtHeightOnFork(forkTip, types.BlockHeight(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.ID() != exp.ID() {
+			t.Fatalf("wrong block at height %v of the fork", i)
+		}
+	}
+	tip := cst.cs.CurrentBlock().ID()
+	for i := types.BlockHeight(0); i <= cst.cs.Height(); i++ {
+		b, err := cst.cs.BlockAtHeightOnFork(tip, i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp, _ := cst.cs.BlockAtHeight(i); b.ID() != exp.ID() {
+			t.Fatalf("wrong block at height %v of the current path", i)
+		}
+	}
+
+	if _, err := cst.cs.BlockAtHeightOnFork(forkTip, types.BlockHeight(len(chain))); err != errHeightAboveTip {
+		t.Fatal("expected errHeightAboveTip, got", err)
+	}
+	if _, err := cst.cs.BlockAtHeightOnFork(types.BlockID{}, 0); err != errUnknownBlock {
+		t.Fatal("expected errUnknownBlock, got", err)
+	}
+}
+
+// TestWalkBackward checks that WalkBackward visits the ancestors of a block
+// from newest to oldest, and stops when the visitor asks it to.
+func TestWalkBackward(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	chain, cs, err := NewTestChain(TestChainParams{Name: t.Name()}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+	fork, err := ExtendTestChain(cs, chain[0].ID(), 2, types.UnlockHash{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Walking from the tip should visit every block down to genesis.
+	var visited []modules.ProcessedBlockInfo
+	err = cs.WalkBackward(chain[len(chain)-1].ID(), func(pbi modules.ProcessedBlockInfo) (bool, error) {
+		visited = append(visited, pbi)
+		return false, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(visited) != len(chain)+1 {
+		t.Fatalf("expected %v blocks, visited %v", len(chain)+1, len(visited))
+	}
+	for i, pbi := range visited {
+		exp, _ := cs.BlockAtHeight(types.BlockHeight(len(chain) - i))
+		if pbi.ID != exp.ID() || pbi.Height != types.BlockHeight(len(chain)-i) || !pbi.InCurrentPath {
+			t.Fatalf("wrong block visited at step %v: %v", i, pbi.Height)
+		}
+	}
+
+	// Walking from the tip of the fork should visit the fork, then the
+	// current path once the fork joins it.
+	var inPath []bool
+	err = cs.WalkBackward(fork[len(fork)-1].ID(), func(pbi modules.ProcessedBlockInfo) (bool, error) {
+		inPath = append(inPath, pbi.InCurrentPath)
+		return false, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inPath) != 4 || inPath[0] || inPath[1] || !inPath[2] || !inPath[3] {
+		t.Fatal("fork was walked incorrectly:", inPath)
+	}
+
+	// The walk should stop when the visitor says so, or returns an error.
+	visits := 0
+	err = cs.WalkBackward(chain[len(chain)-1].ID(), func(pbi modules.ProcessedBlockInfo) (bool, error) {
+		visits++
+		return pbi.Height == 3, nil
+	})
+	if err != nil || visits != 3 {
+		t.Fatalf("expected 3 visits and no error, got %v visits and %v", visits, err)
+	}
+	errVisit := errors.New("visitor error")
+	visits = 0
+	err = cs.WalkBackward(chain[len(chain)-1].ID(), func(pbi modules.ProcessedBlockInfo) (bool, error) {
+		visits++
+		return false, errVisit
+	})
+	if err != errVisit || visits != 1 {
+		t.Fatalf("expected 1 visit and errVisit, got %v visits and %v", visits, err)
+	}
+
+	err = cs.WalkBackward(types.BlockID{}, func(modules.ProcessedBlockInfo) (bool, error) {
+		t.Fatal("visited an unknown block")
+		return false, nil
+	})
+	if err != errUnknownBlock {
+		t.Fatal("expected errUnknownBlock, got", err)
+	}
+}
+
+// TestValidateBlockAgainst checks that blocks are validated against the
+// supplied parent rather than their own.
+func TestValidateBlockAgainst(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Build a block on the current block without submitting it, then extend
+	// the current path.
+	parent := cst.cs.CurrentBlock()
+	b, _ := cst.miner.FindBlock()
+	if err := cst.cs.ValidateBlockAgainst(b, parent.ID()); err != nil {
+		t.Fatal(err)
+	}
+	child, err := cst.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The block remains valid against its own parent, but not against the
+	// new current block, where its miner payouts are wrong.
+	if err := cst.cs.ValidateBlockAgainst(b, parent.ID()); err != nil {
+		t.Fatal(err)
+	}
+	if err := cst.cs.ValidateBlockAgainst(b, child.ID()); err != errBadMinerPayouts {
+		t.Fatal("expected errBadMinerPayouts, got", err)
+	}
+	if err := cst.cs.ValidateBlockAgainst(b, types.BlockID{}); err != errOrphan {
+		t.Fatal("expected errOrphan, got", err)
+	}
+}
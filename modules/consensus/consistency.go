@@ -79,9 +79,26 @@ func consensusChecksum(tx *bolt.Tx) crypto.Hash {
 // checkSiacoinCount checks that the number of siacoins countable within the
 // consensus set equal the expected number of siacoins for the block height.
 func checkSiacoinCount(tx *bolt.Tx) {
+	dscoSiacoins, scoSiacoins, fcSiacoins, claimSiacoins := countSiacoins(tx)
+	expectedSiacoins := types.CalculateNumSiacoins(blockHeight(tx))
+	totalSiacoins := dscoSiacoins.Add(scoSiacoins).Add(fcSiacoins).Add(claimSiacoins)
+	if !totalSiacoins.Equals(expectedSiacoins) {
+		diagnostics := fmt.Sprintf("Wrong number of siacoins\nDsco: %v\nSco: %v\nFc: %v\nClaim: %v\n", dscoSiacoins, scoSiacoins, fcSiacoins, claimSiacoins)
+		if totalSiacoins.Cmp(expectedSiacoins) < 0 {
+			diagnostics += fmt.Sprintf("total: %v\nexpected: %v\n expected is bigger: %v", totalSiacoins, expectedSiacoins, expectedSiacoins.Sub(totalSiacoins))
+		} else {
+			diagnostics += fmt.Sprintf("total: %v\nexpected: %v\n expected is bigger: %v", totalSiacoins, expectedSiacoins, totalSiacoins.Sub(expectedSiacoins))
+		}
+		manageErr(tx, errors.New(diagnostics))
+	}
+}
+
+// countSiacoins adds up the siacoins in the delayed siacoin outputs, the
+// siacoin outputs, the file contracts and the unclaimed siafund claims of the
+// consensus set.
+func countSiacoins(tx *bolt.Tx) (dscoSiacoins, scoSiacoins, fcSiacoins, claimSiacoins types.Currency) {
 	// Iterate through all the buckets looking for the delayed siacoin output
 	// buckets, and check that they are for the correct heights.
-	var dscoSiacoins types.Currency
 	err := tx.ForEach(func(name []byte, b *bolt.Bucket) error {
 		// Check if the bucket is a delayed siacoin output bucket.
 		if !bytes.HasPrefix(name, prefixDSCO) {
@@ -108,7 +125,6 @@ func checkSiacoinCount(tx *bolt.Tx) {
 	}
 
 	// Add all of the siacoin outputs.
-	var scoSiacoins types.Currency
 	err = tx.Bucket(SiacoinOutputs).ForEach(func(_, scoBytes []byte) error {
 		var sco types.SiacoinOutput
 		err := encoding.Unmarshal(scoBytes, &sco)
@@ -123,7 +139,6 @@ func checkSiacoinCount(tx *bolt.Tx) {
 	}
 
 	// Add all of the payouts from file contracts.
-	var fcSiacoins types.Currency
 	err = tx.Bucket(FileContracts).ForEach(func(_, fcBytes []byte) error {
 		var fc types.FileContract
 		err := encoding.Unmarshal(fcBytes, &fc)
@@ -142,7 +157,6 @@ func checkSiacoinCount(tx *bolt.Tx) {
 	}
 
 	// Add all of the siafund claims.
-	var claimSiacoins types.Currency
 	err = tx.Bucket(SiafundOutputs).ForEach(func(_, sfoBytes []byte) error {
 		var sfo types.SiafundOutput
 		err := encoding.Unmarshal(sfoBytes, &sfo)
@@ -159,17 +173,7 @@ func checkSiacoinCount(tx *bolt.Tx) {
 		manageErr(tx, err)
 	}
 
-	expectedSiacoins := types.CalculateNumSiacoins(blockHeight(tx))
-	totalSiacoins := dscoSiacoins.Add(scoSiacoins).Add(fcSiacoins).Add(claimSiacoins)
-	if !totalSiacoins.Equals(expectedSiacoins) {
-		diagnostics := fmt.Sprintf("Wrong number of siacoins\nDsco: %v\nSco: %v\nFc: %v\nClaim: %v\n", dscoSiacoins, scoSiacoins, fcSiacoins, claimSiacoins)
-		if totalSiacoins.Cmp(expectedSiacoins) < 0 {
-			diagnostics += fmt.Sprintf("total: %v\nexpected: %v\n expected is bigger: %v", totalSiacoins, expectedSiacoins, expectedSiacoins.Sub(totalSiacoins))
-		} else {
-			diagnostics += fmt.Sprintf("total: %v\nexpected: %v\n expected is bigger: %v", totalSiacoins, expectedSiacoins, totalSiacoins.Sub(expectedSiacoins))
-		}
-		manageErr(tx, errors.New(diagnostics))
-	}
+	return dscoSiacoins, scoSiacoins, fcSiacoins, claimSiacoins
 }
 
 // checkSiafundCount checks that the number of siafunds countable within the
@@ -64,7 +64,7 @@ func (cs *ConsensusSet) replaceDatabase(filename string) error {
 
 	// Try again to create a new database, this time without checking for an
 	// outdated database error.
-	cs.db, err = persist.OpenDatabase(dbMetadata, filename)
+	cs.db, err = persist.OpenDatabaseWithOptions(dbMetadata, filename, cs.staticDatabaseOptions.boltOptions())
 	if err != nil {
 		return errors.New("error opening consensus database: " + err.Error())
 	}
@@ -73,7 +73,7 @@ func (cs *ConsensusSet) replaceDatabase(filename string) error {
 
 // openDB loads the set database and populates it with the necessary buckets
 func (cs *ConsensusSet) openDB(filename string) (err error) {
-	cs.db, err = persist.OpenDatabase(dbMetadata, filename)
+	cs.db, err = persist.OpenDatabaseWithOptions(dbMetadata, filename, cs.staticDatabaseOptions.boltOptions())
 	if err == persist.ErrBadVersion {
 		return cs.replaceDatabase(filename)
 	}
@@ -0,0 +1,71 @@
+package consensus
+
+import (
+	"time"
+
+	"github.com/coreos/bbolt"
+)
+
+const (
+	// defaultDatabaseTimeout is the time that the consensus set waits for the
+	// lock on the database file before giving up.
+	defaultDatabaseTimeout = 3 * time.Second
+)
+
+// DatabaseOptions tune how the consensus database is opened. They trade
+// durability for speed; none of them affect which blocks are accepted.
+type DatabaseOptions struct {
+	// NoSync skips the fsync after each database transaction. This can speed
+	// up the initial blockchain download considerably, but a crash or power
+	// failure may corrupt the database or lose recently accepted blocks,
+	// requiring the blockchain to be downloaded again. A process crash alone
+	// is safe, as the writes have been handed to the operating system. Sync
+	// can be enabled again with SetDatabaseNoSync once the node has caught up.
+	NoSync bool
+
+	// MmapFlags are passed to mmap when the database file is mapped, e.g.
+	// syscall.MAP_POPULATE to read the whole file into memory up front on
+	// Linux. They do not affect durability.
+	MmapFlags int
+
+	// InitialMmapSize is the size in bytes of the initial memory map of the
+	// database. Setting it above the size of the database prevents the map
+	// from being grown, which blocks writes until all reads have finished.
+	// It does not affect durability, and has no effect if it is smaller than
+	// the database.
+	InitialMmapSize int
+
+	// Timeout is how long to wait for the lock on the database file, which
+	// is held by any other process that has the database open. It does not
+	// affect durability. Defaults to defaultDatabaseTimeout.
+	Timeout time.Duration
+}
+
+// boltOptions returns the bolt options that correspond to opts.
+func (opts DatabaseOptions) boltOptions() *bolt.Options {
+	return &bolt.Options{
+		Timeout:         opts.Timeout,
+		MmapFlags:       opts.MmapFlags,
+		InitialMmapSize: opts.InitialMmapSize,
+		NoSync:          opts.NoSync,
+	}
+}
+
+// SetDatabaseNoSync sets whether the consensus database skips the fsync after
+// each transaction, see DatabaseOptions.NoSync. When sync is enabled again,
+// the database is synced immediately so that every block accepted so far is
+// durable.
+func (cs *ConsensusSet) SetDatabaseNoSync(noSync bool) error {
+	if err := cs.tg.Add(); err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	cs.db.NoSync = noSync
+	if !noSync {
+		return cs.db.Sync()
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package consensus
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/gateway"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestDatabaseOptions checks that the database options are passed to bolt,
+// and that sync can be enabled again after the database was opened without
+// it.
+func TestDatabaseOptions(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	testdir := build.TempDir(modules.ConsensusDir, t.Name())
+	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	csDir := filepath.Join(testdir, modules.ConsensusDir)
+	cs, err := NewConfiguredConsensusSet(g, false, csDir, modules.ProdDependencies, Config{
+		Database: DatabaseOptions{
+			NoSync:          true,
+			InitialMmapSize: 1 << 24,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+	if !cs.db.NoSync {
+		t.Fatal("database was not opened with NoSync")
+	}
+	if cs.staticDatabaseOptions.Timeout != defaultDatabaseTimeout {
+		t.Fatal("default timeout was not set:", cs.staticDatabaseOptions.Timeout)
+	}
+	// Enable sync again. Blocks are still accepted.
+	if err := cs.SetDatabaseNoSync(false); err != nil {
+		t.Fatal(err)
+	}
+	if cs.db.NoSync {
+		t.Fatal("sync was not enabled")
+	}
+	if cs.Height() != 0 {
+		t.Fatal("expected a new consensus set")
+	}
+	if cs.CurrentBlock().ID() != types.GenesisID {
+		t.Fatal("wrong current block")
+	}
+
+	// A second consensus set cannot open the database while it is in use,
+	// and gives up after the timeout.
+	start := time.Now()
+	_, err = NewConfiguredConsensusSet(g, false, csDir, modules.ProdDependencies, Config{
+		Database: DatabaseOptions{Timeout: 100 * time.Millisecond},
+	})
+	if err == nil {
+		t.Fatal("database was opened twice")
+	}
+	if elapsed := time.Since(start); elapsed > defaultDatabaseTimeout {
+		t.Fatal("timeout was not used:", elapsed)
+	}
+}
@@ -192,16 +192,39 @@ func commitDiffSet(tx *bolt.Tx, pb *processedBlock, dir modules.DiffDirection) {
 
 	createUpcomingDelayedOutputMaps(tx, pb, dir)
 	commitNodeDiffs(tx, pb, dir)
+	commitOutputCreations(tx, pb, dir)
+	commitOutputSpends(tx, pb, dir)
+	commitTransactionHeights(tx, pb, dir)
+	commitTransactionCount(tx, pb, dir)
+	commitCoinSupply(tx, pb, dir)
 	deleteObsoleteDelayedOutputMaps(tx, pb, dir)
 	updateCurrentPath(tx, pb, dir)
 }
 
-// generateAndApplyDiff will verify the block and then integrate it into the
+// A txnValidation determines how thoroughly the transactions of a block are
+// validated while the block is applied.
+type txnValidation int
+
+const (
+	// validateTransactions fully validates every transaction.
+	validateTransactions txnValidation = iota
+
+	// validateNoSignatures validates every transaction except for its
+	// signatures.
+	validateNoSignatures
+
+	// validateNothing applies transactions without validating them.
+	validateNothing
+)
+
+// generateAndApplyDiffs will verify the block and then integrate it into the
 // consensus state. These two actions must happen at the same time because
 // transactions are allowed to depend on each other. We can't be sure that a
 // transaction is valid unless we have applied all of the previous transactions
-// in the block, which means we need to apply while we verify.
-func generateAndApplyDiff(tx *bolt.Tx, pb *processedBlock) error {
+// in the block, which means we need to apply while we verify. Transactions
+// should only go without full validation if the block is known to be part of
+// a trusted chain.
+func generateAndApplyDiffs(tx *bolt.Tx, pb *processedBlock, validation txnValidation) error {
 	// Sanity check - the block being applied should have the current block as
 	// a parent.
 	if build.DEBUG && pb.Block.ParentID != currentBlockID(tx) {
@@ -217,7 +240,13 @@ func generateAndApplyDiff(tx *bolt.Tx, pb *processedBlock) error {
 	// validated all at once because some transactions may not be valid until
 	// previous transactions have been applied.
 	for _, txn := range pb.Block.Transactions {
-		err := validTransaction(tx, txn)
+		var err error
+		switch validation {
+		case validateTransactions:
+			err = validTransaction(tx, txn)
+		case validateNoSignatures:
+			err = validTransactionNoSignatures(tx, txn)
+		}
 		if err != nil {
 			return err
 		}
@@ -230,6 +259,13 @@ func generateAndApplyDiff(tx *bolt.Tx, pb *processedBlock) error {
 	// the miner payouts to the list of delayed outputs.
 	applyMaintenance(tx, pb)
 
+	// Index the outputs that were created and spent by the block.
+	commitOutputCreations(tx, pb, modules.DiffApply)
+	commitOutputSpends(tx, pb, modules.DiffApply)
+	commitTransactionHeights(tx, pb, modules.DiffApply)
+	commitTransactionCount(tx, pb, modules.DiffApply)
+	commitCoinSupply(tx, pb, modules.DiffApply)
+
 	// DiffsGenerated are only set to true after the block has been fully
 	// validated and integrated. This is required to prevent later blocks from
 	// being accepted on top of an invalid block - if the consensus set ever
@@ -0,0 +1,91 @@
+package consensus
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// defaultMaxDoSBlocks is the number of DoS blocks that are remembered
+	// until SetMaxDoSBlocks is called.
+	defaultMaxDoSBlocks = build.Select(build.Var{
+		Standard: 10000,
+		Dev:      1000,
+		Testing:  100,
+	}).(int)
+)
+
+// dosBlockSet is a bounded set of DoS blocks. When the set is full, the block
+// that was least recently checked is evicted. An evicted block is validated
+// again if it is seen again, which adds it back to the set.
+type dosBlockSet struct {
+	capacity int
+	elems    map[types.BlockID]*list.Element
+	order    *list.List // most recently checked first
+	mu       sync.Mutex
+}
+
+// newDoSBlockSet returns an empty dosBlockSet that holds up to capacity
+// blocks.
+func newDoSBlockSet(capacity int) *dosBlockSet {
+	return &dosBlockSet{
+		capacity: capacity,
+		elems:    make(map[types.BlockID]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// evict removes the least recently checked blocks until the set is within its
+// capacity. The caller must hold s.mu.
+func (s *dosBlockSet) evict() {
+	for s.order.Len() > s.capacity {
+		delete(s.elems, s.order.Remove(s.order.Back()).(types.BlockID))
+	}
+}
+
+// add marks a block as a DoS block.
+func (s *dosBlockSet) add(id types.BlockID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, exists := s.elems[id]; exists {
+		s.order.MoveToFront(e)
+		return
+	}
+	s.elems[id] = s.order.PushFront(id)
+	s.evict()
+}
+
+// contains returns true if the block is a known DoS block, counting the check
+// as a use of the block.
+func (s *dosBlockSet) contains(id types.BlockID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, exists := s.elems[id]
+	if exists {
+		s.order.MoveToFront(e)
+	}
+	return exists
+}
+
+// setCapacity changes the number of blocks held by the set, evicting blocks
+// if the set no longer fits.
+func (s *dosBlockSet) setCapacity(capacity int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.capacity = capacity
+	s.evict()
+}
+
+// SetMaxDoSBlocks sets the number of known invalid blocks that the consensus
+// set remembers in order to reject them without validating them again. When
+// the limit is reached, the blocks that were least recently seen are
+// forgotten. Values below 1 are treated as 1.
+func (cs *ConsensusSet) SetMaxDoSBlocks(n int) {
+	if n < 1 {
+		n = 1
+	}
+	cs.dosBlocks.setCapacity(n)
+}
@@ -0,0 +1,89 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+// newTestDoSBlockSet returns a dosBlockSet containing the provided blocks.
+func newTestDoSBlockSet(ids map[types.BlockID]struct{}) *dosBlockSet {
+	s := newDoSBlockSet(defaultMaxDoSBlocks)
+	for id := range ids {
+		s.add(id)
+	}
+	return s
+}
+
+// TestDoSBlockSetEviction checks that the dosBlockSet evicts the least
+// recently checked blocks once it is full.
+func TestDoSBlockSetEviction(t *testing.T) {
+	s := newDoSBlockSet(3)
+	for i := byte(0); i < 3; i++ {
+		s.add(types.BlockID{i})
+	}
+
+	// Checking block 0 makes block 1 the least recently checked.
+	if !s.contains(types.BlockID{0}) {
+		t.Fatal("block 0 is missing")
+	}
+	s.add(types.BlockID{3})
+	if s.contains(types.BlockID{1}) {
+		t.Fatal("block 1 was not evicted")
+	}
+	for _, i := range []byte{0, 2, 3} {
+		if !s.contains(types.BlockID{i}) {
+			t.Fatal("block was evicted:", i)
+		}
+	}
+
+	// Shrinking the set evicts the least recently checked blocks, which are
+	// now 0 and 2.
+	s.setCapacity(1)
+	if len(s.elems) != 1 || s.order.Len() != 1 || !s.contains(types.BlockID{3}) {
+		t.Fatal("set was not shrunk to the most recently checked block")
+	}
+}
+
+// TestSetMaxDoSBlocks checks that SetMaxDoSBlocks bounds the DoS blocks of a
+// consensus set.
+func TestSetMaxDoSBlocks(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	cst.cs.SetMaxDoSBlocks(2)
+	for i := byte(0); i < 5; i++ {
+		cst.cs.dosBlocks.add(types.BlockID{i})
+	}
+	if len(cst.cs.dosBlocks.elems) != 2 {
+		t.Fatal("expected 2 DoS blocks, got", len(cst.cs.dosBlocks.elems))
+	}
+
+	// An evicted block is no longer rejected as a DoS block.
+	validateHeader := func(h types.BlockHeader) (err error) {
+		_ = cst.cs.db.View(func(tx *bolt.Tx) error {
+			err = cst.cs.validateHeader(boltTxWrapper{tx}, h)
+			return nil
+		})
+		return err
+	}
+	h := types.BlockHeader{ParentID: types.BlockID{9}}
+	cst.cs.dosBlocks.add(h.ID())
+	if err := validateHeader(h); err != errDoSBlock {
+		t.Fatal("expected errDoSBlock, got", err)
+	}
+	cst.cs.SetMaxDoSBlocks(0)
+	cst.cs.dosBlocks.add(types.BlockID{1})
+	if err := validateHeader(h); err == errDoSBlock {
+		t.Fatal("evicted block was rejected as a DoS block")
+	}
+}
@@ -0,0 +1,170 @@
+package consensus
+
+import (
+	"sync"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+// fileContractSubscription queues the file contract events of a subscriber.
+// The queue is unbounded so that a slow subscriber neither blocks the
+// consensus set nor misses events, which would leave it inconsistent.
+type fileContractSubscription struct {
+	ch     chan<- modules.FileContractEvent
+	queue  []modules.FileContractEvent
+	wakeCh chan struct{}
+	mu     sync.Mutex
+}
+
+// push adds events to the queue of the subscription.
+func (s *fileContractSubscription) push(events []modules.FileContractEvent) {
+	s.mu.Lock()
+	s.queue = append(s.queue, events...)
+	s.mu.Unlock()
+	select {
+	case s.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// fileContractEvents returns the file contract events caused by applying a
+// block, in the order that they happened.
+func fileContractEvents(pb *processedBlock) []modules.FileContractEvent {
+	var events []modules.FileContractEvent
+	addEvent := func(id types.FileContractID, t modules.FileContractEventType) {
+		events = append(events, modules.FileContractEvent{
+			ID:      id,
+			Type:    t,
+			BlockID: pb.Block.ID(),
+			Height:  pb.Height,
+		})
+	}
+
+	proven := make(map[types.FileContractID]struct{})
+	for _, txn := range pb.Block.Transactions {
+		for i := range txn.FileContracts {
+			addEvent(txn.FileContractID(uint64(i)), modules.FileContractCreated)
+		}
+		for _, fcr := range txn.FileContractRevisions {
+			addEvent(fcr.ParentID, modules.FileContractRevised)
+		}
+		for _, sp := range txn.StorageProofs {
+			addEvent(sp.ParentID, modules.FileContractProven)
+			proven[sp.ParentID] = struct{}{}
+		}
+	}
+
+	// Contracts that were removed by the block without being recreated by a
+	// revision or resolved by a storage proof expired.
+	applied := make(map[types.FileContractID]struct{})
+	for _, fcd := range pb.FileContractDiffs {
+		if fcd.Direction == modules.DiffApply {
+			applied[fcd.ID] = struct{}{}
+		}
+	}
+	for _, fcd := range pb.FileContractDiffs {
+		_, isApplied := applied[fcd.ID]
+		_, isProven := proven[fcd.ID]
+		if fcd.Direction == modules.DiffRevert && !isApplied && !isProven {
+			addEvent(fcd.ID, modules.FileContractExpired)
+		}
+	}
+	return events
+}
+
+// computeFileContractEvents returns the file contract events of a change
+// entry. The events of each reverted block are reported in reverse order with
+// Reverted set, followed by the events of the applied blocks.
+func computeFileContractEvents(tx *bolt.Tx, ce changeEntry) ([]modules.FileContractEvent, error) {
+	var events []modules.FileContractEvent
+	for _, id := range ce.RevertedBlocks {
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			return nil, err
+		}
+		blockEvents := fileContractEvents(pb)
+		for i := len(blockEvents) - 1; i >= 0; i-- {
+			blockEvents[i].Reverted = true
+			events = append(events, blockEvents[i])
+		}
+	}
+	for _, id := range ce.AppliedBlocks {
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, fileContractEvents(pb)...)
+	}
+	return events, nil
+}
+
+// threadedDeliverFileContractEvents sends the queued events of a subscription
+// to its channel until the consensus set shuts down.
+func (cs *ConsensusSet) threadedDeliverFileContractEvents(s *fileContractSubscription) {
+	if err := cs.tg.Add(); err != nil {
+		return
+	}
+	defer cs.tg.Done()
+
+	for {
+		s.mu.Lock()
+		events := s.queue
+		s.queue = nil
+		s.mu.Unlock()
+		for _, e := range events {
+			select {
+			case s.ch <- e:
+			case <-cs.tg.StopChan():
+				return
+			}
+		}
+		select {
+		case <-s.wakeCh:
+		case <-cs.tg.StopChan():
+			return
+		}
+	}
+}
+
+// updateFileContractSubscriptions queues the file contract events of a change
+// entry for every subscription.
+func (cs *ConsensusSet) updateFileContractSubscriptions(ce changeEntry) {
+	if len(cs.fileContractSubs) == 0 {
+		return
+	}
+	var events []modules.FileContractEvent
+	err := cs.db.View(func(tx *bolt.Tx) error {
+		var err error
+		events, err = computeFileContractEvents(tx, ce)
+		return err
+	})
+	if err != nil {
+		cs.log.Critical("computeFileContractEvents failed:", err)
+		return
+	}
+	if len(events) == 0 {
+		return
+	}
+	for _, s := range cs.fileContractSubs {
+		s.push(events)
+	}
+}
+
+// SubscribeFileContracts registers a channel that receives an event whenever
+// a block creates, revises, or resolves a file contract. If a block is
+// reverted, its events are sent again in reverse order with Reverted set.
+// Events are delivered in order from a separate goroutine, and are queued
+// while the channel is not being read.
+func (cs *ConsensusSet) SubscribeFileContracts(ch chan<- modules.FileContractEvent) {
+	s := &fileContractSubscription{
+		ch:     ch,
+		wakeCh: make(chan struct{}, 1),
+	}
+	cs.mu.Lock()
+	cs.fileContractSubs = append(cs.fileContractSubs, s)
+	cs.mu.Unlock()
+	go cs.threadedDeliverFileContractEvents(s)
+}
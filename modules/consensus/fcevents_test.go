@@ -0,0 +1,110 @@
+package consensus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// readFileContractEvents reads n events from ch, and checks that no further
+// events arrive.
+func readFileContractEvents(t *testing.T, ch <-chan modules.FileContractEvent, n int) []modules.FileContractEvent {
+	var events []modules.FileContractEvent
+	for len(events) < n {
+		select {
+		case e := <-ch:
+			events = append(events, e)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected %v events, got %v", n, len(events))
+		}
+	}
+	select {
+	case e := <-ch:
+		t.Fatal("received an unexpected event:", e)
+	case <-time.After(100 * time.Millisecond):
+	}
+	return events
+}
+
+// TestFileContractEvents checks that the subscribers of SubscribeFileContracts
+// are told about contracts as they are created and expire, and that the
+// events are reverted and reapplied during reorgs.
+func TestFileContractEvents(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	rs := createReorgSets(t.Name())
+	defer rs.Close()
+
+	ch := make(chan modules.FileContractEvent)
+	rs.cstMain.cs.SubscribeFileContracts(ch)
+
+	// Create a contract that expires without a storage proof.
+	rs.cstMain.testMissedStorageProofBlocks()
+	events := readFileContractEvents(t, ch, 2)
+	created, expired := events[0], events[1]
+	if created.Type != modules.FileContractCreated || created.Reverted {
+		t.Fatal("expected a created event, got", created)
+	}
+	if expired.Type != modules.FileContractExpired || expired.Reverted || expired.ID != created.ID {
+		t.Fatal("expected an expired event for the contract, got", expired)
+	}
+	if expired.Height != created.Height+1 {
+		t.Fatalf("contract created at %v but expired at %v", created.Height, expired.Height)
+	}
+	if id, _ := rs.cstMain.cs.BlockAtHeight(created.Height); id.ID() != created.BlockID {
+		t.Fatal("created event has the wrong block id")
+	}
+
+	// Reorg the contract out of the consensus set. The events are reverted
+	// in reverse order.
+	rs.save()
+	rs.extend()
+	events = readFileContractEvents(t, ch, 2)
+	if events[0].Type != modules.FileContractExpired || !events[0].Reverted || events[0].ID != created.ID {
+		t.Fatal("expected a reverted expired event, got", events[0])
+	}
+	if events[1].Type != modules.FileContractCreated || !events[1].Reverted || events[1].ID != created.ID {
+		t.Fatal("expected a reverted created event, got", events[1])
+	}
+
+	// Reorg the contract back in.
+	rs.restore()
+	events = readFileContractEvents(t, ch, 2)
+	if events[0] != created || events[1] != expired {
+		t.Fatal("events were not reapplied:", events)
+	}
+}
+
+// TestFileContractEventsRevision checks that revisions and storage proofs are
+// reported.
+func TestFileContractEventsRevision(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	ch := make(chan modules.FileContractEvent, 100)
+	cst.cs.SubscribeFileContracts(ch)
+	cst.testValidStorageProofBlocks()
+	events := readFileContractEvents(t, ch, 2)
+	if events[0].Type != modules.FileContractCreated || events[1].Type != modules.FileContractProven || events[0].ID != events[1].ID {
+		t.Fatal("expected created and proven events, got", events)
+	}
+
+	cst.testFileContractRevision()
+	var revised bool
+	for _, e := range readFileContractEvents(t, ch, 3) {
+		revised = revised || e.Type == modules.FileContractRevised
+	}
+	if !revised {
+		t.Fatal("revision was not reported")
+	}
+}
@@ -61,6 +61,7 @@ func (cs *ConsensusSet) revertToBlock(tx *bolt.Tx, pb *processedBlock) (reverted
 	for currentBlockID(tx) != pb.Block.ID() {
 		block := currentProcessedBlock(tx)
 		commitDiffSet(tx, block, modules.DiffRevert)
+		cs.blockCache.evict(tx, block.Block.ID())
 		revertedBlocks = append(revertedBlocks, block)
 
 		// Sanity check - after removing a block, check that the consensus set
@@ -75,8 +76,9 @@ func (cs *ConsensusSet) revertToBlock(tx *bolt.Tx, pb *processedBlock) (reverted
 }
 
 // applyUntilBlock will successively apply the blocks between the consensus
-// set's current path and 'pb'.
-func (cs *ConsensusSet) applyUntilBlock(tx *bolt.Tx, pb *processedBlock) (appliedBlocks []*processedBlock, err error) {
+// set's current path and 'pb'. 'extendsPath' indicates that no blocks were
+// reverted to reach the common parent.
+func (cs *ConsensusSet) applyUntilBlock(tx *bolt.Tx, pb *processedBlock, extendsPath bool) (appliedBlocks []*processedBlock, err error) {
 	// Backtrack to the common parent of 'bn' and current path and then apply the new blocks.
 	newPath := backtrackToCurrentPath(tx, pb)
 	for _, block := range newPath[1:] {
@@ -85,10 +87,11 @@ func (cs *ConsensusSet) applyUntilBlock(tx *bolt.Tx, pb *processedBlock) (applie
 		if block.DiffsGenerated {
 			commitDiffSet(tx, block, modules.DiffApply)
 		} else {
-			err := generateAndApplyDiff(tx, block)
+			err := generateAndApplyDiffs(tx, block, cs.blockTxnValidation(tx, block, extendsPath))
+			cs.blockCache.evict(tx, block.Block.ID())
 			if err != nil {
 				// Mark the block as invalid.
-				cs.dosBlocks[block.Block.ID()] = struct{}{}
+				cs.dosBlocks.add(block.Block.ID())
 				return nil, err
 			}
 		}
@@ -112,7 +115,7 @@ func (cs *ConsensusSet) applyUntilBlock(tx *bolt.Tx, pb *processedBlock) (applie
 func (cs *ConsensusSet) forkBlockchain(tx *bolt.Tx, newBlock *processedBlock) (revertedBlocks, appliedBlocks []*processedBlock, err error) {
 	commonParent := backtrackToCurrentPath(tx, newBlock)[0]
 	revertedBlocks = cs.revertToBlock(tx, commonParent)
-	appliedBlocks, err = cs.applyUntilBlock(tx, newBlock)
+	appliedBlocks, err = cs.applyUntilBlock(tx, newBlock, len(revertedBlocks) == 0)
 	if err != nil {
 		return nil, nil, err
 	}
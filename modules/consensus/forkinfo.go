@@ -0,0 +1,138 @@
+package consensus
+
+import (
+	"encoding/binary"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+const (
+	// maxForkDepth is the number of blocks below the current height that a
+	// fork tip can be and still be reported by Forks. Older forks can no
+	// longer cause a reorg in practice, and skipping them bounds the number
+	// of blocks that are read to find the fork tips.
+	maxForkDepth = 144
+)
+
+var (
+	// BlocksByHeight is a database bucket that indexes every block in the
+	// block map by its height, so that the recent blocks can be found without
+	// scanning the whole block map. The keys are the big-endian height of a
+	// block followed by its id, the values are empty.
+	BlocksByHeight = []byte("BlocksByHeight")
+)
+
+// blocksByHeightKey returns the key of a block in the BlocksByHeight bucket.
+func blocksByHeightKey(height types.BlockHeight, id types.BlockID) []byte {
+	key := make([]byte, 8+len(id))
+	binary.BigEndian.PutUint64(key, uint64(height))
+	copy(key[8:], id[:])
+	return key
+}
+
+// addBlockHeight adds a block to the BlocksByHeight index.
+func addBlockHeight(tx *bolt.Tx, height types.BlockHeight, id types.BlockID) {
+	err := tx.Bucket(BlocksByHeight).Put(blocksByHeightKey(height, id), []byte{})
+	if build.DEBUG && err != nil {
+		panic(err)
+	}
+}
+
+// removeBlockHeight removes a block from the BlocksByHeight index.
+func removeBlockHeight(tx *bolt.Tx, height types.BlockHeight, id types.BlockID) error {
+	return tx.Bucket(BlocksByHeight).Delete(blocksByHeightKey(height, id))
+}
+
+// forkNode is the prefix of an encoded processedBlock. Decoding only the
+// prefix avoids decoding the diffs of every block in the block map.
+type forkNode struct {
+	Block  types.Block
+	Height types.BlockHeight
+	Depth  types.Target
+}
+
+// findForks returns the tips of all forks in the block map that branch off
+// from the current path and have a height of at least 'minHeight'. Only the
+// blocks at or above 'minHeight' are read, using the BlocksByHeight index.
+func findForks(tx *bolt.Tx, minHeight types.BlockHeight) ([]modules.ForkInfo, error) {
+	// Collect the recent blocks, along with the set of blocks that have at
+	// least one child.
+	nodes := make(map[types.BlockID]forkNode)
+	hasChild := make(map[types.BlockID]struct{})
+	blockMap := tx.Bucket(BlockMap)
+	c := tx.Bucket(BlocksByHeight).Cursor()
+	for k, _ := c.Seek(blocksByHeightKey(minHeight, types.BlockID{})); k != nil; k, _ = c.Next() {
+		var id types.BlockID
+		copy(id[:], k[8:])
+		var fn forkNode
+		if err := encoding.Unmarshal(blockMap.Get(id[:]), &fn); err != nil {
+			return nil, err
+		}
+		nodes[id] = fn
+		hasChild[fn.Block.ParentID] = struct{}{}
+	}
+
+	var forks []modules.ForkInfo
+	for id, fn := range nodes {
+		if _, exists := hasChild[id]; exists {
+			continue
+		}
+		if pathID, err := getPath(tx, fn.Height); err == nil && pathID == id {
+			continue
+		}
+
+		// Trace the fork back to the current path.
+		ancestorID, ancestorHeight := fn.Block.ParentID, fn.Height-1
+		for {
+			pathID, err := getPath(tx, ancestorHeight)
+			if err == nil && pathID == ancestorID {
+				break
+			}
+			ancestor, exists := nodes[ancestorID]
+			if !exists {
+				pb, err := getBlockMap(tx, ancestorID)
+				if err != nil {
+					return nil, err
+				}
+				ancestor = forkNode{Block: pb.Block, Height: pb.Height}
+			}
+			ancestorID, ancestorHeight = ancestor.Block.ParentID, ancestor.Height-1
+		}
+		forks = append(forks, modules.ForkInfo{
+			Tip:                  id,
+			Height:               fn.Height,
+			Work:                 fn.Depth.Difficulty(),
+			CommonAncestor:       ancestorID,
+			CommonAncestorHeight: ancestorHeight,
+		})
+	}
+	return forks, nil
+}
+
+// Forks returns the tips of the forks that branch off from the current path,
+// along with their total work and the block at which they branch off. Only
+// forks with a tip within maxForkDepth blocks of the current height are
+// reported.
+func (cs *ConsensusSet) Forks() (forks []modules.ForkInfo, err error) {
+	if err := cs.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer cs.tg.Done()
+
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		var minHeight types.BlockHeight
+		if height := blockHeight(tx); height > maxForkDepth {
+			minHeight = height - maxForkDepth
+		}
+		forks, err = findForks(tx, minHeight)
+		return err
+	})
+	return forks, err
+}
@@ -0,0 +1,62 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestForks checks that Forks reports a fork that branches off from the
+// current path.
+func TestForks(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cstMain, err := createConsensusSetTester(t.Name() + "-main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cstMain.Close()
+	cstAlt, err := createConsensusSetTester(t.Name() + "-alt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cstAlt.Close()
+
+	forks, err := cstMain.cs.Forks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(forks) != 0 {
+		t.Fatal("consensus set reports forks before any were submitted:", forks)
+	}
+
+	// Submit a fork that is not heavy enough to cause a reorg.
+	mainTip := cstMain.cs.CurrentBlock().ID()
+	for i := types.BlockHeight(1); i < cstAlt.cs.Height(); i++ {
+		b, _ := cstAlt.cs.BlockAtHeight(i)
+		_ = cstMain.cs.AcceptBlock(b)
+	}
+	if cstMain.cs.CurrentBlock().ID() != mainTip {
+		t.Fatal("fork caused a reorg")
+	}
+
+	forks, err = cstMain.cs.Forks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(forks) != 1 {
+		t.Fatal("expected 1 fork, got", len(forks))
+	}
+	altTip, _ := cstAlt.cs.BlockAtHeight(cstAlt.cs.Height() - 1)
+	if forks[0].Tip != altTip.ID() || forks[0].Height != cstAlt.cs.Height()-1 {
+		t.Error("fork has the wrong tip")
+	}
+	if forks[0].CommonAncestor != types.GenesisID || forks[0].CommonAncestorHeight != 0 {
+		t.Error("fork has the wrong common ancestor")
+	}
+	if forks[0].Work.IsZero() {
+		t.Error("fork has no work")
+	}
+}
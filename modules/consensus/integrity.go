@@ -0,0 +1,119 @@
+package consensus
+
+// integrity.go checks that the height index (the BlockHeight and BlockPath
+// buckets) agrees with the block map. The two are always updated in the same
+// transaction, but a partial write to disk can leave them out of sync, which
+// would cause currentProcessedBlock to return a stale tip and addBlockToTree to
+// make fork decisions against the wrong block.
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+var (
+	errBrokenPath        = errors.New("block path contains a block that is not the child of the previous block")
+	errHeavierFork       = errors.New("block map contains a fork that is heavier than the current path")
+	errHeightMismatch    = errors.New("block path contains a block whose height does not match its index")
+	errMissingPathBlock  = errors.New("block path contains a block that is not in the block map")
+	errPathBeyondHeight  = errors.New("block path extends beyond the current block height")
+	errPathShorterHeight = errors.New("block path ends before the current block height")
+)
+
+// checkPathEntry checks that the block at the given height of the block path
+// is in the block map at the same height, and is the child of the block below
+// it in the path.
+func checkPathEntry(tx *bolt.Tx, height types.BlockHeight) (*processedBlock, error) {
+	id, err := getPath(tx, height)
+	if err != nil {
+		return nil, errPathShorterHeight
+	}
+	pb, err := getBlockMap(tx, id)
+	if err != nil {
+		return nil, errMissingPathBlock
+	}
+	if pb.Height != height {
+		return nil, errHeightMismatch
+	}
+	if height > 0 {
+		parentID, err := getPath(tx, height-1)
+		if err != nil || parentID != pb.Block.ParentID {
+			return nil, errBrokenPath
+		}
+	}
+	return pb, nil
+}
+
+// checkTipIntegrity checks that the block path ends exactly at the current
+// block height, and that the current block agrees with the block map. The
+// check is cheap enough to be run every time the database is loaded.
+func checkTipIntegrity(tx *bolt.Tx) error {
+	height := blockHeight(tx)
+	if tx.Bucket(BlockPath).Get(encoding.Marshal(height+1)) != nil {
+		return errPathBeyondHeight
+	}
+	_, err := checkPathEntry(tx, height)
+	return err
+}
+
+// checkPathIntegrity checks every block in the block path against the block
+// map, and then checks that no block in the block map is heavier than the
+// current block. The check reads the entire block map, and is therefore only
+// run on request.
+func checkPathIntegrity(tx *bolt.Tx) error {
+	err := checkTipIntegrity(tx)
+	if err != nil {
+		return err
+	}
+	height := blockHeight(tx)
+	for i := types.BlockHeight(0); i < height; i++ {
+		_, err := checkPathEntry(tx, i)
+		if err != nil {
+			return err
+		}
+	}
+
+	tip := currentProcessedBlock(tx)
+	return tx.Bucket(BlockMap).ForEach(func(_, pbBytes []byte) error {
+		var pb processedBlock
+		err := encoding.Unmarshal(pbBytes, &pb)
+		if err != nil {
+			return err
+		}
+		if pb.heavierThan(tip) {
+			return errHeavierFork
+		}
+		return nil
+	})
+}
+
+// VerifyIntegrity checks that the height index of the consensus database
+// agrees with the block map, and that the coin supply agrees with the siacoins
+// in the consensus set. If a discrepancy is found, the consensus set
+// refuses to accept new blocks until a later call to VerifyIntegrity succeeds.
+func (cs *ConsensusSet) VerifyIntegrity() error {
+	err := cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		err := checkPathIntegrity(tx)
+		if err != nil {
+			return err
+		}
+		return checkCoinSupply(tx)
+	})
+	if err != nil {
+		cs.log.Println("ERROR: consensus database integrity check failed:", err)
+	}
+	cs.integrityErr = err
+	return err
+}
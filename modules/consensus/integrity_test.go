@@ -0,0 +1,96 @@
+package consensus
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/gateway"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+// setBlockHeight overwrites the block height in the database without updating
+// the block path, simulating a partial write.
+func (cs *ConsensusSet) setBlockHeight(height types.BlockHeight) error {
+	return cs.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(BlockHeight).Put(BlockHeight, encoding.Marshal(height))
+	})
+}
+
+// TestVerifyIntegrity checks that VerifyIntegrity detects a height index that
+// disagrees with the block path, and that blocks are refused until the
+// discrepancy is resolved.
+func TestVerifyIntegrity(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	if err := cst.cs.VerifyIntegrity(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Roll back the block height without popping the block path.
+	height := cst.cs.Height()
+	if err := cst.cs.setBlockHeight(height - 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := cst.cs.VerifyIntegrity(); err != errPathBeyondHeight {
+		t.Fatal("expected errPathBeyondHeight, got", err)
+	}
+	if _, err := cst.miner.AddBlock(); err != errPathBeyondHeight {
+		t.Fatal("block was accepted by a corrupt consensus set:", err)
+	}
+
+	// Once the height is restored, blocks should be accepted again.
+	if err := cst.cs.setBlockHeight(height); err != nil {
+		t.Fatal(err)
+	}
+	if err := cst.cs.VerifyIntegrity(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestIntegrityCheckOnLoad checks that a corrupt height index is detected when
+// the consensus set is loaded.
+func TestIntegrityCheckOnLoad(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	if err := cst.cs.setBlockHeight(cst.cs.Height() + 1); err != nil {
+		t.Fatal(err)
+	}
+	cst.cs.Close()
+
+	g, err := gateway.New("localhost:0", false, build.TempDir(modules.ConsensusDir, t.Name(), modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cst.cs, err = New(g, false, filepath.Join(cst.persistDir, modules.ConsensusDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := cst.miner.FindBlock()
+	if err := cst.cs.AcceptBlock(b); err != errPathShorterHeight {
+		t.Fatal("expected errPathShorterHeight, got", err)
+	}
+}
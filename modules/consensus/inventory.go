@@ -0,0 +1,118 @@
+package consensus
+
+// inventory.go tracks which blocks each peer is known to have. Blocks are
+// announced to peers by relaying their header (the RelayHeader RPC), and peers
+// that do not have the block request it with the SendBlk RPC. The inventory
+// prevents headers from being announced to peers that already have the block,
+// and prevents the same block from being requested from several peers at once.
+
+import (
+	"sync"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// maxPeerInventory is the number of block ids that are remembered for
+	// each peer. Once the limit is reached, the oldest ids are forgotten.
+	maxPeerInventory = build.Select(build.Var{
+		Standard: 1000,
+		Dev:      100,
+		Testing:  20,
+	}).(int)
+)
+
+// peerInventory is the set of blocks that a single peer is known to have,
+// along with the order in which they were added so that the oldest can be
+// evicted.
+type peerInventory struct {
+	ids   map[types.BlockID]struct{}
+	order []types.BlockID
+}
+
+// blockInventory tracks the blocks known to each peer and the blocks that are
+// currently being requested from a peer.
+type blockInventory struct {
+	peers     map[modules.NetAddress]*peerInventory
+	requested map[types.BlockID]modules.NetAddress
+	mu        sync.Mutex
+}
+
+// newBlockInventory returns an empty blockInventory.
+func newBlockInventory() *blockInventory {
+	return &blockInventory{
+		peers:     make(map[modules.NetAddress]*peerInventory),
+		requested: make(map[types.BlockID]modules.NetAddress),
+	}
+}
+
+// markSeen records that the peer at addr has the block with the provided id.
+func (bi *blockInventory) markSeen(addr modules.NetAddress, id types.BlockID) {
+	bi.mu.Lock()
+	defer bi.mu.Unlock()
+	pi, ok := bi.peers[addr]
+	if !ok {
+		pi = &peerInventory{ids: make(map[types.BlockID]struct{})}
+		bi.peers[addr] = pi
+	}
+	if _, ok := pi.ids[id]; ok {
+		return
+	}
+	pi.ids[id] = struct{}{}
+	pi.order = append(pi.order, id)
+	if len(pi.order) > maxPeerInventory {
+		delete(pi.ids, pi.order[0])
+		pi.order = pi.order[1:]
+	}
+}
+
+// hasSeen returns true if the peer at addr is known to have the block with the
+// provided id.
+func (bi *blockInventory) hasSeen(addr modules.NetAddress, id types.BlockID) bool {
+	bi.mu.Lock()
+	defer bi.mu.Unlock()
+	pi, ok := bi.peers[addr]
+	if !ok {
+		return false
+	}
+	_, ok = pi.ids[id]
+	return ok
+}
+
+// startRequest records that the block with the provided id is being requested
+// from the peer at addr. It returns false if the block is already being
+// requested, in which case the caller should not request it again.
+func (bi *blockInventory) startRequest(id types.BlockID, addr modules.NetAddress) bool {
+	bi.mu.Lock()
+	defer bi.mu.Unlock()
+	if _, ok := bi.requested[id]; ok {
+		return false
+	}
+	bi.requested[id] = addr
+	return true
+}
+
+// finishRequest records that a request started by startRequest has completed,
+// successfully or not, allowing the block to be requested again.
+func (bi *blockInventory) finishRequest(id types.BlockID) {
+	bi.mu.Lock()
+	defer bi.mu.Unlock()
+	delete(bi.requested, id)
+}
+
+// prune forgets the inventory of every peer that is not in the provided list.
+func (bi *blockInventory) prune(peers []modules.Peer) {
+	connected := make(map[modules.NetAddress]struct{}, len(peers))
+	for _, p := range peers {
+		connected[p.NetAddress] = struct{}{}
+	}
+	bi.mu.Lock()
+	defer bi.mu.Unlock()
+	for addr := range bi.peers {
+		if _, ok := connected[addr]; !ok {
+			delete(bi.peers, addr)
+		}
+	}
+}
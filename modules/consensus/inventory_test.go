@@ -0,0 +1,113 @@
+package consensus
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestBlockInventory probes the seen and requested tracking of the
+// blockInventory.
+func TestBlockInventory(t *testing.T) {
+	bi := newBlockInventory()
+	const addr1, addr2 = modules.NetAddress("1.1.1.1:1"), modules.NetAddress("2.2.2.2:2")
+	id := types.BlockID{1}
+
+	bi.markSeen(addr1, id)
+	if !bi.hasSeen(addr1, id) || bi.hasSeen(addr2, id) {
+		t.Fatal("block was not attributed to the right peer")
+	}
+
+	// Only one request for a block may be in flight at a time.
+	if !bi.startRequest(id, addr1) {
+		t.Fatal("first request was refused")
+	}
+	if bi.startRequest(id, addr2) {
+		t.Fatal("duplicate request was allowed")
+	}
+	bi.finishRequest(id)
+	if !bi.startRequest(id, addr2) {
+		t.Fatal("request was refused after the previous one finished")
+	}
+
+	// The oldest ids are evicted once the limit is reached.
+	inventoryID := func(i int) (id types.BlockID) {
+		id[0] = 2
+		binary.LittleEndian.PutUint64(id[1:], uint64(i))
+		return id
+	}
+	for i := 0; i < maxPeerInventory; i++ {
+		bi.markSeen(addr1, inventoryID(i))
+	}
+	if bi.hasSeen(addr1, id) {
+		t.Fatal("oldest id was not evicted")
+	}
+	if !bi.hasSeen(addr1, inventoryID(maxPeerInventory-1)) {
+		t.Fatal("newest id was evicted")
+	}
+
+	// Pruning forgets disconnected peers.
+	bi.markSeen(addr2, id)
+	bi.prune([]modules.Peer{{NetAddress: addr2}})
+	if bi.hasSeen(addr1, inventoryID(maxPeerInventory-1)) || !bi.hasSeen(addr2, id) {
+		t.Fatal("prune removed the wrong peers")
+	}
+}
+
+// TestInventoryRelay checks that a relayed block is recorded as known by the
+// peer that announced it, so that it is not announced back to that peer.
+func TestInventoryRelay(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst1, err := createConsensusSetTester(t.Name() + "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst1.Close()
+	cst2, err := blankConsensusSetTester(t.Name()+"2", modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst2.Close()
+	if err := cst2.gateway.Connect(cst1.gateway.Address()); err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		if cst2.cs.CurrentBlock().ID() != cst1.cs.CurrentBlock().ID() {
+			return errors.New("consensus sets did not synchronize")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Mine a block on cst1. cst1 has announced it to cst2, and cst2 should
+	// know that cst1 has it once the block is accepted.
+	b, err := cst1.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cst1.cs.inventory.hasSeen(cst2.gateway.Address(), b.ID()) {
+		t.Fatal("announced block was not recorded in the announcer's inventory")
+	}
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		if cst2.cs.CurrentBlock().ID() != b.ID() {
+			return errors.New("block was not relayed")
+		}
+		if !cst2.cs.inventory.hasSeen(cst1.gateway.Address(), b.ID()) {
+			return errors.New("relayed block was not recorded in the receiver's inventory")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,88 @@
+package consensus
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// maxOrphans is the number of orphan blocks that are remembered while
+	// they wait for their parents.
+	maxOrphans = build.Select(build.Var{
+		Standard: 1000,
+		Dev:      100,
+		Testing:  10,
+	}).(int)
+)
+
+// orphanPool tracks the blocks that were received before their parents. A
+// block leaves the pool once it is added to the block tree. When the pool is
+// full, the oldest orphan is evicted.
+type orphanPool struct {
+	capacity int
+	elems    map[types.BlockID]*list.Element
+	order    *list.List // most recently received first
+	seen     uint64
+	mu       sync.Mutex
+}
+
+// newOrphanPool returns an empty orphanPool that holds up to capacity blocks.
+func newOrphanPool(capacity int) *orphanPool {
+	return &orphanPool{
+		capacity: capacity,
+		elems:    make(map[types.BlockID]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// add records that a block was received before its parent.
+func (p *orphanPool) add(id types.BlockID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.seen++
+	if e, exists := p.elems[id]; exists {
+		p.order.MoveToFront(e)
+		return
+	}
+	p.elems[id] = p.order.PushFront(id)
+	for p.order.Len() > p.capacity {
+		delete(p.elems, p.order.Remove(p.order.Back()).(types.BlockID))
+	}
+}
+
+// remove removes blocks that are no longer orphans from the pool.
+func (p *orphanPool) remove(ids []types.BlockID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, id := range ids {
+		if e, exists := p.elems[id]; exists {
+			p.order.Remove(e)
+			delete(p.elems, id)
+		}
+	}
+}
+
+// RecentOrphans returns the ids of the blocks that were received before their
+// parents and are still waiting for them, most recently received first. Only
+// the most recent orphans are remembered.
+func (cs *ConsensusSet) RecentOrphans() []types.BlockID {
+	cs.orphans.mu.Lock()
+	defer cs.orphans.mu.Unlock()
+	ids := make([]types.BlockID, 0, cs.orphans.order.Len())
+	for e := cs.orphans.order.Front(); e != nil; e = e.Next() {
+		ids = append(ids, e.Value.(types.BlockID))
+	}
+	return ids
+}
+
+// OrphansSeen returns the number of orphan blocks that the consensus set has
+// received since it was started, including orphans that were received more
+// than once.
+func (cs *ConsensusSet) OrphansSeen() uint64 {
+	cs.orphans.mu.Lock()
+	defer cs.orphans.mu.Unlock()
+	return cs.orphans.seen
+}
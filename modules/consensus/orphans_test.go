@@ -0,0 +1,79 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestOrphanPool checks that the orphan pool evicts the oldest orphans and
+// forgets orphans once they are removed.
+func TestOrphanPool(t *testing.T) {
+	p := newOrphanPool(2)
+	ids := []types.BlockID{{1}, {2}, {3}}
+	for _, id := range ids {
+		p.add(id)
+	}
+	p.add(ids[2])
+	if p.order.Len() != 2 || p.elems[ids[0]] != nil {
+		t.Fatal("oldest orphan was not evicted")
+	}
+	if p.seen != 4 {
+		t.Fatal("expected 4 orphans to be seen, got", p.seen)
+	}
+	p.remove([]types.BlockID{ids[1], ids[0]})
+	if p.order.Len() != 1 || p.order.Front().Value.(types.BlockID) != ids[2] {
+		t.Fatal("orphan was not removed")
+	}
+}
+
+// TestRecentOrphans checks that blocks received before their parents are
+// reported until their parents arrive.
+func TestRecentOrphans(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+	cstAlt, err := createConsensusSetTester(t.Name() + "-alt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cstAlt.Close()
+	for cstAlt.cs.Height() <= cst.cs.Height() {
+		if _, err := cstAlt.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The tip of the other chain is an orphan.
+	tip := cstAlt.cs.CurrentBlock()
+	if err := cst.cs.AcceptBlock(tip); err != errOrphan {
+		t.Fatal("expected errOrphan, got", err)
+	}
+	if orphans := cst.cs.RecentOrphans(); len(orphans) != 1 || orphans[0] != tip.ID() {
+		t.Fatal("orphan was not reported:", orphans)
+	}
+	if n := cst.cs.OrphansSeen(); n != 1 {
+		t.Fatal("expected 1 orphan to be seen, got", n)
+	}
+
+	// Once the rest of the chain arrives, the block is no longer an orphan.
+	for i := types.BlockHeight(1); i <= cstAlt.cs.Height(); i++ {
+		b, _ := cstAlt.cs.BlockAtHeight(i)
+		_ = cst.cs.AcceptBlock(b)
+	}
+	if cst.cs.CurrentBlock().ID() != tip.ID() {
+		t.Fatal("consensus set did not reorg")
+	}
+	if orphans := cst.cs.RecentOrphans(); len(orphans) != 0 {
+		t.Fatal("block is still reported as an orphan:", orphans)
+	}
+	if n := cst.cs.OrphansSeen(); n != 1 {
+		t.Fatal("expected 1 orphan to be seen, got", n)
+	}
+}
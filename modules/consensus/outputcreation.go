@@ -0,0 +1,111 @@
+package consensus
+
+// outputcreation.go maintains an index from siacoin output ids to the block
+// that created the output. The index is updated whenever a block is applied or
+// reverted, and entries are kept after the output is spent, which allows the
+// provenance of historic outputs to be traced.
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+var (
+	// OutputCreations is a database bucket that maps siacoin output ids to
+	// the block that created the output. Outputs are not removed from the
+	// bucket when they are spent, only when the creating block is reverted.
+	OutputCreations = []byte("OutputCreations")
+
+	errOutputCreationNotFound = errors.New("no block is known to have created the requested output")
+)
+
+// outputCreation is the value stored in the OutputCreations bucket.
+type outputCreation struct {
+	BlockID types.BlockID
+	Height  types.BlockHeight
+}
+
+// createdOutputIDs returns the ids of all siacoin outputs that were created by
+// the processed block. Delayed outputs are attributed to the block that
+// created them rather than the block in which they matured.
+func createdOutputIDs(pb *processedBlock) []types.SiacoinOutputID {
+	matured := make(map[types.SiacoinOutputID]struct{})
+	var ids []types.SiacoinOutputID
+	for _, dscod := range pb.DelayedSiacoinOutputDiffs {
+		if dscod.Direction == modules.DiffApply {
+			ids = append(ids, dscod.ID)
+		} else {
+			matured[dscod.ID] = struct{}{}
+		}
+	}
+	for _, scod := range pb.SiacoinOutputDiffs {
+		if scod.Direction != modules.DiffApply {
+			continue
+		}
+		if _, exists := matured[scod.ID]; exists {
+			continue
+		}
+		ids = append(ids, scod.ID)
+	}
+	return ids
+}
+
+// commitOutputCreations adds the outputs created by a block to the output
+// creation index when the block is applied, and removes them when the block is
+// reverted.
+func commitOutputCreations(tx *bolt.Tx, pb *processedBlock, dir modules.DiffDirection) {
+	b := tx.Bucket(OutputCreations)
+	oc := encoding.Marshal(outputCreation{
+		BlockID: pb.Block.ID(),
+		Height:  pb.Height,
+	})
+	for _, id := range createdOutputIDs(pb) {
+		var err error
+		if dir == modules.DiffApply {
+			err = b.Put(id[:], oc)
+		} else {
+			err = b.Delete(id[:])
+		}
+		if build.DEBUG && err != nil {
+			panic(err)
+		}
+	}
+}
+
+// getOutputCreation returns the id and height of the block that created the
+// siacoin output with the provided id.
+func getOutputCreation(tx *bolt.Tx, id types.SiacoinOutputID) (types.BlockID, types.BlockHeight, error) {
+	ocBytes := tx.Bucket(OutputCreations).Get(id[:])
+	if ocBytes == nil {
+		return types.BlockID{}, 0, errOutputCreationNotFound
+	}
+	var oc outputCreation
+	err := encoding.Unmarshal(ocBytes, &oc)
+	if build.DEBUG && err != nil {
+		panic(err)
+	}
+	return oc.BlockID, oc.Height, nil
+}
+
+// OutputCreationBlock returns the id and height of the block that created the
+// siacoin output with the provided id. Outputs that have already been spent
+// are still reported, so long as the creating block is in the current path.
+func (cs *ConsensusSet) OutputCreationBlock(id types.SiacoinOutputID) (bid types.BlockID, height types.BlockHeight, err error) {
+	err = cs.tg.Add()
+	if err != nil {
+		return types.BlockID{}, 0, err
+	}
+	defer cs.tg.Done()
+
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		bid, height, err = getOutputCreation(tx, id)
+		return err
+	})
+	return bid, height, err
+}
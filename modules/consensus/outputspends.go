@@ -0,0 +1,83 @@
+package consensus
+
+// outputspends.go maintains an index from siacoin output ids to the
+// transaction that spent the output. The index is updated whenever a block is
+// applied or reverted, so spends that were rolled back by a reorg are no
+// longer reported.
+
+import (
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+var (
+	// OutputSpends is a database bucket that maps siacoin output ids to the
+	// transaction in the current path that spent the output.
+	OutputSpends = []byte("OutputSpends")
+)
+
+// outputSpend is the value stored in the OutputSpends bucket.
+type outputSpend struct {
+	Height        types.BlockHeight
+	TransactionID types.TransactionID
+}
+
+// commitOutputSpends adds the outputs spent by a block to the output spend
+// index when the block is applied, and removes them when the block is
+// reverted.
+func commitOutputSpends(tx *bolt.Tx, pb *processedBlock, dir modules.DiffDirection) {
+	b := tx.Bucket(OutputSpends)
+	for _, txn := range pb.Block.Transactions {
+		os := encoding.Marshal(outputSpend{
+			Height:        pb.Height,
+			TransactionID: txn.ID(),
+		})
+		for _, sci := range txn.SiacoinInputs {
+			var err error
+			if dir == modules.DiffApply {
+				err = b.Put(sci.ParentID[:], os)
+			} else {
+				err = b.Delete(sci.ParentID[:])
+			}
+			if build.DEBUG && err != nil {
+				panic(err)
+			}
+		}
+	}
+}
+
+// getOutputSpend returns the height and id of the transaction that spent the
+// siacoin output with the provided id.
+func getOutputSpend(tx *bolt.Tx, id types.SiacoinOutputID) (spent bool, height types.BlockHeight, txid types.TransactionID) {
+	osBytes := tx.Bucket(OutputSpends).Get(id[:])
+	if osBytes == nil {
+		return false, 0, types.TransactionID{}
+	}
+	var os outputSpend
+	err := encoding.Unmarshal(osBytes, &os)
+	if build.DEBUG && err != nil {
+		panic(err)
+	}
+	return true, os.Height, os.TransactionID
+}
+
+// OutputSpentAtHeight reports whether the siacoin output with the provided id
+// has been spent in the current path, along with the height and id of the
+// spending transaction. Unspent and unknown outputs are reported as unspent.
+func (cs *ConsensusSet) OutputSpentAtHeight(id types.SiacoinOutputID) (spent bool, spendHeight types.BlockHeight, spendTxID types.TransactionID, err error) {
+	err = cs.tg.Add()
+	if err != nil {
+		return false, 0, types.TransactionID{}, err
+	}
+	defer cs.tg.Done()
+
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		spent, spendHeight, spendTxID = getOutputSpend(tx, id)
+		return nil
+	})
+	return spent, spendHeight, spendTxID, err
+}
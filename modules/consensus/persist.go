@@ -47,6 +47,15 @@ func (cs *ConsensusSet) loadDB() error {
 			return err
 		}
 
+		// Older consensus databases will not have the output creation and
+		// spend indices, the transaction count, offsets and heights, or the
+		// coin supply, so they are created and filled separately from
+		// 'initDB'.
+		err = backfillIndices(tx)
+		if err != nil {
+			return err
+		}
+
 		// Check that the genesis block is correct - typically only incorrect
 		// in the event of developer binaries vs. release binaires.
 		genesisID, err := getPath(tx, 0)
@@ -89,8 +98,23 @@ func (cs *ConsensusSet) initPersist() error {
 	if err != nil {
 		return err
 	}
+	cs.refreshSiafundPool()
+	// Check that the height index agrees with the block map. A mismatch
+	// indicates a partial write, and blocks will be refused until the
+	// discrepancy has been resolved.
+	cs.integrityErr = cs.db.View(func(tx *bolt.Tx) error {
+		return checkTipIntegrity(tx)
+	})
+	if cs.integrityErr != nil {
+		cs.log.Println("ERROR: consensus database integrity check failed:", cs.integrityErr)
+	}
 	// Set up the closing of the database.
 	cs.tg.AfterStop(func() {
+		if cs.changeLogFile != nil {
+			if err := cs.changeLogFile.Close(); err != nil {
+				cs.log.Println("ERROR: Unable to close the change log file at shutdown:", err)
+			}
+		}
 		err := cs.db.Close()
 		if err != nil {
 			cs.log.Println("ERROR: Unable to close consensus set database at shutdown:", err)
@@ -0,0 +1,41 @@
+package consensus
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// ErrBlockVetoed is returned when a block is rejected by the BlockPolicy
+	// of the consensus set.
+	ErrBlockVetoed = errors.New("block was rejected by the node's block policy")
+)
+
+// A BlockPolicy is a node-local filter on the blocks that the consensus set
+// accepts. It is consulted for every block after the block has passed header
+// and block validation, but before it is added to the block tree.
+//
+// A BlockPolicy does not change the consensus rules of the network. Other
+// nodes will still accept blocks that the policy rejects, so a node with a
+// policy can end up following a minority chain, or no chain at all if the
+// rest of the network builds on a rejected block. Rejected blocks are not
+// marked as invalid for DoS purposes, and may be submitted again.
+type BlockPolicy interface {
+	// CheckBlock returns a non-nil error if the block at the given height
+	// should not be accepted. The error is logged, and AcceptBlock returns
+	// ErrBlockVetoed.
+	CheckBlock(b types.Block, height types.BlockHeight) error
+}
+
+// checkBlockPolicy consults the block policy of the consensus set, if any.
+func (cs *ConsensusSet) checkBlockPolicy(b types.Block, id types.BlockID, height types.BlockHeight) error {
+	if cs.blockPolicy == nil {
+		return nil
+	}
+	if err := cs.blockPolicy.CheckBlock(b, height); err != nil {
+		cs.log.Printf("INFO: block %v at height %v was vetoed by the block policy: %v", id, height, err)
+		return ErrBlockVetoed
+	}
+	return nil
+}
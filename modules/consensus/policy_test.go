@@ -0,0 +1,86 @@
+package consensus
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/gateway"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// blacklistPolicy is a BlockPolicy that rejects blocks which create siacoin
+// outputs for a blacklisted address.
+type blacklistPolicy struct {
+	blacklisted types.UnlockHash
+}
+
+// CheckBlock implements the BlockPolicy interface.
+func (bp blacklistPolicy) CheckBlock(b types.Block, height types.BlockHeight) error {
+	for _, txn := range b.Transactions {
+		for _, sco := range txn.SiacoinOutputs {
+			if sco.UnlockHash == bp.blacklisted {
+				return errors.New("block pays a blacklisted address")
+			}
+		}
+	}
+	return nil
+}
+
+// TestBlockPolicy checks that a BlockPolicy can veto blocks, and that vetoed
+// blocks are not treated as DoS blocks.
+func TestBlockPolicy(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Create a second consensus set with a policy that blacklists an address.
+	blacklisted := randAddress()
+	testdir := build.TempDir(modules.ConsensusDir, t.Name()+"-cs2")
+	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	cs, err := NewConfiguredConsensusSet(g, false, filepath.Join(testdir, modules.ConsensusDir), modules.ProdDependencies, Config{
+		BlockPolicy: blacklistPolicy{blacklisted: blacklisted},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	// Blocks that don't pay the blacklisted address are accepted.
+	for i := types.BlockHeight(1); i <= cst.cs.Height(); i++ {
+		b, _ := cst.cs.BlockAtHeight(i)
+		if err := cs.AcceptBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A block that pays the blacklisted address is vetoed.
+	if _, err := cst.wallet.SendSiacoins(types.SiacoinPrecision, blacklisted); err != nil {
+		t.Fatal(err)
+	}
+	b, err := cst.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.AcceptBlock(b); err != ErrBlockVetoed {
+		t.Fatal("expected ErrBlockVetoed, got", err)
+	}
+	if cs.CurrentBlock().ID() != b.ParentID {
+		t.Fatal("vetoed block was added to the consensus set")
+	}
+	if cs.dosBlocks.contains(b.ID()) {
+		t.Fatal("vetoed block was marked as a DoS block")
+	}
+}
@@ -58,7 +58,7 @@ func (pb *processedBlock) childDepth() types.Target {
 
 // targetAdjustmentBase returns the magnitude that the target should be
 // adjusted by before a clamp is applied.
-func (cs *ConsensusSet) targetAdjustmentBase(blockMap *bolt.Bucket, pb *processedBlock) *big.Rat {
+func (cs *ConsensusSet) targetAdjustmentBase(blockMap dbBucket, pb *processedBlock) *big.Rat {
 	// Grab the block that was generated 'TargetWindow' blocks prior to the
 	// parent. If there are not 'TargetWindow' blocks yet, stop at the genesis
 	// block.
@@ -101,7 +101,7 @@ func clampTargetAdjustment(base *big.Rat) *big.Rat {
 
 // setChildTarget computes the target of a blockNode's child. All children of a node
 // have the same target.
-func (cs *ConsensusSet) setChildTarget(blockMap *bolt.Bucket, pb *processedBlock) {
+func (cs *ConsensusSet) setChildTarget(blockMap dbBucket, pb *processedBlock) {
 	// Fetch the parent block.
 	var parent processedBlock
 	parentBytes := blockMap.Get(pb.Block.ParentID[:])
@@ -119,6 +119,17 @@ func (cs *ConsensusSet) setChildTarget(blockMap *bolt.Bucket, pb *processedBlock
 	pb.ChildTarget = types.RatToTarget(adjustedRatTarget)
 }
 
+// setChildTargetAt sets the target of the children of 'child', using the
+// difficulty adjustment algorithm that is active at the height of 'parent'.
+// 'parentTotalTime' and 'parentTotalTarget' are the oak totals of 'parent'.
+func (cs *ConsensusSet) setChildTargetAt(blockMap dbBucket, parent, child *processedBlock, parentTotalTime int64, parentTotalTarget types.Target) {
+	if parent.Height < types.OakHardforkBlock {
+		cs.setChildTarget(blockMap, child)
+		return
+	}
+	child.ChildTarget = cs.childTargetOak(parentTotalTime, parentTotalTarget, parent.ChildTarget, parent.Height, parent.Block.Timestamp)
+}
+
 // newChild creates a blockNode from a block and adds it to the parent's set of
 // children. The new node is also returned. It necessarily modifies the database
 func (cs *ConsensusSet) newChild(tx *bolt.Tx, pb *processedBlock, b types.Block) *processedBlock {
@@ -141,14 +152,13 @@ func (cs *ConsensusSet) newChild(tx *bolt.Tx, pb *processedBlock, b types.Block)
 	// Use the difficulty adjustment algorithm to set the target of the child
 	// block and put the new processed block into the database.
 	blockMap := tx.Bucket(BlockMap)
-	if pb.Height < types.OakHardforkBlock {
-		cs.setChildTarget(blockMap, child)
-	} else {
-		child.ChildTarget = cs.childTargetOak(prevTotalTime, prevTotalTarget, pb.ChildTarget, pb.Height, pb.Block.Timestamp)
-	}
+	cs.setChildTargetAt(blockMap, pb, child, prevTotalTime, prevTotalTarget)
+	cs.blockCache.evict(tx, childID)
 	err = blockMap.Put(childID[:], encoding.Marshal(*child))
 	if build.DEBUG && err != nil {
 		panic(err)
 	}
+	addTransactionOffsets(tx, b)
+	addBlockHeight(tx, child.Height, childID)
 	return child
 }
@@ -0,0 +1,59 @@
+package consensus
+
+import (
+	"io"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/persist"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// benignRejection returns true if err indicates that a block was rejected for
+// a reason that does not imply that the block or its sender is misbehaving.
+func benignRejection(err error) bool {
+	return err == modules.ErrBlockKnown || err == modules.ErrNonExtendingBlock ||
+		err == ErrFutureTimestamp || err == errOrphan
+}
+
+// logRejectedBlock writes a block that was not added to the current path to
+// the rejected block log if the log level calls for it. Blocks that failed
+// validation are logged as rejected, blocks that were not added for a benign
+// reason, such as already being known or being on a side chain, are logged as
+// ignored. Only the id of the block is logged, never its contents. An empty
+// origin means that the block did not come from a peer. The caller must hold
+// cs.mu.
+func (cs *ConsensusSet) logRejectedBlock(id types.BlockID, origin modules.NetAddress, err error) {
+	benign := benignRejection(err)
+	switch cs.rejectLogLevel {
+	case modules.RejectedBlockLogOff:
+		return
+	case modules.RejectedBlockLogInvalid:
+		if benign {
+			return
+		}
+	}
+	peer := string(origin)
+	if peer == "" {
+		peer = "local"
+	}
+	if benign {
+		cs.rejectLog.Printf("ignored block %v from %v: %v", id, peer, err)
+		return
+	}
+	cs.rejectLog.Printf("rejected block %v from %v: %v", id, peer, err)
+}
+
+// SetRejectedBlockLog sets the writer that receives the id, origin peer, and
+// rejection reason of blocks rejected by the consensus set, at the given
+// verbosity. A nil writer disables the log.
+func (cs *ConsensusSet) SetRejectedBlockLog(w io.Writer, level modules.RejectedBlockLogLevel) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if w == nil || level == modules.RejectedBlockLogOff {
+		cs.rejectLog = nil
+		cs.rejectLogLevel = modules.RejectedBlockLogOff
+		return
+	}
+	cs.rejectLog = persist.NewLogger(w)
+	cs.rejectLogLevel = level
+}
@@ -0,0 +1,75 @@
+package consensus
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestRejectedBlockLog checks that rejected blocks are logged with their id,
+// origin, and reason at the configured verbosity.
+func TestRejectedBlockLog(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Create an invalid block and a block that is already known.
+	invalid, target, err := cst.miner.BlockForWork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalid.MinerPayouts = append(invalid.MinerPayouts, types.SiacoinOutput{Value: types.SiacoinPrecision})
+	invalid, _ = cst.miner.SolveBlock(invalid, target)
+	known := cst.cs.CurrentBlock()
+	const origin = modules.NetAddress("1.2.3.4:9981")
+
+	// Nothing is logged while the log is disabled.
+	var buf bytes.Buffer
+	cst.cs.SetRejectedBlockLog(&buf, modules.RejectedBlockLogOff)
+	cst.cs.managedAcceptBlocksFrom([]types.Block{invalid}, origin)
+	if buf.Len() != 0 {
+		t.Fatal("disabled log was written to:", buf.String())
+	}
+
+	// Invalid blocks are logged with their origin, known blocks are not.
+	cst.cs.SetRejectedBlockLog(&buf, modules.RejectedBlockLogInvalid)
+	cst.cs.managedAcceptBlocksFrom([]types.Block{invalid}, origin)
+	cst.cs.managedAcceptBlocksFrom([]types.Block{known}, origin)
+	out := buf.String()
+	if !strings.Contains(out, "rejected block "+invalid.ID().String()) || !strings.Contains(out, string(origin)) || !strings.Contains(out, errBadMinerPayouts.Error()) {
+		t.Fatal("invalid block was not logged:", out)
+	}
+	if strings.Contains(out, known.ID().String()) {
+		t.Fatal("known block was logged at the invalid level:", out)
+	}
+
+	// Known blocks are logged as ignored at the highest verbosity, and blocks
+	// without an origin are attributed to the local node.
+	buf.Reset()
+	cst.cs.SetRejectedBlockLog(&buf, modules.RejectedBlockLogAll)
+	cst.cs.AcceptBlock(known)
+	out = buf.String()
+	if !strings.Contains(out, "ignored block "+known.ID().String()) || !strings.Contains(out, "local") || !strings.Contains(out, modules.ErrBlockKnown.Error()) {
+		t.Fatal("known block was not logged:", out)
+	}
+	if strings.Contains(out, "rejected") {
+		t.Fatal("known block was logged as rejected:", out)
+	}
+
+	// A nil writer disables the log.
+	cst.cs.SetRejectedBlockLog(nil, modules.RejectedBlockLogAll)
+	buf.Reset()
+	cst.cs.AcceptBlock(known)
+	if buf.Len() != 0 {
+		t.Fatal("log was written to after being disabled:", buf.String())
+	}
+}
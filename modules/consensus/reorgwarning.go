@@ -0,0 +1,132 @@
+package consensus
+
+import (
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+// reorgWarningSubscription is a channel that receives a warning whenever a
+// reorg reverts more than 'depth' blocks.
+type reorgWarningSubscription struct {
+	depth types.BlockHeight
+	ch    chan<- modules.ReorgWarning
+}
+
+// computeReorgWarning computes the reorg warning for a change entry.
+func computeReorgWarning(tx *bolt.Tx, ce changeEntry) (modules.ReorgWarning, error) {
+	rw := modules.ReorgWarning{
+		Depth:          types.BlockHeight(len(ce.RevertedBlocks)),
+		RevertedBlocks: ce.RevertedBlocks,
+		AppliedBlocks:  ce.AppliedBlocks,
+	}
+
+	// Collect everything that is in the applied blocks, so that it can be
+	// excluded from the warning.
+	appliedTxns := make(map[types.TransactionID]struct{})
+	appliedObjects := make(map[types.OutputID]struct{})
+	for _, id := range ce.AppliedBlocks {
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			return modules.ReorgWarning{}, err
+		}
+		for _, txn := range pb.Block.Transactions {
+			appliedTxns[txn.ID()] = struct{}{}
+		}
+		for _, scod := range pb.SiacoinOutputDiffs {
+			appliedObjects[types.OutputID(scod.ID)] = struct{}{}
+		}
+		for _, dscod := range pb.DelayedSiacoinOutputDiffs {
+			appliedObjects[types.OutputID(dscod.ID)] = struct{}{}
+		}
+		for _, sfod := range pb.SiafundOutputDiffs {
+			appliedObjects[types.OutputID(sfod.ID)] = struct{}{}
+		}
+		for _, fcd := range pb.FileContractDiffs {
+			appliedObjects[types.OutputID(fcd.ID)] = struct{}{}
+		}
+	}
+
+	for _, id := range ce.RevertedBlocks {
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			return modules.ReorgWarning{}, err
+		}
+		for _, txn := range pb.Block.Transactions {
+			if _, exists := appliedTxns[txn.ID()]; !exists {
+				rw.RevertedTransactions = append(rw.RevertedTransactions, txn)
+			}
+		}
+		for _, scid := range createdOutputIDs(pb) {
+			if _, exists := appliedObjects[types.OutputID(scid)]; !exists {
+				rw.RevertedSiacoinOutputs = append(rw.RevertedSiacoinOutputs, scid)
+			}
+		}
+		for _, sfod := range pb.SiafundOutputDiffs {
+			_, exists := appliedObjects[types.OutputID(sfod.ID)]
+			if sfod.Direction == modules.DiffApply && !exists {
+				rw.RevertedSiafundOutputs = append(rw.RevertedSiafundOutputs, sfod.ID)
+			}
+		}
+		for _, fcd := range pb.FileContractDiffs {
+			_, exists := appliedObjects[types.OutputID(fcd.ID)]
+			if fcd.Direction == modules.DiffApply && !exists {
+				rw.RevertedFileContracts = append(rw.RevertedFileContracts, fcd.ID)
+			}
+		}
+	}
+	return rw, nil
+}
+
+// updateReorgWarnings sends a reorg warning to every subscription whose depth
+// is exceeded by the change entry. Warnings are sent in a separate goroutine
+// so that a slow subscriber cannot block the consensus set.
+func (cs *ConsensusSet) updateReorgWarnings(ce changeEntry) {
+	depth := types.BlockHeight(len(ce.RevertedBlocks))
+	var subs []reorgWarningSubscription
+	for _, sub := range cs.reorgWarnings {
+		if depth > sub.depth {
+			subs = append(subs, sub)
+		}
+	}
+	if len(subs) == 0 {
+		return
+	}
+
+	var rw modules.ReorgWarning
+	err := cs.db.View(func(tx *bolt.Tx) error {
+		var err error
+		rw, err = computeReorgWarning(tx, ce)
+		return err
+	})
+	if err != nil {
+		cs.log.Critical("computeReorgWarning failed:", err)
+		return
+	}
+	cs.log.Printf("WARN: reorg reverted %v blocks and %v transactions\n", rw.Depth, len(rw.RevertedTransactions))
+	for _, sub := range subs {
+		go func(ch chan<- modules.ReorgWarning) {
+			if err := cs.tg.Add(); err != nil {
+				return
+			}
+			defer cs.tg.Done()
+			select {
+			case ch <- rw:
+			case <-cs.tg.StopChan():
+			}
+		}(sub.ch)
+	}
+}
+
+// SubscribeReorgWarning registers a channel that will receive a warning
+// whenever a reorg reverts more than 'depth' blocks. Reorgs that stay within
+// the threshold are not reported.
+func (cs *ConsensusSet) SubscribeReorgWarning(depth types.BlockHeight, ch chan<- modules.ReorgWarning) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.reorgWarnings = append(cs.reorgWarnings, reorgWarningSubscription{
+		depth: depth,
+		ch:    ch,
+	})
+}
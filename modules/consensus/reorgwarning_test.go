@@ -0,0 +1,93 @@
+package consensus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestReorgWarning checks that reorg warnings are sent only when a reorg
+// exceeds the subscribed depth, and that they report the reverted
+// transactions and outputs.
+func TestReorgWarning(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cstMain, err := createConsensusSetTester(t.Name() + "-main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cstMain.Close()
+	cstAlt, err := createConsensusSetTester(t.Name() + "-alt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cstAlt.Close()
+
+	// Confirm a transaction on the main chain.
+	txns, err := cstMain.wallet.SendSiacoins(types.SiacoinPrecision, randAddress())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cstMain.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	payment := txns[len(txns)-1]
+
+	mainHeight := cstMain.cs.Height()
+	deep := make(chan modules.ReorgWarning, 1)
+	shallow := make(chan modules.ReorgWarning, 1)
+	cstMain.cs.SubscribeReorgWarning(mainHeight-1, shallow)
+	cstMain.cs.SubscribeReorgWarning(mainHeight, deep)
+
+	// Reorg the main chain all the way back to the genesis block.
+	for cstAlt.cs.Height() <= mainHeight {
+		if _, err := cstAlt.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := types.BlockHeight(1); i <= cstAlt.cs.Height(); i++ {
+		b, _ := cstAlt.cs.BlockAtHeight(i)
+		_ = cstMain.cs.AcceptBlock(b)
+	}
+	if cstMain.cs.CurrentBlock().ID() != cstAlt.cs.CurrentBlock().ID() {
+		t.Fatal("main chain did not reorg")
+	}
+
+	var rw modules.ReorgWarning
+	select {
+	case rw = <-shallow:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reorg warning was sent")
+	}
+	select {
+	case <-deep:
+		t.Fatal("reorg warning was sent for a reorg within the threshold")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if rw.Depth != mainHeight || len(rw.RevertedBlocks) != int(mainHeight) {
+		t.Error("reorg warning has the wrong depth:", rw.Depth)
+	}
+	found := false
+	for _, txn := range rw.RevertedTransactions {
+		if txn.ID() == payment.ID() {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("reverted payment is not in the reorg warning")
+	}
+	found = false
+	for _, id := range rw.RevertedSiacoinOutputs {
+		if id == payment.SiacoinOutputID(0) {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("output of the reverted payment is not in the reorg warning")
+	}
+}
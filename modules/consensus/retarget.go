@@ -0,0 +1,78 @@
+package consensus
+
+import (
+	"time"
+
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+// retargetWindow returns the window of blocks in the current path that
+// determined the target of the child of the current block, along with the
+// timespan measured over that window.
+//
+// Before the oak hardfork, the target is adjusted every TargetWindow/2 blocks
+// based on the time between the adjusting block and its TargetWindow'th
+// ancestor, see targetAdjustmentBase. Until the first adjustment, the window is
+// empty. After the hardfork, the target is derived from the decayed total time
+// of the parent of the current block, which covers every block since the
+// totals were reset just before the hardfork, see childTargetOak.
+func (cs *ConsensusSet) retargetWindow(tx *bolt.Tx) (start, end types.BlockHeight, timespan time.Duration, err error) {
+	height := blockHeight(tx)
+	if height > types.OakHardforkBlock {
+		end = height - 1
+		id, err := getPath(tx, end)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		totalTime, _ := cs.getBlockTotals(tx, id)
+		return types.OakHardforkBlock - 1, end, time.Duration(totalTime) * time.Second, nil
+	}
+
+	end = height - height%(types.TargetWindow/2)
+	if end == 0 {
+		return 0, 0, 0, nil
+	}
+	start = 0
+	if end > types.TargetWindow {
+		start = end - types.TargetWindow
+	}
+	startID, err := getPath(tx, start)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	endID, err := getPath(tx, end)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	startBlock, err := getBlockMap(tx, startID)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	endBlock, err := getBlockMap(tx, endID)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	timespan = time.Duration(int64(endBlock.Block.Timestamp)-int64(startBlock.Block.Timestamp)) * time.Second
+	return start, end, timespan, nil
+}
+
+// RetargetWindow returns the heights of the first and last blocks of the
+// window that determined the target of the next block, and the timespan that
+// was measured over the window. After the oak hardfork, the timespan is the
+// exponentially decayed total time that the oak algorithm uses.
+func (cs *ConsensusSet) RetargetWindow() (start, end types.BlockHeight, timespan time.Duration, err error) {
+	err = cs.tg.Add()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer cs.tg.Done()
+
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		var err error
+		start, end, timespan, err = cs.retargetWindow(tx)
+		return err
+	})
+	return start, end, timespan, err
+}
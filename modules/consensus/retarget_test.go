@@ -0,0 +1,72 @@
+package consensus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+// TestRetargetWindow checks the retarget window before the first target
+// adjustment and after the oak hardfork, and that the reported timespan
+// reproduces the target of the next block.
+func TestRetargetWindow(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	_, cs, err := NewTestChain(TestChainParams{Name: t.Name()}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	// No adjustment has happened yet.
+	start, end, timespan, err := cs.RetargetWindow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start != 0 || end != 0 || timespan != 0 {
+		t.Fatal("expected an empty window, got", start, end, timespan)
+	}
+
+	// Move past the oak hardfork.
+	if _, err := ExtendTestChain(cs, cs.CurrentBlock().ID(), int(types.OakHardforkBlock), types.UnlockHash{}); err != nil {
+		t.Fatal(err)
+	}
+	start, end, timespan, err = cs.RetargetWindow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start != types.OakHardforkBlock-1 || end != cs.Height()-1 {
+		t.Fatal("wrong window:", start, end)
+	}
+
+	// Recompute the target of the next block from the timespan.
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		parentID, err := getPath(tx, end)
+		if err != nil {
+			return err
+		}
+		parent, err := getBlockMap(tx, parentID)
+		if err != nil {
+			return err
+		}
+		current := cs.CurrentBlock().ID()
+		pb, err := getBlockMap(tx, current)
+		if err != nil {
+			return err
+		}
+		_, totalTarget := cs.getBlockTotals(tx, parentID)
+		target := cs.childTargetOak(int64(timespan/time.Second), totalTarget, parent.ChildTarget, parent.Height, parent.Block.Timestamp)
+		if target != pb.ChildTarget {
+			t.Error("timespan does not reproduce the target of the next block")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,67 @@
+package consensus
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+var (
+	errSiafundPoolHeight = errors.New("cannot get the siafund pool above the current block height")
+)
+
+// refreshSiafundPool reloads the cached value of the siafund pool from
+// the database. The caller must hold cs.mu.
+func (cs *ConsensusSet) refreshSiafundPool() {
+	_ = cs.db.View(func(tx *bolt.Tx) error {
+		cs.siafundPool = getSiafundPool(tx)
+		return nil
+	})
+}
+
+// SiafundPool returns the current value of the siafund pool, which holds the
+// fees that are payable to siafund holders.
+func (cs *ConsensusSet) SiafundPool() types.Currency {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.siafundPool
+}
+
+// SiafundPoolAtHeight returns the value of the siafund pool after the block at
+// the given height in the current path was applied. Blocks that do not change
+// the pool have no pool diffs, so the path is searched backwards for the most
+// recent block that did.
+func (cs *ConsensusSet) SiafundPoolAtHeight(height types.BlockHeight) (pool types.Currency, err error) {
+	err = cs.tg.Add()
+	if err != nil {
+		return types.Currency{}, err
+	}
+	defer cs.tg.Done()
+
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		if height > blockHeight(tx) {
+			return errSiafundPoolHeight
+		}
+		for h := height; ; h-- {
+			id, err := getPath(tx, h)
+			if err != nil {
+				return err
+			}
+			pb, err := getBlockMap(tx, id)
+			if err != nil {
+				return err
+			}
+			if n := len(pb.SiafundPoolDiffs); n > 0 {
+				pool = pb.SiafundPoolDiffs[n-1].Adjusted
+				return nil
+			}
+			if h == 0 {
+				pool = types.ZeroCurrency
+				return nil
+			}
+		}
+	})
+	return pool, err
+}
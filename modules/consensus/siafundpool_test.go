@@ -0,0 +1,85 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestSiafundPool checks that the cached siafund pool follows the database as
+// file contracts are created, and that historic pool values can be queried.
+func TestSiafundPool(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	startHeight := cst.cs.Height()
+	startPool := cst.cs.SiafundPool()
+	if !startPool.Equals(cst.cs.dbGetSiafundPool()) {
+		t.Fatal("cached pool does not match the database")
+	}
+
+	// Create a file contract, which adds its tax to the pool.
+	payout := types.NewCurrency64(400e6)
+	fc := types.FileContract{
+		WindowStart:        startHeight + 10,
+		WindowEnd:          startHeight + 20,
+		Payout:             payout,
+		ValidProofOutputs:  []types.SiacoinOutput{{Value: types.PostTax(startHeight, payout)}},
+		MissedProofOutputs: []types.SiacoinOutput{{Value: types.PostTax(startHeight, payout)}},
+	}
+	txnBuilder, err := cst.wallet.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := txnBuilder.FundSiacoins(payout); err != nil {
+		t.Fatal(err)
+	}
+	txnBuilder.AddFileContract(fc)
+	txnSet, err := txnBuilder.Sign(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cst.tpool.AcceptTransactionSet(txnSet); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	contractPool := startPool.Add(types.Tax(startHeight, payout))
+	if pool := cst.cs.SiafundPool(); !pool.Equals(contractPool) || !pool.Equals(cst.cs.dbGetSiafundPool()) {
+		t.Fatalf("expected pool %v, got %v", contractPool, pool)
+	}
+
+	// Blocks without contracts do not change the pool.
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		height types.BlockHeight
+		pool   types.Currency
+	}{
+		{0, types.ZeroCurrency},
+		{startHeight, startPool},
+		{startHeight + 1, contractPool},
+		{startHeight + 2, contractPool},
+	}
+	for _, test := range tests {
+		pool, err := cst.cs.SiafundPoolAtHeight(test.height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pool.Equals(test.pool) {
+			t.Errorf("expected pool %v at height %v, got %v", test.pool, test.height, pool)
+		}
+	}
+	if _, err := cst.cs.SiafundPoolAtHeight(cst.cs.Height() + 1); err != errSiafundPoolHeight {
+		t.Fatal("expected errSiafundPoolHeight, got", err)
+	}
+}
@@ -0,0 +1,98 @@
+package consensus
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+const (
+	// softForkActivationThreshold is the fraction of blocks in a target
+	// window that must signal for a soft fork before it is considered
+	// activated.
+	softForkActivationThreshold = 0.95
+
+	// maxSoftForkBit is the number of version bits that can be signaled.
+	maxSoftForkBit = 64
+)
+
+var (
+	errInvalidSoftForkBit = errors.New("soft fork version bit is out of range")
+)
+
+// blockVersionBits returns the version bits signaled by a block. Signals are
+// read from the arbitrary data of the block's transactions; if a block
+// contains multiple signals, their bits are combined.
+func blockVersionBits(b types.Block) (bits uint64) {
+	for _, txn := range b.Transactions {
+		for _, arb := range txn.ArbitraryData {
+			var prefix types.Specifier
+			var versionBits uint64
+			if encoding.UnmarshalAll(arb, &prefix, &versionBits) != nil {
+				continue
+			}
+			if prefix == modules.PrefixSoftForkSignal {
+				bits |= versionBits
+			}
+		}
+	}
+	return bits
+}
+
+// softForkStatus counts the blocks in the most recent target window that
+// signal for the given version bit. A soft fork can only be activated once a
+// full window of blocks is available.
+func softForkStatus(tx *bolt.Tx, bit uint) (signaling float64, activated bool, err error) {
+	if bit >= maxSoftForkBit {
+		return 0, false, errInvalidSoftForkBit
+	}
+
+	// The genesis block never signals, so it is excluded from the window.
+	height := blockHeight(tx)
+	window := types.TargetWindow
+	if height < window {
+		window = height
+	}
+	if window == 0 {
+		return 0, false, nil
+	}
+
+	var signals types.BlockHeight
+	for i := height - window + 1; i <= height; i++ {
+		id, err := getPath(tx, i)
+		if err != nil {
+			return 0, false, err
+		}
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			return 0, false, err
+		}
+		if blockVersionBits(pb.Block)&(1<<bit) != 0 {
+			signals++
+		}
+	}
+	signaling = float64(signals) / float64(window)
+	activated = window == types.TargetWindow && signaling >= softForkActivationThreshold
+	return signaling, activated, nil
+}
+
+// SoftForkStatus returns the fraction of blocks in the most recent target
+// window that signal readiness for the soft fork identified by 'bit', and
+// whether the fraction has reached the activation threshold.
+func (cs *ConsensusSet) SoftForkStatus(bit uint) (signaling float64, activated bool, err error) {
+	err = cs.tg.Add()
+	if err != nil {
+		return 0, false, err
+	}
+	defer cs.tg.Done()
+
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		signaling, activated, err = softForkStatus(tx, bit)
+		return err
+	})
+	return signaling, activated, err
+}
@@ -0,0 +1,88 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// mineSignalingBlock mines a block that signals for the provided version bits.
+func (cst *consensusSetTester) mineSignalingBlock(versionBits uint64) error {
+	block, target, err := cst.miner.BlockForWork()
+	if err != nil {
+		return err
+	}
+	block.Transactions = append(block.Transactions, modules.SoftForkSignalTransaction(versionBits))
+	solvedBlock, _ := cst.miner.SolveBlock(block, target)
+	return cst.cs.AcceptBlock(solvedBlock)
+}
+
+// TestSoftForkStatus checks that version bit signaling is tallied over the
+// most recent target window.
+func TestSoftForkStatus(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := blankConsensusSetTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Out of range bits should be rejected.
+	if _, _, err := cst.cs.SoftForkStatus(maxSoftForkBit); err != errInvalidSoftForkBit {
+		t.Fatal("expected errInvalidSoftForkBit, got", err)
+	}
+
+	// Signal for bit 3 for half of a window. The fork should not activate
+	// until a full window is available.
+	for i := types.BlockHeight(0); i < types.TargetWindow/2; i++ {
+		if err := cst.mineSignalingBlock(1 << 3); err != nil {
+			t.Fatal(err)
+		}
+	}
+	signaling, activated, err := cst.cs.SoftForkStatus(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signaling != 1 || activated {
+		t.Fatal("unexpected status for partial window:", signaling, activated)
+	}
+
+	// Complete the window.
+	for i := types.TargetWindow / 2; i < types.TargetWindow; i++ {
+		if err := cst.mineSignalingBlock(1 << 3); err != nil {
+			t.Fatal(err)
+		}
+	}
+	signaling, activated, err = cst.cs.SoftForkStatus(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signaling != 1 || !activated {
+		t.Fatal("soft fork should be activated:", signaling, activated)
+	}
+	signaling, activated, err = cst.cs.SoftForkStatus(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signaling != 0 || activated {
+		t.Fatal("unsignaled bit reports signaling:", signaling, activated)
+	}
+
+	// Mine half a window of blocks without signals.
+	for i := types.BlockHeight(0); i < types.TargetWindow/2; i++ {
+		if _, err := cst.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	signaling, activated, err = cst.cs.SoftForkStatus(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signaling != 0.5 || activated {
+		t.Fatal("unexpected status after signaling stopped:", signaling, activated)
+	}
+}
@@ -0,0 +1,95 @@
+package consensus
+
+import (
+	"time"
+
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+// notifyTipChanged wakes every goroutine waiting for the current block to
+// change. The caller must hold cs.mu.
+func (cs *ConsensusSet) notifyTipChanged() {
+	close(cs.tipChanged)
+	cs.tipChanged = make(chan struct{})
+}
+
+// managedTip returns the current block along with a channel that is closed
+// once the current block changes.
+func (cs *ConsensusSet) managedTip() (b types.Block, tipChanged <-chan struct{}) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	_ = cs.db.View(func(tx *bolt.Tx) error {
+		b = currentProcessedBlock(tx).Block
+		return nil
+	})
+	return b, cs.tipChanged
+}
+
+// tipAge returns how long ago the provided block was timestamped, according
+// to the consensus set's clock.
+func (cs *ConsensusSet) tipAge(b types.Block) time.Duration {
+	now := cs.staticClock.Now()
+	if b.Timestamp >= now {
+		return 0
+	}
+	return time.Duration(now-b.Timestamp) * time.Second
+}
+
+// TipAge returns how long ago the current block was timestamped.
+func (cs *ConsensusSet) TipAge() time.Duration {
+	if err := cs.tg.Add(); err != nil {
+		return 0
+	}
+	defer cs.tg.Done()
+	b, _ := cs.managedTip()
+	return cs.tipAge(b)
+}
+
+// threadedWatchStaleTip sends the age of the current block to ch whenever the
+// current block becomes older than threshold. Each block is reported at most
+// once.
+func (cs *ConsensusSet) threadedWatchStaleTip(threshold time.Duration, ch chan<- time.Duration) {
+	if err := cs.tg.Add(); err != nil {
+		return
+	}
+	defer cs.tg.Done()
+
+	// Round the threshold up to the resolution of block timestamps.
+	thresholdSecs := types.Timestamp((threshold + time.Second - 1) / time.Second)
+	var reported types.BlockID
+	for {
+		tip, tipChanged := cs.managedTip()
+		if tip.ID() == reported {
+			select {
+			case <-cs.tg.StopChan():
+				return
+			case <-tipChanged:
+			}
+			continue
+		}
+		select {
+		case <-cs.tg.StopChan():
+			return
+		case <-tipChanged:
+			continue
+		case <-cs.staticClock.WaitUntil(tip.Timestamp + thresholdSecs):
+		}
+		reported = tip.ID()
+		select {
+		case <-cs.tg.StopChan():
+			return
+		case <-tipChanged:
+		case ch <- cs.tipAge(tip):
+		}
+	}
+}
+
+// SubscribeStaleTip registers a channel that receives the age of the current
+// block whenever it has gone longer than threshold without being replaced.
+// Each stale block is reported once; the subscription fires again if a new
+// block is accepted and then also becomes stale.
+func (cs *ConsensusSet) SubscribeStaleTip(threshold time.Duration, ch chan<- time.Duration) {
+	go cs.threadedWatchStaleTip(threshold, ch)
+}
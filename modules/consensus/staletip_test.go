@@ -0,0 +1,93 @@
+package consensus
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/gateway"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestStaleTip checks that TipAge follows the consensus set's clock and that
+// SubscribeStaleTip fires once per stale block.
+func TestStaleTip(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	clock := newManualClock(cst.cs.CurrentBlock().Timestamp)
+	testdir := build.TempDir(modules.ConsensusDir, t.Name()+"-cs2")
+	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	cs, err := NewConfiguredConsensusSet(g, false, filepath.Join(testdir, modules.ConsensusDir), modules.ProdDependencies, Config{
+		Clock: clock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+	for i := types.BlockHeight(1); i <= cst.cs.Height(); i++ {
+		b, _ := cst.cs.BlockAtHeight(i)
+		if err := cs.AcceptBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if age := cs.TipAge(); age != 0 {
+		t.Fatal("expected a fresh tip, got age", age)
+	}
+
+	stale := make(chan time.Duration)
+	cs.SubscribeStaleTip(time.Minute, stale)
+	clock.advance(30)
+	if age := cs.TipAge(); age != 30*time.Second {
+		t.Fatal("expected tip age of 30s, got", age)
+	}
+	select {
+	case age := <-stale:
+		t.Fatal("stale tip reported before the threshold, age", age)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Once the threshold passes, the tip is reported exactly once.
+	clock.advance(40)
+	select {
+	case age := <-stale:
+		if age != 70*time.Second {
+			t.Fatal("expected reported age of 70s, got", age)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stale tip was not reported")
+	}
+	clock.advance(100)
+	select {
+	case age := <-stale:
+		t.Fatal("stale tip was reported twice, age", age)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// A new block that is already stale is reported as well.
+	b, err := cst.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.AcceptBlock(b); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-stale:
+	case <-time.After(time.Second):
+		t.Fatal("new stale tip was not reported")
+	}
+}
@@ -1,7 +1,9 @@
 package consensus
 
 import (
+	"bytes"
 	"errors"
+	"sort"
 	"time"
 
 	"github.com/NebulousLabs/Sia/build"
@@ -11,6 +13,26 @@ import (
 	"github.com/coreos/bbolt"
 )
 
+// sortConsensusChangeDiffs sorts the output and file contract diffs of a
+// consensus change by id. The sort is stable, so multiple diffs of the same
+// object keep the order in which they were applied or reverted. Diffs of
+// different objects are independent of each other, so reordering them does not
+// change the result of processing the change.
+func sortConsensusChangeDiffs(cc *modules.ConsensusChange) {
+	sort.SliceStable(cc.SiacoinOutputDiffs, func(i, j int) bool {
+		return bytes.Compare(cc.SiacoinOutputDiffs[i].ID[:], cc.SiacoinOutputDiffs[j].ID[:]) < 0
+	})
+	sort.SliceStable(cc.FileContractDiffs, func(i, j int) bool {
+		return bytes.Compare(cc.FileContractDiffs[i].ID[:], cc.FileContractDiffs[j].ID[:]) < 0
+	})
+	sort.SliceStable(cc.SiafundOutputDiffs, func(i, j int) bool {
+		return bytes.Compare(cc.SiafundOutputDiffs[i].ID[:], cc.SiafundOutputDiffs[j].ID[:]) < 0
+	})
+	sort.SliceStable(cc.DelayedSiacoinOutputDiffs, func(i, j int) bool {
+		return bytes.Compare(cc.DelayedSiacoinOutputDiffs[i].ID[:], cc.DelayedSiacoinOutputDiffs[j].ID[:]) < 0
+	})
+}
+
 // computeConsensusChange computes the consensus change from the change entry
 // at index 'i' in the change log. If i is out of bounds, an error is returned.
 func (cs *ConsensusSet) computeConsensusChange(tx *bolt.Tx, ce changeEntry) (modules.ConsensusChange, error) {
@@ -18,7 +40,7 @@ func (cs *ConsensusSet) computeConsensusChange(tx *bolt.Tx, ce changeEntry) (mod
 		ID: ce.ID(),
 	}
 	for _, revertedBlockID := range ce.RevertedBlocks {
-		revertedBlock, err := getBlockMap(tx, revertedBlockID)
+		revertedBlock, err := cs.blockCache.getBlockMap(tx, revertedBlockID)
 		if err != nil {
 			cs.log.Critical("getBlockMap failed in computeConsensusChange:", err)
 			return modules.ConsensusChange{}, err
@@ -54,7 +76,7 @@ func (cs *ConsensusSet) computeConsensusChange(tx *bolt.Tx, ce changeEntry) (mod
 		}
 	}
 	for _, appliedBlockID := range ce.AppliedBlocks {
-		appliedBlock, err := getBlockMap(tx, appliedBlockID)
+		appliedBlock, err := cs.blockCache.getBlockMap(tx, appliedBlockID)
 		if err != nil {
 			cs.log.Critical("getBlockMap failed in computeConsensusChange:", err)
 			return modules.ConsensusChange{}, err
@@ -78,9 +100,11 @@ func (cs *ConsensusSet) computeConsensusChange(tx *bolt.Tx, ce changeEntry) (mod
 		}
 	}
 
+	sortConsensusChangeDiffs(&cc)
+
 	// Grab the child target and the minimum valid child timestamp.
 	recentBlock := ce.AppliedBlocks[len(ce.AppliedBlocks)-1]
-	pb, err := getBlockMap(tx, recentBlock)
+	pb, err := cs.blockCache.getBlockMap(tx, recentBlock)
 	if err != nil {
 		cs.log.Critical("could not find process block for known block")
 	}
@@ -102,7 +126,9 @@ func (cs *ConsensusSet) computeConsensusChange(tx *bolt.Tx, ce changeEntry) (mod
 // consensus set. updateSubscribers does not alter the changelog, the changelog
 // must be updated beforehand.
 func (cs *ConsensusSet) updateSubscribers(ce changeEntry) {
-	if len(cs.subscribers) == 0 {
+	cs.updateReorgWarnings(ce)
+	cs.updateFileContractSubscriptions(ce)
+	if len(cs.subscribers) == 0 && cs.blockNotifier == nil {
 		return
 	}
 	// Get the consensus change and send it to all subscribers.
@@ -117,9 +143,16 @@ func (cs *ConsensusSet) updateSubscribers(ce changeEntry) {
 		cs.log.Critical("computeConsensusChange failed:", err)
 		return
 	}
-	for _, subscriber := range cs.subscribers {
-		subscriber.ProcessConsensusChange(cc)
+	cs.subscriberStats.queued()
+	if cs.staticParallelSubscribers {
+		// Each subscriber processes the change from its own goroutine.
+		cs.queueChange(cc)
+	} else {
+		for _, subscriber := range cs.subscribers {
+			cs.managedDeliver(subscriber, cc)
+		}
 	}
+	cs.notifyBlock(cc)
 }
 
 // managedInitializeSubscribe will take a subscriber and feed them all of the
@@ -273,7 +306,12 @@ func (cs *ConsensusSet) ConsensusSetSubscribe(subscriber modules.ConsensusSetSub
 		}
 	}
 
-	// Add the module to the list of subscribers.
+	cs.addSubscriber(subscriber)
+	return nil
+}
+
+// addSubscriber adds a module to the list of subscribers.
+func (cs *ConsensusSet) addSubscriber(subscriber modules.ConsensusSetSubscriber) {
 	// Sanity check - subscriber should not be already subscribed.
 	for _, s := range cs.subscribers {
 		if s == subscriber {
@@ -281,12 +319,62 @@ func (cs *ConsensusSet) ConsensusSetSubscribe(subscriber modules.ConsensusSetSub
 		}
 	}
 	cs.subscribers = append(cs.subscribers, subscriber)
+	cs.subscriberStats.add(subscriber)
+	if cs.staticParallelSubscribers {
+		cs.startSubscriberQueue(subscriber)
+	}
+}
+
+// SubscribeAtomic adds a subscriber to the list of subscribers after sending
+// it every consensus change that has occurred since the change with the
+// provided id. Unlike ConsensusSetSubscribe, the consensus lock is held for
+// the entire replay, so no block can be accepted between the replay and the
+// registration and every change is delivered exactly once. Because new blocks
+// are blocked during the replay, SubscribeAtomic should only be used when the
+// subscriber is close to the current tip.
+func (cs *ConsensusSet) SubscribeAtomic(subscriber modules.ConsensusSetSubscriber, start modules.ConsensusChangeID) error {
+	if err := cs.tg.Add(); err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if start != modules.ConsensusChangeRecent {
+		err := cs.db.View(func(tx *bolt.Tx) error {
+			var entry changeEntry
+			var exists bool
+			if start == modules.ConsensusChangeBeginning {
+				entry, exists = cs.genesisEntry(), true
+			} else {
+				entry, exists = getEntry(tx, start)
+				if !exists {
+					return modules.ErrInvalidConsensusChangeID
+				}
+				entry, exists = entry.NextEntry(tx)
+			}
+			for ; exists; entry, exists = entry.NextEntry(tx) {
+				cc, err := cs.computeConsensusChange(tx, entry)
+				if err != nil {
+					return err
+				}
+				subscriber.ProcessConsensusChange(cc)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cs.addSubscriber(subscriber)
 	return nil
 }
 
 // Unsubscribe removes a subscriber from the list of subscribers, allowing for
 // garbage collection and rescanning. If the subscriber is not found in the
-// subscriber database, no action is taken.
+// subscriber database, no action is taken. With ParallelSubscribers, changes
+// that are still queued for the subscriber are discarded, but a change that it
+// is processing may finish after Unsubscribe returns.
 func (cs *ConsensusSet) Unsubscribe(subscriber modules.ConsensusSetSubscriber) {
 	if cs.tg.Add() != nil {
 		return
@@ -304,6 +392,8 @@ func (cs *ConsensusSet) Unsubscribe(subscriber modules.ConsensusSetSubscriber) {
 			cs.subscribers[i] = nil
 			// Delete the entry from the slice.
 			cs.subscribers = append(cs.subscribers[0:i], cs.subscribers[i+1:]...)
+			cs.subscriberStats.remove(subscriber)
+			cs.stopSubscriberQueue(subscriber)
 			break
 		}
 	}
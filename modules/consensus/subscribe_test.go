@@ -1,10 +1,18 @@
 package consensus
 
 import (
+	"bytes"
+	"errors"
+	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/gateway"
+	"github.com/NebulousLabs/Sia/types"
 	bolt "github.com/coreos/bbolt"
 )
 
@@ -237,3 +245,285 @@ func TestModuleDesync(t *testing.T) {
 		t.Fatal("last update doesn't equal recentChangeID")
 	}
 }
+
+// TestSubscribeAtomic checks that SubscribeAtomic delivers every consensus
+// change exactly once, even when blocks are accepted during the subscription.
+func TestSubscribeAtomic(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Mine blocks while the subscriber is being added.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			if _, err := cst.miner.AddBlock(); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	ms := newMockSubscriber()
+	if err := cst.cs.SubscribeAtomic(&ms, modules.ConsensusChangeBeginning); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	// Every block in the current path should have been applied exactly once.
+	applied := make(map[types.BlockID]int)
+	for _, cc := range ms.updates {
+		if len(cc.RevertedBlocks) != 0 {
+			t.Fatal("unexpected reverted blocks")
+		}
+		for _, b := range cc.AppliedBlocks {
+			applied[b.ID()]++
+		}
+	}
+	if len(applied) != int(cst.cs.Height())+1 {
+		t.Fatal("subscriber did not receive every block:", len(applied), cst.cs.Height()+1)
+	}
+	for id, n := range applied {
+		if n != 1 {
+			t.Fatalf("block %v was applied %v times", id, n)
+		}
+	}
+	cst.cs.mu.Lock()
+	recentID, err := cst.cs.recentConsensusChangeID()
+	cst.cs.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ms.updates[len(ms.updates)-1].ID != recentID {
+		t.Error("subscriber did not receive the most recent change")
+	}
+
+	// Subscribing with an unknown change id should fail without adding the
+	// subscriber.
+	ms2 := newMockSubscriber()
+	err = cst.cs.SubscribeAtomic(&ms2, modules.ConsensusChangeID{255, 255, 255})
+	if err != modules.ErrInvalidConsensusChangeID {
+		t.Fatal("expected ErrInvalidConsensusChangeID, got", err)
+	}
+	cst.cs.mu.Lock()
+	defer cst.cs.mu.Unlock()
+	for _, s := range cst.cs.subscribers {
+		if s == &ms2 {
+			t.Fatal("subscriber was added after a failed subscription")
+		}
+	}
+}
+
+// slowSubscriber is a subscriber that takes a fixed amount of time to process
+// each consensus change.
+type slowSubscriber struct {
+	delay time.Duration
+	ids   []modules.ConsensusChangeID
+	mu    sync.Mutex
+}
+
+// ProcessConsensusChange records the id of the change after a delay.
+func (ss *slowSubscriber) ProcessConsensusChange(cc modules.ConsensusChange) {
+	time.Sleep(ss.delay)
+	ss.mu.Lock()
+	ss.ids = append(ss.ids, cc.ID)
+	ss.mu.Unlock()
+}
+
+// changeIDs returns the ids of the changes that the subscriber has processed.
+func (ss *slowSubscriber) changeIDs() []modules.ConsensusChangeID {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return append([]modules.ConsensusChangeID(nil), ss.ids...)
+}
+
+// TestParallelSubscribers checks that subscribers are notified concurrently
+// when ParallelSubscribers is set, and that each subscriber still receives
+// changes in order.
+func TestParallelSubscribers(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	testdir := build.TempDir(modules.ConsensusDir, t.Name()+"-cs2")
+	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	cs, err := NewConfiguredConsensusSet(g, false, filepath.Join(testdir, modules.ConsensusDir), modules.ProdDependencies, Config{
+		ParallelSubscribers: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	const numSubscribers = 5
+	const delay = 100 * time.Millisecond
+	subs := make([]*slowSubscriber, numSubscribers)
+	for i := range subs {
+		subs[i] = &slowSubscriber{delay: delay}
+		if err := cs.ConsensusSetSubscribe(subs[i], modules.ConsensusChangeRecent, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Accepting blocks should not wait for the subscribers, and the
+	// subscribers should process the changes concurrently.
+	const numBlocks = 3
+	start := time.Now()
+	for i := types.BlockHeight(1); i <= numBlocks; i++ {
+		b, _ := cst.cs.BlockAtHeight(i)
+		if err := cs.AcceptBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed >= delay {
+		t.Fatal("accepting blocks waited for the subscribers:", elapsed)
+	}
+	err = build.Retry(100, 50*time.Millisecond, func() error {
+		for _, sub := range subs {
+			if len(sub.changeIDs()) != numBlocks {
+				return errors.New("subscribers have not processed every change")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed >= numBlocks*numSubscribers*delay/2 {
+		t.Fatal("subscribers were not notified concurrently:", elapsed)
+	}
+
+	// Every subscriber received the changes in the same order.
+	expected := subs[0].changeIDs()
+	for _, sub := range subs {
+		ids := sub.changeIDs()
+		for i := range ids {
+			if ids[i] != expected[i] {
+				t.Fatal("subscribers received changes in different orders")
+			}
+		}
+	}
+
+	// An unsubscribed subscriber is not sent any more changes.
+	cs.Unsubscribe(subs[0])
+	b, _ := cst.cs.BlockAtHeight(numBlocks + 1)
+	if err := cs.AcceptBlock(b); err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(100, 50*time.Millisecond, func() error {
+		if len(subs[1].changeIDs()) != numBlocks+1 {
+			return errors.New("subscriber has not processed the change")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs[0].changeIDs()) != numBlocks {
+		t.Fatal("unsubscribed subscriber was sent a change")
+	}
+}
+
+// TestSortConsensusChangeDiffs checks that diffs are sorted by id, and that
+// diffs sharing an id keep their order.
+func TestSortConsensusChangeDiffs(t *testing.T) {
+	cc := modules.ConsensusChange{
+		SiacoinOutputDiffs: []modules.SiacoinOutputDiff{
+			{ID: types.SiacoinOutputID{3}, Direction: modules.DiffApply},
+			{ID: types.SiacoinOutputID{1}, Direction: modules.DiffApply},
+			{ID: types.SiacoinOutputID{3}, Direction: modules.DiffRevert},
+			{ID: types.SiacoinOutputID{2}, Direction: modules.DiffApply},
+		},
+		FileContractDiffs: []modules.FileContractDiff{
+			{ID: types.FileContractID{2}, Direction: modules.DiffRevert},
+			{ID: types.FileContractID{2}, Direction: modules.DiffApply},
+			{ID: types.FileContractID{1}, Direction: modules.DiffApply},
+		},
+	}
+	sortConsensusChangeDiffs(&cc)
+	expected := []modules.SiacoinOutputDiff{
+		{ID: types.SiacoinOutputID{1}, Direction: modules.DiffApply},
+		{ID: types.SiacoinOutputID{2}, Direction: modules.DiffApply},
+		{ID: types.SiacoinOutputID{3}, Direction: modules.DiffApply},
+		{ID: types.SiacoinOutputID{3}, Direction: modules.DiffRevert},
+	}
+	for i := range expected {
+		if cc.SiacoinOutputDiffs[i].ID != expected[i].ID || cc.SiacoinOutputDiffs[i].Direction != expected[i].Direction {
+			t.Fatal("siacoin output diffs sorted incorrectly:", cc.SiacoinOutputDiffs)
+		}
+	}
+	if cc.FileContractDiffs[0].ID != (types.FileContractID{1}) || cc.FileContractDiffs[1].Direction != modules.DiffRevert || cc.FileContractDiffs[2].Direction != modules.DiffApply {
+		t.Fatal("file contract diffs sorted incorrectly:", cc.FileContractDiffs)
+	}
+}
+
+// TestConsensusChangeDiffOrder checks that subscribers receive the diffs of
+// every consensus change sorted by id, and that recomputing the changes yields
+// the same diffs.
+func TestConsensusChangeDiffOrder(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Mine a block with several transactions.
+	for i := 0; i < 5; i++ {
+		if _, err := cst.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(uint64(i+1)), types.UnlockHash{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	ms1, ms2 := newMockSubscriber(), newMockSubscriber()
+	if err := cst.cs.ConsensusSetSubscribe(&ms1, modules.ConsensusChangeBeginning, cst.cs.tg.StopChan()); err != nil {
+		t.Fatal(err)
+	}
+	if err := cst.cs.ConsensusSetSubscribe(&ms2, modules.ConsensusChangeBeginning, cst.cs.tg.StopChan()); err != nil {
+		t.Fatal(err)
+	}
+	if len(ms1.updates) != len(ms2.updates) {
+		t.Fatal("subscribers received different numbers of changes")
+	}
+	for i, cc := range ms1.updates {
+		for j := 1; j < len(cc.SiacoinOutputDiffs); j++ {
+			if bytes.Compare(cc.SiacoinOutputDiffs[j-1].ID[:], cc.SiacoinOutputDiffs[j].ID[:]) > 0 {
+				t.Fatal("siacoin output diffs are not sorted in change", i)
+			}
+		}
+		for j := 1; j < len(cc.DelayedSiacoinOutputDiffs); j++ {
+			if bytes.Compare(cc.DelayedSiacoinOutputDiffs[j-1].ID[:], cc.DelayedSiacoinOutputDiffs[j].ID[:]) > 0 {
+				t.Fatal("delayed siacoin output diffs are not sorted in change", i)
+			}
+		}
+		cc2 := ms2.updates[i]
+		if !bytes.Equal(encoding.Marshal(cc.SiacoinOutputDiffs), encoding.Marshal(cc2.SiacoinOutputDiffs)) ||
+			!bytes.Equal(encoding.Marshal(cc.DelayedSiacoinOutputDiffs), encoding.Marshal(cc2.DelayedSiacoinOutputDiffs)) ||
+			!bytes.Equal(encoding.Marshal(cc.FileContractDiffs), encoding.Marshal(cc2.FileContractDiffs)) ||
+			!bytes.Equal(encoding.Marshal(cc.SiafundOutputDiffs), encoding.Marshal(cc2.SiafundOutputDiffs)) {
+			t.Fatal("recomputed diffs differ in change", i)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package consensus
+
+import (
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+var (
+	// subscriberQueueSize is the number of consensus changes that are queued
+	// for each subscriber when subscribers are notified in parallel. Once a
+	// subscriber's queue is full, the consensus set waits for the subscriber
+	// to catch up before accepting more blocks.
+	subscriberQueueSize = build.Select(build.Var{
+		Standard: 100,
+		Dev:      50,
+		Testing:  5,
+	}).(int)
+)
+
+// A subscriberQueue delivers consensus changes to a single subscriber from its
+// own goroutine, in the order that they were queued. It is used instead of
+// calling the subscriber directly when the consensus set is configured with
+// ParallelSubscribers.
+type subscriberQueue struct {
+	subscriber modules.ConsensusSetSubscriber
+	queue      chan modules.ConsensusChange
+	stop       chan struct{}
+}
+
+// threadedDeliverQueue delivers the queued changes of a subscriber until the
+// subscriber is unsubscribed or the consensus set shuts down.
+func (cs *ConsensusSet) threadedDeliverQueue(sq *subscriberQueue) {
+	if err := cs.tg.Add(); err != nil {
+		return
+	}
+	defer cs.tg.Done()
+	for {
+		// Check for a stop first, so that no change is delivered after the
+		// subscriber was removed.
+		select {
+		case <-sq.stop:
+			return
+		case <-cs.tg.StopChan():
+			return
+		default:
+		}
+		select {
+		case cc := <-sq.queue:
+			cs.managedDeliver(sq.subscriber, cc)
+		case <-sq.stop:
+			return
+		case <-cs.tg.StopChan():
+			return
+		}
+	}
+}
+
+// startSubscriberQueue starts delivering changes to a subscriber through its
+// own queue.
+func (cs *ConsensusSet) startSubscriberQueue(subscriber modules.ConsensusSetSubscriber) {
+	sq := &subscriberQueue{
+		subscriber: subscriber,
+		queue:      make(chan modules.ConsensusChange, subscriberQueueSize),
+		stop:       make(chan struct{}),
+	}
+	cs.subscriberQueues[subscriber] = sq
+	go cs.threadedDeliverQueue(sq)
+}
+
+// stopSubscriberQueue stops delivering changes to a subscriber. Changes that
+// are still queued are discarded. A change that the subscriber is processing
+// when stopSubscriberQueue is called may finish afterwards.
+func (cs *ConsensusSet) stopSubscriberQueue(subscriber modules.ConsensusSetSubscriber) {
+	sq, exists := cs.subscriberQueues[subscriber]
+	if !exists {
+		return
+	}
+	close(sq.stop)
+	delete(cs.subscriberQueues, subscriber)
+}
+
+// queueChange adds a consensus change to the queue of every subscriber. If a
+// subscriber's queue is full, queueChange blocks until there is room, so that
+// no change is ever dropped.
+func (cs *ConsensusSet) queueChange(cc modules.ConsensusChange) {
+	for _, subscriber := range cs.subscribers {
+		sq := cs.subscriberQueues[subscriber]
+		select {
+		case sq.queue <- cc:
+		case <-cs.tg.StopChan():
+			return
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package consensus
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// subscriberStat holds the delivery counters of a single subscriber.
+type subscriberStat struct {
+	subscriber   modules.ConsensusSetSubscriber
+	name         string
+	delivered    uint64
+	pending      uint64
+	lastLatency  time.Duration
+	maxLatency   time.Duration
+	totalLatency time.Duration
+}
+
+// subscriberStats tracks how quickly each subscriber processes the changes
+// delivered by updateSubscribers. It has its own lock so that the stats can
+// be read while the consensus set is busy delivering a change.
+type subscriberStats struct {
+	stats []*subscriberStat
+	mu    sync.Mutex
+}
+
+// add starts tracking a subscriber.
+func (s *subscriberStats) add(subscriber modules.ConsensusSetSubscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats = append(s.stats, &subscriberStat{
+		subscriber: subscriber,
+		name:       fmt.Sprintf("%T", subscriber),
+	})
+}
+
+// remove stops tracking a subscriber.
+func (s *subscriberStats) remove(subscriber modules.ConsensusSetSubscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, stat := range s.stats {
+		if stat.subscriber == subscriber {
+			s.stats = append(s.stats[:i], s.stats[i+1:]...)
+			return
+		}
+	}
+}
+
+// queued records that a change is waiting to be delivered to every tracked
+// subscriber.
+func (s *subscriberStats) queued() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, stat := range s.stats {
+		stat.pending++
+	}
+}
+
+// delivered records that a subscriber processed a change in the given time.
+func (s *subscriberStats) delivered(subscriber modules.ConsensusSetSubscriber, latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, stat := range s.stats {
+		if stat.subscriber != subscriber {
+			continue
+		}
+		stat.delivered++
+		if stat.pending > 0 {
+			stat.pending--
+		}
+		stat.lastLatency = latency
+		stat.totalLatency += latency
+		if latency > stat.maxLatency {
+			stat.maxLatency = latency
+		}
+		return
+	}
+}
+
+// managedDeliver sends a change to a subscriber and records how long the
+// subscriber took to process it.
+func (cs *ConsensusSet) managedDeliver(subscriber modules.ConsensusSetSubscriber, cc modules.ConsensusChange) {
+	start := time.Now()
+	subscriber.ProcessConsensusChange(cc)
+	cs.subscriberStats.delivered(subscriber, time.Since(start))
+}
+
+// SubscriberStats returns the delivery statistics of every subscriber of the
+// consensus set, in the order that the subscribers receive changes. Only the
+// changes delivered after a subscriber finished subscribing are counted.
+func (cs *ConsensusSet) SubscriberStats() []modules.SubscriberStat {
+	cs.subscriberStats.mu.Lock()
+	defer cs.subscriberStats.mu.Unlock()
+	stats := make([]modules.SubscriberStat, 0, len(cs.subscriberStats.stats))
+	for _, stat := range cs.subscriberStats.stats {
+		s := modules.SubscriberStat{
+			Subscriber:  stat.name,
+			Delivered:   stat.delivered,
+			Pending:     stat.pending,
+			LastLatency: stat.lastLatency,
+			MaxLatency:  stat.maxLatency,
+		}
+		if stat.delivered > 0 {
+			s.AverageLatency = stat.totalLatency / time.Duration(stat.delivered)
+		}
+		stats = append(stats, s)
+	}
+	return stats
+}
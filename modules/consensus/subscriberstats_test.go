@@ -0,0 +1,100 @@
+package consensus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// blockingSubscriber is a subscriber that does not finish processing a change
+// until it is released.
+type blockingSubscriber struct {
+	received chan struct{}
+	release  chan struct{}
+}
+
+// ProcessConsensusChange signals that a change was received and waits to be
+// released.
+func (bs *blockingSubscriber) ProcessConsensusChange(cc modules.ConsensusChange) {
+	bs.received <- struct{}{}
+	<-bs.release
+}
+
+// TestSubscriberStats checks that the deliveries to each subscriber are
+// counted, and that the stats can be read while a subscriber is holding up
+// the consensus set.
+func TestSubscriberStats(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	findStat := func(name string) modules.SubscriberStat {
+		for _, stat := range cst.cs.SubscriberStats() {
+			if stat.Subscriber == name {
+				return stat
+			}
+		}
+		t.Fatal("no stats for", name)
+		return modules.SubscriberStat{}
+	}
+
+	// Changes replayed during subscription are not counted.
+	slow := &slowSubscriber{delay: 10 * time.Millisecond}
+	if err := cst.cs.ConsensusSetSubscribe(slow, modules.ConsensusChangeBeginning, nil); err != nil {
+		t.Fatal(err)
+	}
+	if stat := findStat("*consensus.slowSubscriber"); stat.Delivered != 0 || stat.Pending != 0 {
+		t.Fatal("replayed changes were counted:", stat)
+	}
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	stat := findStat("*consensus.slowSubscriber")
+	if stat.Delivered != 1 || stat.Pending != 0 {
+		t.Fatal("wrong delivery counts:", stat)
+	}
+	if stat.LastLatency < slow.delay || stat.AverageLatency < slow.delay || stat.MaxLatency < slow.delay {
+		t.Fatal("latency is lower than the delay of the subscriber:", stat)
+	}
+
+	// Stall the consensus set with a subscriber that does not return. The
+	// stats show the change as pending.
+	bs := &blockingSubscriber{
+		received: make(chan struct{}),
+		release:  make(chan struct{}),
+	}
+	if err := cst.cs.ConsensusSetSubscribe(bs, modules.ConsensusChangeRecent, nil); err != nil {
+		t.Fatal(err)
+	}
+	errChan := make(chan error)
+	go func() {
+		_, err := cst.miner.AddBlock()
+		errChan <- err
+	}()
+	<-bs.received
+	if stat := findStat("*consensus.blockingSubscriber"); stat.Delivered != 0 || stat.Pending != 1 {
+		t.Fatal("stalled change is not pending:", stat)
+	}
+	close(bs.release)
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+	if stat := findStat("*consensus.blockingSubscriber"); stat.Delivered != 1 || stat.Pending != 0 {
+		t.Fatal("wrong delivery counts after release:", stat)
+	}
+
+	// Unsubscribed subscribers are no longer reported.
+	cst.cs.Unsubscribe(slow)
+	for _, stat := range cst.cs.SubscriberStats() {
+		if stat.Subscriber == "*consensus.slowSubscriber" {
+			t.Fatal("unsubscribed subscriber is still reported")
+		}
+	}
+}
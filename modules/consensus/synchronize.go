@@ -199,7 +199,7 @@ func (cs *ConsensusSet) managedReceiveBlocks(conn modules.PeerConn) (returnErr e
 				panic("blockchain extension reporting is incorrect")
 			}
 			fullBlock := cs.managedCurrentBlock() // TODO: Add cacheing, replace this line by looking at the cache.
-			go cs.gateway.Broadcast("RelayHeader", fullBlock.Header(), cs.gateway.Peers())
+			cs.managedBroadcastBlock(fullBlock, conn.RPCAddr())
 		}
 	}()
 
@@ -209,7 +209,7 @@ func (cs *ConsensusSet) managedReceiveBlocks(conn modules.PeerConn) (returnErr e
 	for moreAvailable {
 		// Read a slice of blocks from the wire.
 		var newBlocks []types.Block
-		if err := encoding.ReadObject(conn, &newBlocks, uint64(MaxCatchUpBlocks)*types.BlockSizeLimit); err != nil {
+		if err := encoding.ReadObject(conn, &newBlocks, uint64(MaxCatchUpBlocks)*cs.staticMaxBlockSize); err != nil {
 			return err
 		}
 		if err := encoding.ReadObject(conn, &moreAvailable, 1); err != nil {
@@ -220,9 +220,21 @@ func (cs *ConsensusSet) managedReceiveBlocks(conn modules.PeerConn) (returnErr e
 		}
 		stalled = false
 
+		// Record the height of the peer before accepting the blocks, which can
+		// take a long time. The height can only be derived if the blocks form
+		// a chain.
+		chained := true
+		for i := 1; i < len(newBlocks) && chained; i++ {
+			chained = newBlocks[i].ParentID == newBlocks[i-1].ID()
+		}
+		lastID := newBlocks[len(newBlocks)-1].ID()
+		if chained {
+			cs.managedRecordPeerTip(conn.RPCAddr(), newBlocks[0].ParentID, len(newBlocks), lastID)
+		}
+
 		// Call managedAcceptBlock instead of AcceptBlock so as not to broadcast
 		// every block.
-		extended, acceptErr := cs.managedAcceptBlocks(newBlocks)
+		extended, acceptErr := cs.managedAcceptBlocksFrom(newBlocks, conn.RPCAddr())
 		if extended {
 			chainExtended = true
 		}
@@ -232,6 +244,9 @@ func (cs *ConsensusSet) managedReceiveBlocks(conn modules.PeerConn) (returnErr e
 		if acceptErr != nil && acceptErr != modules.ErrNonExtendingBlock && acceptErr != modules.ErrBlockKnown {
 			return acceptErr
 		}
+
+		// The peer has all of the blocks that it sent.
+		cs.inventory.markSeen(conn.RPCAddr(), lastID)
 	}
 	return nil
 }
@@ -300,7 +315,7 @@ func (cs *ConsensusSet) rpcSendBlocks(conn modules.PeerConn) error {
 	err = cs.db.View(func(tx *bolt.Tx) error {
 		csHeight = blockHeight(tx)
 		for _, id := range knownBlocks {
-			pb, err := getBlockMap(tx, id)
+			pb, err := cs.blockCache.getBlockMap(tx, id)
 			if err != nil {
 				continue
 			}
@@ -352,7 +367,7 @@ func (cs *ConsensusSet) rpcSendBlocks(conn modules.PeerConn) error {
 					cs.log.Critical("Unable to get path: height", height, ":: request", i)
 					return err
 				}
-				pb, err := getBlockMap(tx, id)
+				pb, err := cs.blockCache.getBlockMap(tx, id)
 				if err != nil {
 					cs.log.Critical("Unable to get block from block map: height", height, ":: request", i, ":: id", id)
 					return err
@@ -418,6 +433,7 @@ func (cs *ConsensusSet) threadedRPCRelayHeader(conn modules.PeerConn) error {
 	if err != nil {
 		return err
 	}
+	cs.inventory.markSeen(conn.RPCAddr(), h.ID())
 
 	// Start verification inside of a bolt View tx.
 	cs.mu.RLock()
@@ -426,6 +442,11 @@ func (cs *ConsensusSet) threadedRPCRelayHeader(conn modules.PeerConn) error {
 		return cs.validateHeader(boltTxWrapper{tx}, h)
 	})
 	cs.mu.RUnlock()
+	// A valid header tells us the height of the peer before its block has
+	// been fetched.
+	if err == nil || err == modules.ErrBlockKnown {
+		cs.managedRecordPeerTip(conn.RPCAddr(), h.ParentID, 1, h.ID())
+	}
 	// WARN: orphan multithreading logic (dangerous areas, see below)
 	//
 	// If the header is valid and extends the heaviest chain, fetch the
@@ -453,9 +474,16 @@ func (cs *ConsensusSet) threadedRPCRelayHeader(conn modules.PeerConn) error {
 	}
 
 	// WARN: orphan multithreading logic case #2
+	//
+	// Only request the block if it is not already being requested from
+	// another peer that announced it.
+	if !cs.inventory.startRequest(h.ID(), conn.RPCAddr()) {
+		return nil
+	}
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
+		defer cs.inventory.finishRequest(h.ID())
 		err = cs.gateway.RPC(conn.RPCAddr(), "SendBlk", cs.managedReceiveBlock(h.ID()))
 		if err != nil {
 			cs.log.Debugln("WARN: failed to get header's corresponding block:", err)
@@ -495,7 +523,7 @@ func (cs *ConsensusSet) rpcSendBlk(conn modules.PeerConn) error {
 	var b types.Block
 	cs.mu.RLock()
 	err = cs.db.View(func(tx *bolt.Tx) error {
-		pb, err := getBlockMap(tx, id)
+		pb, err := cs.blockCache.getBlockMap(tx, id)
 		if err != nil {
 			return err
 		}
@@ -523,17 +551,19 @@ func (cs *ConsensusSet) managedReceiveBlock(id types.BlockID) modules.RPCFunc {
 			return err
 		}
 		var block types.Block
-		if err := encoding.ReadObject(conn, &block, types.BlockSizeLimit); err != nil {
+		if err := encoding.ReadObject(conn, &block, cs.staticMaxBlockSize); err != nil {
 			return err
 		}
-		chainExtended, err := cs.managedAcceptBlocks([]types.Block{block})
-		if chainExtended {
-			cs.managedBroadcastBlock(block)
+		cs.inventory.markSeen(conn.RPCAddr(), block.ID())
+		cs.managedRecordPeerTip(conn.RPCAddr(), block.ParentID, 1, block.ID())
+		chainExtended, err := cs.managedAcceptBlocksFrom([]types.Block{block}, conn.RPCAddr())
+		if err == nil || err == ErrFutureTimestamp {
+			cs.clockSkew.record(block.Timestamp, cs.staticClock.Now())
 		}
-		if err != nil {
-			return err
+		if chainExtended {
+			cs.managedBroadcastBlock(block, conn.RPCAddr())
 		}
-		return nil
+		return err
 	}
 }
 
@@ -0,0 +1,96 @@
+package consensus
+
+import (
+	"sort"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+var (
+	// behindPeersThreshold is the number of blocks that the current height
+	// must be below the median peer height for the consensus set to consider
+	// itself behind its peers.
+	behindPeersThreshold = build.Select(build.Var{
+		Standard: types.BlockHeight(24),
+		Dev:      types.BlockHeight(12),
+		Testing:  types.BlockHeight(3),
+	}).(types.BlockHeight)
+)
+
+// managedRecordPeerTip informs the gateway that the peer at addr has a chain
+// of n blocks that extends the block with id parentID and ends with the block
+// with id tipID. The height of the tip is derived from the height of the
+// parent, so that it can be recorded from headers and blocks before they are
+// accepted. Nothing is recorded if the parent is not in the block map.
+func (cs *ConsensusSet) managedRecordPeerTip(addr modules.NetAddress, parentID types.BlockID, n int, tipID types.BlockID) {
+	var height types.BlockHeight
+	cs.mu.RLock()
+	err := cs.db.View(func(tx *bolt.Tx) error {
+		pb, err := cs.blockCache.getBlockMap(tx, parentID)
+		if err != nil {
+			return err
+		}
+		height = pb.Height + types.BlockHeight(n)
+		return nil
+	})
+	cs.mu.RUnlock()
+	if err != nil {
+		return
+	}
+	cs.gateway.SetPeerTip(addr, height, tipID)
+}
+
+// SyncProgress estimates how far the consensus set has progressed in
+// synchronizing with the network. The target height is the highest block
+// height reported by a connected peer, and fraction is current/target, capped
+// at 1. If no peer has reported a height, target and fraction are 0.
+func (cs *ConsensusSet) SyncProgress() (current, target types.BlockHeight, fraction float64) {
+	if err := cs.tg.Add(); err != nil {
+		return 0, 0, 0
+	}
+	defer cs.tg.Done()
+
+	current = cs.Height()
+	for _, height := range cs.gateway.PeerHeights() {
+		if height > target {
+			target = height
+		}
+	}
+	if target == 0 {
+		return current, 0, 0
+	}
+	if current >= target {
+		return current, target, 1
+	}
+	return current, target, float64(current) / float64(target)
+}
+
+// BehindPeers reports whether the current height is more than
+// behindPeersThreshold blocks below the median height reported by connected
+// peers, which indicates that the consensus set is either still synchronizing
+// or is being eclipsed. Peer heights are recorded from the headers and blocks
+// that peers relay before the blocks are accepted, so a consensus set that is
+// busy accepting a long chain reports being behind until it has caught up. If
+// no peer has reported a height, the median is 0 and the consensus set is not
+// considered behind.
+func (cs *ConsensusSet) BehindPeers() (median types.BlockHeight, behind bool) {
+	if err := cs.tg.Add(); err != nil {
+		return 0, false
+	}
+	defer cs.tg.Done()
+
+	var heights []types.BlockHeight
+	for _, height := range cs.gateway.PeerHeights() {
+		heights = append(heights, height)
+	}
+	if len(heights) == 0 {
+		return 0, false
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+	median = heights[len(heights)/2]
+	return median, cs.Height()+behindPeersThreshold < median
+}
@@ -0,0 +1,159 @@
+package consensus
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestSyncProgress checks that the sync progress uses the heights reported by
+// peers as they send blocks.
+func TestSyncProgress(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst1, err := createConsensusSetTester(t.Name() + "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst1.Close()
+	cst2, err := blankConsensusSetTester(t.Name()+"2", modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst2.Close()
+
+	// Without peers there is no target.
+	current, target, fraction := cst2.cs.SyncProgress()
+	if current != 0 || target != 0 || fraction != 0 {
+		t.Fatalf("expected no progress without peers, got %v/%v (%v)", current, target, fraction)
+	}
+
+	// Connecting to a peer triggers a sync, after which the peer's height is
+	// the target.
+	if err := cst2.gateway.Connect(cst1.gateway.Address()); err != nil {
+		t.Fatal(err)
+	}
+	height := cst1.cs.Height()
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		current, target, fraction := cst2.cs.SyncProgress()
+		if current != height || target != height || fraction != 1 {
+			return fmt.Errorf("expected %v/%v (1), got %v/%v (%v)", height, height, current, target, fraction)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A peer height above our own gives a partial fraction.
+	cst2.gateway.SetPeerTip(cst1.gateway.Address(), 4*height, types.BlockID{})
+	current, target, fraction = cst2.cs.SyncProgress()
+	if current != height || target != 4*height || fraction != 0.25 {
+		t.Fatalf("expected %v/%v (0.25), got %v/%v (%v)", height, 4*height, current, target, fraction)
+	}
+}
+
+// TestBehindPeers checks that the consensus set reports being behind when its
+// height is well below the median peer height, and that the gateway records
+// the tips relayed by peers.
+func TestBehindPeers(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst1, err := createConsensusSetTester(t.Name() + "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst1.Close()
+	cst2, err := blankConsensusSetTester(t.Name()+"2", modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst2.Close()
+
+	if median, behind := cst2.cs.BehindPeers(); median != 0 || behind {
+		t.Fatalf("expected no median without peers, got %v (%v)", median, behind)
+	}
+
+	// After synchronizing, the peer's tip is recorded and the consensus set
+	// is not behind.
+	if err := cst2.gateway.Connect(cst1.gateway.Address()); err != nil {
+		t.Fatal(err)
+	}
+	tip := cst1.cs.CurrentBlock().ID()
+	height := cst1.cs.Height()
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		peerTip := cst2.gateway.PeerTips()[cst1.gateway.Address()]
+		if peerTip.ID != tip || peerTip.Height != height {
+			return fmt.Errorf("expected tip %v at %v, got %v", tip, height, peerTip)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if median, behind := cst2.cs.BehindPeers(); median != height || behind {
+		t.Fatalf("expected median %v and not behind, got %v (%v)", height, median, behind)
+	}
+
+	// A peer relaying a chain of blocks that reaches well above the current
+	// height puts the consensus set behind before the blocks are accepted.
+	n := int(behindPeersThreshold) + 1
+	cst2.cs.managedRecordPeerTip(cst1.gateway.Address(), cst2.cs.CurrentBlock().ID(), n, types.BlockID{1})
+	if median, behind := cst2.cs.BehindPeers(); median != height+behindPeersThreshold+1 || !behind {
+		t.Fatalf("expected to be behind median %v, got %v (%v)", height+behindPeersThreshold+1, median, behind)
+	}
+}
+
+// TestRecordPeerTip checks that the height of a peer is derived from the
+// parent of the blocks that it relays, before the blocks are known.
+func TestRecordPeerTip(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst1, err := createConsensusSetTester(t.Name() + "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst1.Close()
+	cst2, err := blankConsensusSetTester(t.Name()+"2", modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst2.Close()
+	if err := cst2.gateway.Connect(cst1.gateway.Address()); err != nil {
+		t.Fatal(err)
+	}
+	addr := cst1.gateway.Address()
+	height := cst1.cs.Height()
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		if cst2.cs.Height() != height {
+			return fmt.Errorf("expected height %v, got %v", height, cst2.cs.Height())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A chain of unknown blocks extending the current block.
+	tipID := types.BlockID{1}
+	cst2.cs.managedRecordPeerTip(addr, cst2.cs.CurrentBlock().ID(), 5, tipID)
+	if peerTip := cst2.gateway.PeerTips()[addr]; peerTip.Height != height+5 || peerTip.ID != tipID {
+		t.Fatalf("expected tip %v at %v, got %v", tipID, height+5, peerTip)
+	}
+
+	// Nothing is recorded for a chain with an unknown parent.
+	cst2.cs.managedRecordPeerTip(addr, types.BlockID{2}, 100, types.BlockID{3})
+	if peerTip := cst2.gateway.PeerTips()[addr]; peerTip.Height != height+5 {
+		t.Fatal("recorded the tip of a chain with an unknown parent:", peerTip)
+	}
+}
@@ -0,0 +1,167 @@
+package consensus
+
+import (
+	"encoding/binary"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/gateway"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestChainParams configures the consensus set created by NewTestChain.
+type TestChainParams struct {
+	// Name names the test directory of the consensus set. It must be unique
+	// among the tests that run in parallel.
+	Name string
+
+	// PayoutAddress receives the miner payouts of the mined blocks.
+	PayoutAddress types.UnlockHash
+}
+
+// NewTestChain creates a consensus set and extends it with nBlocks freshly
+// mined blocks, which are returned in order. The blocks can be replayed into
+// another consensus set with AcceptBlock. The gateway of the consensus set is
+// closed along with the consensus set.
+func NewTestChain(params TestChainParams, nBlocks int) ([]types.Block, *ConsensusSet, error) {
+	testdir := build.TempDir(modules.ConsensusDir, params.Name)
+	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
+	if err != nil {
+		return nil, nil, err
+	}
+	cs, err := New(g, false, filepath.Join(testdir, modules.ConsensusDir))
+	if err != nil {
+		g.Close()
+		return nil, nil, err
+	}
+	cs.tg.AfterStop(func() {
+		g.Close()
+	})
+
+	blocks, err := ExtendTestChain(cs, cs.CurrentBlock().ID(), nBlocks, params.PayoutAddress)
+	if err != nil {
+		cs.Close()
+		return nil, nil, err
+	}
+	return blocks, cs, nil
+}
+
+// ExtendTestChain mines n blocks on top of the block with the provided id and
+// adds them to the consensus set, returning the blocks in order. The parent may
+// be any block that the consensus set knows about, so ExtendTestChain can be
+// used to build forks. If the fork becomes heavier than the current path, the
+// consensus set reorgs onto it. Blocks that pay out to different addresses are
+// distinct, even if they have the same parent.
+func ExtendTestChain(cs *ConsensusSet, parent types.BlockID, n int, payout types.UnlockHash) ([]types.Block, error) {
+	var blocks []types.Block
+	for i := 0; i < n; i++ {
+		b, err := MineTestBlock(cs, parent, nil, payout)
+		if err != nil {
+			return nil, err
+		}
+		err = cs.AcceptBlock(b)
+		if err != nil && err != modules.ErrNonExtendingBlock {
+			return nil, err
+		}
+		blocks = append(blocks, b)
+		parent = b.ID()
+	}
+	return blocks, nil
+}
+
+// MineTestBlock returns a solved block with the provided transactions on top
+// of the block with the provided id, without adding it to the consensus set.
+// The miner payout, including the fees of the transactions, is sent to
+// payout.
+func MineTestBlock(cs *ConsensusSet, parent types.BlockID, txns []types.Transaction, payout types.UnlockHash) (types.Block, error) {
+	pbi, err := cs.ProcessedBlock(parent)
+	if err != nil {
+		return types.Block{}, err
+	}
+	target, exists := cs.ChildTarget(parent)
+	if !exists {
+		return types.Block{}, errors.New("no target for the parent block")
+	}
+	minTimestamp, _ := cs.MinimumValidChildTimestamp(parent)
+
+	b := types.Block{
+		ParentID:     parent,
+		Timestamp:    types.CurrentTimestamp(),
+		Transactions: txns,
+	}
+	if b.Timestamp < minTimestamp {
+		b.Timestamp = minTimestamp
+	}
+	b.MinerPayouts = []types.SiacoinOutput{{
+		Value:      b.CalculateSubsidy(pbi.Height + 1),
+		UnlockHash: payout,
+	}}
+
+	// Only the nonce changes while solving, so the merkle root of the block
+	// does not need to be recomputed.
+	header := b.Header()
+	for nonce := uint64(0); ; nonce++ {
+		binary.LittleEndian.PutUint64(header.Nonce[:], nonce)
+		if checkTarget(b, header.ID(), target) {
+			b.Nonce = header.Nonce
+			return b, nil
+		}
+	}
+}
+
+// TestNewTestChain checks that the test chain helpers produce valid blocks
+// that can be replayed, and forks that reorg the consensus set once they
+// become heavier than the current path.
+func TestNewTestChain(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	blocks, cs, err := NewTestChain(TestChainParams{Name: t.Name()}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+	if len(blocks) != 5 || cs.Height() != 5 || cs.CurrentBlock().ID() != blocks[4].ID() {
+		t.Fatal("test chain has the wrong blocks")
+	}
+
+	// Replay the blocks into another consensus set.
+	_, replay, err := NewTestChain(TestChainParams{Name: t.Name() + "-replay"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replay.Close()
+	for _, b := range blocks {
+		if err := replay.AcceptBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if replay.CurrentBlock().ID() != cs.CurrentBlock().ID() {
+		t.Fatal("replayed chain has a different tip")
+	}
+
+	// A fork that is not heavier than the current path does not change it.
+	fork, err := ExtendTestChain(cs, blocks[2].ID(), 1, randAddress())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fork[0].ID() == blocks[3].ID() {
+		t.Fatal("fork block is identical to the current path")
+	}
+	if cs.CurrentBlock().ID() != blocks[4].ID() {
+		t.Fatal("consensus set reorged onto a lighter fork")
+	}
+
+	// Extending the fork past the current path causes a reorg.
+	fork, err = ExtendTestChain(cs, fork[0].ID(), 2, randAddress())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs.Height() != 6 || cs.CurrentBlock().ID() != fork[1].ID() {
+		t.Fatal("consensus set did not reorg onto the heavier fork")
+	}
+}
@@ -0,0 +1,289 @@
+package consensus
+
+// trusted.go implements bootstrapping the consensus set from a trusted file of
+// blocks, as produced by StreamBlocks. Blocks up to a trusted tip are applied
+// without validating proof of work, timestamps or transactions, which is much
+// faster than accepting them from peers.
+//
+// The trusted blocks are first added to the block tree without being applied,
+// in batches of trustedLoadBatchSize blocks per database transaction. Only
+// once the trusted tip has been found are they applied to the current path,
+// again in batches. A file that does not lead to the trusted tip therefore
+// never moves the current block, and its blocks are removed from the block
+// tree again.
+
+import (
+	"bufio"
+	"errors"
+	"io"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+var (
+	// trustedLoadBatchSize is the number of blocks that are added, applied or
+	// removed in a single database transaction by LoadBlockchainFile.
+	trustedLoadBatchSize = build.Select(build.Var{
+		Standard: 1000,
+		Dev:      100,
+		Testing:  2,
+	}).(int)
+
+	errTrustedBlockNotExtending = errors.New("trusted block does not extend the current path")
+	errTrustedTipNotFound       = errors.New("blockchain file does not contain the trusted tip")
+)
+
+// stageTrustedBlock adds a block that is an ancestor of a trusted tip to the
+// block tree without applying it. Only the structure of the block is checked,
+// and the block must be a child of 'parentID'. A block that is already in the
+// block tree off the current path, such as a block left over from an earlier
+// load, is reused. 'staged' reports whether the block was added by this call.
+func (cs *ConsensusSet) stageTrustedBlock(tx *bolt.Tx, b types.Block, id, parentID types.BlockID) (staged bool, err error) {
+	if b.ParentID != parentID {
+		return false, errTrustedBlockNotExtending
+	}
+	if pb, err := getBlockMap(tx, id); err == nil {
+		if pb.DiffsGenerated {
+			// The block was applied before, and was reverted by a reorg.
+			return false, errTrustedBlockNotExtending
+		}
+		return false, nil
+	}
+	if uint64(b.MarshalSiaSize()) > types.BlockSizeLimit {
+		return false, errLargeBlock
+	}
+	parent, err := getBlockMap(tx, parentID)
+	if err != nil {
+		return false, err
+	}
+	if err := cs.checkBlockPolicy(b, id, parent.Height+1); err != nil {
+		return false, err
+	}
+	cs.newChild(tx, parent, b)
+	return true, nil
+}
+
+// unstageTrustedBlocks removes blocks that were added by stageTrustedBlock from
+// the block tree. The blocks were never applied, so no change entry refers to
+// them.
+func (cs *ConsensusSet) unstageTrustedBlocks(ids []types.BlockID) error {
+	for len(ids) > 0 {
+		n := trustedLoadBatchSize
+		if n > len(ids) {
+			n = len(ids)
+		}
+		err := cs.db.Update(func(tx *bolt.Tx) error {
+			for _, id := range ids[:n] {
+				pb, err := getBlockMap(tx, id)
+				if err != nil {
+					return err
+				}
+				if err := removeBlockHeight(tx, pb.Height, id); err != nil {
+					return err
+				}
+				cs.blockCache.evict(tx, id)
+				for _, bucket := range [][]byte{BlockMap, BucketOak, TransactionOffsets} {
+					if err := tx.Bucket(bucket).Delete(id[:]); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		ids = ids[n:]
+	}
+	return nil
+}
+
+// applyTrustedBlocks applies the blocks in 'path', which must extend the
+// current block, without validating them.
+func (cs *ConsensusSet) applyTrustedBlocks(path []types.BlockID) error {
+	for len(path) > 0 {
+		n := trustedLoadBatchSize
+		if n > len(path) {
+			n = len(path)
+		}
+		var changes []changeEntry
+		err := cs.updateChangeLogged(func(tx *bolt.Tx) error {
+			for _, id := range path[:n] {
+				pb, err := getBlockMap(tx, id)
+				if err != nil {
+					return err
+				}
+				if pb.Block.ParentID != currentBlockID(tx) {
+					return errTrustedBlockNotExtending
+				}
+				err = generateAndApplyDiffs(tx, pb, validateNothing)
+				cs.blockCache.evict(tx, id)
+				if err != nil {
+					return err
+				}
+				ce := changeEntry{AppliedBlocks: []types.BlockID{id}}
+				if err := cs.logChange(tx, ce); err != nil {
+					return err
+				}
+				changes = append(changes, ce)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		cs.refreshSiafundPool()
+		cs.notifyTipChanged()
+		for _, ce := range changes {
+			cs.updateSubscribers(ce)
+		}
+		path = path[n:]
+	}
+	return nil
+}
+
+// LoadBlockchainFile reads blocks encoded by StreamBlocks from 'r' and adds
+// them to the consensus set. Blocks up to and including 'trustedTip' only get
+// their structure and parent links checked, blocks after the trusted tip are
+// fully validated. If the trusted tip is not found in the file, or any block
+// before it is rejected, the current block is left unchanged and none of the
+// blocks are added. Blocks after the trusted tip are accepted like blocks
+// from peers: if one is rejected, the blocks before it remain in the
+// consensus set.
+func (cs *ConsensusSet) LoadBlockchainFile(r io.Reader, trustedTip types.BlockID) error {
+	err := cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if cs.integrityErr != nil {
+		return cs.integrityErr
+	}
+
+	br := bufio.NewReader(r)
+	dec := encoding.NewDecoder(br)
+	// readBlock reads the next block of the file. The decoder does not report
+	// a clean io.EOF, so the end of the file is checked before decoding.
+	readBlock := func() (b types.Block, err error) {
+		if _, err = br.Peek(1); err != nil {
+			return types.Block{}, err
+		}
+		err = dec.Decode(&b)
+		return b, err
+	}
+
+	// Add the blocks up to the trusted tip to the block tree. The blocks at the
+	// start of the file that are already in the current path are skipped, the
+	// first new block must extend the current block.
+	var path, staged []types.BlockID
+	var tip types.BlockID
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		tip = currentBlockID(tx)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	reachedTip := false
+	for !reachedTip && err == nil {
+		var batchStaged []types.BlockID
+		err = cs.db.Update(func(tx *bolt.Tx) error {
+			for i := 0; i < trustedLoadBatchSize && !reachedTip; i++ {
+				b, err := readBlock()
+				if err == io.EOF {
+					return errTrustedTipNotFound
+				} else if err != nil {
+					return err
+				}
+				id := b.ID()
+				reachedTip = id == trustedTip
+
+				if len(path) == 0 {
+					if pb, err := getBlockMap(tx, id); err == nil {
+						if pathID, err := getPath(tx, pb.Height); err == nil && pathID == id {
+							continue
+						}
+					}
+				}
+				parentID := tip
+				if len(path) > 0 {
+					parentID = path[len(path)-1]
+				}
+				added, err := cs.stageTrustedBlock(tx, b, id, parentID)
+				if err != nil {
+					return err
+				}
+				if added {
+					batchStaged = append(batchStaged, id)
+				}
+				path = append(path, id)
+			}
+			return nil
+		})
+		if err == nil {
+			staged = append(staged, batchStaged...)
+		}
+	}
+	if err != nil {
+		cs.log.Println("WARN: failed to load blockchain file:", err)
+		if unstageErr := cs.unstageTrustedBlocks(staged); unstageErr != nil {
+			cs.log.Severe("ERROR: unable to remove the blocks of a failed blockchain file load:", unstageErr)
+		}
+		return err
+	}
+
+	// Apply the trusted blocks.
+	if err := cs.applyTrustedBlocks(path); err != nil {
+		cs.log.Println("WARN: failed to apply the blocks of a blockchain file:", err)
+		return err
+	}
+
+	// Blocks beyond the trusted tip are fully validated.
+	for done := false; !done; {
+		var changes []changeEntry
+		err = cs.updateChangeLogged(func(tx *bolt.Tx) error {
+			for i := 0; i < trustedLoadBatchSize; i++ {
+				b, err := readBlock()
+				if err == io.EOF {
+					done = true
+					return nil
+				} else if err != nil {
+					return err
+				}
+				id := b.ID()
+				parent, err := cs.validateHeaderAndBlock(boltTxWrapper{tx}, b, id)
+				if err == modules.ErrBlockKnown {
+					continue
+				} else if err != nil {
+					return err
+				}
+				ce, err := cs.addBlockToTree(tx, b, parent)
+				if err == modules.ErrNonExtendingBlock {
+					continue
+				} else if err != nil {
+					return err
+				}
+				changes = append(changes, ce)
+			}
+			return nil
+		})
+		if err != nil {
+			cs.log.Println("WARN: failed to load blockchain file:", err)
+			return err
+		}
+		cs.refreshSiafundPool()
+		cs.notifyTipChanged()
+		for _, ce := range changes {
+			cs.updateSubscribers(ce)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package consensus
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+// TestLoadBlockchainFile checks that a consensus set can be bootstrapped from
+// a file produced by StreamBlocks, and that failed loads add no blocks.
+func TestLoadBlockchainFile(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+	// Mine enough blocks for the trusted part of the file to span several
+	// batches.
+	for cst.cs.Height()/2 <= types.BlockHeight(trustedLoadBatchSize)+1 {
+		if _, err := cst.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := cst.cs.StreamBlocks(0, &buf); err != nil {
+		t.Fatal(err)
+	}
+	file := buf.Bytes()
+	trustedHeight := cst.cs.Height() / 2
+	trustedTip, _ := cst.cs.BlockAtHeight(trustedHeight)
+
+	blank, err := blankConsensusSetTester(t.Name()+"-blank", modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer blank.Close()
+
+	// inBlockTree reports whether the block at 'height' of the tester is in
+	// the block tree of the blank consensus set.
+	inBlockTree := func(height types.BlockHeight) bool {
+		b, _ := cst.cs.BlockAtHeight(height)
+		var exists bool
+		blank.cs.db.View(func(tx *bolt.Tx) error {
+			_, err := getBlockMap(tx, b.ID())
+			exists = err == nil
+			return nil
+		})
+		return exists
+	}
+
+	// A trusted tip that is not in the file should cause the load to fail
+	// without adding any blocks.
+	err = blank.cs.LoadBlockchainFile(bytes.NewReader(file), types.BlockID{1})
+	if err != errTrustedTipNotFound {
+		t.Fatal("expected errTrustedTipNotFound, got", err)
+	}
+	if blank.cs.Height() != 0 || inBlockTree(1) {
+		t.Fatal("blocks were added by a failed load")
+	}
+
+	// A file with a broken parent link should be rejected, both within the
+	// first batch and after earlier batches were committed.
+	for _, tamperHeight := range []types.BlockHeight{1, trustedHeight - 1} {
+		var tampered bytes.Buffer
+		enc := encoding.NewEncoder(&tampered)
+		for i := types.BlockHeight(0); i <= cst.cs.Height(); i++ {
+			b, _ := cst.cs.BlockAtHeight(i)
+			if i == tamperHeight {
+				b.Timestamp++
+			}
+			if err := enc.Encode(b); err != nil {
+				t.Fatal(err)
+			}
+		}
+		err = blank.cs.LoadBlockchainFile(&tampered, trustedTip.ID())
+		if err != errTrustedBlockNotExtending {
+			t.Fatal("expected errTrustedBlockNotExtending, got", err)
+		}
+		if blank.cs.Height() != 0 || inBlockTree(1) {
+			t.Fatal("blocks were added by a failed load")
+		}
+	}
+
+	// Load the file, fully validating the blocks beyond the trusted tip.
+	err = blank.cs.LoadBlockchainFile(bytes.NewReader(file), trustedTip.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blank.cs.CurrentBlock().ID() != cst.cs.CurrentBlock().ID() {
+		t.Fatal("consensus set did not load the blockchain file")
+	}
+
+	// The consensus set should be able to continue from the loaded blocks.
+	b, err := cst.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := blank.cs.AcceptBlock(b); err != nil {
+		t.Fatal(err)
+	}
+}
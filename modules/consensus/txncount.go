@@ -0,0 +1,69 @@
+package consensus
+
+// txncount.go maintains a running count of the transactions in the current
+// path. The count is updated in the same database transaction that applies or
+// reverts a block, so it stays consistent across reorgs and restarts.
+
+import (
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+
+	"github.com/coreos/bbolt"
+)
+
+var (
+	// TransactionCount is a database bucket that holds the number of
+	// transactions in the current path, stored under a key of the same
+	// name.
+	TransactionCount = []byte("TransactionCount")
+)
+
+// getTransactionCount returns the number of transactions in the current path.
+func getTransactionCount(tx *bolt.Tx) (count uint64) {
+	err := encoding.Unmarshal(tx.Bucket(TransactionCount).Get(TransactionCount), &count)
+	if build.DEBUG && err != nil {
+		panic(err)
+	}
+	return count
+}
+
+// setTransactionCount stores the number of transactions in the current path.
+func setTransactionCount(tx *bolt.Tx, count uint64) {
+	err := tx.Bucket(TransactionCount).Put(TransactionCount, encoding.Marshal(count))
+	if build.DEBUG && err != nil {
+		panic(err)
+	}
+}
+
+// commitTransactionCount adds the transactions of a block to the count when
+// the block is applied, and subtracts them when the block is reverted.
+func commitTransactionCount(tx *bolt.Tx, pb *processedBlock, dir modules.DiffDirection) {
+	count := getTransactionCount(tx)
+	n := uint64(len(pb.Block.Transactions))
+	if dir == modules.DiffApply {
+		count += n
+	} else {
+		if build.DEBUG && count < n {
+			panic("transaction count underflow")
+		}
+		count -= n
+	}
+	setTransactionCount(tx, count)
+}
+
+// TotalTransactions returns the number of transactions in the blocks of the
+// current path, including the genesis block.
+func (cs *ConsensusSet) TotalTransactions() (count uint64, err error) {
+	err = cs.tg.Add()
+	if err != nil {
+		return 0, err
+	}
+	defer cs.tg.Done()
+
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		count = getTransactionCount(tx)
+		return nil
+	})
+	return count, err
+}
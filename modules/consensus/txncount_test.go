@@ -0,0 +1,96 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+// pathTransactions counts the transactions in the current path of the
+// consensus set by scanning every block.
+func pathTransactions(cs *ConsensusSet) (count uint64) {
+	for i := types.BlockHeight(0); i <= cs.Height(); i++ {
+		b, _ := cs.BlockAtHeight(i)
+		count += uint64(len(b.Transactions))
+	}
+	return count
+}
+
+// TestTotalTransactions checks that the transaction count follows the current
+// path through block application, reorgs and a rebuild of the count.
+func TestTotalTransactions(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cstMain, err := createConsensusSetTester(t.Name() + "-main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cstMain.Close()
+	cstAlt, err := createConsensusSetTester(t.Name() + "-alt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cstAlt.Close()
+
+	checkCount := func(cs *ConsensusSet) {
+		t.Helper()
+		count, err := cs.TotalTransactions()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp := pathTransactions(cs); count != exp {
+			t.Fatalf("expected %v transactions, got %v", exp, count)
+		}
+	}
+	checkCount(cstMain.cs)
+
+	// Confirm a transaction on the main chain.
+	before, _ := cstMain.cs.TotalTransactions()
+	if _, err := cstMain.wallet.SendSiacoins(types.SiacoinPrecision, randAddress()); err != nil {
+		t.Fatal(err)
+	}
+	b, err := cstMain.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := cstMain.cs.TotalTransactions(); len(b.Transactions) == 0 || after != before+uint64(len(b.Transactions)) {
+		t.Fatalf("expected %v transactions after the block, got %v", before+uint64(len(b.Transactions)), after)
+	}
+	checkCount(cstMain.cs)
+
+	// Reorg the main chain onto the alternate chain.
+	for cstAlt.cs.Height() <= cstMain.cs.Height() {
+		if _, err := cstAlt.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := types.BlockHeight(1); i <= cstAlt.cs.Height(); i++ {
+		b, _ := cstAlt.cs.BlockAtHeight(i)
+		_ = cstMain.cs.AcceptBlock(b)
+	}
+	if cstMain.cs.CurrentBlock().ID() != cstAlt.cs.CurrentBlock().ID() {
+		t.Fatal("main chain did not reorg")
+	}
+	checkCount(cstMain.cs)
+	mainCount, _ := cstMain.cs.TotalTransactions()
+	altCount, _ := cstAlt.cs.TotalTransactions()
+	if mainCount != altCount {
+		t.Fatalf("counts differ after reorg: %v vs %v", mainCount, altCount)
+	}
+
+	// Databases without the count rebuild it from the current path.
+	err = cstMain.cs.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(TransactionCount); err != nil {
+			return err
+		}
+		return backfillIndices(tx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCount(cstMain.cs)
+}
@@ -0,0 +1,75 @@
+package consensus
+
+// txnheights.go maintains an index from transaction ids to the height of the
+// block in the current path that contains the transaction. The index is
+// updated whenever a block is applied or reverted, so transactions that were
+// rolled back by a reorg are no longer reported.
+
+import (
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+var (
+	// TransactionHeights is a database bucket that maps the id of each
+	// transaction in the current path to the height of its block.
+	TransactionHeights = []byte("TransactionHeights")
+)
+
+// commitTransactionHeights adds the transactions of a block to the
+// transaction height index when the block is applied, and removes them when
+// the block is reverted. A transaction without inputs can appear in the
+// current path more than once, in which case the earliest height is kept.
+func commitTransactionHeights(tx *bolt.Tx, pb *processedBlock, dir modules.DiffDirection) {
+	b := tx.Bucket(TransactionHeights)
+	height := encoding.Marshal(pb.Height)
+	for _, txn := range pb.Block.Transactions {
+		id := txn.ID()
+		existing, exists := getTransactionHeight(tx, id)
+		var err error
+		if dir == modules.DiffApply && !exists {
+			err = b.Put(id[:], height)
+		} else if dir == modules.DiffRevert && exists && existing == pb.Height {
+			err = b.Delete(id[:])
+		}
+		if build.DEBUG && err != nil {
+			panic(err)
+		}
+	}
+}
+
+// getTransactionHeight returns the height of the block in the current path
+// that contains the transaction with the provided id.
+func getTransactionHeight(tx *bolt.Tx, id types.TransactionID) (height types.BlockHeight, exists bool) {
+	heightBytes := tx.Bucket(TransactionHeights).Get(id[:])
+	if heightBytes == nil {
+		return 0, false
+	}
+	err := encoding.Unmarshal(heightBytes, &height)
+	if build.DEBUG && err != nil {
+		panic(err)
+	}
+	return height, true
+}
+
+// TransactionExists reports whether the transaction with the provided id is
+// in the current path, along with the height of the block that contains it.
+// If the transaction does not exist, it is not in any block up to the current
+// height; a reorg may still change that.
+func (cs *ConsensusSet) TransactionExists(txid types.TransactionID) (exists bool, height types.BlockHeight, err error) {
+	err = cs.tg.Add()
+	if err != nil {
+		return false, 0, err
+	}
+	defer cs.tg.Done()
+
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		height, exists = getTransactionHeight(tx, txid)
+		return nil
+	})
+	return exists, height, err
+}
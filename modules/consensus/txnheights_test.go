@@ -0,0 +1,96 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+// TestTransactionExists checks that the transaction height index follows the
+// current path through block application, reorgs and a rebuild of the index.
+func TestTransactionExists(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cstMain, err := createConsensusSetTester(t.Name() + "-main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cstMain.Close()
+	cstAlt, err := createConsensusSetTester(t.Name() + "-alt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cstAlt.Close()
+
+	checkExists := func(txid types.TransactionID, expExists bool, expHeight types.BlockHeight) {
+		t.Helper()
+		exists, height, err := cstMain.cs.TransactionExists(txid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists != expExists || height != expHeight {
+			t.Fatalf("expected (%v, %v), got (%v, %v)", expExists, expHeight, exists, height)
+		}
+	}
+
+	// The transactions of the genesis block are indexed.
+	genesis, _ := cstMain.cs.BlockAtHeight(0)
+	checkExists(genesis.Transactions[0].ID(), true, 0)
+	checkExists(types.TransactionID{1}, false, 0)
+
+	// Confirm a transaction on the main chain.
+	txns, err := cstMain.wallet.SendSiacoins(types.SiacoinPrecision, randAddress())
+	if err != nil {
+		t.Fatal(err)
+	}
+	txid := txns[len(txns)-1].ID()
+	checkExists(txid, false, 0)
+	if _, err := cstMain.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	confirmHeight := cstMain.cs.Height()
+	checkExists(txid, true, confirmHeight)
+
+	// Reorg the main chain onto the alternate chain, which does not contain
+	// the transaction.
+	for cstAlt.cs.Height() <= cstMain.cs.Height() {
+		if _, err := cstAlt.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := types.BlockHeight(1); i <= cstAlt.cs.Height(); i++ {
+		b, _ := cstAlt.cs.BlockAtHeight(i)
+		_ = cstMain.cs.AcceptBlock(b)
+	}
+	if cstMain.cs.CurrentBlock().ID() != cstAlt.cs.CurrentBlock().ID() {
+		t.Fatal("main chain did not reorg")
+	}
+	checkExists(txid, false, 0)
+	for i := types.BlockHeight(1); i <= cstAlt.cs.Height(); i++ {
+		b, _ := cstAlt.cs.BlockAtHeight(i)
+		for _, txn := range b.Transactions {
+			checkExists(txn.ID(), true, i)
+		}
+	}
+
+	// Databases without the index rebuild it from the current path.
+	tip, _ := cstAlt.cs.BlockAtHeight(cstAlt.cs.Height())
+	err = cstMain.cs.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(TransactionHeights); err != nil {
+			return err
+		}
+		return backfillIndices(tx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkExists(genesis.Transactions[0].ID(), true, 0)
+	for _, txn := range tip.Transactions {
+		checkExists(txn.ID(), true, cstAlt.cs.Height())
+	}
+	checkExists(txid, false, 0)
+}
@@ -0,0 +1,83 @@
+package consensus
+
+// txnindex.go maintains the offsets of each transaction within the encoded
+// blocks of the block map. The offsets allow a single transaction to be read
+// from a block without decoding the rest of the block.
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+var (
+	// TransactionOffsets is a database bucket that maps the id of each block
+	// in the block map to the offsets of its transactions within the encoded
+	// processed block.
+	TransactionOffsets = []byte("TransactionOffsets")
+
+	errTxnIndexOutOfRange = errors.New("transaction index is out of range for the block")
+)
+
+// transactionOffsets returns the offsets at which each transaction of b begins
+// within the encoding of b, followed by the offset at which the last
+// transaction ends. The block is the first field of a processed block, so the
+// offsets are also valid within the encoded processed block.
+func transactionOffsets(b types.Block) []uint64 {
+	// The parent id, nonce, timestamp, and length prefixes of the miner
+	// payouts and transactions.
+	offset := uint64(len(b.ParentID) + len(b.Nonce) + 8 + 8 + 8)
+	for _, sco := range b.MinerPayouts {
+		offset += uint64(sco.Value.MarshalSiaSize() + len(sco.UnlockHash))
+	}
+	offsets := make([]uint64, 0, len(b.Transactions)+1)
+	for _, txn := range b.Transactions {
+		offsets = append(offsets, offset)
+		offset += uint64(txn.MarshalSiaSize())
+	}
+	return append(offsets, offset)
+}
+
+// addTransactionOffsets stores the transaction offsets of a block.
+func addTransactionOffsets(tx *bolt.Tx, b types.Block) {
+	id := b.ID()
+	err := tx.Bucket(TransactionOffsets).Put(id[:], encoding.Marshal(transactionOffsets(b)))
+	if build.DEBUG && err != nil {
+		panic(err)
+	}
+}
+
+// TransactionInBlock returns the transaction at the given index of a block in
+// the block map. Only the requested transaction is decoded.
+func (cs *ConsensusSet) TransactionInBlock(blockID types.BlockID, index int) (txn types.Transaction, err error) {
+	err = cs.tg.Add()
+	if err != nil {
+		return types.Transaction{}, err
+	}
+	defer cs.tg.Done()
+
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		pbBytes := tx.Bucket(BlockMap).Get(blockID[:])
+		offsetBytes := tx.Bucket(TransactionOffsets).Get(blockID[:])
+		if pbBytes == nil || offsetBytes == nil {
+			return errUnknownBlock
+		}
+		var offsets []uint64
+		if err := encoding.Unmarshal(offsetBytes, &offsets); err != nil {
+			return err
+		}
+		if index < 0 || index >= len(offsets)-1 {
+			return errTxnIndexOutOfRange
+		}
+		start, end := offsets[index], offsets[index+1]
+		if end > uint64(len(pbBytes)) || start > end {
+			return errors.New("transaction offsets do not match the block")
+		}
+		return encoding.Unmarshal(pbBytes[start:end], &txn)
+	})
+	return txn, err
+}
@@ -0,0 +1,75 @@
+package consensus
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+// TestTransactionInBlock checks that TransactionInBlock returns the same
+// transactions as decoding the full block, including after the offsets have
+// been rebuilt from the block map.
+func TestTransactionInBlock(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Mine a block with a few transactions.
+	for i := 0; i < 3; i++ {
+		_, err := cst.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(uint64(i+1)), types.UnlockHash{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := cst.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Transactions) < 3 {
+		t.Fatal("expected the block to contain transactions, got", len(b.Transactions))
+	}
+
+	checkBlock := func() {
+		for i, expected := range b.Transactions {
+			txn, err := cst.cs.TransactionInBlock(b.ID(), i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(encoding.Marshal(txn), encoding.Marshal(expected)) {
+				t.Fatalf("transaction %v does not match the block", i)
+			}
+		}
+		if _, err := cst.cs.TransactionInBlock(b.ID(), -1); err != errTxnIndexOutOfRange {
+			t.Fatal("expected errTxnIndexOutOfRange, got", err)
+		}
+		if _, err := cst.cs.TransactionInBlock(b.ID(), len(b.Transactions)); err != errTxnIndexOutOfRange {
+			t.Fatal("expected errTxnIndexOutOfRange, got", err)
+		}
+	}
+	checkBlock()
+	if _, err := cst.cs.TransactionInBlock(types.BlockID{}, 0); err != errUnknownBlock {
+		t.Fatal("expected errUnknownBlock, got", err)
+	}
+
+	// Databases without the offsets have them rebuilt from the block map.
+	err = cst.cs.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(TransactionOffsets); err != nil {
+			return err
+		}
+		return backfillIndices(tx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBlock()
+}
@@ -0,0 +1,89 @@
+package consensus
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+var (
+	// errCheckpointFork is returned for blocks that would fork the chain
+	// below the trusted block of ValidationCheckpoint.
+	errCheckpointFork = errors.New("block forks the chain below the checkpoint")
+
+	// errCheckpointMismatch is returned for blocks at the height of the
+	// trusted block of ValidationCheckpoint that are not the trusted block.
+	errCheckpointMismatch = errors.New("block at the checkpoint height is not the checkpoint")
+)
+
+// blockTxnValidation returns how thoroughly the transactions of a block that
+// is being applied are validated. Signatures are only skipped for blocks on
+// the trusted chain: blocks that extend the current path without a
+// reorganization, at or below the height of the trusted block, while the
+// trusted block is not yet known. A block at the trusted height must be the
+// trusted block itself. Every other block is fully validated, so an unknown or
+// mistyped trusted block can not disable signature verification above the
+// trusted height. The caller must hold cs.mu.
+func (cs *ConsensusSet) blockTxnValidation(tx *bolt.Tx, pb *processedBlock, extendsPath bool) txnValidation {
+	if cs.validationLevel == modules.ValidationFull || !extendsPath {
+		return validateTransactions
+	}
+	if pb.Height > cs.trustedHeight || (pb.Height == cs.trustedHeight && pb.Block.ID() != cs.trustedBlock) {
+		return validateTransactions
+	}
+	if tx.Bucket(BlockMap).Get(cs.trustedBlock[:]) != nil {
+		return validateTransactions
+	}
+	return validateNoSignatures
+}
+
+// checkCheckpoint returns errCheckpointFork if a new block with the given
+// parent would be at or below the height of the trusted block of
+// ValidationCheckpoint once the trusted block is known. Every block up to that
+// height on the trusted chain is already known, so a new block at such a
+// height can only be a fork. Before the trusted block is known,
+// errCheckpointMismatch is returned for any other block at its height. The
+// caller must hold cs.mu.
+func (cs *ConsensusSet) checkCheckpoint(tx dbTx, parent *processedBlock, id types.BlockID) error {
+	if cs.validationLevel != modules.ValidationCheckpoint {
+		return nil
+	}
+	height := parent.Height + 1
+	if tx.Bucket(BlockMap).Get(cs.trustedBlock[:]) != nil {
+		if height <= cs.trustedHeight {
+			return errCheckpointFork
+		}
+		return nil
+	}
+	if height == cs.trustedHeight && id != cs.trustedBlock {
+		return errCheckpointMismatch
+	}
+	return nil
+}
+
+// SetValidationLevel sets how strictly the consensus set validates blocks
+// relative to a trusted block at the provided height. At ValidationCheckpoint
+// and ValidationAssumeValid, the signatures of blocks on the trusted chain at
+// or below the trusted height are not verified until the trusted block has
+// been added to the consensus set, which greatly speeds up the initial sync.
+// Blocks above the trusted height, blocks applied by a reorganization, and
+// blocks that are applied after the trusted block is known are fully
+// validated. See the documentation of each level for the trust that it
+// requires. Unknown levels are treated as ValidationFull.
+func (cs *ConsensusSet) SetValidationLevel(level modules.ValidationLevel, trustedBlock types.BlockID, trustedHeight types.BlockHeight) {
+	switch level {
+	case modules.ValidationFull, modules.ValidationCheckpoint, modules.ValidationAssumeValid:
+	default:
+		build.Critical("unknown validation level:", level)
+		level = modules.ValidationFull
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.validationLevel = level
+	cs.trustedBlock = trustedBlock
+	cs.trustedHeight = trustedHeight
+}
@@ -0,0 +1,184 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// badSignatureBlock returns a solved block containing a transaction that
+// spends a confirmed output with an invalid signature.
+func (cst *consensusSetTester) badSignatureBlock(t *testing.T) types.Block {
+	_, pk := crypto.GenerateKeyPair()
+	uc := types.UnlockConditions{
+		PublicKeys:         []types.SiaPublicKey{types.Ed25519PublicKey(pk)},
+		SignaturesRequired: 1,
+	}
+	value := types.SiacoinPrecision
+	txns, err := cst.wallet.SendSiacoins(value, uc.UnlockHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	var parentID types.SiacoinOutputID
+	for i, sco := range txns[len(txns)-1].SiacoinOutputs {
+		if sco.UnlockHash == uc.UnlockHash() {
+			parentID = txns[len(txns)-1].SiacoinOutputID(uint64(i))
+		}
+	}
+
+	txn := types.Transaction{
+		SiacoinInputs:  []types.SiacoinInput{{ParentID: parentID, UnlockConditions: uc}},
+		SiacoinOutputs: []types.SiacoinOutput{{Value: value, UnlockHash: randAddress()}},
+		TransactionSignatures: []types.TransactionSignature{{
+			ParentID:      crypto.Hash(parentID),
+			CoveredFields: types.CoveredFields{WholeTransaction: true},
+			Signature:     fastrand.Bytes(crypto.SignatureSize),
+		}},
+	}
+	b, target, err := cst.miner.BlockForWork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Transactions = append(b.Transactions, txn)
+	solved, ok := cst.miner.SolveBlock(b, target)
+	if !ok {
+		t.Fatal("failed to solve block")
+	}
+	return solved
+}
+
+// TestValidationLevel checks that signatures are only skipped up to the
+// trusted height until the trusted block is known.
+func TestValidationLevel(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// While the trusted block is unknown, bad signatures are accepted below
+	// the trusted height.
+	cst.cs.SetValidationLevel(modules.ValidationAssumeValid, types.BlockID{1}, cst.cs.Height()+100)
+	if err := cst.cs.AcceptBlock(cst.badSignatureBlock(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Above the trusted height, blocks are fully validated even though the
+	// trusted block is unknown.
+	cst.cs.SetValidationLevel(modules.ValidationAssumeValid, types.BlockID{1}, cst.cs.Height())
+	if err := cst.cs.AcceptBlock(cst.badSignatureBlock(t)); err != crypto.ErrInvalidSignature {
+		t.Fatal("expected ErrInvalidSignature, got", err)
+	}
+
+	// Once the trusted block is known, blocks are fully validated.
+	cst.cs.SetValidationLevel(modules.ValidationAssumeValid, cst.cs.CurrentBlock().ID(), cst.cs.Height()+100)
+	if err := cst.cs.AcceptBlock(cst.badSignatureBlock(t)); err != crypto.ErrInvalidSignature {
+		t.Fatal("expected ErrInvalidSignature, got", err)
+	}
+
+	// The same holds at full validation, regardless of the trusted block.
+	cst.cs.SetValidationLevel(modules.ValidationFull, types.BlockID{1}, cst.cs.Height()+100)
+	if err := cst.cs.AcceptBlock(cst.badSignatureBlock(t)); err != crypto.ErrInvalidSignature {
+		t.Fatal("expected ErrInvalidSignature, got", err)
+	}
+}
+
+// TestValidationLevelFork checks that the signatures of fork blocks are
+// verified, even below the trusted height while the trusted block is unknown.
+func TestValidationLevelFork(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	cst.cs.SetValidationLevel(modules.ValidationAssumeValid, types.BlockID{1}, cst.cs.Height()+100)
+
+	// Put a block with a bad signature on a side chain, then extend the side
+	// chain so that it becomes the heaviest.
+	bad := cst.badSignatureBlock(t)
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cst.cs.AcceptBlock(bad); err != modules.ErrNonExtendingBlock {
+		t.Fatal("expected ErrNonExtendingBlock, got", err)
+	}
+	target, exists := cst.cs.ChildTarget(bad.ID())
+	if !exists {
+		t.Fatal("side chain block is unknown")
+	}
+	child := types.Block{
+		ParentID:     bad.ID(),
+		Timestamp:    types.CurrentTimestamp(),
+		MinerPayouts: []types.SiacoinOutput{{Value: types.CalculateCoinbase(cst.cs.Height() + 1), UnlockHash: randAddress()}},
+	}
+	child, ok := cst.miner.SolveBlock(child, target)
+	if !ok {
+		t.Fatal("failed to solve block")
+	}
+	tip := cst.cs.CurrentBlock().ID()
+	if err := cst.cs.AcceptBlock(child); err != crypto.ErrInvalidSignature {
+		t.Fatal("expected ErrInvalidSignature, got", err)
+	}
+	if cst.cs.CurrentBlock().ID() != tip {
+		t.Fatal("consensus set reorganized onto the invalid fork")
+	}
+}
+
+// TestValidationCheckpoint checks that the chain cannot be forked below the
+// trusted block at ValidationCheckpoint.
+func TestValidationCheckpoint(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Build a block that competes with the next block.
+	fork, _ := cst.miner.FindBlock()
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	checkpoint := cst.cs.CurrentBlock().ID()
+	height := cst.cs.Height()
+
+	cst.cs.SetValidationLevel(modules.ValidationCheckpoint, checkpoint, height)
+	if err := cst.cs.AcceptBlock(fork); err != errCheckpointFork {
+		t.Fatal("expected errCheckpointFork, got", err)
+	}
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// At ValidationAssumeValid, the fork is allowed.
+	cst.cs.SetValidationLevel(modules.ValidationAssumeValid, checkpoint, height)
+	if err := cst.cs.AcceptBlock(fork); err != modules.ErrNonExtendingBlock {
+		t.Fatal("expected ErrNonExtendingBlock, got", err)
+	}
+
+	// Before the checkpoint is known, any other block at its height is
+	// rejected.
+	next, _ := cst.miner.FindBlock()
+	cst.cs.SetValidationLevel(modules.ValidationCheckpoint, types.BlockID{1}, cst.cs.Height()+1)
+	if err := cst.cs.AcceptBlock(next); err != errCheckpointMismatch {
+		t.Fatal("expected errCheckpointMismatch, got", err)
+	}
+}
@@ -291,10 +291,23 @@ func validTransaction(tx *bolt.Tx, t types.Transaction) error {
 	if err != nil {
 		return err
 	}
+	return validTransactionContext(tx, t)
+}
+
+// validTransactionNoSignatures performs the checks of validTransaction except
+// for the verification of signatures.
+func validTransactionNoSignatures(tx *bolt.Tx, t types.Transaction) error {
+	err := t.StandaloneValidNoSignatures(blockHeight(tx))
+	if err != nil {
+		return err
+	}
+	return validTransactionContext(tx, t)
+}
 
-	// Check that each portion of the transaction is legal given the current
-	// consensus set.
-	err = validSiacoins(tx, t)
+// validTransactionContext checks that each portion of the transaction is legal
+// given the current consensus set.
+func validTransactionContext(tx *bolt.Tx, t types.Transaction) error {
+	err := validSiacoins(tx, t)
 	if err != nil {
 		return err
 	}
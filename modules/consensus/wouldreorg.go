@@ -0,0 +1,153 @@
+package consensus
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+var (
+	errNoHeaders         = errors.New("no headers were provided")
+	errHeadersNotChained = errors.New("headers do not form a chain")
+)
+
+// headerBlockMap is a block map that holds the processed blocks of headers
+// that have not been added to the consensus set, on top of the block map of
+// the database. The processed blocks only have the header fields of their
+// block set, which is all that the target and timestamp rules read, so a chain
+// of headers can be validated with the same helpers as a chain of blocks.
+type headerBlockMap struct {
+	base    dbBucket
+	headers map[types.BlockID][]byte
+}
+
+// Get implements the dbBucket interface.
+func (hm headerBlockMap) Get(key []byte) []byte {
+	var id types.BlockID
+	copy(id[:], key)
+	if pbBytes, exists := hm.headers[id]; exists {
+		return pbBytes
+	}
+	return hm.base.Get(key)
+}
+
+// headerChain is a chain of headers that has not been added to the consensus
+// set, extending a block that has.
+type headerChain struct {
+	blockMap headerBlockMap
+
+	// tip is the last header of the chain, along with its oak totals.
+	tip         processedBlock
+	totalTime   int64
+	totalTarget types.Target
+}
+
+// extendHeaderChain validates a header that extends the tip of the chain and
+// makes it the new tip. The header is validated by validateHeaderParent and
+// its child target is set by setChildTargetAt, like a block in validateHeader
+// and newChild.
+func (cs *ConsensusSet) extendHeaderChain(hc *headerChain, h types.BlockHeader) error {
+	id := h.ID()
+	if cs.dosBlocks.contains(id) {
+		return errDoSBlock
+	}
+	parent := hc.tip
+	if err := cs.validateHeaderParent(hc.blockMap, h, &parent); err != nil {
+		return err
+	}
+
+	child := processedBlock{
+		Block: types.Block{
+			ParentID:  h.ParentID,
+			Nonce:     h.Nonce,
+			Timestamp: h.Timestamp,
+		},
+		Height: parent.Height + 1,
+		Depth:  parent.childDepth(),
+	}
+	cs.setChildTargetAt(hc.blockMap, &parent, &child, hc.totalTime, hc.totalTarget)
+	hc.blockMap.headers[id] = encoding.Marshal(child)
+	hc.totalTime, hc.totalTarget = oakBlockTotals(child.Height, hc.totalTime, parent.Block.Timestamp, h.Timestamp, hc.totalTarget, parent.ChildTarget)
+	hc.tip = child
+	return nil
+}
+
+// headerChainTip validates a chain of headers and returns the processed block
+// that the last header would have. The first header must extend a known block,
+// and headers of known blocks are skipped.
+func (cs *ConsensusSet) headerChainTip(tx *bolt.Tx, headers []types.BlockHeader) (processedBlock, error) {
+	blockMap := tx.Bucket(BlockMap)
+	if blockMap == nil {
+		return processedBlock{}, errNoBlockMap
+	}
+	fork, err := getBlockMap(tx, headers[0].ParentID)
+	if err != nil {
+		return processedBlock{}, errOrphan
+	}
+
+	// Skip the headers of known blocks.
+	for len(headers) > 0 {
+		pb, err := getBlockMap(tx, headers[0].ID())
+		if err != nil {
+			break
+		}
+		fork, headers = pb, headers[1:]
+	}
+
+	hc := &headerChain{
+		blockMap: headerBlockMap{
+			base:    blockMap,
+			headers: make(map[types.BlockID][]byte),
+		},
+		tip: *fork,
+	}
+	hc.totalTime, hc.totalTarget = cs.getBlockTotals(tx, fork.Block.ID())
+	for _, h := range headers {
+		if err := cs.extendHeaderChain(hc, h); err != nil {
+			return processedBlock{}, err
+		}
+	}
+	return hc.tip, nil
+}
+
+// WouldReorg checks whether a chain of headers would become the current path
+// if the blocks it belongs to were submitted, without needing the block
+// bodies. The first header must extend a known block, and the rest must
+// extend the header before them. Headers of known blocks are skipped. The
+// height that the chain ends at is returned along with the result.
+//
+// Only the headers are validated, so the blocks may still turn out to be
+// invalid once they are downloaded.
+func (cs *ConsensusSet) WouldReorg(headers []types.BlockHeader) (wouldReorg bool, newHeight types.BlockHeight, err error) {
+	if len(headers) == 0 {
+		return false, 0, errNoHeaders
+	}
+	for i := 1; i < len(headers); i++ {
+		if headers[i].ParentID != headers[i-1].ID() {
+			return false, 0, errHeadersNotChained
+		}
+	}
+
+	err = cs.tg.Add()
+	if err != nil {
+		return false, 0, err
+	}
+	defer cs.tg.Done()
+
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		tip, err := cs.headerChainTip(tx, headers)
+		if err != nil {
+			return err
+		}
+		wouldReorg = tip.heavierThan(currentProcessedBlock(tx))
+		newHeight = tip.Height
+		return nil
+	})
+	if err != nil {
+		return false, 0, err
+	}
+	return wouldReorg, newHeight, nil
+}
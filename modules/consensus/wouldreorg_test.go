@@ -0,0 +1,107 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+// TestWouldReorg checks that WouldReorg predicts whether a fork becomes the
+// current path, and that the targets computed from the headers alone match
+// the ones computed from the full blocks.
+func TestWouldReorg(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	_, cs, err := NewTestChain(TestChainParams{Name: t.Name()}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+	// The fork crosses the oak hardfork height.
+	forkBlocks, forkCS, err := NewTestChain(TestChainParams{
+		Name:          t.Name() + "Fork",
+		PayoutAddress: types.UnlockHash{1},
+	}, int(types.OakHardforkBlock)+10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer forkCS.Close()
+	var headers []types.BlockHeader
+	for _, b := range forkBlocks {
+		headers = append(headers, b.Header())
+	}
+
+	if _, _, err := cs.WouldReorg(nil); err != errNoHeaders {
+		t.Fatal("expected errNoHeaders, got", err)
+	}
+	if _, _, err := cs.WouldReorg(headers[1:]); err != errOrphan {
+		t.Fatal("expected errOrphan, got", err)
+	}
+	if _, _, err := cs.WouldReorg([]types.BlockHeader{headers[1], headers[0]}); err != errHeadersNotChained {
+		t.Fatal("expected errHeadersNotChained, got", err)
+	}
+
+	// A fork that is shorter than the current path should not cause a reorg.
+	wouldReorg, height, err := cs.WouldReorg(headers[:3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wouldReorg || height != 3 {
+		t.Fatalf("expected no reorg at height 3, got %v at height %v", wouldReorg, height)
+	}
+
+	// A longer fork should.
+	wouldReorg, height, err = cs.WouldReorg(headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !wouldReorg || height != types.BlockHeight(len(headers)) {
+		t.Fatalf("expected a reorg at height %v, got %v at height %v", len(headers), wouldReorg, height)
+	}
+
+	// The depth and target of the tip should match those of the fork's
+	// consensus set.
+	var tip processedBlock
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		tip, err = cs.headerChainTip(tx, headers)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var forkTip *processedBlock
+	err = forkCS.db.View(func(tx *bolt.Tx) error {
+		forkTip, err = getBlockMap(tx, forkBlocks[len(forkBlocks)-1].ID())
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tip.Height != forkTip.Height || tip.Depth != forkTip.Depth || tip.ChildTarget != forkTip.ChildTarget {
+		t.Fatal("the tip computed from the headers does not match the fork")
+	}
+
+	// Submitting the fork should cause the predicted reorg, after which the
+	// headers are all known and no longer cause a reorg.
+	for _, b := range forkBlocks {
+		if err := cs.AcceptBlock(b); err != nil && err != modules.ErrNonExtendingBlock {
+			t.Fatal(err)
+		}
+	}
+	if cs.CurrentBlock().ID() != forkBlocks[len(forkBlocks)-1].ID() {
+		t.Fatal("consensus set did not reorg onto the fork")
+	}
+	wouldReorg, height, err = cs.WouldReorg(headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wouldReorg || height != types.BlockHeight(len(headers)) {
+		t.Fatalf("expected no reorg at height %v, got %v at height %v", len(headers), wouldReorg, height)
+	}
+}
@@ -45,6 +45,21 @@ type (
 		TotalRevisionVolume types.Currency `json:"totalrevisionvolume"`
 	}
 
+	// ExplorerStatistics contains chain-wide aggregates about the current
+	// state of the blockchain.
+	ExplorerStatistics struct {
+		Height              types.BlockHeight `json:"height"`
+		TotalCoins          types.Currency    `json:"totalcoins"`
+		TotalSiafunds       types.Currency    `json:"totalsiafunds"`
+		ActiveContractCount uint64            `json:"activecontractcount"`
+		ActiveContractSize  types.Currency    `json:"activecontractsize"`
+		Difficulty          types.Currency    `json:"difficulty"`
+
+		// AverageBlockTime is the average number of seconds between blocks,
+		// measured over the most recent blocks.
+		AverageBlockTime uint64 `json:"averageblocktime"`
+	}
+
 	// Explorer tracks the blockchain and provides tools for gathering
 	// statistics and finding objects or patterns within the blockchain.
 	Explorer interface {
@@ -60,6 +75,10 @@ type (
 		// in the explorer's database.
 		LatestBlockFacts() BlockFacts
 
+		// Statistics returns chain-wide aggregates about the blockchain as of
+		// the latest block in the explorer's database.
+		Statistics() (ExplorerStatistics, error)
+
 		// Transaction returns the block that contains the input transaction
 		// id. The transaction itself is either the block (indicating the miner
 		// payouts are somehow involved), or it is a transaction inside of the
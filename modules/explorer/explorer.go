@@ -4,16 +4,23 @@ package explorer
 
 import (
 	"errors"
+	"sync"
 
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/persist"
 	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
 )
 
 const (
 	// hashrateEstimationBlocks is the number of blocks that are used to
 	// estimate the current hashrate.
 	hashrateEstimationBlocks = 200 // 33 hours
+
+	// blockTimeEstimationBlocks is the number of blocks that are used to
+	// calculate the average block time.
+	blockTimeEstimationBlocks = 144 // 24 hours
 )
 
 var (
@@ -47,6 +54,11 @@ type (
 		cs         modules.ConsensusSet
 		db         *persist.BoltDatabase
 		persistDir string
+
+		// stats is a cache of the chain-wide statistics, updated each time
+		// a consensus change is processed.
+		stats   modules.ExplorerStatistics
+		statsMu sync.RWMutex
 	}
 )
 
@@ -83,6 +95,18 @@ func New(cs modules.ConsensusSet, persistDir string) (*Explorer, error) {
 		return nil, errors.New("explorer subscription failed: " + err.Error())
 	}
 
+	// The subscription will not have computed the statistics if the explorer
+	// was already synced with consensus.
+	var stats modules.ExplorerStatistics
+	err = e.db.View(func(tx *bolt.Tx) (err error) {
+		stats, err = e.dbComputeStatistics(tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.setStatistics(stats)
+
 	return e, nil
 }
 
@@ -53,6 +53,15 @@ func (e *Explorer) LatestBlockFacts() modules.BlockFacts {
 	return bf.BlockFacts
 }
 
+// Statistics returns chain-wide aggregates about the blockchain as of the
+// latest block processed by the explorer. The statistics are computed as
+// consensus changes are processed, so this call is cheap.
+func (e *Explorer) Statistics() (modules.ExplorerStatistics, error) {
+	e.statsMu.RLock()
+	defer e.statsMu.RUnlock()
+	return e.stats, nil
+}
+
 // Transaction takes a transaction ID and finds the block containing the
 // transaction. Because of the miner payouts, the transaction ID might be a
 // block ID. To find the transaction, iterate through the block.
@@ -75,6 +75,52 @@ func TestBlockFacts(t *testing.T) {
 	}
 }
 
+// TestStatistics checks that the explorer statistics match the latest block
+// facts and are updated as blocks are added.
+func TestStatistics(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	et, err := createExplorerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := et.explorer.Statistics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	facts := et.explorer.LatestBlockFacts()
+	if stats.Height != et.cs.Height() || stats.Height != facts.Height {
+		t.Error("wrong height reported in statistics:", stats.Height, et.cs.Height())
+	}
+	if !stats.TotalCoins.Equals(types.CalculateNumSiacoins(et.cs.Height())) {
+		t.Error("wrong number of total coins:", stats.TotalCoins)
+	}
+	if !stats.TotalSiafunds.Equals(types.SiafundCount) {
+		t.Error("wrong number of total siafunds:", stats.TotalSiafunds)
+	}
+	if !stats.Difficulty.Equals(facts.Difficulty) {
+		t.Error("difficulty does not match latest block facts")
+	}
+
+	// Mine a block and check that the statistics are updated.
+	_, err = et.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err = et.explorer.Statistics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Height != et.cs.Height() {
+		t.Error("statistics were not updated after mining a block")
+	}
+	if !stats.TotalCoins.Equals(types.CalculateNumSiacoins(et.cs.Height())) {
+		t.Error("wrong number of total coins after mining a block:", stats.TotalCoins)
+	}
+}
+
 // TestFileContractPayouts checks that file contract outputs are tracked by the explorer
 func TestFileContractPayoutsMissingProof(t *testing.T) {
 	if testing.Short() {
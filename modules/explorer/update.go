@@ -18,6 +18,7 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 		build.Critical("Explorer.ProcessConsensusChange called with a ConsensusChange that has no AppliedBlocks")
 	}
 
+	var stats modules.ExplorerStatistics
 	err := e.db.Update(func(tx *bolt.Tx) (err error) {
 		// use exception-style error handling to enable more concise update code
 		defer func() {
@@ -276,11 +277,15 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 			return err
 		}
 
-		return nil
+		stats, err = e.dbComputeStatistics(tx)
+		return err
 	})
 	if err != nil {
 		build.Critical("explorer update failed:", err)
+		return
 	}
+	// The statistics only change once the update has been committed.
+	e.setStatistics(stats)
 }
 
 // helper functions
@@ -524,6 +529,55 @@ func dbCalculateBlockFacts(tx *bolt.Tx, cs modules.ConsensusSet, block types.Blo
 	return bf
 }
 
+// dbComputeStatistics computes the chain-wide statistics from the block facts
+// of the latest block.
+func (e *Explorer) dbComputeStatistics(tx *bolt.Tx) (modules.ExplorerStatistics, error) {
+	var height types.BlockHeight
+	err := dbGetInternal(internalBlockHeight, &height)(tx)
+	if err != nil {
+		return modules.ExplorerStatistics{}, err
+	}
+	var bf blockFacts
+	err = e.dbGetBlockFacts(height, &bf)(tx)
+	if err != nil {
+		return modules.ExplorerStatistics{}, err
+	}
+
+	// Calculate the average block time over the most recent blocks.
+	var averageBlockTime uint64
+	window := types.BlockHeight(blockTimeEstimationBlocks)
+	if height < window {
+		window = height
+	}
+	if window > 0 {
+		var oldFacts blockFacts
+		err = e.dbGetBlockFacts(height-window, &oldFacts)(tx)
+		if err != nil {
+			return modules.ExplorerStatistics{}, err
+		}
+		if bf.Timestamp > oldFacts.Timestamp {
+			averageBlockTime = uint64(bf.Timestamp-oldFacts.Timestamp) / uint64(window)
+		}
+	}
+
+	return modules.ExplorerStatistics{
+		Height:              height,
+		TotalCoins:          bf.TotalCoins,
+		TotalSiafunds:       types.SiafundCount,
+		ActiveContractCount: bf.ActiveContractCount,
+		ActiveContractSize:  bf.ActiveContractSize,
+		Difficulty:          bf.Difficulty,
+		AverageBlockTime:    averageBlockTime,
+	}, nil
+}
+
+// setStatistics replaces the cached chain-wide statistics.
+func (e *Explorer) setStatistics(stats modules.ExplorerStatistics) {
+	e.statsMu.Lock()
+	e.stats = stats
+	e.statsMu.Unlock()
+}
+
 // Special handling for the genesis block. No other functions are called on it.
 func dbAddGenesisBlock(tx *bolt.Tx) {
 	id := types.GenesisID
@@ -2,8 +2,10 @@ package modules
 
 import (
 	"net"
+	"time"
 
 	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/types"
 )
 
 const (
@@ -137,6 +139,25 @@ type (
 		Version    string     `json:"version"`
 	}
 
+	// A PeerTip is the best block that a peer is known to have.
+	PeerTip struct {
+		Height types.BlockHeight `json:"height"`
+		ID     types.BlockID     `json:"id"`
+	}
+
+	// A DiversityReport describes how the outbound peers of the gateway are
+	// spread across subnets. Local peers are not grouped into subnets.
+	DiversityReport struct {
+		OutboundPeers int `json:"outboundpeers"`
+		LocalPeers    int `json:"localpeers"`
+
+		// Subnets maps each subnet to the number of outbound peers in it, and
+		// LargestSubnet is the number of outbound peers in the most crowded
+		// subnet.
+		Subnets       map[string]int `json:"subnets"`
+		LargestSubnet int            `json:"largestsubnet"`
+	}
+
 	// A PeerConn is the connection type used when communicating with peers during
 	// an RPC. It is identical to a net.Conn with the additional RPCAddr method.
 	// This method acts as an identifier for peers and is the address that the
@@ -171,6 +192,68 @@ type (
 		// Peers returns the addresses that the Gateway is currently connected to.
 		Peers() []Peer
 
+		// PeerHeights returns the heights of the best blocks that connected
+		// peers are known to have. Peers that have not reported a height are
+		// omitted.
+		PeerHeights() map[NetAddress]types.BlockHeight
+
+		// PeerTips returns the best blocks that connected peers are known to
+		// have. Peers that have not reported a tip are omitted.
+		PeerTips() map[NetAddress]PeerTip
+
+		// PeerDiversity reports how the outbound peers are spread across
+		// subnets. The gateway prefers to dial nodes in subnets that it has
+		// few outbound peers in, to make eclipse attacks more expensive.
+		PeerDiversity() DiversityReport
+
+		// SetPeerTip records that a connected peer has the block with the
+		// given id and height. It is called by the consensus set as peers
+		// relay blocks.
+		SetPeerTip(NetAddress, types.BlockHeight, types.BlockID)
+
+		// BandwidthUsage returns the total number of bytes that have been
+		// downloaded from and uploaded to peers.
+		BandwidthUsage() (down, up uint64)
+
+		// SetRateLimits sets the maximum download and upload rates, in bytes
+		// per second, shared by all peer connections. A limit of 0 means
+		// unlimited.
+		SetRateLimits(downBytesPerSec, upBytesPerSec int64)
+
+		// SetMaxMessageSize sets the maximum number of bytes that a peer may
+		// send over a single incoming RPC. The default is the size of one
+		// block. Peers that exceed the limit are disconnected and removed
+		// from the node list.
+		SetMaxMessageSize(bytes uint64)
+
+		// SetHandshakeTimeout sets the amount of time that a peer has to
+		// complete the connection handshake before it is dropped.
+		SetHandshakeTimeout(time.Duration)
+
+		// SetMinPeerVersion sets the oldest version of peers that the
+		// Gateway will connect to or accept connections from.
+		SetMinPeerVersion(v string) error
+
+		// SetReconnectPolicy sets the exponential backoff used when
+		// re-dialing peers that dropped or failed to connect. Each delay is
+		// randomly adjusted by up to jitter times its length.
+		SetReconnectPolicy(initial, max time.Duration, jitter float64) error
+
+		// SetProxy routes all outbound connections through the SOCKS5 proxy
+		// at the given address, which allows connecting to .onion addresses.
+		// While a proxy is set, peers are never contacted directly. An empty
+		// address removes the proxy.
+		SetProxy(socks5Addr string) error
+
+		// SetAcceptInbound sets whether the Gateway accepts connections
+		// from peers.
+		SetAcceptInbound(accept bool)
+
+		// SetBootstrapSeeds sets the DNS seeds of the Gateway. If the Gateway
+		// did not know of any nodes on startup, the seeds are resolved to
+		// peer addresses which are added to the node list.
+		SetBootstrapSeeds(hosts []string)
+
 		// RegisterRPC registers a function to handle incoming connections that
 		// supply the given RPC ID.
 		RegisterRPC(string, RPCFunc)
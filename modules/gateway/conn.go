@@ -2,9 +2,11 @@ package gateway
 
 import (
 	"net"
+	"sync/atomic"
 	"time"
 
 	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/ratelimit"
 )
 
 // peerConn is a simple type that implements the modules.PeerConn interface.
@@ -19,18 +21,87 @@ func (pc peerConn) RPCAddr() modules.NetAddress {
 	return pc.dialbackAddr
 }
 
-// staticDial will staticDial the input address and return a connection. staticDial appropriately
-// handles things like clean shutdown, fast shutdown, and chooses the correct
-// communication protocol.
-func (g *Gateway) staticDial(addr modules.NetAddress) (net.Conn, error) {
+// limitedConn is a modules.PeerConn that returns errMessageTooLarge once more
+// than a fixed number of bytes have been read from it.
+type limitedConn struct {
+	modules.PeerConn
+	remaining uint64
+	exceeded  bool
+}
+
+// Read implements the io.Reader interface.
+func (lc *limitedConn) Read(b []byte) (int, error) {
+	if lc.remaining == 0 {
+		lc.exceeded = true
+		return 0, errMessageTooLarge
+	}
+	if uint64(len(b)) > lc.remaining {
+		b = b[:lc.remaining]
+	}
+	n, err := lc.PeerConn.Read(b)
+	lc.remaining -= uint64(n)
+	return n, err
+}
+
+// countingConn is a net.Conn that adds the number of bytes read and written to
+// the bandwidth counters of the gateway.
+type countingConn struct {
+	net.Conn
+	g *Gateway
+}
+
+// Read implements the io.Reader interface.
+func (cc *countingConn) Read(b []byte) (int, error) {
+	n, err := cc.Conn.Read(b)
+	atomic.AddUint64(&cc.g.atomicBytesDown, uint64(n))
+	return n, err
+}
+
+// Write implements the io.Writer interface.
+func (cc *countingConn) Write(b []byte) (int, error) {
+	n, err := cc.Conn.Write(b)
+	atomic.AddUint64(&cc.g.atomicBytesUp, uint64(n))
+	return n, err
+}
+
+// staticWrapConn wraps a connection to a peer so that all traffic on it is
+// subject to the gateway's rate limits and counted towards its bandwidth
+// usage. The rate limiter is shared by all peer connections. Connections that
+// are waiting on the rate limiter are released when the gateway shuts down.
+func (g *Gateway) staticWrapConn(conn net.Conn) net.Conn {
+	return &countingConn{
+		Conn: ratelimit.NewRLConn(conn, g.staticRL, g.threads.StopChan()),
+		g:    g,
+	}
+}
+
+// managedDial will dial the input address and return a connection.
+// managedDial appropriately handles things like clean shutdown, fast shutdown,
+// and chooses the correct communication protocol. If a proxy is set, the
+// connection is made through the proxy.
+func (g *Gateway) managedDial(addr modules.NetAddress) (net.Conn, error) {
 	dialer := &net.Dialer{
 		Cancel:  g.threads.StopChan(),
 		Timeout: dialTimeout,
 	}
-	conn, err := dialer.Dial("tcp", string(addr))
+	proxy := g.managedProxyAddr()
+	if proxy == "" && addr.IsOnion() {
+		return nil, errOnionNoProxy
+	}
+	dialAddr := addr
+	if proxy != "" {
+		dialAddr = proxy
+	}
+	conn, err := dialer.Dial("tcp", string(dialAddr))
 	if err != nil {
 		return nil, err
 	}
 	conn.SetDeadline(time.Now().Add(connStdDeadline))
-	return conn, nil
+	if proxy != "" {
+		if err := socks5Handshake(conn, addr); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return g.staticWrapConn(conn), nil
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
 )
 
 const (
@@ -33,9 +34,38 @@ const (
 	// codebase were made that weren't backwards compatible. This might include
 	// changes to the protocol or hardforks.
 	minimumAcceptablePeerVersion = "1.3.1"
+
+	// rateLimitPacketSize is the size of the packets that are written to and
+	// read from peer connections when rate limits are in effect.
+	rateLimitPacketSize = 4 * 4096
+
+	// ipv4SubnetBits and ipv6SubnetBits are the prefix lengths of the subnets
+	// that peers are grouped into. A single entity can easily obtain many
+	// addresses within a /16 IPv4 subnet or a /32 IPv6 subnet, so peers in
+	// the same subnet add little diversity to the peer list.
+	ipv4SubnetBits = 16
+	ipv6SubnetBits = 32
+
+	// maxOutboundPeersPerSubnet is the number of outbound peers that the
+	// gateway keeps in a single subnet before replacing some of them with
+	// peers from other subnets.
+	maxOutboundPeersPerSubnet = 2
 )
 
 var (
+	// defaultHandshakeTimeout is the default amount of time that a peer has
+	// to complete the connection handshake before it is dropped.
+	defaultHandshakeTimeout = build.Select(build.Var{
+		Standard: 2 * time.Minute,
+		Dev:      time.Minute,
+		Testing:  10 * time.Second,
+	}).(time.Duration)
+
+	// defaultMaxMessageSize is the default maximum number of bytes that a peer
+	// may send over a single incoming RPC. No RPC handler needs to read more
+	// than a block from a peer.
+	defaultMaxMessageSize = uint64(types.BlockSizeLimit)
+
 	// fastNodePurgeDelay defines the amount of time that is waited between each
 	// iteration of the purge loop when the gateway has enough nodes to be
 	// needing to purge quickly.
@@ -250,3 +280,21 @@ var (
 		Testing:  100 * time.Millisecond,
 	}).(time.Duration)
 )
+
+var (
+	// defaultReconnectPolicy is the backoff that the gateway uses when
+	// re-dialing nodes that dropped or failed to connect.
+	defaultReconnectPolicy = build.Select(build.Var{
+		Standard: reconnectPolicy{initial: 10 * time.Second, max: time.Hour, jitter: 0.2},
+		Dev:      reconnectPolicy{initial: 5 * time.Second, max: 5 * time.Minute, jitter: 0.2},
+		Testing:  reconnectPolicy{initial: 100 * time.Millisecond, max: 2 * time.Second, jitter: 0.2},
+	}).(reconnectPolicy)
+
+	// maxReconnectFailures is the number of consecutive failed connection
+	// attempts after which a node is removed from the node list.
+	maxReconnectFailures = build.Select(build.Var{
+		Standard: 8,
+		Dev:      5,
+		Testing:  3,
+	}).(int)
+)
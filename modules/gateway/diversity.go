@@ -0,0 +1,135 @@
+package gateway
+
+import (
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// outboundSubnetCounts returns the number of outbound peers in each subnet.
+// Local peers are left out, as they cannot be used to eclipse the gateway.
+func (g *Gateway) outboundSubnetCounts() map[string]int {
+	counts := make(map[string]int)
+	for addr, p := range g.peers {
+		if !p.Inbound && !p.Local {
+			counts[ipSubnet(addr)]++
+		}
+	}
+	return counts
+}
+
+// diversifyNodeList reorders nodes so that nodes in subnets with few outbound
+// peers are dialed first. counts holds the number of outbound peers in each
+// subnet. Each node in the list is assumed to become an outbound peer, so the
+// list alternates between subnets instead of exhausting one subnet at a time.
+// Nodes in the same subnet keep their relative order.
+func diversifyNodeList(nodes []modules.NetAddress, counts map[string]int) []modules.NetAddress {
+	// Group the nodes by subnet.
+	var subnets []string
+	groups := make(map[string][]modules.NetAddress)
+	for _, addr := range nodes {
+		subnet := ipSubnet(addr)
+		if _, exists := groups[subnet]; !exists {
+			subnets = append(subnets, subnet)
+		}
+		groups[subnet] = append(groups[subnet], addr)
+	}
+
+	used := make(map[string]int, len(counts))
+	for subnet, n := range counts {
+		used[subnet] = n
+	}
+	diverse := make([]modules.NetAddress, 0, len(nodes))
+	for len(diverse) < len(nodes) {
+		// Take the next node of the least used subnet. Ties go to the subnet
+		// that appeared first in the original list.
+		best, found := "", false
+		for _, subnet := range subnets {
+			if len(groups[subnet]) > 0 && (!found || used[subnet] < used[best]) {
+				best, found = subnet, true
+			}
+		}
+		diverse = append(diverse, groups[best][0])
+		groups[best] = groups[best][1:]
+		used[best]++
+	}
+	return diverse
+}
+
+// crowdedOutboundPeer returns an outbound peer from the subnet with the most
+// outbound peers, if that subnet has more than maxOutboundPeersPerSubnet
+// peers and the node list has a node from a subnet without outbound peers to
+// replace it with.
+func (g *Gateway) crowdedOutboundPeer() (modules.NetAddress, bool) {
+	counts := g.outboundSubnetCounts()
+	crowded := ""
+	for subnet, n := range counts {
+		if n > maxOutboundPeersPerSubnet && (crowded == "" || n > counts[crowded]) {
+			crowded = subnet
+		}
+	}
+	if crowded == "" {
+		return "", false
+	}
+
+	// Only disconnect the peer if there is a better candidate.
+	replaceable := false
+	for addr := range g.nodes {
+		if _, exists := counts[ipSubnet(addr)]; !exists && g.peers[addr] == nil && !addr.IsLocal() && g.reconnectReady(addr) {
+			replaceable = true
+			break
+		}
+	}
+	if !replaceable {
+		return "", false
+	}
+
+	var candidates []modules.NetAddress
+	for addr, p := range g.peers {
+		if !p.Inbound && !p.Local && ipSubnet(addr) == crowded {
+			candidates = append(candidates, addr)
+		}
+	}
+	return candidates[fastrand.Intn(len(candidates))], true
+}
+
+// managedDisconnectCrowdedPeer disconnects an outbound peer from a subnet that
+// has too many outbound peers, making room for the peer manager to connect to
+// a node in another subnet. The node stays in the node list. It returns false
+// if no peer was disconnected.
+func (g *Gateway) managedDisconnectCrowdedPeer() bool {
+	g.mu.Lock()
+	addr, ok := g.crowdedOutboundPeer()
+	if !ok {
+		g.mu.Unlock()
+		return false
+	}
+	p := g.peers[addr]
+	delete(g.peers, addr)
+	g.mu.Unlock()
+
+	p.sess.Close()
+	g.log.Printf("INFO: disconnected from %v to improve the subnet diversity of outbound peers\n", addr)
+	return true
+}
+
+// PeerDiversity reports how the outbound peers of the gateway are spread
+// across subnets.
+func (g *Gateway) PeerDiversity() modules.DiversityReport {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	report := modules.DiversityReport{
+		Subnets: g.outboundSubnetCounts(),
+	}
+	for _, p := range g.peers {
+		if !p.Inbound && p.Local {
+			report.LocalPeers++
+		}
+	}
+	for _, n := range report.Subnets {
+		report.OutboundPeers += n
+		if n > report.LargestSubnet {
+			report.LargestSubnet = n
+		}
+	}
+	return report
+}
@@ -0,0 +1,128 @@
+package gateway
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// TestDiversifyNodeList checks that nodes from subnets with few outbound
+// peers are moved to the front of the node list.
+func TestDiversifyNodeList(t *testing.T) {
+	nodes := []modules.NetAddress{
+		"1.1.0.1:9981",
+		"1.1.0.2:9981",
+		"1.1.0.3:9981",
+		"2.2.0.1:9981",
+		"2.2.0.2:9981",
+		"3.3.0.1:9981",
+	}
+
+	// Without outbound peers, the list alternates between subnets.
+	exp := []modules.NetAddress{
+		"1.1.0.1:9981",
+		"2.2.0.1:9981",
+		"3.3.0.1:9981",
+		"1.1.0.2:9981",
+		"2.2.0.2:9981",
+		"1.1.0.3:9981",
+	}
+	if diverse := diversifyNodeList(nodes, nil); fmt.Sprint(diverse) != fmt.Sprint(exp) {
+		t.Fatal("wrong order:", diverse)
+	}
+
+	// Subnets that already have outbound peers come last.
+	counts := map[string]int{
+		ipSubnet("1.1.0.1:9981"): 2,
+		ipSubnet("2.2.0.1:9981"): 1,
+	}
+	exp = []modules.NetAddress{
+		"3.3.0.1:9981",
+		"2.2.0.1:9981",
+		"1.1.0.1:9981",
+		"2.2.0.2:9981",
+		"1.1.0.2:9981",
+		"1.1.0.3:9981",
+	}
+	if diverse := diversifyNodeList(nodes, counts); fmt.Sprint(diverse) != fmt.Sprint(exp) {
+		t.Fatal("wrong order:", diverse)
+	}
+	if counts[ipSubnet("1.1.0.1:9981")] != 2 {
+		t.Fatal("diversifyNodeList modified the counts")
+	}
+}
+
+// TestPeerDiversity checks that the outbound peers are reported by subnet,
+// and that a peer from a crowded subnet is replaced only if there is a node
+// from another subnet to replace it with.
+func TestPeerDiversity(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g := newTestingGateway(t)
+	defer g.Close()
+
+	addPeer := func(addr modules.NetAddress, inbound bool) {
+		g.mu.Lock()
+		g.addPeer(&peer{
+			Peer: modules.Peer{
+				NetAddress: addr,
+				Inbound:    inbound,
+				Local:      addr.IsLocal(),
+			},
+			sess: newClientStream(new(dummyConn), build.Version),
+		})
+		g.mu.Unlock()
+	}
+	for i := 0; i < maxOutboundPeersPerSubnet+1; i++ {
+		addPeer(modules.NetAddress(fmt.Sprintf("1.1.0.%d:9981", i+1)), false)
+	}
+	addPeer("2.2.0.1:9981", false)
+	addPeer("3.3.0.1:9981", true)
+	addPeer("127.0.0.1:9981", false)
+
+	report := g.PeerDiversity()
+	if report.OutboundPeers != maxOutboundPeersPerSubnet+2 || report.LocalPeers != 1 {
+		t.Fatal("wrong peer counts:", report)
+	}
+	if len(report.Subnets) != 2 || report.Subnets[ipSubnet("2.2.0.1:9981")] != 1 {
+		t.Fatal("wrong subnets:", report.Subnets)
+	}
+	if report.LargestSubnet != maxOutboundPeersPerSubnet+1 {
+		t.Fatal("wrong largest subnet:", report.LargestSubnet)
+	}
+
+	// Nodes in subnets that already have outbound peers are not
+	// replacements.
+	g.mu.Lock()
+	g.nodes["1.1.0.100:9981"] = &node{NetAddress: "1.1.0.100:9981"}
+	g.nodes["2.2.0.100:9981"] = &node{NetAddress: "2.2.0.100:9981"}
+	g.mu.Unlock()
+	if g.managedDisconnectCrowdedPeer() {
+		t.Fatal("peer was disconnected without a replacement")
+	}
+
+	// Once there is a node in a new subnet, a peer from the crowded subnet is
+	// disconnected, and the new node is the first to be dialed.
+	g.mu.Lock()
+	g.nodes["4.4.0.1:9981"] = &node{NetAddress: "4.4.0.1:9981"}
+	g.mu.Unlock()
+	if !g.managedDisconnectCrowdedPeer() {
+		t.Fatal("crowded peer was not disconnected")
+	}
+	if report := g.PeerDiversity(); report.LargestSubnet != maxOutboundPeersPerSubnet {
+		t.Fatal("wrong largest subnet after disconnecting:", report)
+	}
+	if g.managedDisconnectCrowdedPeer() {
+		t.Fatal("peer was disconnected from a subnet that is not crowded")
+	}
+	g.mu.RLock()
+	nodes := g.buildPeerManagerNodeList()
+	g.mu.RUnlock()
+	if len(nodes) == 0 || nodes[0] != "4.4.0.1:9981" {
+		t.Fatal("node from the new subnet is not dialed first:", nodes)
+	}
+}
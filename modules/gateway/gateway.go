@@ -45,7 +45,13 @@ import (
 // Furthermore, to increase the difficulty of attack, if a new inbound
 // connection shares the same IP address as an existing connection, the shared
 // connection is the connection that gets dropped (unless that connection is a
-// local or outbound connection).
+// local or outbound connection). Failing that, a connection from the same
+// subnet is preferred.
+//
+// An attacker can cheaply obtain many addresses within a single subnet, so the
+// gateway also spreads its outbound peers across subnets. Nodes from subnets
+// with few outbound peers are dialed first, and if too many outbound peers
+// share a subnet, one of them is replaced with a node from another subnet.
 //
 // Nodes are added to a peerlist in two methods. The first method is that a
 // gateway will ask its outbound peers for a list of nodes. If the node list is
@@ -68,12 +74,6 @@ import (
 //     Stubborn Mining: Generalizing Selfish Mining and Combining with an Eclipse Attack (Nayak, Kumar, Miller, Shi)
 //     An Overview of BGP Hijacking (https://www.bishopfox.com/blog/2015/08/an-overview-of-bgp-hijacking/)
 
-// TODO: Currently the gateway does not do much in terms of bucketing. The
-// gateway should make sure that it has outbound peers from a wide range of IP
-// addresses, and when kicking inbound peers it shouldn't just favor kicking
-// peers of the same IP address, it should favor kicking peers of the same ip
-// address range.
-//
 // TODO: There is no public key exchange, so communications cannot be
 // effectively encrypted or authenticated.
 //
@@ -102,24 +102,54 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 
+	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/persist"
 	siasync "github.com/NebulousLabs/Sia/sync"
 	"github.com/NebulousLabs/fastrand"
+	"github.com/NebulousLabs/ratelimit"
 )
 
 var (
-	errNoPeers     = errors.New("no peers")
-	errUnreachable = errors.New("peer did not respond to ping")
+	errMessageTooLarge   = errors.New("peer exceeded the maximum message size")
+	errMinPeerVersionLow = errors.New("minimum peer version is below the oldest supported protocol version " + minimumAcceptablePeerVersion)
+	errMinPeerVersionBad = errors.New("minimum peer version is not a valid version")
+	errNoPeers           = errors.New("no peers")
+	errUnreachable       = errors.New("peer did not respond to ping")
 )
 
 // Gateway implements the modules.Gateway interface.
 type Gateway struct {
+	// atomicBytesDown and atomicBytesUp count the total number of bytes read
+	// from and written to peer connections. They are placed at the top of
+	// the struct to guarantee 64-bit alignment for atomic operations.
+	atomicBytesDown uint64
+	atomicBytesUp   uint64
+
+	// atomicMaxMessageSize is the maximum number of bytes that a peer may
+	// send over a single incoming RPC.
+	atomicMaxMessageSize uint64
+
+	// atomicHandshakeTimeout is the time in nanoseconds that a peer has to
+	// complete the connection handshake before it is dropped.
+	atomicHandshakeTimeout int64
+
 	listener net.Listener
 	myAddr   modules.NetAddress
 	port     string
 
+	// proxyAddr is the address of the SOCKS5 proxy that outbound
+	// connections are made through. If rejectInbound is set, connections
+	// from peers are refused.
+	proxyAddr     modules.NetAddress
+	rejectInbound bool
+
+	// minPeerVersion is the oldest version of peers that the gateway will
+	// connect to or accept connections from.
+	minPeerVersion string
+
 	// handlers are the RPCs that the Gateway can handle.
 	//
 	// initRPCs are the RPCs that the Gateway calls upon connecting to a peer.
@@ -145,12 +175,24 @@ type Gateway struct {
 	peers  map[modules.NetAddress]*peer
 	peerTG siasync.ThreadGroup
 
+	// reconnects tracks the backoff of nodes that dropped or failed to
+	// connect, following the reconnectPolicy.
+	reconnects      map[modules.NetAddress]*reconnectState
+	reconnectPolicy reconnectPolicy
+
 	// Utilities.
 	log        *persist.Logger
 	mu         sync.RWMutex
 	persistDir string
 	threads    siasync.ThreadGroup
 
+	// staticRL is the rate limiter shared by all peer connections.
+	staticRL *ratelimit.RateLimit
+
+	// staticNoKnownNodes indicates that the gateway did not load any nodes
+	// from disk on startup, meaning that it has to be bootstrapped.
+	staticNoKnownNodes bool
+
 	// Unique ID
 	staticId gatewayID
 }
@@ -176,6 +218,64 @@ func (g *Gateway) Address() modules.NetAddress {
 	return g.myAddr
 }
 
+// BandwidthUsage returns the total number of bytes that have been read from and
+// written to peer connections since the gateway was created.
+func (g *Gateway) BandwidthUsage() (down, up uint64) {
+	return atomic.LoadUint64(&g.atomicBytesDown), atomic.LoadUint64(&g.atomicBytesUp)
+}
+
+// SetRateLimits sets the maximum download and upload rates, in bytes per
+// second, of the gateway. The limits apply to all peer connections combined.
+// A limit of 0 means unlimited.
+func (g *Gateway) SetRateLimits(downBytesPerSec, upBytesPerSec int64) {
+	g.staticRL.SetLimits(downBytesPerSec, upBytesPerSec, rateLimitPacketSize)
+}
+
+// SetMaxMessageSize sets the maximum number of bytes that a peer may send over
+// a single incoming RPC. The default, defaultMaxMessageSize, is the size of
+// one block. Peers that exceed the limit are disconnected and removed from the
+// node list.
+func (g *Gateway) SetMaxMessageSize(bytes uint64) {
+	atomic.StoreUint64(&g.atomicMaxMessageSize, bytes)
+}
+
+// SetHandshakeTimeout sets the amount of time that a peer has to complete the
+// connection handshake. Peers that do not complete the handshake in time are
+// dropped.
+func (g *Gateway) SetHandshakeTimeout(d time.Duration) {
+	atomic.StoreInt64(&g.atomicHandshakeTimeout, int64(d))
+}
+
+// SetMinPeerVersion sets the oldest version of peers that the gateway will
+// connect to or accept connections from. Peers below the version are
+// disconnected during the handshake. Existing peers are not affected.
+func (g *Gateway) SetMinPeerVersion(v string) error {
+	if !build.IsVersion(v) {
+		return errMinPeerVersionBad
+	}
+	if build.VersionCmp(v, minimumAcceptablePeerVersion) < 0 {
+		return errMinPeerVersionLow
+	}
+	g.mu.Lock()
+	g.minPeerVersion = v
+	g.mu.Unlock()
+	return nil
+}
+
+// managedHandshakeDeadline returns the deadline for a handshake that starts
+// now.
+func (g *Gateway) managedHandshakeDeadline() time.Time {
+	return time.Now().Add(time.Duration(atomic.LoadInt64(&g.atomicHandshakeTimeout)))
+}
+
+// managedMinPeerVersion returns the oldest version of peers that the gateway
+// will connect to.
+func (g *Gateway) managedMinPeerVersion() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.minPeerVersion
+}
+
 // Close saves the state of the Gateway and stops its listener process.
 func (g *Gateway) Close() error {
 	if err := g.threads.Stop(); err != nil {
@@ -201,7 +301,17 @@ func New(addr string, bootstrap bool, persistDir string) (*Gateway, error) {
 		nodes: make(map[modules.NetAddress]*node),
 		peers: make(map[modules.NetAddress]*peer),
 
+		reconnects:      make(map[modules.NetAddress]*reconnectState),
+		reconnectPolicy: defaultReconnectPolicy,
+
 		persistDir: persistDir,
+
+		staticRL: ratelimit.NewRateLimit(0, 0, 0),
+
+		atomicMaxMessageSize: defaultMaxMessageSize,
+
+		atomicHandshakeTimeout: int64(defaultHandshakeTimeout),
+		minPeerVersion:         minimumAcceptablePeerVersion,
 	}
 
 	// Set Unique GatewayID
@@ -247,6 +357,7 @@ func New(addr string, bootstrap bool, persistDir string) (*Gateway, error) {
 	if loadErr := g.load(); loadErr != nil && !os.IsNotExist(loadErr) {
 		return nil, loadErr
 	}
+	g.staticNoKnownNodes = len(g.nodes) == 0
 	// Spawn the thread to periodically save the gateway.
 	go g.threadedSaveLoop()
 	// Make sure that the gateway saves after shutdown.
@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	siasync "github.com/NebulousLabs/Sia/sync"
 )
@@ -120,6 +121,58 @@ func TestPeers(t *testing.T) {
 	}
 }
 
+// TestBandwidthUsage checks that traffic between peers is counted by the
+// gateway, including when rate limits are in effect.
+func TestBandwidthUsage(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g1 := newNamedTestingGateway(t, "1")
+	defer g1.Close()
+	g2 := newNamedTestingGateway(t, "2")
+	defer g2.Close()
+
+	if down, up := g1.BandwidthUsage(); down != 0 || up != 0 {
+		t.Fatal("new gateway reports bandwidth usage:", down, up)
+	}
+	err := g1.Connect(g2.Address())
+	if err != nil {
+		t.Fatal("failed to connect:", err)
+	}
+	down1, up1 := g1.BandwidthUsage()
+	if down1 == 0 || up1 == 0 {
+		t.Fatal("connecting did not register bandwidth usage:", down1, up1)
+	}
+
+	// Set a low rate limit and check that RPCs still complete and are
+	// counted.
+	g1.SetRateLimits(1<<16, 1<<16)
+	g2.RegisterRPC("Foo", func(conn modules.PeerConn) error {
+		return encoding.WriteObject(conn, "foo")
+	})
+	var foo string
+	err = g1.RPC(g2.Address(), "Foo", func(conn modules.PeerConn) error {
+		return encoding.ReadObject(conn, &foo, 11)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if foo != "foo" {
+		t.Fatal("Foo gave wrong response:", foo)
+	}
+	down2, up2 := g1.BandwidthUsage()
+	if down2 <= down1 || up2 <= up1 {
+		t.Fatal("RPC did not register bandwidth usage:", down2, up2)
+	}
+
+	// Remove the limits again.
+	g1.SetRateLimits(0, 0)
+	if down, up, _ := g1.staticRL.Limits(); down != 0 || up != 0 {
+		t.Fatal("rate limits were not removed:", down, up)
+	}
+}
+
 // TestNew checks that a call to New is effective.
 func TestNew(t *testing.T) {
 	if testing.Short() {
@@ -80,14 +80,37 @@ func (g *Gateway) managedIPFromPeers() (string, error) {
 			continue
 		}
 		// If an address was returned by more than half the peers we consider
-		// it valid.
-		for addr, count := range addresses {
-			if count > successfulResponses/2 {
-				g.log.Println("ip successfully discovered using peers:", addr)
-				return addr, nil
-			}
+		// it valid. Peers connected over IPv4 and IPv6 report different
+		// addresses for a dual-stack node, so the majority is taken within
+		// each address family.
+		if addr := majorityAddress(addresses); addr != "" {
+			g.log.Println("ip successfully discovered using peers:", addr)
+			return addr, nil
 		}
 		// Otherwise we wait before trying again.
 		g.managedSleep(peerDiscoveryRetryInterval)
 	}
 }
+
+// majorityAddress returns the address that was reported by more than half of
+// the peers reporting an address of the same family. If addresses of both
+// families qualify, the one reported by more peers is returned. The empty
+// string is returned if no address qualifies.
+func majorityAddress(addresses map[string]int) string {
+	familyResponses := make(map[bool]int)
+	for addr, count := range addresses {
+		familyResponses[net.ParseIP(addr).To4() != nil] += count
+	}
+	var best string
+	var bestCount int
+	for addr, count := range addresses {
+		responses := familyResponses[net.ParseIP(addr).To4() != nil]
+		if responses < minPeersForIPDiscovery || count <= responses/2 {
+			continue
+		}
+		if count > bestCount || (count == bestCount && addr < best) {
+			best, bestCount = addr, count
+		}
+	}
+	return best
+}
@@ -78,3 +78,30 @@ func TestIPFromPeers(t *testing.T) {
 		t.Fatalf("ip should be %v but was %v", g1.Address().Host(), host)
 	}
 }
+
+// TestUnitMajorityAddress checks that the majority of reported addresses is
+// taken separately for IPv4 and IPv6.
+func TestUnitMajorityAddress(t *testing.T) {
+	// The counts are relative to the number of responses that is needed for
+	// an address to be considered at all.
+	n := minPeersForIPDiscovery
+	tests := []struct {
+		addresses map[string]int
+		expected  string
+	}{
+		{map[string]int{}, ""},
+		{map[string]int{"1.2.3.4": n - 1}, ""},
+		{map[string]int{"1.2.3.4": n}, "1.2.3.4"},
+		{map[string]int{"1.2.3.4": n, "5.6.7.8": n}, ""},
+		{map[string]int{"1.2.3.4": n + 1, "5.6.7.8": n}, "1.2.3.4"},
+		// A dual-stack node is reported under both families.
+		{map[string]int{"1.2.3.4": n, "2001:db8::1": n + 1}, "2001:db8::1"},
+		{map[string]int{"1.2.3.4": n + 1, "2001:db8::1": n}, "1.2.3.4"},
+		{map[string]int{"1.2.3.4": n, "2001:db8::1": n, "2001:db8::2": n}, "1.2.3.4"},
+	}
+	for _, test := range tests {
+		if addr := majorityAddress(test.addresses); addr != test.expected {
+			t.Errorf("expected %q for %v, got %q", test.expected, test.addresses, addr)
+		}
+	}
+}
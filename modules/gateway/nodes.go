@@ -34,7 +34,7 @@ func (g *Gateway) addNode(addr modules.NetAddress) error {
 		return errNodeExists
 	} else if addr.IsStdValid() != nil {
 		return errors.New("address is not valid: " + string(addr))
-	} else if net.ParseIP(addr.Host()) == nil {
+	} else if net.ParseIP(addr.Host()) == nil && !(addr.IsOnion() && g.proxyAddr != "") {
 		return errors.New("address must be an IP address: " + string(addr))
 	}
 	g.nodes[addr] = &node{
@@ -44,19 +44,19 @@ func (g *Gateway) addNode(addr modules.NetAddress) error {
 	return nil
 }
 
-// staticPingNode verifies that there is a reachable node at the provided address
+// managedPingNode verifies that there is a reachable node at the provided address
 // by performing the Sia gateway handshake protocol.
-func (g *Gateway) staticPingNode(addr modules.NetAddress) error {
+func (g *Gateway) managedPingNode(addr modules.NetAddress) error {
 	// Ping the untrusted node to see whether or not there's actually a
 	// reachable node at the provided address.
-	conn, err := g.staticDial(addr)
+	conn, err := g.managedDial(addr)
 	if err != nil {
 		return err
 	}
 	defer conn.Close()
 
 	// Read the node's version.
-	remoteVersion, err := connectVersionHandshake(conn, build.Version)
+	remoteVersion, err := connectVersionHandshake(conn, build.Version, minimumAcceptablePeerVersion)
 	if err != nil {
 		return err
 	}
@@ -98,6 +98,7 @@ func (g *Gateway) removeNode(addr modules.NetAddress) error {
 		return errors.New("no record of that node")
 	}
 	delete(g.nodes, addr)
+	delete(g.reconnects, addr)
 	return nil
 }
 
@@ -267,7 +268,7 @@ func (g *Gateway) permanentNodePurger(closeChan chan struct{}) {
 		// through, which would cause the node to be pruned even though it may
 		// be a good node. Because nodes are plentiful, this is an acceptable
 		// bug.
-		if err = g.staticPingNode(node); err != nil {
+		if err = g.managedPingNode(node); err != nil {
 			g.mu.Lock()
 			if len(g.nodes) > pruneNodeListLen {
 				// Check if the number of nodes is still above the threshold.
@@ -37,6 +37,10 @@ func (s invalidVersionError) Error() string {
 type peer struct {
 	modules.Peer
 	sess streamSession
+
+	// tip is the best block that the peer is known to have, as reported by
+	// SetPeerTip. A height of 0 means that no tip has been reported.
+	tip modules.PeerTip
 }
 
 // sessionHeader is sent after the initial version exchange. It prevents peers
@@ -120,7 +124,7 @@ func (g *Gateway) permanentListen(closeChan chan struct{}) {
 			return
 		}
 
-		go g.threadedAcceptConn(conn)
+		go g.threadedAcceptConn(g.staticWrapConn(conn))
 
 		// Sleep after each accept. This limits the rate at which the Gateway
 		// will accept new connections. The intent here is to prevent new
@@ -141,12 +145,21 @@ func (g *Gateway) threadedAcceptConn(conn net.Conn) {
 		return
 	}
 	defer g.threads.Done()
-	conn.SetDeadline(time.Now().Add(connStdDeadline))
+	conn.SetDeadline(g.managedHandshakeDeadline())
 
 	addr := modules.NetAddress(conn.RemoteAddr().String())
 	g.log.Debugf("INFO: %v wants to connect", addr)
 
-	remoteVersion, err := acceptVersionHandshake(conn, build.Version)
+	g.mu.RLock()
+	rejectInbound := g.rejectInbound
+	g.mu.RUnlock()
+	if rejectInbound {
+		g.log.Debugf("INFO: %v wanted to connect, but inbound connections are disabled", addr)
+		conn.Close()
+		return
+	}
+
+	remoteVersion, err := acceptVersionHandshake(conn, build.Version, g.managedMinPeerVersion())
 	if err != nil {
 		g.log.Debugf("INFO: %v wanted to connect but version handshake failed: %v", addr, err)
 		conn.Close()
@@ -235,7 +248,7 @@ func (g *Gateway) managedAcceptConnPeer(conn net.Conn, remoteVersion string) err
 	// do this in a goroutine so that we can begin communicating with the peer
 	// immediately.
 	go func() {
-		err := g.staticPingNode(remoteAddr)
+		err := g.managedPingNode(remoteAddr)
 		if err == nil {
 			g.mu.Lock()
 			g.addNode(remoteAddr)
@@ -246,6 +259,23 @@ func (g *Gateway) managedAcceptConnPeer(conn net.Conn, remoteVersion string) err
 	return nil
 }
 
+// ipSubnet returns the subnet that the host of addr belongs to. Hosts that
+// are not IP addresses, such as onion addresses, are their own subnet.
+func ipSubnet(addr modules.NetAddress) string {
+	host := addr.Host()
+	if host == "" {
+		host = string(addr)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return host
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return fmt.Sprintf("%v/%v", ip4.Mask(net.CIDRMask(ipv4SubnetBits, 8*net.IPv4len)), ipv4SubnetBits)
+	}
+	return fmt.Sprintf("%v/%v", ip.Mask(net.CIDRMask(ipv6SubnetBits, 8*net.IPv6len)), ipv6SubnetBits)
+}
+
 // acceptPeer makes room for the peer if necessary by kicking out existing
 // peers, then adds the peer to the peer list.
 func (g *Gateway) acceptPeer(p *peer) {
@@ -257,20 +287,28 @@ func (g *Gateway) acceptPeer(p *peer) {
 
 	// Select a peer to kick. Outbound peers and local peers are not
 	// available to be kicked.
-	var addrs []modules.NetAddress
+	var addrs, sameSubnet []modules.NetAddress
 	for addr, peer := range g.peers {
 		// Do not kick outbound peers or local peers.
 		if !peer.Inbound || peer.Local {
 			continue
 		}
 
-		// Prefer kicking a peer with the same hostname.
+		// Prefer kicking a peer with the same hostname, followed by peers
+		// in the same subnet.
 		if addr.Host() == p.NetAddress.Host() {
 			addrs = []modules.NetAddress{addr}
+			sameSubnet = nil
 			break
 		}
+		if ipSubnet(addr) == ipSubnet(p.NetAddress) {
+			sameSubnet = append(sameSubnet, addr)
+		}
 		addrs = append(addrs, addr)
 	}
+	if len(sameSubnet) > 0 {
+		addrs = sameSubnet
+	}
 	if len(addrs) == 0 {
 		// There is nobody suitable to kick, therefore do not kick anyone.
 		g.addPeer(p)
@@ -286,12 +324,13 @@ func (g *Gateway) acceptPeer(p *peer) {
 	g.addPeer(p)
 }
 
-// acceptableVersion returns an error if the version is unacceptable.
-func acceptableVersion(version string) error {
+// acceptableVersion returns an error if the version is unacceptable, i.e. if
+// it is invalid or below minVersion.
+func acceptableVersion(version, minVersion string) error {
 	if !build.IsVersion(version) {
 		return invalidVersionError(version)
 	}
-	if build.VersionCmp(version, minimumAcceptablePeerVersion) < 0 {
+	if build.VersionCmp(version, minVersion) < 0 {
 		return insufficientVersionError(version)
 	}
 	return nil
@@ -299,8 +338,8 @@ func acceptableVersion(version string) error {
 
 // connectVersionHandshake performs the version handshake and should be called
 // on the side making the connection request. The remote version is only
-// returned if err == nil.
-func connectVersionHandshake(conn net.Conn, version string) (remoteVersion string, err error) {
+// returned if err == nil, and must be at least minVersion.
+func connectVersionHandshake(conn net.Conn, version, minVersion string) (remoteVersion string, err error) {
 	// Send our version.
 	if err := encoding.WriteObject(conn, version); err != nil {
 		return "", fmt.Errorf("failed to write version: %v", err)
@@ -313,7 +352,7 @@ func connectVersionHandshake(conn net.Conn, version string) (remoteVersion strin
 	if remoteVersion == "reject" {
 		return "", errPeerRejectedConn
 	}
-	if err := acceptableVersion(remoteVersion); err != nil {
+	if err := acceptableVersion(remoteVersion, minVersion); err != nil {
 		return "", err
 	}
 	return remoteVersion, nil
@@ -321,14 +360,15 @@ func connectVersionHandshake(conn net.Conn, version string) (remoteVersion strin
 
 // acceptVersionHandshake performs the version handshake and should be
 // called on the side accepting a connection request. The remote version is
-// only returned if err == nil.
-func acceptVersionHandshake(conn net.Conn, version string) (remoteVersion string, err error) {
+// only returned if err == nil, and must be at least minVersion. Peers below
+// minVersion are sent a rejection.
+func acceptVersionHandshake(conn net.Conn, version, minVersion string) (remoteVersion string, err error) {
 	// Read remote version.
 	if err := encoding.ReadObject(conn, &remoteVersion, build.MaxEncodedVersionLength); err != nil {
 		return "", fmt.Errorf("failed to read remote version: %v", err)
 	}
 	// Check that their version is acceptable.
-	if err := acceptableVersion(remoteVersion); err != nil {
+	if err := acceptableVersion(remoteVersion, minVersion); err != nil {
 		if err := encoding.WriteObject(conn, "reject"); err != nil {
 			return "", fmt.Errorf("failed to write reject: %v", err)
 		}
@@ -414,7 +454,7 @@ func (g *Gateway) managedConnect(addr modules.NetAddress) error {
 	if err := addr.IsStdValid(); err != nil {
 		return errors.New("can't connect to invalid address")
 	}
-	if net.ParseIP(addr.Host()) == nil {
+	if net.ParseIP(addr.Host()) == nil && !addr.IsOnion() {
 		return errors.New("address must be an IP address")
 	}
 	g.mu.RLock()
@@ -425,13 +465,15 @@ func (g *Gateway) managedConnect(addr modules.NetAddress) error {
 	}
 
 	// Dial the peer and perform peer initialization.
-	conn, err := g.staticDial(addr)
+	conn, err := g.managedDial(addr)
 	if err != nil {
 		return err
 	}
 
-	// Perform peer initialization.
-	remoteVersion, err := connectVersionHandshake(conn, build.Version)
+	// Perform peer initialization. The handshake has to complete before the
+	// handshake timeout.
+	conn.SetDeadline(g.managedHandshakeDeadline())
+	remoteVersion, err := connectVersionHandshake(conn, build.Version, g.managedMinPeerVersion())
 	if err != nil {
 		conn.Close()
 		return err
@@ -527,6 +569,45 @@ func (g *Gateway) Peers() []modules.Peer {
 	return peers
 }
 
+// PeerHeights returns the heights of the best blocks that connected peers are
+// known to have. Peers that have not reported a height are omitted.
+func (g *Gateway) PeerHeights() map[modules.NetAddress]types.BlockHeight {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	heights := make(map[modules.NetAddress]types.BlockHeight)
+	for addr, p := range g.peers {
+		if p.tip.Height > 0 {
+			heights[addr] = p.tip.Height
+		}
+	}
+	return heights
+}
+
+// PeerTips returns the best blocks that connected peers are known to have.
+// Peers that have not reported a tip are omitted.
+func (g *Gateway) PeerTips() map[modules.NetAddress]modules.PeerTip {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	tips := make(map[modules.NetAddress]modules.PeerTip)
+	for addr, p := range g.peers {
+		if p.tip.Height > 0 {
+			tips[addr] = p.tip
+		}
+	}
+	return tips
+}
+
+// SetPeerTip records that a connected peer has the block with the given id and
+// height. Tips lower than the peer's known tip are ignored, as are peers that
+// are not connected.
+func (g *Gateway) SetPeerTip(addr modules.NetAddress, height types.BlockHeight, id types.BlockID) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if p, exists := g.peers[addr]; exists && height > p.tip.Height {
+		p.tip = modules.PeerTip{Height: height, ID: id}
+	}
+}
+
 // Online returns true if the node is connected to the internet. During testing
 // we always assume that the node is online
 func (g *Gateway) Online() bool {
@@ -127,6 +127,75 @@ func TestAcceptPeer(t *testing.T) {
 	}
 }
 
+// TestUnitIPSubnet checks that addresses are grouped into the correct
+// subnets.
+func TestUnitIPSubnet(t *testing.T) {
+	tests := []struct {
+		addr   modules.NetAddress
+		subnet string
+	}{
+		{"1.2.3.4:9981", "1.2.0.0/16"},
+		{"1.2.255.255:9981", "1.2.0.0/16"},
+		{"1.3.0.0:9981", "1.3.0.0/16"},
+		{"9.9.9.9", "9.9.0.0/16"},
+		{"[2001:db8:1:2::1]:9981", "2001:db8::/32"},
+		{"[2001:db8:ffff::1]:9981", "2001:db8::/32"},
+		{"[2001:db9::1]:9981", "2001:db9::/32"},
+		{"[::ffff:1.2.3.4]:9981", "1.2.0.0/16"},
+		{"foo.onion:9981", "foo.onion"},
+	}
+	for _, test := range tests {
+		if subnet := ipSubnet(test.addr); subnet != test.subnet {
+			t.Errorf("expected subnet %v for %v, got %v", test.subnet, test.addr, subnet)
+		}
+	}
+}
+
+// TestAcceptPeerSameSubnet checks that acceptPeer prefers kicking a peer in
+// the same subnet as the new peer.
+func TestAcceptPeerSameSubnet(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g := newTestingGateway(t)
+	defer g.Close()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Fill the peer list with kickable peers, one of which shares a subnet
+	// with the new peer.
+	for i := 0; i < fullyConnectedThreshold-1; i++ {
+		g.addPeer(&peer{
+			Peer: modules.Peer{
+				NetAddress: modules.NetAddress(fmt.Sprintf("[2001:db8:%x::1]:9981", i+1)),
+				Inbound:    true,
+			},
+			sess: newClientStream(new(dummyConn), build.Version),
+		})
+	}
+	g.addPeer(&peer{
+		Peer: modules.Peer{
+			NetAddress: "[2001:db9::1]:9981",
+			Inbound:    true,
+		},
+		sess: newClientStream(new(dummyConn), build.Version),
+	})
+	g.acceptPeer(&peer{
+		Peer: modules.Peer{
+			NetAddress: "[2001:db9:1::1]:9981",
+			Inbound:    true,
+		},
+		sess: newClientStream(new(dummyConn), build.Version),
+	})
+	if _, exists := g.peers["[2001:db9::1]:9981"]; exists {
+		t.Fatal("acceptPeer did not kick the peer in the same subnet")
+	}
+	if len(g.peers) != fullyConnectedThreshold {
+		t.Fatal("expected a single peer to be kicked, have", len(g.peers))
+	}
+}
+
 // TestRandomInbountPeer checks that randomOutboundPeer returns the correct
 // peer.
 func TestRandomOutboundPeer(t *testing.T) {
@@ -175,7 +244,7 @@ func TestListen(t *testing.T) {
 		t.Fatal("dial failed:", err)
 	}
 	addr := modules.NetAddress(conn.LocalAddr().String())
-	ack, err := connectVersionHandshake(conn, "0.1")
+	ack, err := connectVersionHandshake(conn, "0.1", minimumAcceptablePeerVersion)
 	if err != errPeerRejectedConn {
 		t.Fatal(err)
 	}
@@ -201,7 +270,7 @@ func TestListen(t *testing.T) {
 		t.Fatal("dial failed:", err)
 	}
 	addr = modules.NetAddress(conn.LocalAddr().String())
-	ack, err = connectVersionHandshake(conn, build.Version)
+	ack, err = connectVersionHandshake(conn, build.Version, minimumAcceptablePeerVersion)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -227,7 +296,7 @@ func TestListen(t *testing.T) {
 		t.Fatal("dial failed:", err)
 	}
 	addr = modules.NetAddress(conn.LocalAddr().String())
-	ack, err = connectVersionHandshake(conn, build.Version)
+	ack, err = connectVersionHandshake(conn, build.Version, minimumAcceptablePeerVersion)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -349,6 +418,71 @@ func TestConnect(t *testing.T) {
 	g.mu.RUnlock()
 }
 
+// TestConnectIPv6 checks that gateways listening on the IPv6 loopback address
+// can connect to each other.
+func TestConnectIPv6(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	if l, err := net.Listen("tcp", "[::1]:0"); err != nil {
+		t.Skip("IPv6 is not available:", err)
+	} else {
+		l.Close()
+	}
+
+	g1, err := New("[::1]:0", false, build.TempDir("gateway", t.Name()+"1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g1.Close()
+	g2, err := New("[::1]:0", false, build.TempDir("gateway", t.Name()+"2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g2.Close()
+	if g1.Address().Host() != "::1" {
+		t.Fatal("expected gateway to listen on ::1, got", g1.Address())
+	}
+
+	// Connect and check that both sides see the other's IPv6 address.
+	if err := g1.Connect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		g2.mu.RLock()
+		defer g2.mu.RUnlock()
+		if _, exists := g2.peers[g1.Address()]; !exists {
+			return fmt.Errorf("g2 has not accepted g1: %v", g2.peers)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g1.mu.RLock()
+	_, exists := g1.peers[g2.Address()]
+	g1.mu.RUnlock()
+	if !exists {
+		t.Fatal("g1 is not connected to g2")
+	}
+
+	// RPCs work over the connection.
+	err = g1.RPC(g2.Address(), "DiscoverIP", func(conn modules.PeerConn) error {
+		var address string
+		if err := encoding.ReadObject(conn, &address, 100); err != nil {
+			return err
+		}
+		if address != "::1" {
+			return fmt.Errorf("expected ::1, got %v", address)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 // TestUnitAcceptableVersion tests that the acceptableVersion func returns an
 // error for unacceptable versions.
 func TestUnitAcceptableVersion(t *testing.T) {
@@ -391,7 +525,7 @@ func TestUnitAcceptableVersion(t *testing.T) {
 		".9.0.",
 	}
 	for _, v := range invalidVersions {
-		err := acceptableVersion(v)
+		err := acceptableVersion(v, minimumAcceptablePeerVersion)
 		if _, ok := err.(invalidVersionError); err == nil || !ok {
 			t.Errorf("acceptableVersion returned %q for version %q, but expected invalidVersionError", err, v)
 		}
@@ -417,7 +551,7 @@ func TestUnitAcceptableVersion(t *testing.T) {
 		"1.3.0",
 	}
 	for _, v := range insufficientVersions {
-		err := acceptableVersion(v)
+		err := acceptableVersion(v, minimumAcceptablePeerVersion)
 		if _, ok := err.(insufficientVersionError); err == nil || !ok {
 			t.Errorf("acceptableVersion returned %q for version %q, but expected insufficientVersionError", err, v)
 		}
@@ -440,7 +574,7 @@ func TestUnitAcceptableVersion(t *testing.T) {
 		"9.9.9",
 	}
 	for _, v := range validVersions {
-		err := acceptableVersion(v)
+		err := acceptableVersion(v, minimumAcceptablePeerVersion)
 		if err != nil {
 			t.Errorf("acceptableVersion returned %q for version %q, but expected nil", err, v)
 		}
@@ -622,7 +756,7 @@ func TestConnectRejectsVersions(t *testing.T) {
 			if err != nil {
 				panic(fmt.Sprintf("test #%d failed: %s", testIndex, err))
 			}
-			remoteVersion, err := acceptVersionHandshake(conn, tt.version)
+			remoteVersion, err := acceptVersionHandshake(conn, tt.version, minimumAcceptablePeerVersion)
 			if err != nil {
 				panic(fmt.Sprintf("test #%d failed: %s", testIndex, err))
 			}
@@ -775,7 +909,7 @@ func TestAcceptConnRejectsVersions(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		remoteVersion, err := connectVersionHandshake(conn, tt.remoteVersion)
+		remoteVersion, err := connectVersionHandshake(conn, tt.remoteVersion, minimumAcceptablePeerVersion)
 		if err != tt.errWant {
 			t.Fatal(err)
 		}
@@ -1164,3 +1298,117 @@ func TestBuildPeerManagerNodeList(t *testing.T) {
 		t.Fatal("bad nodelist:", nodelist)
 	}
 }
+
+// TestMinPeerVersion checks that peers below the minimum peer version are
+// rejected in both directions.
+func TestMinPeerVersion(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g1 := newNamedTestingGateway(t, "1")
+	defer g1.Close()
+	g2 := newNamedTestingGateway(t, "2")
+	defer g2.Close()
+
+	// Invalid minimum versions are rejected.
+	if err := g1.SetMinPeerVersion("not a version"); err != errMinPeerVersionBad {
+		t.Fatal("expected errMinPeerVersionBad, got", err)
+	}
+	if err := g1.SetMinPeerVersion("1.0.0"); err != errMinPeerVersionLow {
+		t.Fatal("expected errMinPeerVersionLow, got", err)
+	}
+
+	// Require a version that is newer than ours.
+	if err := g1.SetMinPeerVersion("999.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g2.Connect(g1.Address()); err != errPeerRejectedConn {
+		t.Fatal("expected errPeerRejectedConn, got", err)
+	}
+	err := g1.Connect(g2.Address())
+	if _, ok := err.(insufficientVersionError); !ok {
+		t.Fatal("expected insufficientVersionError, got", err)
+	}
+	if len(g1.Peers()) != 0 || len(g2.Peers()) != 0 {
+		t.Fatal("peers were added despite the version requirement")
+	}
+
+	// Lowering the requirement allows the connection.
+	if err := g1.SetMinPeerVersion(build.Version); err != nil {
+		t.Fatal(err)
+	}
+	if err := g2.Connect(g1.Address()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestHandshakeTimeout checks that a peer which does not complete the
+// handshake is dropped after the handshake timeout.
+func TestHandshakeTimeout(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g := newTestingGateway(t)
+	defer g.Close()
+	g.SetHandshakeTimeout(200 * time.Millisecond)
+
+	conn, err := net.Dial("tcp", string(g.Address()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Stay silent; the gateway should close the connection.
+	start := time.Now()
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Fatal("expected the connection to be closed")
+	} else if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		t.Fatal("gateway did not drop the stalled handshake")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("handshake timeout took too long")
+	}
+}
+
+// TestPeerHeights checks that reported peer tips are tracked for connected
+// peers only, and never decrease.
+func TestPeerHeights(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g1 := newNamedTestingGateway(t, "1")
+	defer g1.Close()
+	g2 := newNamedTestingGateway(t, "2")
+	defer g2.Close()
+
+	if err := g1.Connect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	if len(g1.PeerHeights()) != 0 {
+		t.Fatal("peer has a height before reporting one")
+	}
+	tip := types.BlockID{1}
+	g1.SetPeerTip(g2.Address(), 10, tip)
+	g1.SetPeerTip(g2.Address(), 5, types.BlockID{2})
+	g1.SetPeerTip("1.2.3.4:1234", 20, types.BlockID{3})
+	heights := g1.PeerHeights()
+	if len(heights) != 1 || heights[g2.Address()] != 10 {
+		t.Fatal("unexpected peer heights:", heights)
+	}
+	tips := g1.PeerTips()
+	if len(tips) != 1 || tips[g2.Address()] != (modules.PeerTip{Height: 10, ID: tip}) {
+		t.Fatal("unexpected peer tips:", tips)
+	}
+
+	// Disconnecting removes the height.
+	if err := g1.Disconnect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	if len(g1.PeerHeights()) != 0 || len(g1.PeerTips()) != 0 {
+		t.Fatal("disconnected peer still has a tip")
+	}
+}
@@ -40,16 +40,21 @@ func (g *Gateway) managedPeerManagerConnect(addr modules.NetAddress) {
 		}
 		g.mu.Unlock()
 	} else if err != nil {
-		g.log.Debugf("[PMC] [ERROR] [%v] WARN: removing peer because automatic connect failed: %v\n", addr, err)
-
-		// Remove the node, but only if there are enough nodes in the node list.
+		// Back off before dialing the node again. Nodes that fail repeatedly
+		// are removed, but only if there are enough nodes in the node list.
 		g.mu.Lock()
-		if len(g.nodes) > pruneNodeListLen {
+		if g.recordConnectFailure(addr) && len(g.nodes) > pruneNodeListLen {
+			g.log.Debugf("[PMC] [ERROR] [%v] WARN: removing peer because automatic connect failed: %v\n", addr, err)
 			g.removeNode(addr)
+		} else {
+			g.log.Debugf("[PMC] [ERROR] [%v] WARN: backing off because automatic connect failed: %v\n", addr, err)
 		}
 		g.mu.Unlock()
 	} else {
 		g.log.Debugf("[PMC] [SUCCESS] [%v] peer successfully added", addr)
+		g.mu.Lock()
+		delete(g.reconnects, addr)
+		g.mu.Unlock()
 	}
 }
 
@@ -98,6 +103,11 @@ func (g *Gateway) permanentPeerManager(closedChan chan struct{}) {
 			isOutboundPeer := g.peers[addr] != nil && !g.peers[addr].Inbound
 			g.mu.RUnlock()
 			if numOutboundPeers >= wellConnectedThreshold {
+				// Replace a peer from a crowded subnet. The node list is
+				// rebuilt so that the replacement comes from another subnet.
+				if g.managedDisconnectCrowdedPeer() {
+					break
+				}
 				g.log.Debugln("INFO: [PPM] Gateway has enough peers, sleeping.")
 				if !g.managedSleep(wellConnectedDelay) {
 					return
@@ -159,7 +169,9 @@ func (g *Gateway) permanentPeerManager(closedChan chan struct{}) {
 }
 
 // buildPeerManagerNodeList returns the gateway's node list in the order that
-// permanentPeerManager should attempt to connect to them.
+// permanentPeerManager should attempt to connect to them. Nodes that are
+// backing off after a failed connection attempt are left out, and nodes from
+// subnets without outbound peers are tried first.
 func (g *Gateway) buildPeerManagerNodeList() []modules.NetAddress {
 	// flatten the node map, inserting in random order
 	nodes := make([]modules.NetAddress, len(g.nodes))
@@ -168,6 +180,13 @@ func (g *Gateway) buildPeerManagerNodeList() []modules.NetAddress {
 		nodes[perm[0]] = node.NetAddress
 		perm = perm[1:]
 	}
+	ready := nodes[:0]
+	for _, addr := range nodes {
+		if g.reconnectReady(addr) {
+			ready = append(ready, addr)
+		}
+	}
+	nodes = ready
 
 	// swap the outbound nodes to the front of the list
 	numOutbound := 0
@@ -177,5 +196,7 @@ func (g *Gateway) buildPeerManagerNodeList() []modules.NetAddress {
 			numOutbound++
 		}
 	}
-	return nodes
+
+	// Prefer nodes from subnets that have few outbound peers.
+	return diversifyNodeList(nodes, g.outboundSubnetCounts())
 }
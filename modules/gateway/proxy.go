@@ -0,0 +1,138 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+const (
+	// socks5Version is the version byte that starts every SOCKS5 message.
+	socks5Version = 0x05
+
+	// socks5NoAuth is the SOCKS5 authentication method that requires no
+	// authentication.
+	socks5NoAuth = 0x00
+
+	// socks5Connect is the SOCKS5 command that opens a TCP connection.
+	socks5Connect = 0x01
+
+	// The SOCKS5 address types.
+	socks5IPv4   = 0x01
+	socks5Domain = 0x03
+	socks5IPv6   = 0x04
+)
+
+var (
+	errOnionNoProxy     = errors.New("onion addresses can only be reached through a proxy")
+	errSocksAuth        = errors.New("proxy requires authentication")
+	errSocksAddressType = errors.New("proxy replied with an unknown address type")
+	errSocksHostLength  = errors.New("hostname is too long for the proxy")
+	errSocksVersion     = errors.New("proxy is not a SOCKS5 proxy")
+)
+
+// socks5Handshake asks the SOCKS5 proxy at the other end of conn to connect to
+// addr. Hostnames are sent to the proxy unresolved, so that no DNS lookups are
+// made outside of the proxy.
+func socks5Handshake(conn net.Conn, addr modules.NetAddress) error {
+	// Offer only the no-authentication method.
+	if _, err := conn.Write([]byte{socks5Version, 1, socks5NoAuth}); err != nil {
+		return err
+	}
+	resp := make([]byte, 2)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return err
+	}
+	if resp[0] != socks5Version {
+		return errSocksVersion
+	} else if resp[1] != socks5NoAuth {
+		return errSocksAuth
+	}
+
+	// Send the connect request.
+	port, err := strconv.ParseUint(addr.Port(), 10, 16)
+	if err != nil {
+		return err
+	}
+	req := []byte{socks5Version, socks5Connect, 0}
+	host := addr.Host()
+	if ip := net.ParseIP(host); ip != nil && ip.To4() != nil {
+		req = append(append(req, socks5IPv4), ip.To4()...)
+	} else if ip != nil {
+		req = append(append(req, socks5IPv6), ip.To16()...)
+	} else if len(host) > 255 {
+		return errSocksHostLength
+	} else {
+		req = append(append(req, socks5Domain, byte(len(host))), host...)
+	}
+	req = append(req, byte(port>>8), byte(port))
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	// Read the reply, discarding the address that the proxy bound to.
+	reply := make([]byte, 4)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != socks5Version {
+		return errSocksVersion
+	} else if reply[1] != 0 {
+		return fmt.Errorf("proxy refused the connection with code %v", reply[1])
+	}
+	var bindLen int
+	switch reply[3] {
+	case socks5IPv4:
+		bindLen = net.IPv4len
+	case socks5IPv6:
+		bindLen = net.IPv6len
+	case socks5Domain:
+		l := make([]byte, 1)
+		if _, err := io.ReadFull(conn, l); err != nil {
+			return err
+		}
+		bindLen = int(l[0])
+	default:
+		return errSocksAddressType
+	}
+	_, err = io.ReadFull(conn, make([]byte, bindLen+2))
+	return err
+}
+
+// managedProxyAddr returns the address of the SOCKS5 proxy that outbound
+// connections are made through, or the empty string if no proxy is set.
+func (g *Gateway) managedProxyAddr() modules.NetAddress {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.proxyAddr
+}
+
+// SetAcceptInbound sets whether the gateway accepts connections from peers.
+// Disabling inbound connections is useful when all traffic should go through
+// a proxy.
+func (g *Gateway) SetAcceptInbound(accept bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.rejectInbound = !accept
+}
+
+// SetProxy routes all outbound connections through the SOCKS5 proxy at
+// socks5Addr, such as a Tor client. While a proxy is set, the gateway never
+// connects to peers directly, does not try to discover its external IP, and
+// can connect to .onion addresses. An empty address removes the proxy.
+func (g *Gateway) SetProxy(socks5Addr string) error {
+	addr := modules.NetAddress(socks5Addr)
+	if addr != "" {
+		if err := addr.IsStdValid(); err != nil {
+			return err
+		}
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.proxyAddr = addr
+	return nil
+}
@@ -0,0 +1,166 @@
+package gateway
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// testSOCKS5Proxy is a minimal SOCKS5 proxy that records the addresses it is
+// asked to connect to.
+type testSOCKS5Proxy struct {
+	listener net.Listener
+	targets  []string
+	mu       sync.Mutex
+}
+
+// newTestSOCKS5Proxy starts a SOCKS5 proxy on the loopback interface.
+func newTestSOCKS5Proxy(t *testing.T) *testSOCKS5Proxy {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &testSOCKS5Proxy{listener: l}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go p.serve(conn)
+		}
+	}()
+	return p
+}
+
+// serve handles a single proxied connection.
+func (p *testSOCKS5Proxy) serve(conn net.Conn) {
+	defer conn.Close()
+	// Read the greeting and accept the no-authentication method.
+	buf := make([]byte, 262)
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+		return
+	}
+	if _, err := conn.Write([]byte{socks5Version, socks5NoAuth}); err != nil {
+		return
+	}
+
+	// Read the connect request.
+	if _, err := io.ReadFull(conn, buf[:4]); err != nil {
+		return
+	}
+	var host string
+	switch buf[3] {
+	case socks5IPv4:
+		io.ReadFull(conn, buf[:net.IPv4len])
+		host = net.IP(buf[:net.IPv4len]).String()
+	case socks5IPv6:
+		io.ReadFull(conn, buf[:net.IPv6len])
+		host = net.IP(buf[:net.IPv6len]).String()
+	case socks5Domain:
+		io.ReadFull(conn, buf[:1])
+		n := int(buf[0])
+		io.ReadFull(conn, buf[:n])
+		host = string(buf[:n])
+	}
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return
+	}
+	target := net.JoinHostPort(host, strconv.Itoa(int(buf[0])<<8|int(buf[1])))
+	p.mu.Lock()
+	p.targets = append(p.targets, target)
+	p.mu.Unlock()
+
+	// Connect to the target and relay traffic in both directions.
+	tconn, err := net.Dial("tcp", target)
+	if err != nil {
+		conn.Write([]byte{socks5Version, 0x05, 0, socks5IPv4, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer tconn.Close()
+	if _, err := conn.Write([]byte{socks5Version, 0, 0, socks5IPv4, 0, 0, 0, 0, 0, 0}); err != nil {
+		return
+	}
+	go io.Copy(tconn, conn)
+	io.Copy(conn, tconn)
+}
+
+// TestSetProxy checks that outbound connections are made through the proxy,
+// and that onion addresses require a proxy.
+func TestSetProxy(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g1 := newNamedTestingGateway(t, "1")
+	defer g1.Close()
+	g2 := newNamedTestingGateway(t, "2")
+	defer g2.Close()
+	proxy := newTestSOCKS5Proxy(t)
+	defer proxy.listener.Close()
+
+	// Onion addresses cannot be dialed without a proxy.
+	if _, err := g1.managedDial("expyuzz4wqqyqhjn.onion:9981"); err != errOnionNoProxy {
+		t.Fatal("expected errOnionNoProxy, got", err)
+	}
+
+	if err := g1.SetProxy("not an address"); err == nil {
+		t.Fatal("invalid proxy address was accepted")
+	}
+	if err := g1.SetProxy(proxy.listener.Addr().String()); err != nil {
+		t.Fatal(err)
+	}
+	if err := g1.Connect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	proxy.mu.Lock()
+	targets := proxy.targets
+	proxy.mu.Unlock()
+	if len(targets) != 1 || targets[0] != string(g2.Address()) {
+		t.Fatal("connection was not made through the proxy:", targets)
+	}
+
+	// Onion addresses are sent to the proxy unresolved.
+	if _, err := g1.managedDial("expyuzz4wqqyqhjn.onion:9981"); err == nil {
+		t.Fatal("expected the proxy to fail to reach the onion address")
+	}
+	proxy.mu.Lock()
+	targets = proxy.targets
+	proxy.mu.Unlock()
+	if len(targets) != 2 || targets[1] != "expyuzz4wqqyqhjn.onion:9981" {
+		t.Fatal("onion address was not sent to the proxy:", targets)
+	}
+}
+
+// TestSetAcceptInbound checks that a gateway refuses connections from peers
+// while inbound connections are disabled.
+func TestSetAcceptInbound(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g1 := newNamedTestingGateway(t, "1")
+	defer g1.Close()
+	g2 := newNamedTestingGateway(t, "2")
+	defer g2.Close()
+
+	g2.SetAcceptInbound(false)
+	if err := g1.Connect(g2.Address()); err == nil {
+		t.Fatal("connected to a gateway that refuses inbound connections")
+	}
+	g2.SetAcceptInbound(true)
+	if err := g1.Connect(g2.Address()); err != nil {
+		t.Fatal(err)
+	}
+	g1.mu.RLock()
+	_, ok := g1.peers[g2.Address()]
+	g1.mu.RUnlock()
+	if !ok {
+		t.Fatal("g2 is not a peer of g1")
+	}
+}
@@ -0,0 +1,98 @@
+package gateway
+
+import (
+	"errors"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/fastrand"
+)
+
+var errBadReconnectPolicy = errors.New("reconnect delays must be positive with max at least initial, and jitter must be between 0 and 1")
+
+// A reconnectPolicy controls how long the gateway waits before re-dialing a
+// node that dropped or failed to connect. The delay starts at initial and
+// doubles with each consecutive failure up to max. Each delay is randomly
+// adjusted by up to jitter times its length, so that nodes dropped at the same
+// time are not re-dialed at the same time.
+type reconnectPolicy struct {
+	initial time.Duration
+	max     time.Duration
+	jitter  float64
+}
+
+// reconnectState tracks the consecutive failed connection attempts to a node,
+// and the earliest time at which the node may be dialed again.
+type reconnectState struct {
+	failures int
+	next     time.Time
+}
+
+// delay returns the time to wait before re-dialing a node after the given
+// number of consecutive failures.
+func (rp reconnectPolicy) delay(failures int) time.Duration {
+	d := rp.initial
+	for i := 1; i < failures && d < rp.max; i++ {
+		d *= 2
+	}
+	if d > rp.max {
+		d = rp.max
+	}
+	f := float64(fastrand.Intn(1e6))/1e6*2 - 1
+	return time.Duration(float64(d) * (1 + rp.jitter*f))
+}
+
+// reconnectReady returns whether the node may be dialed, i.e. whether its
+// backoff has expired. The caller must hold g.mu.
+func (g *Gateway) reconnectReady(addr modules.NetAddress) bool {
+	rs, exists := g.reconnects[addr]
+	return !exists || !time.Now().Before(rs.next)
+}
+
+// recordDroppedPeer delays re-dialing an outbound peer that disconnected. The
+// caller must hold g.mu.
+func (g *Gateway) recordDroppedPeer(addr modules.NetAddress) {
+	if _, exists := g.nodes[addr]; !exists {
+		return
+	}
+	rs, exists := g.reconnects[addr]
+	if !exists {
+		rs = new(reconnectState)
+		g.reconnects[addr] = rs
+	}
+	rs.next = time.Now().Add(g.reconnectPolicy.delay(rs.failures + 1))
+}
+
+// recordConnectFailure records a failed connection attempt to a node, backing
+// off further before the node is dialed again. It returns whether the node has
+// failed often enough to be removed from the node list. The caller must hold
+// g.mu.
+func (g *Gateway) recordConnectFailure(addr modules.NetAddress) (remove bool) {
+	rs, exists := g.reconnects[addr]
+	if !exists {
+		rs = new(reconnectState)
+		g.reconnects[addr] = rs
+	}
+	rs.failures++
+	rs.next = time.Now().Add(g.reconnectPolicy.delay(rs.failures))
+	return rs.failures >= maxReconnectFailures
+}
+
+// SetReconnectPolicy sets the exponential backoff used when re-dialing nodes
+// that dropped or failed to connect. The first re-dial waits initial, and each
+// consecutive failure doubles the wait up to max. Every wait is randomly
+// lengthened or shortened by up to jitter times its length. Nodes that fail
+// maxReconnectFailures times in a row are removed from the node list.
+func (g *Gateway) SetReconnectPolicy(initial, max time.Duration, jitter float64) error {
+	if initial <= 0 || max < initial || jitter < 0 || jitter > 1 {
+		return errBadReconnectPolicy
+	}
+	g.mu.Lock()
+	g.reconnectPolicy = reconnectPolicy{
+		initial: initial,
+		max:     max,
+		jitter:  jitter,
+	}
+	g.mu.Unlock()
+	return nil
+}
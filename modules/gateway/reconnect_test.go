@@ -0,0 +1,92 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// TestReconnectPolicyDelay checks that reconnect delays grow exponentially up
+// to the maximum, within the bounds of the jitter.
+func TestReconnectPolicyDelay(t *testing.T) {
+	rp := reconnectPolicy{initial: time.Second, max: 10 * time.Second, jitter: 0.5}
+	tests := []struct {
+		failures int
+		base     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{100, 10 * time.Second},
+	}
+	for _, test := range tests {
+		for i := 0; i < 100; i++ {
+			d := rp.delay(test.failures)
+			if d < test.base/2 || d > test.base*3/2 {
+				t.Fatalf("delay after %v failures should be within 50%% of %v, got %v", test.failures, test.base, d)
+			}
+		}
+	}
+
+	rp.jitter = 0
+	if d := rp.delay(3); d != 4*time.Second {
+		t.Fatal("expected a delay of 4s without jitter, got", d)
+	}
+}
+
+// TestReconnectBackoff checks that nodes that fail to connect are left out of
+// the peer manager's node list until their backoff expires, and that they are
+// flagged for removal after repeated failures.
+func TestReconnectBackoff(t *testing.T) {
+	g := &Gateway{
+		nodes: map[modules.NetAddress]*node{
+			"foo": {NetAddress: "foo", WasOutboundPeer: true},
+			"bar": {NetAddress: "bar"},
+		},
+		reconnects: make(map[modules.NetAddress]*reconnectState),
+	}
+	if err := g.SetReconnectPolicy(time.Second, time.Minute, 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, rp := range []reconnectPolicy{{0, time.Second, 0}, {time.Second, time.Millisecond, 0}, {time.Second, time.Second, 1.5}} {
+		if err := g.SetReconnectPolicy(rp.initial, rp.max, rp.jitter); err != errBadReconnectPolicy {
+			t.Fatal("expected errBadReconnectPolicy, got", err)
+		}
+	}
+
+	// A failed node is backing off.
+	if g.recordConnectFailure("foo") {
+		t.Fatal("node flagged for removal after a single failure")
+	}
+	if nodes := g.buildPeerManagerNodeList(); len(nodes) != 1 || nodes[0] != "bar" {
+		t.Fatal("backing off node was not left out:", nodes)
+	}
+
+	// A dropped peer backs off too, and a node is ready again once its
+	// backoff expires.
+	g.recordDroppedPeer("bar")
+	if nodes := g.buildPeerManagerNodeList(); len(nodes) != 0 {
+		t.Fatal("dropped peer was not left out:", nodes)
+	}
+	g.reconnects["foo"].next = time.Now()
+	if nodes := g.buildPeerManagerNodeList(); len(nodes) != 1 || nodes[0] != "foo" {
+		t.Fatal("node was not ready after its backoff:", nodes)
+	}
+
+	// Repeated failures flag the node for removal.
+	for i := 1; i < maxReconnectFailures-1; i++ {
+		if g.recordConnectFailure("foo") {
+			t.Fatal("node flagged for removal too early")
+		}
+	}
+	if !g.recordConnectFailure("foo") {
+		t.Fatal("node was not flagged for removal")
+	}
+	g.removeNode("foo")
+	if _, exists := g.reconnects["foo"]; exists {
+		t.Fatal("removed node still has a backoff")
+	}
+}
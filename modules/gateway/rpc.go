@@ -3,6 +3,7 @@ package gateway
 import (
 	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/NebulousLabs/Sia/build"
@@ -155,10 +156,14 @@ func (g *Gateway) threadedListenPeer(p *peer) {
 		case <-peerCloseChan:
 		}
 
-		// Close the session and remove p from the peer list.
+		// Close the session and remove p from the peer list. Outbound peers
+		// that dropped are re-dialed after a backoff.
 		p.sess.Close()
 		g.mu.Lock()
 		delete(g.peers, p.NetAddress)
+		if !p.Inbound {
+			g.recordDroppedPeer(p.NetAddress)
+		}
 		g.mu.Unlock()
 	}()
 
@@ -216,8 +221,23 @@ func (g *Gateway) threadedHandleConn(conn modules.PeerConn) {
 	}
 	g.log.Debugf("INFO: incoming conn %v requested RPC \"%v\"", conn.RPCAddr(), id)
 
-	// call fn
-	err = fn(conn)
+	// call fn, limiting the amount of data that the peer can send. The limit
+	// defaults to defaultMaxMessageSize, which is one block.
+	lc := &limitedConn{
+		PeerConn:  conn,
+		remaining: atomic.LoadUint64(&g.atomicMaxMessageSize),
+	}
+	err = fn(lc)
+	if lc.exceeded {
+		// A peer that exceeds the limit is disconnected and removed from the
+		// node list, so that it is not connected to again.
+		g.log.Printf("WARN: peer %v exceeded the maximum message size in RPC \"%v\", disconnecting and removing it from the node list", conn.RPCAddr(), id)
+		g.Disconnect(conn.RPCAddr()) // error is ignored; the peer may already be gone.
+		g.mu.Lock()
+		g.removeNode(conn.RPCAddr()) // error is ignored; Disconnect may have removed the node.
+		g.mu.Unlock()
+		return
+	}
 	// don't log benign errors
 	if err == modules.ErrDuplicateTransactionSet || err == modules.ErrBlockKnown {
 		err = nil
@@ -3,13 +3,16 @@ package gateway
 import (
 	"errors"
 	"io"
+	"io/ioutil"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/fastrand"
 )
 
 func TestRPCID(t *testing.T) {
@@ -312,6 +315,71 @@ func TestThreadedHandleConn(t *testing.T) {
 	}
 }
 
+// TestMaxMessageSize checks that a peer which sends more than the maximum
+// message size over an RPC is cut off at the limit and disconnected.
+func TestMaxMessageSize(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g1 := newNamedTestingGateway(t, "1")
+	defer g1.Close()
+	g2 := newNamedTestingGateway(t, "2")
+	defer g2.Close()
+
+	err := g1.Connect(g2.Address())
+	if err != nil {
+		t.Fatal("failed to connect:", err)
+	}
+
+	const maxMessageSize = 1e3
+	g2.SetMaxMessageSize(maxMessageSize)
+	readChan := make(chan int, 1)
+	errChan := make(chan error, 1)
+	g2.RegisterRPC("Oversize", func(conn modules.PeerConn) error {
+		data, err := ioutil.ReadAll(conn)
+		readChan <- len(data)
+		errChan <- err
+		return err
+	})
+
+	// Send a message that is much larger than the limit.
+	err = g1.RPC(g2.Address(), "Oversize", func(conn modules.PeerConn) error {
+		_, err := conn.Write(fastrand.Bytes(10 * maxMessageSize))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case n := <-readChan:
+		if n > maxMessageSize {
+			t.Fatalf("handler read %v bytes, expected at most %v", n, maxMessageSize)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("handler was not called")
+	}
+	if err := <-errChan; err != errMessageTooLarge {
+		t.Fatal("expected errMessageTooLarge, got", err)
+	}
+
+	// g2 should disconnect from g1 and forget about it.
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		g2.mu.RLock()
+		defer g2.mu.RUnlock()
+		if _, exists := g2.peers[g1.Address()]; exists {
+			return errors.New("peer is still connected")
+		}
+		if _, exists := g2.nodes[g1.Address()]; exists {
+			return errors.New("peer is still in the node list")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 // TestBroadcast tests that calling broadcast with a slice of peers only
 // broadcasts to those peers.
 func TestBroadcast(t *testing.T) {
@@ -0,0 +1,128 @@
+package gateway
+
+import (
+	"errors"
+	"net"
+	"path/filepath"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/persist"
+)
+
+const (
+	// seedsFile is the name of the file that contains the peer addresses
+	// that were most recently resolved from the DNS seeds.
+	seedsFile = "seeds.json"
+
+	// defaultSeedPort is the port that is used for resolved seed addresses
+	// when the seed does not specify a port.
+	defaultSeedPort = "9981"
+)
+
+var (
+	errNoSeedAddresses = errors.New("no addresses could be resolved from the bootstrap seeds")
+
+	// seedsMetadata contains the header and version strings that identify
+	// the seed cache file.
+	seedsMetadata = persist.Metadata{
+		Header:  "Sia Seed Peers",
+		Version: "1.3.3",
+	}
+)
+
+// resolveSeeds resolves each seed to a list of peer addresses. A seed is
+// either a hostname or a hostname and port. Seeds that fail to resolve are
+// skipped; an error is only returned if no addresses could be resolved.
+func resolveSeeds(seeds []string) ([]modules.NetAddress, error) {
+	var addrs []modules.NetAddress
+	for _, seed := range seeds {
+		host, port, err := net.SplitHostPort(seed)
+		if err != nil {
+			host, port = seed, defaultSeedPort
+		}
+		ips, err := net.LookupHost(host)
+		if err != nil {
+			continue
+		}
+		for _, ip := range ips {
+			addrs = append(addrs, modules.NetAddress(net.JoinHostPort(ip, port)))
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, errNoSeedAddresses
+	}
+	return addrs, nil
+}
+
+// addSeedAddresses adds the provided addresses to the node list, returning
+// the number of addresses that were added.
+func (g *Gateway) addSeedAddresses(addrs []modules.NetAddress) (added int) {
+	for _, addr := range addrs {
+		err := g.addNode(addr)
+		if err == nil || err == errNodeExists {
+			added++
+		} else {
+			g.log.Debugf("WARN: failed to add the seed node '%v': %v", addr, err)
+		}
+	}
+	return added
+}
+
+// threadedBootstrapFromSeeds adds peer addresses from the DNS seeds to the
+// node list. Addresses that were resolved on a previous startup are used if
+// they are available, otherwise the seeds are resolved and the result is
+// cached. If the seeds cannot be resolved, the hardcoded bootstrap peers are
+// used instead. The seeds are not resolved while a proxy is set, as the DNS
+// lookups would bypass the proxy.
+func (g *Gateway) threadedBootstrapFromSeeds(seeds []string) {
+	if err := g.threads.Add(); err != nil {
+		return
+	}
+	defer g.threads.Done()
+
+	// Try the cached addresses first.
+	seedsPath := filepath.Join(g.persistDir, seedsFile)
+	var cached []modules.NetAddress
+	if err := persist.LoadJSON(seedsMetadata, &cached, seedsPath); err == nil {
+		g.mu.Lock()
+		added := g.addSeedAddresses(cached)
+		g.mu.Unlock()
+		if added > 0 {
+			g.log.Printf("INFO: added %v cached seed nodes", added)
+			return
+		}
+	}
+
+	if g.managedProxyAddr() != "" {
+		g.log.Println("INFO: not resolving the bootstrap seeds because a proxy is set")
+		g.mu.Lock()
+		g.addSeedAddresses(modules.BootstrapPeers)
+		g.mu.Unlock()
+		return
+	}
+
+	addrs, err := resolveSeeds(seeds)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err != nil {
+		g.log.Printf("WARN: failed to resolve bootstrap seeds %v: %v", seeds, err)
+		g.addSeedAddresses(modules.BootstrapPeers)
+		return
+	}
+	added := g.addSeedAddresses(addrs)
+	g.log.Printf("INFO: added %v nodes from the bootstrap seeds", added)
+	if err := persist.SaveJSON(seedsMetadata, addrs, seedsPath); err != nil {
+		g.log.Println("WARN: failed to cache the resolved seed addresses:", err)
+	}
+}
+
+// SetBootstrapSeeds sets the DNS seeds of the gateway. If the gateway did not
+// know of any nodes when it was started, the seeds are resolved to peer
+// addresses in the background and added to the node list. Seeds are not
+// resolved while a proxy is set.
+func (g *Gateway) SetBootstrapSeeds(hosts []string) {
+	if !g.staticNoKnownNodes || len(hosts) == 0 {
+		return
+	}
+	go g.threadedBootstrapFromSeeds(append([]string(nil), hosts...))
+}
@@ -0,0 +1,111 @@
+package gateway
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/persist"
+)
+
+// TestSetBootstrapSeeds checks that the gateway adds the addresses resolved
+// from its seeds to the node list, and that it falls back to the cached
+// addresses on a subsequent startup.
+func TestSetBootstrapSeeds(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	seedAddr := modules.NetAddress("127.0.0.1:9981")
+	hasNode := func(g *Gateway) error {
+		g.mu.RLock()
+		defer g.mu.RUnlock()
+		if _, exists := g.nodes[seedAddr]; !exists {
+			return errNoNodes
+		}
+		return nil
+	}
+
+	g1 := newNamedTestingGateway(t, "1")
+	defer g1.Close()
+	g1.SetBootstrapSeeds([]string{"localhost:9981"})
+	err := build.Retry(50, 100*time.Millisecond, func() error {
+		return hasNode(g1)
+	})
+	if err != nil {
+		t.Fatal("resolved seed address was not added to the node list")
+	}
+	var cached []modules.NetAddress
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		return persist.LoadJSON(seedsMetadata, &cached, filepath.Join(g1.persistDir, seedsFile))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A gateway that cannot resolve its seeds should use the cached
+	// addresses.
+	dir := build.TempDir("gateway", t.Name()+"2")
+	os.RemoveAll(dir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := persist.SaveJSON(seedsMetadata, cached, filepath.Join(dir, seedsFile)); err != nil {
+		t.Fatal(err)
+	}
+	g2, err := New("localhost:0", false, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g2.Close()
+	g2.SetBootstrapSeeds([]string{"seed.invalid"})
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		return hasNode(g2)
+	})
+	if err != nil {
+		t.Fatal("cached seed address was not added to the node list")
+	}
+}
+
+// TestResolveSeeds probes the resolveSeeds function.
+func TestResolveSeeds(t *testing.T) {
+	addrs, err := resolveSeeds([]string{"127.0.0.1", "seed.invalid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 1 || addrs[0] != modules.NetAddress("127.0.0.1:"+defaultSeedPort) {
+		t.Fatal("seed was not resolved to the expected address:", addrs)
+	}
+	if _, err := resolveSeeds([]string{"seed.invalid"}); err != errNoSeedAddresses {
+		t.Fatal("expected errNoSeedAddresses, got", err)
+	}
+}
+
+// TestBootstrapSeedsProxy checks that the seeds are not resolved while a proxy
+// is set.
+func TestBootstrapSeedsProxy(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g := newTestingGateway(t)
+	defer g.Close()
+	if err := g.SetProxy("127.0.0.1:9050"); err != nil {
+		t.Fatal(err)
+	}
+
+	g.threadedBootstrapFromSeeds([]string{"localhost:9981"})
+	g.mu.RLock()
+	_, exists := g.nodes["127.0.0.1:9981"]
+	g.mu.RUnlock()
+	if exists {
+		t.Fatal("seed was resolved while a proxy was set")
+	}
+	if _, err := os.Stat(filepath.Join(g.persistDir, seedsFile)); !os.IsNotExist(err) {
+		t.Fatal("resolved seeds were cached while a proxy was set:", err)
+	}
+}
@@ -65,6 +65,15 @@ func (g *Gateway) threadedLearnHostname() {
 	}()
 
 	for {
+		// Looking up the external IP would bypass the proxy, so discovery
+		// is paused while a proxy is set.
+		if g.managedProxyAddr() != "" {
+			if !g.managedSleep(rediscoverIPIntervalFailure) {
+				return // shutdown interrupted sleep
+			}
+			continue
+		}
+
 		// try UPnP first, then fallback to myexternalip.com and peer-to-peer
 		// discovery.
 		var host string
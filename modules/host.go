@@ -1,6 +1,8 @@
 package modules
 
 import (
+	"time"
+
 	"github.com/NebulousLabs/Sia/types"
 )
 
@@ -77,6 +79,17 @@ type (
 		UploadBandwidthRevenue            types.Currency `json:"uploadbandwidthrevenue"`
 	}
 
+	// CollateralPolicy controls how much collateral the host is willing to
+	// lock in file contracts. Collateral is the amount of collateral per byte
+	// per block, MaxCollateral is the most collateral that the host will lock
+	// in a single contract, and CollateralBudget is the most collateral that
+	// the host will lock across all of its contracts.
+	CollateralPolicy struct {
+		Collateral       types.Currency `json:"collateral"`
+		CollateralBudget types.Currency `json:"collateralbudget"`
+		MaxCollateral    types.Currency `json:"maxcollateral"`
+	}
+
 	// HostInternalSettings contains a list of settings that can be changed.
 	HostInternalSettings struct {
 		AcceptingContracts   bool              `json:"acceptingcontracts"`
@@ -176,9 +189,17 @@ type (
 		// PublicKey returns the public key of the host.
 		PublicKey() types.SiaPublicKey
 
+		// SetCollateralPolicy sets the amount of collateral that the host
+		// is willing to lock in file contracts.
+		SetCollateralPolicy(CollateralPolicy) error
+
 		// SetInternalSettings sets the hosting parameters of the host.
 		SetInternalSettings(HostInternalSettings) error
 
+		// SetProofRetryWindow sets the amount of time that the host will
+		// keep retrying a failed sector read while building a storage proof.
+		SetProofRetryWindow(time.Duration)
+
 		// StorageObligations returns the set of storage obligations held by
 		// the host.
 		StorageObligations() []StorageObligation
@@ -34,6 +34,23 @@ const (
 )
 
 var (
+	// defaultProofRetryWindow is the default amount of time that the host
+	// will keep retrying to read a sector for a storage proof before giving
+	// up on the proof.
+	defaultProofRetryWindow = build.Select(build.Var{
+		Standard: time.Minute * 10,
+		Dev:      time.Minute * 1,
+		Testing:  time.Second * 3,
+	}).(time.Duration)
+
+	// proofRetryInterval is the amount of time that the host waits between
+	// attempts to read a sector for a storage proof.
+	proofRetryInterval = build.Select(build.Var{
+		Standard: time.Second * 30,
+		Dev:      time.Second * 5,
+		Testing:  time.Millisecond * 100,
+	}).(time.Duration)
+
 	// connectablityCheckFirstWait defines how often the host's connectability
 	// check is run.
 	connectabilityCheckFirstWait = build.Select(build.Var{
@@ -196,6 +213,17 @@ var (
 		Testing:  time.Millisecond,
 	}).(time.Duration)
 
+	// settingsCacheTimeout defines how long the host serves its cached external
+	// settings before computing them again. Changes to the settings made
+	// through the host invalidate the cache right away, the timeout bounds how
+	// stale the transaction fees and the storage of the contract manager can
+	// get.
+	settingsCacheTimeout = build.Select(build.Var{
+		Dev:      time.Second * 10,
+		Standard: time.Minute,
+		Testing:  time.Second,
+	}).(time.Duration)
+
 	// workingStatusFirstCheck defines how frequently the Host's working status
 	// check runs
 	workingStatusFirstCheck = build.Select(build.Var{
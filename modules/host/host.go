@@ -69,6 +69,7 @@ import (
 	"net"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/crypto"
@@ -98,6 +99,10 @@ var (
 	// having been closed.
 	errHostClosed = errors.New("call is disabled because the host is closed")
 
+	// errMaxCollateralExceedsBudget is returned if a collateral policy allows
+	// a single contract to lock more collateral than the total budget.
+	errMaxCollateralExceedsBudget = errors.New("max collateral per contract cannot exceed the collateral budget")
+
 	// Nil dependency errors.
 	errNilCS     = errors.New("host cannot use a nil state")
 	errNilTpool  = errors.New("host cannot use a nil transaction pool")
@@ -157,8 +162,15 @@ type Host struct {
 	financialMetrics     modules.HostFinancialMetrics
 	settings             modules.HostInternalSettings
 	revisionNumber       uint64
+	settingsCache        settingsCache
 	workingStatus        modules.HostWorkingStatus
 	connectabilityStatus modules.HostConnectabilityStatus
+	proofRetryWindow     time.Duration
+
+	// proofRetryDeadlines holds, for every storage obligation whose proof
+	// sector could not be read, the time after which the read is no longer
+	// retried.
+	proofRetryDeadlines map[types.FileContractID]time.Time
 
 	// A map of storage obligations that are currently being modified. Locks on
 	// storage obligations can be long-running, and each storage obligation can
@@ -201,6 +213,7 @@ func (h *Host) checkUnlockHash() error {
 		// the host will be using this unlock hash to establish identity, and
 		// losing it will mean silently losing part of the host identity.
 		h.unlockHash = uc.UnlockHash()
+		h.invalidateSettings()
 		err = h.saveSync()
 		if err != nil {
 			return err
@@ -235,6 +248,9 @@ func newHost(dependencies modules.Dependencies, cs modules.ConsensusSet, tpool m
 
 		lockedStorageObligations: make(map[types.FileContractID]*siasync.TryMutex),
 
+		proofRetryWindow:    defaultProofRetryWindow,
+		proofRetryDeadlines: make(map[types.FileContractID]time.Time),
+
 		persistDir: persistDir,
 	}
 
@@ -365,6 +381,33 @@ func (h *Host) PublicKey() types.SiaPublicKey {
 	return h.publicKey
 }
 
+// SetCollateralPolicy updates the collateral settings of the host. The
+// collateral that the host advertises for a contract is further limited by
+// the host's remaining storage and by the unused part of the collateral
+// budget, see maxContractCollateral.
+func (h *Host) SetCollateralPolicy(policy modules.CollateralPolicy) error {
+	if err := h.tg.Add(); err != nil {
+		return err
+	}
+	defer h.tg.Done()
+	if policy.MaxCollateral.Cmp(policy.CollateralBudget) > 0 {
+		return errMaxCollateralExceedsBudget
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.settings.Collateral = policy.Collateral
+	h.settings.CollateralBudget = policy.CollateralBudget
+	h.settings.MaxCollateral = policy.MaxCollateral
+	h.invalidateSettings()
+
+	err := h.saveSync()
+	if err != nil {
+		return errors.New("collateral policy updated, but failed saving to disk: " + err.Error())
+	}
+	return nil
+}
+
 // SetInternalSettings updates the host's internal HostInternalSettings object.
 func (h *Host) SetInternalSettings(settings modules.HostInternalSettings) error {
 	h.mu.Lock()
@@ -399,7 +442,7 @@ func (h *Host) SetInternalSettings(settings modules.HostInternalSettings) error
 	}
 
 	h.settings = settings
-	h.revisionNumber++
+	h.invalidateSettings()
 
 	err = h.saveSync()
 	if err != nil {
@@ -408,6 +451,16 @@ func (h *Host) SetInternalSettings(settings modules.HostInternalSettings) error
 	return nil
 }
 
+// SetProofRetryWindow sets the amount of time that the host will keep retrying
+// to read a sector when building a storage proof. Retrying allows the host to
+// recover from transient I/O errors, such as a storage folder being remounted.
+// A window of 0 disables retrying.
+func (h *Host) SetProofRetryWindow(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.proofRetryWindow = d
+}
+
 // InternalSettings returns the settings of a host.
 func (h *Host) InternalSettings() modules.HostInternalSettings {
 	h.mu.RLock()
@@ -552,3 +552,70 @@ func TestPersistentSettings(t *testing.T) {
 	}
 }
 */
+
+// TestMaxContractCollateral checks that the collateral offered for a contract
+// is limited by the max collateral, the remaining storage and the collateral
+// budget.
+func TestMaxContractCollateral(t *testing.T) {
+	settings := modules.HostInternalSettings{
+		Collateral:       types.NewCurrency64(1),
+		CollateralBudget: types.NewCurrency64(1000),
+		MaxCollateral:    types.NewCurrency64(100),
+		MaxDuration:      10,
+	}
+	tests := []struct {
+		remainingStorage uint64
+		locked           uint64
+		expected         uint64
+	}{
+		{50, 0, 100},  // limited by MaxCollateral
+		{5, 0, 50},    // limited by the remaining storage
+		{50, 950, 50}, // limited by the collateral budget
+		{50, 1000, 0}, // budget exhausted
+		{50, 2000, 0}, // budget exceeded
+		{0, 0, 0},     // no storage remaining
+		{5, 980, 20},  // limited by both, budget is tighter
+	}
+	for _, test := range tests {
+		mc := maxContractCollateral(settings, test.remainingStorage, types.NewCurrency64(test.locked))
+		if !mc.Equals64(test.expected) {
+			t.Errorf("expected max collateral %v for %v remaining storage and %v locked, got %v", test.expected, test.remainingStorage, test.locked, mc)
+		}
+	}
+}
+
+// TestSetCollateralPolicy checks that the collateral policy updates the host
+// settings and is rejected if it allows a contract to exceed the budget.
+func TestSetCollateralPolicy(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	ht, err := newHostTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ht.Close()
+
+	policy := modules.CollateralPolicy{
+		Collateral:       types.NewCurrency64(3),
+		CollateralBudget: types.SiacoinPrecision.Mul64(10),
+		MaxCollateral:    types.SiacoinPrecision.Mul64(20),
+	}
+	if err := ht.host.SetCollateralPolicy(policy); err != errMaxCollateralExceedsBudget {
+		t.Fatal("expected errMaxCollateralExceedsBudget, got", err)
+	}
+	policy.MaxCollateral = types.SiacoinPrecision.Mul64(5)
+	if err := ht.host.SetCollateralPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	settings := ht.host.InternalSettings()
+	if !settings.Collateral.Equals(policy.Collateral) || !settings.CollateralBudget.Equals(policy.CollateralBudget) || !settings.MaxCollateral.Equals(policy.MaxCollateral) {
+		t.Fatal("collateral policy was not applied to the internal settings")
+	}
+
+	// The advertised max collateral should never exceed the policy.
+	if ht.host.ExternalSettings().MaxCollateral.Cmp(policy.MaxCollateral) > 0 {
+		t.Error("advertised max collateral exceeds the collateral policy")
+	}
+}
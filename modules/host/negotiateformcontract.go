@@ -268,9 +268,11 @@ func (h *Host) managedVerifyNewContract(txnSet []types.Transaction, renterPK cry
 		return errLowHostValidOutput
 	}
 	// Check that the collateral does not exceed the maximum amount of
-	// collateral allowed.
+	// collateral allowed. The advertised maximum also depends on the
+	// remaining storage, which may have changed since the renter fetched the
+	// settings, so the limit of the collateral policy is enforced instead.
 	expectedCollateral := contractCollateral(eSettings, fc)
-	if expectedCollateral.Cmp(eSettings.MaxCollateral) > 0 {
+	if expectedCollateral.Cmp(iSettings.MaxCollateral) > 0 {
 		return errMaxCollateralReached
 	}
 	// Check that the host has enough room in the collateral budget to add this
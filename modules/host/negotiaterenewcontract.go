@@ -270,9 +270,11 @@ func (h *Host) managedVerifyRenewedContract(so storageObligation, txnSet []types
 	}
 
 	// Check that the collateral does not exceed the maximum amount of
-	// collateral allowed.
+	// collateral allowed. The advertised maximum also depends on the
+	// remaining storage, which may have changed since the renter fetched the
+	// settings, so the limit of the collateral policy is enforced instead.
 	expectedCollateral := renewContractCollateral(so, externalSettings, fc)
-	if expectedCollateral.Cmp(externalSettings.MaxCollateral) > 0 {
+	if expectedCollateral.Cmp(internalSettings.MaxCollateral) > 0 {
 		return errMaxCollateralReached
 	}
 	// Check that the host has enough room in the collateral budget to add this
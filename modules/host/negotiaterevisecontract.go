@@ -137,7 +137,11 @@ func (h *Host) managedRevisionIteration(conn net.Conn, so *storageObligation, fi
 			}
 		}
 		newRevenue := storageRevenue.Add(bandwidthRevenue)
-		return extendErr("unable to verify updated contract: ", verifyRevision(*so, revision, blockHeight, newRevenue, newCollateral))
+		st, err := h.managedSectorTree(so.id(), so.SectorRoots)
+		if err != nil {
+			return extendErr("unable to compute the new Merkle root: ", ErrorInternal(err.Error()))
+		}
+		return extendErr("unable to verify updated contract: ", verifyRevision(*so, revision, blockHeight, newRevenue, newCollateral, st.root()))
 	}()
 	if err != nil {
 		modules.WriteNegotiationRejection(conn, err) // Error is ignored so that the error type can be preserved in extendErr.
@@ -227,7 +231,7 @@ func (h *Host) managedRPCReviseContract(conn net.Conn) error {
 
 // verifyRevision checks that the revision pays the host correctly, and that
 // the revision does not attempt any malicious or unexpected changes.
-func verifyRevision(so storageObligation, revision types.FileContractRevision, blockHeight types.BlockHeight, expectedExchange, expectedCollateral types.Currency) error {
+func verifyRevision(so storageObligation, revision types.FileContractRevision, blockHeight types.BlockHeight, expectedExchange, expectedCollateral types.Currency, merkleRoot crypto.Hash) error {
 	// Check that the revision is well-formed.
 	if len(revision.NewValidProofOutputs) != 2 || len(revision.NewMissedProofOutputs) != 3 {
 		return errBadContractOutputCounts
@@ -321,17 +325,8 @@ func verifyRevision(so storageObligation, revision types.FileContractRevision, b
 		return errBadRevisionNumber
 	}
 
-	// The Merkle root is checked last because it is the most expensive check.
-	log2SectorSize := uint64(0)
-	for 1<<log2SectorSize < (modules.SectorSize / crypto.SegmentSize) {
-		log2SectorSize++
-	}
-	ct := crypto.NewCachedTree(log2SectorSize)
-	for _, root := range so.SectorRoots {
-		ct.Push(root)
-	}
-	expectedMerkleRoot := ct.Root()
-	if revision.NewFileMerkleRoot != expectedMerkleRoot {
+	// Check that the Merkle root matches the updated sector roots.
+	if revision.NewFileMerkleRoot != merkleRoot {
 		return errBadFileMerkleRoot
 	}
 
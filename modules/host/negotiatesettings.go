@@ -1,14 +1,63 @@
 package host
 
 import (
+	"bytes"
 	"net"
 	"time"
 
 	"github.com/NebulousLabs/Sia/build"
-	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
 )
 
+var (
+	// errBadRenterVersion is returned if the renter sends a version that
+	// cannot be parsed during RPCSettingsVersioned.
+	errBadRenterVersion = ErrorCommunication("renter has provided an invalid version")
+
+	// settingsFormats lists the formats in which the host can send its
+	// external settings, newest first. A renter is sent the first format
+	// whose minimum renter version it meets.
+	settingsFormats = []struct {
+		minRenterVersion string
+		settings         func(modules.HostExternalSettings) interface{}
+	}{
+		{
+			minRenterVersion: modules.MinVersionedSettingsVersion,
+			settings: func(hes modules.HostExternalSettings) interface{} {
+				return modules.HostVersionedSettings{
+					SettingsVersion:      modules.HostSettingsVersion,
+					HostExternalSettings: hes,
+				}
+			},
+		},
+		{
+			minRenterVersion: "0.0.0",
+			settings: func(hes modules.HostExternalSettings) interface{} {
+				return hes
+			},
+		},
+	}
+)
+
+// settingsFormat returns the index of the newest format in settingsFormats
+// that is understood by a renter of the provided version.
+func settingsFormat(renterVersion string) int {
+	for i, format := range settingsFormats {
+		if build.VersionCmp(renterVersion, format.minRenterVersion) >= 0 {
+			return i
+		}
+	}
+	return len(settingsFormats) - 1
+}
+
+// settingsForVersion returns the external settings in the newest format that
+// is understood by a renter of the provided version.
+func settingsForVersion(hes modules.HostExternalSettings, renterVersion string) interface{} {
+	return settingsFormats[settingsFormat(renterVersion)].settings(hes)
+}
+
 // capacity returns the amount of storage still available on the machine. The
 // amount can be negative if the total capacity was reduced to below the active
 // capacity.
@@ -23,11 +72,55 @@ func (h *Host) capacity() (total, remaining uint64) {
 	return total, remaining
 }
 
-// externalSettings compiles and returns the external settings for the host.
+// maxContractCollateral returns the most collateral that the host is willing
+// to lock in a single new file contract. Beyond the MaxCollateral setting, the
+// collateral is limited to what is needed to fill the remaining storage for
+// the maximum contract duration, and to the unused part of the collateral
+// budget, so that the host never commits more funds than it has budgeted.
+func maxContractCollateral(settings modules.HostInternalSettings, remainingStorage uint64, lockedCollateral types.Currency) types.Currency {
+	maxCollateral := settings.MaxCollateral
+	storageCollateral := settings.Collateral.Mul64(remainingStorage).Mul64(uint64(settings.MaxDuration))
+	if storageCollateral.Cmp(maxCollateral) < 0 {
+		maxCollateral = storageCollateral
+	}
+	if lockedCollateral.Cmp(settings.CollateralBudget) >= 0 {
+		return types.ZeroCurrency
+	}
+	if budget := settings.CollateralBudget.Sub(lockedCollateral); budget.Cmp(maxCollateral) < 0 {
+		maxCollateral = budget
+	}
+	return maxCollateral
+}
+
+// externalSettings returns the external settings for the host. The settings
+// are cached, and only compiled again once the cache has been invalidated or
+// has timed out. The revision number of the settings is incremented whenever
+// the compiled settings differ from the cached ones, so renters can tell
+// whether their copy of the settings is current by comparing revision numbers.
 func (h *Host) externalSettings() modules.HostExternalSettings {
-	// Increment the revision number for the external settings
-	h.revisionNumber++
+	if h.settingsCache.valid && time.Since(h.settingsCache.compiled) < settingsCacheTimeout {
+		return h.settingsCache.settings
+	}
+
+	hes := h.compileExternalSettings()
+	cached := h.settingsCache.settings
+	cached.RevisionNumber = hes.RevisionNumber
+	if !bytes.Equal(encoding.Marshal(cached), encoding.Marshal(hes)) {
+		h.revisionNumber++
+		hes.RevisionNumber = h.revisionNumber
+	}
+	h.settingsCache = settingsCache{
+		settings: hes,
+		compiled: time.Now(),
+		signed:   make(map[int][]byte),
+		valid:    true,
+	}
+	return hes
+}
 
+// compileExternalSettings compiles the external settings for the host, using
+// the current revision number.
+func (h *Host) compileExternalSettings() modules.HostExternalSettings {
 	totalStorage, remainingStorage := h.capacity()
 	var netAddr modules.NetAddress
 	if h.settings.NetAddress != "" {
@@ -56,7 +149,7 @@ func (h *Host) externalSettings() modules.HostExternalSettings {
 		WindowSize:           h.settings.WindowSize,
 
 		Collateral:    h.settings.Collateral,
-		MaxCollateral: h.settings.MaxCollateral,
+		MaxCollateral: maxContractCollateral(h.settings, remainingStorage, h.financialMetrics.LockedStorageCollateral),
 
 		ContractPrice:          contractPrice,
 		DownloadBandwidthPrice: h.settings.MinDownloadBandwidthPrice,
@@ -73,28 +166,56 @@ func (h *Host) managedRPCSettings(conn net.Conn) error {
 	// Set the negotiation deadline.
 	conn.SetDeadline(time.Now().Add(modules.NegotiateSettingsTime))
 
-	// The revision number is updated so that the renter can be certain that
-	// they have the most recent copy of the settings. The revision number and
-	// signature can be compared against other settings objects that the renter
-	// may have, and if the new revision number is not higher the renter can
-	// suspect foul play. Largely, the revision number is in place to enable
-	// renters to share host settings with each other, a feature that has not
-	// yet been implemented.
+	// The revision number changes whenever the settings change, so that the
+	// renter can be certain that they have the most recent copy of the
+	// settings. The revision number and signature can be compared against
+	// other settings objects that the renter may have, and if the new revision
+	// number is lower the renter can suspect foul play. Largely, the revision
+	// number is in place to enable renters to share host settings with each
+	// other, a feature that has not yet been implemented.
 	//
-	// While updating the revision number, also grab the secret key and
-	// external settings.
-	var hes modules.HostExternalSettings
-	var secretKey crypto.SecretKey
+	// The settings are sent in the legacy format, which is the last entry of
+	// settingsFormats. The signed settings are cached along with the settings
+	// themselves, so the host does not sign them again for every renter.
 	h.mu.Lock()
-	secretKey = h.secretKey
-	hes = h.externalSettings()
+	signedSettings := h.signedExternalSettings(len(settingsFormats) - 1)
 	h.mu.Unlock()
 
 	// Write the settings to the renter. If the write fails, return a
 	// connection error.
-	err := crypto.WriteSignedObject(conn, hes, secretKey)
+	_, err := conn.Write(signedSettings)
 	if err != nil {
 		return ErrorConnection("failed WriteSignedObject during RPCSettings: " + err.Error())
 	}
 	return nil
 }
+
+// managedRPCSettingsVersioned is an rpc that returns the host's settings in a
+// format that is understood by the renter. The renter sends its version, and
+// the host responds with the settings in the newest format that the version
+// supports.
+func (h *Host) managedRPCSettingsVersioned(conn net.Conn) error {
+	// Set the negotiation deadline.
+	conn.SetDeadline(time.Now().Add(modules.NegotiateSettingsTime))
+
+	// Read the version of the renter.
+	var renterVersion string
+	err := encoding.ReadObject(conn, &renterVersion, build.MaxEncodedVersionLength)
+	if err != nil {
+		return ErrorConnection("failed to read renter version during RPCSettingsVersioned: " + err.Error())
+	}
+	if !build.IsVersion(renterVersion) {
+		return errBadRenterVersion
+	}
+
+	// Grab the signed settings, see managedRPCSettings.
+	h.mu.Lock()
+	signedSettings := h.signedExternalSettings(settingsFormat(renterVersion))
+	h.mu.Unlock()
+
+	_, err = conn.Write(signedSettings)
+	if err != nil {
+		return ErrorConnection("failed WriteSignedObject during RPCSettingsVersioned: " + err.Error())
+	}
+	return nil
+}
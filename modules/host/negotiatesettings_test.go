@@ -0,0 +1,93 @@
+package host
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// TestSettingsForVersion checks that renters on either side of the versioned
+// settings boundary are served a format they understand.
+func TestSettingsForVersion(t *testing.T) {
+	hes := modules.HostExternalSettings{RevisionNumber: 5, Version: build.Version}
+	if _, ok := settingsForVersion(hes, "1.3.3").(modules.HostExternalSettings); !ok {
+		t.Error("old renter was not served the legacy settings")
+	}
+	for _, v := range []string{modules.MinVersionedSettingsVersion, "1.4.0", "2.0"} {
+		vs, ok := settingsForVersion(hes, v).(modules.HostVersionedSettings)
+		if !ok {
+			t.Fatalf("renter %v was not served versioned settings", v)
+		}
+		if vs.SettingsVersion != modules.HostSettingsVersion || !reflect.DeepEqual(vs.HostExternalSettings, hes) {
+			t.Errorf("renter %v was served bad settings: %v", v, vs)
+		}
+	}
+}
+
+// TestRPCSettingsVersioned checks that the host responds to
+// RPCSettingsVersioned with settings in the renter's format.
+func TestRPCSettingsVersioned(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	ht, err := newHostTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ht.Close()
+	var pk crypto.PublicKey
+	copy(pk[:], ht.host.PublicKey().Key)
+
+	// requestSettings sends the renter version to the host and reads the
+	// signed response into obj.
+	requestSettings := func(version string, obj interface{}) error {
+		rConn, hConn := net.Pipe()
+		defer rConn.Close()
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- ht.host.managedRPCSettingsVersioned(hConn)
+			hConn.Close()
+		}()
+		if err := encoding.WriteObject(rConn, version); err != nil {
+			return err
+		}
+		if obj != nil {
+			if err := crypto.ReadSignedObject(rConn, obj, modules.NegotiateMaxHostExternalSettingsLen, pk); err != nil {
+				return err
+			}
+		}
+		return <-errChan
+	}
+
+	// A renter below the boundary receives the legacy settings.
+	var hes modules.HostExternalSettings
+	if err := requestSettings("1.3.3", &hes); err != nil {
+		t.Fatal(err)
+	}
+	if hes.NetAddress != ht.host.ExternalSettings().NetAddress || hes.Version != build.Version {
+		t.Error("legacy settings do not match the host's settings:", hes)
+	}
+
+	// A renter at the boundary receives the versioned settings.
+	var vs modules.HostVersionedSettings
+	if err := requestSettings(modules.MinVersionedSettingsVersion, &vs); err != nil {
+		t.Fatal(err)
+	}
+	if vs.SettingsVersion != modules.HostSettingsVersion {
+		t.Error("wrong settings version:", vs.SettingsVersion)
+	}
+	if vs.RevisionNumber != hes.RevisionNumber || vs.NetAddress != hes.NetAddress {
+		t.Error("versioned settings do not match the host's settings:", vs.HostExternalSettings)
+	}
+
+	// An invalid version is rejected.
+	if err := requestSettings("not a version", nil); err != errBadRenterVersion {
+		t.Fatal("expected errBadRenterVersion, got", err)
+	}
+}
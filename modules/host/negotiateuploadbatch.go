@@ -0,0 +1,155 @@
+package host
+
+import (
+	"net"
+	"time"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// uploadBatchCosts returns the Merkle roots of a batch of sectors that are
+// being appended to a storage obligation, along with the storage revenue,
+// upload bandwidth revenue and collateral that the batch adds to the
+// obligation. Every sector in the batch must be exactly one sector in size.
+func uploadBatchCosts(so storageObligation, sectors [][]byte, settings modules.HostExternalSettings, blockHeight types.BlockHeight) (roots []crypto.Hash, storageRevenue, bandwidthRevenue, newCollateral types.Currency, err error) {
+	if len(sectors) == 0 {
+		return nil, types.ZeroCurrency, types.ZeroCurrency, types.ZeroCurrency, errEmptyObject
+	}
+	blocksRemaining := so.proofDeadline() - blockHeight
+	blockBytesCurrency := types.NewCurrency64(uint64(blocksRemaining)).Mul64(modules.SectorSize)
+	for _, data := range sectors {
+		if uint64(len(data)) != modules.SectorSize {
+			return nil, types.ZeroCurrency, types.ZeroCurrency, types.ZeroCurrency, errBadSectorSize
+		}
+		bandwidthRevenue = bandwidthRevenue.Add(settings.UploadBandwidthPrice.Mul64(modules.SectorSize))
+		storageRevenue = storageRevenue.Add(settings.StoragePrice.Mul(blockBytesCurrency))
+		newCollateral = newCollateral.Add(settings.Collateral.Mul(blockBytesCurrency))
+		roots = append(roots, crypto.MerkleRoot(data))
+	}
+	return roots, storageRevenue, bandwidthRevenue, newCollateral, nil
+}
+
+// managedRPCUploadBatch accepts a request to append a batch of sectors to an
+// existing contract in a single round trip. The sectors are paid for by a
+// single revision, and are either all added to the contract or, if any sector
+// or the revision is rejected, none of them are. Once the revision is signed,
+// the host sends the new Merkle root of the contract and the signed revision
+// transaction to the renter.
+func (h *Host) managedRPCUploadBatch(conn net.Conn) error {
+	// Perform the file contract revision exchange, giving the renter the most
+	// recent file contract revision and getting the storage obligation that
+	// will be used to pay for the data.
+	_, so, err := h.managedRPCRecentRevision(conn)
+	if err != nil {
+		return extendErr("failed RPCRecentRevision during RPCUploadBatch: ", err)
+	}
+	// The storage obligation is received with a lock on it. Defer a call to
+	// unlock the storage obligation.
+	defer func() {
+		h.managedUnlockStorageObligation(so.id())
+	}()
+
+	// Send the settings to the renter, who will either accept or reject them.
+	err = h.managedRPCSettings(conn)
+	if err != nil {
+		return extendErr("RPCSettings failed: ", err)
+	}
+	conn.SetDeadline(time.Now().Add(modules.NegotiateFileContractRevisionTime))
+	err = modules.ReadNegotiationAcceptance(conn)
+	if err != nil {
+		return extendErr("renter rejected host settings: ", ErrorCommunication(err.Error()))
+	}
+
+	// Read some variables from the host for use later in the function.
+	h.mu.Lock()
+	settings := h.externalSettings()
+	secretKey := h.secretKey
+	blockHeight := h.blockHeight
+	h.mu.Unlock()
+
+	// The renter is going to send the sectors, followed by the file contract
+	// revision that pays for them.
+	var sectors [][]byte
+	var revision types.FileContractRevision
+	err = encoding.ReadObject(conn, &sectors, settings.MaxReviseBatchSize)
+	if err != nil {
+		return extendErr("unable to read sector batch: ", ErrorConnection(err.Error()))
+	}
+	err = encoding.ReadObject(conn, &revision, modules.NegotiateMaxFileContractRevisionSize)
+	if err != nil {
+		return extendErr("unable to read proposed revision: ", ErrorConnection(err.Error()))
+	}
+
+	// Verify that the revision pays for the whole batch. The sector roots of
+	// the obligation are only updated once the revision has been signed.
+	var sectorRoots []crypto.Hash
+	var merkleRoot crypto.Hash
+	roots, storageRevenue, bandwidthRevenue, newCollateral, err := uploadBatchCosts(so, sectors, settings, blockHeight)
+	if err == nil {
+		sectorRoots = append(append([]crypto.Hash(nil), so.SectorRoots...), roots...)
+		var st *sectorTree
+		st, err = h.managedSectorTree(so.id(), sectorRoots)
+		if err != nil {
+			err = extendErr("unable to compute the new Merkle root: ", ErrorInternal(err.Error()))
+		} else {
+			merkleRoot = st.root()
+			newRevenue := storageRevenue.Add(bandwidthRevenue)
+			newSO := so
+			newSO.SectorRoots = sectorRoots
+			err = extendErr("unable to verify updated contract: ", verifyRevision(newSO, revision, blockHeight, newRevenue, newCollateral, merkleRoot))
+		}
+	}
+	if err != nil {
+		modules.WriteNegotiationRejection(conn, err) // Error is ignored so that the error type can be preserved in extendErr.
+		return extendErr("rejected sector batch: ", err)
+	}
+	err = modules.WriteNegotiationAcceptance(conn)
+	if err != nil {
+		return extendErr("could not accept sector batch: ", ErrorConnection(err.Error()))
+	}
+
+	// Renter will send a transaction signature for the file contract revision.
+	var renterSig types.TransactionSignature
+	err = encoding.ReadObject(conn, &renterSig, modules.NegotiateMaxTransactionSignatureSize)
+	if err != nil {
+		return extendErr("could not read renter transaction signature: ", ErrorConnection(err.Error()))
+	}
+	txn, err := createRevisionSignature(revision, renterSig, secretKey, blockHeight)
+	if err != nil {
+		modules.WriteNegotiationRejection(conn, err) // Error is ignored so that the error type can be preserved in extendErr.
+		return extendErr("could not create revision signature: ", err)
+	}
+
+	// Store all of the sectors and the new revision together.
+	so.SectorRoots = sectorRoots
+	so.PotentialStorageRevenue = so.PotentialStorageRevenue.Add(storageRevenue)
+	so.RiskedCollateral = so.RiskedCollateral.Add(newCollateral)
+	so.PotentialUploadRevenue = so.PotentialUploadRevenue.Add(bandwidthRevenue)
+	so.RevisionTransactionSet = []types.Transaction{txn}
+	h.mu.Lock()
+	err = h.modifyStorageObligation(so, nil, roots, sectors)
+	h.mu.Unlock()
+	if err != nil {
+		modules.WriteNegotiationRejection(conn, err) // Error is ignored so that the error type can be preserved in extendErr.
+		return extendErr("could not modify storage obligation: ", ErrorInternal(err.Error()))
+	}
+
+	// Send acceptance, followed by the new Merkle root and the signed
+	// revision transaction.
+	err = modules.WriteNegotiationAcceptance(conn)
+	if err != nil {
+		return extendErr("could not accept revision: ", ErrorConnection(err.Error()))
+	}
+	err = encoding.WriteObject(conn, merkleRoot)
+	if err != nil {
+		return extendErr("failed to write new Merkle root: ", ErrorConnection(err.Error()))
+	}
+	err = encoding.WriteObject(conn, txn)
+	if err != nil {
+		return extendErr("failed to write signed revision: ", ErrorConnection(err.Error()))
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package host
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// TestUploadBatchCosts checks that a batch of sectors is priced as the sum of
+// its sectors, and that malformed batches are rejected.
+func TestUploadBatchCosts(t *testing.T) {
+	so := storageObligation{
+		OriginTransactionSet: []types.Transaction{{
+			FileContracts: []types.FileContract{{WindowEnd: 110}},
+		}},
+	}
+	settings := modules.HostExternalSettings{
+		Collateral:           types.NewCurrency64(1),
+		StoragePrice:         types.NewCurrency64(2),
+		UploadBandwidthPrice: types.NewCurrency64(3),
+	}
+	sectors := [][]byte{fastrand.Bytes(int(modules.SectorSize)), fastrand.Bytes(int(modules.SectorSize))}
+
+	roots, storage, bandwidth, collateral, err := uploadBatchCosts(so, sectors, settings, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roots) != 2 || roots[0] != crypto.MerkleRoot(sectors[0]) || roots[1] != crypto.MerkleRoot(sectors[1]) {
+		t.Fatal("wrong sector roots:", roots)
+	}
+	blockBytes := types.NewCurrency64(100 * modules.SectorSize)
+	if !storage.Equals(blockBytes.Mul64(2 * 2)) {
+		t.Error("wrong storage revenue:", storage)
+	}
+	if !bandwidth.Equals(types.NewCurrency64(3 * 2 * modules.SectorSize)) {
+		t.Error("wrong bandwidth revenue:", bandwidth)
+	}
+	if !collateral.Equals(blockBytes.Mul64(2)) {
+		t.Error("wrong collateral:", collateral)
+	}
+
+	// A batch is rejected as a whole if any of its sectors is malformed.
+	if _, _, _, _, err := uploadBatchCosts(so, nil, settings, 10); err != errEmptyObject {
+		t.Error("expected errEmptyObject, got", err)
+	}
+	sectors = append(sectors, make([]byte, modules.SectorSize-1))
+	if _, _, _, _, err := uploadBatchCosts(so, sectors, settings, 10); err != errBadSectorSize {
+		t.Error("expected errBadSectorSize, got", err)
+	}
+}
@@ -290,9 +290,15 @@ func (h *Host) threadedHandleConn(conn net.Conn) {
 	case modules.RPCReviseContract:
 		atomic.AddUint64(&h.atomicReviseCalls, 1)
 		err = extendErr("incoming RPCReviseContract failed: ", h.managedRPCReviseContract(conn))
+	case modules.RPCUploadBatch:
+		atomic.AddUint64(&h.atomicReviseCalls, 1)
+		err = extendErr("incoming RPCUploadBatch failed: ", h.managedRPCUploadBatch(conn))
 	case modules.RPCSettings:
 		atomic.AddUint64(&h.atomicSettingsCalls, 1)
 		err = extendErr("incoming RPCSettings failed: ", h.managedRPCSettings(conn))
+	case modules.RPCSettingsVersioned:
+		atomic.AddUint64(&h.atomicSettingsCalls, 1)
+		err = extendErr("incoming RPCSettingsVersioned failed: ", h.managedRPCSettingsVersioned(conn))
 	case rpcSettingsDeprecated:
 		h.log.Debugln("Received deprecated settings call")
 	default:
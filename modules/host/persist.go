@@ -129,6 +129,7 @@ func (h *Host) initDB() (err error) {
 		// database needs to be initialized. Create the database buckets.
 		buckets := [][]byte{
 			bucketActionItems,
+			bucketSectorTrees,
 			bucketStorageObligations,
 		}
 		for _, bucket := range buckets {
@@ -0,0 +1,252 @@
+package host
+
+// sectortree.go maintains a cached Merkle tree over the sector roots of each
+// storage obligation. Every interior node of the tree is kept, so changing a
+// single sector only requires rehashing the path from that sector to the top
+// of the tree, and the Merkle root and storage proofs of a contract can be
+// computed with a logarithmic number of hashes instead of rehashing every
+// sector root.
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+var (
+	// bucketSectorTrees maps the id of a storage obligation to the cached
+	// Merkle tree of its sector roots.
+	bucketSectorTrees = []byte("BucketSectorTrees")
+
+	// errBadSectorTree is returned when a cached sector tree cannot be
+	// decoded.
+	errBadSectorTree = errors.New("cached sector tree is corrupt")
+)
+
+// sectorTree is a Merkle tree whose leaves are sector roots. levels[0] holds
+// the sector roots, and levels[i+1][j] is the hash of levels[i][2j] and
+// levels[i][2j+1]. A node is only stored once both of its children exist, so
+// levels[i] holds the roots of the complete subtrees of 2^i sectors.
+type sectorTree struct {
+	levels [][]crypto.Hash
+}
+
+// sumNodes returns the hash of an interior Merkle tree node, matching the
+// hashing used by crypto.CachedMerkleTree.
+func sumNodes(left, right crypto.Hash) crypto.Hash {
+	var buf [1 + 2*crypto.HashSize]byte
+	buf[0] = 1
+	copy(buf[1:], left[:])
+	copy(buf[1+crypto.HashSize:], right[:])
+	return crypto.HashBytes(buf[:])
+}
+
+// largestPow2Below returns the largest power of two that is smaller than n,
+// which is where the Merkle tree splits n leaves into two subtrees.
+func largestPow2Below(n uint64) uint64 {
+	p := uint64(1)
+	for p*2 < n {
+		p *= 2
+	}
+	return p
+}
+
+// newSectorTree builds the sector tree of the provided sector roots from
+// scratch.
+func newSectorTree(roots []crypto.Hash) *sectorTree {
+	st := &sectorTree{levels: [][]crypto.Hash{nil}}
+	for _, root := range roots {
+		st.append(root)
+	}
+	return st
+}
+
+// numSectors returns the number of sectors in the tree.
+func (st *sectorTree) numSectors() uint64 {
+	return uint64(len(st.levels[0]))
+}
+
+// append adds a sector root to the end of the tree, hashing any subtrees that
+// it completes.
+func (st *sectorTree) append(root crypto.Hash) {
+	st.levels[0] = append(st.levels[0], root)
+	for i := 1; len(st.levels[i-1])%2 == 0; i++ {
+		if i == len(st.levels) {
+			st.levels = append(st.levels, nil)
+		}
+		below := st.levels[i-1]
+		st.levels[i] = append(st.levels[i], sumNodes(below[len(below)-2], below[len(below)-1]))
+	}
+}
+
+// set replaces the sector root at the provided index, rehashing the nodes
+// above it.
+func (st *sectorTree) set(index uint64, root crypto.Hash) {
+	st.levels[0][index] = root
+	for i := 1; i < len(st.levels); i++ {
+		index /= 2
+		if index >= uint64(len(st.levels[i])) {
+			return
+		}
+		st.levels[i][index] = sumNodes(st.levels[i-1][2*index], st.levels[i-1][2*index+1])
+	}
+}
+
+// truncate removes all sectors at or after the provided index.
+func (st *sectorTree) truncate(n uint64) {
+	for i := range st.levels {
+		st.levels[i] = st.levels[i][:n>>uint(i)]
+	}
+	for len(st.levels) > 1 && len(st.levels[len(st.levels)-1]) == 0 {
+		st.levels = st.levels[:len(st.levels)-1]
+	}
+}
+
+// update brings the tree in line with the provided sector roots, only
+// rehashing the parts of the tree that cover sectors which changed.
+func (st *sectorTree) update(roots []crypto.Hash) {
+	if uint64(len(roots)) < st.numSectors() {
+		st.truncate(uint64(len(roots)))
+	}
+	for i, root := range st.levels[0] {
+		if roots[i] != root {
+			st.set(uint64(i), roots[i])
+		}
+	}
+	for _, root := range roots[st.numSectors():] {
+		st.append(root)
+	}
+}
+
+// subtreeRoot returns the root of the n sectors starting at start. start must
+// be a multiple of the largest power of two that is not larger than n, which
+// holds for every subtree of the tree.
+func (st *sectorTree) subtreeRoot(start, n uint64) crypto.Hash {
+	if n&(n-1) == 0 {
+		height := uint(0)
+		for 1<<height < n {
+			height++
+		}
+		return st.levels[height][start>>height]
+	}
+	split := largestPow2Below(n)
+	return sumNodes(st.subtreeRoot(start, split), st.subtreeRoot(start+split, n-split))
+}
+
+// root returns the Merkle root of the sector roots, which is the Merkle root of
+// the data of the storage obligation.
+func (st *sectorTree) root() crypto.Hash {
+	if st.numSectors() == 0 {
+		return crypto.Hash{}
+	}
+	return st.subtreeRoot(0, st.numSectors())
+}
+
+// prove returns the hashes that prove the sector at the provided index to the
+// root of the tree, ordered from the bottom of the tree to the top. Combined
+// with a proof of a segment within the sector, this forms a storage proof.
+func (st *sectorTree) prove(index uint64) []crypto.Hash {
+	var proof []crypto.Hash
+	var prove func(start, n uint64)
+	prove = func(start, n uint64) {
+		if n <= 1 {
+			return
+		}
+		split := largestPow2Below(n)
+		if index < start+split {
+			prove(start, split)
+			proof = append(proof, st.subtreeRoot(start+split, n-split))
+		} else {
+			prove(start+split, n-split)
+			proof = append(proof, st.subtreeRoot(start, split))
+		}
+	}
+	prove(0, st.numSectors())
+	return proof
+}
+
+// marshal encodes the tree as the number of sectors followed by the hashes of
+// each level.
+func (st *sectorTree) marshal() []byte {
+	size := 8
+	for _, level := range st.levels {
+		size += len(level) * crypto.HashSize
+	}
+	b := make([]byte, 8, size)
+	binary.LittleEndian.PutUint64(b, st.numSectors())
+	for _, level := range st.levels {
+		for _, h := range level {
+			b = append(b, h[:]...)
+		}
+	}
+	return b
+}
+
+// unmarshal decodes a tree encoded by marshal. The shape of the tree is
+// determined by the number of sectors, so a tree of the wrong size is
+// rejected.
+func (st *sectorTree) unmarshal(b []byte) error {
+	if len(b) < 8 {
+		return errBadSectorTree
+	}
+	n := binary.LittleEndian.Uint64(b)
+	b = b[8:]
+	st.levels = nil
+	for count := n; ; count /= 2 {
+		if uint64(len(b))/crypto.HashSize < count {
+			return errBadSectorTree
+		}
+		level := make([]crypto.Hash, count)
+		for i := range level {
+			copy(level[i][:], b[i*crypto.HashSize:])
+		}
+		st.levels = append(st.levels, level)
+		b = b[count*crypto.HashSize:]
+		if count <= 1 {
+			break
+		}
+	}
+	if len(b) != 0 {
+		return errBadSectorTree
+	}
+	return nil
+}
+
+// getSectorTree returns the sector tree of a storage obligation, updated to
+// match the provided sector roots. If the cached tree is missing or corrupt,
+// it is rebuilt from the sector roots.
+func getSectorTree(tx *bolt.Tx, soid types.FileContractID, roots []crypto.Hash) *sectorTree {
+	st := new(sectorTree)
+	if err := st.unmarshal(tx.Bucket(bucketSectorTrees).Get(soid[:])); err != nil {
+		return newSectorTree(roots)
+	}
+	st.update(roots)
+	return st
+}
+
+// putSectorTree updates the cached sector tree of a storage obligation to
+// match its sector roots. The tree is deleted once the obligation has no
+// sectors.
+func putSectorTree(tx *bolt.Tx, so storageObligation) error {
+	soid := so.id()
+	if len(so.SectorRoots) == 0 {
+		return tx.Bucket(bucketSectorTrees).Delete(soid[:])
+	}
+	st := getSectorTree(tx, soid, so.SectorRoots)
+	return tx.Bucket(bucketSectorTrees).Put(soid[:], st.marshal())
+}
+
+// managedSectorTree returns the sector tree of a storage obligation, updated to
+// match the provided sector roots. The cached tree in the database is not
+// modified.
+func (h *Host) managedSectorTree(soid types.FileContractID, roots []crypto.Hash) (st *sectorTree, err error) {
+	err = h.db.View(func(tx *bolt.Tx) error {
+		st = getSectorTree(tx, soid, roots)
+		return nil
+	})
+	return st, err
+}
@@ -0,0 +1,167 @@
+package host
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/fastrand"
+	"github.com/coreos/bbolt"
+)
+
+// randomRoots returns n random sector roots.
+func randomRoots(n int) []crypto.Hash {
+	roots := make([]crypto.Hash, n)
+	for i := range roots {
+		fastrand.Read(roots[i][:])
+	}
+	return roots
+}
+
+// checkSectorTree verifies the root and proofs of a sector tree against a
+// crypto.CachedMerkleTree built from scratch.
+func checkSectorTree(t *testing.T, st *sectorTree, roots []crypto.Hash) {
+	t.Helper()
+	ct := crypto.NewCachedTree(0)
+	for _, root := range roots {
+		ct.Push(root)
+	}
+	if st.root() != ct.Root() {
+		t.Fatalf("root of %v sectors does not match", len(roots))
+	}
+	for i := range roots {
+		ct := crypto.NewCachedTree(0)
+		ct.SetIndex(uint64(i))
+		for _, root := range roots {
+			ct.Push(root)
+		}
+		expected := ct.Prove(roots[i][:], nil)
+		proof := st.prove(uint64(i))
+		if len(proof) != len(expected) {
+			t.Fatalf("proof of sector %v/%v has %v hashes, expected %v", i, len(roots), len(proof), len(expected))
+		}
+		for j := range proof {
+			if proof[j] != expected[j] {
+				t.Fatalf("proof of sector %v/%v does not match", i, len(roots))
+			}
+		}
+	}
+}
+
+// TestSectorTree checks that the sector tree produces the same roots and
+// proofs as a cached Merkle tree as sectors are appended, modified, inserted,
+// and deleted.
+func TestSectorTree(t *testing.T) {
+	for n := 0; n < 35; n++ {
+		roots := randomRoots(n)
+		st := newSectorTree(roots)
+		checkSectorTree(t, st, roots)
+
+		// Round trip the tree through its encoding.
+		var decoded sectorTree
+		if err := decoded.unmarshal(st.marshal()); err != nil {
+			t.Fatal(err)
+		}
+		checkSectorTree(t, &decoded, roots)
+		if n > 0 {
+			if err := decoded.unmarshal(st.marshal()[:len(st.marshal())-1]); err != errBadSectorTree {
+				t.Fatal("expected errBadSectorTree, got", err)
+			}
+		}
+	}
+
+	// Apply a series of modifications, checking the tree after each one.
+	roots := randomRoots(20)
+	st := newSectorTree(roots)
+	modify := func(newRoots []crypto.Hash) {
+		t.Helper()
+		roots = newRoots
+		st.update(roots)
+		checkSectorTree(t, st, roots)
+	}
+	modify(append(roots, randomRoots(5)...))
+	modify(append(append([]crypto.Hash{}, roots[:7]...), append(randomRoots(1), roots[7:]...)...))
+	modify(append(append([]crypto.Hash{}, roots[:3]...), roots[4:]...))
+	newRoots := append([]crypto.Hash{}, roots...)
+	newRoots[11] = randomRoots(1)[0]
+	modify(newRoots)
+	modify(roots[:9])
+	modify(roots[:0])
+	modify(randomRoots(4))
+}
+
+// TestSectorTreeCache checks that a missing or corrupt cached sector tree is
+// rebuilt from the sector roots.
+func TestSectorTreeCache(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	ht, err := newHostTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ht.Close()
+
+	soid := types.FileContractID{1}
+	roots := randomRoots(9)
+	expected := newSectorTree(roots).root()
+	err = ht.host.db.Update(func(tx *bolt.Tx) error {
+		// A missing tree is rebuilt.
+		if getSectorTree(tx, soid, roots).root() != expected {
+			t.Error("missing tree was not rebuilt")
+		}
+		// A corrupt tree is rebuilt.
+		if err := tx.Bucket(bucketSectorTrees).Put(soid[:], []byte("garbage")); err != nil {
+			return err
+		}
+		if getSectorTree(tx, soid, roots).root() != expected {
+			t.Error("corrupt tree was not rebuilt")
+		}
+		// A stale tree is updated.
+		stale := newSectorTree(roots[:5])
+		if err := tx.Bucket(bucketSectorTrees).Put(soid[:], stale.marshal()); err != nil {
+			return err
+		}
+		if getSectorTree(tx, soid, roots).root() != expected {
+			t.Error("stale tree was not updated")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// benchmarkSectors is the number of sectors in the contract used by the proof
+// benchmarks, 16 GiB worth of 4 MiB sectors.
+const benchmarkSectors = 4096
+
+// BenchmarkStorageProofCachedTree measures building the contract level part
+// of a storage proof by pushing every sector root into a cached Merkle tree.
+func BenchmarkStorageProofCachedTree(b *testing.B) {
+	roots := randomRoots(benchmarkSectors)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ct := crypto.NewCachedTree(0)
+		ct.SetIndex(uint64(i % benchmarkSectors))
+		for _, root := range roots {
+			ct.Push(root)
+		}
+		ct.Prove(roots[i%benchmarkSectors][:], nil)
+	}
+}
+
+// BenchmarkStorageProofSectorTree measures building the contract level part of
+// a storage proof from a cached sector tree after one sector was modified.
+func BenchmarkStorageProofSectorTree(b *testing.B) {
+	roots := randomRoots(benchmarkSectors)
+	st := newSectorTree(roots)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fastrand.Read(roots[i%benchmarkSectors][:])
+		st.update(roots)
+		st.prove(uint64(i % benchmarkSectors))
+	}
+}
@@ -0,0 +1,80 @@
+package host
+
+import (
+	"time"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// settingsCache holds the most recently compiled external settings of the
+// host, along with their signed encodings. Renters request the settings far
+// more often than they change, so the host only compiles and signs them again
+// after the cache has been invalidated or has timed out, see
+// settingsCacheTimeout.
+type settingsCache struct {
+	settings modules.HostExternalSettings
+	compiled time.Time
+
+	// signed maps an index of settingsFormats to the signed encoding of the
+	// settings in that format, as written by crypto.WriteSignedObject.
+	signed map[int][]byte
+
+	valid bool
+}
+
+// invalidateSettings marks the cached external settings as out of date, so
+// that they are compiled again the next time that they are needed. It should
+// be called whenever something that the external settings depend on changes.
+func (h *Host) invalidateSettings() {
+	h.settingsCache.valid = false
+}
+
+// signedExternalSettings returns the external settings in the format with the
+// provided index in settingsFormats, signed by the host. The result is cached
+// until the settings change.
+func (h *Host) signedExternalSettings(format int) []byte {
+	hes := h.externalSettings()
+	if signed, exists := h.settingsCache.signed[format]; exists {
+		return signed
+	}
+	objBytes := encoding.Marshal(settingsFormats[format].settings(hes))
+	sig := crypto.SignHash(crypto.HashBytes(objBytes), h.secretKey)
+	signed := encoding.MarshalAll(sig, objBytes)
+	h.settingsCache.signed[format] = signed
+	return signed
+}
+
+// AddStorageFolder adds a storage folder to the host, see
+// modules.StorageManager. The remaining storage advertised by the host is
+// updated right away.
+func (h *Host) AddStorageFolder(path string, size uint64) error {
+	err := h.StorageManager.AddStorageFolder(path, size)
+	h.mu.Lock()
+	h.invalidateSettings()
+	h.mu.Unlock()
+	return err
+}
+
+// RemoveStorageFolder removes a storage folder from the host, see
+// modules.StorageManager. The remaining storage advertised by the host is
+// updated right away.
+func (h *Host) RemoveStorageFolder(index uint16, force bool) error {
+	err := h.StorageManager.RemoveStorageFolder(index, force)
+	h.mu.Lock()
+	h.invalidateSettings()
+	h.mu.Unlock()
+	return err
+}
+
+// ResizeStorageFolder changes the size of a storage folder of the host, see
+// modules.StorageManager. The remaining storage advertised by the host is
+// updated right away.
+func (h *Host) ResizeStorageFolder(index uint16, newSize uint64, force bool) error {
+	err := h.StorageManager.ResizeStorageFolder(index, newSize, force)
+	h.mu.Lock()
+	h.invalidateSettings()
+	h.mu.Unlock()
+	return err
+}
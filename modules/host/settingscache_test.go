@@ -0,0 +1,87 @@
+package host
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestSettingsCache checks that the host serves cached settings until they
+// change, and that the revision number only changes along with the settings.
+func TestSettingsCache(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	ht, err := newHostTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ht.Close()
+
+	// Unchanged settings keep their revision number.
+	hes := ht.host.ExternalSettings()
+	if rev := ht.host.ExternalSettings().RevisionNumber; rev != hes.RevisionNumber {
+		t.Fatalf("revision number changed from %v to %v without a settings change", hes.RevisionNumber, rev)
+	}
+
+	// Setting the same internal settings again should not change the
+	// revision number either.
+	settings := ht.host.InternalSettings()
+	if err := ht.host.SetInternalSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	if rev := ht.host.ExternalSettings().RevisionNumber; rev != hes.RevisionNumber {
+		t.Fatalf("revision number changed from %v to %v without a settings change", hes.RevisionNumber, rev)
+	}
+
+	// A price change should be reflected right away, with a new revision
+	// number.
+	settings.MinStoragePrice = settings.MinStoragePrice.Add(types.NewCurrency64(1))
+	if err := ht.host.SetInternalSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	newHES := ht.host.ExternalSettings()
+	if !newHES.StoragePrice.Equals(settings.MinStoragePrice) {
+		t.Fatal("price change was not reflected in the external settings")
+	}
+	if newHES.RevisionNumber != hes.RevisionNumber+1 {
+		t.Fatalf("expected revision number %v, got %v", hes.RevisionNumber+1, newHES.RevisionNumber)
+	}
+
+	// So should a new storage folder.
+	sfPath := filepath.Join(ht.persistDir, "settingsCacheStorageFolder")
+	if err := os.MkdirAll(sfPath, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ht.host.AddStorageFolder(sfPath, modules.SectorSize*64); err != nil {
+		t.Fatal(err)
+	}
+	if ht.host.ExternalSettings().TotalStorage != newHES.TotalStorage+modules.SectorSize*64 {
+		t.Fatal("new storage folder was not reflected in the external settings")
+	}
+
+	// The signed settings should be cached, and be signed by the host.
+	ht.host.mu.Lock()
+	signed := ht.host.signedExternalSettings(0)
+	signedAgain := ht.host.signedExternalSettings(0)
+	ht.host.mu.Unlock()
+	if &signed[0] != &signedAgain[0] {
+		t.Fatal("signed settings were not cached")
+	}
+	var pk crypto.PublicKey
+	copy(pk[:], ht.host.PublicKey().Key)
+	var vs modules.HostVersionedSettings
+	err = crypto.ReadSignedObject(bytes.NewReader(signed), &vs, modules.NegotiateMaxHostExternalSettingsLen, pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vs.RevisionNumber != ht.host.ExternalSettings().RevisionNumber {
+		t.Fatal("signed settings do not match the external settings")
+	}
+}
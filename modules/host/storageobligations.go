@@ -35,6 +35,7 @@ import (
 	"encoding/json"
 	"errors"
 	"strconv"
+	"time"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/crypto"
@@ -181,14 +182,19 @@ func getStorageObligation(tx *bolt.Tx, soid types.FileContractID) (so storageObl
 }
 
 // putStorageObligation places a storage obligation into the database,
-// overwriting the existing storage obligation if there is one.
+// overwriting the existing storage obligation if there is one. The cached
+// sector tree of the obligation is updated to match its sector roots.
 func putStorageObligation(tx *bolt.Tx, so storageObligation) error {
 	soBytes, err := json.Marshal(so)
 	if err != nil {
 		return err
 	}
 	soid := so.id()
-	return tx.Bucket(bucketStorageObligations).Put(soid[:], soBytes)
+	err = tx.Bucket(bucketStorageObligations).Put(soid[:], soBytes)
+	if err != nil {
+		return err
+	}
+	return putSectorTree(tx, so)
 }
 
 // expiration returns the height at which the storage obligation expires.
@@ -390,7 +396,9 @@ func (h *Host) managedAddStorageObligation(so storageObligation) error {
 		}
 
 		// Update the host financial metrics with regards to this storage
-		// obligation.
+		// obligation. The locked collateral and the used storage affect the
+		// external settings.
+		h.invalidateSettings()
 		h.financialMetrics.ContractCount++
 		h.financialMetrics.PotentialContractCompensation = h.financialMetrics.PotentialContractCompensation.Add(so.ContractCost)
 		h.financialMetrics.LockedStorageCollateral = h.financialMetrics.LockedStorageCollateral.Add(so.LockedCollateral)
@@ -527,7 +535,9 @@ func (h *Host) modifyStorageObligation(so storageObligation, sectorsRemoved []cr
 	}
 
 	// Update the financial information for the storage obligation - apply the
-	// new values.
+	// new values. The locked collateral and the used storage affect the
+	// external settings.
+	h.invalidateSettings()
 	h.financialMetrics.PotentialContractCompensation = h.financialMetrics.PotentialContractCompensation.Add(so.ContractCost)
 	h.financialMetrics.LockedStorageCollateral = h.financialMetrics.LockedStorageCollateral.Add(so.LockedCollateral)
 	h.financialMetrics.PotentialStorageRevenue = h.financialMetrics.PotentialStorageRevenue.Add(so.PotentialStorageRevenue)
@@ -554,6 +564,8 @@ func (h *Host) removeStorageObligation(so storageObligation, sos storageObligati
 	// Error is not checked, we want to call remove on every sector even if
 	// there are problems - disk health information will be updated.
 	_ = h.RemoveSectorBatch(so.SectorRoots)
+	h.invalidateSettings()
+	delete(h.proofRetryDeadlines, so.id())
 
 	// Update the host revenue metrics based on the status of the obligation.
 	if sos == obligationUnresolved {
@@ -624,6 +636,37 @@ func (h *Host) removeStorageObligation(so storageObligation, sos storageObligati
 	})
 }
 
+// managedScheduleProofRetry schedules another attempt at the action item of a
+// storage obligation whose proof sector could not be read, so that a
+// transient I/O error does not cause the proof to fail. The attempt is made
+// after proofRetryInterval, and the storage obligation is not locked in the
+// meantime. false is returned if the proof retry window has elapsed since the
+// first failed read, in which case no attempt is scheduled.
+func (h *Host) managedScheduleProofRetry(soid types.FileContractID) bool {
+	h.mu.Lock()
+	deadline, exists := h.proofRetryDeadlines[soid]
+	if !exists {
+		deadline = time.Now().Add(h.proofRetryWindow)
+		h.proofRetryDeadlines[soid] = deadline
+	}
+	if time.Now().Add(proofRetryInterval).After(deadline) {
+		delete(h.proofRetryDeadlines, soid)
+		h.mu.Unlock()
+		return false
+	}
+	h.mu.Unlock()
+
+	go func() {
+		select {
+		case <-time.After(proofRetryInterval):
+		case <-h.tg.StopChan():
+			return
+		}
+		h.threadedHandleActionItem(soid)
+	}()
+	return true
+}
+
 // threadedHandleActionItem will look at a storage obligation and determine
 // which action is necessary for the storage obligation to succeed.
 func (h *Host) threadedHandleActionItem(soid types.FileContractID) {
@@ -817,25 +860,29 @@ func (h *Host) threadedHandleActionItem(soid types.FileContractID) {
 		sectorRoot := so.SectorRoots[sectorIndex]
 		sectorBytes, err := h.ReadSector(sectorRoot)
 		if err != nil {
-			h.log.Debugln(err)
+			if h.managedScheduleProofRetry(so.id()) {
+				h.log.Debugln("Host failed to read sector for storage proof, retrying:", err)
+			} else {
+				h.log.Println("Host unable to read sector for storage proof, proof will not be submitted for", so.id(), ":", err)
+			}
 			return
 		}
+		h.mu.Lock()
+		delete(h.proofRetryDeadlines, so.id())
+		h.mu.Unlock()
 
 		// Build the storage proof for just the sector.
 		sectorSegment := segmentIndex % (modules.SectorSize / crypto.SegmentSize)
 		base, cachedHashSet := crypto.MerkleProof(sectorBytes, sectorSegment)
 
-		// Using the sector, build a cached root.
-		log2SectorSize := uint64(0)
-		for 1<<log2SectorSize < (modules.SectorSize / crypto.SegmentSize) {
-			log2SectorSize++
-		}
-		ct := crypto.NewCachedTree(log2SectorSize)
-		ct.SetIndex(segmentIndex)
-		for _, root := range so.SectorRoots {
-			ct.Push(root)
+		// Extend the proof from the sector to the root of the contract using
+		// the cached sector tree.
+		st, err := h.managedSectorTree(so.id(), so.SectorRoots)
+		if err != nil {
+			h.log.Println("Host unable to build the storage proof for", so.id(), ":", err)
+			return
 		}
-		hashSet := ct.Prove(base, cachedHashSet)
+		hashSet := append(cachedHashSet, st.prove(sectorIndex)...)
 		sp := types.StorageProof{
 			ParentID: so.id(),
 			HashSet:  hashSet,
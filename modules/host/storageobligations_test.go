@@ -2,8 +2,10 @@ package host
 
 import (
 	"testing"
+	"time"
 
 	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/fastrand"
 )
 
 // TestStorageObligationID checks that the return function of the storage
@@ -80,3 +82,53 @@ func TestStorageObligationID(t *testing.T) {
 		t.Error("id function of storage obligation incorrect for file contracts with dependencies")
 	}
 }
+
+// TestScheduleProofRetry checks that the host keeps scheduling attempts at a
+// storage proof whose sector could not be read until the proof retry window
+// has elapsed, without holding the storage obligation lock in between.
+func TestScheduleProofRetry(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	ht, err := newHostTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ht.Close()
+
+	// With retrying disabled, no attempt should be scheduled.
+	var soid types.FileContractID
+	fastrand.Read(soid[:])
+	ht.host.SetProofRetryWindow(0)
+	if ht.host.managedScheduleProofRetry(soid) {
+		t.Fatal("retry was scheduled with retrying disabled")
+	}
+
+	// With retrying enabled, attempts should be scheduled until the window
+	// has elapsed.
+	window := 5 * proofRetryInterval
+	ht.host.SetProofRetryWindow(window)
+	start := time.Now()
+	for ht.host.managedScheduleProofRetry(soid) {
+		// The storage obligation must not be locked while the retry is
+		// pending.
+		if err := ht.host.managedTryLockStorageObligation(soid); err != nil {
+			t.Fatal("storage obligation is locked between retries:", err)
+		}
+		ht.host.managedUnlockStorageObligation(soid)
+		if time.Since(start) > 2*window {
+			t.Fatal("retries were scheduled after the window elapsed")
+		}
+		time.Sleep(proofRetryInterval)
+	}
+	if time.Since(start) < window-2*proofRetryInterval {
+		t.Fatal("retrying stopped before the window elapsed")
+	}
+	ht.host.mu.RLock()
+	_, exists := ht.host.proofRetryDeadlines[soid]
+	ht.host.mu.RUnlock()
+	if exists {
+		t.Fatal("retry deadline was not cleared after giving up")
+	}
+}
@@ -126,6 +126,10 @@ func (h *Host) ProcessConsensusChange(cc modules.ConsensusChange) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	// The transaction fees that the contract price is based on, and the
+	// collateral that the host has locked, may change with each block.
+	h.invalidateSettings()
+
 	// Wrap the whole parsing into a single large database tx to keep things
 	// efficient.
 	var actionItems []types.FileContractID
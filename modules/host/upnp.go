@@ -79,6 +79,7 @@ func (h *Host) managedLearnHostname() {
 
 	h.mu.Lock()
 	h.autoAddress = autoAddress
+	h.invalidateSettings()
 	err = h.saveSync()
 	h.mu.Unlock()
 	if err != nil {
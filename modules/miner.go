@@ -27,6 +27,68 @@ type BlockManager interface {
 	// BlocksMined returns the number of blocks and stale blocks that have been
 	// mined using this miner.
 	BlocksMined() (goodBlocks, staleBlocks int)
+
+	// AddTransactionToTemplate pins a transaction into the block template, so
+	// that it is included in mined blocks regardless of its fee until it is
+	// confirmed or becomes invalid. Pinned transactions are persisted.
+	AddTransactionToTemplate(types.Transaction) error
+
+	// SubscribePinnedDrops registers a channel that receives an event
+	// whenever a pinned transaction is dropped because it became invalid.
+	SubscribePinnedDrops(ch chan<- PinnedDropEvent)
+}
+
+// A PinnedDropEvent is sent to the subscribers of SubscribePinnedDrops when a
+// pinned transaction is dropped from the block template because it is no
+// longer valid, e.g. because one of its inputs was spent by another
+// transaction.
+type PinnedDropEvent struct {
+	TransactionID types.TransactionID
+	Reason        string
+}
+
+// WorkerID identifies a worker that was registered with the miner's work
+// server.
+type WorkerID uint64
+
+// MiningWork is a unit of work handed out by the work server. Each unit has a
+// unique extranonce embedded in the block, so workers can grind the entire
+// nonce space of the header without overlapping.
+type MiningWork struct {
+	// Header is the header to grind. The nonce of the header is zero.
+	Header types.BlockHeader `json:"header"`
+
+	// Target is the target that a solution must meet to be a valid block.
+	Target types.Target `json:"target"`
+
+	// ShareTarget is the easier target that a solution must meet to be
+	// accepted as a share.
+	ShareTarget types.Target `json:"sharetarget"`
+}
+
+// WorkerStats contains the share accounting of a worker.
+type WorkerStats struct {
+	Shares      uint64 `json:"shares"`      // Accepted shares, including blocks.
+	StaleShares uint64 `json:"staleshares"` // Shares submitted for outdated work.
+	Blocks      uint64 `json:"blocks"`      // Shares that were valid blocks.
+}
+
+// WorkServer distributes work to multiple workers, as done by a mining pool,
+// and keeps track of the shares that each worker submits.
+type WorkServer interface {
+	// RegisterWorker registers a new worker with the work server.
+	RegisterWorker() (WorkerID, error)
+
+	// GetWork returns a new unit of work for the worker.
+	GetWork(WorkerID) (MiningWork, error)
+
+	// SubmitWork submits a solved header for a unit of work. Headers that
+	// meet the share target are credited to the worker, and headers that
+	// meet the block target are also submitted to consensus.
+	SubmitWork(WorkerID, types.BlockHeader) error
+
+	// WorkerStats returns the share accounting of a worker.
+	WorkerStats(WorkerID) (WorkerStats, error)
 }
 
 // CPUMiner provides access to a single-threaded cpu miner.
@@ -77,5 +139,6 @@ type TestMiner interface {
 type Miner interface {
 	BlockManager
 	CPUMiner
+	WorkServer
 	io.Closer
 }
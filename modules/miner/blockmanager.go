@@ -26,7 +26,16 @@ func (m *Miner) blockForWork() types.Block {
 		b.Timestamp = types.CurrentTimestamp()
 	}
 
-	// Update the address + payouts.
+	// Add an arb-data txn to the block to create a unique merkle root.
+	randBytes := fastrand.Bytes(types.SpecifierLen)
+	randTxn := types.Transaction{
+		ArbitraryData: [][]byte{append(modules.PrefixNonSia[:], randBytes...)},
+	}
+	b.Transactions = append([]types.Transaction{randTxn}, m.templateTransactions()...)
+
+	// Update the address + payouts. The subsidy includes the fees of the
+	// transactions, so it is computed after the final set of transactions has
+	// been chosen.
 	err := m.checkAddress()
 	if err != nil {
 		m.log.Println(err)
@@ -36,13 +45,6 @@ func (m *Miner) blockForWork() types.Block {
 		UnlockHash: m.persist.Address,
 	}}
 
-	// Add an arb-data txn to the block to create a unique merkle root.
-	randBytes := fastrand.Bytes(types.SpecifierLen)
-	randTxn := types.Transaction{
-		ArbitraryData: [][]byte{append(modules.PrefixNonSia[:], randBytes...)},
-	}
-	b.Transactions = append([]types.Transaction{randTxn}, b.Transactions...)
-
 	return b
 }
 
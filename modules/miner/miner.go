@@ -96,6 +96,15 @@ type Miner struct {
 	splitSetIDFromTxID map[types.TransactionID]splitSetID
 	unsolvedBlockIndex map[types.TransactionID]int
 
+	// pinnedDropSubs receive an event whenever a pinned transaction is
+	// dropped. The pinned transactions themselves are kept in the persist.
+	pinnedDropSubs []*pinnedDropSubscription
+
+	// Work server variables. Each registered worker has its own extranonce
+	// space and its own set of work.
+	nextWorkerID modules.WorkerID
+	workers      map[modules.WorkerID]*poolWorker
+
 	// CPUMiner variables.
 	miningOn bool  // indicates if the miner is supposed to be running
 	mining   bool  // indicates if the miner is actually running
@@ -186,6 +195,8 @@ func New(cs modules.ConsensusSet, tpool modules.TransactionPool, w modules.Walle
 		splitSetIDFromTxID: make(map[types.TransactionID]splitSetID),
 		unsolvedBlockIndex: make(map[types.TransactionID]int),
 
+		workers: make(map[modules.WorkerID]*poolWorker),
+
 		persistDir: persistDir,
 	}
 
@@ -32,6 +32,10 @@ type (
 		Address       types.UnlockHash
 		BlocksFound   []types.BlockID
 		UnsolvedBlock types.Block
+
+		// PinnedTransactions are included in every block template ahead
+		// of the transactions from the transaction pool.
+		PinnedTransactions []types.Transaction
 	}
 )
 
@@ -0,0 +1,249 @@
+package miner
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errPinnedKnown    = errors.New("transaction is already pinned to the block template")
+	errPinnedTooLarge = errors.New("pinned transactions would not fit in a block")
+
+	// templateSizeLimit is the number of bytes of transactions that the miner
+	// puts into a block, leaving room for the rest of the block.
+	templateSizeLimit = int(types.BlockSizeLimit - 5e3)
+)
+
+// spentObjects returns the ids of the outputs and file contracts that a
+// transaction consumes.
+func spentObjects(txn types.Transaction) []types.OutputID {
+	var ids []types.OutputID
+	for _, sci := range txn.SiacoinInputs {
+		ids = append(ids, types.OutputID(sci.ParentID))
+	}
+	for _, sfi := range txn.SiafundInputs {
+		ids = append(ids, types.OutputID(sfi.ParentID))
+	}
+	for _, fcr := range txn.FileContractRevisions {
+		ids = append(ids, types.OutputID(fcr.ParentID))
+	}
+	for _, sp := range txn.StorageProofs {
+		ids = append(ids, types.OutputID(sp.ParentID))
+	}
+	return ids
+}
+
+// createdObjects returns the ids of the outputs and file contracts that a
+// transaction creates.
+func createdObjects(txn types.Transaction) []types.OutputID {
+	var ids []types.OutputID
+	for i := range txn.SiacoinOutputs {
+		ids = append(ids, types.OutputID(txn.SiacoinOutputID(uint64(i))))
+	}
+	for i := range txn.FileContracts {
+		ids = append(ids, types.OutputID(txn.FileContractID(uint64(i))))
+	}
+	for i := range txn.SiafundOutputs {
+		ids = append(ids, types.OutputID(txn.SiafundOutputID(uint64(i))))
+	}
+	return ids
+}
+
+// templateTransactions returns the transactions of the unsolved block, with
+// the pinned transactions placed first. Transactions from the transaction pool
+// that conflict with a pinned transaction, that depend on an excluded
+// transaction, or that no longer fit in the block are excluded.
+func (m *Miner) templateTransactions() []types.Transaction {
+	if len(m.persist.PinnedTransactions) == 0 {
+		return m.persist.UnsolvedBlock.Transactions
+	}
+
+	txns := append([]types.Transaction(nil), m.persist.PinnedTransactions...)
+	pinned := make(map[types.TransactionID]struct{})
+	blocked := make(map[types.OutputID]struct{})
+	var size int
+	for _, txn := range m.persist.PinnedTransactions {
+		pinned[txn.ID()] = struct{}{}
+		for _, id := range spentObjects(txn) {
+			blocked[id] = struct{}{}
+		}
+		size += txn.MarshalSiaSize()
+	}
+	for _, txn := range m.persist.UnsolvedBlock.Transactions {
+		if _, exists := pinned[txn.ID()]; exists {
+			continue
+		}
+		include := size+txn.MarshalSiaSize() <= templateSizeLimit
+		for _, id := range spentObjects(txn) {
+			if _, exists := blocked[id]; exists {
+				include = false
+			}
+		}
+		if !include {
+			for _, id := range createdObjects(txn) {
+				blocked[id] = struct{}{}
+			}
+			continue
+		}
+		for _, id := range spentObjects(txn) {
+			blocked[id] = struct{}{}
+		}
+		txns = append(txns, txn)
+		size += txn.MarshalSiaSize()
+	}
+	return txns
+}
+
+// pinnedDropSubscription queues the drop events of a subscriber, so that a
+// slow subscriber does not block the miner.
+type pinnedDropSubscription struct {
+	ch     chan<- modules.PinnedDropEvent
+	queue  []modules.PinnedDropEvent
+	wakeCh chan struct{}
+	mu     sync.Mutex
+}
+
+// push adds events to the queue of the subscription.
+func (s *pinnedDropSubscription) push(events []modules.PinnedDropEvent) {
+	s.mu.Lock()
+	s.queue = append(s.queue, events...)
+	s.mu.Unlock()
+	select {
+	case s.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// threadedDeliverPinnedDrops sends the queued events of a subscription to its
+// channel until the miner is closed.
+func (m *Miner) threadedDeliverPinnedDrops(s *pinnedDropSubscription) {
+	if err := m.tg.Add(); err != nil {
+		return
+	}
+	defer m.tg.Done()
+
+	for {
+		s.mu.Lock()
+		events := s.queue
+		s.queue = nil
+		s.mu.Unlock()
+		for _, e := range events {
+			select {
+			case s.ch <- e:
+			case <-m.tg.StopChan():
+				return
+			}
+		}
+		select {
+		case <-s.wakeCh:
+		case <-m.tg.StopChan():
+			return
+		}
+	}
+}
+
+// updatePinnedTxns drops the pinned transactions that are no longer valid
+// after a consensus change, either because they were confirmed or because
+// their inputs were spent by other transactions. The subscribers are notified
+// of the transactions that became invalid.
+func (m *Miner) updatePinnedTxns(cc modules.ConsensusChange) {
+	if len(m.persist.PinnedTransactions) == 0 {
+		return
+	}
+	confirmed := make(map[types.TransactionID]struct{})
+	for _, b := range cc.AppliedBlocks {
+		for _, txn := range b.Transactions {
+			confirmed[txn.ID()] = struct{}{}
+		}
+	}
+
+	var valid []types.Transaction
+	var dropped []modules.PinnedDropEvent
+	for _, txn := range m.persist.PinnedTransactions {
+		if _, exists := confirmed[txn.ID()]; exists {
+			continue
+		}
+		if _, err := cc.TryTransactionSet(append(valid, txn)); err != nil {
+			m.log.Printf("WARN: dropping pinned transaction %v: %v", txn.ID(), err)
+			dropped = append(dropped, modules.PinnedDropEvent{
+				TransactionID: txn.ID(),
+				Reason:        err.Error(),
+			})
+			continue
+		}
+		valid = append(valid, txn)
+	}
+	if len(valid) == len(m.persist.PinnedTransactions) {
+		return
+	}
+	m.persist.PinnedTransactions = valid
+	if err := m.saveSync(); err != nil {
+		m.log.Println("ERROR: Unable to save pinned transactions:", err)
+	}
+	if len(dropped) == 0 {
+		return
+	}
+	for _, s := range m.pinnedDropSubs {
+		s.push(dropped)
+	}
+}
+
+// AddTransactionToTemplate pins a transaction into the miner's block template,
+// so that it is included in every block mined until it is confirmed. The
+// transaction must be valid together with the transactions that are already
+// pinned. Pinned transactions are included ahead of the transactions chosen
+// from the transaction pool, regardless of their fees. A pinned transaction
+// that becomes invalid, e.g. because one of its inputs was spent by another
+// transaction, is dropped and the subscribers of SubscribePinnedDrops are
+// notified. Pinned transactions are saved with the rest of the miner's
+// persist, so they survive a restart.
+func (m *Miner) AddTransactionToTemplate(txn types.Transaction) error {
+	if err := m.tg.Add(); err != nil {
+		return err
+	}
+	defer m.tg.Done()
+
+	// The consensus set is queried without holding the miner's lock, because
+	// the consensus set holds its own lock while updating the miner.
+	m.mu.RLock()
+	set := append(append([]types.Transaction(nil), m.persist.PinnedTransactions...), txn)
+	m.mu.RUnlock()
+	var size int
+	for _, pinned := range set[:len(set)-1] {
+		if pinned.ID() == txn.ID() {
+			return errPinnedKnown
+		}
+		size += pinned.MarshalSiaSize()
+	}
+	if size+txn.MarshalSiaSize() > templateSizeLimit {
+		return errPinnedTooLarge
+	}
+	if _, err := m.cs.TryTransactionSet(set); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.persist.PinnedTransactions = append(m.persist.PinnedTransactions, txn)
+	m.newSourceBlock()
+	return m.saveSync()
+}
+
+// SubscribePinnedDrops registers a channel that receives an event whenever a
+// pinned transaction is dropped from the block template because it became
+// invalid. Confirmed transactions are unpinned without an event. Events are
+// delivered in order from a separate goroutine, and are queued while the
+// channel is not being read.
+func (m *Miner) SubscribePinnedDrops(ch chan<- modules.PinnedDropEvent) {
+	s := &pinnedDropSubscription{
+		ch:     ch,
+		wakeCh: make(chan struct{}, 1),
+	}
+	m.mu.Lock()
+	m.pinnedDropSubs = append(m.pinnedDropSubs, s)
+	m.mu.Unlock()
+	go m.threadedDeliverPinnedDrops(s)
+}
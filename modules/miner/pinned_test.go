@@ -0,0 +1,259 @@
+package miner
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/persist"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestIntegrationAddTransactionToTemplate checks that pinned transactions are
+// mined ahead of the transaction pool and unpinned once they are confirmed.
+func TestIntegrationAddTransactionToTemplate(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	mt, err := createMinerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mt.miner.Close()
+
+	// Build a transaction set without submitting it to the transaction pool.
+	tb, err := mt.wallet.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.FundSiacoins(types.SiacoinPrecision); err != nil {
+		t.Fatal(err)
+	}
+	tb.AddSiacoinOutput(types.SiacoinOutput{Value: types.SiacoinPrecision})
+	set, err := tb.Sign(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The transactions of the set must be pinned in order.
+	if len(set) > 1 {
+		if err := mt.miner.AddTransactionToTemplate(set[len(set)-1]); err == nil {
+			t.Fatal("pinned a transaction whose parent is not pinned")
+		}
+	}
+	for _, txn := range set {
+		if err := mt.miner.AddTransactionToTemplate(txn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mt.miner.AddTransactionToTemplate(set[0]); err != errPinnedKnown {
+		t.Fatal("expected errPinnedKnown, got", err)
+	}
+
+	// The next block contains the pinned transactions, after which they are
+	// no longer pinned.
+	b, err := mt.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mined := make(map[types.TransactionID]struct{})
+	for _, txn := range b.Transactions {
+		mined[txn.ID()] = struct{}{}
+	}
+	for _, txn := range set {
+		if _, exists := mined[txn.ID()]; !exists {
+			t.Fatal("pinned transaction was not mined")
+		}
+	}
+	mt.miner.mu.RLock()
+	pinned := len(mt.miner.persist.PinnedTransactions)
+	mt.miner.mu.RUnlock()
+	if pinned != 0 {
+		t.Fatal("confirmed transactions are still pinned:", pinned)
+	}
+
+	// A transaction that is already confirmed cannot be pinned.
+	if err := mt.miner.AddTransactionToTemplate(set[len(set)-1]); err == nil {
+		t.Fatal("pinned a confirmed transaction")
+	}
+}
+
+// TestIntegrationPinnedTransactionFees checks that the miner payout of a block
+// includes the fees of the pinned transactions, so that the block is valid.
+func TestIntegrationPinnedTransactionFees(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	mt, err := createMinerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mt.miner.Close()
+
+	// Build a fee-paying transaction set without submitting it to the
+	// transaction pool, and pin it.
+	fee := types.SiacoinPrecision.Mul64(3)
+	tb, err := mt.wallet.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.FundSiacoins(types.SiacoinPrecision.Add(fee)); err != nil {
+		t.Fatal(err)
+	}
+	tb.AddMinerFee(fee)
+	tb.AddSiacoinOutput(types.SiacoinOutput{Value: types.SiacoinPrecision})
+	set, err := tb.Sign(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, txn := range set {
+		if err := mt.miner.AddTransactionToTemplate(txn); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The mined block should be accepted, and pay out the fee.
+	height := mt.cs.Height()
+	b, err := mt.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mt.cs.Height() != height+1 || mt.cs.CurrentBlock().ID() != b.ID() {
+		t.Fatal("block with a pinned fee-paying transaction was not accepted")
+	}
+	var payout types.Currency
+	for _, sco := range b.MinerPayouts {
+		payout = payout.Add(sco.Value)
+	}
+	if payout.Cmp(types.CalculateCoinbase(height+1).Add(fee)) < 0 {
+		t.Fatal("miner payout does not include the fee of the pinned transaction")
+	}
+}
+
+// TestIntegrationPinnedTransactionsPersist checks that pinned transactions
+// survive a restart of the miner.
+func TestIntegrationPinnedTransactionsPersist(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	mt, err := createMinerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tb, err := mt.wallet.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.FundSiacoins(types.SiacoinPrecision); err != nil {
+		t.Fatal(err)
+	}
+	tb.AddSiacoinOutput(types.SiacoinOutput{Value: types.SiacoinPrecision})
+	set, err := tb.Sign(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, txn := range set {
+		if err := mt.miner.AddTransactionToTemplate(txn); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Reboot the miner and verify that the transactions are still pinned.
+	if err := mt.miner.Close(); err != nil {
+		t.Fatal(err)
+	}
+	m, err := New(mt.cs, mt.tpool, mt.wallet, filepath.Join(mt.persistDir, modules.MinerDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	m.mu.RLock()
+	pinned := m.persist.PinnedTransactions
+	m.mu.RUnlock()
+	if len(pinned) != len(set) {
+		t.Fatalf("expected %v pinned transactions after reboot, got %v", len(set), len(pinned))
+	}
+	for i := range set {
+		if pinned[i].ID() != set[i].ID() {
+			t.Fatal("pinned transactions changed after reboot")
+		}
+	}
+}
+
+// TestUpdatePinnedTxns checks that pinned transactions that become invalid are
+// dropped, along with the pinned transactions that depend on them, and that
+// subscribers are notified of the drops.
+func TestUpdatePinnedTxns(t *testing.T) {
+	parent := types.Transaction{
+		SiacoinOutputs: []types.SiacoinOutput{{Value: types.SiacoinPrecision}},
+	}
+	child := types.Transaction{
+		SiacoinInputs: []types.SiacoinInput{{ParentID: parent.SiacoinOutputID(0)}},
+	}
+	other := types.Transaction{
+		ArbitraryData: [][]byte{[]byte("other")},
+	}
+	persistDir := build.TempDir(modules.MinerDir, t.Name())
+	if err := os.MkdirAll(persistDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	m := &Miner{
+		log:        persist.NewLogger(ioutil.Discard),
+		persistDir: persistDir,
+	}
+	m.persist.PinnedTransactions = []types.Transaction{parent, child, other}
+	drops := make(chan modules.PinnedDropEvent)
+	m.SubscribePinnedDrops(drops)
+	defer m.tg.Stop()
+
+	// Reject any set that contains the parent.
+	cc := modules.ConsensusChange{
+		TryTransactionSet: func(txns []types.Transaction) (modules.ConsensusChange, error) {
+			for _, txn := range txns {
+				if txn.ID() == parent.ID() {
+					return modules.ConsensusChange{}, errors.New("double spend")
+				}
+				for _, sci := range txn.SiacoinInputs {
+					if sci.ParentID == parent.SiacoinOutputID(0) {
+						return modules.ConsensusChange{}, errors.New("unknown output")
+					}
+				}
+			}
+			return modules.ConsensusChange{}, nil
+		},
+	}
+	m.mu.Lock()
+	m.updatePinnedTxns(cc)
+	m.mu.Unlock()
+	if len(m.persist.PinnedTransactions) != 1 || m.persist.PinnedTransactions[0].ID() != other.ID() {
+		t.Fatal("invalid transactions were not dropped:", m.persist.PinnedTransactions)
+	}
+	for _, txn := range []types.Transaction{parent, child} {
+		select {
+		case e := <-drops:
+			if e.TransactionID != txn.ID() || e.Reason == "" {
+				t.Fatal("wrong drop event:", e)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("no drop event for", txn.ID())
+		}
+	}
+
+	// The drops should have been saved.
+	var saved persistence
+	if err := persist.LoadJSON(settingsMetadata, &saved, filepath.Join(persistDir, settingsFile)); err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.PinnedTransactions) != 1 || saved.PinnedTransactions[0].ID() != other.ID() {
+		t.Fatal("dropped transactions were not saved:", saved.PinnedTransactions)
+	}
+}
@@ -292,6 +292,7 @@ func (m *Miner) ProcessConsensusChange(cc modules.ConsensusChange) {
 	m.persist.UnsolvedBlock.ParentID = cc.AppliedBlocks[len(cc.AppliedBlocks)-1].ID()
 	m.persist.Target = cc.ChildTarget
 	m.persist.UnsolvedBlock.Timestamp = cc.MinimumValidChildTimestamp
+	m.updatePinnedTxns(cc)
 
 	// There is a new parent block, the source block should be updated to keep
 	// the stale rate as low as possible.
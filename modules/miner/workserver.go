@@ -0,0 +1,246 @@
+package miner
+
+// workserver.go implements a work server for mining pools. Each registered
+// worker receives block templates with an extranonce in the arbitrary data
+// transaction, made up of the worker id and a per-worker counter. This gives
+// every unit of work a unique merkle root, so workers can grind the whole
+// nonce space without overlapping. Solutions that meet an easier share target
+// are counted for payout accounting, and solutions that meet the full target
+// are submitted to consensus.
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// shareDifficultyDivisor is the factor by which the difficulty of a
+	// share is lower than the difficulty of a block.
+	shareDifficultyDivisor = build.Select(build.Var{
+		Standard: int64(1e4),
+		Dev:      int64(100),
+		Testing:  int64(16),
+	}).(int64)
+
+	// workerWorkMemory is the number of units of work that are remembered
+	// per worker. Solutions for older work are rejected.
+	workerWorkMemory = build.Select(build.Var{
+		Standard: 20,
+		Dev:      10,
+		Testing:  5,
+	}).(int)
+
+	errDuplicateShare = errors.New("share has already been submitted")
+	errLowShare       = errors.New("solution does not meet the share target")
+	errStaleWork      = errors.New("work is for an outdated block")
+	errUnknownWork    = errors.New("work was not handed out to the worker or has been forgotten")
+	errUnknownWorker  = errors.New("worker is not registered")
+)
+
+type (
+	// workUnit is a block template that was handed out to a worker.
+	workUnit struct {
+		block       *types.Block
+		arbData     []byte
+		target      types.Target
+		shareTarget types.Target
+		shares      map[types.BlockNonce]struct{}
+	}
+
+	// poolWorker tracks the work that was handed out to a worker and the
+	// shares that the worker has submitted.
+	poolWorker struct {
+		extraNonce uint64
+		work       map[types.BlockHeader]*workUnit // Keyed by header with a zero nonce.
+		workOrder  []types.BlockHeader             // Oldest work first.
+		stats      modules.WorkerStats
+	}
+)
+
+// shareTarget returns the target that a solution must meet to be accepted as
+// a share.
+func shareTarget(target types.Target) types.Target {
+	return types.IntToTarget(new(big.Int).Mul(target.Int(), big.NewInt(shareDifficultyDivisor)))
+}
+
+// meetsTarget returns true if the block id meets the target.
+func meetsTarget(id types.BlockID, target types.Target) bool {
+	return bytes.Compare(target[:], id[:]) >= 0
+}
+
+// workBlock returns a copy of the unit's block that contains the unit's
+// arbitrary data, with the provided nonce.
+func (wu *workUnit) workBlock(nonce types.BlockNonce) types.Block {
+	b := *wu.block
+	b.Transactions = make([]types.Transaction, len(wu.block.Transactions))
+	copy(b.Transactions, wu.block.Transactions)
+	b.Transactions[0].ArbitraryData = [][]byte{wu.arbData}
+	b.Nonce = nonce
+	return b
+}
+
+// RegisterWorker registers a new worker with the work server.
+func (m *Miner) RegisterWorker() (modules.WorkerID, error) {
+	if err := m.tg.Add(); err != nil {
+		return 0, err
+	}
+	defer m.tg.Done()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := m.nextWorkerID
+	m.nextWorkerID++
+	m.workers[id] = &poolWorker{
+		work: make(map[types.BlockHeader]*workUnit),
+	}
+	return id, nil
+}
+
+// GetWork returns a new unit of work for the worker.
+func (m *Miner) GetWork(id modules.WorkerID) (modules.MiningWork, error) {
+	if err := m.tg.Add(); err != nil {
+		return modules.MiningWork{}, err
+	}
+	defer m.tg.Done()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, exists := m.workers[id]
+	if !exists {
+		return modules.MiningWork{}, errUnknownWorker
+	}
+
+	// The wallet needs to be unlocked for the miner to have an address for
+	// the payouts.
+	unlocked, err := m.wallet.Unlocked()
+	if err != nil {
+		return modules.MiningWork{}, err
+	}
+	if !unlocked {
+		return modules.MiningWork{}, modules.ErrLockedWallet
+	}
+	err = m.checkAddress()
+	if err != nil {
+		return modules.MiningWork{}, err
+	}
+	if m.sourceBlock == nil || time.Since(m.sourceBlockTime) > MaxSourceBlockAge {
+		m.newSourceBlock()
+	}
+
+	// Create the extranonce from the worker id and the worker's counter.
+	arbData := make([]byte, crypto.EntropySize)
+	copy(arbData, modules.PrefixNonSia[:])
+	binary.LittleEndian.PutUint64(arbData[types.SpecifierLen:], uint64(id))
+	binary.LittleEndian.PutUint64(arbData[types.SpecifierLen+8:], w.extraNonce)
+	w.extraNonce++
+
+	wu := &workUnit{
+		block:       m.sourceBlock,
+		arbData:     arbData,
+		target:      m.persist.Target,
+		shareTarget: shareTarget(m.persist.Target),
+		shares:      make(map[types.BlockNonce]struct{}),
+	}
+	b := wu.workBlock(types.BlockNonce{})
+	header := b.Header()
+
+	// Remember the work, forgetting the oldest work of the worker if needed.
+	if len(w.workOrder) >= workerWorkMemory {
+		delete(w.work, w.workOrder[0])
+		w.workOrder = w.workOrder[1:]
+	}
+	w.work[header] = wu
+	w.workOrder = append(w.workOrder, header)
+
+	return modules.MiningWork{
+		Header:      header,
+		Target:      wu.target,
+		ShareTarget: wu.shareTarget,
+	}, nil
+}
+
+// SubmitWork submits a solved header for a unit of work that was handed out
+// to the worker. Headers that meet the share target are credited to the
+// worker, and headers that also meet the block target are submitted to
+// consensus.
+func (m *Miner) SubmitWork(id modules.WorkerID, bh types.BlockHeader) error {
+	if err := m.tg.Add(); err != nil {
+		return err
+	}
+	defer m.tg.Done()
+
+	// Check the share and reconstruct the block while holding the lock, the
+	// block is submitted after the lock is released.
+	var b types.Block
+	var isBlock bool
+	err := func() error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		w, exists := m.workers[id]
+		if !exists {
+			return errUnknownWorker
+		}
+		nonce := bh.Nonce
+		bh.Nonce = types.BlockNonce{}
+		wu, exists := w.work[bh]
+		if !exists {
+			return errUnknownWork
+		}
+		if _, exists := wu.shares[nonce]; exists {
+			return errDuplicateShare
+		}
+		b = wu.workBlock(nonce)
+		bid := b.ID()
+		if !meetsTarget(bid, wu.shareTarget) {
+			return errLowShare
+		}
+		wu.shares[nonce] = struct{}{}
+
+		// Shares for work on an outdated parent cannot become blocks, and are
+		// not credited.
+		if b.ParentID != m.persist.UnsolvedBlock.ParentID {
+			w.stats.StaleShares++
+			return errStaleWork
+		}
+		w.stats.Shares++
+		isBlock = meetsTarget(bid, wu.target)
+		if isBlock {
+			w.stats.Blocks++
+		}
+		return nil
+	}()
+	if err != nil || !isBlock {
+		return err
+	}
+	err = m.managedSubmitBlock(b)
+	if err != nil {
+		m.log.Println("ERROR returned by managedSubmitBlock:", err)
+		return err
+	}
+	return nil
+}
+
+// WorkerStats returns the share accounting of a worker.
+func (m *Miner) WorkerStats(id modules.WorkerID) (modules.WorkerStats, error) {
+	if err := m.tg.Add(); err != nil {
+		return modules.WorkerStats{}, err
+	}
+	defer m.tg.Done()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	w, exists := m.workers[id]
+	if !exists {
+		return modules.WorkerStats{}, errUnknownWorker
+	}
+	return w.stats, nil
+}
@@ -0,0 +1,114 @@
+package miner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestShareTarget checks that the share target is easier than the block
+// target, and that it does not overflow.
+func TestShareTarget(t *testing.T) {
+	target := types.IntToTarget(big.NewInt(1000))
+	expected := types.IntToTarget(big.NewInt(1000 * shareDifficultyDivisor))
+	if shareTarget(target) != expected {
+		t.Error("wrong share target:", shareTarget(target))
+	}
+	if shareTarget(types.RootDepth) != types.RootDepth {
+		t.Error("share target overflowed")
+	}
+}
+
+// TestWorkServer checks that the work server hands out unique work to each
+// worker, credits shares, and submits solved blocks.
+func TestWorkServer(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	mt, err := createMinerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := mt.miner.GetWork(100); err != errUnknownWorker {
+		t.Fatal("expected errUnknownWorker, got", err)
+	}
+	w1, err := mt.miner.RegisterWorker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w2, err := mt.miner.RegisterWorker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w1 == w2 {
+		t.Fatal("workers were given the same id")
+	}
+
+	// Workers should never receive the same work.
+	work1, err := mt.miner.GetWork(w1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	work2, err := mt.miner.GetWork(w2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if work1.Header == work2.Header {
+		t.Fatal("workers were given the same work")
+	}
+
+	// Work can only be submitted by the worker that received it.
+	solved := solveHeader(work1.Header, work1.Target)
+	if err := mt.miner.SubmitWork(w2, solved); err != errUnknownWork {
+		t.Fatal("expected errUnknownWork, got", err)
+	}
+
+	// A solved block should be submitted to consensus and credited as both a
+	// share and a block.
+	height := mt.cs.Height()
+	if err := mt.miner.SubmitWork(w1, solved); err != nil {
+		t.Fatal(err)
+	}
+	if mt.cs.Height() != height+1 {
+		t.Fatal("solved work was not submitted to consensus")
+	}
+	stats, err := mt.miner.WorkerStats(w1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Shares != 1 || stats.Blocks != 1 || stats.StaleShares != 0 {
+		t.Fatalf("wrong stats after submitting a block: %+v", stats)
+	}
+	if err := mt.miner.SubmitWork(w1, solved); err != errDuplicateShare {
+		t.Fatal("expected errDuplicateShare, got", err)
+	}
+
+	// The work of the second worker is now stale.
+	solved = solveHeader(work2.Header, work2.ShareTarget)
+	if err := mt.miner.SubmitWork(w2, solved); err != errStaleWork {
+		t.Fatal("expected errStaleWork, got", err)
+	}
+	stats, err = mt.miner.WorkerStats(w2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Shares != 0 || stats.StaleShares != 1 {
+		t.Fatalf("wrong stats after submitting a stale share: %+v", stats)
+	}
+
+	// Old work should be forgotten.
+	old, err := mt.miner.GetWork(w2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < workerWorkMemory; i++ {
+		if _, err := mt.miner.GetWork(w2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mt.miner.SubmitWork(w2, solveHeader(old.Header, old.ShareTarget)); err != errUnknownWork {
+		t.Fatal("expected errUnknownWork, got", err)
+	}
+}
@@ -23,6 +23,19 @@ const (
 	StopResponse = "stop"
 )
 
+const (
+	// HostSettingsVersion is the version of the settings format that is sent
+	// as HostVersionedSettings. It must be increased whenever fields are
+	// added to the format.
+	HostSettingsVersion = "1.0.0"
+
+	// MinVersionedSettingsVersion is the first version of siad that supports
+	// RPCSettingsVersioned. Renters only use the RPC with hosts of at least
+	// this version, and hosts only send HostVersionedSettings to renters of
+	// at least this version.
+	MinVersionedSettingsVersion = "1.3.4"
+)
+
 const (
 	// NegotiateDownloadTime defines the amount of time that the renter and
 	// host have to negotiate a download request batch. The time is set high
@@ -167,6 +180,21 @@ var (
 	// RPCSettings is the specifier for requesting settings from the host.
 	RPCSettings = types.Specifier{'S', 'e', 't', 't', 'i', 'n', 'g', 's', 2}
 
+	// RPCSettingsVersioned is the specifier for requesting settings from the
+	// host in a format that the renter understands. The renter sends its
+	// version after the specifier, and the host responds with the newest
+	// settings format that is supported by that version.
+	RPCSettingsVersioned = types.Specifier{'S', 'e', 't', 't', 'i', 'n', 'g', 's', 3}
+
+	// RPCUploadBatch is the specifier for appending a batch of sectors to an
+	// existing file contract in a single round trip. After the recent
+	// revision exchange and the host's settings, the renter sends the
+	// sectors followed by a single revision that pays for all of them. The
+	// host adds either every sector or none, and responds to the renter's
+	// signature with the new Merkle root and the signed revision
+	// transaction.
+	RPCUploadBatch = types.Specifier{'U', 'p', 'l', 'o', 'a', 'd', 'B', 'a', 't', 'c', 'h', 2}
+
 	// SectorSize defines how large a sector should be in bytes. The sector
 	// size needs to be a power of two to be compatible with package
 	// merkletree. 4MB has been chosen for the live network because large
@@ -262,6 +290,14 @@ type (
 		Version        string `json:"version"`
 	}
 
+	// HostVersionedSettings are the host's external settings prefixed with
+	// the version of the settings format. They are sent by RPCSettingsVersioned
+	// to renters whose version is at least MinVersionedSettingsVersion.
+	HostVersionedSettings struct {
+		SettingsVersion string `json:"settingsversion"`
+		HostExternalSettings
+	}
+
 	// A RevisionAction is a description of an edit to be performed on a file
 	// contract. Three types are allowed, 'ActionDelete', 'ActionInsert', and
 	// 'ActionModify'. ActionDelete just takes a sector index, indicating which
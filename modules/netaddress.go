@@ -45,6 +45,12 @@ func (na NetAddress) Port() string {
 	return port
 }
 
+// IsOnion returns true for Tor onion service addresses, which can only be
+// reached through a Tor proxy.
+func (na NetAddress) IsOnion() bool {
+	return strings.HasSuffix(strings.ToLower(strings.TrimSuffix(na.Host(), ".")), ".onion")
+}
+
 // IsLoopback returns true for IP addresses that are on the same machine.
 func (na NetAddress) IsLoopback() bool {
 	host, _, err := net.SplitHostPort(string(na))
@@ -82,6 +88,7 @@ func (na NetAddress) IsLocal() bool {
 		"172.16.0.0/12",
 		"192.168.0.0/16",
 		"fd00::/8",
+		"fe80::/10",
 	}
 	for _, cidr := range localCIDRs {
 		_, ipnet, _ := net.ParseCIDR(cidr)
@@ -284,6 +284,10 @@ func TestIsLocal(t *testing.T) {
 		{"[fdff:ffff:ffff:ffff:ffff:ffff:ffff:ffff]:1234", true},
 		{"fe00:0000:0000:0000:0000:0000:0000:0000", false},
 		{"[fe00:0000:0000:0000:0000:0000:0000:0000]:1234", false},
+		{"fe80:0000:0000:0000:0000:0000:0000:0001", false},
+		{"[fe80:0000:0000:0000:0000:0000:0000:0001]:1234", true},
+		{"[febf:ffff:ffff:ffff:ffff:ffff:ffff:ffff]:1234", true},
+		{"[fec0:0000:0000:0000:0000:0000:0000:0000]:1234", false},
 
 		// Unspecified address tests.
 		{"0.0.0.0:1234", false},
@@ -317,3 +321,31 @@ func TestIsLocal(t *testing.T) {
 		}
 	}
 }
+
+// TestIsOnion checks that onion service addresses are recognized and pass
+// validation.
+func TestIsOnion(t *testing.T) {
+	t.Parallel()
+
+	testSet := []struct {
+		query           NetAddress
+		desiredResponse bool
+	}{
+		{"expyuzz4wqqyqhjn.onion:9981", true},
+		{"vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyyd.onion:9981", true},
+		{"EXPYUZZ4WQQYQHJN.ONION.:9981", true},
+		{"expyuzz4wqqyqhjn.onion", false},
+		{"onion.example.com:9981", false},
+		{"123.123.123.123:9981", false},
+	}
+	for _, test := range testSet {
+		if test.query.IsOnion() != test.desiredResponse {
+			t.Errorf("IsOnion(%v) = %v, expected %v", test.query, !test.desiredResponse, test.desiredResponse)
+		}
+		if test.desiredResponse {
+			if err := test.query.IsStdValid(); err != nil {
+				t.Errorf("onion address %v is not valid: %v", test.query, err)
+			}
+		}
+	}
+}
@@ -82,6 +82,7 @@ type DownloadInfo struct {
 	Error                string    `json:"error"`                // Will be the empty string unless there was an error.
 	Received             uint64    `json:"received"`             // Amount of data confirmed and decoded.
 	StartTime            time.Time `json:"starttime"`            // The time when the download was started.
+	Throughput           uint64    `json:"throughput"`           // Average rate of data transfer in bytes per second.
 	TotalDataTransferred uint64    `json:"totaldatatransferred"` // Total amount of data transferred, including negotiation, etc.
 }
 
@@ -93,6 +94,16 @@ type FileUploadParams struct {
 	ErasureCode ErasureCoder
 }
 
+// DirectoryInfo provides information about a directory of siapaths. Files
+// and Size cover every file in the directory and its subdirectories, while
+// Subdirs only lists the immediate subdirectories.
+type DirectoryInfo struct {
+	SiaPath string   `json:"siapath"`
+	Files   uint64   `json:"files"`
+	Size    uint64   `json:"size"`
+	Subdirs []string `json:"subdirs"`
+}
+
 // FileInfo provides information about a file.
 type FileInfo struct {
 	SiaPath        string            `json:"siapath"`
@@ -101,11 +112,24 @@ type FileInfo struct {
 	Available      bool              `json:"available"`
 	Renewing       bool              `json:"renewing"`
 	Redundancy     float64           `json:"redundancy"`
+	Critical       bool              `json:"critical"`
 	UploadedBytes  uint64            `json:"uploadedbytes"`
 	UploadProgress float64           `json:"uploadprogress"`
 	Expiration     types.BlockHeight `json:"expiration"`
 }
 
+// FileVersion provides information about a snapshot of a file.
+// UploadedBytes is the storage used by the version's pieces, and UniqueBytes
+// is the part of it that is not shared with the current file or any other
+// version of the file, i.e. the storage that purging the version would free.
+type FileVersion struct {
+	ID            string    `json:"id"`
+	Created       time.Time `json:"created"`
+	Filesize      uint64    `json:"filesize"`
+	UploadedBytes uint64    `json:"uploadedbytes"`
+	UniqueBytes   uint64    `json:"uniquebytes"`
+}
+
 // A HostDBEntry represents one host entry in the Renter's host DB. It
 // aggregates the host's external settings and metrics with its public key.
 type HostDBEntry struct {
@@ -185,6 +209,15 @@ type RenterSettings struct {
 	StreamCacheSize  uint64    `json:"streamcachesize"`
 }
 
+// DownloadCacheStats reports the usage of the renter's download cache.
+type DownloadCacheStats struct {
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+	Entries uint64 `json:"entries"`
+	Size    int64  `json:"size"`
+	MaxSize int64  `json:"maxsize"`
+}
+
 // HostDBScans represents a sortable slice of scans.
 type HostDBScans []HostDBScan
 
@@ -298,6 +331,46 @@ type ContractorSpending struct {
 	ContractSpendingDeprecated types.Currency `json:"contractspending"`
 }
 
+// ContractSpending describes how the funds of a single contract were spent.
+type ContractSpending struct {
+	ID            types.FileContractID `json:"id"`
+	HostPublicKey types.SiaPublicKey   `json:"hostpublickey"`
+	StartHeight   types.BlockHeight    `json:"startheight"`
+	EndHeight     types.BlockHeight    `json:"endheight"`
+
+	// ContractFees is the sum of the ContractFee and TxnFee of the contract.
+	// The siafund fee is reported separately in SiafundFees.
+	ContractFees     types.Currency `json:"contractfees"`
+	DownloadSpending types.Currency `json:"downloadspending"`
+	SiafundFees      types.Currency `json:"siafundfees"`
+	StorageSpending  types.Currency `json:"storagespending"`
+	UploadSpending   types.Currency `json:"uploadspending"`
+
+	// RenterFunds is the amount remaining in the contract that the renter
+	// can spend.
+	RenterFunds types.Currency `json:"renterfunds"`
+}
+
+// SpendingReport breaks down the spending of the renter during the current
+// billing period, both in total and per contract.
+type SpendingReport struct {
+	// ContractFees is the sum of the contract and transaction fees of all
+	// contracts. The siafund fees are reported separately in SiafundFees.
+	ContractFees     types.Currency `json:"contractfees"`
+	DownloadSpending types.Currency `json:"downloadspending"`
+	SiafundFees      types.Currency `json:"siafundfees"`
+	StorageSpending  types.Currency `json:"storagespending"`
+	UploadSpending   types.Currency `json:"uploadspending"`
+
+	// Unspent is the amount of the allowance that has not been spent on
+	// fees, storage, or bandwidth.
+	Unspent types.Currency `json:"unspent"`
+
+	// Contracts contains the spending of each contract of the current
+	// period, including contracts that have since been renewed.
+	Contracts []ContractSpending `json:"contracts"`
+}
+
 // A Renter uploads, tracks, repairs, and downloads a set of files for the
 // user.
 type Renter interface {
@@ -325,9 +398,45 @@ type Renter interface {
 	// billing period.
 	PeriodSpending() ContractorSpending
 
-	// DeleteFile deletes a file entry from the renter.
+	// AllowanceStatus returns the amount of the allowance spent during the
+	// current billing period, the amount remaining, and the height at which
+	// the period ends. Unspent funds carry over into the next period, where
+	// they are used to renew the renter's contracts.
+	AllowanceStatus() (spent, remaining types.Currency, periodEnd types.BlockHeight, err error)
+
+	// HostFailures returns the number of consecutive failed interactions with
+	// a host, and whether the host has been blacklisted. Blacklisted hosts are
+	// not used for new contracts, and their data is migrated to other hosts.
+	// Failures are forgotten after a period without further failures.
+	HostFailures(types.SiaPublicKey) (count int, blacklisted bool)
+
+	// ClearHostBlacklist removes every host from the blacklist.
+	ClearHostBlacklist() error
+
+	// SpendingBreakdown returns the spending of the current billing period,
+	// broken down by category and by contract.
+	SpendingBreakdown() (SpendingReport, error)
+
+	// CreateDir creates an empty directory. Directories that contain files
+	// exist without being created.
+	CreateDir(siapath string) error
+
+	// DeleteDir deletes a directory and every file in it, including the
+	// files in its subdirectories.
+	DeleteDir(siapath string) error
+
+	// DeleteFile deletes a file entry from the renter. Versions of the file
+	// are kept until they are purged.
 	DeleteFile(path string) error
 
+	// Dir returns the file count and total size of a directory, and lists
+	// its subdirectories. The empty siapath refers to the root directory.
+	Dir(siapath string) (DirectoryInfo, error)
+
+	// DirFileList returns information on all of the files in a directory
+	// and its subdirectories.
+	DirFileList(siapath string) ([]FileInfo, error)
+
 	// Download performs a download according to the parameters passed, including
 	// downloads of `offset` and `length` type.
 	Download(params RenterDownloadParameters) error
@@ -336,12 +445,20 @@ type Renter interface {
 	// blocking, including downloads of `offset` and `length` type.
 	DownloadAsync(params RenterDownloadParameters) error
 
+	// DownloadCacheStats returns the hit and miss counts and the size of the
+	// download cache.
+	DownloadCacheStats() DownloadCacheStats
+
 	// DownloadHistory lists all the files that have been scheduled for download.
 	DownloadHistory() []DownloadInfo
 
 	// File returns information on specific file queried by user
 	File(siaPath string) (FileInfo, error)
 
+	// FileHealth returns the redundancy of a file, counting only the pieces
+	// that are stored on reachable hosts.
+	FileHealth(siaPath string) (float64, error)
+
 	// FileList returns information on all of the files stored by the renter.
 	FileList() []FileInfo
 
@@ -352,6 +469,9 @@ type Renter interface {
 	// hostdb is completed.
 	InitialScanComplete() (bool, error)
 
+	// ListVersions returns the versions of a file, oldest first.
+	ListVersions(siapath string) ([]FileVersion, error)
+
 	// LoadSharedFiles loads a '.sia' file into the renter. A .sia file may
 	// contain multiple files. The paths of the added files are returned.
 	LoadSharedFiles(source string) ([]string, error)
@@ -364,9 +484,20 @@ type Renter interface {
 	// storage and data operations.
 	PriceEstimation() RenterPriceEstimation
 
+	// PurgeVersions removes all versions of a file.
+	PurgeVersions(siapath string) error
+
+	// RenameDir changes the path of a directory, moving all of the files in
+	// it. Either every file is moved or none are.
+	RenameDir(siapath, newSiapath string) error
+
 	// RenameFile changes the path of a file.
 	RenameFile(path, newPath string) error
 
+	// RestoreVersion replaces a file with one of its versions, recreating
+	// the file if it has been deleted.
+	RestoreVersion(siapath string, versionID string) error
+
 	// EstimateHostScore will return the score for a host with the provided
 	// settings, assuming perfect age and uptime adjustments
 	EstimateHostScore(entry HostDBEntry) HostScoreBreakdown
@@ -378,6 +509,15 @@ type Renter interface {
 	// Settings returns the Renter's current settings.
 	Settings() RenterSettings
 
+	// SetDownloadCacheSize sets the maximum number of bytes of recently
+	// downloaded files that are kept on disk. A size of 0 disables the cache.
+	SetDownloadCacheSize(bytes int64) error
+
+	// SetDownloadConcurrency sets the number of chunks that are downloaded
+	// in parallel. A value of 0 lets the renter tune the number based on
+	// the measured download throughput.
+	SetDownloadConcurrency(chunks int) error
+
 	// SetSettings sets the Renter's settings.
 	SetSettings(RenterSettings) error
 
@@ -387,6 +527,10 @@ type Renter interface {
 	// ShareFilesAscii creates an ASCII-encoded '.sia' file.
 	ShareFilesASCII(paths []string) (asciiSia string, err error)
 
+	// SnapshotFile records the current chunk set of a file as an immutable
+	// version, returning the id of the version.
+	SnapshotFile(siapath string) (versionID string, err error)
+
 	// Streamer creates a io.ReadSeeker that can be used to stream downloads
 	// from the Sia network and also returns the fileName of the streamed
 	// resource.
@@ -57,6 +57,11 @@ const (
 	// last updated
 	persistVersion = "1.3.3"
 
+	// criticalRedundancy is the redundancy at or below which a file is
+	// flagged as critical, as losing any further pieces of its least
+	// redundant chunk makes the file unrecoverable.
+	criticalRedundancy = 1.0
+
 	// defaultFilePerm defines the default permissions used for a new file if no
 	// permissions are supplied.
 	defaultFilePerm = 0666
@@ -98,6 +103,23 @@ var (
 		Testing:  1 * time.Minute,
 	}).(time.Duration)
 
+	// downloadConcurrencyTuneInterval is the amount of time over which the
+	// download throughput is measured before the auto-tuned download
+	// concurrency is adjusted.
+	downloadConcurrencyTuneInterval = build.Select(build.Var{
+		Dev:      10 * time.Second,
+		Standard: 30 * time.Second,
+		Testing:  time.Second,
+	}).(time.Duration)
+
+	// initialDownloadConcurrency is the number of chunks that are downloaded
+	// in parallel when auto-tuning starts.
+	initialDownloadConcurrency = build.Select(build.Var{
+		Dev:      4,
+		Standard: 10,
+		Testing:  4,
+	}).(int)
+
 	// maxConsecutivePenalty determines how many times the timeout/cooldown for
 	// being a bad host can be doubled before a maximum cooldown is reached.
 	maxConsecutivePenalty = build.Select(build.Var{
@@ -106,6 +128,14 @@ var (
 		Testing:  3,
 	}).(int)
 
+	// maxDownloadConcurrency is the largest number of chunks that the
+	// auto-tuner will download in parallel.
+	maxDownloadConcurrency = build.Select(build.Var{
+		Dev:      20,
+		Standard: 50,
+		Testing:  10,
+	}).(int)
+
 	// maxScheduledDownloads specifies the number of chunks that can be downloaded
 	// for auto repair at once. If the limit is reached new ones will only be scheduled
 	// once old ones are scheduled for upload
@@ -146,6 +176,16 @@ var (
 		Testing:  time.Second,
 	}).(time.Duration)
 
+	// slowWorkerThroughputDivisor defines which workers are considered slow
+	// for a chunk. A worker whose measured throughput is less than the
+	// throughput of the fastest worker for the chunk divided by this number is
+	// put on standby until the faster workers prove insufficient.
+	slowWorkerThroughputDivisor = build.Select(build.Var{
+		Dev:      4,
+		Standard: 4,
+		Testing:  4,
+	}).(int)
+
 	// workerPoolUpdateTimeout is the amount of time that can pass before the
 	// worker pool should be updated.
 	workerPoolUpdateTimeout = build.Select(build.Var{
@@ -5,10 +5,12 @@ import (
 	"reflect"
 
 	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
 )
 
 var (
 	errAllowanceNoHosts    = errors.New("hosts must be non-zero")
+	errAllowanceNotSet     = errors.New("no allowance has been set")
 	errAllowanceNotSynced  = errors.New("you must be synced to set an allowance")
 	errAllowanceWindowSize = errors.New("renew window must be less than period")
 	errAllowanceZeroPeriod = errors.New("period must be non-zero")
@@ -159,3 +161,29 @@ func (c *Contractor) managedCancelAllowance() error {
 	}
 	return nil
 }
+
+// periodEnd returns the height at which the current billing period ends. At
+// that height the contracts of the period are renewed into the next period,
+// and the funds that were not spent carry over. The caller must hold c.mu.
+func (c *Contractor) periodEnd() types.BlockHeight {
+	return c.currentPeriod + c.allowance.Period - c.allowance.RenewWindow
+}
+
+// AllowanceStatus returns the amount of the allowance that has been spent
+// during the current billing period, the amount that remains, and the height
+// at which the period ends. Unspent funds are not lost at the end of a period;
+// they are used to renew the contracts for the next period.
+func (c *Contractor) AllowanceStatus() (spent, remaining types.Currency, periodEnd types.BlockHeight, err error) {
+	if err := c.tg.Add(); err != nil {
+		return types.ZeroCurrency, types.ZeroCurrency, 0, err
+	}
+	defer c.tg.Done()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if reflect.DeepEqual(c.allowance, modules.Allowance{}) {
+		return types.ZeroCurrency, types.ZeroCurrency, 0, errAllowanceNotSet
+	}
+	spending := c.periodSpending()
+	spent = spending.ContractFees.Add(spending.DownloadSpending).Add(spending.UploadSpending).Add(spending.StorageSpending)
+	return spent, spending.Unspent, c.periodEnd(), nil
+}
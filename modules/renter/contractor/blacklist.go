@@ -0,0 +1,104 @@
+package contractor
+
+import (
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// hostFailure tracks the consecutive failed interactions with a host.
+type hostFailure struct {
+	Failures    int               `json:"failures"`
+	LastFailure types.BlockHeight `json:"lastfailure"`
+}
+
+// blacklisted returns whether the failures have reached the blacklist
+// threshold.
+func (hf hostFailure) blacklisted() bool {
+	return hf.Failures >= hostBlacklistThreshold
+}
+
+// readlockHostFailure returns the failures of a host, ignoring failures that
+// have decayed.
+func (c *Contractor) readlockHostFailure(pk types.SiaPublicKey) hostFailure {
+	hf, exists := c.hostFailures[pk.String()]
+	if !exists || c.blockHeight >= hf.LastFailure+hostFailureDecay {
+		return hostFailure{}
+	}
+	return hf
+}
+
+// pruneHostFailures removes the failures that have decayed. The caller must
+// hold c.mu.
+func (c *Contractor) pruneHostFailures() {
+	for key, hf := range c.hostFailures {
+		if c.blockHeight >= hf.LastFailure+hostFailureDecay {
+			delete(c.hostFailures, key)
+		}
+	}
+}
+
+// readlockBlacklistedHosts returns the keys of the hosts that are
+// blacklisted.
+func (c *Contractor) readlockBlacklistedHosts() []types.SiaPublicKey {
+	var hosts []types.SiaPublicKey
+	for key, hf := range c.hostFailures {
+		if !hf.blacklisted() || c.blockHeight >= hf.LastFailure+hostFailureDecay {
+			continue
+		}
+		var pk types.SiaPublicKey
+		pk.LoadString(key)
+		hosts = append(hosts, pk)
+	}
+	return hosts
+}
+
+// RecordHostFailure records a failed interaction with a host. A host that
+// fails hostBlacklistThreshold times in a row is blacklisted: the contractor
+// stops forming contracts with it, and its contracts are marked as !GoodForUpload
+// and !GoodForRenew so that its data is migrated to other hosts.
+func (c *Contractor) RecordHostFailure(pk types.SiaPublicKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hf := c.readlockHostFailure(pk)
+	hf.Failures++
+	hf.LastFailure = c.blockHeight
+	c.hostFailures[pk.String()] = hf
+	if hf.Failures == hostBlacklistThreshold {
+		c.log.Printf("INFO: blacklisting host %v after %v consecutive failures", pk.String(), hf.Failures)
+		if err := c.save(); err != nil {
+			c.log.Println("Unable to save the host blacklist:", err)
+		}
+	}
+}
+
+// RecordHostSuccess records a successful interaction with a host, resetting
+// its consecutive failures. A host that is already blacklisted stays
+// blacklisted until its failures decay.
+func (c *Contractor) RecordHostSuccess(pk types.SiaPublicKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if hf, exists := c.hostFailures[pk.String()]; exists && !hf.blacklisted() {
+		delete(c.hostFailures, pk.String())
+	}
+}
+
+// HostFailures returns the number of consecutive failed interactions with a
+// host, and whether the host is blacklisted.
+func (c *Contractor) HostFailures(pk types.SiaPublicKey) (count int, blacklisted bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	hf := c.readlockHostFailure(pk)
+	return hf.Failures, hf.blacklisted()
+}
+
+// ClearHostBlacklist forgets the failures of all hosts, removing every host
+// from the blacklist.
+func (c *Contractor) ClearHostBlacklist() error {
+	if err := c.tg.Add(); err != nil {
+		return err
+	}
+	defer c.tg.Done()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hostFailures = make(map[string]hostFailure)
+	return c.save()
+}
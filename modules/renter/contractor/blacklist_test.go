@@ -0,0 +1,80 @@
+package contractor
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/persist"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestHostBlacklist tests that hosts are blacklisted after repeated failures,
+// and that the blacklist decays, persists, and can be cleared.
+func TestHostBlacklist(t *testing.T) {
+	c := &Contractor{
+		hostFailures: make(map[string]hostFailure),
+		log:          persist.NewLogger(ioutil.Discard),
+		persist:      new(memPersist),
+	}
+	host := types.SiaPublicKey{Key: []byte("foo")}
+	other := types.SiaPublicKey{Key: []byte("bar")}
+
+	// A success resets the consecutive failures.
+	for i := 0; i < hostBlacklistThreshold-1; i++ {
+		c.RecordHostFailure(host)
+	}
+	if count, blacklisted := c.HostFailures(host); count != hostBlacklistThreshold-1 || blacklisted {
+		t.Fatal("unexpected failures:", count, blacklisted)
+	}
+	c.RecordHostSuccess(host)
+	if count, _ := c.HostFailures(host); count != 0 {
+		t.Fatal("success did not reset the failures:", count)
+	}
+
+	// Reaching the threshold blacklists the host, and a success does not
+	// remove it from the blacklist.
+	for i := 0; i < hostBlacklistThreshold; i++ {
+		c.RecordHostFailure(host)
+	}
+	c.RecordHostFailure(other)
+	c.RecordHostSuccess(host)
+	if count, blacklisted := c.HostFailures(host); count != hostBlacklistThreshold || !blacklisted {
+		t.Fatal("host was not blacklisted:", count, blacklisted)
+	}
+	if hosts := c.readlockBlacklistedHosts(); len(hosts) != 1 || hosts[0].String() != host.String() {
+		t.Fatal("wrong blacklisted hosts:", hosts)
+	}
+
+	// The blacklist persists.
+	if err := c.save(); err != nil {
+		t.Fatal(err)
+	}
+	c.hostFailures = make(map[string]hostFailure)
+	if err := c.load(); err != nil {
+		t.Fatal(err)
+	}
+	if _, blacklisted := c.HostFailures(host); !blacklisted {
+		t.Fatal("blacklist was not restored")
+	}
+
+	// The failures decay.
+	c.blockHeight += hostFailureDecay
+	if count, blacklisted := c.HostFailures(host); count != 0 || blacklisted {
+		t.Fatal("failures did not decay:", count, blacklisted)
+	}
+	c.pruneHostFailures()
+	if len(c.hostFailures) != 0 {
+		t.Fatal("decayed failures were not pruned:", c.hostFailures)
+	}
+
+	// Clearing the blacklist forgets all failures.
+	for i := 0; i < hostBlacklistThreshold; i++ {
+		c.RecordHostFailure(host)
+	}
+	if err := c.ClearHostBlacklist(); err != nil {
+		t.Fatal(err)
+	}
+	if count, blacklisted := c.HostFailures(host); count != 0 || blacklisted {
+		t.Fatal("blacklist was not cleared:", count, blacklisted)
+	}
+}
@@ -45,3 +45,23 @@ var (
 	// host is allowed to have before being marked as !GoodForUpload.
 	scoreLeeway = types.NewCurrency64(100)
 )
+
+// Constants related to blacklisting hosts that fail repeatedly.
+var (
+	// hostBlacklistThreshold is the number of consecutive failed interactions
+	// after which a host is blacklisted.
+	hostBlacklistThreshold = build.Select(build.Var{
+		Dev:      5,
+		Standard: 10,
+		Testing:  3,
+	}).(int)
+
+	// hostFailureDecay is the number of blocks after the last failure at
+	// which a host's failures are forgotten, allowing a host that has
+	// recovered to be used again.
+	hostFailureDecay = build.Select(build.Var{
+		Dev:      types.BlockHeight(100),
+		Standard: types.BlockHeight(1008), // 1 week
+		Testing:  types.BlockHeight(10),
+	}).(types.BlockHeight)
+)
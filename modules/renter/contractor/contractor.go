@@ -64,6 +64,10 @@ type Contractor struct {
 	staticContracts *proto.ContractSet
 	oldContracts    map[types.FileContractID]modules.RenterContract
 	renewedIDs      map[types.FileContractID]types.FileContractID
+
+	// hostFailures tracks the consecutive failed interactions with each host,
+	// keyed by the string form of the host's public key.
+	hostFailures map[string]hostFailure
 }
 
 // readlockResolveID returns the ID of the most recent renewal of id.
@@ -88,7 +92,12 @@ func (c *Contractor) Allowance() modules.Allowance {
 func (c *Contractor) PeriodSpending() modules.ContractorSpending {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
+	return c.periodSpending()
+}
 
+// periodSpending returns the amount spent on contracts during the current
+// billing period. The caller must hold c.mu.
+func (c *Contractor) periodSpending() modules.ContractorSpending {
 	var spending modules.ContractorSpending
 	for _, contract := range c.staticContracts.ViewAll() {
 		// Calculate ContractFees
@@ -131,6 +140,57 @@ func (c *Contractor) PeriodSpending() modules.ContractorSpending {
 	return spending
 }
 
+// SpendingBreakdown returns the spending of the current billing period,
+// broken down by category and by contract. Contracts that were renewed during
+// the current period are included.
+func (c *Contractor) SpendingBreakdown() (modules.SpendingReport, error) {
+	if err := c.tg.Add(); err != nil {
+		return modules.SpendingReport{}, err
+	}
+	defer c.tg.Done()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var report modules.SpendingReport
+	addContract := func(contract modules.RenterContract) {
+		cs := modules.ContractSpending{
+			ID:               contract.ID,
+			HostPublicKey:    contract.HostPublicKey,
+			StartHeight:      contract.StartHeight,
+			EndHeight:        contract.EndHeight,
+			ContractFees:     contract.ContractFee.Add(contract.TxnFee),
+			DownloadSpending: contract.DownloadSpending,
+			SiafundFees:      contract.SiafundFee,
+			StorageSpending:  contract.StorageSpending,
+			UploadSpending:   contract.UploadSpending,
+			RenterFunds:      contract.RenterFunds,
+		}
+		report.ContractFees = report.ContractFees.Add(cs.ContractFees)
+		report.DownloadSpending = report.DownloadSpending.Add(cs.DownloadSpending)
+		report.SiafundFees = report.SiafundFees.Add(cs.SiafundFees)
+		report.StorageSpending = report.StorageSpending.Add(cs.StorageSpending)
+		report.UploadSpending = report.UploadSpending.Add(cs.UploadSpending)
+		report.Contracts = append(report.Contracts, cs)
+	}
+	for _, contract := range c.staticContracts.ViewAll() {
+		addContract(contract)
+	}
+	for _, old := range c.oldContracts {
+		if old.StartHeight >= c.currentPeriod {
+			addContract(old)
+		}
+	}
+
+	allSpending := report.ContractFees.Add(report.SiafundFees)
+	allSpending = allSpending.Add(report.DownloadSpending)
+	allSpending = allSpending.Add(report.UploadSpending)
+	allSpending = allSpending.Add(report.StorageSpending)
+	if c.allowance.Funds.Cmp(allSpending) >= 0 {
+		report.Unspent = c.allowance.Funds.Sub(allSpending)
+	}
+	return report, nil
+}
+
 // ContractByID returns the contract with the id specified, if it exists. The
 // contract will be resolved if possible to the most recent child contract.
 func (c *Contractor) ContractByID(id types.FileContractID) (modules.RenterContract, bool) {
@@ -240,6 +300,7 @@ func NewCustomContractor(cs consensusSet, w wallet, tp transactionPool, hdb host
 		staticContracts: contractSet,
 		downloaders:     make(map[types.FileContractID]*hostDownloader),
 		editors:         make(map[types.FileContractID]*hostEditor),
+		hostFailures:    make(map[string]hostFailure),
 		oldContracts:    make(map[types.FileContractID]modules.RenterContract),
 		renewedIDs:      make(map[types.FileContractID]types.FileContractID),
 		renewing:        make(map[types.FileContractID]bool),
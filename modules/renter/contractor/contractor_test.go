@@ -348,6 +348,43 @@ func TestAllowanceSpending(t *testing.T) {
 			expectedFees.HumanString(), reportedSpending.ContractFees.HumanString())
 	}
 
+	// The spending breakdown should agree with PeriodSpending and with the
+	// sum of its contracts.
+	report, err := c.SpendingBreakdown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.ContractFees.Add(report.SiafundFees).Cmp(reportedSpending.ContractFees) != 0 {
+		t.Fatal("breakdown fees do not match period spending")
+	}
+	if report.UploadSpending.Cmp(reportedSpending.UploadSpending) != 0 || report.StorageSpending.Cmp(reportedSpending.StorageSpending) != 0 ||
+		report.DownloadSpending.Cmp(reportedSpending.DownloadSpending) != 0 || report.Unspent.Cmp(reportedSpending.Unspent) != 0 {
+		t.Fatal("breakdown does not match period spending")
+	}
+	var contractUpload types.Currency
+	for _, cs := range report.Contracts {
+		contractUpload = contractUpload.Add(cs.UploadSpending)
+	}
+	if contractUpload.Cmp(report.UploadSpending) != 0 {
+		t.Fatal("per-contract upload spending does not add up to the total")
+	}
+
+	// The allowance status should agree with PeriodSpending.
+	spent, remaining, periodEnd, err := c.AllowanceStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedSpent := reportedSpending.ContractFees.Add(reportedSpending.DownloadSpending).Add(reportedSpending.UploadSpending).Add(reportedSpending.StorageSpending)
+	if spent.Cmp(expectedSpent) != 0 || remaining.Cmp(reportedSpending.Unspent) != 0 {
+		t.Fatalf("allowance status %v spent, %v remaining does not match period spending", spent.HumanString(), remaining.HumanString())
+	}
+	c.mu.RLock()
+	expectedEnd := c.currentPeriod + c.allowance.Period - c.allowance.RenewWindow
+	c.mu.RUnlock()
+	if periodEnd != expectedEnd {
+		t.Fatalf("expected period to end at %v, got %v", expectedEnd, periodEnd)
+	}
+
 	// enter a new period. PeriodSpending should reset.
 	c.mu.Lock()
 	renewHeight := c.blockHeight + c.allowance.RenewWindow
@@ -125,6 +125,15 @@ func (c *Contractor) managedMarkContractsUtility() error {
 				u.GoodForRenew = false
 				return
 			}
+			// Contract has no utility if the host is blacklisted.
+			c.mu.RLock()
+			blacklisted := c.readlockHostFailure(contract.HostPublicKey).blacklisted()
+			c.mu.RUnlock()
+			if blacklisted {
+				u.GoodForUpload = false
+				u.GoodForRenew = false
+				return
+			}
 			// Contract has no utility if renew has already completed. (grab some
 			// extra values while we have the mutex)
 			c.mu.RLock()
@@ -630,13 +639,14 @@ func (c *Contractor) threadedContractMaintenance() {
 	}
 
 	// Assemble an exclusion list that includes all of the hosts that we already
-	// have contracts with, then select a new batch of hosts to attempt contract
-	// formation with.
+	// have contracts with and the blacklisted hosts, then select a new batch of
+	// hosts to attempt contract formation with.
 	c.mu.RLock()
 	var exclude []types.SiaPublicKey
 	for _, contract := range c.staticContracts.ViewAll() {
 		exclude = append(exclude, contract.HostPublicKey)
 	}
+	exclude = append(exclude, c.readlockBlacklistedHosts()...)
 	initialContractFunds := c.allowance.Funds.Div64(c.allowance.Hosts).Div64(3)
 	c.mu.RUnlock()
 	hosts, err := c.hdb.RandomHosts(neededContracts*2+randomHostsBufferForScore, exclude)
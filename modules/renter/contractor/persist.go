@@ -21,6 +21,7 @@ type contractorPersist struct {
 	LastChange    modules.ConsensusChangeID `json:"lastchange"`
 	OldContracts  []modules.RenterContract  `json:"oldcontracts"`
 	RenewedIDs    map[string]string         `json:"renewedids"`
+	HostFailures  map[string]hostFailure    `json:"hostfailures"`
 }
 
 // persistData returns the data in the Contractor that will be saved to disk.
@@ -31,6 +32,7 @@ func (c *Contractor) persistData() contractorPersist {
 		CurrentPeriod: c.currentPeriod,
 		LastChange:    c.lastChange,
 		RenewedIDs:    make(map[string]string),
+		HostFailures:  make(map[string]hostFailure),
 	}
 	for _, contract := range c.oldContracts {
 		data.OldContracts = append(data.OldContracts, contract)
@@ -38,6 +40,9 @@ func (c *Contractor) persistData() contractorPersist {
 	for oldID, newID := range c.renewedIDs {
 		data.RenewedIDs[oldID.String()] = newID.String()
 	}
+	for key, hf := range c.hostFailures {
+		data.HostFailures[key] = hf
+	}
 	return data
 }
 
@@ -61,6 +66,9 @@ func (c *Contractor) load() error {
 		newHash.LoadString(newString)
 		c.renewedIDs[types.FileContractID(oldHash)] = types.FileContractID(newHash)
 	}
+	for key, hf := range data.HostFailures {
+		c.hostFailures[key] = hf
+	}
 
 	return nil
 }
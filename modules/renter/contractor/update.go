@@ -61,6 +61,9 @@ func (c *Contractor) ProcessConsensusChange(cc modules.ConsensusChange) {
 	// TODO: How to make this more explicit.
 	cycleLen := c.allowance.Period - c.allowance.RenewWindow
 	if c.blockHeight >= c.currentPeriod+cycleLen {
+		// Log the funds that carry over into the next period. They are used
+		// to renew the contracts of the period.
+		c.log.Printf("Entering new period at height %v, %v of the allowance was unspent", c.currentPeriod+cycleLen, c.periodSpending().Unspent.HumanString())
 		c.currentPeriod += cycleLen
 		// COMPATv1.0.4-lts
 		// if we were storing a special metrics contract, it will be invalid
@@ -68,6 +71,7 @@ func (c *Contractor) ProcessConsensusChange(cc modules.ConsensusChange) {
 		delete(c.oldContracts, metricsContractID)
 	}
 
+	c.pruneHostFailures()
 	c.lastChange = cc.ID
 	err := c.save()
 	if err != nil {
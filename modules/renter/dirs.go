@@ -0,0 +1,303 @@
+package renter
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/persist"
+)
+
+var (
+	// ErrDirOverload is an error when a directory already exists at that
+	// location
+	ErrDirOverload = errors.New("a directory already exists at that location")
+	// ErrUnknownDir is an error when a directory cannot be found with the
+	// given path
+	ErrUnknownDir = errors.New("no directory known with that path")
+
+	errRenameDirIntoItself = errors.New("cannot move a directory into itself")
+)
+
+// dirPrefix returns the prefix shared by the siapaths of everything in the
+// directory. The root directory is the empty siapath.
+func dirPrefix(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	return dir + "/"
+}
+
+// validateDirpath checks that a siapath is a legal directory name. Trailing
+// slashes are removed.
+func validateDirpath(siapath string) (string, error) {
+	siapath = strings.TrimRight(siapath, "/")
+	return siapath, validateSiapath(siapath)
+}
+
+// dirExists returns whether a directory was created explicitly or contains any
+// files.
+func (r *Renter) dirExists(dir string) bool {
+	if _, exists := r.persist.Directories[dir]; exists {
+		return true
+	}
+	prefix := dirPrefix(dir)
+	for d := range r.persist.Directories {
+		if strings.HasPrefix(d, prefix) {
+			return true
+		}
+	}
+	for name := range r.files {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// dirFiles returns the files in a directory and its subdirectories.
+func (r *Renter) dirFiles(dir string) []*file {
+	prefix := dirPrefix(dir)
+	var files []*file
+	for name, f := range r.files {
+		if strings.HasPrefix(name, prefix) {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// CreateDir creates an empty directory. Creating a directory is only needed
+// to keep it while it holds no files.
+func (r *Renter) CreateDir(siapath string) error {
+	siapath, err := validateDirpath(siapath)
+	if err != nil {
+		return err
+	}
+
+	lockID := r.mu.Lock()
+	defer r.mu.Unlock(lockID)
+	if _, exists := r.files[siapath]; exists {
+		return ErrPathOverload
+	}
+	if r.dirExists(siapath) {
+		return ErrDirOverload
+	}
+	r.persist.Directories[siapath] = struct{}{}
+	return r.saveSync()
+}
+
+// Dir returns the number of files in a directory and its subdirectories, their
+// total size, and the immediate subdirectories of the directory.
+func (r *Renter) Dir(siapath string) (modules.DirectoryInfo, error) {
+	siapath = strings.TrimRight(siapath, "/")
+	if siapath != "" {
+		if err := validateSiapath(siapath); err != nil {
+			return modules.DirectoryInfo{}, err
+		}
+	}
+
+	lockID := r.mu.RLock()
+	defer r.mu.RUnlock(lockID)
+	if siapath != "" && !r.dirExists(siapath) {
+		return modules.DirectoryInfo{}, ErrUnknownDir
+	}
+
+	di := modules.DirectoryInfo{
+		SiaPath: siapath,
+		Subdirs: []string{},
+	}
+	prefix := dirPrefix(siapath)
+	subdirs := make(map[string]struct{})
+	addSubdir := func(name string) {
+		rest := strings.TrimPrefix(name, prefix)
+		if i := strings.Index(rest, "/"); i >= 0 {
+			subdirs[prefix+rest[:i]] = struct{}{}
+		}
+	}
+	for _, f := range r.dirFiles(siapath) {
+		di.Files++
+		di.Size += f.size
+		addSubdir(f.name)
+	}
+	for d := range r.persist.Directories {
+		if strings.HasPrefix(d, prefix) {
+			// Add a trailing slash so that the directory itself is listed
+			// as well as the directories that lead to it.
+			addSubdir(d + "/")
+		}
+	}
+	for d := range subdirs {
+		di.Subdirs = append(di.Subdirs, d)
+	}
+	sort.Strings(di.Subdirs)
+	return di, nil
+}
+
+// DirFileList returns information on all of the files in a directory and its
+// subdirectories.
+func (r *Renter) DirFileList(siapath string) ([]modules.FileInfo, error) {
+	siapath, err := validateDirpath(siapath)
+	if err != nil {
+		return nil, err
+	}
+	lockID := r.mu.RLock()
+	exists := r.dirExists(siapath)
+	r.mu.RUnlock(lockID)
+	if !exists {
+		return nil, ErrUnknownDir
+	}
+
+	prefix := dirPrefix(siapath)
+	files := []modules.FileInfo{}
+	for _, fi := range r.FileList() {
+		if strings.HasPrefix(fi.SiaPath, prefix) {
+			files = append(files, fi)
+		}
+	}
+	return files, nil
+}
+
+// DeleteDir deletes a directory, all of its subdirectories, and all of the
+// files that they contain.
+func (r *Renter) DeleteDir(siapath string) error {
+	siapath, err := validateDirpath(siapath)
+	if err != nil {
+		return err
+	}
+
+	lockID := r.mu.Lock()
+	if !r.dirExists(siapath) {
+		r.mu.Unlock(lockID)
+		return ErrUnknownDir
+	}
+	var names []string
+	for _, f := range r.dirFiles(siapath) {
+		names = append(names, f.name)
+	}
+	prefix := dirPrefix(siapath)
+	for d := range r.persist.Directories {
+		if d == siapath || strings.HasPrefix(d, prefix) {
+			delete(r.persist.Directories, d)
+		}
+	}
+	err = r.saveSync()
+	r.mu.Unlock(lockID)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		// The file may have been deleted in the meantime.
+		if err := r.DeleteFile(name); err != nil && err != ErrUnknownPath {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenameDir changes the path of a directory, moving all of the files that it
+// and its subdirectories contain. If any of the files cannot be saved under
+// its new name, all of the files are left where they were.
+func (r *Renter) RenameDir(currentName, newName string) error {
+	currentName, err := validateDirpath(currentName)
+	if err != nil {
+		return err
+	}
+	newName, err = validateDirpath(newName)
+	if err != nil {
+		return err
+	}
+	if newName == currentName || strings.HasPrefix(newName, dirPrefix(currentName)) {
+		return errRenameDirIntoItself
+	}
+
+	lockID := r.mu.Lock()
+	defer r.mu.Unlock(lockID)
+
+	// Check that currentName exists and newName doesn't.
+	if !r.dirExists(currentName) {
+		return ErrUnknownDir
+	}
+	if _, exists := r.files[newName]; exists {
+		return ErrPathOverload
+	}
+	if r.dirExists(newName) {
+		return ErrDirOverload
+	}
+
+	// Save every file under its new name. If any of them fails, restore the
+	// files that were already moved.
+	files := r.dirFiles(currentName)
+	oldNames := make([]string, len(files))
+	for i, f := range files {
+		f.mu.Lock()
+		oldNames[i] = f.name
+		f.name = newName + strings.TrimPrefix(f.name, currentName)
+		err = r.saveFile(f)
+		f.mu.Unlock()
+		if err != nil {
+			for j := i; j >= 0; j-- {
+				files[j].mu.Lock()
+				newPath := filepath.Join(r.persistDir, files[j].name+ShareExtension)
+				files[j].name = oldNames[j]
+				files[j].mu.Unlock()
+				if j < i {
+					persist.RemoveFile(newPath)
+				}
+			}
+			return err
+		}
+	}
+
+	// Update the entries in the renter.
+	versionsChanged := false
+	for i, f := range files {
+		oldName := oldNames[i]
+		delete(r.files, oldName)
+		r.files[f.name] = f
+		if err := r.staticDownloadCache.managedInvalidate(oldName); err != nil {
+			r.log.Println("WARN: couldn't remove file from download cache:", err)
+		}
+		if t, ok := r.persist.Tracking[oldName]; ok {
+			delete(r.persist.Tracking, oldName)
+			r.persist.Tracking[f.name] = t
+		}
+		if versions, ok := r.versions[oldName]; ok {
+			delete(r.versions, oldName)
+			r.versions[f.name] = append(r.versions[f.name], versions...)
+			versionsChanged = true
+		}
+	}
+	var dirs []string
+	prefix := dirPrefix(currentName)
+	for d := range r.persist.Directories {
+		if d == currentName || strings.HasPrefix(d, prefix) {
+			dirs = append(dirs, d)
+		}
+	}
+	for _, d := range dirs {
+		delete(r.persist.Directories, d)
+		r.persist.Directories[newName+strings.TrimPrefix(d, currentName)] = struct{}{}
+	}
+	err = r.saveSync()
+	if err != nil {
+		return err
+	}
+	if versionsChanged {
+		if err := r.saveVersions(); err != nil {
+			return err
+		}
+	}
+
+	// Delete the old .sia files.
+	for _, oldName := range oldNames {
+		err := persist.RemoveFile(filepath.Join(r.persistDir, oldName+ShareExtension))
+		if err != nil {
+			r.log.Println("WARN: couldn't remove file :", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package renter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// addTestingFiles adds testing files with the given siapaths to the renter.
+func (rt *renterTester) addTestingFiles(names ...string) {
+	id := rt.renter.mu.Lock()
+	defer rt.renter.mu.Unlock(id)
+	for _, name := range names {
+		f := newTestingFile()
+		f.name = name
+		rt.renter.files[name] = f
+	}
+}
+
+// TestRenterDirs probes the CreateDir, Dir and DirFileList methods of the
+// renter.
+func TestRenterDirs(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	if _, err := rt.renter.Dir("dne"); err != ErrUnknownDir {
+		t.Fatal("expected ErrUnknownDir, got", err)
+	}
+	if _, err := rt.renter.DirFileList("dne"); err != ErrUnknownDir {
+		t.Fatal("expected ErrUnknownDir, got", err)
+	}
+
+	rt.addTestingFiles("a/1", "a/b/2", "a/b/c/3", "ab/4", "5")
+	if err := rt.renter.CreateDir("a/empty/"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.renter.CreateDir("a/empty"); err != ErrDirOverload {
+		t.Fatal("expected ErrDirOverload, got", err)
+	}
+	if err := rt.renter.CreateDir("a/b"); err != ErrDirOverload {
+		t.Fatal("expected ErrDirOverload, got", err)
+	}
+	if err := rt.renter.CreateDir("5"); err != ErrPathOverload {
+		t.Fatal("expected ErrPathOverload, got", err)
+	}
+	if err := rt.renter.CreateDir("../a"); err == nil {
+		t.Fatal("expected an invalid siapath to be rejected")
+	}
+
+	// The directory should contain the files of its subdirectories, but not
+	// the files of directories that share its prefix.
+	di, err := rt.renter.Dir("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var size uint64
+	for _, name := range []string{"a/1", "a/b/2", "a/b/c/3"} {
+		size += rt.renter.files[name].size
+	}
+	if di.Files != 3 || di.Size != size {
+		t.Fatalf("expected 3 files of %v bytes, got %v files of %v bytes", size, di.Files, di.Size)
+	}
+	if !reflect.DeepEqual(di.Subdirs, []string{"a/b", "a/empty"}) {
+		t.Fatal("wrong subdirectories:", di.Subdirs)
+	}
+	files, err := rt.renter.DirFileList("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatal("expected 3 files, got", len(files))
+	}
+
+	// The root directory should contain every file.
+	di, err = rt.renter.Dir("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if di.Files != 5 || !reflect.DeepEqual(di.Subdirs, []string{"a", "ab"}) {
+		t.Fatal("wrong root directory:", di)
+	}
+
+	// An explicitly created directory should be empty and survive a restart.
+	di, err = rt.renter.Dir("a/empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if di.Files != 0 || di.Size != 0 || len(di.Subdirs) != 0 {
+		t.Fatal("expected an empty directory, got", di)
+	}
+	id := rt.renter.mu.Lock()
+	err = rt.renter.loadSettings()
+	rt.renter.mu.Unlock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rt.renter.Dir("a/empty"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRenterRenameDir probes the RenameDir method of the renter.
+func TestRenterRenameDir(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	if err := rt.renter.RenameDir("dne", "new"); err != ErrUnknownDir {
+		t.Fatal("expected ErrUnknownDir, got", err)
+	}
+
+	rt.addTestingFiles("a/1", "a/b/2", "ab/3", "c/4")
+	id := rt.renter.mu.Lock()
+	for _, f := range rt.renter.files {
+		if err := rt.renter.saveFile(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rt.renter.persist.Tracking["a/b/2"] = trackedFile{"foo"}
+	rt.renter.mu.Unlock(id)
+	if err := rt.renter.CreateDir("a/empty"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rt.renter.RenameDir("a", "c"); err != ErrDirOverload {
+		t.Fatal("expected ErrDirOverload, got", err)
+	}
+	if err := rt.renter.RenameDir("a", "a/d"); err != errRenameDirIntoItself {
+		t.Fatal("expected errRenameDirIntoItself, got", err)
+	}
+	if err := rt.renter.RenameDir("a", "d/e"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The files, the tracking set and the explicit directories should have
+	// been moved, and the .sia files should have been moved on disk.
+	for _, name := range []string{"d/e/1", "d/e/b/2", "ab/3", "c/4"} {
+		f, exists := rt.renter.files[name]
+		if !exists || f.name != name {
+			t.Fatal("missing file", name)
+		}
+	}
+	if len(rt.renter.files) != 4 {
+		t.Fatal("expected 4 files, got", len(rt.renter.files))
+	}
+	if _, exists := rt.renter.persist.Tracking["d/e/b/2"]; !exists {
+		t.Fatal("tracking set was not updated")
+	}
+	if _, err := rt.renter.Dir("d/e/empty"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rt.renter.Dir("a"); err != ErrUnknownDir {
+		t.Fatal("expected ErrUnknownDir, got", err)
+	}
+	if _, err := os.Stat(filepath.Join(rt.renter.persistDir, "d/e/b/2"+ShareExtension)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(rt.renter.persistDir, "a/b/2"+ShareExtension)); !os.IsNotExist(err) {
+		t.Fatal("old .sia file was not removed:", err)
+	}
+}
+
+// TestRenterDeleteDir probes the DeleteDir method of the renter.
+func TestRenterDeleteDir(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	if err := rt.renter.DeleteDir("dne"); err != ErrUnknownDir {
+		t.Fatal("expected ErrUnknownDir, got", err)
+	}
+
+	rt.addTestingFiles("a/1", "a/b/2", "ab/3")
+	if err := rt.renter.CreateDir("a/b/empty"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.renter.DeleteDir("a"); err != nil {
+		t.Fatal(err)
+	}
+	files := rt.renter.FileList()
+	if len(files) != 1 || files[0].SiaPath != "ab/3" {
+		t.Fatal("expected only ab/3 to remain, got", files)
+	}
+	if _, err := rt.renter.Dir("a/b/empty"); err != ErrUnknownDir {
+		t.Fatal("expected ErrUnknownDir, got", err)
+	}
+}
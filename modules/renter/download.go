@@ -312,8 +312,7 @@ func (r *Renter) managedDownload(p modules.RenterDownloadParameters) (*download,
 		destinationType = "file"
 	}
 
-	// Create the download object.
-	d, err := r.managedNewDownload(downloadParams{
+	params := downloadParams{
 		destination:       dw,
 		destinationType:   destinationType,
 		destinationString: p.Destination,
@@ -325,10 +324,37 @@ func (r *Renter) managedDownload(p modules.RenterDownloadParameters) (*download,
 		offset:        p.Offset,
 		overdrive:     3, // TODO: moderate default until full overdrive support is added.
 		priority:      5, // TODO: moderate default until full priority support is added.
-	})
+	}
+
+	// Serve the download from the download cache if possible, otherwise
+	// create the download object.
+	d, cached, err := r.managedDownloadFromCache(file, params)
 	if err != nil {
 		return nil, err
 	}
+	if !cached {
+		// Copy downloads of complete files into the download cache.
+		var cd *downloadCacheDestination
+		if !isHTTPResp && p.Offset == 0 && p.Length == file.size {
+			cd, err = r.staticDownloadCache.managedNewDestination(params.destination, file.size)
+			if err != nil {
+				r.log.Println("WARN: unable to cache download:", err)
+			}
+			if cd != nil {
+				params.destination = cd
+			}
+		}
+		d, err = r.managedNewDownload(params)
+		if err != nil {
+			if cd != nil {
+				cd.managedDiscard()
+			}
+			return nil, err
+		}
+		if cd != nil {
+			go r.threadedAddToDownloadCache(d, file, cd)
+		}
+	}
 
 	// Add the download object to the download queue.
 	r.downloadHistoryMu.Lock()
@@ -508,6 +534,14 @@ func (r *Renter) DownloadHistory() []modules.DownloadInfo {
 			StartTime:            d.staticStartTime,
 			TotalDataTransferred: atomic.LoadUint64(&d.atomicTotalDataTransferred),
 		}
+		// Compute the average throughput of the download so far.
+		end := d.endTime
+		if end.IsZero() {
+			end = time.Now()
+		}
+		if elapsed := end.Sub(d.staticStartTime).Seconds(); elapsed > 0 {
+			downloads[i].Throughput = uint64(float64(downloads[i].TotalDataTransferred) / elapsed)
+		}
 		// Release download lock before calling d.Err(), which will acquire the
 		// lock. The error needs to be checked separately because we need to
 		// know if it's 'nil' before grabbing the error string.
@@ -0,0 +1,489 @@
+package renter
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/persist"
+	"github.com/NebulousLabs/errors"
+)
+
+const (
+	// downloadCacheDir is the name of the directory within the renter's
+	// persist directory that holds the download cache.
+	downloadCacheDir = "downloadcache"
+
+	// downloadCacheIndexFile is the name of the file that holds the index of
+	// the download cache.
+	downloadCacheIndexFile = "index.json"
+
+	// downloadCacheTempPrefix is the prefix of the temporary files that hold
+	// the data of downloads that are in progress.
+	downloadCacheTempPrefix = "download-"
+)
+
+var (
+	// downloadCacheMetadata is the header of the download cache index file.
+	downloadCacheMetadata = persist.Metadata{
+		Header:  "Renter Download Cache",
+		Version: "1.3.3",
+	}
+
+	errCacheCorrupt      = errors.New("cached data does not match its merkle root")
+	errNegativeCacheSize = errors.New("download cache size cannot be negative")
+)
+
+type (
+	// downloadCacheEntry describes a file that is stored in the download
+	// cache. The merkle root of the data is recorded when the file is added,
+	// and is used to verify the integrity of the data before it is served.
+	downloadCacheEntry struct {
+		SiaPath    string
+		Version    crypto.Hash
+		Size       int64
+		MerkleRoot crypto.Hash
+		LastAccess time.Time
+	}
+
+	// downloadCacheIndex is the persisted form of the download cache.
+	downloadCacheIndex struct {
+		Entries []downloadCacheEntry
+	}
+
+	// downloadCache is an on-disk LRU cache of recently downloaded files. The
+	// cache only holds complete files, and serves any range of a cached file.
+	// Entries are keyed by siapath and version, where the version changes
+	// every time a file is uploaded.
+	downloadCache struct {
+		entries map[string]*downloadCacheEntry
+		size    int64
+		maxSize int64
+
+		hits   uint64
+		misses uint64
+
+		dir string
+		mu  sync.Mutex
+	}
+
+	// downloadCacheDestination is a downloadDestination that copies the
+	// verified data of a download into a temporary file of the download
+	// cache while writing it to the destination chosen by the user. The copy
+	// is added to the cache once the download completes, so that the cache
+	// does not depend on what happens to the user's file afterwards.
+	downloadCacheDestination struct {
+		downloadDestination
+		file   *os.File
+		closed bool
+		err    error
+		mu     sync.Mutex
+	}
+)
+
+// WriteAt writes data to the underlying destination and to the cache file.
+// Errors writing the cache file only prevent the file from being cached.
+func (cd *downloadCacheDestination) WriteAt(data []byte, offset int64) (int, error) {
+	n, err := cd.downloadDestination.WriteAt(data, offset)
+	if err != nil {
+		return n, err
+	}
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+	if cd.err == nil && !cd.closed {
+		_, cd.err = cd.file.WriteAt(data, offset)
+	}
+	return n, nil
+}
+
+// Close closes the underlying destination and the cache file.
+func (cd *downloadCacheDestination) Close() error {
+	cd.managedCloseCacheFile()
+	return cd.downloadDestination.Close()
+}
+
+// managedCloseCacheFile closes the cache file, returning the first error that
+// occurred while writing it.
+func (cd *downloadCacheDestination) managedCloseCacheFile() error {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+	if !cd.closed {
+		cd.closed = true
+		if err := cd.file.Close(); cd.err == nil {
+			cd.err = err
+		}
+	}
+	return cd.err
+}
+
+// managedDiscard closes and deletes the cache file.
+func (cd *downloadCacheDestination) managedDiscard() {
+	cd.managedCloseCacheFile()
+	os.Remove(cd.file.Name())
+}
+
+// readerMerkleRoot returns the Merkle root and the length of the data read
+// from r, without holding all of the data in memory.
+func readerMerkleRoot(r io.Reader) (crypto.Hash, int64, error) {
+	t := crypto.NewTree()
+	buf := make([]byte, crypto.SegmentSize)
+	var size int64
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			t.Push(buf[:n])
+			size += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return t.Root(), size, nil
+		} else if err != nil {
+			return crypto.Hash{}, 0, err
+		}
+	}
+}
+
+// fileMerkleRoot returns the Merkle root and the size of a file.
+func fileMerkleRoot(path string) (crypto.Hash, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return crypto.Hash{}, 0, err
+	}
+	defer f.Close()
+	return readerMerkleRoot(f)
+}
+
+// fileVersion returns an identifier that changes each time a file is
+// uploaded to a siapath. The master key of a file is generated randomly for
+// each upload and never changes afterwards.
+func fileVersion(f *file) crypto.Hash {
+	return crypto.HashObject(f.masterKey)
+}
+
+// newDownloadCache returns a download cache that stores its data in 'dir',
+// loading any entries that were persisted by a previous instance.
+func newDownloadCache(dir string, maxSize int64) (*downloadCache, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+	dc := &downloadCache{
+		entries: make(map[string]*downloadCacheEntry),
+		maxSize: maxSize,
+		dir:     dir,
+	}
+
+	var index downloadCacheIndex
+	err = persist.LoadJSON(downloadCacheMetadata, &index, filepath.Join(dir, downloadCacheIndexFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	// Temporary files of downloads that were interrupted by a shutdown are
+	// never added to the cache.
+	tempFiles, _ := filepath.Glob(filepath.Join(dir, downloadCacheTempPrefix+"*"))
+	for _, path := range tempFiles {
+		os.Remove(path)
+	}
+	for i := range index.Entries {
+		entry := index.Entries[i]
+		dc.entries[entry.SiaPath] = &entry
+		dc.size += entry.Size
+	}
+	dc.prune(dc.maxSize)
+	return dc, nil
+}
+
+// dataPath returns the path of the file that holds the cached data for a
+// siapath.
+func (dc *downloadCache) dataPath(siapath string) string {
+	return filepath.Join(dc.dir, crypto.HashObject(siapath).String()+".dat")
+}
+
+// remove deletes an entry and its data from the cache.
+func (dc *downloadCache) remove(siapath string) {
+	entry, exists := dc.entries[siapath]
+	if !exists {
+		return
+	}
+	delete(dc.entries, siapath)
+	dc.size -= entry.Size
+	os.Remove(dc.dataPath(siapath))
+}
+
+// prune evicts the least recently used entries until the cache holds at most
+// 'size' bytes.
+func (dc *downloadCache) prune(size int64) {
+	for dc.size > size {
+		var oldest *downloadCacheEntry
+		for _, entry := range dc.entries {
+			if oldest == nil || entry.LastAccess.Before(oldest.LastAccess) {
+				oldest = entry
+			}
+		}
+		dc.remove(oldest.SiaPath)
+	}
+}
+
+// save persists the index of the cache.
+func (dc *downloadCache) save() error {
+	var index downloadCacheIndex
+	for _, entry := range dc.entries {
+		index.Entries = append(index.Entries, *entry)
+	}
+	return persist.SaveJSON(downloadCacheMetadata, index, filepath.Join(dc.dir, downloadCacheIndexFile))
+}
+
+// managedNewDestination wraps the destination of a download of a complete
+// file so that the downloaded data is copied into the cache. A nil
+// destination is returned if the file is larger than the cache.
+func (dc *downloadCache) managedNewDestination(dst downloadDestination, size uint64) (*downloadCacheDestination, error) {
+	dc.mu.Lock()
+	maxSize := dc.maxSize
+	dc.mu.Unlock()
+	if size > uint64(maxSize) {
+		return nil, nil
+	}
+	f, err := ioutil.TempFile(dc.dir, downloadCacheTempPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return &downloadCacheDestination{
+		downloadDestination: dst,
+		file:                f,
+	}, nil
+}
+
+// managedAdd adds the data copied by a completed download to the cache,
+// evicting older entries to make room. Files that are larger than the cache
+// are ignored. The data is hashed before the cache is locked.
+func (dc *downloadCache) managedAdd(siapath string, version crypto.Hash, cd *downloadCacheDestination) error {
+	path := cd.file.Name()
+	if err := cd.managedCloseCacheFile(); err != nil {
+		os.Remove(path)
+		return err
+	}
+	root, size, err := fileMerkleRoot(path)
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	if size > dc.maxSize {
+		os.Remove(path)
+		return nil
+	}
+
+	// Make room for the new entry and move the data into place.
+	dc.remove(siapath)
+	dc.prune(dc.maxSize - size)
+	err = os.Rename(path, dc.dataPath(siapath))
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	dc.entries[siapath] = &downloadCacheEntry{
+		SiaPath:    siapath,
+		Version:    version,
+		Size:       size,
+		MerkleRoot: root,
+		LastAccess: time.Now(),
+	}
+	dc.size += size
+	return dc.save()
+}
+
+// managedInvalidate removes the cached data for a siapath.
+func (dc *downloadCache) managedInvalidate(siapath string) error {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	if _, exists := dc.entries[siapath]; !exists {
+		return nil
+	}
+	dc.remove(siapath)
+	return dc.save()
+}
+
+// managedRetrieve writes the requested range of a cached file to the
+// destination. The returned bool indicates whether the data was served from
+// the cache. Entries with a stale version or corrupt data are removed, and
+// requests for a range outside of the file are treated as misses. The data is
+// verified and streamed to the destination without holding the lock of the
+// cache, and without reading the whole range into memory. Reads only update
+// the index in memory; the access times are persisted the next time that the
+// index changes.
+func (dc *downloadCache) managedRetrieve(siapath string, version crypto.Hash, offset, length uint64, dst downloadDestination) (bool, error) {
+	dc.mu.Lock()
+	entry, exists := dc.entries[siapath]
+	if exists && entry.Version != version {
+		dc.remove(siapath)
+		exists = false
+	}
+	if !exists || uint64(entry.Size) < offset+length {
+		dc.misses++
+		dc.mu.Unlock()
+		return false, nil
+	}
+	merkleRoot := entry.MerkleRoot
+	dc.mu.Unlock()
+
+	// Verify the cached data before serving any of it. The entry may be
+	// replaced while the lock is not held, in which case the data will not
+	// match and the request is treated as a miss. The open file keeps
+	// referring to the verified data even if it is replaced.
+	f, err := os.Open(dc.dataPath(siapath))
+	if err == nil {
+		defer f.Close()
+		var root crypto.Hash
+		root, _, err = readerMerkleRoot(f)
+		if err == nil && root != merkleRoot {
+			err = errCacheCorrupt
+		}
+	}
+
+	dc.mu.Lock()
+	if err != nil {
+		// Only remove the entry that was checked, not one that replaced it.
+		dc.misses++
+		err = nil
+		if dc.entries[siapath] == entry {
+			dc.remove(siapath)
+			err = dc.save()
+		}
+		dc.mu.Unlock()
+		return false, err
+	}
+	entry.LastAccess = time.Now()
+	dc.hits++
+	dc.mu.Unlock()
+
+	w := &destinationWriter{dst: dst}
+	_, err = io.CopyN(w, io.NewSectionReader(f, int64(offset), int64(length)), int64(length))
+	if err != nil {
+		return true, errors.AddContext(err, "failed to write cached file to destination")
+	}
+	return true, nil
+}
+
+// destinationWriter is an io.Writer that writes to a downloadDestination
+// sequentially, starting at offset 0.
+type destinationWriter struct {
+	dst    downloadDestination
+	offset int64
+}
+
+// Write writes p to the destination at the current offset.
+func (w *destinationWriter) Write(p []byte) (int, error) {
+	n, err := w.dst.WriteAt(p, w.offset)
+	w.offset += int64(n)
+	return n, err
+}
+
+// managedSetMaxSize sets the maximum number of bytes held by the cache,
+// evicting entries if necessary. A size of 0 disables the cache.
+func (dc *downloadCache) managedSetMaxSize(maxSize int64) error {
+	if maxSize < 0 {
+		return errNegativeCacheSize
+	}
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	dc.maxSize = maxSize
+	dc.prune(maxSize)
+	return dc.save()
+}
+
+// managedStats returns the usage statistics of the cache.
+func (dc *downloadCache) managedStats() modules.DownloadCacheStats {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	return modules.DownloadCacheStats{
+		Hits:    dc.hits,
+		Misses:  dc.misses,
+		Size:    dc.size,
+		MaxSize: dc.maxSize,
+		Entries: uint64(len(dc.entries)),
+	}
+}
+
+// managedDownloadFromCache tries to serve a download from the download cache.
+// If successful, a completed download object is returned.
+func (r *Renter) managedDownloadFromCache(f *file, params downloadParams) (*download, bool, error) {
+	served, err := r.staticDownloadCache.managedRetrieve(f.name, fileVersion(f), params.offset, params.length, params.destination)
+	if !served {
+		return nil, false, err
+	}
+	if err != nil {
+		params.destination.Close()
+		return nil, true, err
+	}
+
+	now := time.Now()
+	d := &download{
+		atomicDataReceived:         params.length,
+		atomicTotalDataTransferred: 0,
+		completeChan:               make(chan struct{}),
+
+		endTime:         now,
+		staticStartTime: now,
+
+		destinationString:     params.destinationString,
+		staticDestinationType: params.destinationType,
+		staticLength:          params.length,
+		staticOffset:          params.offset,
+		staticSiaPath:         f.name,
+
+		log:           r.log,
+		memoryManager: r.memoryManager,
+	}
+	close(d.completeChan)
+	err = params.destination.Close()
+	return d, true, err
+}
+
+// threadedAddToDownloadCache waits for a download of a complete file to
+// finish, and then adds the data that it copied to the download cache.
+func (r *Renter) threadedAddToDownloadCache(d *download, f *file, cd *downloadCacheDestination) {
+	if err := r.tg.Add(); err != nil {
+		cd.managedDiscard()
+		return
+	}
+	defer r.tg.Done()
+
+	select {
+	case <-d.completeChan:
+	case <-r.tg.StopChan():
+		cd.managedDiscard()
+		return
+	}
+	if d.Err() != nil {
+		cd.managedDiscard()
+		return
+	}
+	err := r.staticDownloadCache.managedAdd(f.name, fileVersion(f), cd)
+	if err != nil {
+		r.log.Println("WARN: unable to add downloaded file to the download cache:", err)
+	}
+}
+
+// DownloadCacheStats returns the usage statistics of the download cache.
+func (r *Renter) DownloadCacheStats() modules.DownloadCacheStats {
+	return r.staticDownloadCache.managedStats()
+}
+
+// SetDownloadCacheSize sets the maximum number of bytes that the download
+// cache can hold. A size of 0 disables the cache.
+func (r *Renter) SetDownloadCacheSize(size int64) error {
+	err := r.staticDownloadCache.managedSetMaxSize(size)
+	if err != nil {
+		return err
+	}
+	id := r.mu.Lock()
+	defer r.mu.Unlock(id)
+	r.persist.DownloadCacheSize = size
+	return r.saveSync()
+}
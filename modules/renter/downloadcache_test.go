@@ -0,0 +1,160 @@
+package renter
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// TestDownloadCache tests that the download cache serves, evicts, and
+// invalidates cached files correctly.
+func TestDownloadCache(t *testing.T) {
+	dir := build.TempDir("renter", t.Name())
+	dc, err := newDownloadCache(filepath.Join(dir, downloadCacheDir), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// add downloads random data to a file through a cache destination, and
+	// adds the copy to the cache.
+	add := func(siapath string, version crypto.Hash, size int) []byte {
+		data := fastrand.Bytes(size)
+		f, err := os.Create(filepath.Join(dir, siapath))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cd, err := dc.managedNewDestination(f, uint64(size))
+		if err != nil || cd == nil {
+			t.Fatal("unable to create cache destination:", err)
+		}
+		if _, err := cd.WriteAt(data, 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := cd.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := dc.managedAdd(siapath, version, cd); err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	// retrieve reads a range of a cached file.
+	retrieve := func(siapath string, version crypto.Hash, offset, length uint64) ([]byte, bool) {
+		dst, err := os.Create(filepath.Join(dir, "dst"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer dst.Close()
+		served, err := dc.managedRetrieve(siapath, version, offset, length, dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadFile(dst.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data, served
+	}
+
+	// Add a file and retrieve a range of it.
+	v1 := crypto.HashObject("v1")
+	data := add("foo", v1, 60)
+	got, served := retrieve("foo", v1, 10, 20)
+	if !served || !bytes.Equal(got, data[10:30]) {
+		t.Fatal("cache did not serve the correct data")
+	}
+
+	// The cache holds the downloaded data, not the user's copy of it.
+	if err := ioutil.WriteFile(filepath.Join(dir, "foo"), fastrand.Bytes(60), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, served := retrieve("foo", v1, 0, 60); !served || !bytes.Equal(got, data) {
+		t.Fatal("cache did not serve the downloaded data")
+	}
+
+	// A range outside of the file is a miss, but does not evict the file.
+	if _, served := retrieve("foo", v1, 50, 20); served {
+		t.Fatal("cache served a range outside of the file")
+	}
+	if _, served := retrieve("foo", v1, 0, 60); !served {
+		t.Fatal("file was evicted by a request for a bad range")
+	}
+
+	// A different version of the file should not be served.
+	if _, served := retrieve("foo", crypto.HashObject("v2"), 0, 60); served {
+		t.Fatal("cache served a stale version of the file")
+	}
+	if stats := dc.managedStats(); stats.Hits != 3 || stats.Misses != 2 || stats.Size != 0 {
+		t.Fatal("unexpected stats:", stats)
+	}
+
+	// Adding a file that does not fit should evict the least recently used
+	// file.
+	add("foo", v1, 60)
+	data = add("bar", v1, 60)
+	if _, served := retrieve("foo", v1, 0, 60); served {
+		t.Fatal("least recently used file was not evicted")
+	}
+	if got, served := retrieve("bar", v1, 0, 60); !served || !bytes.Equal(got, data) {
+		t.Fatal("cache did not serve the correct data")
+	}
+
+	// Corrupt data should be detected and not served.
+	if err := ioutil.WriteFile(dc.dataPath("bar"), fastrand.Bytes(60), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, served := retrieve("bar", v1, 0, 60); served {
+		t.Fatal("cache served corrupt data")
+	}
+
+	// Invalidated files should not be served.
+	add("bar", v1, 60)
+	if err := dc.managedInvalidate("bar"); err != nil {
+		t.Fatal(err)
+	}
+	if _, served := retrieve("bar", v1, 0, 60); served {
+		t.Fatal("cache served an invalidated file")
+	}
+
+	// The cache should persist across restarts.
+	data = add("bar", v1, 60)
+	dc, err = newDownloadCache(filepath.Join(dir, downloadCacheDir), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, served := retrieve("bar", v1, 0, 60); !served || !bytes.Equal(got, data) {
+		t.Fatal("cache did not persist")
+	}
+
+	// Shrinking the cache should evict files.
+	if err := dc.managedSetMaxSize(10); err != nil {
+		t.Fatal(err)
+	}
+	if stats := dc.managedStats(); stats.Entries != 0 || stats.Size != 0 {
+		t.Fatal("cache was not pruned:", stats)
+	}
+
+	// Large ranges are streamed to the destination, and serving them does
+	// not rewrite the index.
+	if err := dc.managedSetMaxSize(1 << 20); err != nil {
+		t.Fatal(err)
+	}
+	data = add("baz", v1, 300e3)
+	indexPath := filepath.Join(dc.dir, downloadCacheIndexFile)
+	index, err := ioutil.ReadFile(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, served := retrieve("baz", v1, 1234, 250e3); !served || !bytes.Equal(got, data[1234:1234+250e3]) {
+		t.Fatal("cache did not serve the correct data")
+	}
+	if newIndex, err := ioutil.ReadFile(indexPath); err != nil || !bytes.Equal(newIndex, index) {
+		t.Fatal("index was rewritten by a read:", err)
+	}
+}
@@ -67,6 +67,14 @@ type unfinishedDownloadChunk struct {
 	workersRemaining  int       // Number of workers still able to fetch the chunk.
 	workersStandby    []*worker // Set of workers that are able to work on this download, but are not needed unless other workers fail.
 
+	// Worker selection variables - need mutex to access.
+	fastestThroughput uint64 // Throughput of the fastest worker with a piece of this chunk.
+	standbyReleased   bool   // Whether standby workers have been called upon.
+
+	// concurrency is the download concurrency limiter that the chunk holds a
+	// slot of. It is nil if the chunk does not hold a slot.
+	concurrency *downloadConcurrency
+
 	// Memory management variables.
 	memoryAllocated uint64
 
@@ -104,6 +112,17 @@ func (udc *unfinishedDownloadChunk) managedCleanUp() {
 	// Return any excess memory.
 	udc.returnMemory()
 
+	// Release the chunk's download slot once the chunk has finished.
+	if udc.concurrency != nil && udc.recoveryComplete {
+		concurrency := udc.concurrency
+		udc.concurrency = nil
+		var downloaded uint64
+		if !udc.failed {
+			downloaded = udc.staticFetchLength
+		}
+		defer concurrency.managedRelease(downloaded)
+	}
+
 	// Nothing to do if the chunk has failed.
 	if udc.failed {
 		udc.mu.Unlock()
@@ -128,7 +147,9 @@ func (udc *unfinishedDownloadChunk) managedCleanUp() {
 		standbyWorkers = append(standbyWorkers, udc.workersStandby[i])
 	}
 	udc.workersStandby = udc.workersStandby[:0] // Workers have been taken off of standby.
+	udc.standbyReleased = true
 	udc.mu.Unlock()
+	sortWorkersByThroughput(standbyWorkers)
 	for i := 0; i < len(standbyWorkers); i++ {
 		standbyWorkers[i].managedQueueDownloadChunk(udc)
 	}
@@ -0,0 +1,155 @@
+package renter
+
+// downloadconcurrency.go limits the number of chunks that are downloaded in
+// parallel. The limit is either set by the user, or automatically tuned by
+// measuring the throughput of completed chunks: the limit keeps moving in the
+// same direction while throughput improves, and reverses direction when
+// throughput drops.
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	errNegativeDownloadConcurrency = errors.New("download concurrency cannot be negative")
+)
+
+// downloadConcurrency is a counting semaphore that bounds the number of chunks
+// being downloaded at once.
+type downloadConcurrency struct {
+	active  int // Number of chunks currently downloading.
+	limit   int // Number of chunks allowed to download at once.
+	setting int // Limit set by the user, 0 means auto-tune.
+
+	// freed is signaled whenever a chunk releases its slot.
+	freed chan struct{}
+
+	// Auto-tuning state. Throughput is measured over windows of
+	// downloadConcurrencyTuneInterval during which chunks were downloading.
+	direction      int
+	lastThroughput float64
+	windowBytes    uint64
+	windowStart    time.Time
+
+	mu sync.Mutex
+}
+
+// newDownloadConcurrency returns a downloadConcurrency with the given setting.
+func newDownloadConcurrency(setting int) *downloadConcurrency {
+	dc := &downloadConcurrency{
+		freed:       make(chan struct{}, 1),
+		direction:   1,
+		windowStart: time.Now(),
+	}
+	dc.managedSetLimit(setting)
+	return dc
+}
+
+// managedAcquire blocks until a chunk is allowed to start downloading. 'false'
+// is returned if 'stop' is closed before a slot becomes available.
+func (dc *downloadConcurrency) managedAcquire(stop <-chan struct{}) bool {
+	for {
+		dc.mu.Lock()
+		if dc.active < dc.limit {
+			// Throughput is only measured while chunks are downloading, so
+			// start a fresh window when coming out of an idle period.
+			if dc.active == 0 {
+				dc.windowBytes = 0
+				dc.windowStart = time.Now()
+			}
+			dc.active++
+			dc.mu.Unlock()
+			return true
+		}
+		dc.mu.Unlock()
+
+		select {
+		case <-dc.freed:
+		case <-stop:
+			return false
+		}
+	}
+}
+
+// managedRelease releases the slot of a chunk that has finished downloading.
+// 'bytes' is the amount of data that the chunk downloaded.
+func (dc *downloadConcurrency) managedRelease(bytes uint64) {
+	dc.mu.Lock()
+	dc.active--
+	dc.windowBytes += bytes
+	if dc.setting == 0 {
+		dc.tune()
+	}
+	dc.mu.Unlock()
+
+	select {
+	case dc.freed <- struct{}{}:
+	default:
+	}
+}
+
+// managedLimit returns the current number of chunks that may download at once.
+func (dc *downloadConcurrency) managedLimit() int {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	return dc.limit
+}
+
+// managedSetLimit sets the number of chunks that may download at once. A
+// setting of 0 enables auto-tuning.
+func (dc *downloadConcurrency) managedSetLimit(setting int) {
+	dc.mu.Lock()
+	dc.setting = setting
+	dc.limit = setting
+	if setting == 0 {
+		dc.limit = initialDownloadConcurrency
+		dc.direction = 1
+		dc.lastThroughput = 0
+	}
+	dc.mu.Unlock()
+
+	// Wake the download loop in case the limit was raised.
+	select {
+	case dc.freed <- struct{}{}:
+	default:
+	}
+}
+
+// tune adjusts the limit once a full measurement window has elapsed.
+func (dc *downloadConcurrency) tune() {
+	elapsed := time.Since(dc.windowStart)
+	if elapsed < downloadConcurrencyTuneInterval {
+		return
+	}
+	throughput := float64(dc.windowBytes) / elapsed.Seconds()
+	if throughput < dc.lastThroughput {
+		dc.direction = -dc.direction
+	}
+	dc.limit += dc.direction
+	if dc.limit < 1 {
+		dc.limit = 1
+		dc.direction = 1
+	} else if dc.limit > maxDownloadConcurrency {
+		dc.limit = maxDownloadConcurrency
+		dc.direction = -1
+	}
+	dc.lastThroughput = throughput
+	dc.windowBytes = 0
+	dc.windowStart = time.Now()
+}
+
+// SetDownloadConcurrency sets the number of chunks that the renter will
+// download in parallel. A value of 0 lets the renter tune the number based on
+// the measured download throughput.
+func (r *Renter) SetDownloadConcurrency(chunks int) error {
+	if chunks < 0 {
+		return errNegativeDownloadConcurrency
+	}
+	r.staticDownloadConcurrency.managedSetLimit(chunks)
+	id := r.mu.Lock()
+	defer r.mu.Unlock(id)
+	r.persist.DownloadConcurrency = chunks
+	return r.saveSync()
+}
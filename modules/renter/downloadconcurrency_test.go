@@ -0,0 +1,136 @@
+package renter
+
+import (
+	"testing"
+	"time"
+)
+
+// TestDownloadConcurrencyLimit checks that the download concurrency limiter
+// blocks chunks once the limit is reached.
+func TestDownloadConcurrencyLimit(t *testing.T) {
+	t.Parallel()
+	dc := newDownloadConcurrency(2)
+	stop := make(chan struct{})
+	if !dc.managedAcquire(stop) || !dc.managedAcquire(stop) {
+		t.Fatal("unable to acquire slots below the limit")
+	}
+
+	// The third acquire should block until a slot is released.
+	acquired := make(chan bool)
+	go func() {
+		acquired <- dc.managedAcquire(stop)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("acquired a slot beyond the limit")
+	case <-time.After(100 * time.Millisecond):
+	}
+	dc.managedRelease(0)
+	select {
+	case ok := <-acquired:
+		if !ok {
+			t.Fatal("acquire failed after a slot was released")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("acquire did not unblock after a slot was released")
+	}
+
+	// Raising the limit should unblock waiting chunks, and closing the stop
+	// channel should abort them.
+	go func() {
+		acquired <- dc.managedAcquire(stop)
+	}()
+	dc.managedSetLimit(3)
+	if ok := <-acquired; !ok {
+		t.Fatal("acquire failed after the limit was raised")
+	}
+	go func() {
+		acquired <- dc.managedAcquire(stop)
+	}()
+	close(stop)
+	if ok := <-acquired; ok {
+		t.Fatal("acquire succeeded after stop")
+	}
+}
+
+// TestDownloadConcurrencyTune checks that the auto-tuner keeps raising the
+// limit while throughput improves and reverses when throughput drops.
+func TestDownloadConcurrencyTune(t *testing.T) {
+	t.Parallel()
+	dc := newDownloadConcurrency(0)
+	if dc.managedLimit() != initialDownloadConcurrency {
+		t.Fatal("auto-tuning did not start at the initial concurrency")
+	}
+
+	// Simulate a measurement window with improving throughput.
+	dc.windowStart = time.Now().Add(-downloadConcurrencyTuneInterval)
+	dc.windowBytes = 1e6
+	dc.tune()
+	if dc.limit != initialDownloadConcurrency+1 {
+		t.Fatal("limit was not raised after throughput improved:", dc.limit)
+	}
+
+	// Simulate a window with worse throughput.
+	dc.windowStart = time.Now().Add(-downloadConcurrencyTuneInterval)
+	dc.windowBytes = 1e3
+	dc.tune()
+	if dc.limit != initialDownloadConcurrency {
+		t.Fatal("limit was not lowered after throughput dropped:", dc.limit)
+	}
+
+	// A fixed setting is not tuned.
+	dc.managedSetLimit(7)
+	dc.windowStart = time.Now().Add(-downloadConcurrencyTuneInterval)
+	dc.active = 1
+	dc.managedRelease(1e9)
+	if dc.managedLimit() != 7 {
+		t.Fatal("fixed limit was tuned")
+	}
+}
+
+// TestSortWorkersByThroughput checks that workers are sorted fastest first.
+func TestSortWorkersByThroughput(t *testing.T) {
+	t.Parallel()
+	workers := []*worker{
+		{atomicDownloadThroughput: 10},
+		{atomicDownloadThroughput: 0},
+		{atomicDownloadThroughput: 30},
+		{atomicDownloadThroughput: 20},
+	}
+	sortWorkersByThroughput(workers)
+	for i, expected := range []uint64{30, 20, 10, 0} {
+		if workers[i].atomicDownloadThroughput != expected {
+			t.Fatal("workers are not sorted by throughput")
+		}
+	}
+}
+
+// TestSetDownloadConcurrency checks that SetDownloadConcurrency rejects
+// negative values and persists the setting.
+func TestSetDownloadConcurrency(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	if err := rt.renter.SetDownloadConcurrency(-1); err != errNegativeDownloadConcurrency {
+		t.Fatal("expected errNegativeDownloadConcurrency, got", err)
+	}
+	if err := rt.renter.SetDownloadConcurrency(5); err != nil {
+		t.Fatal(err)
+	}
+	if rt.renter.staticDownloadConcurrency.managedLimit() != 5 {
+		t.Error("download concurrency was not set")
+	}
+	id := rt.renter.mu.RLock()
+	persisted := rt.renter.persist.DownloadConcurrency
+	rt.renter.mu.RUnlock(id)
+	if persisted != 5 {
+		t.Error("download concurrency was not persisted")
+	}
+}
@@ -10,6 +10,7 @@ package renter
 import (
 	"container/heap"
 	"errors"
+	"sync/atomic"
 	"time"
 )
 
@@ -121,11 +122,28 @@ func (r *Renter) managedBlockUntilOnline() bool {
 func (r *Renter) managedDistributeDownloadChunkToWorkers(udc *unfinishedDownloadChunk) {
 	// Distribute the chunk to workers, marking the number of workers
 	// that have received the work.
+	//
+	// The chunk is queued to the fastest workers first, and the throughput of
+	// the fastest worker that has a piece of the chunk is recorded so that
+	// slow workers can be put on standby.
 	id := r.mu.Lock()
+	workers := make([]*worker, 0, len(r.workerPool))
+	for _, worker := range r.workerPool {
+		workers = append(workers, worker)
+	}
+	sortWorkersByThroughput(workers)
+	var fastest uint64
+	for _, worker := range workers {
+		if _, exists := udc.staticChunkMap[worker.contract.ID]; exists {
+			fastest = atomic.LoadUint64(&worker.atomicDownloadThroughput)
+			break
+		}
+	}
 	udc.mu.Lock()
-	udc.workersRemaining = len(r.workerPool)
+	udc.workersRemaining = len(workers)
+	udc.fastestThroughput = fastest
 	udc.mu.Unlock()
-	for _, worker := range r.workerPool {
+	for _, worker := range workers {
 		worker.managedQueueDownloadChunk(udc)
 	}
 	r.mu.Unlock(id)
@@ -200,7 +218,13 @@ LOOP:
 				continue
 			}
 
-			// Get the required memory to download this chunk.
+			// Wait until the chunk is allowed to download, then get the
+			// required memory to download this chunk.
+			if !r.staticDownloadConcurrency.managedAcquire(r.tg.StopChan()) {
+				// The renter shut down before the chunk could start.
+				return
+			}
+			nextChunk.concurrency = r.staticDownloadConcurrency
 			if !r.managedAcquireMemoryForDownloadChunk(nextChunk) {
 				// The renter shut down before memory could be acquired.
 				return
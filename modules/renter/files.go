@@ -205,6 +205,13 @@ func (f *file) redundancy(offlineMap map[types.FileContractID]bool, goodForRenew
 	return redundancy
 }
 
+// isCritical returns true if a file with the given redundancy is at risk of
+// becoming unrecoverable. Empty files, which have a redundancy of -1, are never
+// critical.
+func isCritical(redundancy float64) bool {
+	return redundancy >= 0 && redundancy <= criticalRedundancy
+}
+
 // expiration returns the lowest height at which any of the file's contracts
 // will expire.
 func (f *file) expiration() types.BlockHeight {
@@ -235,7 +242,8 @@ func newFile(name string, code modules.ErasureCoder, pieceSize, fileSize uint64)
 }
 
 // DeleteFile removes a file entry from the renter and deletes its data from
-// the hosts it is stored on.
+// the hosts it is stored on. Versions of the file are kept so that the file can
+// be restored; PurgeVersions removes them.
 //
 // TODO: The data is not cleared from any contracts where the host is not
 // immediately online.
@@ -257,6 +265,11 @@ func (r *Renter) DeleteFile(nickname string) error {
 	r.saveSync()
 	r.mu.Unlock(lockID)
 
+	// Drop any cached copy of the file.
+	if err := r.staticDownloadCache.managedInvalidate(nickname); err != nil {
+		r.log.Println("WARN: couldn't remove file from download cache:", err)
+	}
+
 	// delete the file's associated contract data.
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -307,13 +320,15 @@ func (r *Renter) FileList() []modules.FileInfo {
 		if exists {
 			localPath = tf.RepairPath
 		}
+		redundancy := f.redundancy(offline, goodForRenew)
 		fileList = append(fileList, modules.FileInfo{
 			SiaPath:        f.name,
 			LocalPath:      localPath,
 			Filesize:       f.size,
 			Renewing:       renewing,
 			Available:      f.available(offline),
-			Redundancy:     f.redundancy(offline, goodForRenew),
+			Redundancy:     redundancy,
+			Critical:       isCritical(redundancy),
 			UploadedBytes:  f.uploadedBytes(),
 			UploadProgress: f.uploadProgress(),
 			Expiration:     f.expiration(),
@@ -361,13 +376,15 @@ func (r *Renter) File(siaPath string) (modules.FileInfo, error) {
 	if exists {
 		localPath = tf.RepairPath
 	}
+	redundancy := file.redundancy(offline, goodForRenew)
 	fileInfo = modules.FileInfo{
 		SiaPath:        file.name,
 		LocalPath:      localPath,
 		Filesize:       file.size,
 		Renewing:       renewing,
 		Available:      file.available(offline),
-		Redundancy:     file.redundancy(offline, goodForRenew),
+		Redundancy:     redundancy,
+		Critical:       isCritical(redundancy),
 		UploadedBytes:  file.uploadedBytes(),
 		UploadProgress: file.uploadProgress(),
 		Expiration:     file.expiration(),
@@ -376,6 +393,16 @@ func (r *Renter) File(siaPath string) (modules.FileInfo, error) {
 	return fileInfo, nil
 }
 
+// FileHealth returns the redundancy of the file at siaPath, counting only the
+// pieces that are stored on hosts which are online.
+func (r *Renter) FileHealth(siaPath string) (float64, error) {
+	fi, err := r.File(siaPath)
+	if err != nil {
+		return 0, err
+	}
+	return fi.Redundancy, nil
+}
+
 // RenameFile takes an existing file and changes the nickname. The original
 // file must exist, and there must not be any file that already has the
 // replacement nickname.
@@ -410,6 +437,9 @@ func (r *Renter) RenameFile(currentName, newName string) error {
 	// Update the entries in the renter.
 	delete(r.files, currentName)
 	r.files[newName] = file
+	if err := r.staticDownloadCache.managedInvalidate(currentName); err != nil {
+		r.log.Println("WARN: couldn't remove file from download cache:", err)
+	}
 	if t, ok := r.persist.Tracking[currentName]; ok {
 		delete(r.persist.Tracking, currentName)
 		r.persist.Tracking[newName] = t
@@ -418,6 +448,13 @@ func (r *Renter) RenameFile(currentName, newName string) error {
 	if err != nil {
 		return err
 	}
+	if versions, ok := r.versions[currentName]; ok {
+		delete(r.versions, currentName)
+		r.versions[newName] = append(r.versions[newName], versions...)
+		if err := r.saveVersions(); err != nil {
+			return err
+		}
+	}
 
 	// Delete the old .sia file.
 	oldPath := filepath.Join(r.persistDir, currentName+ShareExtension)
@@ -453,3 +453,49 @@ func TestRenterRenameFile(t *testing.T) {
 		t.Error("renaming should have updated the entry in the tracking set")
 	}
 }
+
+// TestRenterFileHealth probes the FileHealth method of the renter type.
+func TestRenterFileHealth(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	if _, err := rt.renter.FileHealth("unknown"); err != ErrUnknownPath {
+		t.Fatal("expected ErrUnknownPath, got", err)
+	}
+
+	// A file without any pieces on reachable hosts is critical.
+	id := rt.renter.mu.Lock()
+	f := newTestingFile()
+	rt.renter.files[f.name] = f
+	rt.renter.mu.Unlock(id)
+	health, err := rt.renter.FileHealth(f.name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if health != 0 {
+		t.Fatal("expected a health of 0, got", health)
+	}
+	fi, err := rt.renter.File(f.name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.Critical {
+		t.Fatal("file without pieces was not flagged as critical")
+	}
+
+	// Files are critical at or below 1x redundancy, empty files never are.
+	for _, test := range []struct {
+		redundancy float64
+		critical   bool
+	}{{-1, false}, {0, true}, {1, true}, {1.1, false}, {3, false}} {
+		if isCritical(test.redundancy) != test.critical {
+			t.Errorf("isCritical(%v) should be %v", test.redundancy, test.critical)
+		}
+	}
+}
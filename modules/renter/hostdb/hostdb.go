@@ -12,6 +12,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/modules/renter/hostdb/hosttree"
 	"github.com/NebulousLabs/Sia/persist"
@@ -58,6 +59,10 @@ type HostDB struct {
 
 	blockHeight types.BlockHeight
 	lastChange  modules.ConsensusChangeID
+
+	// staticVersion is the version of siad that the hostdb reports to hosts
+	// when requesting their settings. It is build.Version outside of tests.
+	staticVersion string
 }
 
 // New returns a new HostDB.
@@ -85,6 +90,8 @@ func NewCustomHostDB(g modules.Gateway, cs modules.ConsensusSet, persistDir stri
 		persistDir: persistDir,
 
 		scanMap: make(map[string]struct{}),
+
+		staticVersion: build.Version,
 	}
 
 	// Create the persist directory if it does not yet exist.
@@ -305,12 +305,33 @@ func (hdb *HostDB) managedScanHost(entry modules.HostDBEntry) {
 		defer close(connCloseChan)
 		conn.SetDeadline(time.Now().Add(hostScanDeadline))
 
+		var pubkey crypto.PublicKey
+		copy(pubkey[:], pubKey.Key)
+
+		// Hosts that are known to support versioned settings are asked for
+		// settings in the format of the renter's version.
+		if build.VersionCmp(entry.Version, modules.MinVersionedSettingsVersion) >= 0 {
+			err = encoding.WriteObject(conn, modules.RPCSettingsVersioned)
+			if err != nil {
+				return err
+			}
+			err = encoding.WriteObject(conn, hdb.staticVersion)
+			if err != nil {
+				return err
+			}
+			if build.VersionCmp(hdb.staticVersion, modules.MinVersionedSettingsVersion) < 0 {
+				return crypto.ReadSignedObject(conn, &settings, maxSettingsLen, pubkey)
+			}
+			var vs modules.HostVersionedSettings
+			err = crypto.ReadSignedObject(conn, &vs, maxSettingsLen, pubkey)
+			settings = vs.HostExternalSettings
+			return err
+		}
+
 		err = encoding.WriteObject(conn, modules.RPCSettings)
 		if err != nil {
 			return err
 		}
-		var pubkey crypto.PublicKey
-		copy(pubkey[:], pubKey.Key)
 		return crypto.ReadSignedObject(conn, &settings, maxSettingsLen, pubkey)
 	}()
 	if err != nil {
@@ -2,10 +2,13 @@ package hostdb
 
 import (
 	"errors"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/host"
 	"github.com/NebulousLabs/Sia/types"
 )
 
@@ -182,3 +185,48 @@ func TestUpdateEntry(t *testing.T) {
 		t.Error("host not reporting historic uptime?")
 	}
 }
+
+// TestScanHostVersionedSettings checks that a real host is scanned through
+// RPCSettingsVersioned when both the host and the renter are at least
+// MinVersionedSettingsVersion, and that a renter below that version can still
+// read the host's settings. The versions are overridden because
+// MinVersionedSettingsVersion is newer than build.Version.
+func TestScanHostVersionedSettings(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	hdbt, err := newHDBTesterDeps(t.Name(), &disableScanLoopDeps{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := host.New(hdbt.cs, hdbt.tpool, hdbt.wallet, "localhost:0", filepath.Join(hdbt.persistDir, modules.HostDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	if err := h.SetInternalSettings(modules.HostInternalSettings{AcceptingContracts: true}); err != nil {
+		t.Fatal(err)
+	}
+	hes := h.ExternalSettings()
+	pk := h.PublicKey()
+
+	for _, version := range []string{modules.MinVersionedSettingsVersion, build.Version} {
+		hdbt.hdb.staticVersion = version
+		entry := modules.HostDBEntry{PublicKey: pk}
+		entry.NetAddress = hes.NetAddress
+		entry.Version = modules.MinVersionedSettingsVersion
+		hdbt.hdb.managedScanHost(entry)
+
+		scanned, exists := hdbt.hdb.Host(pk)
+		if !exists {
+			t.Fatalf("renter %v: host was not added to the hostdb", version)
+		}
+		if len(scanned.ScanHistory) == 0 || !scanned.ScanHistory[len(scanned.ScanHistory)-1].Success {
+			t.Fatalf("renter %v: scan of the host failed", version)
+		}
+		if scanned.Version != build.Version || !scanned.AcceptingContracts || scanned.NetAddress != hes.NetAddress {
+			t.Fatalf("renter %v: scanned settings do not match the host's settings: %v", version, scanned.HostExternalSettings)
+		}
+	}
+}
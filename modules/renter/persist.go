@@ -51,10 +51,16 @@ var (
 type (
 	// persist contains all of the persistent renter data.
 	persistence struct {
-		MaxDownloadSpeed int64
-		MaxUploadSpeed   int64
-		StreamCacheSize  uint64
-		Tracking         map[string]trackedFile
+		DownloadCacheSize   int64
+		DownloadConcurrency int
+		MaxDownloadSpeed    int64
+		MaxUploadSpeed      int64
+		StreamCacheSize     uint64
+		Tracking            map[string]trackedFile
+
+		// Directories contains the directories that were created explicitly
+		// with CreateDir. Directories that contain files exist implicitly.
+		Directories map[string]struct{}
 	}
 )
 
@@ -246,7 +252,8 @@ func (r *Renter) loadSiaFiles() error {
 // load fetches the saved renter data from disk.
 func (r *Renter) loadSettings() error {
 	r.persist = persistence{
-		Tracking: make(map[string]trackedFile),
+		Directories: make(map[string]struct{}),
+		Tracking:    make(map[string]trackedFile),
 	}
 	err := persist.LoadJSON(settingsMetadata, &r.persist, filepath.Join(r.persistDir, PersistFilename))
 	if os.IsNotExist(err) {
@@ -270,6 +277,9 @@ func (r *Renter) loadSettings() error {
 	} else if err != nil {
 		return err
 	}
+	if r.persist.Directories == nil {
+		r.persist.Directories = make(map[string]struct{})
+	}
 
 	// Set the bandwidth limits on the contractor, which was already initialized
 	// without bandwidth limits.
@@ -446,6 +456,10 @@ func (r *Renter) initPersist() error {
 	if err != nil {
 		return err
 	}
+	err = r.loadVersions()
+	if err != nil {
+		return err
+	}
 
 	// Load the siafiles into memory.
 	return r.loadSiaFiles()
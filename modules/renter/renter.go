@@ -22,6 +22,7 @@ package renter
 
 import (
 	"errors"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"sync"
@@ -107,6 +108,11 @@ type hostContractor interface {
 	// Allowance returns the current allowance
 	Allowance() modules.Allowance
 
+	// AllowanceStatus returns the amount of the allowance spent during the
+	// current period, the amount remaining, and the height at which the
+	// period ends.
+	AllowanceStatus() (spent, remaining types.Currency, periodEnd types.BlockHeight, err error)
+
 	// Close closes the hostContractor.
 	Close() error
 
@@ -128,6 +134,10 @@ type hostContractor interface {
 	// billing period.
 	PeriodSpending() modules.ContractorSpending
 
+	// SpendingBreakdown returns the spending of the current billing period,
+	// broken down by category and by contract.
+	SpendingBreakdown() (modules.SpendingReport, error)
+
 	// Editor creates an Editor from the specified contract ID, allowing the
 	// insertion, deletion, and modification of sectors.
 	Editor(types.FileContractID, <-chan struct{}) (contractor.Editor, error)
@@ -149,6 +159,19 @@ type hostContractor interface {
 	// SetRateLimits sets the bandwidth limits for connections created by the
 	// contractor and its submodules.
 	SetRateLimits(int64, int64, uint64)
+
+	// RecordHostFailure records a failed interaction with a host.
+	RecordHostFailure(types.SiaPublicKey)
+
+	// RecordHostSuccess records a successful interaction with a host.
+	RecordHostSuccess(types.SiaPublicKey)
+
+	// HostFailures returns the number of consecutive failed interactions
+	// with a host, and whether the host is blacklisted.
+	HostFailures(types.SiaPublicKey) (int, bool)
+
+	// ClearHostBlacklist removes every host from the blacklist.
+	ClearHostBlacklist() error
 }
 
 // A trackedFile contains metadata about files being tracked by the Renter.
@@ -176,6 +199,10 @@ type Renter struct {
 	// default, files loaded through sharing are not maintained by the user.
 	files map[string]*file
 
+	// versions contains the snapshots of files, keyed by siapath. Versions
+	// are kept when a file is deleted, so that it can be restored.
+	versions map[string][]fileSnapshot
+
 	// Download management. The heap has a separate mutex because it is always
 	// accessed in isolation.
 	downloadHeapMu sync.Mutex         // Used to protect the downloadHeap.
@@ -201,18 +228,20 @@ type Renter struct {
 	lastEstimation modules.RenterPriceEstimation
 
 	// Utilities.
-	staticStreamCache *streamCache
-	cs                modules.ConsensusSet
-	deps              modules.Dependencies
-	g                 modules.Gateway
-	hostContractor    hostContractor
-	hostDB            hostDB
-	log               *persist.Logger
-	persist           persistence
-	persistDir        string
-	mu                *siasync.RWMutex
-	tg                threadgroup.ThreadGroup
-	tpool             modules.TransactionPool
+	staticDownloadCache       *downloadCache
+	staticDownloadConcurrency *downloadConcurrency
+	staticStreamCache         *streamCache
+	cs                        modules.ConsensusSet
+	deps                      modules.Dependencies
+	g                         modules.Gateway
+	hostContractor            hostContractor
+	hostDB                    hostDB
+	log                       *persist.Logger
+	persist                   persistence
+	persistDir                string
+	mu                        *siasync.RWMutex
+	tg                        threadgroup.ThreadGroup
+	tpool                     modules.TransactionPool
 }
 
 // Close closes the Renter and its dependencies
@@ -400,6 +429,26 @@ func (r *Renter) ContractUtility(id types.FileContractID) (modules.ContractUtili
 // PeriodSpending returns the host contractor's period spending
 func (r *Renter) PeriodSpending() modules.ContractorSpending { return r.hostContractor.PeriodSpending() }
 
+// HostFailures returns the number of consecutive failed interactions with a
+// host, and whether the host is blacklisted.
+func (r *Renter) HostFailures(pk types.SiaPublicKey) (count int, blacklisted bool) {
+	return r.hostContractor.HostFailures(pk)
+}
+
+// ClearHostBlacklist removes every host from the blacklist, allowing the
+// renter to form contracts with them again.
+func (r *Renter) ClearHostBlacklist() error { return r.hostContractor.ClearHostBlacklist() }
+
+// AllowanceStatus returns the host contractor's allowance status
+func (r *Renter) AllowanceStatus() (spent, remaining types.Currency, periodEnd types.BlockHeight, err error) {
+	return r.hostContractor.AllowanceStatus()
+}
+
+// SpendingBreakdown returns the host contractor's spending breakdown
+func (r *Renter) SpendingBreakdown() (modules.SpendingReport, error) {
+	return r.hostContractor.SpendingBreakdown()
+}
+
 // Settings returns the host contractor's allowance
 func (r *Renter) Settings() modules.RenterSettings {
 	download, upload, _ := r.hostContractor.RateLimits()
@@ -517,6 +566,15 @@ func NewCustomRenter(g modules.Gateway, cs modules.ConsensusSet, tpool modules.T
 	// Initialize the streaming cache.
 	r.staticStreamCache = newStreamCache(r.persist.StreamCacheSize)
 
+	// Initialize the download cache.
+	r.staticDownloadCache, err = newDownloadCache(filepath.Join(persistDir, downloadCacheDir), r.persist.DownloadCacheSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize the download concurrency limit.
+	r.staticDownloadConcurrency = newDownloadConcurrency(r.persist.DownloadConcurrency)
+
 	// Subscribe to the consensus set.
 	err = cs.ConsensusSetSubscribe(r, modules.ConsensusChangeRecent, r.tg.StopChan())
 	if err != nil {
@@ -97,6 +97,12 @@ func (r *Renter) Upload(up modules.FileUploadParams) error {
 		return err
 	}
 
+	// Any data cached for a previous file at this siapath is now stale.
+	err = r.staticDownloadCache.managedInvalidate(up.SiaPath)
+	if err != nil {
+		return err
+	}
+
 	// Send the upload to the repair loop.
 	hosts := r.managedRefreshHostsAndWorkers()
 	id := r.mu.Lock()
@@ -43,3 +43,34 @@ func TestRenterUploadInode(t *testing.T) {
 		t.Fatal("expected errUploadDirectory, got", err)
 	}
 }
+
+// TestUploadHeapFileHealth checks that chunks of the least healthy files are
+// popped from the upload heap first.
+func TestUploadHeapFileHealth(t *testing.T) {
+	uh := uploadHeap{
+		activeChunks: make(map[uploadChunkID]struct{}),
+	}
+	f := newTestingFile()
+	chunks := []*unfinishedUploadChunk{
+		{index: 0, fileHealth: 2, piecesCompleted: 4, piecesNeeded: 10},
+		{index: 1, fileHealth: 0.5, piecesCompleted: 8, piecesNeeded: 10},
+		{index: 2, fileHealth: 2, piecesCompleted: 2, piecesNeeded: 10},
+		{index: 3, fileHealth: 1, piecesCompleted: 0, piecesNeeded: 10},
+	}
+	for _, uuc := range chunks {
+		uuc.renterFile = f
+		uh.managedPush(uuc)
+	}
+	for _, index := range []uint64{1, 3, 2, 0} {
+		if uuc := uh.managedPop(); uuc == nil || uuc.index != index {
+			t.Fatalf("expected chunk %v to be popped, got %v", index, uuc)
+		}
+	}
+
+	// fileHealth reports the redundancy of the least redundant chunk.
+	chunks[0].minimumPieces = 2
+	chunks[1].minimumPieces = 4
+	if h := fileHealth(chunks[:2]); h != 2 {
+		t.Fatal("expected a file health of 2, got", h)
+	}
+}
@@ -39,6 +39,11 @@ type unfinishedUploadChunk struct {
 	offset         int64  // Offset of the chunk within the file.
 	piecesNeeded   int    // number of pieces to achieve a 100% complete upload
 
+	// fileHealth is the redundancy of the file's least redundant chunk at the
+	// time the chunk was queued. It is used to repair the files that are
+	// closest to being unrecoverable first.
+	fileHealth float64
+
 	// The logical data is the data that is presented to the user when the user
 	// requests the chunk. The physical data is all of the pieces that get
 	// stored across the network.
@@ -18,6 +18,7 @@ package renter
 
 import (
 	"container/heap"
+	"math"
 	"sync"
 	"time"
 
@@ -40,7 +41,9 @@ type uploadHeap struct {
 }
 
 // uploadChunkHeap is a bunch of priority-sorted chunks that need to be either
-// uploaded or repaired.
+// uploaded or repaired. Chunks of the files that are closest to being
+// unrecoverable come first, and within a file the least complete chunks come
+// first.
 //
 // TODO: When the file system is adjusted to have a tree structure, the
 // filesystem itself will serve as the uploadChunkHeap, making this structure
@@ -50,6 +53,9 @@ type uploadChunkHeap []*unfinishedUploadChunk
 // Implementation of heap.Interface for uploadChunkHeap.
 func (uch uploadChunkHeap) Len() int { return len(uch) }
 func (uch uploadChunkHeap) Less(i, j int) bool {
+	if uch[i].fileHealth != uch[j].fileHealth {
+		return uch[i].fileHealth < uch[j].fileHealth
+	}
 	return float64(uch[i].piecesCompleted)/float64(uch[i].piecesNeeded) < float64(uch[j].piecesCompleted)/float64(uch[j].piecesNeeded)
 }
 func (uch uploadChunkHeap) Swap(i, j int)       { uch[i], uch[j] = uch[j], uch[i] }
@@ -80,7 +86,7 @@ func (uh *uploadHeap) managedPush(uuc *unfinishedUploadChunk) {
 	_, exists := uh.activeChunks[ucid]
 	if !exists {
 		uh.activeChunks[ucid] = struct{}{}
-		uh.heap.Push(uuc)
+		heap.Push(&uh.heap, uuc)
 	}
 	uh.mu.Unlock()
 }
@@ -95,6 +101,18 @@ func (uh *uploadHeap) managedPop() (uc *unfinishedUploadChunk) {
 	return uc
 }
 
+// fileHealth returns the redundancy of the least redundant chunk out of the
+// chunks of a file.
+func fileHealth(chunks []*unfinishedUploadChunk) float64 {
+	health := math.Inf(1)
+	for _, uuc := range chunks {
+		if r := float64(uuc.piecesCompleted) / float64(uuc.minimumPieces); r < health {
+			health = r
+		}
+	}
+	return health
+}
+
 // buildUnfinishedChunks will pull all of the unfinished chunks out of a file.
 //
 // TODO / NOTE: This code can be substantially simplified once the files store
@@ -216,6 +234,14 @@ func (r *Renter) buildUnfinishedChunks(f *file, hosts map[string]struct{}) []*un
 		}
 	}
 
+	// The health of the file is the redundancy of its least redundant chunk.
+	// Every chunk is tagged with the health of its file so that the repair of
+	// the files closest to being unrecoverable is prioritized.
+	fileHealth := fileHealth(newUnfinishedChunks)
+	for _, uuc := range newUnfinishedChunks {
+		uuc.fileHealth = fileHealth
+	}
+
 	// Iterate through the set of newUnfinishedChunks and remove any that are
 	// completed.
 	incompleteChunks := newUnfinishedChunks[:0]
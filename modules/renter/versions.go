@@ -0,0 +1,213 @@
+package renter
+
+// versions.go implements file versioning. A version is an immutable snapshot
+// of a file's metadata, including the pieces that the file's chunks are made
+// of. Because the data on the hosts is addressed by Merkle root, versions that
+// share pieces with the current file or with each other share the storage of
+// those pieces.
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/persist"
+)
+
+const (
+	// versionsFilename is the file that the versions of the renter's files
+	// are persisted in.
+	versionsFilename = "versions.json"
+)
+
+var (
+	// ErrUnknownVersion is returned when a file has no version with the given
+	// id.
+	ErrUnknownVersion = errors.New("no version known with that id")
+
+	versionsMetadata = persist.Metadata{
+		Header:  "Renter File Versions",
+		Version: persistVersion133,
+	}
+)
+
+// A fileSnapshot is a version of a file.
+type fileSnapshot struct {
+	ID      string
+	Created time.Time
+	File    []byte // The file, encoded with MarshalSia.
+}
+
+// file decodes the file of a version.
+func (fv fileSnapshot) file() (*file, error) {
+	f := new(file)
+	err := encoding.Unmarshal(fv.File, f)
+	return f, err
+}
+
+// pieceRoots returns the Merkle roots of all the pieces of a file.
+func (f *file) pieceRoots() map[crypto.Hash]struct{} {
+	roots := make(map[crypto.Hash]struct{})
+	for _, fc := range f.contracts {
+		for _, p := range fc.Pieces {
+			roots[p.MerkleRoot] = struct{}{}
+		}
+	}
+	return roots
+}
+
+// saveVersions stores the versions of the renter's files to disk.
+func (r *Renter) saveVersions() error {
+	return persist.SaveJSON(versionsMetadata, r.versions, filepath.Join(r.persistDir, versionsFilename))
+}
+
+// loadVersions loads the versions of the renter's files from disk.
+func (r *Renter) loadVersions() error {
+	r.versions = make(map[string][]fileSnapshot)
+	err := persist.LoadJSON(versionsMetadata, &r.versions, filepath.Join(r.persistDir, versionsFilename))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// SnapshotFile records the current state of a file as a new version, returning
+// the id of the version. The id is derived from the contents of the snapshot,
+// so snapshotting an unchanged file returns the id of the existing version.
+func (r *Renter) SnapshotFile(siapath string) (string, error) {
+	lockID := r.mu.Lock()
+	defer r.mu.Unlock(lockID)
+	f, exists := r.files[siapath]
+	if !exists {
+		return "", ErrUnknownPath
+	}
+	f.mu.RLock()
+	data := encoding.Marshal(f)
+	f.mu.RUnlock()
+
+	id := crypto.HashBytes(data).String()
+	for _, fv := range r.versions[siapath] {
+		if fv.ID == id {
+			return id, nil
+		}
+	}
+	r.versions[siapath] = append(r.versions[siapath], fileSnapshot{
+		ID:      id,
+		Created: time.Now(),
+		File:    data,
+	})
+	if err := r.saveVersions(); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// ListVersions returns the versions of a file, oldest first. Versions are kept
+// after the file is deleted, until they are purged.
+func (r *Renter) ListVersions(siapath string) ([]modules.FileVersion, error) {
+	lockID := r.mu.RLock()
+	defer r.mu.RUnlock(lockID)
+	current, exists := r.files[siapath]
+	versions := r.versions[siapath]
+	if !exists && len(versions) == 0 {
+		return nil, ErrUnknownPath
+	}
+
+	// Count how many times each piece is referenced by the file and its
+	// versions, so that the storage that only a single version uses can be
+	// determined.
+	refs := make(map[crypto.Hash]int)
+	if exists {
+		current.mu.RLock()
+		for root := range current.pieceRoots() {
+			refs[root]++
+		}
+		current.mu.RUnlock()
+	}
+	files := make([]*file, len(versions))
+	for i, fv := range versions {
+		f, err := fv.file()
+		if err != nil {
+			return nil, err
+		}
+		files[i] = f
+		for root := range f.pieceRoots() {
+			refs[root]++
+		}
+	}
+
+	infos := make([]modules.FileVersion, 0, len(versions))
+	for i, fv := range versions {
+		var unique uint64
+		for root := range files[i].pieceRoots() {
+			if refs[root] == 1 {
+				unique += modules.SectorSize
+			}
+		}
+		infos = append(infos, modules.FileVersion{
+			ID:            fv.ID,
+			Created:       fv.Created,
+			Filesize:      files[i].size,
+			UploadedBytes: files[i].uploadedBytes(),
+			UniqueBytes:   unique,
+		})
+	}
+	return infos, nil
+}
+
+// RestoreVersion replaces a file with one of its versions. If the file has
+// been deleted, it is recreated.
+func (r *Renter) RestoreVersion(siapath string, versionID string) error {
+	lockID := r.mu.Lock()
+	var version *fileSnapshot
+	for i := range r.versions[siapath] {
+		if r.versions[siapath][i].ID == versionID {
+			version = &r.versions[siapath][i]
+		}
+	}
+	if version == nil {
+		r.mu.Unlock(lockID)
+		return ErrUnknownVersion
+	}
+	f, err := version.file()
+	if err != nil {
+		r.mu.Unlock(lockID)
+		return err
+	}
+	f.name = siapath
+
+	// Replace the current file, marking it as deleted so that it is no
+	// longer repaired.
+	if old, exists := r.files[siapath]; exists {
+		old.mu.Lock()
+		old.deleted = true
+		old.mu.Unlock()
+	}
+	r.files[siapath] = f
+	err = r.saveFile(f)
+	r.mu.Unlock(lockID)
+	if err != nil {
+		return err
+	}
+
+	// Drop any cached copy of the replaced file.
+	if err := r.staticDownloadCache.managedInvalidate(siapath); err != nil {
+		r.log.Println("WARN: couldn't remove file from download cache:", err)
+	}
+	return nil
+}
+
+// PurgeVersions removes all versions of a file.
+func (r *Renter) PurgeVersions(siapath string) error {
+	lockID := r.mu.Lock()
+	defer r.mu.Unlock(lockID)
+	if _, exists := r.versions[siapath]; !exists {
+		return nil
+	}
+	delete(r.versions, siapath)
+	return r.saveVersions()
+}
@@ -0,0 +1,126 @@
+package renter
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestFileVersions tests snapshotting, listing, restoring and purging the
+// versions of a file.
+func TestFileVersions(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	// Create a file with a single piece.
+	rsc, _ := NewRSCode(1, 1)
+	f := newFile("foo", rsc, modules.SectorSize, 100)
+	shared := pieceData{Chunk: 0, Piece: 0, MerkleRoot: crypto.Hash{1}}
+	f.contracts[types.FileContractID{1}] = fileContract{
+		ID:     types.FileContractID{1},
+		Pieces: []pieceData{shared},
+	}
+	rt.renter.files[f.name] = f
+
+	if _, err := rt.renter.SnapshotFile("bar"); err != ErrUnknownPath {
+		t.Fatal("expected ErrUnknownPath, got", err)
+	}
+	v1, err := rt.renter.SnapshotFile("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := rt.renter.SnapshotFile("foo"); err != nil || id != v1 {
+		t.Fatal("snapshotting an unchanged file should return the existing version", id, err)
+	}
+
+	// Change the file and snapshot it again.
+	f.contracts[types.FileContractID{2}] = fileContract{
+		ID:     types.FileContractID{2},
+		Pieces: []pieceData{{Chunk: 0, Piece: 1, MerkleRoot: crypto.Hash{2}}},
+	}
+	v2, err := rt.renter.SnapshotFile("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v2 == v1 {
+		t.Fatal("snapshot of a changed file has the same id")
+	}
+
+	// Change the file once more, so that the second piece is only referenced
+	// by the second version.
+	delete(f.contracts, types.FileContractID{2})
+	versions, err := rt.renter.ListVersions("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 || versions[0].ID != v1 || versions[1].ID != v2 {
+		t.Fatal("wrong versions listed:", versions)
+	}
+	if versions[0].UploadedBytes != modules.SectorSize || versions[0].UniqueBytes != 0 {
+		t.Error("wrong storage reported for the first version:", versions[0])
+	}
+	if versions[1].UploadedBytes != 2*modules.SectorSize || versions[1].UniqueBytes != modules.SectorSize {
+		t.Error("wrong storage reported for the second version:", versions[1])
+	}
+
+	// Restore the second version.
+	if err := rt.renter.RestoreVersion("foo", "bar"); err != ErrUnknownVersion {
+		t.Fatal("expected ErrUnknownVersion, got", err)
+	}
+	if err := rt.renter.RestoreVersion("foo", v2); err != nil {
+		t.Fatal(err)
+	}
+	if len(rt.renter.files["foo"].contracts) != 2 {
+		t.Fatal("restored file does not have the contracts of the version")
+	}
+	if !f.deleted {
+		t.Error("replaced file was not marked as deleted")
+	}
+
+	// Versions should be kept after the file is deleted, and persist across
+	// restarts.
+	if err := rt.renter.DeleteFile("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.renter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	rt.renter, err = New(rt.gateway, rt.cs, rt.wallet, rt.tpool, filepath.Join(rt.dir, modules.RenterDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions, err = rt.renter.ListVersions("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 {
+		t.Fatal("versions were not persisted")
+	}
+	if err := rt.renter.RestoreVersion("foo", v1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rt.renter.File("foo"); err != nil {
+		t.Fatal("deleted file was not recreated:", err)
+	}
+
+	// Purge the versions.
+	if err := rt.renter.PurgeVersions("foo"); err != nil {
+		t.Fatal(err)
+	}
+	versions, err = rt.renter.ListVersions("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 0 {
+		t.Fatal("versions were not purged")
+	}
+}
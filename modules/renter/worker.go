@@ -22,6 +22,12 @@ import (
 // uploading and downloading with flaky hosts in the worker sets has
 // substantially reduced overall performance and throughput.
 type worker struct {
+	// atomicDownloadThroughput is a moving average of the worker's download
+	// throughput in bytes per second. It is zero until the worker has
+	// completed a download. It is placed at the top of the struct to
+	// guarantee 64-bit alignment for atomic operations.
+	atomicDownloadThroughput uint64
+
 	// The contract and host used by this worker.
 	contract   modules.RenterContract
 	hostPubKey types.SiaPublicKey
@@ -98,6 +104,16 @@ func (r *Renter) managedUpdateWorkerPool() {
 	r.mu.Unlock(lockID)
 }
 
+// managedRecordHostFailure reports a failed interaction with the worker's host
+// to the contractor, which blacklists hosts that fail repeatedly. Failures are
+// only reported while the gateway is online, as it is not the host's fault if
+// the renter is offline.
+func (w *worker) managedRecordHostFailure() {
+	if w.renter.g.Online() {
+		w.renter.hostContractor.RecordHostFailure(w.hostPubKey)
+	}
+}
+
 // threadedWorkLoop repeatedly issues work to a worker, stopping when the worker
 // is killed or when the thread group is closed.
 func (w *worker) threadedWorkLoop() {
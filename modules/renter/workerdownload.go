@@ -5,6 +5,7 @@ package renter
 // coordinating resource management between the workers operating on a chunk.
 
 import (
+	"sort"
 	"sync/atomic"
 	"time"
 )
@@ -34,12 +35,16 @@ func (w *worker) managedDownload(udc *unfinishedDownloadChunk) {
 		return
 	}
 	defer d.Close()
+	start := time.Now()
 	data, err := d.Sector(udc.staticChunkMap[w.contract.ID].root)
 	if err != nil {
 		w.renter.log.Debugln("worker failed to download sector:", err)
+		w.managedRecordHostFailure()
 		udc.managedUnregisterWorker(w)
 		return
 	}
+	w.renter.hostContractor.RecordHostSuccess(w.hostPubKey)
+	w.ownedUpdateDownloadThroughput(udc.staticPieceSize, time.Since(start))
 	// TODO: Instead of adding the whole sector after the download completes,
 	// have the 'd.Sector' call add to this value ongoing as the sector comes
 	// in. Perhaps even include the data from creating the downloader and other
@@ -121,6 +126,14 @@ func (w *worker) managedQueueDownloadChunk(udc *unfinishedDownloadChunk) {
 	}
 }
 
+// sortWorkersByThroughput sorts workers by their measured download throughput,
+// fastest first. Workers without a measurement are placed last.
+func sortWorkersByThroughput(workers []*worker) {
+	sort.SliceStable(workers, func(i, j int) bool {
+		return atomic.LoadUint64(&workers[i].atomicDownloadThroughput) > atomic.LoadUint64(&workers[j].atomicDownloadThroughput)
+	})
+}
+
 // managedUnregisterWorker will remove the worker from an unfinished download
 // chunk, and then un-register the pieces that it grabbed. This function should
 // only be called when a worker download fails.
@@ -131,6 +144,21 @@ func (udc *unfinishedDownloadChunk) managedUnregisterWorker(w *worker) {
 	udc.mu.Unlock()
 }
 
+// ownedUpdateDownloadThroughput updates the moving average of the worker's
+// download throughput after downloading 'size' bytes in 'elapsed' time. This
+// function should only be called by the master worker thread.
+func (w *worker) ownedUpdateDownloadThroughput(size uint64, elapsed time.Duration) {
+	if elapsed <= 0 {
+		elapsed = time.Nanosecond
+	}
+	sample := uint64(float64(size) / elapsed.Seconds())
+	old := atomic.LoadUint64(&w.atomicDownloadThroughput)
+	if old != 0 {
+		sample = (old*3 + sample) / 4
+	}
+	atomic.StoreUint64(&w.atomicDownloadThroughput, sample)
+}
+
 // ownedOnDownloadCooldown returns true if the worker is on cooldown from failed
 // downloads. This function should only be called by the master worker thread,
 // and does not require any mutexes.
@@ -188,7 +216,16 @@ func (w *worker) ownedProcessDownloadChunk(udc *unfinishedDownloadChunk) *unfini
 	// metrics, so that we can avoid holding the worker lock and the udc lock
 	// simultaneously (deadlock risk). The 'owned' variables of the worker are
 	// variables that are only accessed by the master worker thread.
+	//
+	// Workers that are much slower than the fastest worker for this chunk are
+	// put on standby, so that the fastest hosts are used first. Once standby
+	// workers have been called upon, the criteria are relaxed so that the
+	// slow workers are not put back on standby.
 	meetsExtraCriteria := true
+	throughput := atomic.LoadUint64(&w.atomicDownloadThroughput)
+	if !udc.standbyReleased && throughput > 0 && throughput < udc.fastestThroughput/uint64(slowWorkerThroughputDivisor) {
+		meetsExtraCriteria = false
+	}
 
 	// TODO: There's going to need to be some method for relaxing criteria after
 	// the first wave of workers are sent off. If the first waves of workers
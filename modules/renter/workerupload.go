@@ -111,12 +111,14 @@ func (w *worker) managedUpload(uc *unfinishedUploadChunk, pieceIndex uint64) {
 	root, err := e.Upload(uc.physicalChunkData[pieceIndex])
 	if err != nil {
 		w.renter.log.Debugln("Worker failed to upload via the editor:", err)
+		w.managedRecordHostFailure()
 		w.managedUploadFailed(uc, pieceIndex)
 		return
 	}
 	w.mu.Lock()
 	w.uploadConsecutiveFailures = 0
 	w.mu.Unlock()
+	w.renter.hostContractor.RecordHostSuccess(w.hostPubKey)
 
 	// Update the renter metadata.
 	addr := e.Address()
@@ -19,6 +19,28 @@ const (
 	TransactionSizeLimit = 32e3
 )
 
+const (
+	// TxTypeSiacoinSend is a transaction that only moves siacoins.
+	TxTypeSiacoinSend TxType = iota
+
+	// TxTypeFileContract is a transaction that forms file contracts.
+	TxTypeFileContract
+
+	// TxTypeFileContractRevision is a transaction that revises file
+	// contracts.
+	TxTypeFileContractRevision
+
+	// TxTypeStorageProof is a transaction that submits storage proofs.
+	TxTypeStorageProof
+
+	// TxTypeSiafund is a transaction that moves siafunds.
+	TxTypeSiafund
+
+	// TxTypeOther is a transaction that only carries arbitrary data, such as
+	// a host announcement, or that is otherwise empty.
+	TxTypeOther
+)
+
 var (
 	// ErrDuplicateTransactionSet is the error that gets returned if a
 	// duplicate transaction set is given to the transaction pool.
@@ -53,6 +75,24 @@ var (
 )
 
 type (
+	// A TxType classifies a transaction by what it does, see
+	// ClassifyTransaction.
+	TxType int
+
+	// FeeStats summarizes the fees paid by a group of transactions in the
+	// transaction pool.
+	FeeStats struct {
+		Transactions int            `json:"transactions"`
+		Size         uint64         `json:"size"`
+		Fees         types.Currency `json:"fees"`
+
+		// The fees per byte of the transactions. Transactions that pay no
+		// fees of their own, such as the parents of a set, are included.
+		MinFeePerByte    types.Currency `json:"minfeeperbyte"`
+		MedianFeePerByte types.Currency `json:"medianfeeperbyte"`
+		MaxFeePerByte    types.Currency `json:"maxfeeperbyte"`
+	}
+
 	// ConsensusConflict implements the error interface, and indicates that a
 	// transaction was rejected due to being incompatible with the current
 	// consensus set, meaning either a double spend or a consensus rule violation -
@@ -83,6 +123,27 @@ type (
 		Sizes        []uint64
 		Transactions []types.Transaction
 	}
+
+	// A DoubleSpendEvent is sent to the subscribers of SubscribeDoubleSpend
+	// when a block confirms a transaction that spends an output which is
+	// also spent by a different transaction in the transaction pool.
+	DoubleSpendEvent struct {
+		OutputID             types.OutputID
+		ConfirmedTransaction types.TransactionID
+		PoolTransaction      types.TransactionID
+
+		// BlockID identifies the block that confirmed ConfirmedTransaction.
+		BlockID types.BlockID
+	}
+
+	// TransactionSummary contains the ID, encoded size, and total miner fee
+	// of a transaction, allowing callers to inspect a transaction before it
+	// is submitted to the transaction pool.
+	TransactionSummary struct {
+		ID   types.TransactionID `json:"id"`
+		Size uint64              `json:"size"`
+		Fee  types.Currency      `json:"fee"`
+	}
 )
 
 type (
@@ -117,6 +178,11 @@ type (
 		// within 10 blocks.
 		FeeEstimation() (minimumRecommended, maximumRecommended types.Currency)
 
+		// FeeStatsByType returns statistics about the fees paid by the
+		// transactions in the pool, broken down by transaction type. Types
+		// without transactions in the pool are left out.
+		FeeStatsByType() map[TxType]FeeStats
+
 		// PurgeTransactionPool is a temporary function available to the miner. In
 		// the event that a miner mines an unacceptable block, the transaction pool
 		// will be purged to clear out the transaction pool and get rid of the
@@ -124,6 +190,33 @@ type (
 		// that make this condition necessary.
 		PurgeTransactionPool()
 
+		// ReplaceTransactionSet removes the transaction set containing the
+		// given transaction and accepts the provided set in its place. The
+		// replacement must pay more fees than the set that it replaces. If the
+		// replacement is rejected, the pool is left unchanged.
+		ReplaceTransactionSet(replaced types.TransactionID, ts []types.Transaction) error
+
+		// SetMinRelayFee sets the minimum fee per byte that a transaction
+		// set must pay to be accepted into the pool and relayed. Sets below
+		// the minimum are rejected, and sets already in the pool that fall
+		// below it are removed. A minimum of zero disables the check.
+		SetMinRelayFee(perByte types.Currency)
+
+		// SetPersistence sets whether the unconfirmed transactions are saved
+		// to disk and reloaded, after being validated again, when the node
+		// restarts. Persistence is enabled by default.
+		SetPersistence(enabled bool)
+
+		// SetTransactionExpiry sets the number of blocks that a transaction
+		// may spend in the pool without being confirmed. Expired
+		// transactions are dropped, freeing their inputs for reuse.
+		SetTransactionExpiry(blocks types.BlockHeight)
+
+		// SubscribeDoubleSpend registers a channel that receives an event
+		// whenever a block spends an output that is also spent by a
+		// different transaction in the pool.
+		SubscribeDoubleSpend(ch chan<- DoubleSpendEvent)
+
 		// Transaction returns the transaction and unconfirmed parents
 		// corresponding to the provided transaction id.
 		Transaction(id types.TransactionID) (txn types.Transaction, unconfirmedParents []types.Transaction, exists bool)
@@ -176,3 +269,57 @@ func CalculateFee(ts []types.Transaction) types.Currency {
 	size := len(encoding.Marshal(ts))
 	return sum.Div64(uint64(size))
 }
+
+// ClassifyTransaction returns the type of a transaction. A transaction that
+// does several things is classified by the first of storage proofs, file
+// contracts, file contract revisions, siafunds and siacoins that it contains.
+func ClassifyTransaction(t types.Transaction) TxType {
+	switch {
+	case len(t.StorageProofs) > 0:
+		return TxTypeStorageProof
+	case len(t.FileContracts) > 0:
+		return TxTypeFileContract
+	case len(t.FileContractRevisions) > 0:
+		return TxTypeFileContractRevision
+	case len(t.SiafundInputs) > 0 || len(t.SiafundOutputs) > 0:
+		return TxTypeSiafund
+	case len(t.SiacoinInputs) > 0 || len(t.SiacoinOutputs) > 0:
+		return TxTypeSiacoinSend
+	default:
+		return TxTypeOther
+	}
+}
+
+// String returns the name of a transaction type.
+func (t TxType) String() string {
+	switch t {
+	case TxTypeSiacoinSend:
+		return "siacoin send"
+	case TxTypeFileContract:
+		return "file contract"
+	case TxTypeFileContractRevision:
+		return "file contract revision"
+	case TxTypeStorageProof:
+		return "storage proof"
+	case TxTypeSiafund:
+		return "siafund"
+	case TxTypeOther:
+		return "other"
+	default:
+		return "unknown"
+	}
+}
+
+// SummarizeTransaction returns the ID, encoded size in bytes, and total miner
+// fee of a transaction.
+func SummarizeTransaction(t types.Transaction) TransactionSummary {
+	var fee types.Currency
+	for _, mf := range t.MinerFees {
+		fee = fee.Add(mf)
+	}
+	return TransactionSummary{
+		ID:   t.ID(),
+		Size: uint64(t.MarshalSiaSize()),
+		Fee:  fee,
+	}
+}
@@ -21,6 +21,8 @@ var (
 	errFullTransactionPool = errors.New("transaction pool cannot accept more transactions")
 	errLowMinerFees        = errors.New("transaction set needs more miner fees to be accepted")
 	errObjectConflict      = errors.New("transaction set conflicts with an existing transaction set")
+	errReplacementFees     = errors.New("replacement transaction set must pay more fees than the set it replaces")
+	errReplacementUnknown  = errors.New("transaction to be replaced is not in the transaction pool")
 )
 
 // relatedObjectIDs determines all of the object ids related to a transaction.
@@ -203,6 +205,7 @@ func (tp *TransactionPool) handleConflicts(ts []types.Transaction, conflicts []T
 	// Add the transaction set to the pool.
 	setID := TransactionSetID(crypto.HashObject(superset))
 	tp.transactionSets[setID] = superset
+	tp.classifyTransactions(superset)
 	for _, diff := range cc.SiacoinOutputDiffs {
 		tp.knownObjects[ObjectID(diff.ID)] = setID
 	}
@@ -254,6 +257,11 @@ func (tp *TransactionPool) acceptTransactionSet(ts []types.Transaction, txnFn fu
 		return err
 	}
 
+	// Check that the transaction set pays at least the minimum relay fee.
+	if tp.belowMinRelayFee(ts, setSize) {
+		return errBelowMinRelayFee
+	}
+
 	// Check that the transaction set has enough fees to justify adding it to
 	// the transaction list.
 	requiredFees := tp.requiredFeesToExtendTpool().Mul64(setSize)
@@ -294,6 +302,7 @@ func (tp *TransactionPool) acceptTransactionSet(ts []types.Transaction, txnFn fu
 	// Add the transaction set to the pool.
 	setID := TransactionSetID(crypto.HashObject(ts))
 	tp.transactionSets[setID] = ts
+	tp.classifyTransactions(ts)
 	for _, oid := range oids {
 		tp.knownObjects[oid] = setID
 	}
@@ -348,6 +357,170 @@ func (tp *TransactionPool) AcceptTransactionSet(ts []types.Transaction) error {
 	})
 }
 
+// replacedTransactions returns the transactions of 'set' that are evicted when
+// the transaction 'replaced' is replaced: the transaction itself, and every
+// transaction that depends on it. The other transactions of the set may have
+// been merged into it by handleConflicts, and are unrelated to the
+// replacement.
+func replacedTransactions(set []types.Transaction, replaced types.TransactionID) map[types.TransactionID]struct{} {
+	evicted := make(map[types.TransactionID]struct{})
+	evictedObjects := make(map[ObjectID]struct{})
+	for _, txn := range set {
+		depends := txn.ID() == replaced
+		for _, oid := range relatedObjectIDs([]types.Transaction{txn}) {
+			if _, exists := evictedObjects[oid]; exists {
+				depends = true
+				break
+			}
+		}
+		if !depends {
+			continue
+		}
+		evicted[txn.ID()] = struct{}{}
+		for _, oid := range relatedObjectIDs([]types.Transaction{txn}) {
+			evictedObjects[oid] = struct{}{}
+		}
+	}
+	return evicted
+}
+
+// replaceTransactionSet removes the transaction set containing the transaction
+// 'replaced' from the pool and accepts 'ts' in its place. If 'ts' is not
+// accepted, the removed set is restored. If the removed set also contained
+// transactions that do not depend on 'replaced', they are added back to the
+// pool after 'ts' has been accepted.
+func (tp *TransactionPool) replaceTransactionSet(replaced types.TransactionID, ts []types.Transaction, txnFn func([]types.Transaction) (modules.ConsensusChange, error)) error {
+	// Find the set containing the replaced transaction.
+	var oldID TransactionSetID
+	var oldSet []types.Transaction
+	for id, set := range tp.transactionSets {
+		for _, txn := range set {
+			if txn.ID() == replaced {
+				oldID, oldSet = id, set
+			}
+		}
+	}
+	if oldSet == nil {
+		return errReplacementUnknown
+	}
+	evicted := replacedTransactions(oldSet, replaced)
+
+	// The replacement must pay more fees than the transactions it evicts,
+	// otherwise replacements could be used to relay transactions for free.
+	var oldFees, newFees types.Currency
+	for _, txn := range oldSet {
+		if _, exists := evicted[txn.ID()]; !exists {
+			continue
+		}
+		for _, fee := range txn.MinerFees {
+			oldFees = oldFees.Add(fee)
+		}
+	}
+	for _, txn := range ts {
+		for _, fee := range txn.MinerFees {
+			newFees = newFees.Add(fee)
+		}
+	}
+	if newFees.Cmp(oldFees) <= 0 {
+		return errReplacementFees
+	}
+
+	// Remove the old set from the pool, remembering everything that is
+	// needed to add it back.
+	oldDiff := tp.transactionSetDiffs[oldID]
+	var oldObjects []ObjectID
+	for oid, setID := range tp.knownObjects {
+		if setID == oldID {
+			oldObjects = append(oldObjects, oid)
+		}
+	}
+	oldHeights := make(map[types.TransactionID]types.BlockHeight)
+	for _, txn := range oldSet {
+		if height, exists := tp.transactionHeights[txn.ID()]; exists {
+			oldHeights[txn.ID()] = height
+		}
+	}
+	tp.removeTransactionSet(oldID)
+
+	// Add the replacement, adding the old set back if it is rejected.
+	err := tp.acceptTransactionSet(ts, txnFn)
+	if err != nil {
+		for _, oid := range oldObjects {
+			tp.knownObjects[oid] = oldID
+		}
+		for txid, height := range oldHeights {
+			tp.transactionHeights[txid] = height
+		}
+		tp.transactionSets[oldID] = oldSet
+		tp.transactionSetDiffs[oldID] = oldDiff
+		tp.transactionListSize += len(encoding.Marshal(oldSet))
+		tp.classifyTransactions(oldSet)
+		return err
+	}
+	tp.log.Debugf("replaced transaction set %v, which contained transaction %v\n", oldID, replaced)
+
+	// Add back the transactions of the old set that were merged into it but
+	// do not depend on the replaced transaction. The transactions that are
+	// part of the replacement are already in the pool again.
+	inReplacement := make(map[types.TransactionID]struct{})
+	for _, txn := range ts {
+		inReplacement[txn.ID()] = struct{}{}
+	}
+	var unrelated []types.Transaction
+	for _, txn := range oldSet {
+		_, isEvicted := evicted[txn.ID()]
+		_, isReplacement := inReplacement[txn.ID()]
+		if !isEvicted && !isReplacement {
+			unrelated = append(unrelated, txn)
+		}
+	}
+	if len(unrelated) > 0 {
+		err := tp.acceptTransactionSet(unrelated, txnFn)
+		if err != nil && err != modules.ErrDuplicateTransactionSet {
+			tp.log.Debugf("dropped %v transactions of replaced set %v: %v\n", len(unrelated), oldID, err)
+		}
+		// Keep the age of the transactions that were added back, so that
+		// they still expire on time.
+		for _, txn := range unrelated {
+			_, inPool := tp.transactionHeights[txn.ID()]
+			if height, exists := oldHeights[txn.ID()]; exists && inPool {
+				tp.transactionHeights[txn.ID()] = height
+			}
+		}
+	}
+	return nil
+}
+
+// ReplaceTransactionSet removes the transaction set containing the transaction
+// 'replaced' from the pool and adds 'ts' in its place. The replacement must
+// pay more fees than the set it replaces. If the replacement is accepted, it
+// will be relayed to connected peers.
+//
+// NOTE: peers that have already seen the replaced set will reject the
+// replacement as a double spend.
+func (tp *TransactionPool) ReplaceTransactionSet(replaced types.TransactionID, ts []types.Transaction) error {
+	// assert on consensus set to get special method
+	cs, ok := tp.consensusSet.(interface {
+		LockedTryTransactionSet(fn func(func(txns []types.Transaction) (modules.ConsensusChange, error)) error) error
+	})
+	if !ok {
+		return errors.New("consensus set does not support LockedTryTransactionSet method")
+	}
+
+	return cs.LockedTryTransactionSet(func(txnFn func(txns []types.Transaction) (modules.ConsensusChange, error)) error {
+		tp.mu.Lock()
+		defer tp.mu.Unlock()
+		err := tp.replaceTransactionSet(replaced, ts, txnFn)
+		if err != nil {
+			tp.log.Debugln("Transaction set replacement has failed:", err)
+			return err
+		}
+		go tp.gateway.Broadcast("RelayTransactionSet", ts, tp.gateway.Peers())
+		tp.updateSubscribersTransactions()
+		return nil
+	})
+}
+
 // relayTransactionSet is an RPC that accepts a transaction set from a peer. If
 // the accept is successful, the transaction will be relayed to the gateway's
 // other peers.
@@ -372,7 +545,7 @@ func (tp *TransactionPool) relayTransactionSet(conn modules.PeerConn) error {
 	}()
 
 	var ts []types.Transaction
-	err = encoding.ReadObject(conn, &ts, types.BlockSizeLimit)
+	err = encoding.ReadObject(conn, &ts, modules.TransactionSetSizeLimit)
 	if err != nil {
 		return err
 	}
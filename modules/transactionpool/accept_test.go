@@ -120,6 +120,199 @@ func TestConflictingTransactionSets(t *testing.T) {
 	}
 }
 
+// TestReplaceTransactionSet checks that a transaction set can be replaced by a
+// conflicting set that pays higher fees.
+func TestReplaceTransactionSet(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	// Create two transaction sets that spend the same output, one of which
+	// pays a higher fee.
+	fund := types.NewCurrency64(30e6)
+	txnBuilder, err := tpt.wallet.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = txnBuilder.FundSiacoins(fund)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lowFeeSet, err := txnBuilder.Sign(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	highFeeSet := make([]types.Transaction, len(lowFeeSet))
+	copy(highFeeSet, lowFeeSet)
+	txnIndex := len(lowFeeSet) - 1
+	lowFee := types.NewCurrency64(10)
+	lowFeeSet[txnIndex].MinerFees = append(lowFeeSet[txnIndex].MinerFees, lowFee)
+	lowFeeSet[txnIndex].SiacoinOutputs = append(lowFeeSet[txnIndex].SiacoinOutputs, types.SiacoinOutput{Value: fund.Sub(lowFee)})
+	highFeeSet[txnIndex].MinerFees = append(highFeeSet[txnIndex].MinerFees, fund)
+	lowID, highID := lowFeeSet[txnIndex].ID(), highFeeSet[txnIndex].ID()
+
+	err = tpt.tpool.AcceptTransactionSet(lowFeeSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tpt.tpool.ReplaceTransactionSet(types.TransactionID{}, highFeeSet)
+	if err != errReplacementUnknown {
+		t.Fatal("expected errReplacementUnknown, got", err)
+	}
+	err = tpt.tpool.ReplaceTransactionSet(lowID, highFeeSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, exists := tpt.tpool.Transaction(lowID); exists {
+		t.Error("replaced transaction is still in the pool")
+	}
+	if _, _, exists := tpt.tpool.Transaction(highID); !exists {
+		t.Error("replacement is not in the pool")
+	}
+	tpt.tpool.mu.Lock()
+	_, heightExists := tpt.tpool.transactionHeights[lowID]
+	_, typeExists := tpt.tpool.transactionTypes[lowID]
+	highHeight := tpt.tpool.transactionHeights[highID]
+	tpt.tpool.mu.Unlock()
+	if heightExists || typeExists {
+		t.Error("replaced transaction is still tracked by the pool")
+	}
+
+	// Replacing the set with a lower fee set should fail and leave the pool
+	// unchanged.
+	err = tpt.tpool.ReplaceTransactionSet(highID, lowFeeSet)
+	if err != errReplacementFees {
+		t.Fatal("expected errReplacementFees, got", err)
+	}
+	if _, _, exists := tpt.tpool.Transaction(highID); !exists {
+		t.Error("failed replacement removed the original set")
+	}
+
+	// So should an invalid replacement, which is only rejected after the
+	// original set has been removed.
+	invalidSet := make([]types.Transaction, len(highFeeSet))
+	copy(invalidSet, highFeeSet)
+	invalidSet[txnIndex].MinerFees = []types.Currency{fund.Add(fund)}
+	err = tpt.tpool.ReplaceTransactionSet(highID, invalidSet)
+	if err == nil {
+		t.Fatal("invalid replacement was accepted")
+	}
+	if _, _, exists := tpt.tpool.Transaction(highID); !exists {
+		t.Error("failed replacement removed the original set")
+	}
+	tpt.tpool.mu.Lock()
+	height, heightExists := tpt.tpool.transactionHeights[highID]
+	_, typeExists = tpt.tpool.transactionTypes[highID]
+	tpt.tpool.mu.Unlock()
+	if !heightExists || height != highHeight || !typeExists {
+		t.Error("failed replacement did not restore the original set")
+	}
+}
+
+// TestReplaceMergedTransactionSet checks that replacing a transaction whose
+// set was merged with unrelated sets only evicts the transaction and its
+// dependents.
+func TestReplaceMergedTransactionSet(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+	// Mine another block so that the wallet can fund two sets.
+	if _, err := tpt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create two independent sets, each ending in a transaction with an
+	// output that anyone can spend.
+	fund := types.NewCurrency64(30e6)
+	fee := types.NewCurrency64(10)
+	fundedSet := func() []types.Transaction {
+		txnBuilder, err := tpt.wallet.StartTransaction()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := txnBuilder.FundSiacoins(fund); err != nil {
+			t.Fatal(err)
+		}
+		set, err := txnBuilder.Sign(false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return set
+	}
+	spendable := func(set []types.Transaction) ([]types.Transaction, types.SiacoinOutputID) {
+		set = append([]types.Transaction(nil), set...)
+		txn := &set[len(set)-1]
+		txn.MinerFees = append(txn.MinerFees, fee)
+		txn.SiacoinOutputs = append(txn.SiacoinOutputs, types.SiacoinOutput{
+			Value:      fund.Sub(fee),
+			UnlockHash: types.UnlockConditions{}.UnlockHash(),
+		})
+		return set, txn.SiacoinOutputID(uint64(len(txn.SiacoinOutputs) - 1))
+	}
+	base := fundedSet()
+	replacedSet, replacedOutput := spendable(base)
+	unrelatedSet, unrelatedOutput := spendable(fundedSet())
+	replacedID := replacedSet[len(replacedSet)-1].ID()
+	unrelatedID := unrelatedSet[len(unrelatedSet)-1].ID()
+	if err := tpt.tpool.AcceptTransactionSet(replacedSet); err != nil {
+		t.Fatal(err)
+	}
+	if err := tpt.tpool.AcceptTransactionSet(unrelatedSet); err != nil {
+		t.Fatal(err)
+	}
+
+	// Spend both outputs in one transaction, which merges the two sets.
+	child := types.Transaction{
+		SiacoinInputs: []types.SiacoinInput{
+			{ParentID: replacedOutput},
+			{ParentID: unrelatedOutput},
+		},
+		SiacoinOutputs: []types.SiacoinOutput{{
+			Value:      fund.Sub(fee).Mul64(2),
+			UnlockHash: types.UnlockConditions{}.UnlockHash(),
+		}},
+	}
+	if err := tpt.tpool.AcceptTransactionSet([]types.Transaction{child}); err != nil {
+		t.Fatal(err)
+	}
+	tpt.tpool.mu.Lock()
+	numSets := len(tpt.tpool.transactionSets)
+	tpt.tpool.mu.Unlock()
+	if numSets != 1 {
+		t.Fatal("expected the sets to be merged, got", numSets, "sets")
+	}
+
+	// Replace the first transaction. The child depends on it and is evicted,
+	// the unrelated set stays in the pool.
+	replacement := append([]types.Transaction(nil), base...)
+	replacement[len(replacement)-1].MinerFees = []types.Currency{fund}
+	if err := tpt.tpool.ReplaceTransactionSet(replacedID, replacement); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, exists := tpt.tpool.Transaction(replacedID); exists {
+		t.Error("replaced transaction is still in the pool")
+	}
+	if _, _, exists := tpt.tpool.Transaction(child.ID()); exists {
+		t.Error("dependent of the replaced transaction is still in the pool")
+	}
+	if _, _, exists := tpt.tpool.Transaction(replacement[len(replacement)-1].ID()); !exists {
+		t.Error("replacement is not in the pool")
+	}
+	if _, _, exists := tpt.tpool.Transaction(unrelatedID); !exists {
+		t.Error("unrelated transaction was dropped by the replacement")
+	}
+}
+
 // TestCheckMinerFees probes the checkMinerFees method of the
 // transaction pool.
 func TestCheckMinerFees(t *testing.T) {
@@ -776,3 +969,80 @@ func TestPartialConfirmationWeave(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// TestMinRelayFee checks that transaction sets paying less than the minimum
+// relay fee are rejected, and that raising the minimum evicts sets from the
+// pool and from the miner's block template.
+func TestMinRelayFee(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	// Create and confirm an output that TransactionGraph can spend.
+	txns, err := tpt.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(100), types.UnlockConditions{}.UnlockHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tpt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	sourceID := txns[len(txns)-1].SiacoinOutputID(0)
+	graphTxns := func(fee types.Currency) []types.Transaction {
+		edge := types.TransactionGraphEdge{
+			Dest:   1,
+			Fee:    fee,
+			Source: 0,
+			Value:  types.SiacoinPrecision.Mul64(100).Sub(fee),
+		}
+		txns, err := types.TransactionGraph(sourceID, []types.TransactionGraphEdge{edge})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return txns
+	}
+
+	// A set below the minimum relay fee should be rejected.
+	tpt.tpool.SetMinRelayFee(types.SiacoinPrecision.Div64(1e3))
+	if err := tpt.tpool.AcceptTransactionSet(graphTxns(types.NewCurrency64(1))); err != errBelowMinRelayFee {
+		t.Fatal("expected errBelowMinRelayFee, got", err)
+	}
+	if min, _ := tpt.tpool.FeeEstimation(); min.Cmp(types.SiacoinPrecision.Div64(1e3)) < 0 {
+		t.Error("fee estimation is below the minimum relay fee")
+	}
+
+	// A set above the minimum should be accepted and included in the miner's
+	// block template.
+	set := graphTxns(types.SiacoinPrecision.Mul64(10))
+	if err := tpt.tpool.AcceptTransactionSet(set); err != nil {
+		t.Fatal(err)
+	}
+	inTemplate := func() bool {
+		b, _, err := tpt.miner.BlockForWork()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, txn := range b.Transactions {
+			if txn.ID() == set[0].ID() {
+				return true
+			}
+		}
+		return false
+	}
+	if !inTemplate() {
+		t.Fatal("transaction set is not in the block template")
+	}
+
+	// Raising the minimum above the fee of the set should evict it.
+	tpt.tpool.SetMinRelayFee(types.SiacoinPrecision)
+	if _, _, exists := tpt.tpool.Transaction(set[0].ID()); exists {
+		t.Error("set below the new minimum relay fee is still in the pool")
+	}
+	if inTemplate() {
+		t.Error("set below the new minimum relay fee is still in the block template")
+	}
+}
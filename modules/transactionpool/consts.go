@@ -16,8 +16,9 @@ const (
 
 // Constants related to the size and ease-of-entry of the transaction pool.
 const (
-	// maxTxnAge determines the maximum age of a transaction (in block height)
-	// allowed before the transaction is pruned from the transaction pool.
+	// maxTxnAge determines the default maximum age of a transaction (in block
+	// height) allowed before the transaction is pruned from the transaction
+	// pool.
 	maxTxnAge = types.BlockHeight(24)
 
 	// TransactionPoolFeeExponentiation defines the polynomial rate of growth
@@ -1,6 +1,7 @@
 package transactionpool
 
 import (
+	"bytes"
 	"encoding/json"
 
 	"github.com/NebulousLabs/Sia/build"
@@ -33,6 +34,14 @@ var (
 	// bucketRecentConsensusChange holds the most recent consensus change seen
 	// by the transaction pool.
 	bucketRecentConsensusChange = []byte("RecentConsensusChange")
+
+	// bucketSettings holds the settings of the transaction pool that persist
+	// across restarts.
+	bucketSettings = []byte("Settings")
+
+	// bucketTransactionSets holds the unconfirmed transaction sets of the
+	// pool, so that they can be reloaded after a restart.
+	bucketTransactionSets = []byte("TransactionSets")
 )
 
 // Explicitly named fields in the database.
@@ -52,6 +61,10 @@ var (
 	// fieldRecentConsensusChange is the field in bucketRecentConsensusChange
 	// that holds the value of the most recent consensus change.
 	fieldRecentConsensusChange = []byte("RecentConsensusChange")
+
+	// fieldPersistTransactionSets is the field in bucketSettings that
+	// indicates whether the unconfirmed transaction sets are persisted.
+	fieldPersistTransactionSets = []byte("PersistTransactionSets")
 )
 
 // Errors relating to the database.
@@ -106,6 +119,12 @@ func (tp *TransactionPool) getFeeMedian(tx *bolt.Tx) (medianPersist, error) {
 	return mp, nil
 }
 
+// getPersistTransactionSets returns whether the unconfirmed transaction sets
+// are persisted. Persistence is enabled unless it was disabled.
+func (tp *TransactionPool) getPersistTransactionSets(tx *bolt.Tx) bool {
+	return !bytes.Equal(tx.Bucket(bucketSettings).Get(fieldPersistTransactionSets), []byte{0})
+}
+
 // getRecentBlockID will fetch the most recent block id and most recent parent
 // id from the database.
 func (tp *TransactionPool) getRecentBlockID(tx *bolt.Tx) (recentID types.BlockID, err error) {
@@ -131,6 +150,19 @@ func (tp *TransactionPool) getRecentConsensusChange(tx *bolt.Tx) (cc modules.Con
 	return cc, nil
 }
 
+// getTransactionSets returns the transaction sets stored in the database.
+func (tp *TransactionPool) getTransactionSets(tx *bolt.Tx) (sets [][]types.Transaction, err error) {
+	err = tx.Bucket(bucketTransactionSets).ForEach(func(_, setBytes []byte) error {
+		var set []types.Transaction
+		if err := encoding.Unmarshal(setBytes, &set); err != nil {
+			return err
+		}
+		sets = append(sets, set)
+		return nil
+	})
+	return sets, err
+}
+
 // putBlockHeight updates the transaction pool's block height.
 func (tp *TransactionPool) putBlockHeight(tx *bolt.Tx, height types.BlockHeight) error {
 	tp.blockHeight = height
@@ -158,7 +190,51 @@ func (tp *TransactionPool) putRecentConsensusChange(tx *bolt.Tx, cc modules.Cons
 	return tx.Bucket(bucketRecentConsensusChange).Put(fieldRecentConsensusChange, cc[:])
 }
 
+// putPersistTransactionSets sets whether the unconfirmed transaction sets
+// are persisted.
+func (tp *TransactionPool) putPersistTransactionSets(tx *bolt.Tx, enabled bool) error {
+	value := []byte{0}
+	if enabled {
+		value = []byte{1}
+	}
+	return tx.Bucket(bucketSettings).Put(fieldPersistTransactionSets, value)
+}
+
 // putTransaction adds a transaction to the list of confirmed transactions.
 func (tp *TransactionPool) putTransaction(tx *bolt.Tx, id types.TransactionID) error {
 	return tx.Bucket(bucketConfirmedTransactions).Put(id[:], []byte{})
 }
+
+// putTransactionSets replaces the transaction sets stored in the database.
+// The bucket is updated in place: sets that are no longer in the pool are
+// deleted, and sets that are not stored yet are added. A set's id is the hash
+// of the set, so sets that are already stored do not need to be rewritten.
+func (tp *TransactionPool) putTransactionSets(tx *bolt.Tx, sets map[TransactionSetID][]types.Transaction) error {
+	b := tx.Bucket(bucketTransactionSets)
+	var stale [][]byte
+	err := b.ForEach(func(k, _ []byte) error {
+		var id TransactionSetID
+		copy(id[:], k)
+		if _, exists := sets[id]; !exists {
+			stale = append(stale, append([]byte(nil), k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range stale {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	for id, set := range sets {
+		if b.Get(id[:]) != nil {
+			continue
+		}
+		if err := b.Put(id[:], encoding.Marshal(set)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
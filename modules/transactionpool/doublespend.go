@@ -0,0 +1,140 @@
+package transactionpool
+
+import (
+	"sync"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// doubleSpendSubscription queues the double spend events of a subscriber, so
+// that a slow subscriber does not block the transaction pool.
+type doubleSpendSubscription struct {
+	ch     chan<- modules.DoubleSpendEvent
+	queue  []modules.DoubleSpendEvent
+	wakeCh chan struct{}
+	mu     sync.Mutex
+}
+
+// push adds events to the queue of the subscription.
+func (s *doubleSpendSubscription) push(events []modules.DoubleSpendEvent) {
+	s.mu.Lock()
+	s.queue = append(s.queue, events...)
+	s.mu.Unlock()
+	select {
+	case s.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// spentOutputs returns the ids of the siacoin and siafund outputs spent by a
+// transaction.
+func spentOutputs(txn types.Transaction) []types.OutputID {
+	ids := make([]types.OutputID, 0, len(txn.SiacoinInputs)+len(txn.SiafundInputs))
+	for _, sci := range txn.SiacoinInputs {
+		ids = append(ids, types.OutputID(sci.ParentID))
+	}
+	for _, sfi := range txn.SiafundInputs {
+		ids = append(ids, types.OutputID(sfi.ParentID))
+	}
+	return ids
+}
+
+// doubleSpendEvents returns an event for every output that is spent both by a
+// transaction in an applied block and by a different transaction in the pool.
+// It must be called before the pool is purged.
+func (tp *TransactionPool) doubleSpendEvents(cc modules.ConsensusChange) []modules.DoubleSpendEvent {
+	spenders := make(map[types.OutputID]types.TransactionID)
+	for _, set := range tp.transactionSets {
+		for _, txn := range set {
+			txid := txn.ID()
+			for _, id := range spentOutputs(txn) {
+				spenders[id] = txid
+			}
+		}
+	}
+	if len(spenders) == 0 {
+		return nil
+	}
+
+	var events []modules.DoubleSpendEvent
+	for _, block := range cc.AppliedBlocks {
+		for _, txn := range block.Transactions {
+			txid := txn.ID()
+			for _, id := range spentOutputs(txn) {
+				poolTxid, exists := spenders[id]
+				if !exists || poolTxid == txid {
+					continue
+				}
+				events = append(events, modules.DoubleSpendEvent{
+					OutputID:             id,
+					ConfirmedTransaction: txid,
+					PoolTransaction:      poolTxid,
+					BlockID:              block.ID(),
+				})
+			}
+		}
+	}
+	return events
+}
+
+// updateDoubleSpendSubscriptions queues the double spend events of a
+// consensus change for every subscription. It must be called before the pool
+// is purged.
+func (tp *TransactionPool) updateDoubleSpendSubscriptions(cc modules.ConsensusChange) {
+	if len(tp.doubleSpendSubs) == 0 {
+		return
+	}
+	events := tp.doubleSpendEvents(cc)
+	if len(events) == 0 {
+		return
+	}
+	for _, s := range tp.doubleSpendSubs {
+		s.push(events)
+	}
+}
+
+// threadedDeliverDoubleSpendEvents sends the queued events of a subscription
+// to its channel until the transaction pool is closed.
+func (tp *TransactionPool) threadedDeliverDoubleSpendEvents(s *doubleSpendSubscription) {
+	if err := tp.tg.Add(); err != nil {
+		return
+	}
+	defer tp.tg.Done()
+
+	for {
+		s.mu.Lock()
+		events := s.queue
+		s.queue = nil
+		s.mu.Unlock()
+		for _, e := range events {
+			select {
+			case s.ch <- e:
+			case <-tp.tg.StopChan():
+				return
+			}
+		}
+		select {
+		case <-s.wakeCh:
+		case <-tp.tg.StopChan():
+			return
+		}
+	}
+}
+
+// SubscribeDoubleSpend registers a channel that receives an event whenever an
+// applied block spends an output that is also spent by a different,
+// unconfirmed transaction in the pool. The pool transaction can no longer be
+// confirmed, so a payment that it makes has been replaced. Events are
+// delivered in order from a separate goroutine, and are queued while the
+// channel is not being read.
+func (tp *TransactionPool) SubscribeDoubleSpend(ch chan<- modules.DoubleSpendEvent) {
+	s := &doubleSpendSubscription{
+		ch:     ch,
+		wakeCh: make(chan struct{}, 1),
+	}
+	tp.mu.Lock()
+	tp.doubleSpendSubs = append(tp.doubleSpendSubs, s)
+	tp.mu.Unlock()
+	go tp.threadedDeliverDoubleSpendEvents(s)
+}
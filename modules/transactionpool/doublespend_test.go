@@ -0,0 +1,104 @@
+package transactionpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestSubscribeDoubleSpend checks that subscribers are told when a block
+// confirms a transaction that double spends a transaction in the pool.
+func TestSubscribeDoubleSpend(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	// Create and confirm an output that can be spent without signatures.
+	txns, err := tpt.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(100), types.UnlockConditions{}.UnlockHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := txns[len(txns)-1].SiacoinOutputID(0)
+	if _, err := tpt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create two transactions that spend the output.
+	pending, err := types.TransactionGraph(source, []types.TransactionGraphEdge{{
+		Dest:   1,
+		Fee:    types.SiacoinPrecision.Mul64(10),
+		Source: 0,
+		Value:  types.SiacoinPrecision.Mul64(90),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	replacement, err := types.TransactionGraph(source, []types.TransactionGraphEdge{{
+		Dest:   1,
+		Fee:    types.ZeroCurrency,
+		Source: 0,
+		Value:  types.SiacoinPrecision.Mul64(100),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Mine the replacement in a block that is built before the pending
+	// transaction enters the pool.
+	block, target, err := tpt.miner.BlockForWork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	block.Transactions = append(block.Transactions, replacement...)
+	solved, ok := tpt.miner.SolveBlock(block, target)
+	if !ok {
+		t.Fatal("failed to solve block")
+	}
+	if err := tpt.tpool.AcceptTransactionSet(pending); err != nil {
+		t.Fatal(err)
+	}
+	ch := make(chan modules.DoubleSpendEvent)
+	tpt.tpool.SubscribeDoubleSpend(ch)
+	if err := tpt.cs.AcceptBlock(solved); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case e := <-ch:
+		exp := modules.DoubleSpendEvent{
+			OutputID:             types.OutputID(source),
+			ConfirmedTransaction: replacement[0].ID(),
+			PoolTransaction:      pending[0].ID(),
+			BlockID:              solved.ID(),
+		}
+		if e != exp {
+			t.Fatal("wrong event:", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("double spend was not reported")
+	}
+	if _, _, exists := tpt.tpool.Transaction(pending[0].ID()); exists {
+		t.Fatal("double spent transaction is still in the pool")
+	}
+
+	// Transactions that are confirmed without conflict are not reported.
+	if _, err := tpt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tpt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-ch:
+		t.Fatal("unexpected event:", e)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,66 @@
+package transactionpool
+
+import (
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// removeTransactionSet removes a transaction set from the pool, freeing the
+// objects that it spends and creates.
+func (tp *TransactionPool) removeTransactionSet(id TransactionSetID) {
+	set, exists := tp.transactionSets[id]
+	if !exists {
+		return
+	}
+	for oid, setID := range tp.knownObjects {
+		if setID == id {
+			delete(tp.knownObjects, oid)
+		}
+	}
+	for _, txn := range set {
+		delete(tp.transactionHeights, txn.ID())
+		delete(tp.transactionTypes, txn.ID())
+	}
+	delete(tp.transactionSets, id)
+	delete(tp.transactionSetDiffs, id)
+	tp.transactionListSize -= len(encoding.Marshal(set))
+}
+
+// expired returns true if the transaction entered the pool more than
+// txnExpiry blocks ago.
+func (tp *TransactionPool) expired(txn types.Transaction) bool {
+	seenHeight, seen := tp.transactionHeights[txn.ID()]
+	return seen && tp.blockHeight-seenHeight > tp.txnExpiry
+}
+
+// evictExpired removes all transaction sets that contain an expired
+// transaction from the pool.
+func (tp *TransactionPool) evictExpired() {
+	for id, set := range tp.transactionSets {
+		for _, txn := range set {
+			if tp.expired(txn) {
+				tp.removeTransactionSet(id)
+				break
+			}
+		}
+	}
+}
+
+// SetTransactionExpiry sets the number of blocks that a transaction may spend
+// in the pool without being confirmed. Transactions that have been in the pool
+// for longer are dropped, freeing their inputs to be spent by other
+// transactions. Transaction sets that have already expired under the new
+// setting are removed immediately.
+func (tp *TransactionPool) SetTransactionExpiry(blocks types.BlockHeight) {
+	if err := tp.tg.Add(); err != nil {
+		return
+	}
+	defer tp.tg.Done()
+
+	tp.mu.Lock()
+	tp.txnExpiry = blocks
+	tp.evictExpired()
+	tp.mu.Demote()
+	tp.updateSubscribersTransactions()
+	tp.mu.DemotedUnlock()
+}
@@ -0,0 +1,96 @@
+package transactionpool
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestTransactionExpiry checks that transactions which are not confirmed
+// within the expiry are dropped from the pool, freeing their inputs.
+func TestTransactionExpiry(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	// Create and confirm an output that TransactionGraph can spend.
+	txns, err := tpt.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(100), types.UnlockConditions{}.UnlockHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tpt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	sourceID := txns[len(txns)-1].SiacoinOutputID(0)
+	graphTxns := func(dest int) []types.Transaction {
+		edges := []types.TransactionGraphEdge{{
+			Dest:   1,
+			Source: 0,
+			Value:  types.SiacoinPrecision.Mul64(100),
+		}}
+		for i := 1; i < dest; i++ {
+			edges = append(edges, types.TransactionGraphEdge{
+				Dest:   i + 1,
+				Source: i,
+				Value:  types.SiacoinPrecision.Mul64(100),
+			})
+		}
+		txns, err := types.TransactionGraph(sourceID, edges)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return txns
+	}
+
+	// addEmptyBlock adds a block that does not confirm any of the pool's
+	// transactions.
+	addEmptyBlock := func() {
+		b, target, err := tpt.miner.BlockForWork()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.Transactions = b.Transactions[:1]
+		solved, ok := tpt.miner.SolveBlock(b, target)
+		if !ok {
+			t.Fatal("failed to solve block")
+		}
+		if err := tpt.cs.AcceptBlock(solved); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tpt.tpool.SetTransactionExpiry(2)
+	set := graphTxns(1)
+	if err := tpt.tpool.AcceptTransactionSet(set); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		addEmptyBlock()
+		if _, _, exists := tpt.tpool.Transaction(set[0].ID()); !exists {
+			t.Fatal("transaction was dropped before it expired")
+		}
+	}
+	addEmptyBlock()
+	if _, _, exists := tpt.tpool.Transaction(set[0].ID()); exists {
+		t.Fatal("expired transaction is still in the pool")
+	}
+
+	// The input of the expired transaction should be free to be spent by a
+	// new transaction set.
+	set = graphTxns(2)
+	if err := tpt.tpool.AcceptTransactionSet(set); err != nil {
+		t.Fatal(err)
+	}
+
+	// Lowering the expiry should drop transactions immediately.
+	addEmptyBlock()
+	tpt.tpool.SetTransactionExpiry(0)
+	if _, _, exists := tpt.tpool.Transaction(set[0].ID()); exists {
+		t.Fatal("expired transaction is still in the pool after lowering the expiry")
+	}
+}
@@ -0,0 +1,58 @@
+package transactionpool
+
+import (
+	"sort"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// classifyTransactions records the type of each transaction of a set that is
+// entering the pool.
+func (tp *TransactionPool) classifyTransactions(ts []types.Transaction) {
+	for _, txn := range ts {
+		tp.transactionTypes[txn.ID()] = modules.ClassifyTransaction(txn)
+	}
+}
+
+// FeeStatsByType returns statistics about the fees paid by the transactions
+// in the pool, broken down by transaction type. Because transactions of
+// different types differ greatly in size, the fees per byte of the type of a
+// transaction are a better guide to its fee than FeeEstimation alone.
+func (tp *TransactionPool) FeeStatsByType() map[modules.TxType]modules.FeeStats {
+	if err := tp.tg.Add(); err != nil {
+		return nil
+	}
+	defer tp.tg.Done()
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	stats := make(map[modules.TxType]modules.FeeStats)
+	feesPerByte := make(map[modules.TxType][]types.Currency)
+	for _, set := range tp.transactionSets {
+		for _, txn := range set {
+			typ, exists := tp.transactionTypes[txn.ID()]
+			if !exists {
+				typ = modules.ClassifyTransaction(txn)
+			}
+			summary := modules.SummarizeTransaction(txn)
+			s := stats[typ]
+			s.Transactions++
+			s.Size += summary.Size
+			s.Fees = s.Fees.Add(summary.Fee)
+			stats[typ] = s
+			feesPerByte[typ] = append(feesPerByte[typ], summary.Fee.Div64(summary.Size))
+		}
+	}
+	for typ, fees := range feesPerByte {
+		sort.Slice(fees, func(i, j int) bool {
+			return fees[i].Cmp(fees[j]) < 0
+		})
+		s := stats[typ]
+		s.MinFeePerByte = fees[0]
+		s.MedianFeePerByte = fees[len(fees)/2]
+		s.MaxFeePerByte = fees[len(fees)-1]
+		stats[typ] = s
+	}
+	return stats
+}
@@ -0,0 +1,68 @@
+package transactionpool
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestFeeStatsByType checks that the fee statistics of the pool reflect the
+// transactions in the pool.
+func TestFeeStatsByType(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	if stats := tpt.tpool.FeeStatsByType(); len(stats) != 0 {
+		t.Fatal("expected no stats for an empty pool, got", stats)
+	}
+
+	// Send some siacoins, which puts siacoin sends into the pool.
+	txns, err := tpt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := tpt.tpool.FeeStatsByType()
+	if len(stats) != 1 {
+		t.Fatal("expected stats for a single type, got", stats)
+	}
+	s := stats[modules.TxTypeSiacoinSend]
+	var size uint64
+	var fees types.Currency
+	for _, txn := range txns {
+		size += uint64(txn.MarshalSiaSize())
+		for _, fee := range txn.MinerFees {
+			fees = fees.Add(fee)
+		}
+	}
+	if s.Transactions != len(txns) || s.Size != size || s.Fees.Cmp(fees) != 0 {
+		t.Fatal("stats do not match the transactions in the pool:", s)
+	}
+	if s.MinFeePerByte.Cmp(s.MedianFeePerByte) > 0 || s.MedianFeePerByte.Cmp(s.MaxFeePerByte) > 0 {
+		t.Fatal("fee per byte statistics are out of order:", s)
+	}
+	if s.MaxFeePerByte.IsZero() {
+		t.Fatal("expected the transactions to pay fees")
+	}
+
+	// Once the transactions are confirmed, the stats are empty again.
+	if _, err := tpt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if stats := tpt.tpool.FeeStatsByType(); len(stats) != 0 {
+		t.Fatal("expected no stats after the pool was emptied, got", stats)
+	}
+	tpt.tpool.mu.Lock()
+	n := len(tpt.tpool.transactionTypes)
+	tpt.tpool.mu.Unlock()
+	if n != 0 {
+		t.Fatal("transaction types were not cleaned up:", n)
+	}
+}
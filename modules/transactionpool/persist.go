@@ -40,7 +40,8 @@ func (tp *TransactionPool) threadedRegularSync() {
 // syncDB commits the current global transaction and immediately begins a new
 // one.
 func (tp *TransactionPool) syncDB() {
-	// Commit the existing tx.
+	// Commit the existing tx, along with the current transaction sets.
+	tp.saveTransactionSets()
 	err := tp.dbTx.Commit()
 	if err != nil {
 		tp.log.Severe("ERROR: failed to apply database update:", err)
@@ -113,6 +114,7 @@ func (tp *TransactionPool) initPersist() error {
 	}
 	tp.tg.AfterStop(func() {
 		tp.mu.Lock()
+		tp.saveTransactionSets()
 		err := tp.dbTx.Commit()
 		tp.mu.Unlock()
 		if err != nil {
@@ -130,6 +132,8 @@ func (tp *TransactionPool) initPersist() error {
 		bucketRecentConsensusChange,
 		bucketConfirmedTransactions,
 		bucketFeeMedian,
+		bucketSettings,
+		bucketTransactionSets,
 	}
 	for _, bucket := range buckets {
 		_, err := tp.dbTx.CreateBucketIfNotExists(bucket)
@@ -138,6 +142,8 @@ func (tp *TransactionPool) initPersist() error {
 		}
 	}
 
+	tp.persistTxnSets = tp.getPersistTransactionSets(tp.dbTx)
+
 	// Get the recent consensus change.
 	cc, err = tp.getRecentConsensusChange(tp.dbTx)
 	if err == errNilConsensusChange {
@@ -204,6 +210,69 @@ func (tp *TransactionPool) initPersist() error {
 	return nil
 }
 
+// saveTransactionSets writes the unconfirmed transaction sets of the pool to
+// the database, or removes them from the database if persistence is
+// disabled. The caller must hold tp.mu.
+func (tp *TransactionPool) saveTransactionSets() {
+	sets := tp.transactionSets
+	if !tp.persistTxnSets {
+		sets = nil
+	}
+	err := tp.putTransactionSets(tp.dbTx, sets)
+	if err != nil {
+		tp.log.Println("ERROR: unable to save the transaction sets:", err)
+	}
+}
+
+// managedLoadTransactionSets adds the transaction sets that were persisted
+// before the last shutdown back into the pool. Each set is validated against
+// the current consensus state, and sets that are no longer valid are
+// discarded.
+func (tp *TransactionPool) managedLoadTransactionSets() {
+	tp.mu.Lock()
+	if !tp.persistTxnSets {
+		tp.mu.Unlock()
+		return
+	}
+	sets, err := tp.getTransactionSets(tp.dbTx)
+	tp.mu.Unlock()
+	if err != nil {
+		tp.log.Println("ERROR: unable to load the persisted transaction sets:", err)
+		return
+	}
+	if len(sets) == 0 {
+		return
+	}
+
+	var loaded int
+	for _, set := range sets {
+		if err := tp.AcceptTransactionSet(set); err == nil {
+			loaded++
+		} else {
+			tp.log.Debugln("Discarding persisted transaction set:", err)
+		}
+	}
+	tp.log.Printf("Reloaded %v of %v persisted transaction sets", loaded, len(sets))
+}
+
+// SetPersistence sets whether the unconfirmed transactions of the pool are
+// saved to disk and reloaded when the node restarts. The setting itself is
+// persisted. Disabling persistence removes the saved transactions.
+func (tp *TransactionPool) SetPersistence(enabled bool) {
+	if err := tp.tg.Add(); err != nil {
+		return
+	}
+	defer tp.tg.Done()
+
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	tp.persistTxnSets = enabled
+	if err := tp.putPersistTransactionSets(tp.dbTx, enabled); err != nil {
+		tp.log.Println("ERROR: unable to save the persistence setting:", err)
+	}
+	tp.saveTransactionSets()
+}
+
 // TransactionConfirmed returns true if the transaction has been seen on the
 // blockchain. Note, however, that the block containing the transaction may
 // later be invalidated by a reorg.
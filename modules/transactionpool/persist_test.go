@@ -93,3 +93,121 @@ func TestRescan(t *testing.T) {
 		t.Fatal("expecting modules.ErrDuplicateTransactionSet, got:", err)
 	}
 }
+
+// TestTransactionSetPersistence checks that unconfirmed transaction sets are
+// reloaded after a restart, that sets which are no longer valid are
+// discarded, and that persistence can be disabled.
+func TestTransactionSetPersistence(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+	persistDir := tpt.tpool.persistDir
+	restart := func() {
+		if err := tpt.tpool.Close(); err != nil {
+			t.Fatal(err)
+		}
+		tpt.tpool, err = New(tpt.cs, tpt.gateway, persistDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The transactions are still in the pool after a restart.
+	txns, err := tpt.wallet.SendSiacoins(types.NewCurrency64(100), types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	restart()
+	for _, txn := range txns {
+		if _, _, exists := tpt.tpool.Transaction(txn.ID()); !exists {
+			t.Fatal("transaction was not reloaded")
+		}
+	}
+
+	// Transactions that were confirmed while the pool was offline are
+	// discarded.
+	if err := tpt.tpool.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tpt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	tpt.tpool, err = New(tpt.cs, tpt.gateway, persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if confirmed, _ := tpt.tpool.TransactionConfirmed(txns[len(txns)-1].ID()); !confirmed {
+		t.Fatal("transaction was not mined")
+	}
+	if n := len(tpt.tpool.TransactionList()); n != 0 {
+		t.Fatal("confirmed transactions were reloaded:", n)
+	}
+
+	// Nothing is reloaded once persistence is disabled, and the setting
+	// survives a restart.
+	tpt.tpool.SetPersistence(false)
+	if err := tpt.tpool.AcceptTransactionSet(txns); err == nil {
+		t.Fatal("confirmed transactions were accepted")
+	}
+	set := []types.Transaction{{ArbitraryData: [][]byte{append(modules.PrefixNonSia[:], 'x')}}}
+	if err := tpt.tpool.AcceptTransactionSet(set); err != nil {
+		t.Fatal(err)
+	}
+	restart()
+	if n := len(tpt.tpool.TransactionList()); n != 0 {
+		t.Fatal("transactions were reloaded with persistence disabled:", n)
+	}
+	if tpt.tpool.persistTxnSets {
+		t.Fatal("persistence setting was not saved")
+	}
+}
+
+// TestPutTransactionSets checks that putTransactionSets leaves exactly the
+// provided sets in the database.
+func TestPutTransactionSets(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	set := func(data string) []types.Transaction {
+		return []types.Transaction{{ArbitraryData: [][]byte{[]byte(data)}}}
+	}
+	tpt.tpool.mu.Lock()
+	defer tpt.tpool.mu.Unlock()
+	tx := tpt.tpool.dbTx
+	err = tpt.tpool.putTransactionSets(tx, map[TransactionSetID][]types.Transaction{
+		{1}: set("a"),
+		{2}: set("b"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tpt.tpool.putTransactionSets(tx, map[TransactionSetID][]types.Transaction{
+		{2}: set("b"),
+		{3}: set("c"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sets, err := tpt.tpool.getTransactionSets(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := make(map[string]bool)
+	for _, s := range sets {
+		stored[string(s[0].ArbitraryData[0])] = true
+	}
+	if len(sets) != 2 || !stored["b"] || !stored["c"] {
+		t.Fatal("wrong sets stored:", sets)
+	}
+}
@@ -0,0 +1,59 @@
+package transactionpool
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// errBelowMinRelayFee is returned if a transaction set pays less than the
+	// minimum relay fee per byte.
+	errBelowMinRelayFee = errors.New("transaction set pays less than the minimum relay fee")
+)
+
+// totalMinerFees returns the sum of the miner fees of a transaction set.
+func totalMinerFees(ts []types.Transaction) (fees types.Currency) {
+	for _, txn := range ts {
+		for _, fee := range txn.MinerFees {
+			fees = fees.Add(fee)
+		}
+	}
+	return fees
+}
+
+// belowMinRelayFee returns true if a transaction set of the given size pays
+// less than the minimum relay fee.
+func (tp *TransactionPool) belowMinRelayFee(ts []types.Transaction, size uint64) bool {
+	return tp.minRelayFee.Mul64(size).Cmp(totalMinerFees(ts)) > 0
+}
+
+// evictBelowMinRelayFee removes all transaction sets that pay less than the
+// minimum relay fee from the pool.
+func (tp *TransactionPool) evictBelowMinRelayFee() {
+	for id, set := range tp.transactionSets {
+		size := len(encoding.Marshal(set))
+		if tp.belowMinRelayFee(set, uint64(size)) {
+			tp.removeTransactionSet(id)
+		}
+	}
+}
+
+// SetMinRelayFee sets the minimum fee per byte that a transaction set must pay
+// to be accepted into the pool and relayed to peers. Sets already in the pool
+// that pay less than the new minimum are removed, so that they are no longer
+// relayed or considered by miners. A minimum of zero disables the check.
+func (tp *TransactionPool) SetMinRelayFee(perByte types.Currency) {
+	if err := tp.tg.Add(); err != nil {
+		return
+	}
+	defer tp.tg.Done()
+
+	tp.mu.Lock()
+	tp.minRelayFee = perByte
+	tp.evictBelowMinRelayFee()
+	tp.mu.Demote()
+	tp.updateSubscribersTransactions()
+	tp.mu.DemotedUnlock()
+}
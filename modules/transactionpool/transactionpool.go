@@ -52,17 +52,37 @@ type (
 		transactionSetDiffs map[TransactionSetID]*modules.ConsensusChange
 		transactionListSize int
 
+		// transactionTypes holds the type of each transaction in the pool,
+		// determined when the transaction entered the pool.
+		transactionTypes map[types.TransactionID]modules.TxType
+
 		// Variables related to the blockchain.
 		blockHeight     types.BlockHeight
 		recentMedians   []types.Currency
 		recentMedianFee types.Currency // SC per byte
 
+		// minRelayFee is the minimum fee per byte that a transaction set
+		// must pay to be accepted into the pool.
+		minRelayFee types.Currency
+
+		// txnExpiry is the number of blocks that a transaction may spend in
+		// the pool without being confirmed before it is dropped.
+		txnExpiry types.BlockHeight
+
+		// persistTxnSets indicates that the unconfirmed transaction sets are
+		// saved to the database and reloaded after a restart.
+		persistTxnSets bool
+
 		// The consensus change index tracks how many consensus changes have
 		// been sent to the transaction pool. When a new subscriber joins the
 		// transaction pool, all prior consensus changes are sent to the new
 		// subscriber.
 		subscribers []modules.TransactionPoolSubscriber
 
+		// doubleSpendSubs are the subscriptions created by
+		// SubscribeDoubleSpend.
+		doubleSpendSubs []*doubleSpendSubscription
+
 		// Utilities.
 		db         *persist.BoltDatabase
 		dbTx       *bolt.Tx
@@ -93,6 +113,9 @@ func New(cs modules.ConsensusSet, g modules.Gateway, persistDir string) (*Transa
 		transactionHeights:  make(map[types.TransactionID]types.BlockHeight),
 		transactionSets:     make(map[TransactionSetID][]types.Transaction),
 		transactionSetDiffs: make(map[TransactionSetID]*modules.ConsensusChange),
+		transactionTypes:    make(map[types.TransactionID]modules.TxType),
+
+		txnExpiry: maxTxnAge,
 
 		persistDir: persistDir,
 	}
@@ -102,6 +125,7 @@ func New(cs modules.ConsensusSet, g modules.Gateway, persistDir string) (*Transa
 	if err != nil {
 		return nil, err
 	}
+	tp.managedLoadTransactionSets()
 
 	// Register RPCs
 	g.RegisterRPC("RelayTransactionSet", tp.relayTransactionSet)
@@ -166,6 +190,14 @@ func (tp *TransactionPool) FeeEstimation() (min, max types.Currency) {
 		max = minEstimation.Mul64(maxMultiplier)
 	}
 
+	// Never recommend a fee that the pool would refuse to relay.
+	if min.Cmp(tp.minRelayFee) < 0 {
+		min = tp.minRelayFee
+	}
+	if max.Cmp(min) < 0 {
+		max = min
+	}
+
 	return
 }
 
@@ -165,6 +165,7 @@ func (tp *TransactionPool) purge() {
 	tp.knownObjects = make(map[ObjectID]TransactionSetID)
 	tp.transactionSets = make(map[TransactionSetID][]types.Transaction)
 	tp.transactionSetDiffs = make(map[TransactionSetID]*modules.ConsensusChange)
+	tp.transactionTypes = make(map[types.TransactionID]modules.TxType)
 	tp.transactionListSize = 0
 }
 
@@ -351,16 +352,18 @@ func (tp *TransactionPool) ProcessConsensusChange(cc modules.ConsensusChange) {
 		unconfirmedSets = append(unconfirmedSets, newTSet)
 	}
 
+	// Report pool transactions that were double spent by the applied blocks.
+	tp.updateDoubleSpendSubscriptions(cc)
+
 	// Purge the transaction pool. Some of the transactions sets may be invalid
 	// after the consensus change.
 	tp.purge()
 
-	// prune transactions older than maxTxnAge.
+	// prune transactions older than txnExpiry.
 	for i, tSet := range unconfirmedSets {
 		var validTxns []types.Transaction
 		for _, txn := range tSet {
-			seenHeight, seen := tp.transactionHeights[txn.ID()]
-			if tp.blockHeight-seenHeight <= maxTxnAge || !seen {
+			if !tp.expired(txn) {
 				validTxns = append(validTxns, txn)
 			} else {
 				delete(tp.transactionHeights, txn.ID())
@@ -418,8 +421,11 @@ func (tp *TransactionPool) ProcessConsensusChange(cc modules.ConsensusChange) {
 }
 
 // PurgeTransactionPool deletes all transactions from the transaction pool.
+// Subscribers are informed that the purged transaction sets were removed.
 func (tp *TransactionPool) PurgeTransactionPool() {
 	tp.mu.Lock()
 	tp.purge()
-	tp.mu.Unlock()
+	tp.mu.Demote()
+	tp.updateSubscribersTransactions()
+	tp.mu.DemotedUnlock()
 }
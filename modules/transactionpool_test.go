@@ -106,3 +106,67 @@ func TestCalculateFee(t *testing.T) {
 		t.Error("got the wrong fee for a multi transaction set")
 	}
 }
+
+// TestSummarizeTransaction checks that SummarizeTransaction reports the ID,
+// size, and total fee of a transaction.
+func TestSummarizeTransaction(t *testing.T) {
+	t.Parallel()
+
+	txn := types.Transaction{
+		SiacoinOutputs: []types.SiacoinOutput{{
+			Value: types.NewCurrency64(253e9),
+		}},
+		MinerFees: []types.Currency{
+			types.NewCurrency64(12e3),
+			types.NewCurrency64(30e3),
+		},
+	}
+	ts := SummarizeTransaction(txn)
+	if ts.ID != txn.ID() {
+		t.Error("summary has the wrong ID")
+	}
+	if ts.Size != uint64(len(encoding.Marshal(txn))) {
+		t.Error("summary has the wrong size:", ts.Size)
+	}
+	if !ts.Fee.Equals64(42e3) {
+		t.Error("summary has the wrong fee:", ts.Fee)
+	}
+
+	// A transaction without miner fees has a fee of zero.
+	if !SummarizeTransaction(types.Transaction{}).Fee.IsZero() {
+		t.Error("empty transaction has a non-zero fee")
+	}
+}
+
+// TestClassifyTransaction checks that transactions are classified by the
+// most significant thing that they do.
+func TestClassifyTransaction(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		txn types.Transaction
+		typ TxType
+	}{
+		{types.Transaction{}, TxTypeOther},
+		{types.Transaction{ArbitraryData: [][]byte{{1}}}, TxTypeOther},
+		{types.Transaction{SiacoinOutputs: []types.SiacoinOutput{{}}}, TxTypeSiacoinSend},
+		{types.Transaction{
+			SiacoinInputs:  []types.SiacoinInput{{}},
+			SiafundOutputs: []types.SiafundOutput{{}},
+		}, TxTypeSiafund},
+		{types.Transaction{FileContractRevisions: []types.FileContractRevision{{}}}, TxTypeFileContractRevision},
+		{types.Transaction{
+			SiacoinInputs: []types.SiacoinInput{{}},
+			FileContracts: []types.FileContract{{}},
+		}, TxTypeFileContract},
+		{types.Transaction{
+			FileContracts: []types.FileContract{{}},
+			StorageProofs: []types.StorageProof{{}},
+		}, TxTypeStorageProof},
+	}
+	for i, test := range tests {
+		if typ := ClassifyTransaction(test.txn); typ != test.typ {
+			t.Errorf("%v: expected %v, got %v", i, test.typ, typ)
+		}
+	}
+}
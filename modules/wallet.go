@@ -3,6 +3,8 @@ package modules
 import (
 	"bytes"
 	"errors"
+	"io"
+	"time"
 
 	"github.com/NebulousLabs/entropy-mnemonics"
 
@@ -23,6 +25,20 @@ const (
 	WalletDir = "wallet"
 )
 
+const (
+	// SelectMinimizeInputs funds transactions with the largest outputs
+	// first, using as few inputs as possible. This is the default strategy.
+	SelectMinimizeInputs SelectionStrategy = "minimize-inputs"
+
+	// SelectMinimizeChange funds transactions with the smallest output that
+	// covers the amount, if one exists, to keep the change output small.
+	SelectMinimizeChange SelectionStrategy = "minimize-change"
+
+	// SelectOldestFirst funds transactions with the oldest outputs first,
+	// which consolidates old and small outputs over time.
+	SelectOldestFirst SelectionStrategy = "oldest-first"
+)
+
 var (
 	// ErrBadEncryptionKey is returned if the incorrect encryption key to a
 	// file is provided.
@@ -52,6 +68,10 @@ type (
 	// addresses.
 	Seed [crypto.EntropySize]byte
 
+	// A SelectionStrategy determines the order in which a transaction
+	// builder picks the wallet's outputs when funding a transaction.
+	SelectionStrategy string
+
 	// WalletTransactionID is a unique identifier for a wallet transaction.
 	WalletTransactionID crypto.Hash
 
@@ -271,6 +291,10 @@ type (
 		// Unlocked returns true if the wallet is currently unlocked, false
 		// otherwise.
 		Unlocked() (bool, error)
+
+		// SetAutoLockTimeout sets the period without spends after which the
+		// wallet locks itself. A timeout of 0 disables auto-locking.
+		SetAutoLockTimeout(time.Duration) error
 	}
 
 	// KeyManager manages wallet keys, including the use of seeds, creating and
@@ -292,6 +316,16 @@ type (
 		// filepath. The backup will have all seeds and keys.
 		CreateBackup(string) error
 
+		// ExportEncryptedBackup writes the seeds and unseeded keys of the
+		// wallet to a writer, encrypted with a key derived from the
+		// passphrase. The wallet must be unlocked.
+		ExportEncryptedBackup(dst io.Writer, masterKey crypto.TwofishKey, passphrase string) error
+
+		// ImportEncryptedBackup restores the wallet from an encrypted
+		// backup, encrypting the wallet with masterKey. A wallet that has
+		// already been encrypted is only replaced if 'overwrite' is set.
+		ImportEncryptedBackup(src io.Reader, passphrase string, masterKey crypto.TwofishKey, overwrite bool) error
+
 		// LoadBackup will load a backup of the wallet from the provided
 		// address. The backup wallet will be added as an auxiliary seed, not
 		// as a primary seed.
@@ -380,6 +414,11 @@ type (
 		// relative to the wallet.
 		UnconfirmedTransactions() ([]ProcessedTransaction, error)
 
+		// NewTransactionBuilder returns an empty TransactionBuilder that
+		// funds the transaction with siacoin outputs picked by the provided
+		// selection strategy.
+		NewTransactionBuilder(SelectionStrategy) (TransactionBuilder, error)
+
 		// RegisterTransaction takes a transaction and its parents and returns
 		// a TransactionBuilder which can be used to expand the transaction.
 		RegisterTransaction(t types.Transaction, parents []types.Transaction) (TransactionBuilder, error)
@@ -404,9 +443,56 @@ type (
 		// are also returned to the caller.
 		SendSiacoins(amount types.Currency, dest types.UnlockHash) ([]types.Transaction, error)
 
+		// SendSiacoinsWithChange is like SendSiacoins, but any change is sent
+		// to the provided change address instead of a new wallet address. The
+		// change address does not need to belong to the wallet; if it does
+		// not, the change can no longer be spent by the wallet. The
+		// transaction containing the output to 'dest' is returned.
+		SendSiacoinsWithChange(amount types.Currency, dest, change types.UnlockHash) (types.Transaction, error)
+
+		// BumpFee replaces an unconfirmed transaction with one that spends
+		// the same inputs, creates the same outputs, and pays the higher
+		// miner fee 'newFee'. The replacement is returned.
+		BumpFee(txid types.TransactionID, newFee types.Currency) (types.Transaction, error)
+
+		// RebroadcastUnconfirmed sends the wallet's unconfirmed transactions
+		// to the network again through the transaction pool, returning the
+		// number of transactions sent. Transactions whose inputs were spent
+		// by confirmed transactions are no longer rebroadcast.
+		RebroadcastUnconfirmed() (count int, err error)
+
 		// SendSiacoinsMulti sends coins to multiple addresses.
 		SendSiacoinsMulti(outputs []types.SiacoinOutput) ([]types.Transaction, error)
 
+		// NewMultiSigAddress creates an address that requires 'required'
+		// signatures from the provided public keys to spend. At least one of
+		// the keys must belong to the wallet.
+		NewMultiSigAddress(pubkeys []types.SiaPublicKey, required uint64) (types.UnlockHash, error)
+
+		// MultiSigAddresses returns the unlock conditions of the multisig
+		// addresses created by the wallet.
+		MultiSigAddresses() ([]types.UnlockConditions, error)
+
+		// SignTransaction adds the wallet's signatures to the inputs of the
+		// transaction with the provided parent ids. Inputs that require
+		// signatures from other parties are partially signed, and the
+		// signatures of each party can be combined by signing the same
+		// transaction in turn.
+		SignTransaction(txn *types.Transaction, toSign []crypto.Hash) error
+
+		// CreateRawTransaction builds an unsigned transaction spending the
+		// provided inputs to the provided outputs, paying 'fee' to the
+		// miners. The inputs must equal the outputs plus the fee.
+		CreateRawTransaction(inputs []types.SiacoinInput, outputs []types.SiacoinOutput, fee types.Currency) (types.Transaction, error)
+
+		// SignRawTransaction returns txn with every siacoin input that the
+		// wallet holds a key for signed.
+		SignRawTransaction(txn types.Transaction) (types.Transaction, error)
+
+		// BroadcastRawTransaction submits a signed transaction to the
+		// transaction pool.
+		BroadcastRawTransaction(txn types.Transaction) error
+
 		// SendSiafunds is a tool for sending siafunds from the wallet to an
 		// address. Sending money usually results in multiple transactions. The
 		// transactions are automatically given to the transaction pool, and
@@ -416,11 +502,22 @@ type (
 		// DustThreshold returns the quantity per byte below which a Currency is
 		// considered to be Dust.
 		DustThreshold() (types.Currency, error)
+
+		// ChangeDustThreshold returns the value below which the change of a
+		// transaction is paid to the miners as a fee instead of being
+		// returned to the wallet.
+		ChangeDustThreshold() (types.Currency, error)
+
+		// SetChangeDustThreshold sets the value below which the change of a
+		// transaction is paid to the miners as a fee instead of being
+		// returned to the wallet. A threshold of 0 disables this.
+		SetChangeDustThreshold(types.Currency) error
 	}
 
 	// WalletSettings control the behavior of the Wallet.
 	WalletSettings struct {
-		NoDefrag bool `json:"noDefrag"`
+		NoDefrag            bool           `json:"noDefrag"`
+		ChangeDustThreshold types.Currency `json:"changeDustThreshold"`
 	}
 )
 
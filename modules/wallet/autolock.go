@@ -0,0 +1,86 @@
+package wallet
+
+import (
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// resetAutoLockTimer restarts the auto-lock timer of the wallet. The timer is
+// only running while the wallet is unlocked, no spends are in progress, and an
+// auto-lock timeout is set. The caller must hold w.mu.
+func (w *Wallet) resetAutoLockTimer() {
+	if w.autoLockTimer != nil {
+		w.autoLockTimer.Stop()
+		w.autoLockTimer = nil
+	}
+	w.autoLockGen++
+	if w.autoLockTimeout == 0 || !w.unlocked || w.activeSpends > 0 {
+		return
+	}
+	gen := w.autoLockGen
+	w.autoLockTimer = time.AfterFunc(w.autoLockTimeout, func() {
+		w.threadedAutoLock(gen)
+	})
+}
+
+// threadedAutoLock locks the wallet once it has been inactive for the
+// auto-lock timeout. gen identifies the timer that fired.
+func (w *Wallet) threadedAutoLock(gen uint64) {
+	if err := w.tg.Add(); err != nil {
+		return
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// The timer may have been replaced while this call waited for the lock.
+	if gen != w.autoLockGen || !w.unlocked {
+		return
+	}
+	w.log.Printf("INFO: Locking wallet after %v of inactivity.", w.autoLockTimeout)
+	w.wipeSecrets()
+	w.unlocked = false
+	w.autoLockTimer = nil
+}
+
+// managedBeginSpend marks the start of an operation that spends from the
+// wallet, returning an error if the wallet is locked. The wallet is not
+// auto-locked until managedEndSpend is called.
+func (w *Wallet) managedBeginSpend() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.unlocked {
+		return modules.ErrLockedWallet
+	}
+	w.activeSpends++
+	w.resetAutoLockTimer()
+	return nil
+}
+
+// managedEndSpend marks the end of an operation started with
+// managedBeginSpend, restarting the auto-lock timer once no spends remain.
+func (w *Wallet) managedEndSpend() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.activeSpends--
+	w.resetAutoLockTimer()
+}
+
+// SetAutoLockTimeout sets the period of inactivity after which the wallet
+// locks itself. Every spend restarts the period, and spends that are in
+// progress when the period ends are allowed to complete. Transaction builders
+// are not spends in progress; a builder that is signed after the wallet has
+// locked returns modules.ErrLockedWallet. A timeout of 0 disables
+// auto-locking.
+func (w *Wallet) SetAutoLockTimeout(d time.Duration) error {
+	if err := w.tg.Add(); err != nil {
+		return modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.autoLockTimeout = d
+	w.resetAutoLockTimer()
+	return nil
+}
@@ -0,0 +1,121 @@
+package wallet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestAutoLock checks that the wallet locks itself after a period without
+// spends, and that spends in progress delay the lock.
+func TestAutoLock(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	unlocked := func() bool {
+		unlocked, err := wt.wallet.Unlocked()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return unlocked
+	}
+
+	// Spends restart the timeout.
+	timeout := 500 * time.Millisecond
+	if err := wt.wallet.SetAutoLockTimeout(timeout); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		time.Sleep(timeout / 2)
+		if _, err := wt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !unlocked() {
+		t.Fatal("wallet locked despite recent spends")
+	}
+	time.Sleep(2 * timeout)
+	if unlocked() {
+		t.Fatal("wallet did not lock after the timeout")
+	}
+	if _, err := wt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{}); err != modules.ErrLockedWallet {
+		t.Fatal("expected ErrLockedWallet, got", err)
+	}
+
+	// Unlocking restarts the timeout, and a spend in progress prevents the
+	// wallet from locking until it completes.
+	if err := wt.wallet.Unlock(wt.walletMasterKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.managedBeginSpend(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * timeout)
+	if !unlocked() {
+		t.Fatal("wallet locked during a spend")
+	}
+	wt.wallet.managedEndSpend()
+	time.Sleep(2 * timeout)
+	if unlocked() {
+		t.Fatal("wallet did not lock after the spend completed")
+	}
+
+	// A timeout of 0 disables auto-locking.
+	if err := wt.wallet.Unlock(wt.walletMasterKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.SetAutoLockTimeout(0); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * timeout)
+	if !unlocked() {
+		t.Fatal("wallet locked with auto-locking disabled")
+	}
+}
+
+// TestAutoLockTransactionBuilder checks that a transaction builder that is
+// signed after the wallet has auto-locked fails cleanly.
+func TestAutoLockTransactionBuilder(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	tb, err := wt.wallet.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.FundSiacoins(types.SiacoinPrecision); err != nil {
+		t.Fatal(err)
+	}
+	tb.AddSiacoinOutput(types.SiacoinOutput{Value: types.SiacoinPrecision})
+
+	timeout := 100 * time.Millisecond
+	if err := wt.wallet.SetAutoLockTimeout(timeout); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * timeout)
+	if unlocked, err := wt.wallet.Unlocked(); err != nil {
+		t.Fatal(err)
+	} else if unlocked {
+		t.Fatal("wallet did not lock after the timeout")
+	}
+	if _, err := tb.Sign(true); err != modules.ErrLockedWallet {
+		t.Fatal("expected ErrLockedWallet, got", err)
+	}
+	tb.Drop()
+}
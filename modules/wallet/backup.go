@@ -0,0 +1,231 @@
+package wallet
+
+// backup.go implements passphrase-encrypted wallet backups. Unlike
+// CreateBackup, which copies the wallet database, an encrypted backup only
+// contains the secrets of the wallet, encrypted with a key derived from a
+// passphrase, so that it can be stored offsite and restored into any wallet.
+
+import (
+	"errors"
+	"io"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/fastrand"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// backupHeader and backupVersion identify an encrypted wallet backup.
+	backupHeader  = "Sia Wallet Backup"
+	backupVersion = "1.1"
+
+	// maxBackupKDFMemory is the largest amount of memory, in KiB, that
+	// deriving the key of a backup may use. It prevents a malicious backup
+	// file from exhausting the memory of the wallet.
+	maxBackupKDFMemory = 1 << 20 // 1 GiB
+)
+
+var (
+	// defaultBackupKDF are the argon2id parameters used for new backups. An
+	// offsite backup can be attacked offline, so deriving its key has to be
+	// expensive enough to make guessing the passphrase impractical.
+	defaultBackupKDF = build.Select(build.Var{
+		Standard: backupKDFParams{Time: 3, Memory: 64 << 10, Threads: 4},
+		Dev:      backupKDFParams{Time: 1, Memory: 8 << 10, Threads: 4},
+		Testing:  backupKDFParams{Time: 1, Memory: 1 << 10, Threads: 1},
+	}).(backupKDFParams)
+
+	errBackupHeader   = errors.New("file is not an encrypted wallet backup")
+	errBackupKDF      = errors.New("encrypted wallet backup has invalid key derivation parameters")
+	errBackupVersion  = errors.New("encrypted wallet backup has an unknown version")
+	errWalletNotEmpty = errors.New("wallet already has a seed; an overwrite is required to import a backup")
+)
+
+type (
+	// walletBackup contains the secrets of a wallet.
+	walletBackup struct {
+		PrimarySeed         modules.Seed
+		PrimarySeedProgress uint64
+		AuxiliarySeeds      []modules.Seed
+		UnseededKeys        []spendableKey
+	}
+
+	// backupKDFParams are the argon2id parameters that the encryption key of
+	// a backup is derived with. Memory is in KiB.
+	backupKDFParams struct {
+		Time    uint32
+		Memory  uint32
+		Threads uint8
+	}
+
+	// encryptedBackup is the encoded form of an encrypted wallet backup. The
+	// backup is encrypted with a key derived from the passphrase and a random
+	// salt. The parameters of the key derivation are stored along with the
+	// backup, so that they can be raised without breaking older backups.
+	encryptedBackup struct {
+		Header                 string
+		Version                string
+		Salt                   uniqueID
+		KDF                    backupKDFParams
+		EncryptionVerification crypto.Ciphertext
+		Backup                 crypto.Ciphertext
+	}
+)
+
+// backupEncryptionKey derives the encryption key of a backup from a
+// passphrase and a salt, using the memory-hard argon2id function.
+func backupEncryptionKey(passphrase string, salt uniqueID, params backupKDFParams) (key crypto.TwofishKey, err error) {
+	if params.Time == 0 || params.Threads == 0 || params.Memory > maxBackupKDFMemory {
+		return crypto.TwofishKey{}, errBackupKDF
+	}
+	copy(key[:], argon2.IDKey([]byte(passphrase), salt[:], params.Time, params.Memory, params.Threads, uint32(len(key))))
+	return key, nil
+}
+
+// decryptBackup reads and decrypts an encrypted wallet backup.
+func decryptBackup(r io.Reader, passphrase string) (wb walletBackup, err error) {
+	var eb encryptedBackup
+	if err := encoding.NewDecoder(r).Decode(&eb); err != nil {
+		return walletBackup{}, errBackupHeader
+	}
+	if eb.Header != backupHeader {
+		return walletBackup{}, errBackupHeader
+	}
+	if eb.Version != backupVersion {
+		return walletBackup{}, errBackupVersion
+	}
+	key, err := backupEncryptionKey(passphrase, eb.Salt, eb.KDF)
+	if err != nil {
+		return walletBackup{}, err
+	}
+	if err := verifyEncryption(key, eb.EncryptionVerification); err != nil {
+		return walletBackup{}, err
+	}
+	plaintext, err := key.DecryptBytes(eb.Backup)
+	if err != nil {
+		return walletBackup{}, err
+	}
+	err = encoding.Unmarshal(plaintext, &wb)
+	return wb, err
+}
+
+// ExportEncryptedBackup writes the seeds and unseeded keys of the wallet to
+// 'dst', encrypted with a key derived from the passphrase. The wallet must be
+// unlocked, and masterKey must be the key that the wallet is encrypted with.
+func (w *Wallet) ExportEncryptedBackup(dst io.Writer, masterKey crypto.TwofishKey, passphrase string) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+
+	var wb walletBackup
+	err := func() error {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if !w.unlocked {
+			return modules.ErrLockedWallet
+		}
+		if err := checkMasterKey(w.dbTx, masterKey); err != nil {
+			return err
+		}
+
+		progress, err := dbGetPrimarySeedProgress(w.dbTx)
+		if err != nil {
+			return err
+		}
+		var keyFiles []spendableKeyFile
+		err = encoding.Unmarshal(w.dbTx.Bucket(bucketWallet).Get(keySpendableKeyFiles), &keyFiles)
+		if err != nil {
+			return err
+		}
+		wb = walletBackup{
+			PrimarySeed:         w.primarySeed,
+			PrimarySeedProgress: progress,
+			AuxiliarySeeds:      append([]modules.Seed(nil), w.seeds...),
+		}
+		for _, skf := range keyFiles {
+			sk, err := decryptSpendableKeyFile(masterKey, skf)
+			if err != nil {
+				return err
+			}
+			wb.UnseededKeys = append(wb.UnseededKeys, sk)
+		}
+		return nil
+	}()
+	if err != nil {
+		return err
+	}
+
+	eb := encryptedBackup{
+		Header:  backupHeader,
+		Version: backupVersion,
+		KDF:     defaultBackupKDF,
+	}
+	fastrand.Read(eb.Salt[:])
+	key, err := backupEncryptionKey(passphrase, eb.Salt, eb.KDF)
+	if err != nil {
+		return err
+	}
+	eb.EncryptionVerification = key.EncryptBytes(verificationPlaintext)
+	eb.Backup = key.EncryptBytes(encoding.Marshal(wb))
+	return encoding.NewEncoder(dst).Encode(eb)
+}
+
+// ImportEncryptedBackup restores a wallet from an encrypted backup, using the
+// backup's primary seed as the primary seed of the wallet and encrypting the
+// wallet with masterKey. If masterKey is blank, the hash of the primary seed
+// is used instead. The backup is fully decrypted before the wallet is
+// modified, so a wrong passphrase leaves the wallet untouched. A wallet that
+// has already been encrypted is only replaced if 'overwrite' is set. The
+// wallet is locked after the import, and rescans the blockchain when it is
+// unlocked.
+func (w *Wallet) ImportEncryptedBackup(src io.Reader, passphrase string, masterKey crypto.TwofishKey, overwrite bool) error {
+	if err := w.tg.Add(); err != nil {
+		return err
+	}
+	defer w.tg.Done()
+
+	wb, err := decryptBackup(src, passphrase)
+	if err != nil {
+		return err
+	}
+	if masterKey == (crypto.TwofishKey{}) {
+		masterKey = crypto.TwofishKey(crypto.HashObject(wb.PrimarySeed))
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.encrypted {
+		if !overwrite {
+			return errWalletNotEmpty
+		}
+		if err := w.reset(); err != nil {
+			return err
+		}
+	}
+	if _, err := w.initEncryption(masterKey, wb.PrimarySeed, wb.PrimarySeedProgress); err != nil {
+		return err
+	}
+
+	seedFiles := make([]seedFile, 0, len(wb.AuxiliarySeeds))
+	for _, seed := range wb.AuxiliarySeeds {
+		seedFiles = append(seedFiles, createSeedFile(masterKey, seed))
+	}
+	keyFiles := make([]spendableKeyFile, 0, len(wb.UnseededKeys))
+	for _, sk := range wb.UnseededKeys {
+		keyFiles = append(keyFiles, createSpendableKeyFile(masterKey, sk))
+	}
+	bucket := w.dbTx.Bucket(bucketWallet)
+	if err := bucket.Put(keyAuxiliarySeedFiles, encoding.Marshal(seedFiles)); err != nil {
+		return err
+	}
+	if err := bucket.Put(keySpendableKeyFiles, encoding.Marshal(keyFiles)); err != nil {
+		return err
+	}
+	// Commit the imported secrets right away, so that they survive a crash.
+	return w.syncDB()
+}
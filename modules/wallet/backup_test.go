@@ -0,0 +1,170 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+// TestEncryptedBackup exports an encrypted backup from a wallet and imports
+// it into a blank wallet.
+func TestEncryptedBackup(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Load an unseeded key into the wallet so that it is part of the backup.
+	err = wt.wallet.LoadSiagKeys(wt.walletMasterKey, []string{"../../types/siag0of1of1.siakey"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, siafundBal, _, err := wt.wallet.ConfirmedBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if siafundBal.IsZero() {
+		t.Fatal("expected the unseeded key to have a siafund balance")
+	}
+	seed, _, err := wt.wallet.PrimarySeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Export the backup.
+	var buf bytes.Buffer
+	if err := wt.wallet.ExportEncryptedBackup(&buf, crypto.TwofishKey{}, "passphrase"); err != modules.ErrBadEncryptionKey {
+		t.Fatal("expected ErrBadEncryptionKey, got", err)
+	}
+	if err := wt.wallet.ExportEncryptedBackup(&buf, wt.walletMasterKey, "passphrase"); err != nil {
+		t.Fatal(err)
+	}
+	backup := buf.Bytes()
+
+	// Create a blank wallet to import the backup into.
+	wt2, err := createBlankWalletTester(t.Name() + "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt2.closeWt()
+
+	// Importing with the wrong passphrase should fail without modifying the
+	// wallet.
+	err = wt2.wallet.ImportEncryptedBackup(bytes.NewReader(backup), "wrong", crypto.TwofishKey{}, false)
+	if err != modules.ErrBadEncryptionKey {
+		t.Fatal("expected ErrBadEncryptionKey, got", err)
+	}
+	if encrypted, err := wt2.wallet.Encrypted(); err != nil {
+		t.Fatal(err)
+	} else if encrypted {
+		t.Fatal("wallet was modified by an import with the wrong passphrase")
+	}
+
+	// Import the backup and unlock the wallet.
+	masterKey := crypto.GenerateTwofishKey()
+	err = wt2.wallet.ImportEncryptedBackup(bytes.NewReader(backup), "passphrase", masterKey, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The imported secrets should already be committed to the database.
+	err = wt2.wallet.db.View(func(tx *bolt.Tx) error {
+		var keyFiles []spendableKeyFile
+		err := encoding.Unmarshal(tx.Bucket(bucketWallet).Get(keySpendableKeyFiles), &keyFiles)
+		if err == nil && len(keyFiles) != 1 {
+			err = errors.New("imported key was not committed")
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wt2.wallet.Unlock(masterKey); err != nil {
+		t.Fatal(err)
+	}
+	seed2, _, err := wt2.wallet.PrimarySeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seed2 != seed {
+		t.Fatal("imported wallet has a different primary seed")
+	}
+	_, siafundBal2, _, err := wt2.wallet.ConfirmedBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !siafundBal2.Equals(siafundBal) {
+		t.Fatalf("imported wallet has siafund balance %v, expected %v", siafundBal2, siafundBal)
+	}
+
+	// Importing into a wallet that already has a seed requires an overwrite.
+	newKey := crypto.GenerateTwofishKey()
+	err = wt.wallet.ImportEncryptedBackup(bytes.NewReader(backup), "passphrase", newKey, false)
+	if err != errWalletNotEmpty {
+		t.Fatal("expected errWalletNotEmpty, got", err)
+	}
+	if err := wt.wallet.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.Unlock(wt.walletMasterKey); err != nil {
+		t.Fatal("wallet was modified by a rejected import:", err)
+	}
+	err = wt.wallet.ImportEncryptedBackup(bytes.NewReader(backup), "passphrase", newKey, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.Unlock(newKey); err != nil {
+		t.Fatal(err)
+	}
+	addrs, err := wt.wallet.AllAddresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) == 0 || addrs[0] == (types.UnlockHash{}) {
+		t.Fatal("overwritten wallet has no addresses")
+	}
+}
+
+// TestEncryptedBackupKDF checks that backups with unusable key derivation
+// parameters are rejected before any key is derived.
+func TestEncryptedBackupKDF(t *testing.T) {
+	for _, params := range []backupKDFParams{
+		{Time: 0, Memory: 1 << 10, Threads: 1},
+		{Time: 1, Memory: 1 << 10, Threads: 0},
+		{Time: 1, Memory: maxBackupKDFMemory + 1, Threads: 1},
+	} {
+		eb := encryptedBackup{
+			Header:  backupHeader,
+			Version: backupVersion,
+			KDF:     params,
+		}
+		if _, err := decryptBackup(bytes.NewReader(encoding.Marshal(eb)), "passphrase"); err != errBackupKDF {
+			t.Fatalf("expected errBackupKDF for %v, got %v", params, err)
+		}
+	}
+
+	// The same passphrase with different salts yields different keys.
+	key1, err := backupEncryptionKey("passphrase", uniqueID{1}, defaultBackupKDF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key2, err := backupEncryptionKey("passphrase", uniqueID{2}, defaultBackupKDF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key1 == key2 {
+		t.Fatal("salt does not affect the backup key")
+	}
+}
@@ -0,0 +1,209 @@
+package wallet
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errBumpFeeTooLow      = errors.New("new fee must be higher than the fee of the original transaction")
+	errBumpForeignFields  = errors.New("transaction contains inputs or signatures that the wallet cannot recreate")
+	errBumpInputsSpent    = errors.New("an input of the transaction has already been spent by another transaction")
+	errBumpNotUnconfirmed = errors.New("transaction is not an unconfirmed wallet transaction")
+	errBumpOutputsSpent   = errors.New("an output of the transaction is spent by another unconfirmed transaction")
+	errBumpReplaced       = errors.New("transaction has already been replaced")
+)
+
+// checkReplaceable checks that the wallet is able to create a replacement for
+// an unconfirmed transaction with the given unconfirmed parents. The
+// transaction must only have inputs controlled by the wallet, each of which
+// must still be unspent or be created by one of the parents.
+func (w *Wallet) checkReplaceable(txn types.Transaction, parents []types.Transaction) error {
+	if len(txn.FileContractRevisions) > 0 || len(txn.StorageProofs) > 0 {
+		return errBumpForeignFields
+	}
+
+	created := make(map[types.OutputID]struct{})
+	for _, parent := range parents {
+		for i := range parent.SiacoinOutputs {
+			created[types.OutputID(parent.SiacoinOutputID(uint64(i)))] = struct{}{}
+		}
+		for i := range parent.SiafundOutputs {
+			created[types.OutputID(parent.SiafundOutputID(uint64(i)))] = struct{}{}
+		}
+	}
+	for _, sci := range txn.SiacoinInputs {
+		if _, exists := w.keys[sci.UnlockConditions.UnlockHash()]; !exists {
+			return errBumpForeignFields
+		}
+		_, confirmed := dbGetSiacoinOutput(w.dbTx, sci.ParentID)
+		_, unconfirmed := created[types.OutputID(sci.ParentID)]
+		if confirmed != nil && !unconfirmed {
+			return errBumpInputsSpent
+		}
+	}
+	for _, sfi := range txn.SiafundInputs {
+		if _, exists := w.keys[sfi.UnlockConditions.UnlockHash()]; !exists {
+			return errBumpForeignFields
+		}
+		_, confirmed := dbGetSiafundOutput(w.dbTx, sfi.ParentID)
+		_, unconfirmed := created[types.OutputID(sfi.ParentID)]
+		if confirmed != nil && !unconfirmed {
+			return errBumpInputsSpent
+		}
+	}
+
+	// Replacing the transaction would invalidate any unconfirmed transaction
+	// that spends its outputs.
+	outputs := make(map[types.SiacoinOutputID]struct{})
+	for i := range txn.SiacoinOutputs {
+		outputs[txn.SiacoinOutputID(uint64(i))] = struct{}{}
+	}
+	for _, upt := range w.unconfirmedProcessedTransactions {
+		for _, sci := range upt.Transaction.SiacoinInputs {
+			if _, exists := outputs[sci.ParentID]; exists {
+				return errBumpOutputsSpent
+			}
+		}
+	}
+	return nil
+}
+
+// BumpFee replaces an unconfirmed transaction with a transaction that spends
+// the same inputs, creates the same outputs, and pays 'newFee' in miner fees.
+// The difference between the old and new fee is funded by an additional
+// input. The replacement is submitted to the transaction pool in place of the
+// original, and the original is recorded as replaced so that it cannot be
+// replaced a second time.
+func (w *Wallet) BumpFee(txid types.TransactionID, newFee types.Currency) (txn types.Transaction, err error) {
+	if err := w.tg.Add(); err != nil {
+		return types.Transaction{}, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	if err := w.managedBeginSpend(); err != nil {
+		return types.Transaction{}, err
+	}
+	defer w.managedEndSpend()
+	w.mu.RLock()
+	_, replacedErr := dbGetReplacedTransaction(w.dbTx, txid)
+	w.mu.RUnlock()
+	if replacedErr == nil {
+		return types.Transaction{}, errBumpReplaced
+	}
+
+	// Find the original transaction and its unconfirmed parents.
+	original, parents, exists := w.tpool.Transaction(txid)
+	if !exists {
+		return types.Transaction{}, errBumpNotUnconfirmed
+	}
+	var oldFee types.Currency
+	for _, fee := range original.MinerFees {
+		oldFee = oldFee.Add(fee)
+	}
+	if newFee.Cmp(oldFee) <= 0 {
+		return types.Transaction{}, errBumpFeeTooLow
+	}
+
+	w.mu.Lock()
+	tracked := false
+	for _, upt := range w.unconfirmedProcessedTransactions {
+		if upt.TransactionID == txid {
+			tracked = true
+			break
+		}
+	}
+	if !tracked {
+		w.mu.Unlock()
+		return types.Transaction{}, errBumpNotUnconfirmed
+	}
+	if err := w.checkReplaceable(original, parents); err != nil {
+		w.mu.Unlock()
+		return types.Transaction{}, err
+	}
+	consensusHeight, err := dbGetConsensusHeight(w.dbTx)
+	if err != nil {
+		w.mu.Unlock()
+		return types.Transaction{}, err
+	}
+
+	// Register the replacement with the transaction builder. The builder
+	// re-signs the original inputs in addition to the inputs it adds. The
+	// transactions share their slices with the sets in the transaction pool,
+	// so they are copied before being modified.
+	var replacement types.Transaction
+	encoding.Unmarshal(encoding.Marshal(original), &replacement)
+	var parentsCopy []types.Transaction
+	encoding.Unmarshal(encoding.Marshal(parents), &parentsCopy)
+	replacement.MinerFees = []types.Currency{newFee}
+	replacement.TransactionSignatures = nil
+	tb := w.registerTransaction(replacement, parentsCopy)
+	for i := range tb.transaction.SiacoinInputs {
+		tb.siacoinInputs = append(tb.siacoinInputs, i)
+	}
+	for i := range tb.transaction.SiafundInputs {
+		tb.siafundInputs = append(tb.siafundInputs, i)
+	}
+	// The outputs of the original will not exist once it has been replaced,
+	// so they are marked as spent while the fee is funded.
+	var originalOutputs []types.OutputID
+	for i := range original.SiacoinOutputs {
+		id := types.OutputID(original.SiacoinOutputID(uint64(i)))
+		if _, err := dbGetSpentOutput(w.dbTx, id); err == nil {
+			continue
+		}
+		originalOutputs = append(originalOutputs, id)
+		if err := dbPutSpentOutput(w.dbTx, id, consensusHeight); err != nil {
+			w.mu.Unlock()
+			return types.Transaction{}, err
+		}
+	}
+	w.mu.Unlock()
+
+	defer func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		for _, id := range originalOutputs {
+			dbDeleteSpentOutput(w.dbTx, id)
+		}
+		// If the replacement failed, release the outputs that were added to
+		// fund it. The inputs of the original are still spent by the original.
+		if err != nil {
+			for _, i := range tb.newParents {
+				for _, sci := range tb.parents[i].SiacoinInputs {
+					dbDeleteSpentOutput(w.dbTx, types.OutputID(sci.ParentID))
+				}
+			}
+		}
+	}()
+
+	err = tb.FundSiacoins(newFee.Sub(oldFee))
+	if err != nil {
+		w.log.Println("Attempt to bump fee has failed - failed to fund transaction:", err)
+		return types.Transaction{}, build.ExtendErr("unable to fund fee increase", err)
+	}
+	txnSet, err := tb.Sign(true)
+	if err != nil {
+		w.log.Println("Attempt to bump fee has failed - failed to sign transaction:", err)
+		return types.Transaction{}, build.ExtendErr("unable to sign transaction", err)
+	}
+	txn = txnSet[len(txnSet)-1]
+	err = w.tpool.ReplaceTransactionSet(txid, txnSet)
+	if err != nil {
+		w.log.Println("Attempt to bump fee has failed - transaction pool rejected replacement:", err)
+		return types.Transaction{}, build.ExtendErr("unable to get replacement accepted", err)
+	}
+
+	w.mu.Lock()
+	err = dbPutReplacedTransaction(w.dbTx, txid, txn.ID())
+	w.mu.Unlock()
+	if err != nil {
+		return types.Transaction{}, err
+	}
+	w.log.Println("Replaced transaction", txid, "with", txn.ID(), "raising the fee from", oldFee.HumanString(), "to", newFee.HumanString())
+	return txn, nil
+}
@@ -0,0 +1,125 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestBumpFee probes the BumpFee method of the wallet.
+func TestBumpFee(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	uc, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	txns, err := wt.wallet.SendSiacoins(types.SiacoinPrecision, uc.UnlockHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := txns[len(txns)-1]
+	var oldFee types.Currency
+	for _, fee := range original.MinerFees {
+		oldFee = oldFee.Add(fee)
+	}
+
+	// The fee must increase.
+	_, err = wt.wallet.BumpFee(original.ID(), oldFee)
+	if err != errBumpFeeTooLow {
+		t.Fatal("expected errBumpFeeTooLow, got", err)
+	}
+
+	// Replace the transaction. The replacement should be in the transaction
+	// pool in place of the original.
+	newFee := oldFee.Mul64(2)
+	replacement, err := wt.wallet.BumpFee(original.ID(), newFee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replacement.MinerFees) != 1 || !replacement.MinerFees[0].Equals(newFee) {
+		t.Error("replacement has the wrong fee")
+	}
+	if replacement.SiacoinOutputs[0].UnlockHash != original.SiacoinOutputs[0].UnlockHash || !replacement.SiacoinOutputs[0].Value.Equals(original.SiacoinOutputs[0].Value) {
+		t.Error("replacement does not create the same output")
+	}
+	if _, _, exists := wt.tpool.Transaction(original.ID()); exists {
+		t.Error("original transaction is still in the transaction pool")
+	}
+	if _, _, exists := wt.tpool.Transaction(replacement.ID()); !exists {
+		t.Error("replacement is not in the transaction pool")
+	}
+
+	// The original cannot be replaced a second time.
+	_, err = wt.wallet.BumpFee(original.ID(), newFee.Mul64(2))
+	if err != errBumpReplaced {
+		t.Fatal("expected errBumpReplaced, got", err)
+	}
+
+	// The replacement should be confirmed in the next block, after which it
+	// can no longer be replaced.
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	pt, found, err := wt.wallet.Transaction(replacement.ID())
+	if err != nil || !found {
+		t.Fatal("replacement was not confirmed:", err)
+	}
+	if pt.ConfirmationHeight != wt.cs.Height() {
+		t.Error("replacement has the wrong confirmation height")
+	}
+	_, err = wt.wallet.BumpFee(replacement.ID(), newFee.Mul64(2))
+	if err != errBumpNotUnconfirmed {
+		t.Fatal("expected errBumpNotUnconfirmed, got", err)
+	}
+}
+
+// TestCheckReplaceable probes the checkReplaceable method of the wallet.
+func TestCheckReplaceable(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	txns, err := wt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn, parents := txns[len(txns)-1], txns[:len(txns)-1]
+
+	wt.wallet.mu.Lock()
+	defer wt.wallet.mu.Unlock()
+	if err := wt.wallet.checkReplaceable(txn, parents); err != nil {
+		t.Fatal(err)
+	}
+
+	// An input that is neither a confirmed wallet output nor created by a
+	// parent has been spent elsewhere.
+	spent := txn
+	spent.SiacoinInputs = append([]types.SiacoinInput{{
+		ParentID:         types.SiacoinOutputID{1},
+		UnlockConditions: txn.SiacoinInputs[0].UnlockConditions,
+	}}, txn.SiacoinInputs...)
+	if err := wt.wallet.checkReplaceable(spent, parents); err != errBumpInputsSpent {
+		t.Error("expected errBumpInputsSpent, got", err)
+	}
+
+	// Inputs that the wallet cannot sign cannot be recreated.
+	foreign := txn
+	foreign.SiacoinInputs = []types.SiacoinInput{{ParentID: txn.SiacoinInputs[0].ParentID}}
+	if err := wt.wallet.checkReplaceable(foreign, parents); err != errBumpForeignFields {
+		t.Error("expected errBumpForeignFields, got", err)
+	}
+}
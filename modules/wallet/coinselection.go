@@ -0,0 +1,101 @@
+package wallet
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+var (
+	// errUnknownSelectionStrategy is returned when a transaction builder is
+	// requested with a selection strategy that the wallet does not support.
+	errUnknownSelectionStrategy = errors.New("unknown coin selection strategy")
+)
+
+// validSelectionStrategy returns true if the wallet supports the strategy. The
+// empty strategy selects the default, SelectMinimizeInputs.
+func validSelectionStrategy(strategy modules.SelectionStrategy) bool {
+	switch strategy {
+	case "", modules.SelectMinimizeInputs, modules.SelectMinimizeChange, modules.SelectOldestFirst:
+		return true
+	}
+	return false
+}
+
+// outputAges returns the position in the wallet's transaction history of the
+// transaction that created each of the provided outputs. Lower positions are
+// older. Outputs that do not appear in the history, such as unconfirmed
+// outputs, are omitted.
+func outputAges(tx *bolt.Tx, ids []types.SiacoinOutputID) map[types.SiacoinOutputID]uint64 {
+	wanted := make(map[types.OutputID]struct{}, len(ids))
+	for _, id := range ids {
+		wanted[types.OutputID(id)] = struct{}{}
+	}
+	ages := make(map[types.SiacoinOutputID]uint64, len(ids))
+	it := dbProcessedTransactionsIterator(tx)
+	for it.next() && len(ages) < len(wanted) {
+		for _, po := range it.value().Outputs {
+			if _, ok := wanted[po.ID]; !ok {
+				continue
+			}
+			if _, seen := ages[types.SiacoinOutputID(po.ID)]; !seen {
+				ages[types.SiacoinOutputID(po.ID)] = it.key()
+			}
+		}
+	}
+	return ages
+}
+
+// orderOutputs sorts spendable siacoin outputs into the order in which they
+// should be used to fund 'amount' under the provided strategy.
+func orderOutputs(tx *bolt.Tx, so sortedOutputs, amount types.Currency, strategy modules.SelectionStrategy) {
+	sort.Sort(sort.Reverse(so))
+	switch strategy {
+	case modules.SelectMinimizeChange:
+		// Move the smallest output that covers the amount to the front. The
+		// outputs are sorted from largest to smallest, so it is the last one
+		// that covers the amount.
+		i := sort.Search(so.Len(), func(i int) bool { return so.outputs[i].Value.Cmp(amount) < 0 })
+		for ; i > 1; i-- {
+			so.Swap(i-1, i-2)
+		}
+	case modules.SelectOldestFirst:
+		ages := outputAges(tx, so.ids)
+		sort.SliceStable(so.ids, func(i, j int) bool {
+			ai, iok := ages[so.ids[i]]
+			aj, jok := ages[so.ids[j]]
+			return iok && (!jok || ai < aj)
+		})
+		// Keep the outputs aligned with the reordered ids.
+		outputs := make(map[types.SiacoinOutputID]types.SiacoinOutput, so.Len())
+		for i := range so.outputs {
+			outputs[so.ids[i]] = so.outputs[i]
+		}
+		for i, id := range so.ids {
+			so.outputs[i] = outputs[id]
+		}
+	}
+}
+
+// NewTransactionBuilder returns an empty transaction builder that funds the
+// transaction with siacoin outputs picked by the provided selection strategy.
+// Outputs used by a builder are marked as spent, so concurrent builders never
+// select the same outputs.
+func (w *Wallet) NewTransactionBuilder(strategy modules.SelectionStrategy) (modules.TransactionBuilder, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer w.tg.Done()
+	if !validSelectionStrategy(strategy) {
+		return nil, errUnknownSelectionStrategy
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	tb := w.registerTransaction(types.Transaction{}, nil)
+	tb.strategy = strategy
+	return tb, nil
+}
@@ -0,0 +1,129 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestOrderOutputs probes the value based selection strategies.
+func TestOrderOutputs(t *testing.T) {
+	values := func(so sortedOutputs) (vs []uint64) {
+		for _, sco := range so.outputs {
+			v, _ := sco.Value.Uint64()
+			vs = append(vs, v)
+		}
+		return vs
+	}
+	newOutputs := func() (so sortedOutputs) {
+		for i, v := range []uint64{1, 5, 3, 10, 7} {
+			so.ids = append(so.ids, types.SiacoinOutputID{byte(i)})
+			so.outputs = append(so.outputs, types.SiacoinOutput{Value: types.NewCurrency64(v)})
+		}
+		return so
+	}
+	tests := []struct {
+		strategy modules.SelectionStrategy
+		amount   uint64
+		want     []uint64
+	}{
+		{"", 4, []uint64{10, 7, 5, 3, 1}},
+		{modules.SelectMinimizeInputs, 4, []uint64{10, 7, 5, 3, 1}},
+		{modules.SelectMinimizeChange, 4, []uint64{5, 10, 7, 3, 1}},
+		{modules.SelectMinimizeChange, 10, []uint64{10, 7, 5, 3, 1}},
+		{modules.SelectMinimizeChange, 11, []uint64{10, 7, 5, 3, 1}},
+		{modules.SelectMinimizeChange, 1, []uint64{1, 10, 7, 5, 3}},
+	}
+	for _, test := range tests {
+		so := newOutputs()
+		orderOutputs(nil, so, types.NewCurrency64(test.amount), test.strategy)
+		got := values(so)
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%q funding %v: expected %v, got %v", test.strategy, test.amount, test.want, got)
+				break
+			}
+		}
+		// The ids must stay aligned with the outputs.
+		for i, id := range so.ids {
+			if v, _ := so.outputs[i].Value.Uint64(); []uint64{1, 5, 3, 10, 7}[id[0]] != v {
+				t.Fatalf("%q funding %v: ids and outputs are misaligned", test.strategy, test.amount)
+			}
+		}
+	}
+}
+
+// TestNewTransactionBuilderOldestFirst checks that a builder using the
+// oldest-first strategy funds transactions with the wallet's oldest output,
+// and that unknown strategies are rejected.
+func TestNewTransactionBuilderOldestFirst(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+	for i := 0; i < 5; i++ {
+		if _, err := wt.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := wt.wallet.NewTransactionBuilder("largest-last"); err != errUnknownSelectionStrategy {
+		t.Fatal("expected errUnknownSelectionStrategy, got", err)
+	}
+
+	// Find the creation height of the oldest output in the wallet.
+	var ids []types.SiacoinOutputID
+	wt.wallet.mu.Lock()
+	err = dbForEachSiacoinOutput(wt.wallet.dbTx, func(id types.SiacoinOutputID, _ types.SiacoinOutput) {
+		ids = append(ids, id)
+	})
+	wt.wallet.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldest := wt.cs.Height()
+	for _, id := range ids {
+		_, height, err := wt.cs.OutputCreationBlock(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if height < oldest {
+			oldest = height
+		}
+	}
+
+	// Two builders funding concurrently must each use one of the oldest
+	// outputs without sharing it.
+	var used []types.SiacoinOutputID
+	for i := 0; i < 2; i++ {
+		tb, err := wt.wallet.NewTransactionBuilder(modules.SelectOldestFirst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tb.FundSiacoins(types.SiacoinPrecision); err != nil {
+			t.Fatal(err)
+		}
+		_, parents := tb.View()
+		if len(parents) != 1 || len(parents[0].SiacoinInputs) != 1 {
+			t.Fatal("expected a single parent with a single input")
+		}
+		used = append(used, parents[0].SiacoinInputs[0].ParentID)
+		defer tb.Drop()
+	}
+	if used[0] == used[1] {
+		t.Fatal("concurrent builders spent the same output")
+	}
+	_, height, err := wt.cs.OutputCreationBlock(used[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if height != oldest {
+		t.Fatalf("expected an output created at height %v, got %v", oldest, height)
+	}
+}
@@ -23,9 +23,15 @@ var (
 	// bucketProcessedTxnIndex maps a ProcessedTransactions ID to it's
 	// autoincremented index in bucketProcessedTransactions
 	bucketProcessedTxnIndex = []byte("bucketProcessedTxnKey")
+	// bucketReplacedTransactions maps the ID of an unconfirmed transaction
+	// that was replaced by BumpFee to the ID of its replacement.
+	bucketReplacedTransactions = []byte("bucketReplacedTransactions")
 	// bucketAddrTransactions maps an UnlockHash to the
 	// ProcessedTransactions that it appears in.
 	bucketAddrTransactions = []byte("bucketAddrTransactions")
+	// bucketMultiSigAddresses maps the UnlockHash of a multisig address that
+	// the wallet participates in to its UnlockConditions.
+	bucketMultiSigAddresses = []byte("bucketMultiSigAddresses")
 	// bucketSiacoinOutputs maps a SiacoinOutputID to its SiacoinOutput. Only
 	// outputs that the wallet controls are stored. The wallet uses these
 	// outputs to fund transactions.
@@ -46,7 +52,9 @@ var (
 	dbBuckets = [][]byte{
 		bucketProcessedTransactions,
 		bucketProcessedTxnIndex,
+		bucketReplacedTransactions,
 		bucketAddrTransactions,
+		bucketMultiSigAddresses,
 		bucketSiacoinOutputs,
 		bucketSiafundOutputs,
 		bucketSpentOutputs,
@@ -57,6 +65,7 @@ var (
 
 	// these keys are used in bucketWallet
 	keyAuxiliarySeedFiles     = []byte("keyAuxiliarySeedFiles")
+	keyChangeDustThreshold    = []byte("keyChangeDustThreshold")
 	keyConsensusChange        = []byte("keyConsensusChange")
 	keyConsensusHeight        = []byte("keyConsensusHeight")
 	keyEncryptionVerification = []byte("keyEncryptionVerification")
@@ -232,6 +241,14 @@ func dbDeleteSpentOutput(tx *bolt.Tx, id types.OutputID) error {
 	return dbDelete(tx.Bucket(bucketSpentOutputs), id)
 }
 
+func dbPutReplacedTransaction(tx *bolt.Tx, txid, replacement types.TransactionID) error {
+	return dbPut(tx.Bucket(bucketReplacedTransactions), txid, replacement)
+}
+func dbGetReplacedTransaction(tx *bolt.Tx, txid types.TransactionID) (replacement types.TransactionID, err error) {
+	err = dbGet(tx.Bucket(bucketReplacedTransactions), txid, &replacement)
+	return
+}
+
 func dbPutAddrTransactions(tx *bolt.Tx, addr types.UnlockHash, txns []uint64) error {
 	return dbPut(tx.Bucket(bucketAddrTransactions), addr, txns)
 }
@@ -477,6 +494,19 @@ func dbPutSiafundPool(tx *bolt.Tx, pool types.Currency) error {
 	return tx.Bucket(bucketWallet).Put(keySiafundPool, encoding.Marshal(pool))
 }
 
+// dbGetChangeDustThreshold returns the value below which change is paid to the
+// miners.
+func dbGetChangeDustThreshold(tx *bolt.Tx) (threshold types.Currency, err error) {
+	err = encoding.Unmarshal(tx.Bucket(bucketWallet).Get(keyChangeDustThreshold), &threshold)
+	return
+}
+
+// dbPutChangeDustThreshold stores the value below which change is paid to the
+// miners.
+func dbPutChangeDustThreshold(tx *bolt.Tx, threshold types.Currency) error {
+	return tx.Bucket(bucketWallet).Put(keyChangeDustThreshold, encoding.Marshal(threshold))
+}
+
 // COMPATv121: these types were stored in the db in v1.2.2 and earlier.
 type (
 	v121ProcessedInput struct {
@@ -224,6 +224,7 @@ func (w *Wallet) managedUnlock(masterKey crypto.TwofishKey) error {
 	w.mu.Lock()
 	w.unlocked = true
 	w.subscribed = true
+	w.resetAutoLockTimer()
 	w.mu.Unlock()
 	return nil
 }
@@ -325,7 +326,12 @@ func (w *Wallet) Reset() error {
 	defer w.tg.Done()
 	w.mu.Lock()
 	defer w.mu.Unlock()
+	return w.reset()
+}
 
+// reset clears the wallet database and returns the wallet to the unencrypted
+// state.
+func (w *Wallet) reset() error {
 	wb := w.dbTx.Bucket(bucketWallet)
 	if wb.Get(keyEncryptionVerification) == nil {
 		return errUnencryptedWallet
@@ -338,6 +344,11 @@ func (w *Wallet) Reset() error {
 	if err != nil {
 		return err
 	}
+	// the settings of the wallet survive the reset
+	err = dbPutChangeDustThreshold(w.dbTx, w.changeDustThreshold)
+	if err != nil {
+		return err
+	}
 	w.wipeSecrets()
 	w.keys = make(map[types.UnlockHash]spendableKey)
 	w.lookahead = make(map[types.UnlockHash]uint64)
@@ -596,6 +607,7 @@ func (w *Wallet) managedLock() error {
 	// we can continue processing blocks.
 	w.wipeSecrets()
 	w.unlocked = false
+	w.resetAutoLockTimer()
 	return nil
 }
 
@@ -0,0 +1,146 @@
+package wallet
+
+// lock.go implements an advisory lock on the wallet's persist directory. A
+// wallet that is opened creates a lock file containing the pid of its process,
+// and removes the file again when it is closed. A second wallet that is opened
+// on the same directory while the lock file exists fails fast instead of
+// sharing the database with the first wallet. If the process that created the
+// lock file is no longer running, the lock is stale and can be broken
+// explicitly using BreakStaleLock.
+//
+// Pids are reused, most commonly by a daemon that runs as the same pid in a
+// container each time it is started. A lock file containing the pid of the
+// current process is therefore only considered held if the current process
+// acquired it.
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+const (
+	lockFile = modules.WalletDir + ".lock"
+)
+
+var (
+	// ErrWalletLocked is returned when the wallet directory is already in use
+	// by a running wallet.
+	ErrWalletLocked = errors.New("wallet directory is in use by another wallet")
+
+	// ErrStaleLock is returned when the wallet directory is locked by a
+	// process that is no longer running. The lock can be removed using
+	// BreakStaleLock.
+	ErrStaleLock = errors.New("wallet directory is locked by a process that is no longer running")
+
+	// errLockNotStale is returned when trying to break a lock that is held by
+	// a running process.
+	errLockNotStale = errors.New("cannot break a lock that is held by a running process")
+)
+
+var (
+	// heldLocks contains the persist directories that are locked by wallets
+	// of the current process.
+	heldLocks   = make(map[string]struct{})
+	heldLocksMu sync.Mutex
+)
+
+// lockKey returns the key of a persist directory in heldLocks.
+func lockKey(persistDir string) string {
+	if abs, err := filepath.Abs(persistDir); err == nil {
+		return abs
+	}
+	return persistDir
+}
+
+// processRunning returns true if a process with the given pid is running.
+func processRunning(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// On windows FindProcess fails for processes that do not exist, and
+	// signals are not supported.
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
+
+// lockHeld returns true if the lock of the persist directory, which contains
+// the given pid, is held by a running wallet.
+func lockHeld(persistDir string, pid int) bool {
+	if pid == os.Getpid() {
+		heldLocksMu.Lock()
+		defer heldLocksMu.Unlock()
+		_, held := heldLocks[lockKey(persistDir)]
+		return held
+	}
+	return processRunning(pid)
+}
+
+// readLock returns the pid stored in the lock file of the persist directory.
+func readLock(persistDir string) (int, error) {
+	b, err := ioutil.ReadFile(filepath.Join(persistDir, lockFile))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(b)))
+}
+
+// acquireLock creates the lock file in the persist directory. If the lock
+// file already exists, ErrWalletLocked or ErrStaleLock is returned.
+func acquireLock(persistDir string) error {
+	f, err := os.OpenFile(filepath.Join(persistDir, lockFile), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if os.IsExist(err) {
+		// A lock file that cannot be parsed was most likely left behind by
+		// a crash while it was being written.
+		pid, err := readLock(persistDir)
+		if err != nil || !lockHeld(persistDir, pid) {
+			return ErrStaleLock
+		}
+		return ErrWalletLocked
+	} else if err != nil {
+		return err
+	}
+	_, err = f.WriteString(strconv.Itoa(os.Getpid()))
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	heldLocksMu.Lock()
+	heldLocks[lockKey(persistDir)] = struct{}{}
+	heldLocksMu.Unlock()
+	return f.Close()
+}
+
+// releaseLock removes the lock file from the persist directory.
+func releaseLock(persistDir string) error {
+	heldLocksMu.Lock()
+	delete(heldLocks, lockKey(persistDir))
+	heldLocksMu.Unlock()
+	return os.Remove(filepath.Join(persistDir, lockFile))
+}
+
+// BreakStaleLock removes the lock file from a wallet directory that was left
+// behind by a wallet which did not shut down cleanly. An error is returned if
+// the lock is held by a running process.
+func BreakStaleLock(persistDir string) error {
+	pid, err := readLock(persistDir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err == nil && lockHeld(persistDir, pid) {
+		return errLockNotStale
+	}
+	return releaseLock(persistDir)
+}
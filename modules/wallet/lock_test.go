@@ -0,0 +1,92 @@
+package wallet
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// TestWalletDirLock checks that a wallet directory cannot be opened by two
+// wallets at once, and that the lock is released when the wallet is closed.
+func TestWalletDirLock(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	wt, err := createBlankWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+	walletDir := filepath.Join(wt.persistDir, modules.WalletDir)
+
+	// Opening a second wallet on the same directory should fail.
+	_, err = New(wt.cs, wt.tpool, walletDir)
+	if err != ErrWalletLocked {
+		t.Fatal("expected ErrWalletLocked, got", err)
+	}
+	// The lock is held by a running process and cannot be broken.
+	if err := BreakStaleLock(walletDir); err != errLockNotStale {
+		t.Fatal("expected errLockNotStale, got", err)
+	}
+
+	// After closing the wallet, the directory can be opened again.
+	if err := wt.wallet.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(walletDir, lockFile)); !os.IsNotExist(err) {
+		t.Fatal("lock file was not removed on close:", err)
+	}
+	w, err := New(wt.cs, wt.tpool, walletDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet = w
+}
+
+// TestWalletDirStaleLock checks that a lock left behind by a process that is
+// no longer running is detected and can be broken.
+func TestWalletDirStaleLock(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	wt, err := createBlankWalletTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+	if err := wt.wallet.Close(); err != nil {
+		t.Fatal(err)
+	}
+	walletDir := filepath.Join(wt.persistDir, modules.WalletDir)
+
+	// Simulate a crash by writing a lock file for a process that has exited.
+	// A lock file that cannot be parsed is treated the same way, and so is a
+	// lock file left behind by an earlier process with the same pid as this
+	// one.
+	for _, contents := range []string{strconv.Itoa(1 << 30), "garbage", strconv.Itoa(os.Getpid())} {
+		err = ioutil.WriteFile(filepath.Join(walletDir, lockFile), []byte(contents), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = New(wt.cs, wt.tpool, walletDir)
+		if err != ErrStaleLock {
+			t.Fatal("expected ErrStaleLock, got", err)
+		}
+		if err := BreakStaleLock(walletDir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The wallet can be opened after the stale lock was broken.
+	w, err := New(wt.cs, wt.tpool, walletDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet = w
+}
@@ -8,6 +8,12 @@ import (
 	"github.com/NebulousLabs/Sia/types"
 )
 
+var (
+	// errChangeMismatch is returned if the funding of a transaction does not
+	// send the entire remainder of the spent outputs to the change address.
+	errChangeMismatch = errors.New("change output does not cover the remainder of the transaction")
+)
+
 // sortedOutputs is a struct containing a slice of siacoin outputs and their
 // corresponding ids. sortedOutputs can be sorted using the sort package.
 type sortedOutputs struct {
@@ -27,6 +33,49 @@ func (w *Wallet) DustThreshold() (types.Currency, error) {
 	return minFee.Mul64(3), nil
 }
 
+// ChangeDustThreshold returns the value below which the change of a
+// transaction is added to the transaction's miner fees instead of being
+// returned to the wallet, see SetChangeDustThreshold.
+func (w *Wallet) ChangeDustThreshold() (types.Currency, error) {
+	if err := w.tg.Add(); err != nil {
+		return types.Currency{}, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.changeDustThreshold, nil
+}
+
+// SetChangeDustThreshold sets the value below which the change of a
+// transaction is added to the transaction's miner fees instead of being
+// returned to the wallet in a new output. Such an output would cost more in
+// fees to spend than it is worth, and would needlessly grow the set of
+// outputs. The tradeoff is that each transaction may overpay its fees by up
+// to the threshold. A threshold of 0, the default, disables this. The
+// threshold is persisted along with the other wallet settings.
+func (w *Wallet) SetChangeDustThreshold(c types.Currency) error {
+	if err := w.tg.Add(); err != nil {
+		return modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.setChangeDustThreshold(c)
+}
+
+// setChangeDustThreshold persists the change dust threshold and then updates
+// it in memory.
+func (w *Wallet) setChangeDustThreshold(c types.Currency) error {
+	if err := dbPutChangeDustThreshold(w.dbTx, c); err != nil {
+		return err
+	}
+	if err := w.syncDB(); err != nil {
+		return err
+	}
+	w.changeDustThreshold = c
+	return nil
+}
+
 // ConfirmedBalance returns the balance of the wallet according to all of the
 // confirmed transactions.
 func (w *Wallet) ConfirmedBalance() (siacoinBalance types.Currency, siafundBalance types.Currency, siafundClaimBalance types.Currency, err error) {
@@ -114,13 +163,11 @@ func (w *Wallet) SendSiacoins(amount types.Currency, dest types.UnlockHash) (txn
 	}
 	defer w.tg.Done()
 
-	w.mu.RLock()
-	unlocked := w.unlocked
-	w.mu.RUnlock()
-	if !unlocked {
+	if err := w.managedBeginSpend(); err != nil {
 		w.log.Println("Attempt to send coins has failed - wallet is locked")
-		return nil, modules.ErrLockedWallet
+		return nil, err
 	}
+	defer w.managedEndSpend()
 
 	_, tpoolFee := w.tpool.FeeEstimation()
 	tpoolFee = tpoolFee.Mul64(750) // Estimated transaction size in bytes
@@ -165,6 +212,93 @@ func (w *Wallet) SendSiacoins(amount types.Currency, dest types.UnlockHash) (txn
 	return txnSet, nil
 }
 
+// SendSiacoinsWithChange creates a transaction sending 'amount' to 'dest',
+// sending any change to 'change' instead of a wallet-generated address. The
+// transaction set is submitted to the transaction pool and the transaction
+// containing the output to 'dest' is returned.
+//
+// WARNING: 'change' does not need to belong to the wallet. If it does not, any
+// change sent to it can no longer be spent by this wallet.
+func (w *Wallet) SendSiacoinsWithChange(amount types.Currency, dest, change types.UnlockHash) (txn types.Transaction, err error) {
+	if err := w.tg.Add(); err != nil {
+		return types.Transaction{}, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	if err := w.managedBeginSpend(); err != nil {
+		w.log.Println("Attempt to send coins has failed - wallet is locked")
+		return types.Transaction{}, err
+	}
+	defer w.managedEndSpend()
+	w.mu.RLock()
+	_, changeOwned := w.keys[change]
+	w.mu.RUnlock()
+	if !changeOwned {
+		w.log.Println("WARN: sending change to an address that does not belong to the wallet:", change)
+	}
+
+	_, tpoolFee := w.tpool.FeeEstimation()
+	tpoolFee = tpoolFee.Mul64(750) // Estimated transaction size in bytes
+	output := types.SiacoinOutput{
+		Value:      amount,
+		UnlockHash: dest,
+	}
+
+	w.mu.Lock()
+	txnBuilder := w.registerTransaction(types.Transaction{}, nil)
+	w.mu.Unlock()
+	defer func() {
+		if err != nil {
+			txnBuilder.Drop()
+		}
+	}()
+	err = txnBuilder.fundSiacoins(amount.Add(tpoolFee), &change)
+	if err != nil {
+		w.log.Println("Attempt to send coins has failed - failed to fund transaction:", err)
+		return types.Transaction{}, build.ExtendErr("unable to fund transaction", err)
+	}
+	txnBuilder.AddMinerFee(tpoolFee)
+	txnBuilder.AddSiacoinOutput(output)
+	txnSet, err := txnBuilder.Sign(true)
+	if err != nil {
+		w.log.Println("Attempt to send coins has failed - failed to sign transaction:", err)
+		return types.Transaction{}, build.ExtendErr("unable to sign transaction", err)
+	}
+
+	// Check that the outputs consumed by the transaction add up to exactly the
+	// amount plus the fee, and that everything else created by the parents is
+	// sent to the change address.
+	txn = txnSet[len(txnSet)-1]
+	spent := make(map[types.SiacoinOutputID]struct{})
+	for _, sci := range txn.SiacoinInputs {
+		spent[sci.ParentID] = struct{}{}
+	}
+	var funded types.Currency
+	for _, parent := range txnSet[:len(txnSet)-1] {
+		for i, sco := range parent.SiacoinOutputs {
+			if _, exists := spent[parent.SiacoinOutputID(uint64(i))]; exists {
+				funded = funded.Add(sco.Value)
+			} else if sco.UnlockHash != change {
+				return types.Transaction{}, errChangeMismatch
+			}
+		}
+	}
+	if !funded.Equals(amount.Add(tpoolFee)) {
+		return types.Transaction{}, errChangeMismatch
+	}
+
+	err = w.tpool.AcceptTransactionSet(txnSet)
+	if err != nil {
+		w.log.Println("Attempt to send coins has failed - transaction pool rejected transaction:", err)
+		return types.Transaction{}, build.ExtendErr("unable to get transaction accepted", err)
+	}
+	w.log.Println("Submitted a siacoin transfer transaction set for value", amount.HumanString(), "with fees", tpoolFee.HumanString(), "and change address", change, "IDs:")
+	for _, t := range txnSet {
+		w.log.Println("\t", t.ID())
+	}
+	return txn, nil
+}
+
 // SendSiacoinsMulti creates a transaction that includes the specified
 // outputs. The transaction is submitted to the transaction pool and is also
 // returned.
@@ -175,13 +309,11 @@ func (w *Wallet) SendSiacoinsMulti(outputs []types.SiacoinOutput) (txns []types.
 		return nil, err
 	}
 	defer w.tg.Done()
-	w.mu.RLock()
-	unlocked := w.unlocked
-	w.mu.RUnlock()
-	if !unlocked {
+	if err := w.managedBeginSpend(); err != nil {
 		w.log.Println("Attempt to send coins has failed - wallet is locked")
-		return nil, modules.ErrLockedWallet
+		return nil, err
 	}
+	defer w.managedEndSpend()
 
 	txnBuilder, err := w.StartTransaction()
 	if err != nil {
@@ -249,12 +381,10 @@ func (w *Wallet) SendSiafunds(amount types.Currency, dest types.UnlockHash) (txn
 		return nil, err
 	}
 	defer w.tg.Done()
-	w.mu.RLock()
-	unlocked := w.unlocked
-	w.mu.RUnlock()
-	if !unlocked {
-		return nil, modules.ErrLockedWallet
+	if err := w.managedBeginSpend(); err != nil {
+		return nil, err
 	}
+	defer w.managedEndSpend()
 
 	_, tpoolFee := w.tpool.FeeEstimation()
 	tpoolFee = tpoolFee.Mul64(750) // Estimated transaction size in bytes
@@ -89,6 +89,123 @@ func TestSendSiacoins(t *testing.T) {
 	}
 }
 
+// TestUnconfirmedBalanceEviction checks that the unconfirmed balance of the
+// wallet is updated when a pending transaction is evicted from the transaction
+// pool.
+func TestUnconfirmedBalanceEviction(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	_, err = wt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	outgoing, incoming, err := wt.wallet.UnconfirmedBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outgoing.IsZero() || outgoing.Cmp(incoming) <= 0 {
+		t.Fatal("pending send is not reflected in the unconfirmed balance")
+	}
+
+	// Evict the transaction from the pool.
+	wt.tpool.PurgeTransactionPool()
+	outgoing, incoming, err = wt.wallet.UnconfirmedBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !outgoing.IsZero() || !incoming.IsZero() {
+		t.Error("evicted transaction is still reflected in the unconfirmed balance")
+	}
+}
+
+// TestSendSiacoinsWithChange probes the SendSiacoinsWithChange method of the
+// wallet, checking that change ends up at the provided address.
+func TestSendSiacoinsWithChange(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Send coins, using a wallet address as the change address.
+	uc, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	change := uc.UnlockHash()
+	sendValue := types.SiacoinPrecision.Mul64(3)
+	_, tpoolFee := wt.wallet.tpool.FeeEstimation()
+	tpoolFee = tpoolFee.Mul64(750)
+	txn, err := wt.wallet.SendSiacoinsWithChange(sendValue, types.UnlockHash{1}, change)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txn.SiacoinOutputs) != 1 || txn.SiacoinOutputs[0].UnlockHash != (types.UnlockHash{1}) || !txn.SiacoinOutputs[0].Value.Equals(sendValue) {
+		t.Fatal("returned transaction does not contain the expected output")
+	}
+
+	// The change output should be in the transaction pool.
+	var changeValue types.Currency
+	for _, ptxn := range wt.tpool.TransactionList() {
+		for _, sco := range ptxn.SiacoinOutputs {
+			if sco.UnlockHash == change {
+				changeValue = changeValue.Add(sco.Value)
+			}
+		}
+	}
+	if changeValue.IsZero() {
+		t.Fatal("no change output was sent to the change address")
+	}
+
+	// Because the change address belongs to the wallet, the balance should
+	// only decrease by the amount sent plus the fee.
+	confirmedBal, _, _, err := wt.wallet.ConfirmedBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := wt.miner.FindBlock()
+	err = wt.cs.AcceptBlock(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirmedBal2, _, _, err := wt.wallet.ConfirmedBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !confirmedBal2.Equals(confirmedBal.Add(types.CalculateCoinbase(2)).Sub(sendValue).Sub(tpoolFee)) {
+		t.Error("confirmed balance did not adjust to the expected value")
+	}
+
+	// Send change to an address that the wallet does not own. The change
+	// should be lost to the wallet.
+	_, err = wt.wallet.SendSiacoinsWithChange(sendValue, types.UnlockHash{1}, types.UnlockHash{2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ = wt.miner.FindBlock()
+	err = wt.cs.AcceptBlock(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirmedBal3, _, _, err := wt.wallet.ConfirmedBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if confirmedBal3.Cmp(confirmedBal2.Add(types.CalculateCoinbase(3)).Sub(sendValue).Sub(tpoolFee)) >= 0 {
+		t.Error("change sent to a foreign address was kept by the wallet")
+	}
+}
+
 // TestIntegrationSendOverUnder sends too many siacoins, resulting in an error,
 // followed by sending few enough siacoins that the send should complete.
 //
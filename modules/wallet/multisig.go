@@ -0,0 +1,185 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/coreos/bbolt"
+)
+
+var (
+	errMultiSigNoWalletKey = errors.New("none of the public keys belong to the wallet")
+	errMultiSigRequired    = errors.New("required signatures must be between 1 and the number of public keys")
+	errSignNoWalletKey     = errors.New("the wallet has no key that can sign for the input")
+	errSignUnknownParent   = errors.New("transaction has no input, siafund input, or revision with the given parent id")
+)
+
+// dbPutMultiSigAddress records the unlock conditions of a multisig address
+// that the wallet participates in.
+func dbPutMultiSigAddress(tx *bolt.Tx, uc types.UnlockConditions) error {
+	return dbPut(tx.Bucket(bucketMultiSigAddresses), uc.UnlockHash(), uc)
+}
+
+// dbForEachMultiSigAddress calls fn on the unlock conditions of every
+// multisig address that the wallet participates in.
+func dbForEachMultiSigAddress(tx *bolt.Tx, fn func(types.UnlockHash, types.UnlockConditions)) error {
+	return dbForEach(tx.Bucket(bucketMultiSigAddresses), fn)
+}
+
+// walletSecretKey returns the secret key of the wallet that corresponds to the
+// provided public key, if the wallet has one.
+func (w *Wallet) walletSecretKey(spk types.SiaPublicKey) (crypto.SecretKey, bool) {
+	if spk.Algorithm != types.SignatureEd25519 {
+		return crypto.SecretKey{}, false
+	}
+	for _, key := range w.keys {
+		for _, sk := range key.SecretKeys {
+			pk := sk.PublicKey()
+			if bytes.Equal(spk.Key, pk[:]) {
+				return sk, true
+			}
+		}
+	}
+	return crypto.SecretKey{}, false
+}
+
+// inputUnlockConditions returns the unlock conditions of the siacoin input,
+// siafund input, or file contract revision of txn with the provided parent
+// id.
+func inputUnlockConditions(txn types.Transaction, parentID crypto.Hash) (types.UnlockConditions, bool) {
+	for _, sci := range txn.SiacoinInputs {
+		if crypto.Hash(sci.ParentID) == parentID {
+			return sci.UnlockConditions, true
+		}
+	}
+	for _, sfi := range txn.SiafundInputs {
+		if crypto.Hash(sfi.ParentID) == parentID {
+			return sfi.UnlockConditions, true
+		}
+	}
+	for _, fcr := range txn.FileContractRevisions {
+		if crypto.Hash(fcr.ParentID) == parentID {
+			return fcr.UnlockConditions, true
+		}
+	}
+	return types.UnlockConditions{}, false
+}
+
+// NewMultiSigAddress creates an address that requires 'required' signatures
+// from the provided public keys to spend. At least one of the public keys
+// must belong to the wallet. The unlock conditions of the address are stored,
+// so the wallet knows which of its keys participate in it.
+func (w *Wallet) NewMultiSigAddress(pubkeys []types.SiaPublicKey, required uint64) (types.UnlockHash, error) {
+	if err := w.tg.Add(); err != nil {
+		return types.UnlockHash{}, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	if required == 0 || required > uint64(len(pubkeys)) {
+		return types.UnlockHash{}, errMultiSigRequired
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.unlocked {
+		return types.UnlockHash{}, modules.ErrLockedWallet
+	}
+	participates := false
+	for _, pk := range pubkeys {
+		if _, ok := w.walletSecretKey(pk); ok {
+			participates = true
+			break
+		}
+	}
+	if !participates {
+		return types.UnlockHash{}, errMultiSigNoWalletKey
+	}
+
+	uc := types.UnlockConditions{
+		PublicKeys:         pubkeys,
+		SignaturesRequired: required,
+	}
+	if err := dbPutMultiSigAddress(w.dbTx, uc); err != nil {
+		return types.UnlockHash{}, err
+	}
+	return uc.UnlockHash(), w.syncDB()
+}
+
+// MultiSigAddresses returns the unlock conditions of the multisig addresses
+// created with NewMultiSigAddress.
+func (w *Wallet) MultiSigAddresses() ([]types.UnlockConditions, error) {
+	if err := w.tg.Add(); err != nil {
+		return nil, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var ucs []types.UnlockConditions
+	err := dbForEachMultiSigAddress(w.dbTx, func(_ types.UnlockHash, uc types.UnlockConditions) {
+		ucs = append(ucs, uc)
+	})
+	return ucs, err
+}
+
+// SignTransaction adds the wallet's signatures to the inputs of txn whose
+// parent ids are in toSign. Each input is signed with every key of the wallet
+// that appears in its unlock conditions, until the input has as many
+// signatures as its unlock conditions require. The signatures cover the whole
+// transaction except for its signatures, so the signatures of other parties
+// can be combined by signing the same transaction in turn.
+func (w *Wallet) SignTransaction(txn *types.Transaction, toSign []crypto.Hash) error {
+	if err := w.tg.Add(); err != nil {
+		return modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	if err := w.managedBeginSpend(); err != nil {
+		return err
+	}
+	defer w.managedEndSpend()
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	for _, parentID := range toSign {
+		uc, ok := inputUnlockConditions(*txn, parentID)
+		if !ok {
+			return errSignUnknownParent
+		}
+
+		// Find the public keys that have already signed for the input.
+		signed := make(map[uint64]struct{})
+		for _, sig := range txn.TransactionSignatures {
+			if sig.ParentID == parentID {
+				signed[sig.PublicKeyIndex] = struct{}{}
+			}
+		}
+
+		canSign := false
+		for i, pk := range uc.PublicKeys {
+			sk, ok := w.walletSecretKey(pk)
+			if !ok {
+				continue
+			}
+			canSign = true
+			if _, ok := signed[uint64(i)]; ok || uint64(len(signed)) >= uc.SignaturesRequired {
+				continue
+			}
+			txn.TransactionSignatures = append(txn.TransactionSignatures, types.TransactionSignature{
+				ParentID:       parentID,
+				CoveredFields:  types.FullCoveredFields,
+				PublicKeyIndex: uint64(i),
+			})
+			sigIndex := len(txn.TransactionSignatures) - 1
+			encodedSig := crypto.SignHash(txn.SigHash(sigIndex), sk)
+			txn.TransactionSignatures[sigIndex].Signature = encodedSig[:]
+			signed[uint64(i)] = struct{}{}
+		}
+		if !canSign {
+			return errSignNoWalletKey
+		}
+	}
+	return nil
+}
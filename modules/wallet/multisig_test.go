@@ -0,0 +1,116 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestMultiSigAddress creates a 2-of-2 multisig address shared between the
+// wallet and an outside key, funds it, and spends from it by combining the
+// wallet's signature with the outside signature.
+func TestMultiSigAddress(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	uc, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	walletKey := uc.PublicKeys[0]
+	sk, pk := crypto.GenerateKeyPair()
+	otherKey := types.Ed25519PublicKey(pk)
+
+	// The wallet must participate in the address, and the number of required
+	// signatures must be satisfiable.
+	if _, err := wt.wallet.NewMultiSigAddress([]types.SiaPublicKey{otherKey}, 1); err != errMultiSigNoWalletKey {
+		t.Fatal("expected errMultiSigNoWalletKey, got", err)
+	}
+	if _, err := wt.wallet.NewMultiSigAddress([]types.SiaPublicKey{walletKey, otherKey}, 3); err != errMultiSigRequired {
+		t.Fatal("expected errMultiSigRequired, got", err)
+	}
+	addr, err := wt.wallet.NewMultiSigAddress([]types.SiaPublicKey{walletKey, otherKey}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ucs, err := wt.wallet.MultiSigAddresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ucs) != 1 || ucs[0].UnlockHash() != addr {
+		t.Fatal("multisig address was not recorded:", ucs)
+	}
+	msuc := ucs[0]
+
+	// Fund the address.
+	txns, err := wt.wallet.SendSiacoins(types.SiacoinPrecision.Mul64(10), addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	fundTxn := txns[len(txns)-1]
+	var parentID types.SiacoinOutputID
+	for i, sco := range fundTxn.SiacoinOutputs {
+		if sco.UnlockHash == addr {
+			parentID = fundTxn.SiacoinOutputID(uint64(i))
+		}
+	}
+
+	// Spend from the address. The wallet's signature alone is not enough.
+	txn := types.Transaction{
+		SiacoinInputs: []types.SiacoinInput{{
+			ParentID:         parentID,
+			UnlockConditions: msuc,
+		}},
+		SiacoinOutputs: []types.SiacoinOutput{{
+			Value:      types.SiacoinPrecision.Mul64(9),
+			UnlockHash: types.UnlockHash{},
+		}},
+		MinerFees: []types.Currency{types.SiacoinPrecision},
+	}
+	if err := wt.wallet.SignTransaction(&txn, []crypto.Hash{{}}); err != errSignUnknownParent {
+		t.Fatal("expected errSignUnknownParent, got", err)
+	}
+	if err := wt.wallet.SignTransaction(&txn, []crypto.Hash{crypto.Hash(parentID)}); err != nil {
+		t.Fatal(err)
+	}
+	if len(txn.TransactionSignatures) != 1 {
+		t.Fatal("expected a single signature, got", len(txn.TransactionSignatures))
+	}
+	// Signing again does not add a duplicate signature.
+	if err := wt.wallet.SignTransaction(&txn, []crypto.Hash{crypto.Hash(parentID)}); err != nil {
+		t.Fatal(err)
+	}
+	if len(txn.TransactionSignatures) != 1 {
+		t.Fatal("signing twice added a duplicate signature")
+	}
+	if err := txn.StandaloneValid(wt.cs.Height()); err == nil {
+		t.Fatal("partially signed transaction is valid")
+	}
+
+	// Combine the outside signature with the wallet's signature.
+	txn.TransactionSignatures = append(txn.TransactionSignatures, types.TransactionSignature{
+		ParentID:       crypto.Hash(parentID),
+		CoveredFields:  types.FullCoveredFields,
+		PublicKeyIndex: 1,
+	})
+	sig := crypto.SignHash(txn.SigHash(1), sk)
+	txn.TransactionSignatures[1].Signature = sig[:]
+	if err := wt.tpool.AcceptTransactionSet([]types.Transaction{txn}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -74,6 +74,9 @@ func (w *Wallet) openDB(filename string) (err error) {
 		if wb.Get(keySiafundPool) == nil {
 			wb.Put(keySiafundPool, encoding.Marshal(types.ZeroCurrency))
 		}
+		if wb.Get(keyChangeDustThreshold) == nil {
+			wb.Put(keyChangeDustThreshold, encoding.Marshal(types.ZeroCurrency))
+		}
 
 		// build the bucketAddrTransactions bucket if necessary
 		if buildAddrTxns {
@@ -88,6 +91,13 @@ func (w *Wallet) openDB(filename string) (err error) {
 
 		// check whether wallet is encrypted
 		w.encrypted = tx.Bucket(bucketWallet).Get(keyEncryptionVerification) != nil
+
+		// load the persisted settings
+		threshold, err := dbGetChangeDustThreshold(tx)
+		if err != nil {
+			return err
+		}
+		w.changeDustThreshold = threshold
 		return nil
 	})
 	return err
@@ -95,10 +105,28 @@ func (w *Wallet) openDB(filename string) (err error) {
 
 // initPersist loads all of the wallet's persistence files into memory,
 // creating them if they do not exist.
-func (w *Wallet) initPersist() error {
+func (w *Wallet) initPersist() (err error) {
 	// Create a directory for the wallet without overwriting an existing
 	// directory.
-	err := os.MkdirAll(w.persistDir, 0700)
+	err = os.MkdirAll(w.persistDir, 0700)
+	if err != nil {
+		return err
+	}
+
+	// Lock the directory so that no other wallet can use it. The lock is
+	// released after everything else has been closed.
+	err = acquireLock(w.persistDir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			releaseLock(w.persistDir)
+		}
+	}()
+	err = w.tg.AfterStop(func() error {
+		return releaseLock(w.persistDir)
+	})
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,129 @@
+package wallet
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errRawNoInputs         = errors.New("raw transaction has no inputs")
+	errRawUnknownInput     = errors.New("raw transaction spends an output that does not exist")
+	errRawUnlockConditions = errors.New("unlock conditions of input do not match the output it spends")
+	errRawUnbalanced       = errors.New("inputs of raw transaction do not equal its outputs plus the fee")
+)
+
+// CreateRawTransaction builds an unsigned transaction that spends exactly the
+// provided inputs and creates exactly the provided outputs. The inputs must
+// spend confirmed outputs. Inputs without unlock conditions that spend an
+// address of the wallet are given the unlock conditions of that address. The
+// value of the inputs must equal the value of the outputs plus 'fee', which is
+// paid to the miners. Requiring the fee explicitly means that a forgotten
+// change output is an error, rather than being paid to the miners.
+func (w *Wallet) CreateRawTransaction(inputs []types.SiacoinInput, outputs []types.SiacoinOutput, fee types.Currency) (types.Transaction, error) {
+	if err := w.tg.Add(); err != nil {
+		return types.Transaction{}, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	if len(inputs) == 0 {
+		return types.Transaction{}, errRawNoInputs
+	}
+
+	// Look up the outputs being spent.
+	ids := make([]types.SiacoinOutputID, 0, len(inputs))
+	for _, sci := range inputs {
+		ids = append(ids, sci.ParentID)
+	}
+	parents, err := w.cs.SiacoinOutputs(ids)
+	if err != nil {
+		return types.Transaction{}, err
+	}
+
+	txn := types.Transaction{
+		SiacoinInputs:  make([]types.SiacoinInput, len(inputs)),
+		SiacoinOutputs: append([]types.SiacoinOutput(nil), outputs...),
+	}
+	var inputSum, outputSum types.Currency
+	w.mu.RLock()
+	for i, sci := range inputs {
+		parent, exists := parents[sci.ParentID]
+		if !exists {
+			w.mu.RUnlock()
+			return types.Transaction{}, errRawUnknownInput
+		}
+		if sci.UnlockConditions.UnlockHash() != parent.UnlockHash {
+			key, owned := w.keys[parent.UnlockHash]
+			if !owned || len(sci.UnlockConditions.PublicKeys) != 0 {
+				w.mu.RUnlock()
+				return types.Transaction{}, errRawUnlockConditions
+			}
+			sci.UnlockConditions = key.UnlockConditions
+		}
+		txn.SiacoinInputs[i] = sci
+		inputSum = inputSum.Add(parent.Value)
+	}
+	w.mu.RUnlock()
+	for _, sco := range outputs {
+		outputSum = outputSum.Add(sco.Value)
+	}
+	if outputSum.Add(fee).Cmp(inputSum) != 0 {
+		return types.Transaction{}, errRawUnbalanced
+	}
+	if !fee.IsZero() {
+		txn.MinerFees = []types.Currency{fee}
+	}
+
+	// Check the rest of the transaction, except for the missing signatures.
+	if err := txn.StandaloneValidNoSignatures(w.cs.Height()); err != nil {
+		return types.Transaction{}, err
+	}
+	return txn, nil
+}
+
+// SignRawTransaction signs every siacoin input of txn that the wallet has a
+// key for, and returns the signed transaction. Inputs that the wallet cannot
+// sign for are left for other parties to sign, see SignTransaction.
+func (w *Wallet) SignRawTransaction(txn types.Transaction) (types.Transaction, error) {
+	if err := w.tg.Add(); err != nil {
+		return types.Transaction{}, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	var toSign []crypto.Hash
+	w.mu.RLock()
+	for _, sci := range txn.SiacoinInputs {
+		for _, pk := range sci.UnlockConditions.PublicKeys {
+			if _, ok := w.walletSecretKey(pk); ok {
+				toSign = append(toSign, crypto.Hash(sci.ParentID))
+				break
+			}
+		}
+	}
+	w.mu.RUnlock()
+	if len(toSign) == 0 {
+		return types.Transaction{}, errSignNoWalletKey
+	}
+
+	txn.TransactionSignatures = append([]types.TransactionSignature(nil), txn.TransactionSignatures...)
+	if err := w.SignTransaction(&txn, toSign); err != nil {
+		return types.Transaction{}, err
+	}
+	return txn, nil
+}
+
+// BroadcastRawTransaction submits a fully signed transaction to the
+// transaction pool, which relays it to the network.
+func (w *Wallet) BroadcastRawTransaction(txn types.Transaction) error {
+	if err := w.tg.Add(); err != nil {
+		return modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	if err := w.tpool.AcceptTransactionSet([]types.Transaction{txn}); err != nil {
+		return err
+	}
+	w.log.Println("Submitted a raw transaction:", txn.ID())
+	return nil
+}
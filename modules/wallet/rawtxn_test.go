@@ -0,0 +1,105 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestRawTransaction creates, signs and broadcasts a raw transaction that
+// spends one of the wallet's outputs, and checks that invalid raw
+// transactions are rejected.
+func TestRawTransaction(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// Pick a confirmed output of the wallet to spend.
+	var parentID types.SiacoinOutputID
+	var parent types.SiacoinOutput
+	wt.wallet.mu.Lock()
+	err = dbForEachSiacoinOutput(wt.wallet.dbTx, func(id types.SiacoinOutputID, sco types.SiacoinOutput) {
+		parentID, parent = id, sco
+	})
+	wt.wallet.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent.Value.IsZero() {
+		t.Fatal("wallet has no confirmed outputs")
+	}
+	inputs := []types.SiacoinInput{{ParentID: parentID}}
+	fee := types.SiacoinPrecision
+	dest := types.UnlockHash{1}
+	sent := []types.SiacoinOutput{{
+		Value:      parent.Value.Sub(fee),
+		UnlockHash: dest,
+	}}
+
+	// Inputs must exist, and the inputs must equal the outputs plus the fee.
+	if _, err := wt.wallet.CreateRawTransaction(nil, sent, fee); err != errRawNoInputs {
+		t.Fatal("expected errRawNoInputs, got", err)
+	}
+	if _, err := wt.wallet.CreateRawTransaction([]types.SiacoinInput{{ParentID: types.SiacoinOutputID{1}}}, sent, fee); err != errRawUnknownInput {
+		t.Fatal("expected errRawUnknownInput, got", err)
+	}
+	tooMuch := []types.SiacoinOutput{{Value: parent.Value.Add(types.NewCurrency64(1)), UnlockHash: dest}}
+	if _, err := wt.wallet.CreateRawTransaction(inputs, tooMuch, types.ZeroCurrency); err != errRawUnbalanced {
+		t.Fatal("expected errRawUnbalanced, got", err)
+	}
+	// A missing change output is not silently paid to the miners.
+	if _, err := wt.wallet.CreateRawTransaction(inputs, sent, types.ZeroCurrency); err != errRawUnbalanced {
+		t.Fatal("expected errRawUnbalanced, got", err)
+	}
+	if _, err := wt.wallet.CreateRawTransaction(inputs, sent, fee.Add(types.NewCurrency64(1))); err != errRawUnbalanced {
+		t.Fatal("expected errRawUnbalanced, got", err)
+	}
+
+	// Create the transaction. The unlock conditions are filled in and the
+	// fee is paid to the miners.
+	txn, err := wt.wallet.CreateRawTransaction(inputs, sent, fee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txn.SiacoinInputs[0].UnlockConditions.UnlockHash() != parent.UnlockHash {
+		t.Fatal("unlock conditions were not filled in")
+	}
+	if len(txn.MinerFees) != 1 || txn.MinerFees[0].Cmp(fee) != 0 {
+		t.Fatal("wrong miner fees:", txn.MinerFees)
+	}
+	if len(txn.TransactionSignatures) != 0 {
+		t.Fatal("raw transaction should not be signed")
+	}
+
+	// An unsigned transaction cannot be broadcast.
+	if err := wt.wallet.BroadcastRawTransaction(txn); err == nil {
+		t.Fatal("unsigned transaction was accepted")
+	}
+
+	// Sign and broadcast the transaction.
+	signed, err := wt.wallet.SignRawTransaction(txn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txn.TransactionSignatures) != 0 {
+		t.Fatal("SignRawTransaction modified its argument")
+	}
+	if err := wt.wallet.BroadcastRawTransaction(signed); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, exists := wt.tpool.Transaction(signed.ID()); !exists {
+		t.Fatal("transaction is not in the transaction pool")
+	}
+
+	// A transaction with no inputs owned by the wallet cannot be signed.
+	if _, err := wt.wallet.SignRawTransaction(types.Transaction{}); err != errSignNoWalletKey {
+		t.Fatal("expected errSignNoWalletKey, got", err)
+	}
+}
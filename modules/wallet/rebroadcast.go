@@ -0,0 +1,140 @@
+package wallet
+
+import (
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/errors"
+)
+
+// replaceBroadcastSets stops tracking the sets that the transaction pool
+// replaced with a set spending the same inputs, such as a fee bump or a
+// superset that includes the same transactions. The replacements are tracked
+// once they are found to be relevant. The caller must hold w.mu.
+func (w *Wallet) replaceBroadcastSets(diff *modules.TransactionPoolDiff) {
+	inputs := make(map[types.OutputID]struct{})
+	for _, set := range diff.AppliedTransactions {
+		for _, txn := range set.Transactions {
+			for _, id := range spentOutputs(txn) {
+				inputs[id] = struct{}{}
+			}
+		}
+	}
+	for _, setID := range diff.RevertedTransactions {
+		for _, txn := range w.broadcastSets[setID] {
+			for _, id := range spentOutputs(txn) {
+				if _, exists := inputs[id]; exists {
+					delete(w.broadcastSets, setID)
+				}
+			}
+		}
+	}
+}
+
+// spentOutputs returns the ids of the siacoin and siafund outputs spent by a
+// transaction.
+func spentOutputs(txn types.Transaction) []types.OutputID {
+	var ids []types.OutputID
+	for _, sci := range txn.SiacoinInputs {
+		ids = append(ids, types.OutputID(sci.ParentID))
+	}
+	for _, sfi := range txn.SiafundInputs {
+		ids = append(ids, types.OutputID(sfi.ParentID))
+	}
+	return ids
+}
+
+// pruneBroadcastSets stops tracking the transactions that were confirmed by a
+// consensus change. Sets that spend an output that was spent by a confirmed
+// transaction can never confirm, and are dropped along with the sets that
+// depend on them. The caller must hold w.mu.
+func (w *Wallet) pruneBroadcastSets(cc modules.ConsensusChange) {
+	if len(w.broadcastSets) == 0 {
+		return
+	}
+	confirmed := make(map[types.TransactionID]struct{})
+	spent := make(map[types.OutputID]struct{})
+	for _, block := range cc.AppliedBlocks {
+		for _, txn := range block.Transactions {
+			confirmed[txn.ID()] = struct{}{}
+			for _, id := range spentOutputs(txn) {
+				spent[id] = struct{}{}
+			}
+		}
+	}
+
+	// The outputs created by a dropped set are added to invalid, which may
+	// cause further sets to be dropped.
+	invalid := make(map[types.OutputID]struct{})
+	for dropped := true; dropped; {
+		dropped = false
+		for id, set := range w.broadcastSets {
+			var unconfirmed []types.Transaction
+			conflict := false
+			for _, txn := range set {
+				if _, exists := confirmed[txn.ID()]; exists {
+					continue
+				}
+				for _, id := range spentOutputs(txn) {
+					_, isSpent := spent[id]
+					_, isInvalid := invalid[id]
+					conflict = conflict || isSpent || isInvalid
+				}
+				unconfirmed = append(unconfirmed, txn)
+			}
+			if conflict {
+				w.log.Printf("WARN: Dropping transaction set %v from rebroadcasting, its inputs were spent by a confirmed transaction", crypto.Hash(id))
+				for _, txn := range unconfirmed {
+					for i := range txn.SiacoinOutputs {
+						invalid[types.OutputID(txn.SiacoinOutputID(uint64(i)))] = struct{}{}
+					}
+					for i := range txn.SiafundOutputs {
+						invalid[types.OutputID(txn.SiafundOutputID(uint64(i)))] = struct{}{}
+					}
+				}
+				delete(w.broadcastSets, id)
+				dropped = true
+			} else if len(unconfirmed) == 0 {
+				delete(w.broadcastSets, id)
+			} else {
+				w.broadcastSets[id] = unconfirmed
+			}
+		}
+	}
+}
+
+// RebroadcastUnconfirmed sends the wallet's unconfirmed transactions to the
+// network again through the transaction pool, returning the number of
+// transactions that were sent. This is useful after the node was offline, as
+// peers may have dropped the transactions in the meantime. Transactions that
+// are still in the transaction pool are broadcast again.
+func (w *Wallet) RebroadcastUnconfirmed() (count int, err error) {
+	if err := w.tg.Add(); err != nil {
+		return 0, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+
+	// The transaction pool is called without holding the wallet's lock, because
+	// the transaction pool holds its own lock while updating the wallet.
+	w.mu.RLock()
+	sets := make([][]types.Transaction, 0, len(w.broadcastSets))
+	for _, set := range w.broadcastSets {
+		sets = append(sets, set)
+	}
+	w.mu.RUnlock()
+
+	for _, set := range sets {
+		acceptErr := w.tpool.AcceptTransactionSet(set)
+		if acceptErr == modules.ErrDuplicateTransactionSet {
+			w.tpool.Broadcast(set)
+			acceptErr = nil
+		}
+		if acceptErr != nil {
+			err = errors.Compose(err, errors.AddContext(acceptErr, "unable to rebroadcast transaction set"))
+			continue
+		}
+		count += len(set)
+	}
+	return count, err
+}
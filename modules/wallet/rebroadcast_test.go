@@ -0,0 +1,118 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestRebroadcastUnconfirmed checks that transactions dropped by the
+// transaction pool are rebroadcast until they confirm.
+func TestRebroadcastUnconfirmed(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	txns, err := wt.wallet.SendSiacoins(types.SiacoinPrecision, types.UnlockHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txid := txns[len(txns)-1].ID()
+
+	// Drop the transactions from the transaction pool, then rebroadcast them.
+	wt.tpool.PurgeTransactionPool()
+	if _, _, exists := wt.tpool.Transaction(txid); exists {
+		t.Fatal("transaction was not purged")
+	}
+	count, err := wt.wallet.RebroadcastUnconfirmed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != len(txns) {
+		t.Fatalf("expected %v transactions to be rebroadcast, got %v", len(txns), count)
+	}
+	if _, _, exists := wt.tpool.Transaction(txid); !exists {
+		t.Fatal("transaction was not rebroadcast")
+	}
+
+	// Transactions that are still in the pool are broadcast again.
+	count, err = wt.wallet.RebroadcastUnconfirmed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != len(txns) {
+		t.Fatalf("expected %v transactions to be rebroadcast, got %v", len(txns), count)
+	}
+
+	// Confirmed transactions are not rebroadcast.
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	count, err = wt.wallet.RebroadcastUnconfirmed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatal("confirmed transactions were rebroadcast:", count)
+	}
+}
+
+// TestPruneBroadcastSets checks that sets whose inputs were spent by a
+// confirmed transaction are dropped, along with the sets that depend on them.
+func TestPruneBroadcastSets(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	input := types.SiacoinInput{ParentID: types.SiacoinOutputID{1}}
+	conflicting := types.Transaction{
+		SiacoinInputs:  []types.SiacoinInput{input},
+		SiacoinOutputs: []types.SiacoinOutput{{Value: types.SiacoinPrecision}},
+	}
+	dependent := types.Transaction{
+		SiacoinInputs: []types.SiacoinInput{{ParentID: conflicting.SiacoinOutputID(0)}},
+	}
+	unrelated := types.Transaction{
+		SiacoinInputs: []types.SiacoinInput{{ParentID: types.SiacoinOutputID{2}}},
+	}
+	confirmed := types.Transaction{
+		SiacoinInputs: []types.SiacoinInput{{ParentID: types.SiacoinOutputID{3}}},
+	}
+	doubleSpend := types.Transaction{
+		SiacoinInputs: []types.SiacoinInput{input},
+		MinerFees:     []types.Currency{types.SiacoinPrecision},
+	}
+
+	wt.wallet.mu.Lock()
+	defer wt.wallet.mu.Unlock()
+	wt.wallet.broadcastSets = map[modules.TransactionSetID][]types.Transaction{
+		{1}: {conflicting},
+		{2}: {dependent},
+		{3}: {confirmed, unrelated},
+	}
+	wt.wallet.pruneBroadcastSets(modules.ConsensusChange{
+		AppliedBlocks: []types.Block{{
+			Transactions: []types.Transaction{confirmed, doubleSpend},
+		}},
+	})
+	if len(wt.wallet.broadcastSets) != 1 {
+		t.Fatal("expected 1 set to remain, got", len(wt.wallet.broadcastSets))
+	}
+	set := wt.wallet.broadcastSets[modules.TransactionSetID{3}]
+	if len(set) != 1 || set[0].ID() != unrelated.ID() {
+		t.Fatal("confirmed transaction was not removed from its set")
+	}
+}
@@ -3,7 +3,6 @@ package wallet
 import (
 	"bytes"
 	"errors"
-	"sort"
 
 	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/encoding"
@@ -44,6 +43,10 @@ type transactionBuilder struct {
 	siafundInputs         []int
 	transactionSignatures []int
 
+	// strategy determines the order in which siacoin outputs are used to
+	// fund the transaction.
+	strategy modules.SelectionStrategy
+
 	wallet *Wallet
 }
 
@@ -117,6 +120,13 @@ func (w *Wallet) checkOutput(tx *bolt.Tx, currentHeight types.BlockHeight, id ty
 // correct value. The siacoin input will not be signed until 'Sign' is called
 // on the transaction builder.
 func (tb *transactionBuilder) FundSiacoins(amount types.Currency) error {
+	return tb.fundSiacoins(amount, nil)
+}
+
+// fundSiacoins is the implementation of FundSiacoins. If 'refundAddress' is
+// non-nil, any refund created while funding the transaction is sent to that
+// address instead of a fresh address generated from the primary seed.
+func (tb *transactionBuilder) fundSiacoins(amount types.Currency, refundAddress *types.UnlockHash) error {
 	// dustThreshold has to be obtained separate from the lock
 	dustThreshold, err := tb.wallet.DustThreshold()
 	if err != nil {
@@ -130,13 +140,30 @@ func (tb *transactionBuilder) FundSiacoins(amount types.Currency) error {
 	if err != nil {
 		return err
 	}
+	if err := types.CheckSiacoinAmount(amount, consensusHeight); err != nil {
+		return err
+	}
 
-	// Collect a value-sorted set of siacoin outputs.
+	// Collect the set of siacoin outputs that can be spent. potentialFund
+	// tracks the balance of the wallet including outputs that have been spent
+	// in other unconfirmed transactions recently. This is to provide the user
+	// with a more useful error message in the event that they are
+	// overspending.
 	var so sortedOutputs
-	err = dbForEachSiacoinOutput(tb.wallet.dbTx, func(scoid types.SiacoinOutputID, sco types.SiacoinOutput) {
+	var potentialFund types.Currency
+	addOutput := func(scoid types.SiacoinOutputID, sco types.SiacoinOutput) {
+		// Check that the output can be spent.
+		if err := tb.wallet.checkOutput(tb.wallet.dbTx, consensusHeight, scoid, sco, dustThreshold); err != nil {
+			if err == errSpendHeightTooHigh {
+				potentialFund = potentialFund.Add(sco.Value)
+			}
+			return
+		}
+		potentialFund = potentialFund.Add(sco.Value)
 		so.ids = append(so.ids, scoid)
 		so.outputs = append(so.outputs, sco)
-	})
+	}
+	err = dbForEachSiacoinOutput(tb.wallet.dbTx, addOutput)
 	if err != nil {
 		return err
 	}
@@ -148,32 +175,19 @@ func (tb *transactionBuilder) FundSiacoins(amount types.Currency) error {
 			if !exists {
 				continue
 			}
-			so.ids = append(so.ids, upt.Transaction.SiacoinOutputID(uint64(i)))
-			so.outputs = append(so.outputs, sco)
+			addOutput(upt.Transaction.SiacoinOutputID(uint64(i)), sco)
 		}
 	}
-	sort.Sort(sort.Reverse(so))
+	orderOutputs(tb.wallet.dbTx, so, amount, tb.strategy)
 
 	// Create and fund a parent transaction that will add the correct amount of
 	// siacoins to the transaction.
 	var fund types.Currency
-	// potentialFund tracks the balance of the wallet including outputs that
-	// have been spent in other unconfirmed transactions recently. This is to
-	// provide the user with a more useful error message in the event that they
-	// are overspending.
-	var potentialFund types.Currency
 	parentTxn := types.Transaction{}
 	var spentScoids []types.SiacoinOutputID
 	for i := range so.ids {
 		scoid := so.ids[i]
 		sco := so.outputs[i]
-		// Check that the output can be spent.
-		if err := tb.wallet.checkOutput(tb.wallet.dbTx, consensusHeight, scoid, sco, dustThreshold); err != nil {
-			if err == errSpendHeightTooHigh {
-				potentialFund = potentialFund.Add(sco.Value)
-			}
-			continue
-		}
 
 		// Add a siacoin input for this output.
 		sci := types.SiacoinInput{
@@ -185,7 +199,6 @@ func (tb *transactionBuilder) FundSiacoins(amount types.Currency) error {
 
 		// Add the output to the total fund
 		fund = fund.Add(sco.Value)
-		potentialFund = potentialFund.Add(sco.Value)
 		if fund.Cmp(amount) >= 0 {
 			break
 		}
@@ -210,15 +223,24 @@ func (tb *transactionBuilder) FundSiacoins(amount types.Currency) error {
 	}
 	parentTxn.SiacoinOutputs = append(parentTxn.SiacoinOutputs, exactOutput)
 
-	// Create a refund output if needed.
-	if !amount.Equals(fund) {
-		refundUnlockConditions, err := tb.wallet.nextPrimarySeedAddress(tb.wallet.dbTx)
-		if err != nil {
-			return err
+	// Create a refund output if needed. A refund below the change dust
+	// threshold is paid to the miners instead.
+	if refund := fund.Sub(amount); !refund.IsZero() && refund.Cmp(tb.wallet.changeDustThreshold) < 0 {
+		parentTxn.MinerFees = append(parentTxn.MinerFees, refund)
+	} else if !refund.IsZero() {
+		var refundUnlockHash types.UnlockHash
+		if refundAddress != nil {
+			refundUnlockHash = *refundAddress
+		} else {
+			refundUnlockConditions, err := tb.wallet.nextPrimarySeedAddress(tb.wallet.dbTx)
+			if err != nil {
+				return err
+			}
+			refundUnlockHash = refundUnlockConditions.UnlockHash()
 		}
 		refundOutput := types.SiacoinOutput{
-			Value:      fund.Sub(amount),
-			UnlockHash: refundUnlockConditions.UnlockHash(),
+			Value:      refund,
+			UnlockHash: refundUnlockHash,
 		}
 		parentTxn.SiacoinOutputs = append(parentTxn.SiacoinOutputs, refundOutput)
 	}
@@ -540,12 +562,26 @@ func (tb *transactionBuilder) Drop() {
 // more fields to be added.
 //
 // Sign should not be called more than once. If, for some reason, there is an
-// error while calling Sign, the builder should be dropped.
+// error while calling Sign, the builder should be dropped. Sign returns
+// modules.ErrLockedWallet if the wallet has been locked, for example by the
+// auto-lock timeout, since the builder was created.
 func (tb *transactionBuilder) Sign(wholeTransaction bool) ([]types.Transaction, error) {
 	if tb.signed {
 		return nil, errBuilderAlreadySigned
 	}
 
+	// Check that the siacoins moved by the transaction do not exceed the
+	// supply, which can only happen due to a programming error.
+	tb.wallet.mu.Lock()
+	consensusHeight, err := dbGetConsensusHeight(tb.wallet.dbTx)
+	tb.wallet.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSiacoinAmounts(tb.transaction, consensusHeight); err != nil {
+		return nil, err
+	}
+
 	// Create the coveredfields struct.
 	var coveredFields types.CoveredFields
 	if wholeTransaction {
@@ -586,9 +622,13 @@ func (tb *transactionBuilder) Sign(wholeTransaction bool) ([]types.Transaction,
 	}
 
 	// For each siacoin input in the transaction that we added, provide a
-	// signature.
+	// signature. The wallet may have been locked since the builder was
+	// created, in which case the secret keys have been wiped.
 	tb.wallet.mu.RLock()
 	defer tb.wallet.mu.RUnlock()
+	if !tb.wallet.unlocked {
+		return nil, modules.ErrLockedWallet
+	}
 	for _, inputIndex := range tb.siacoinInputs {
 		input := tb.transaction.SiacoinInputs[inputIndex]
 		key, ok := tb.wallet.keys[input.UnlockConditions.UnlockHash()]
@@ -615,6 +655,25 @@ func (tb *transactionBuilder) Sign(wholeTransaction bool) ([]types.Transaction,
 	return txnSet, nil
 }
 
+// checkSiacoinAmounts checks that the siacoin outputs, file contract payouts
+// and miner fees of a transaction do not add up to more than the supply of
+// siacoins at the given height.
+func checkSiacoinAmounts(txn types.Transaction, height types.BlockHeight) error {
+	var amounts []types.Currency
+	for _, sco := range txn.SiacoinOutputs {
+		amounts = append(amounts, sco.Value)
+	}
+	for _, fc := range txn.FileContracts {
+		amounts = append(amounts, fc.Payout)
+	}
+	amounts = append(amounts, txn.MinerFees...)
+	total, err := types.SumCurrency(amounts...)
+	if err != nil {
+		return err
+	}
+	return types.CheckSiacoinAmount(total, height)
+}
+
 // ViewTransaction returns a transaction-in-progress along with all of its
 // parents, specified by id. An error is returned if the id is invalid.  Note
 // that ids become invalid for a transaction after 'SignTransaction' has been
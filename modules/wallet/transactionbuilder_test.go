@@ -1,6 +1,7 @@
 package wallet
 
 import (
+	"path/filepath"
 	"sync"
 	"testing"
 
@@ -177,6 +178,115 @@ func TestDoubleSignError(t *testing.T) {
 	}
 }
 
+// TestBuilderSupplyCheck checks that the transaction builder rejects amounts
+// that exceed the supply of siacoins.
+func TestBuilderSupplyCheck(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	tooMuch := types.CalculateNumSiacoins(wt.cs.Height()).Add(types.NewCurrency64(1))
+	b, err := wt.wallet.StartTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.FundSiacoins(tooMuch); err != types.ErrCurrencyExceedsSupply {
+		t.Fatal("expected ErrCurrencyExceedsSupply, got", err)
+	}
+	b.AddSiacoinOutput(types.SiacoinOutput{Value: tooMuch})
+	if _, err := b.Sign(true); err != types.ErrCurrencyExceedsSupply {
+		t.Fatal("expected ErrCurrencyExceedsSupply, got", err)
+	}
+	b.Drop()
+}
+
+// TestDustChange checks that change below the dust threshold is paid to the
+// miners instead of being returned to the wallet.
+func TestDustChange(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// fund builds and submits a transaction sending one siacoin, returning
+	// the parent transaction that funds it.
+	fund := func() types.Transaction {
+		b, err := wt.wallet.StartTransaction()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := b.FundSiacoins(types.SiacoinPrecision); err != nil {
+			t.Fatal(err)
+		}
+		b.AddSiacoinOutput(types.SiacoinOutput{Value: types.SiacoinPrecision})
+		txns, err := b.Sign(true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := wt.tpool.AcceptTransactionSet(txns); err != nil {
+			t.Fatal(err)
+		}
+		return txns[0]
+	}
+
+	// With a threshold above any possible change, the change becomes a fee.
+	balance, _, _, err := wt.wallet.ConfirmedBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.SetChangeDustThreshold(balance); err != nil {
+		t.Fatal(err)
+	}
+	parent := fund()
+	if len(parent.SiacoinOutputs) != 1 || len(parent.MinerFees) != 1 {
+		t.Fatal("change was not paid to the miners:", parent)
+	}
+
+	// With the threshold disabled, the change is returned to the wallet.
+	if _, err := wt.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.SetChangeDustThreshold(types.ZeroCurrency); err != nil {
+		t.Fatal(err)
+	}
+	parent = fund()
+	if len(parent.SiacoinOutputs) != 2 || len(parent.MinerFees) != 0 {
+		t.Fatal("change was not returned to the wallet:", parent)
+	}
+
+	// The threshold is part of the wallet settings, and persists across
+	// restarts.
+	threshold := types.SiacoinPrecision.Div64(10)
+	if err := wt.wallet.SetChangeDustThreshold(threshold); err != nil {
+		t.Fatal(err)
+	}
+	if c, err := wt.wallet.ChangeDustThreshold(); err != nil || !c.Equals(threshold) {
+		t.Fatal("wrong threshold:", c, err)
+	}
+	if s, err := wt.wallet.Settings(); err != nil || !s.ChangeDustThreshold.Equals(threshold) {
+		t.Fatal("wrong threshold in settings:", s, err)
+	}
+	if err := wt.wallet.Close(); err != nil {
+		t.Fatal(err)
+	}
+	wt.wallet, err = New(wt.cs, wt.tpool, filepath.Join(wt.persistDir, modules.WalletDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, err := wt.wallet.ChangeDustThreshold(); err != nil || !c.Equals(threshold) {
+		t.Fatal("threshold was not persisted:", c, err)
+	}
+}
+
 // TestConcurrentBuilders checks that multiple transaction builders can safely
 // be opened at the same time, and that they will make valid transactions when
 // building concurrently.
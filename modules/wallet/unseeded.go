@@ -76,6 +76,18 @@ func decryptSpendableKeyFile(masterKey crypto.TwofishKey, uk spendableKeyFile) (
 	return
 }
 
+// createSpendableKeyFile encrypts a spendableKey, returning a
+// spendableKeyFile.
+func createSpendableKeyFile(masterKey crypto.TwofishKey, sk spendableKey) spendableKeyFile {
+	// Create a UID and encryption verification.
+	var skf spendableKeyFile
+	fastrand.Read(skf.UID[:])
+	encryptionKey := uidEncryptionKey(masterKey, skf.UID)
+	skf.EncryptionVerification = encryptionKey.EncryptBytes(verificationPlaintext)
+	skf.SpendableKey = encryptionKey.EncryptBytes(encoding.Marshal(sk))
+	return skf
+}
+
 // integrateSpendableKey loads a spendableKey into the wallet.
 func (w *Wallet) integrateSpendableKey(masterKey crypto.TwofishKey, sk spendableKey) {
 	w.keys[sk.UnlockConditions.UnlockHash()] = sk
@@ -97,15 +109,8 @@ func (w *Wallet) loadSpendableKey(masterKey crypto.TwofishKey, sk spendableKey)
 
 	// TODO: Check that the key is actually spendable.
 
-	// Create a UID and encryption verification.
-	var skf spendableKeyFile
-	fastrand.Read(skf.UID[:])
-	encryptionKey := uidEncryptionKey(masterKey, skf.UID)
-	skf.EncryptionVerification = encryptionKey.EncryptBytes(verificationPlaintext)
-
 	// Encrypt and save the key.
-	skf.SpendableKey = encryptionKey.EncryptBytes(encoding.Marshal(sk))
-
+	skf := createSpendableKeyFile(masterKey, sk)
 	err := checkMasterKey(w.dbTx, masterKey)
 	if err != nil {
 		return err
@@ -464,6 +464,7 @@ func (w *Wallet) ProcessConsensusChange(cc modules.ConsensusChange) {
 		w.log.Severe("ERROR: failed to update consensus change ID:", err)
 		w.dbRollback = true
 	}
+	w.pruneBroadcastSets(cc)
 
 	if cc.Synced {
 		go w.threadedDefragWallet()
@@ -481,6 +482,8 @@ func (w *Wallet) ReceiveUpdatedUnconfirmedTransactions(diff *modules.Transaction
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
+	w.replaceBroadcastSets(diff)
+
 	// Do the pruning first. If there are any pruned transactions, we will need
 	// to re-allocate the whole processed transactions array.
 	droppedTransactions := make(map[types.TransactionID]struct{})
@@ -547,6 +550,7 @@ func (w *Wallet) ReceiveUpdatedUnconfirmedTransactions(diff *modules.Transaction
 			if !relevant {
 				continue
 			}
+			w.broadcastSets[unconfirmedTxnSet.ID] = unconfirmedTxnSet.Transactions
 
 			pt := modules.ProcessedTransaction{
 				Transaction:           txn,
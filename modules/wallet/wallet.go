@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/coreos/bbolt"
 
@@ -84,6 +85,11 @@ type Wallet struct {
 	unconfirmedSets                  map[modules.TransactionSetID][]types.TransactionID
 	unconfirmedProcessedTransactions []modules.ProcessedTransaction
 
+	// broadcastSets tracks the unconfirmed transaction sets that are relevant
+	// to the wallet, so that they can be rebroadcast if the transaction pool
+	// drops them. Confirmed transactions are removed as blocks are applied.
+	broadcastSets map[modules.TransactionSetID][]types.Transaction
+
 	// The wallet's database tracks its seeds, keys, outputs, and
 	// transactions. A global db transaction is maintained in memory to avoid
 	// excessive disk writes. Any operations involving dbTx must hold an
@@ -111,6 +117,20 @@ type Wallet struct {
 	// defragDisabled determines if the wallet is set to defrag outputs once it
 	// reaches a certain threshold
 	defragDisabled bool
+
+	// Change below changeDustThreshold is added to the miner fees of a
+	// transaction instead of being returned to the wallet.
+	changeDustThreshold types.Currency
+
+	// The wallet locks itself after autoLockTimeout passes without a spend.
+	// activeSpends is the number of spends in progress, which prevent the
+	// wallet from auto-locking. autoLockGen identifies the current
+	// autoLockTimer, so that a timer that fires after being replaced is
+	// ignored.
+	autoLockTimeout time.Duration
+	autoLockTimer   *time.Timer
+	autoLockGen     uint64
+	activeSpends    int
 }
 
 // Height return the internal processed consensus height of the wallet
@@ -160,6 +180,7 @@ func NewCustomWallet(cs modules.ConsensusSet, tpool modules.TransactionPool, per
 		lookahead: make(map[types.UnlockHash]uint64),
 
 		unconfirmedSets: make(map[modules.TransactionSetID][]types.TransactionID),
+		broadcastSets:   make(map[modules.TransactionSetID][]types.Transaction),
 
 		persistDir: persistDir,
 
@@ -259,8 +280,11 @@ func (w *Wallet) Settings() (modules.WalletSettings, error) {
 		return modules.WalletSettings{}, modules.ErrWalletShutdown
 	}
 	defer w.tg.Done()
+	w.mu.RLock()
+	defer w.mu.RUnlock()
 	return modules.WalletSettings{
-		NoDefrag: w.defragDisabled,
+		NoDefrag:            w.defragDisabled,
+		ChangeDustThreshold: w.changeDustThreshold,
 	}, nil
 }
 
@@ -272,7 +296,10 @@ func (w *Wallet) SetSettings(s modules.WalletSettings) error {
 	defer w.tg.Done()
 
 	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.setChangeDustThreshold(s.ChangeDustThreshold); err != nil {
+		return err
+	}
 	w.defragDisabled = s.NoDefrag
-	w.mu.Unlock()
 	return nil
 }
@@ -69,7 +69,14 @@ func (db *BoltDatabase) Close() error {
 func OpenDatabase(md Metadata, filename string) (*BoltDatabase, error) {
 	// Open the database using a 3 second timeout (without the timeout,
 	// database will potentially hang indefinitely.
-	db, err := bolt.Open(filename, 0600, &bolt.Options{Timeout: 3 * time.Second})
+	return OpenDatabaseWithOptions(md, filename, &bolt.Options{Timeout: 3 * time.Second})
+}
+
+// OpenDatabaseWithOptions opens a database using the provided bolt options and
+// validates its metadata. A zero timeout in the options will cause the call to
+// hang indefinitely if another process holds the database open.
+func OpenDatabaseWithOptions(md Metadata, filename string, opts *bolt.Options) (*BoltDatabase, error) {
+	db, err := bolt.Open(filename, 0600, opts)
 	if err != nil {
 		return nil, err
 	}
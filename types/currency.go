@@ -31,6 +31,14 @@ var (
 	// operation results in a negative currency.
 	ErrNegativeCurrency = errors.New("negative currency not allowed")
 
+	// ErrCurrencyExceedsLimit is the error that is returned if a sum of
+	// currencies is larger than the limit it was checked against.
+	ErrCurrencyExceedsLimit = errors.New("sum of currencies exceeds the limit")
+
+	// ErrCurrencyExceedsSupply is the error that is returned if a siacoin
+	// amount is larger than the number of siacoins in circulation.
+	ErrCurrencyExceedsSupply = errors.New("siacoin amount exceeds the supply of siacoins")
+
 	// ErrUint64Overflow is the error that is returned if converting to a
 	// unit64 would cause an overflow.
 	ErrUint64Overflow = errors.New("cannot return the uint64 of this currency - result is an overflow")
@@ -68,6 +76,12 @@ func (x Currency) Big() *big.Int {
 	return new(big.Int).Set(&x.i)
 }
 
+// Clamp returns x limited to the range [lo, hi]. Behavior is undefined when
+// lo > hi.
+func (x Currency) Clamp(lo, hi Currency) Currency {
+	return x.Max(lo).Min(hi)
+}
+
 // Cmp compares two Currency values. The return value follows the convention
 // of math/big.
 func (x Currency) Cmp(y Currency) int {
@@ -92,6 +106,13 @@ func (x Currency) Div64(y uint64) (c Currency) {
 	return
 }
 
+// DivMod returns the quotient q = x / y and the remainder r = x - q*y, so that
+// no value is lost to rounding. Like Div, DivMod panics if y is zero.
+func (x Currency) DivMod(y Currency) (q, r Currency) {
+	q.i.DivMod(&x.i, &y.i, &r.i)
+	return
+}
+
 // Equals returns true if x and y have the same value.
 func (x Currency) Equals(y Currency) bool {
 	return x.Cmp(y) == 0
@@ -102,6 +123,22 @@ func (x Currency) Equals64(y uint64) bool {
 	return x.Cmp64(y) == 0
 }
 
+// Max returns the larger of x and y.
+func (x Currency) Max(y Currency) Currency {
+	if x.Cmp(y) < 0 {
+		return y
+	}
+	return x
+}
+
+// Min returns the smaller of x and y.
+func (x Currency) Min(y Currency) Currency {
+	if x.Cmp(y) > 0 {
+		return y
+	}
+	return x
+}
+
 // Mul returns a new Currency value c = x * y.
 func (x Currency) Mul(y Currency) (c Currency) {
 	c.i.Mul(&x.i, &y.i)
@@ -183,6 +220,16 @@ func (x Currency) Sub(y Currency) (c Currency) {
 	return
 }
 
+// SubUnderflow returns a new Currency value c = x - y. If x < y, c is zero and
+// underflow is true; unlike Sub, this is not treated as a developer error.
+func (x Currency) SubUnderflow(y Currency) (c Currency, underflow bool) {
+	if x.Cmp(y) < 0 {
+		return ZeroCurrency, true
+	}
+	c.i.Sub(&x.i, &y.i)
+	return c, false
+}
+
 // Uint64 converts a Currency to a uint64. An error is returned because this
 // function is sometimes called on values that can be determined by users -
 // rather than have all user-facing points do input checking, the input
@@ -194,3 +241,48 @@ func (x Currency) Uint64() (u uint64, err error) {
 	}
 	return x.Big().Uint64(), nil
 }
+
+// SumCurrency returns the sum of the provided values. The values are summed
+// into a single big.Int, which avoids allocating a new Currency for each
+// addition. An error is returned if any of the values is negative, which can
+// only happen if the value was corrupted.
+func SumCurrency(cs ...Currency) (Currency, error) {
+	var sum Currency
+	for i := range cs {
+		if cs[i].i.Sign() < 0 {
+			return ZeroCurrency, ErrNegativeCurrency
+		}
+		sum.i.Add(&sum.i, &cs[i].i)
+	}
+	return sum, nil
+}
+
+// SumCurrencyLimit returns the sum of the provided values, checking it against
+// limit. If the sum exceeds limit, limit is returned along with
+// ErrCurrencyExceedsLimit. Using the total supply of siacoins as the limit,
+// see CalculateNumSiacoins, catches sums that no valid set of outputs could
+// reach.
+func SumCurrencyLimit(limit Currency, cs ...Currency) (Currency, error) {
+	sum, err := SumCurrency(cs...)
+	if err != nil {
+		return ZeroCurrency, err
+	}
+	if sum.Cmp(limit) > 0 {
+		return limit, ErrCurrencyExceedsLimit
+	}
+	return sum, nil
+}
+
+// CheckSiacoinAmount returns an error if amount is negative or larger than the
+// number of siacoins in circulation at the given height. No valid transaction
+// at that height can move such an amount, so the check catches corrupted or
+// miscalculated amounts before they reach block validation.
+func CheckSiacoinAmount(amount Currency, height BlockHeight) error {
+	if amount.i.Sign() < 0 {
+		return ErrNegativeCurrency
+	}
+	if amount.Cmp(CalculateNumSiacoins(height)) > 0 {
+		return ErrCurrencyExceedsSupply
+	}
+	return nil
+}
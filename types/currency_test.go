@@ -118,6 +118,42 @@ func TestCurrencyDiv64(t *testing.T) {
 	}
 }
 
+// TestCurrencyDivMod checks that the DivMod function has been correctly
+// implemented.
+func TestCurrencyDivMod(t *testing.T) {
+	c0 := NewCurrency64(0)
+	c7 := NewCurrency64(7)
+	c9 := NewCurrency64(9)
+	c10 := NewCurrency64(10)
+	c90 := NewCurrency64(90)
+	c97 := NewCurrency64(97)
+
+	q, r := c90.DivMod(c10)
+	if q.Cmp(c9) != 0 || r.Cmp(c0) != 0 {
+		t.Error("Dividing 90 by 10 should produce 9 remainder 0, got", q, r)
+	}
+	q, r = c97.DivMod(c10)
+	if q.Cmp(c9) != 0 || r.Cmp(c7) != 0 {
+		t.Error("Dividing 97 by 10 should produce 9 remainder 7, got", q, r)
+	}
+	if q.Mul(c10).Add(r).Cmp(c97) != 0 {
+		t.Error("quotient and remainder do not add up to the dividend")
+	}
+
+	// The operands should not be modified.
+	if c97.Cmp64(97) != 0 || c10.Cmp64(10) != 0 {
+		t.Error("DivMod modified its operands")
+	}
+
+	// Dividing by zero should panic, as with Div.
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic when dividing by zero")
+		}
+	}()
+	c97.DivMod(c0)
+}
+
 // TestCurrencyEquals tests the Equals method for the currency type
 func TestCurrencyEquals(t *testing.T) {
 	tests := []struct {
@@ -165,6 +201,53 @@ func TestCurrencyEquals64(t *testing.T) {
 	}
 }
 
+// TestCurrencyMinMax probes the Min and Max methods of the currency type.
+func TestCurrencyMinMax(t *testing.T) {
+	tests := []struct {
+		x, y     Currency
+		min, max Currency
+	}{
+		{NewCurrency64(0), NewCurrency64(0), NewCurrency64(0), NewCurrency64(0)},
+		{NewCurrency64(0), NewCurrency64(1), NewCurrency64(0), NewCurrency64(1)},
+		{NewCurrency64(1), NewCurrency64(0), NewCurrency64(0), NewCurrency64(1)},
+		{NewCurrency64(7), NewCurrency64(7), NewCurrency64(7), NewCurrency64(7)},
+		{NewCurrency64(100), NewCurrency64(7), NewCurrency64(7), NewCurrency64(100)},
+		{SiacoinPrecision, NewCurrency64(1), NewCurrency64(1), SiacoinPrecision},
+	}
+
+	for _, test := range tests {
+		if min := test.x.Min(test.y); !min.Equals(test.min) {
+			t.Errorf("expected %v.Min(%v) == %v, got %v", test.x, test.y, test.min, min)
+		}
+		if max := test.x.Max(test.y); !max.Equals(test.max) {
+			t.Errorf("expected %v.Max(%v) == %v, got %v", test.x, test.y, test.max, max)
+		}
+	}
+}
+
+// TestCurrencyClamp probes the Clamp method of the currency type.
+func TestCurrencyClamp(t *testing.T) {
+	tests := []struct {
+		x, lo, hi Currency
+		exp       Currency
+	}{
+		{NewCurrency64(0), NewCurrency64(0), NewCurrency64(0), NewCurrency64(0)},
+		{NewCurrency64(0), NewCurrency64(1), NewCurrency64(5), NewCurrency64(1)},
+		{NewCurrency64(1), NewCurrency64(1), NewCurrency64(5), NewCurrency64(1)},
+		{NewCurrency64(3), NewCurrency64(1), NewCurrency64(5), NewCurrency64(3)},
+		{NewCurrency64(5), NewCurrency64(1), NewCurrency64(5), NewCurrency64(5)},
+		{NewCurrency64(9), NewCurrency64(1), NewCurrency64(5), NewCurrency64(5)},
+		{NewCurrency64(9), NewCurrency64(5), NewCurrency64(5), NewCurrency64(5)},
+		{NewCurrency64(9), NewCurrency64(0), NewCurrency64(0), NewCurrency64(0)},
+	}
+
+	for _, test := range tests {
+		if c := test.x.Clamp(test.lo, test.hi); !c.Equals(test.exp) {
+			t.Errorf("expected %v.Clamp(%v, %v) == %v, got %v", test.x, test.lo, test.hi, test.exp, c)
+		}
+	}
+}
+
 // TestCurrencyMul probes the Mul function of the currency type.
 func TestCurrencyMul(t *testing.T) {
 	c5 := NewCurrency64(5)
@@ -246,6 +329,26 @@ func TestCurrencySub(t *testing.T) {
 	}
 }
 
+// TestCurrencySubUnderflow probes the SubUnderflow function of the currency
+// type.
+func TestCurrencySubUnderflow(t *testing.T) {
+	c3 := NewCurrency64(3)
+	c13 := NewCurrency64(13)
+	c16 := NewCurrency64(16)
+	if c, underflow := c16.SubUnderflow(c3); underflow || c.Cmp(c13) != 0 {
+		t.Error("16 minus 3 should equal 13 without underflow")
+	}
+	if c, underflow := c3.SubUnderflow(c3); underflow || !c.IsZero() {
+		t.Error("3 minus 3 should equal 0 without underflow")
+	}
+	if c, underflow := c3.SubUnderflow(c16); !underflow || !c.IsZero() {
+		t.Error("3 minus 16 should underflow and return 0")
+	}
+	if !c16.Equals64(16) || !c3.Equals64(3) {
+		t.Error("SubUnderflow modified its operands")
+	}
+}
+
 // TestNegativeCurrencyMulRat checks that negative numbers are rejected when
 // calling MulRat on the currency type.
 func TestNegativeCurrencyMulRat(t *testing.T) {
@@ -318,3 +421,77 @@ func TestCurrencyUint64(t *testing.T) {
 		t.Error("result is not being zeroed in the event of an error")
 	}
 }
+
+// TestSumCurrency probes the SumCurrency and SumCurrencyLimit functions.
+func TestSumCurrency(t *testing.T) {
+	// The empty sum is zero.
+	sum, err := SumCurrency()
+	if err != nil || !sum.IsZero() {
+		t.Fatal("expected an empty sum of zero, got", sum, err)
+	}
+
+	// Sum many large values, whose total is far larger than a uint64.
+	large := NewCurrency64(math.MaxUint64).Mul(SiacoinPrecision)
+	values := make([]Currency, 1000)
+	expected := ZeroCurrency
+	for i := range values {
+		values[i] = large.Add(NewCurrency64(uint64(i)))
+		expected = expected.Add(values[i])
+	}
+	sum, err = SumCurrency(values...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sum.Equals(expected) {
+		t.Fatalf("expected %v, got %v", expected, sum)
+	}
+	// The values must not be modified.
+	if !values[0].Equals(large) {
+		t.Fatal("SumCurrency modified its input")
+	}
+
+	// Check the sum against a limit.
+	if sum, err := SumCurrencyLimit(expected, values...); err != nil || !sum.Equals(expected) {
+		t.Fatal("sum equal to the limit was rejected:", sum, err)
+	}
+	limit := expected.Sub(NewCurrency64(1))
+	if sum, err := SumCurrencyLimit(limit, values...); err != ErrCurrencyExceedsLimit || !sum.Equals(limit) {
+		t.Fatal("expected the limit and ErrCurrencyExceedsLimit, got", sum, err)
+	}
+
+	// Outputs worth more than the total supply are flagged.
+	supply := CalculateNumSiacoins(100)
+	if _, err := SumCurrencyLimit(supply, supply.Sub(SiacoinPrecision), SiacoinPrecision, NewCurrency64(1)); err != ErrCurrencyExceedsLimit {
+		t.Fatal("expected ErrCurrencyExceedsLimit, got", err)
+	}
+
+	// Negative values are rejected.
+	var neg Currency
+	neg.i.SetInt64(-1)
+	if _, err := SumCurrency(NewCurrency64(1), neg); err != ErrNegativeCurrency {
+		t.Fatal("expected ErrNegativeCurrency, got", err)
+	}
+}
+
+// TestCheckSiacoinAmount checks that amounts are checked against the supply of
+// siacoins at a height.
+func TestCheckSiacoinAmount(t *testing.T) {
+	supply := CalculateNumSiacoins(100)
+	if err := CheckSiacoinAmount(supply, 100); err != nil {
+		t.Fatal("the entire supply was rejected:", err)
+	}
+	if err := CheckSiacoinAmount(ZeroCurrency, 0); err != nil {
+		t.Fatal("a zero amount was rejected:", err)
+	}
+	if err := CheckSiacoinAmount(supply.Add(NewCurrency64(1)), 100); err != ErrCurrencyExceedsSupply {
+		t.Fatal("expected ErrCurrencyExceedsSupply, got", err)
+	}
+	if err := CheckSiacoinAmount(supply, 99); err != ErrCurrencyExceedsSupply {
+		t.Fatal("expected ErrCurrencyExceedsSupply at a lower height, got", err)
+	}
+	var neg Currency
+	neg.i.SetInt64(-1)
+	if err := CheckSiacoinAmount(neg, 100); err != ErrNegativeCurrency {
+		t.Fatal("expected ErrNegativeCurrency, got", err)
+	}
+}
@@ -270,6 +270,23 @@ func (b *Block) UnmarshalSia(r io.Reader) error {
 	return d.Err()
 }
 
+// MarshalSiaSize returns the encoded size of b.
+func (b Block) MarshalSiaSize() (size int) {
+	size += len(b.ParentID)
+	size += len(b.Nonce)
+	size += 8 // Timestamp
+	size += 8
+	for _, sco := range b.MinerPayouts {
+		size += sco.Value.MarshalSiaSize()
+		size += len(sco.UnlockHash)
+	}
+	size += 8
+	for i := range b.Transactions {
+		size += b.Transactions[i].MarshalSiaSize()
+	}
+	return
+}
+
 // MarshalJSON marshales a block id as a hex string.
 func (bid BlockID) MarshalJSON() ([]byte, error) {
 	return json.Marshal(bid.String())
@@ -719,6 +719,21 @@ func TestTransactionMarshalSiaSize(t *testing.T) {
 	}
 }
 
+// TestBlockMarshalSiaSize tests that the b.MarshalSiaSize method is always
+// consistent with len(encoding.Marshal(b)).
+func TestBlockMarshalSiaSize(t *testing.T) {
+	b := Block{
+		MinerPayouts: []SiacoinOutput{{Value: NewCurrency64(1)}},
+		Transactions: []Transaction{{
+			SiacoinOutputs: []SiacoinOutput{{}},
+			ArbitraryData:  [][]byte{[]byte("foo")},
+		}, {}},
+	}
+	if b.MarshalSiaSize() != len(encoding.Marshal(b)) {
+		t.Errorf("sizes do not match: expected %v, got %v", len(encoding.Marshal(b)), b.MarshalSiaSize())
+	}
+}
+
 // TestUnlockHashScan checks if the fmt.Scanner implementation of UnlockHash
 // works as expected.
 func TestUnlockHashScan(t *testing.T) {
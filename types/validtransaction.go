@@ -292,6 +292,18 @@ func (t Transaction) validUnlockConditions(currentHeight BlockHeight) (err error
 // transaction. StandaloneValid will not check that all outputs being spent are
 // legal outputs, as it has no confirmed or unconfirmed set to look at.
 func (t Transaction) StandaloneValid(currentHeight BlockHeight) (err error) {
+	err = t.StandaloneValidNoSignatures(currentHeight)
+	if err != nil {
+		return
+	}
+	return t.validSignatures(currentHeight)
+}
+
+// StandaloneValidNoSignatures performs the checks of StandaloneValid except
+// for the verification of signatures, which is by far the most expensive
+// check. It should only be used for transactions whose signatures are known
+// to be valid.
+func (t Transaction) StandaloneValidNoSignatures(currentHeight BlockHeight) (err error) {
 	err = t.fitsInABlock(currentHeight)
 	if err != nil {
 		return
@@ -316,13 +328,5 @@ func (t Transaction) StandaloneValid(currentHeight BlockHeight) (err error) {
 	if err != nil {
 		return
 	}
-	err = t.validUnlockConditions(currentHeight)
-	if err != nil {
-		return
-	}
-	err = t.validSignatures(currentHeight)
-	if err != nil {
-		return
-	}
-	return
+	return t.validUnlockConditions(currentHeight)
 }